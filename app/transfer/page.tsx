@@ -28,7 +28,7 @@ export default function TransferPage() {
   const [status, setStatus] = useState("");
 
   const connection = new web3.Connection(
-    "https://devnet.helius-rpc.com/?api-key=0f803376-0189-4d72-95f6-a5f41cef157d"
+    process.env.NEXT_PUBLIC_SOLANA_RPC_URL ?? web3.clusterApiUrl("devnet")
   );
 
   // Fetch SOL price in USD
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// accessLogWriter records the status and size of a response, and whether the
+// connection was hijacked for a WebSocket upgrade
+type accessLogWriter struct {
+	http.ResponseWriter
+	status   int   // Status code sent, 0 until the header is written
+	bytes    int64 // Response body bytes written
+	upgraded bool  // True once the connection was hijacked
+}
+
+// WriteHeader records the status code
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body size, and an implicit 200 status
+func (w *accessLogWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying writer so streamed exports are not buffered
+func (w *accessLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection to the WebSocket upgrader, which writes the
+// 101 response itself
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.upgraded = true
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// wroteHeader reports whether a status was sent or the connection was hijacked
+func (w *accessLogWriter) wroteHeader() bool {
+	return w.status != 0
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// accessLog wraps a handler so every request is logged once it completes
+// WebSocket connections are logged when they close, with the duration of the
+// connection and whether the upgrade succeeded
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &accessLogWriter{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"duration", time.Since(start),
+			"bytes", recorder.bytes,
+			"client_ip", clientIP(r),
+			"user_agent", r.UserAgent(),
+		}
+		if tenant := requestTenant(r); tenant != "" {
+			attrs = append(attrs, "tenant", tenant)
+		}
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			attrs = append(attrs, "upgraded", recorder.upgraded)
+		}
+		slog.Info("HTTP request", attrs...)
+	})
+}
+
+// clientIP returns the address of the peer that sent the request
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the comma separated domains to obtain
+	// certificates for through ACME, e.g. feed.example.com
+	// ACME is disabled when it is unset
+	acmeDomainsEnv = "ACME_DOMAINS"
+
+	// Environment variable with the directory certificates and the account key
+	// are cached in, so restarts do not hit the CA rate limits
+	acmeCacheDirEnv = "ACME_CACHE_DIR"
+
+	// Environment variable with the contact email registered with the CA
+	acmeEmailEnv = "ACME_EMAIL"
+
+	// Environment variable with the ACME directory URL, e.g. the Let's Encrypt
+	// staging directory for testing; Let's Encrypt production is the default
+	acmeDirectoryURLEnv = "ACME_DIRECTORY_URL"
+
+	// Environment variable with the address of the listener answering HTTP-01
+	// challenges and redirecting everything else to HTTPS
+	acmeHTTPAddrEnv = "ACME_HTTP_ADDR"
+
+	// Cache directory used when ACME_CACHE_DIR is unset
+	defaultACMECacheDir = "acme-cache"
+
+	// Challenge listener address used when ACME_HTTP_ADDR is unset; the CA
+	// always connects to port 80
+	defaultACMEHTTPAddr = ":80"
+)
+
+// acmeTLSConfig obtains and renews certificates for ACME_DOMAINS on demand
+// and starts the HTTP-01 challenge listener
+// TLS-ALPN-01 challenges are answered on the TLS listener itself
+//
+// Returns:
+//   - *tls.Config: Configuration fetching certificates from the CA, nil when ACME is not enabled
+//   - error: Error if the cache directory cannot be created
+func acmeTLSConfig() (*tls.Config, error) {
+	value := os.Getenv(acmeDomainsEnv)
+	if value == "" {
+		return nil, nil
+	}
+
+	var domains []string
+	for _, domain := range strings.Split(value, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("invalid %s %q", acmeDomainsEnv, value)
+	}
+
+	cacheDir := envOrDefault(acmeCacheDirEnv, defaultACMECacheDir)
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create ACME cache directory %s: %w", cacheDir, err)
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      os.Getenv(acmeEmailEnv),
+	}
+	if directory := os.Getenv(acmeDirectoryURLEnv); directory != "" {
+		manager.Client = &acme.Client{DirectoryURL: directory}
+	}
+
+	challengeAddr := envOrDefault(acmeHTTPAddrEnv, defaultACMEHTTPAddr)
+	challengeServer := &http.Server{
+		Addr:        challengeAddr,
+		Handler:     manager.HTTPHandler(nil),
+		ReadTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := challengeServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("ACME challenge server failed", logKeyError, err)
+		}
+	}()
+
+	slog.Info("Obtaining certificates through ACME", "domains", strings.Join(domains, ","), "challenge_addr", challengeAddr)
+	return manager.TLSConfig(), nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// DerivedAddresses represents the accounts derived from the mint of a token
+// that trading clients need to build transactions, so they do not have to
+// reimplement the seed derivation
+type DerivedAddresses struct {
+	BondingCurve           string `json:"bonding_curve"`                   // Bonding curve PDA of the mint
+	AssociatedBondingCurve string `json:"associated_bonding_curve"`        // Token account of the bonding curve
+	CreatorTokenAccount    string `json:"creator_token_account,omitempty"` // Associated token account of the creator, if known
+}
+
+// addDerivedAddresses adds the derived accounts of the token to the event
+// The creator of a trade or completion is taken from the token store, so
+// tokens that were not seen being created get no creator token account
+func addDerivedAddresses(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		if !Features.Enabled(featureDerivedAddresses) {
+			return next(ctx, event)
+		}
+
+		var mint, bondingCurve, creator solana.PublicKey
+		switch decoded := event.Decoded.(type) {
+		case *pumpstream.CreateEvent:
+			mint, bondingCurve, creator = decoded.Mint, decoded.BondingCurve, decoded.User
+		case *pumpstream.TradeEvent:
+			mint = decoded.Mint
+		case *pumpstream.CompleteEvent:
+			mint, bondingCurve = decoded.Mint, decoded.BondingCurve
+		default:
+			return next(ctx, event)
+		}
+
+		if creator.IsZero() {
+			if record, found := Tokens.Get(mint.String()); found {
+				creator, _ = solana.PublicKeyFromBase58(record.Creator)
+			}
+		}
+
+		addresses, err := deriveAddresses(mint, bondingCurve, creator)
+		if err != nil {
+			reportError(errorCategoryDecode, err, logKeySignature, event.Signature, logKeySlot, event.Slot)
+			return next(ctx, event)
+		}
+		event.Addresses = addresses
+		return next(ctx, event)
+	}
+}
+
+// deriveAddresses derives the accounts of a token
+//
+// Parameters:
+//   - mint: Mint of the token
+//   - bondingCurve: Bonding curve of the mint, derived when zero
+//   - creator: Creator wallet, zero if unknown
+//
+// Returns:
+//   - *DerivedAddresses: The derived accounts
+//   - error: Error if an address cannot be derived
+func deriveAddresses(mint, bondingCurve, creator solana.PublicKey) (*DerivedAddresses, error) {
+	if bondingCurve.IsZero() {
+		var err error
+		if bondingCurve, err = pumpstream.BondingCurveAddress(pumpProgram(), mint); err != nil {
+			return nil, err
+		}
+	}
+	associatedBondingCurve, err := pumpstream.AssociatedBondingCurveAddress(bondingCurve, mint)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := &DerivedAddresses{
+		BondingCurve:           bondingCurve.String(),
+		AssociatedBondingCurve: associatedBondingCurve.String(),
+	}
+	if !creator.IsZero() {
+		creatorTokenAccount, err := pumpstream.AssociatedTokenAddress(creator, mint)
+		if err != nil {
+			return nil, err
+		}
+		addresses.CreatorTokenAccount = creatorTokenAccount.String()
+	}
+	return addresses, nil
+}
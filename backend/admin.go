@@ -0,0 +1,315 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Environment variable holding a bearer token granting the operator role on
+	// the admin endpoints; admin endpoints are disabled when neither it nor
+	// ADMIN_TOKENS_FILE is set
+	adminTokenEnv = "ADMIN_TOKEN"
+
+	// Environment variable pointing to a JSON file listing named admin tokens and their roles
+	adminTokensFileEnv = "ADMIN_TOKENS_FILE"
+
+	// Name logged for actions taken with ADMIN_TOKEN
+	adminTokenName = "admin"
+
+	// Path prefix of the admin route group
+	adminPathPrefix = "/admin"
+
+	// Admin endpoints managing webhook registrations
+	adminWebhooksEndpoint = "/admin/webhooks"
+	adminWebhookEndpoint  = "/admin/webhooks/{id}"
+
+	// Admin endpoint listing the connected WebSocket clients
+	adminClientsEndpoint = "/admin/clients"
+)
+
+// Admin roles: read-only tokens may only read, operators may also change state
+const (
+	adminRoleReadOnly = "read-only"
+	adminRoleOperator = "operator"
+)
+
+// adminRoleRank orders the roles, a role granting everything the lower ones do
+var adminRoleRank = map[string]int{adminRoleReadOnly: 1, adminRoleOperator: 2}
+
+// AdminToken describes an admin bearer token in ADMIN_TOKENS_FILE
+type AdminToken struct {
+	Name  string `json:"name"`  // Who holds the token, logged with every action
+	Token string `json:"token"` // Bearer token
+	Role  string `json:"role"`  // read-only or operator
+}
+
+// adminTokens are the tokens loaded from ADMIN_TOKENS_FILE, replaced on reload
+var (
+	adminTokensMutex sync.RWMutex
+	adminTokens      []AdminToken
+)
+
+// ClientInfo is the admin view of a connected WebSocket client
+type ClientInfo struct {
+	ClientID     string    `json:"client_id"`            // Remote address of the connection
+	IP           string    `json:"ip"`                   // IP address of the client
+	UserAgent    string    `json:"user_agent,omitempty"` // User-Agent of the upgrade request
+	Tenant       string    `json:"tenant,omitempty"`     // Tenant of the client certificate, when mutual TLS is enabled
+	ConnectedAt  time.Time `json:"connected_at"`         // Time the connection was upgraded
+	MessagesSent uint64    `json:"messages_sent"`        // Messages written to the client so far
+	BytesSent    uint64    `json:"bytes_sent"`           // Message bytes written to the client so far
+	Geo          GeoInfo   `json:"geo,omitzero"`         // Location of the IP address, when GeoIP is configured
+}
+
+// registerAdminRoutes registers the authenticated admin endpoints on the given
+// router, in a route group under /admin; reading requires the read-only role,
+// changing state the operator role
+func registerAdminRoutes(router *mux.Router) {
+	admin := router.PathPrefix(adminPathPrefix).Subrouter()
+	route := func(path, method, role string, handler http.HandlerFunc) {
+		admin.HandleFunc(strings.TrimPrefix(path, adminPathPrefix), requireRole(role, handler)).Methods(method)
+	}
+
+	route(adminWebhooksEndpoint, http.MethodGet, adminRoleReadOnly, HandleListWebhooks)
+	route(adminWebhooksEndpoint, http.MethodPost, adminRoleOperator, HandleCreateWebhook)
+	route(adminWebhookEndpoint, http.MethodDelete, adminRoleOperator, HandleDeleteWebhook)
+	route(adminConnectionsEndpoint, http.MethodGet, adminRoleReadOnly, HandleListConnections)
+	route(adminClientsEndpoint, http.MethodGet, adminRoleReadOnly, HandleListClients)
+	route(adminStatsEndpoint, http.MethodGet, adminRoleReadOnly, HandleAdminStats)
+	route(adminReloadEndpoint, http.MethodPost, adminRoleOperator, HandleReload)
+	route(adminIPAccessEndpoint, http.MethodGet, adminRoleReadOnly, HandleListIPAccess)
+	route(adminIPAccessListEndpoint, http.MethodPost, adminRoleOperator, HandleAddIPAccess)
+	route(adminIPAccessListEndpoint, http.MethodDelete, adminRoleOperator, HandleRemoveIPAccess)
+	route(adminBansEndpoint, http.MethodGet, adminRoleReadOnly, HandleListBans)
+	route(adminBanEndpoint, http.MethodDelete, adminRoleOperator, HandleLiftBan)
+	route(adminFeaturesEndpoint, http.MethodGet, adminRoleReadOnly, HandleListFeatures)
+	route(adminFeatureEndpoint, http.MethodPut, adminRoleOperator, HandleSetFeature)
+	route(adminFeatureEndpoint, http.MethodDelete, adminRoleOperator, HandleClearFeature)
+	route(adminLogLevelEndpoint, http.MethodGet, adminRoleReadOnly, HandleGetLogLevel)
+	route(adminLogLevelEndpoint, http.MethodPut, adminRoleOperator, HandleSetLogLevel)
+	route(adminLogLevelEndpoint, http.MethodDelete, adminRoleOperator, HandleClearLogLevel)
+	route(adminWalletEndpoint, http.MethodGet, adminRoleReadOnly, HandleGetWallet)
+	route(adminWalletTradesEndpoint, http.MethodPost, adminRoleOperator, HandleSubmitWalletTrade)
+	route(adminWalletTradeEndpoint, http.MethodGet, adminRoleReadOnly, HandleGetWalletTrade)
+	route(adminAutoBuyEndpoint, http.MethodGet, adminRoleReadOnly, HandleGetAutoBuy)
+	route(adminPaperTradingEndpoint, http.MethodGet, adminRoleReadOnly, HandleGetPaperTrading)
+	route(adminAlertRulesEndpoint, http.MethodGet, adminRoleReadOnly, HandleListAlertRules)
+	route(adminAlertRulesEndpoint, http.MethodPost, adminRoleOperator, HandleCreateAlertRule)
+	route(adminAlertRuleEndpoint, http.MethodDelete, adminRoleOperator, HandleDeleteAlertRule)
+	route(adminFilterScriptsEndpoint, http.MethodGet, adminRoleReadOnly, HandleListFilterScripts)
+}
+
+// setupAdminTokens loads the named admin tokens when ADMIN_TOKENS_FILE is set
+func setupAdminTokens() error {
+	if os.Getenv(adminTokensFileEnv) == "" {
+		return nil
+	}
+	if err := loadAdminTokens(); err != nil {
+		return err
+	}
+
+	adminTokensMutex.RLock()
+	defer adminTokensMutex.RUnlock()
+	slog.Info("Loaded admin tokens", "count", len(adminTokens))
+	return nil
+}
+
+// loadAdminTokens reads and validates ADMIN_TOKENS_FILE, replacing the loaded
+// tokens; the tokens are redacted from logs
+func loadAdminTokens() error {
+	path := os.Getenv(adminTokensFileEnv)
+	if path == "" {
+		adminTokensMutex.Lock()
+		adminTokens = nil
+		adminTokensMutex.Unlock()
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read admin tokens file: %w", err)
+	}
+	var tokens []AdminToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("failed to parse admin tokens file: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, token := range tokens {
+		if token.Name == "" || token.Token == "" {
+			return fmt.Errorf("admin tokens file: every token needs a name and a token")
+		}
+		if _, ok := adminRoleRank[token.Role]; !ok {
+			return fmt.Errorf("admin token %s: role must be %s or %s", token.Name, adminRoleReadOnly, adminRoleOperator)
+		}
+		if seen[token.Token] {
+			return fmt.Errorf("admin token %s: token is used twice", token.Name)
+		}
+		seen[token.Token] = true
+		registerSecret(token.Token)
+	}
+
+	adminTokensMutex.Lock()
+	defer adminTokensMutex.Unlock()
+	adminTokens = tokens
+	return nil
+}
+
+// adminEnabled reports whether any admin token is configured
+func adminEnabled() bool {
+	adminTokensMutex.RLock()
+	defer adminTokensMutex.RUnlock()
+	return os.Getenv(adminTokenEnv) != "" || len(adminTokens) > 0
+}
+
+// authenticateAdmin returns the admin token a request carries, ADMIN_TOKEN
+// standing for the operator role
+//
+// Returns:
+//   - AdminToken: The matching token
+//   - bool: False if the request carries no valid token
+func authenticateAdmin(r *http.Request) (AdminToken, bool) {
+	presented, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || presented == "" {
+		return AdminToken{}, false
+	}
+
+	if expected := os.Getenv(adminTokenEnv); expected != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(expected)) == 1 {
+		return AdminToken{Name: adminTokenName, Role: adminRoleOperator}, true
+	}
+
+	adminTokensMutex.RLock()
+	defer adminTokensMutex.RUnlock()
+	for _, token := range adminTokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token.Token)) == 1 {
+			return token, true
+		}
+	}
+	return AdminToken{}, false
+}
+
+// requireRole wraps a handler so it only runs for requests carrying an admin
+// bearer token with at least the given role. Every action is logged with the
+// name of the token; reads at debug level, changes and refusals at info and warn.
+func requireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !adminEnabled() {
+			writeError(w, http.StatusNotFound, "admin API is disabled")
+			return
+		}
+
+		// A valid token passes even from a banned address, so an operator
+		// can always lift a ban; guessing goes nowhere until it ends
+		token, ok := authenticateAdmin(r)
+		if !ok && refuseBanned(w, clientIP(r)) {
+			return
+		}
+		if !ok {
+			slog.Warn("Rejected admin request", "method", r.Method, "path", r.URL.Path, "client_ip", clientIP(r), "reason", "invalid token")
+			AutoBan.Record(clientIP(r), abuseAuthFailure)
+			writeError(w, http.StatusUnauthorized, "invalid admin token")
+			return
+		}
+		if adminRoleRank[token.Role] < adminRoleRank[role] {
+			slog.Warn("Rejected admin request", "admin", token.Name, "role", token.Role, "method", r.Method, "path", r.URL.Path,
+				"client_ip", clientIP(r), "reason", "requires the "+role+" role")
+			writeError(w, http.StatusForbidden, "this action requires the "+role+" role")
+			return
+		}
+
+		recorder := &accessLogWriter{ResponseWriter: w}
+		next(recorder, r)
+
+		level := slog.LevelInfo
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			level = slog.LevelDebug
+		}
+		slog.Log(r.Context(), level, "Admin action", "admin", token.Name, "role", token.Role, "method", r.Method,
+			"path", r.URL.Path, "status", recorder.status, "client_ip", clientIP(r))
+	}
+}
+
+// HandleListWebhooks returns every registered webhook with its delivery status
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Webhooks.List())
+}
+
+// HandleListClients returns the connected WebSocket clients, longest connected first
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListClients(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, connectedClientInfos())
+}
+
+// connectedClientInfos describes every connected WebSocket client, longest connected first
+func connectedClientInfos() []ClientInfo {
+	clients := []ClientInfo{}
+	ConnectedClients.Range(func(address string, client *Client) bool {
+		clients = append(clients, ClientInfo{
+			ClientID:     address,
+			IP:           client.ip,
+			UserAgent:    client.userAgent,
+			Tenant:       client.tenant,
+			ConnectedAt:  client.connectedAt,
+			MessagesSent: client.messagesSent.Load(),
+			BytesSent:    client.bytesSent.Load(),
+			Geo:          client.geo,
+		})
+		return true
+	})
+	slices.SortFunc(clients, func(a, b ClientInfo) int { return a.ConnectedAt.Compare(b.ConnectedAt) })
+	return clients
+}
+
+// HandleCreateWebhook registers a webhook from the JSON request body
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a WebhookConfig body
+func HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var config WebhookConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	registered, err := Webhooks.Add(config)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, registered)
+}
+
+// HandleDeleteWebhook unregisters the webhook with the given ID
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the webhook ID as a path variable
+func HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !Webhooks.Remove(mux.Vars(r)["id"]) {
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testAdminTokens installs ADMIN_TOKEN and the tokens of ADMIN_TOKENS_FILE for
+// the duration of a test
+func testAdminTokens(t *testing.T, token string, tokens ...AdminToken) {
+	t.Setenv(adminTokenEnv, token)
+	adminTokensMutex.Lock()
+	previous := adminTokens
+	adminTokens = tokens
+	adminTokensMutex.Unlock()
+	t.Cleanup(func() {
+		adminTokensMutex.Lock()
+		adminTokens = previous
+		adminTokensMutex.Unlock()
+	})
+}
+
+// TestAuthenticateAdmin checks that ADMIN_TOKEN authenticates as the operator
+// role, named tokens as their own role, and that anything else is refused
+func TestAuthenticateAdmin(t *testing.T) {
+	viewer := AdminToken{Name: "viewer", Token: "viewer-token", Role: adminRoleReadOnly}
+	deployer := AdminToken{Name: "deployer", Token: "deployer-token", Role: adminRoleOperator}
+
+	tests := []struct {
+		name     string
+		token    string
+		header   string
+		expected AdminToken
+		ok       bool
+	}{
+		{name: "admin token", token: "root-token", header: "Bearer root-token", expected: AdminToken{Name: adminTokenName, Role: adminRoleOperator}, ok: true},
+		{name: "read-only token", token: "root-token", header: "Bearer viewer-token", expected: viewer, ok: true},
+		{name: "operator token", header: "Bearer deployer-token", expected: deployer, ok: true},
+		{name: "unknown token", token: "root-token", header: "Bearer other-token"},
+		{name: "prefix of a token", header: "Bearer viewer-toke"},
+		{name: "without the bearer scheme", token: "root-token", header: "root-token"},
+		{name: "lowercase scheme", token: "root-token", header: "bearer root-token"},
+		{name: "empty token", header: "Bearer "},
+		{name: "empty token without ADMIN_TOKEN set", token: "", header: "Bearer"},
+		{name: "missing header", token: "root-token"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testAdminTokens(t, test.token, viewer, deployer)
+			request := httptest.NewRequest(http.MethodGet, adminClientsEndpoint, nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+
+			token, ok := authenticateAdmin(request)
+			if ok != test.ok || token != test.expected {
+				t.Fatalf("got %+v (%v), expected %+v (%v)", token, ok, test.expected, test.ok)
+			}
+		})
+	}
+}
+
+// TestRequireRole checks that admin handlers only run for tokens with at
+// least their role, and that a valid token passes from a banned address
+func TestRequireRole(t *testing.T) {
+	viewer := AdminToken{Name: "viewer", Token: "viewer-token", Role: adminRoleReadOnly}
+	deployer := AdminToken{Name: "deployer", Token: "deployer-token", Role: adminRoleOperator}
+
+	tests := []struct {
+		name     string
+		disabled bool
+		banned   bool
+		role     string
+		header   string
+		expected int
+	}{
+		{name: "read-only reading", role: adminRoleReadOnly, header: "Bearer viewer-token", expected: http.StatusNoContent},
+		{name: "operator reading", role: adminRoleReadOnly, header: "Bearer deployer-token", expected: http.StatusNoContent},
+		{name: "admin token changing", role: adminRoleOperator, header: "Bearer root-token", expected: http.StatusNoContent},
+		{name: "operator changing", role: adminRoleOperator, header: "Bearer deployer-token", expected: http.StatusNoContent},
+		{name: "read-only changing", role: adminRoleOperator, header: "Bearer viewer-token", expected: http.StatusForbidden},
+		{name: "invalid token", role: adminRoleReadOnly, header: "Bearer other-token", expected: http.StatusUnauthorized},
+		{name: "missing header", role: adminRoleReadOnly, expected: http.StatusUnauthorized},
+		{name: "admin disabled", disabled: true, role: adminRoleReadOnly, header: "Bearer root-token", expected: http.StatusNotFound},
+		{name: "banned without a token", banned: true, role: adminRoleReadOnly, expected: http.StatusTooManyRequests},
+		{name: "banned with an invalid token", banned: true, role: adminRoleReadOnly, header: "Bearer other-token", expected: http.StatusTooManyRequests},
+		{name: "banned with a valid token", banned: true, role: adminRoleOperator, header: "Bearer deployer-token", expected: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.disabled {
+				testAdminTokens(t, "")
+			} else {
+				testAdminTokens(t, "root-token", viewer, deployer)
+			}
+			previous := AutoBan
+			t.Cleanup(func() { AutoBan = previous })
+			AutoBan = nil
+			if test.banned {
+				AutoBan = &AutoBanner{threshold: 20, halfLife: time.Minute, duration: time.Minute, records: map[string]*abuseRecord{
+					"192.0.2.1": {updatedAt: time.Now(), ban: Ban{IP: "192.0.2.1", ExpiresAt: time.Now().Add(time.Minute)}},
+				}}
+			}
+
+			called := false
+			handler := requireRole(test.role, func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusNoContent)
+			})
+			request := httptest.NewRequest(http.MethodPost, adminReloadEndpoint, nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			if recorder.Code != test.expected {
+				t.Fatalf("got status %d, expected %d", recorder.Code, test.expected)
+			}
+			if called != (test.expected == http.StatusNoContent) {
+				t.Fatalf("handler called %v, expected %v", called, !called)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Admin endpoint reporting runtime aggregates
+	adminStatsEndpoint = "/admin/stats"
+
+	// Seconds over which rates are averaged
+	rateWindowSeconds = 60
+)
+
+// rateCounter counts occurrences and their rate over the last rateWindowSeconds
+type rateCounter struct {
+	mutex   sync.Mutex
+	total   uint64
+	counts  [rateWindowSeconds]uint64 // Occurrences per second, indexed by Unix second modulo the window
+	seconds [rateWindowSeconds]int64  // Unix second each count belongs to
+}
+
+// Add counts n occurrences now
+func (c *rateCounter) Add(n uint64) {
+	now := time.Now().Unix()
+	i := now % rateWindowSeconds
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.seconds[i] != now {
+		c.seconds[i] = now
+		c.counts[i] = 0
+	}
+	c.counts[i] += n
+	c.total += n
+}
+
+// Snapshot returns the total and the occurrences within the window
+func (c *rateCounter) Snapshot() (total, recent uint64) {
+	now := time.Now().Unix()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i, second := range c.seconds {
+		if now-second < rateWindowSeconds {
+			recent += c.counts[i]
+		}
+	}
+	return c.total, recent
+}
+
+// Counters of published events and of writes to WebSocket clients
+var (
+	publishedEvents   rateCounter
+	broadcastWrites   rateCounter
+	broadcastFailures rateCounter
+)
+
+// droppingSink is implemented by sinks that drop events when they fall behind
+type droppingSink interface {
+	DroppedEvents() uint64
+}
+
+// AdminStats is the JSON body returned by the admin stats endpoint
+// Rates are averaged over the last minute
+type AdminStats struct {
+	Uptime           float64               `json:"uptime_seconds"`           // Seconds since the process started
+	Goroutines       int                   `json:"goroutines"`               // Number of running goroutines
+	ConnectedClients int                   `json:"connected_clients"`        // Currently connected WebSocket clients
+	EventsTotal      uint64                `json:"events_total"`             // Events published since startup
+	EventsPerSecond  float64               `json:"events_per_second"`        // Events published per second
+	Broadcast        BroadcastStats        `json:"broadcast"`                // Writes of live messages to WebSocket clients
+	DeliveryLatency  LatencySnapshot       `json:"delivery_latency"`         // Time from notification receipt to client write, since startup
+	Sinks            map[string]SinkStats  `json:"sinks"`                    // Backlog of every sink by name
+	Bans             *BanStats             `json:"bans,omitempty"`           // Automatic bans, when enabled
+	Dedup            map[string]DedupStats `json:"dedup"`                    // Dedup caches of signatures and mints, by name
+	RPCCache         *RPCCacheStats        `json:"rpc_cache,omitempty"`      // Enrichment RPC cache, when enabled
+	TruncatedLogs    *TruncatedLogStats    `json:"truncated_logs,omitempty"` // Truncated log fallback, when enabled
+	Chaos            *ChaosStats           `json:"chaos,omitempty"`          // Injected faults, in chaos mode
+	Memory           MemoryStats           `json:"memory"`                   // Go runtime memory usage
+}
+
+// BroadcastStats counts the writes of live messages to WebSocket clients
+type BroadcastStats struct {
+	Writes   uint64  `json:"writes"`    // Writes attempted since startup
+	Failures uint64  `json:"failures"`  // Writes that failed since startup
+	DropRate float64 `json:"drop_rate"` // Fraction of writes that failed
+}
+
+// SinkStats describes the backlog of one sink
+type SinkStats struct {
+	Backlog int    `json:"backlog"`           // Events waiting to be sent
+	Dropped uint64 `json:"dropped,omitempty"` // Events dropped because the queue was full
+}
+
+// MemoryStats is the subset of the Go runtime memory statistics useful for triage
+type MemoryStats struct {
+	HeapAlloc   uint64  `json:"heap_alloc_bytes"` // Bytes of allocated heap objects
+	HeapInuse   uint64  `json:"heap_inuse_bytes"` // Bytes in in-use heap spans
+	Sys         uint64  `json:"sys_bytes"`        // Bytes obtained from the operating system
+	NumGC       uint32  `json:"num_gc"`           // Completed garbage collection cycles
+	LastGCPause float64 `json:"last_gc_pause_ms"` // Milliseconds of the last stop-the-world pause
+	GCCPU       float64 `json:"gc_cpu_fraction"`  // Fraction of CPU time used by the collector
+}
+
+// HandleAdminStats returns runtime aggregates for operational triage
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleAdminStats(w http.ResponseWriter, r *http.Request) {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	stats := AdminStats{
+		Uptime:           time.Since(FeedStats.Snapshot().StartedAt).Seconds(),
+		Goroutines:       runtime.NumGoroutine(),
+		ConnectedClients: ConnectedClients.Size(),
+		Sinks:            map[string]SinkStats{},
+		Dedup:            dedupStats(),
+		Memory: MemoryStats{
+			HeapAlloc:   memory.HeapAlloc,
+			HeapInuse:   memory.HeapInuse,
+			Sys:         memory.Sys,
+			NumGC:       memory.NumGC,
+			LastGCPause: float64(memory.PauseNs[(memory.NumGC+255)%256]) / 1e6,
+			GCCPU:       memory.GCCPUFraction,
+		},
+	}
+
+	total, recent := publishedEvents.Snapshot()
+	stats.EventsTotal = total
+	stats.EventsPerSecond = float64(recent) / rateWindowSeconds
+
+	writes, recentWrites := broadcastWrites.Snapshot()
+	failures, recentFailures := broadcastFailures.Snapshot()
+	stats.Broadcast = BroadcastStats{Writes: writes, Failures: failures}
+	stats.DeliveryLatency = deliveryLatency.Snapshot()
+	if recentWrites > 0 {
+		stats.Broadcast.DropRate = float64(recentFailures) / float64(recentWrites)
+	}
+
+	if AutoBan != nil {
+		bans := AutoBan.Stats()
+		stats.Bans = &bans
+	}
+	if RPCResults != nil {
+		cache := RPCResults.Stats()
+		stats.RPCCache = &cache
+	}
+	if TruncatedLogs != nil {
+		truncated := TruncatedLogs.Stats()
+		stats.TruncatedLogs = &truncated
+	}
+	if Chaos != nil {
+		chaos := Chaos.Stats()
+		stats.Chaos = &chaos
+	}
+
+	eventSinksMutex.RLock()
+	for _, sink := range eventSinks {
+		var sinkStats SinkStats
+		if queued, ok := sink.(queuedSink); ok {
+			sinkStats.Backlog = queued.QueueDepth()
+		}
+		if dropping, ok := sink.(droppingSink); ok {
+			sinkStats.Dropped = dropping.DroppedEvents()
+		}
+		stats.Sinks[sink.Name()] = sinkStats
+	}
+	eventSinksMutex.RUnlock()
+
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Configuration constants
+const (
+	// REST endpoint returning aggregated series computed from storage
+	aggregatesEndpoint = "/api/history/aggregates"
+
+	// Largest number of buckets a single request may span
+	maxAggregateBuckets = 2000
+
+	// Span covered when the request does not pass from
+	defaultAggregateSpan = 7 * 24 * time.Hour
+)
+
+// aggregateBuckets maps the accepted bucket names to their width
+var aggregateBuckets = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+// AggregateSeries is the JSON body of the aggregation endpoint
+type AggregateSeries struct {
+	Program string            `json:"program"` // Program the events were stored for
+	Bucket  string            `json:"bucket"`  // Bucket width, hour or day
+	From    time.Time         `json:"from"`    // Start of the first bucket
+	To      time.Time         `json:"to"`      // End of the last bucket
+	Buckets []AggregateBucket `json:"buckets"` // Buckets in time order, empty ones included
+}
+
+// AggregateBucket holds the metrics of the tokens launched during one bucket
+// Graduations count the launches of the bucket whose curve completed before
+// the end of the requested range, so recent buckets fill up over time.
+type AggregateBucket struct {
+	Start          time.Time `json:"start"`                    // Start of the bucket
+	Launches       int       `json:"launches"`                 // Tokens created
+	Graduations    int       `json:"graduations"`              // Tokens created in the bucket that graduated
+	GraduationRate float64   `json:"graduation_rate"`          // Graduations divided by launches, 0 without launches
+	DevBuys        *int      `json:"dev_buys,omitempty"`       // Launches whose transaction bought tokens, with dev_buy=true
+	MedianDevBuy   *uint64   `json:"median_dev_buy,omitempty"` // Median lamports of those buys, with dev_buy=true
+}
+
+// aggregateBucket accumulates a bucket while storage is scanned
+type aggregateBucket struct {
+	launches    int
+	graduations int
+	devBuys     []uint64
+}
+
+// HandleAggregates returns launches, graduations and dev buys of stored
+// creations, bucketed by hour or day
+// Dev buys need every stored trade of the range to be scanned, so they are
+// only computed when dev_buy=true.
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with optional bucket, from, to, program and dev_buy query parameters
+func HandleAggregates(w http.ResponseWriter, r *http.Request) {
+	if Persistence == nil {
+		writeError(w, http.StatusNotFound, "aggregates require a storage backend")
+		return
+	}
+
+	params := r.URL.Query()
+	bucketName := params.Get("bucket")
+	if bucketName == "" {
+		bucketName = "hour"
+	}
+	width, ok := aggregateBuckets[bucketName]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bucket must be hour or day")
+		return
+	}
+	// Only the events of the program the feed listens to are stored
+	if program := params.Get("program"); program != "" && program != pumpProgram().String() {
+		writeError(w, http.StatusBadRequest, "only events of program "+pumpProgram().String()+" are stored")
+		return
+	}
+	devBuys := false
+	if value := params.Get("dev_buy"); value != "" {
+		var err error
+		if devBuys, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, "dev_buy must be true or false")
+			return
+		}
+	}
+
+	from, err := parseTimeBound(params.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be a date (YYYY-MM-DD) or RFC 3339 time")
+		return
+	}
+	to, err := parseTimeBound(params.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be a date (YYYY-MM-DD) or RFC 3339 time")
+		return
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultAggregateSpan)
+	}
+	// Buckets are aligned on UTC, the last one covering to
+	from = from.UTC().Truncate(width)
+	to = to.UTC().Add(width - 1).Truncate(width)
+	count := int(to.Sub(from) / width)
+	if count <= 0 {
+		writeError(w, http.StatusBadRequest, "from must be before to")
+		return
+	}
+	if count > maxAggregateBuckets {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("the range spans %d buckets, at most %d are allowed", count, maxAggregateBuckets))
+		return
+	}
+
+	series, err := aggregateEvents(from, to, width, count, devBuys)
+	if err != nil {
+		slog.Warn("Failed to aggregate stored events", logKeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to read stored events")
+		return
+	}
+	series.Bucket = bucketName
+	writeJSON(w, http.StatusOK, series)
+}
+
+// aggregateEvents scans the stored events of a range into buckets
+//
+// Parameters:
+//   - from, to: Range to scan, aligned on the bucket width
+//   - width: Bucket width
+//   - count: Number of buckets between from and to
+//   - devBuys: Whether to scan trades for the dev buys
+//
+// Returns:
+//   - AggregateSeries: The series, without its bucket name
+//   - error: Error if storage could not be scanned
+func aggregateEvents(from, to time.Time, width time.Duration, count int, devBuys bool) (AggregateSeries, error) {
+	buckets := make([]aggregateBucket, count)
+	launched := make(map[string]int)  // Bucket of the tokens created in the range, by mint
+	creations := make(map[string]int) // Bucket of the creations awaiting their dev buy, by signature
+	bucketOf := func(at time.Time) int { return int(at.Sub(from) / width) }
+
+	query := EventQuery{From: from, To: to, Types: []EventType{EventCreate, EventComplete}}
+	if devBuys {
+		query.Types = append(query.Types, EventTrade)
+	}
+	err := Persistence.ScanEvents(query, func(event Event) error {
+		index := bucketOf(event.ReceivedAt)
+		if index < 0 || index >= count {
+			return nil
+		}
+		switch event.Type {
+		case EventCreate:
+			buckets[index].launches++
+			launched[event.Mint] = index
+			if devBuys {
+				creations[event.Signature] = index
+			}
+		case EventComplete:
+			if created, found := launched[event.Mint]; found {
+				buckets[created].graduations++
+				delete(launched, event.Mint)
+			}
+		case EventTrade:
+			// The dev buy is the first buy of the creation transaction
+			trade, ok := event.Data.(TradeEvent)
+			created, found := creations[event.Signature]
+			if ok && found && trade.IsBuy {
+				buckets[created].devBuys = append(buckets[created].devBuys, trade.SolAmount)
+				delete(creations, event.Signature)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return AggregateSeries{}, err
+	}
+
+	series := AggregateSeries{
+		Program: pumpProgram().String(),
+		From:    from,
+		To:      to,
+		Buckets: make([]AggregateBucket, count),
+	}
+	for i, bucket := range buckets {
+		result := AggregateBucket{
+			Start:       from.Add(time.Duration(i) * width),
+			Launches:    bucket.launches,
+			Graduations: bucket.graduations,
+		}
+		if bucket.launches > 0 {
+			result.GraduationRate = float64(bucket.graduations) / float64(bucket.launches)
+		}
+		if devBuys {
+			buys := len(bucket.devBuys)
+			var median uint64
+			if buys > 0 {
+				slices.Sort(bucket.devBuys)
+				median = bucket.devBuys[buys/2]
+				if buys%2 == 0 {
+					median = (bucket.devBuys[buys/2-1] + bucket.devBuys[buys/2]) / 2
+				}
+			}
+			result.DevBuys = &buys
+			result.MedianDevBuy = &median
+		}
+		series.Buckets[i] = result
+	}
+	return series, nil
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/gorilla/mux"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file of alert rules to load at startup
+	alertRulesFileEnv = "ALERT_RULES_FILE"
+
+	// Admin endpoints managing the alert rules
+	adminAlertRulesEndpoint = "/admin/alert-rules"
+	adminAlertRuleEndpoint  = "/admin/alert-rules/{id}"
+
+	// Kind of the alerts raised by alert rules
+	alertKindRule = "rule"
+
+	// Number of mints remembered exactly per rule so each fires only once per
+	// mint; older ones are remembered by the Bloom filters of the cache
+	alertRuleMaxRememberedMints = 10000
+)
+
+// AlertRuleConfig describes a user-defined alert rule
+type AlertRuleConfig struct {
+	ID         string   `json:"id"`                 // Identifier assigned at registration
+	Name       string   `json:"name"`               // Rule name shown in the alerts
+	Expression string   `json:"expression"`         // expr expression over the fields of alertRuleEnv, e.g. symbol matches "AI" && dev_buy_sol > 2
+	Sinks      []string `json:"sinks"`              // Alerting sinks the alerts go to, e.g. telegram, webhooks, websocket
+	Cooldown   string   `json:"cooldown,omitempty"` // Go duration between two alerts of the rule
+}
+
+// AlertRuleInfo is the admin view of an alert rule
+type AlertRuleInfo struct {
+	AlertRuleConfig
+	Matches  uint64    `json:"matches"`            // Alerts raised so far
+	Errors   uint64    `json:"errors"`             // Evaluations that failed
+	LastFire time.Time `json:"last_fire,omitzero"` // Time of the latest alert
+}
+
+// alertRuleEnv holds the fields an alert rule or filter script expression can use
+// Token fields come from the token store, so events of tokens that were not
+// seen being created have no name, symbol or creator. The dev buy of a token
+// is its first trade: the buy of the creator in the creation transaction, so
+// rules on dev_buy_sol fire on that trade, not on the creation.
+type alertRuleEnv struct {
+	Type            string   `expr:"type"`              // create, trade or complete
+	Mint            string   `expr:"mint"`              // Token mint address
+	Signature       string   `expr:"signature"`         // Transaction signature
+	Slot            uint64   `expr:"slot"`              // Slot of the transaction
+	Name            string   `expr:"name"`              // Token name
+	Symbol          string   `expr:"symbol"`            // Token symbol
+	URI             string   `expr:"uri"`               // Token metadata URI
+	Creator         string   `expr:"creator"`           // Creator wallet
+	CreatorLaunches int      `expr:"creator_launches"`  // Tracked tokens launched by the creator, this one included
+	IsBuy           bool     `expr:"is_buy"`            // True for buys
+	SolAmount       float64  `expr:"sol_amount"`        // SOL exchanged by a trade
+	TokenAmount     uint64   `expr:"token_amount"`      // Token base units exchanged by a trade
+	Trader          string   `expr:"trader"`            // Wallet of a trade
+	IsDevBuy        bool     `expr:"is_dev_buy"`        // True for the buy of the creator in the creation transaction
+	DevBuySOL       float64  `expr:"dev_buy_sol"`       // SOL of the dev buy, on that trade only
+	MarketCapSOL    float64  `expr:"market_cap_sol"`    // Market cap implied by the latest curve price
+	Graduated       bool     `expr:"graduated"`         // True once the curve completed
+	LiquidityUSD    float64  `expr:"liquidity_usd"`     // Liquidity from the market data provider, when listed
+	Volume24hUSD    float64  `expr:"volume_24h_usd"`    // 24 hour volume from the market data provider, when listed
+	HasMarketData   bool     `expr:"has_market_data"`   // True when the market data provider listed the token
+	TopHoldersShare float64  `expr:"top_holders_share"` // Share of the supply held by the 10 largest wallets, with holder concentration enabled
+	RiskFlags       []string `expr:"risk_flags"`        // Heuristic warnings, as on notifications
+	FundingSource   string   `expr:"funding_source"`    // Funding kind of the creator, e.g. cex or fresh_wallet_chain, once traced
+	FundingName     string   `expr:"funding_name"`      // Exchange or bridge that funded the creator, for labelled kinds
+}
+
+// alertRule holds the compiled expression and firing state of a rule
+type alertRule struct {
+	config   AlertRuleConfig
+	program  *vm.Program
+	cooldown time.Duration
+
+	mutex    sync.Mutex
+	matches  uint64
+	errors   uint64
+	lastFire time.Time
+	fired    *dedupCache // Mints the rule fired for
+}
+
+// AlertRuleSet evaluates the alert rules on every event of the pipeline
+type AlertRuleSet struct {
+	mutex sync.RWMutex
+	rules map[string]*alertRule
+}
+
+// AlertRules holds the alert rules, added through the admin API or ALERT_RULES_FILE
+var AlertRules = &AlertRuleSet{rules: make(map[string]*alertRule)}
+
+// setupAlertRules evaluates the alert rules in the pipeline, loads
+// ALERT_RULES_FILE when it is set, and opens the WebSocket alert channel
+// It runs last of the sink setups, so rules can name any alerting sink.
+func setupAlertRules() error {
+	RegisterSink(WebSocketAlerts)
+	Pipeline.Register(StageEnrich, "alert rules", AlertRules.middleware)
+
+	path := os.Getenv(alertRulesFileEnv)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read alert rules file: %w", err)
+	}
+	var configs []AlertRuleConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("failed to parse alert rules file: %w", err)
+	}
+	for _, config := range configs {
+		if _, err := AlertRules.Add(config); err != nil {
+			return fmt.Errorf("alert rule %q: %w", config.Name, err)
+		}
+	}
+
+	slog.Info("Loaded alert rules", "count", len(configs), "path", path)
+	return nil
+}
+
+// Add compiles and registers a rule
+//
+// Returns:
+//   - AlertRuleConfig: The rule with its assigned ID
+//   - error: Error if the expression does not compile to a boolean, a sink is
+//     not an alerting sink, or the ID is taken
+func (s *AlertRuleSet) Add(config AlertRuleConfig) (AlertRuleConfig, error) {
+	rule, err := compileAlertRule(config)
+	if err != nil {
+		return AlertRuleConfig{}, err
+	}
+	if config.ID == "" {
+		config.ID = newDeliveryID()
+		rule.config.ID = config.ID
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.rules[config.ID]; exists {
+		return AlertRuleConfig{}, fmt.Errorf("alert rule %s already exists", config.ID)
+	}
+	s.rules[config.ID] = rule
+	return config, nil
+}
+
+// compileAlertRule compiles the expression of a rule and checks its sinks and cooldown
+func compileAlertRule(config AlertRuleConfig) (*alertRule, error) {
+	if strings.TrimSpace(config.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	program, err := expr.Compile(config.Expression, expr.Env(alertRuleEnv{}), expr.AsBool())
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	if len(config.Sinks) == 0 {
+		return nil, fmt.Errorf("at least one sink is required, one of %s", strings.Join(alertingSinkNames(), ", "))
+	}
+	for _, name := range config.Sinks {
+		if !slices.Contains(alertingSinkNames(), name) {
+			return nil, fmt.Errorf("unknown sink %q, expected one of %s", name, strings.Join(alertingSinkNames(), ", "))
+		}
+	}
+
+	rule := &alertRule{config: config, program: program, fired: newDedupCache("alert_rule", alertRuleMaxRememberedMints)}
+	if config.Cooldown != "" {
+		if rule.cooldown, err = time.ParseDuration(config.Cooldown); err != nil {
+			return nil, fmt.Errorf("invalid cooldown: %w", err)
+		}
+	}
+	return rule, nil
+}
+
+// Remove unregisters a rule, reporting whether it existed
+func (s *AlertRuleSet) Remove(id string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.rules[id]; !exists {
+		return false
+	}
+	delete(s.rules, id)
+	return true
+}
+
+// List returns the rules, sorted by name
+func (s *AlertRuleSet) List() []AlertRuleInfo {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	infos := make([]AlertRuleInfo, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rule.mutex.Lock()
+		infos = append(infos, AlertRuleInfo{AlertRuleConfig: rule.config, Matches: rule.matches, Errors: rule.errors, LastFire: rule.lastFire})
+		rule.mutex.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// middleware evaluates every rule once the rest of the pipeline delivered the
+// event, so the enrichments registered after it are available
+// Evaluation errors are counted, logged and skip the rule; they never stop the event.
+func (s *AlertRuleSet) middleware(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		if err := next(ctx, event); err != nil {
+			return err
+		}
+
+		s.mutex.RLock()
+		rules := make([]*alertRule, 0, len(s.rules))
+		for _, rule := range s.rules {
+			rules = append(rules, rule)
+		}
+		s.mutex.RUnlock()
+
+		if len(rules) > 0 {
+			if env, ok := newAlertRuleEnv(event); ok {
+				for _, rule := range rules {
+					rule.evaluate(env)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// newAlertRuleEnv collects the fields of an event for the expressions
+func newAlertRuleEnv(event *PipelineEvent) (alertRuleEnv, bool) {
+	env := alertRuleEnv{Signature: event.Signature, Slot: event.Slot, Mint: pipelineEventMint(event)}
+	switch decoded := event.Decoded.(type) {
+	case *pumpstream.CreateEvent:
+		env.Type = string(EventCreate)
+	case *pumpstream.TradeEvent:
+		env.Type = string(EventTrade)
+		env.IsBuy = decoded.IsBuy
+		env.SolAmount = float64(decoded.SolAmount) / lamportsPerSOL
+		env.TokenAmount = decoded.TokenAmount
+		env.Trader = decoded.User.String()
+	case *pumpstream.CompleteEvent:
+		env.Type = string(EventComplete)
+	default:
+		return env, false
+	}
+
+	if record, found := Tokens.Get(env.Mint); found {
+		env.Name = record.Creation.Name
+		env.Symbol = record.Creation.Symbol
+		env.URI = record.Creation.Uri
+		env.Creator = record.Creator
+		env.CreatorLaunches = Tokens.CountByCreator(record.Creator)
+		env.Graduated = record.Migration.Complete
+		env.RiskFlags = riskFlags(record)
+		if record.Curve != nil {
+			env.MarketCapSOL = record.Curve.MarketCapSOL()
+		}
+		env.IsDevBuy = env.IsBuy && env.Signature == record.Signature && env.Trader == record.Creator
+		if env.IsDevBuy {
+			env.DevBuySOL = env.SolAmount
+		}
+	}
+	if event.Market != nil {
+		env.HasMarketData = true
+		env.LiquidityUSD = event.Market.LiquidityUSD
+		env.Volume24hUSD = event.Market.Volume24hUSD
+	}
+	if event.Holders != nil {
+		env.TopHoldersShare = event.Holders.TopShare
+	}
+	if event.Funding != nil {
+		env.FundingSource = event.Funding.Kind
+		env.FundingName = event.Funding.Name
+	}
+	return env, true
+}
+
+// evaluate raises an alert when the expression matches, once per mint and at
+// most once per cooldown
+func (r *alertRule) evaluate(env alertRuleEnv) {
+	result, err := expr.Run(r.program, env)
+	if err != nil {
+		r.mutex.Lock()
+		r.errors++
+		r.mutex.Unlock()
+		slog.Debug("Alert rule failed", "rule", r.config.Name, logKeyError, err)
+		return
+	}
+	if matched, _ := result.(bool); !matched {
+		return
+	}
+
+	r.mutex.Lock()
+	if r.fired.Contains(env.Mint) || (r.cooldown > 0 && time.Since(r.lastFire) < r.cooldown) {
+		r.mutex.Unlock()
+		return
+	}
+	r.matches++
+	r.lastFire = time.Now()
+	r.fired.Add(env.Mint)
+	r.mutex.Unlock()
+
+	label := env.Mint
+	if env.Symbol != "" {
+		label = fmt.Sprintf("%s (%s) %s", env.Name, env.Symbol, env.Mint)
+	}
+	raiseAlertTo(OperationalAlert{
+		Kind:    alertKindRule,
+		Firing:  true,
+		Message: fmt.Sprintf("Alert rule %s matched the %s of %s", r.config.Name, env.Type, label),
+		At:      time.Now().UTC(),
+		Rule:    r.config.Name,
+		Mint:    env.Mint,
+	}, r.config.Sinks)
+}
+
+// alertingSinkNames returns the names of the registered sinks that take alerts
+func alertingSinkNames() []string {
+	eventSinksMutex.RLock()
+	defer eventSinksMutex.RUnlock()
+
+	var names []string
+	for _, sink := range eventSinks {
+		if _, ok := sink.(alertingSink); ok {
+			names = append(names, sink.Name())
+		}
+	}
+	return names
+}
+
+// HandleListAlertRules returns the alert rules with their match counts
+func HandleListAlertRules(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, AlertRules.List())
+}
+
+// HandleCreateAlertRule registers an alert rule from the JSON request body
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with an AlertRuleConfig body
+func HandleCreateAlertRule(w http.ResponseWriter, r *http.Request) {
+	var config AlertRuleConfig
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	registered, err := AlertRules.Add(config)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, registered)
+}
+
+// HandleDeleteAlertRule unregisters the alert rule with the given ID
+func HandleDeleteAlertRule(w http.ResponseWriter, r *http.Request) {
+	if !AlertRules.Remove(mux.Vars(r)["id"]) {
+		writeError(w, http.StatusNotFound, "alert rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WebSocketAlertSink sends every alert, operational or raised by a rule, to the
+// WebSocket clients connected with ?alerts=true
+type WebSocketAlertSink struct {
+	mutex   sync.RWMutex
+	clients map[*Client]struct{}
+}
+
+// WebSocketAlerts is the WebSocket alert channel
+var WebSocketAlerts = &WebSocketAlertSink{clients: make(map[*Client]struct{})}
+
+// Name identifies the sink in logs and in the sinks of alert rules
+func (s *WebSocketAlertSink) Name() string {
+	return "websocket"
+}
+
+// Publish ignores events; the channel only carries alerts
+func (s *WebSocketAlertSink) Publish(event Event) {}
+
+// Alert sends the alert to every connected alert client
+func (s *WebSocketAlertSink) Alert(alert OperationalAlert) {
+	message, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for client := range s.clients {
+		goSafe("alert writer", func() {
+			client.Mutex.Lock()
+			defer client.Mutex.Unlock()
+			if err := client.send(message); err != nil {
+				slog.Warn("Failed to send alert to client", logKeyClientID, client.Connection.RemoteAddr().String(), logKeyError, err)
+			}
+		})
+	}
+}
+
+// stream sends alerts to a WebSocket client until it disconnects
+//
+// Returns:
+//   - string: Why the connection ended, for the audit log
+func (s *WebSocketAlertSink) stream(client *Client) string {
+	logger := clientLogger(client.Connection.RemoteAddr().String())
+	logger.Info("Streaming alerts")
+
+	s.mutex.Lock()
+	s.clients[client] = struct{}{}
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, client)
+		s.mutex.Unlock()
+	}()
+
+	reason := disconnectReason(client.readLoop())
+	logger.Info("Client disconnected", "reason", reason)
+	return reason
+}
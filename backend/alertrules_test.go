@@ -0,0 +1,247 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// testAlertSink records the alerts raised to it
+type testAlertSink struct {
+	mutex  sync.Mutex
+	alerts []OperationalAlert
+}
+
+// testAlerts is the alerting sink the rules of the tests raise to, registered once
+var (
+	testAlerts         = &testAlertSink{}
+	registerTestAlerts sync.Once
+)
+
+// Name identifies the sink in the sinks of the rules of the tests
+func (s *testAlertSink) Name() string {
+	return "test_alerts"
+}
+
+// Publish ignores events
+func (s *testAlertSink) Publish(event Event) {}
+
+// Alert records the alert
+func (s *testAlertSink) Alert(alert OperationalAlert) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.alerts = append(s.alerts, alert)
+}
+
+// raised returns the alerts recorded for a rule
+func (s *testAlertSink) raised(rule string) []OperationalAlert {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var alerts []OperationalAlert
+	for _, alert := range s.alerts {
+		if alert.Rule == rule {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// forget drops the alerts recorded for a rule
+func (s *testAlertSink) forget(rule string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.alerts = slices.DeleteFunc(s.alerts, func(alert OperationalAlert) bool { return alert.Rule == rule })
+}
+
+// compileTestAlertRule compiles a rule raising to testAlerts, named after the
+// test, forgetting the alerts of the runs before
+func compileTestAlertRule(t *testing.T, expression, cooldown string) *alertRule {
+	registerTestAlerts.Do(func() { RegisterSink(testAlerts) })
+	testAlerts.forget(t.Name())
+	rule, err := compileAlertRule(AlertRuleConfig{Name: t.Name(), Expression: expression, Sinks: []string{testAlerts.Name()}, Cooldown: cooldown})
+	if err != nil {
+		t.Fatalf("failed to compile %q: %v", expression, err)
+	}
+	return rule
+}
+
+// TestCompileAlertRule checks that only named rules with a boolean expression
+// over the known fields, alerting sinks and a valid cooldown compile
+func TestCompileAlertRule(t *testing.T) {
+	registerTestAlerts.Do(func() { RegisterSink(testAlerts) })
+	sinks := []string{testAlerts.Name()}
+
+	tests := []struct {
+		name   string
+		config AlertRuleConfig
+		err    string // Expected in the error, empty when the rule compiles
+	}{
+		{name: "matches and comparison", config: AlertRuleConfig{Name: "ai", Expression: `symbol matches "AI" && dev_buy_sol > 2`, Sinks: sinks}},
+		{name: "list field", config: AlertRuleConfig{Name: "risky", Expression: `"serial_creator" in risk_flags`, Sinks: sinks, Cooldown: "1m"}},
+		{name: "every type", config: AlertRuleConfig{Name: "all", Expression: `true`, Sinks: sinks}},
+		{name: "missing name", config: AlertRuleConfig{Name: " ", Expression: `true`, Sinks: sinks}, err: "name is required"},
+		{name: "syntax error", config: AlertRuleConfig{Name: "bad", Expression: `symbol ==`, Sinks: sinks}, err: "invalid expression"},
+		{name: "unknown field", config: AlertRuleConfig{Name: "bad", Expression: `holders > 10`, Sinks: sinks}, err: "invalid expression"},
+		{name: "not boolean", config: AlertRuleConfig{Name: "bad", Expression: `sol_amount * 2`, Sinks: sinks}, err: "invalid expression"},
+		{name: "no sink", config: AlertRuleConfig{Name: "bad", Expression: `true`}, err: "at least one sink is required"},
+		{name: "unknown sink", config: AlertRuleConfig{Name: "bad", Expression: `true`, Sinks: []string{"pager"}}, err: `unknown sink "pager"`},
+		{name: "invalid cooldown", config: AlertRuleConfig{Name: "bad", Expression: `true`, Sinks: sinks, Cooldown: "soon"}, err: "invalid cooldown"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, err := compileAlertRule(test.config)
+			switch {
+			case test.err == "" && err != nil:
+				t.Fatalf("failed to compile: %v", err)
+			case test.err == "" && rule.program == nil:
+				t.Fatal("compiled without a program")
+			case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+				t.Fatalf("got error %v, expected one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+// TestNewAlertRuleEnv checks the fields each event type fills in, with the
+// token fields of a tracked token and the enrichments of the event
+func TestNewAlertRuleEnv(t *testing.T) {
+	mint := solana.NewWallet().PublicKey()
+	creator := solana.NewWallet().PublicKey()
+	trader := solana.NewWallet().PublicKey()
+	const signature, slot = "test-creation-signature", 42
+
+	Tokens.RecordCreation(CreateEvent{Name: "Test AI", Symbol: "TAI", Uri: "https://ipfs.io/ipfs/test", Mint: mint.String()}, "", creator.String(), signature, slot)
+	defer Tokens.Remove(mint.String())
+
+	create := &PipelineEvent{Signature: signature, Slot: slot, Decoded: &pumpstream.CreateEvent{Mint: mint, User: creator}}
+	env, ok := newAlertRuleEnv(create)
+	if !ok {
+		t.Fatal("create event has no env")
+	}
+	if env.Type != string(EventCreate) || env.Mint != mint.String() || env.Signature != signature || env.Slot != slot {
+		t.Fatalf("create env %+v, expected the event fields", env)
+	}
+	if env.Name != "Test AI" || env.Symbol != "TAI" || env.URI != "https://ipfs.io/ipfs/test" || env.Creator != creator.String() || env.CreatorLaunches != 1 {
+		t.Fatalf("create env %+v, expected the token fields", env)
+	}
+	if env.IsBuy || env.IsDevBuy || env.DevBuySOL != 0 || env.Graduated {
+		t.Fatalf("create env %+v, expected no trade or graduation", env)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		trade     pumpstream.TradeEvent
+		devBuySOL float64 // Zero when the trade is not the dev buy
+	}{
+		{name: "dev buy", signature: signature, trade: pumpstream.TradeEvent{Mint: mint, SolAmount: 2_500_000_000, TokenAmount: 1000, IsBuy: true, User: creator}, devBuySOL: 2.5},
+		{name: "later buy of the creator", signature: "test-later-signature", trade: pumpstream.TradeEvent{Mint: mint, SolAmount: 2_500_000_000, TokenAmount: 1000, IsBuy: true, User: creator}},
+		{name: "buy of another trader", signature: signature, trade: pumpstream.TradeEvent{Mint: mint, SolAmount: 2_500_000_000, TokenAmount: 1000, IsBuy: true, User: trader}},
+		{name: "sell of the creator", signature: signature, trade: pumpstream.TradeEvent{Mint: mint, SolAmount: 2_500_000_000, TokenAmount: 1000, User: creator}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, ok := newAlertRuleEnv(&PipelineEvent{Signature: test.signature, Slot: slot, Decoded: &test.trade})
+			if !ok {
+				t.Fatal("trade event has no env")
+			}
+			if env.Type != string(EventTrade) || env.IsBuy != test.trade.IsBuy || env.SolAmount != 2.5 || env.TokenAmount != 1000 || env.Trader != test.trade.User.String() {
+				t.Fatalf("trade env %+v, expected the trade fields", env)
+			}
+			if env.IsDevBuy != (test.devBuySOL != 0) || env.DevBuySOL != test.devBuySOL {
+				t.Fatalf("is_dev_buy %v and dev_buy_sol %v, expected dev_buy_sol %v", env.IsDevBuy, env.DevBuySOL, test.devBuySOL)
+			}
+		})
+	}
+
+	Tokens.RecordCompletion(mint.String(), MigrationStatus{Complete: true})
+	complete := &PipelineEvent{
+		Signature: "test-complete-signature",
+		Decoded:   &pumpstream.CompleteEvent{Mint: mint, User: trader},
+		Market:    &MarketData{LiquidityUSD: 12000, Volume24hUSD: 3400},
+		Holders:   &HolderConcentration{TopShare: 0.35},
+		Funding:   &FundingSource{Kind: "cex", Name: "Binance"},
+	}
+	env, ok = newAlertRuleEnv(complete)
+	if !ok {
+		t.Fatal("complete event has no env")
+	}
+	if env.Type != string(EventComplete) || !env.Graduated || env.Trader != "" {
+		t.Fatalf("complete env %+v, expected a graduated token without trade fields", env)
+	}
+	if !env.HasMarketData || env.LiquidityUSD != 12000 || env.Volume24hUSD != 3400 || env.TopHoldersShare != 0.35 || env.FundingSource != "cex" || env.FundingName != "Binance" {
+		t.Fatalf("complete env %+v, expected the enrichment fields", env)
+	}
+
+	untracked := &PipelineEvent{Decoded: &pumpstream.CreateEvent{Mint: solana.NewWallet().PublicKey()}}
+	if env, ok := newAlertRuleEnv(untracked); !ok || env.Symbol != "" || env.Creator != "" || env.HasMarketData {
+		t.Fatalf("untracked env %+v, expected no token or market fields", env)
+	}
+	if _, ok := newAlertRuleEnv(&PipelineEvent{Log: "Program log: Instruction: Buy"}); ok {
+		t.Fatal("undecoded event has an env")
+	}
+}
+
+// TestAlertRuleEvaluate checks that a rule raises once per mint, not within its
+// cooldown, and counts the evaluations that fail
+func TestAlertRuleEvaluate(t *testing.T) {
+	t.Run("once per mint", func(t *testing.T) {
+		rule := compileTestAlertRule(t, `type == "trade" && sol_amount > 1`, "")
+		for _, env := range []alertRuleEnv{
+			{Type: "trade", Mint: "mint-a", SolAmount: 2},
+			{Type: "trade", Mint: "mint-a", SolAmount: 3}, // Already fired for mint-a
+			{Type: "trade", Mint: "mint-b", SolAmount: 0.5},
+			{Type: "create", Mint: "mint-b"},
+			{Type: "trade", Mint: "mint-b", SolAmount: 1.5},
+		} {
+			rule.evaluate(env)
+		}
+
+		alerts := testAlerts.raised(t.Name())
+		if rule.matches != 2 || len(alerts) != 2 {
+			t.Fatalf("%d matches and %d alerts, expected 2", rule.matches, len(alerts))
+		}
+		if alerts[0].Mint != "mint-a" || alerts[1].Mint != "mint-b" || alerts[0].Kind != alertKindRule || !alerts[0].Firing {
+			t.Fatalf("alerts %+v, expected firing rule alerts for mint-a then mint-b", alerts)
+		}
+		if rule.errors != 0 {
+			t.Fatalf("%d errors, expected none", rule.errors)
+		}
+	})
+
+	t.Run("cooldown", func(t *testing.T) {
+		rule := compileTestAlertRule(t, `true`, "1h")
+		rule.evaluate(alertRuleEnv{Type: "create", Mint: "mint-a"})
+		rule.evaluate(alertRuleEnv{Type: "create", Mint: "mint-b"})
+		if rule.matches != 1 || len(testAlerts.raised(t.Name())) != 1 {
+			t.Fatalf("%d matches, expected 1 within the cooldown", rule.matches)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		// Indexing past the risk flags fails at run time, not at compilation
+		rule := compileTestAlertRule(t, `risk_flags[3] == "no_metadata"`, "")
+		rule.evaluate(alertRuleEnv{Type: "create", Mint: "mint-a"})
+		rule.evaluate(alertRuleEnv{Type: "create", Mint: "mint-b", RiskFlags: []string{}})
+		rule.evaluate(alertRuleEnv{Type: "create", Mint: "mint-c", RiskFlags: []string{"a", "b", "c", "no_metadata"}})
+
+		if rule.errors != 2 || rule.matches != 1 {
+			t.Fatalf("%d errors and %d matches, expected 2 errors and 1 match", rule.errors, rule.matches)
+		}
+		AlertRules.mutex.Lock()
+		AlertRules.rules["test-errors"] = rule
+		AlertRules.mutex.Unlock()
+		defer AlertRules.Remove("test-errors")
+		for _, info := range AlertRules.List() {
+			if info.Name == t.Name() && (info.Errors != 2 || info.Matches != 1) {
+				t.Fatalf("listed %+v, expected 2 errors and 1 match", info)
+			}
+		}
+	})
+}
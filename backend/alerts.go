@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with how long the feed may go without a token
+	// creation before a stale feed alert is raised, e.g. 10m
+	// Stale feed alerting is disabled when it is unset
+	staleFeedAfterEnv = "STALE_FEED_AFTER"
+
+	// Environment variable restricting alerts to market hours, as HH:MM-HH:MM,
+	// e.g. 13:30-21:00; a window ending before it starts spans midnight
+	// Alerts are raised at any time when it is unset
+	staleFeedHoursEnv = "STALE_FEED_HOURS"
+
+	// Environment variable with the IANA time zone of STALE_FEED_HOURS,
+	// e.g. America/New_York; UTC when unset
+	staleFeedTimezoneEnv = "STALE_FEED_TIMEZONE"
+
+	// Longest interval between two checks for a stale feed
+	staleFeedMaxCheckInterval = time.Minute
+
+	// Kinds of operational alert
+	alertKindStaleFeed = "stale_feed"
+)
+
+// OperationalAlert reports a problem with the feed itself rather than an on-chain event
+// Every alert is raised once when it starts firing and once when it resolves;
+// alerts of kind rule report a match of a user-defined alert rule and never resolve.
+type OperationalAlert struct {
+	Kind    string    `json:"kind"`           // What is wrong, e.g. stale_feed, or rule
+	Firing  bool      `json:"firing"`         // True when the problem started, false when it resolved
+	Message string    `json:"message"`        // Human readable description
+	At      time.Time `json:"at"`             // Time the alert was raised
+	Rule    string    `json:"rule,omitempty"` // Name of the alert rule that matched
+	Mint    string    `json:"mint,omitempty"` // Token the alert rule matched on
+}
+
+// alertingSink is implemented by sinks that can post operational alerts, such
+// as chat integrations; each sends alerts only to the targets that opted in
+type alertingSink interface {
+	Alert(alert OperationalAlert)
+}
+
+// raiseAlert logs an operational alert and hands it to every alerting sink
+func raiseAlert(alert OperationalAlert) {
+	raiseAlertTo(alert, nil)
+}
+
+// raiseAlertTo logs an alert and hands it to the named alerting sinks
+//
+// Parameters:
+//   - alert: The alert
+//   - sinks: Names of the sinks to alert, every alerting sink when empty
+func raiseAlertTo(alert OperationalAlert, sinks []string) {
+	if alert.Firing {
+		slog.Warn("Alert firing", "kind", alert.Kind, "message", alert.Message)
+	} else {
+		slog.Info("Alert resolved", "kind", alert.Kind, "message", alert.Message)
+	}
+
+	eventSinksMutex.RLock()
+	defer eventSinksMutex.RUnlock()
+
+	for _, sink := range eventSinks {
+		if len(sinks) > 0 && !slices.Contains(sinks, sink.Name()) {
+			continue
+		}
+		if alerting, ok := sink.(alertingSink); ok {
+			alerting.Alert(alert)
+		}
+	}
+}
+
+// marketHours is a daily time window in a given location
+type marketHours struct {
+	start    time.Duration // Offset of the start from midnight
+	end      time.Duration // Offset of the end from midnight
+	location *time.Location
+}
+
+// contains reports whether the time falls inside the window
+func (h *marketHours) contains(now time.Time) bool {
+	if h == nil {
+		return true
+	}
+
+	local := now.In(h.location)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if h.start <= h.end {
+		return offset >= h.start && offset < h.end
+	}
+	return offset >= h.start || offset < h.end
+}
+
+// parseMarketHours parses a HH:MM-HH:MM window
+func parseMarketHours(window string, location *time.Location) (*marketHours, error) {
+	from, to, found := strings.Cut(window, "-")
+	if !found {
+		return nil, fmt.Errorf("invalid %s %q, expected HH:MM-HH:MM", staleFeedHoursEnv, window)
+	}
+
+	hours := &marketHours{location: location}
+	for _, bound := range []struct {
+		text   string
+		offset *time.Duration
+	}{{from, &hours.start}, {to, &hours.end}} {
+		parsed, err := time.Parse("15:04", strings.TrimSpace(bound.text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q, expected HH:MM-HH:MM", staleFeedHoursEnv, window)
+		}
+		*bound.offset = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	}
+	return hours, nil
+}
+
+// setupStaleFeedAlerts starts watching for a stale feed when STALE_FEED_AFTER is set
+func setupStaleFeedAlerts() error {
+	value := os.Getenv(staleFeedAfterEnv)
+	if value == "" {
+		return nil
+	}
+
+	after, err := time.ParseDuration(value)
+	if err != nil || after <= 0 {
+		return fmt.Errorf("invalid %s %q", staleFeedAfterEnv, value)
+	}
+
+	var hours *marketHours
+	if window := os.Getenv(staleFeedHoursEnv); window != "" {
+		location, err := time.LoadLocation(envOrDefault(staleFeedTimezoneEnv, "UTC"))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", staleFeedTimezoneEnv, err)
+		}
+		if hours, err = parseMarketHours(window, location); err != nil {
+			return err
+		}
+	}
+
+	go watchStaleFeed(after, hours)
+
+	if hours != nil {
+		slog.Info("Alerting on a stale feed", "after", after, "hours", os.Getenv(staleFeedHoursEnv), "location", hours.location.String())
+	} else {
+		slog.Info("Alerting on a stale feed", "after", after)
+	}
+	return nil
+}
+
+// watchStaleFeed raises an alert when no creation was observed for the given
+// duration inside market hours, and resolves it with the next creation
+// The time before the first creation counts from startup
+func watchStaleFeed(after time.Duration, hours *marketHours) {
+	ticker := time.NewTicker(min(after/4, staleFeedMaxCheckInterval))
+	defer ticker.Stop()
+
+	started := time.Now()
+	firing := false
+	for now := range ticker.C {
+		lastLaunch := FeedStats.Snapshot().LastLaunchAt
+		since := started
+		if lastLaunch.After(since) {
+			since = lastLaunch
+		}
+		silence := now.Sub(since)
+
+		switch {
+		case !firing && silence >= after && hours.contains(now):
+			firing = true
+			raiseAlert(OperationalAlert{
+				Kind:    alertKindStaleFeed,
+				Firing:  true,
+				Message: fmt.Sprintf("No token creation observed for %s", silence.Round(time.Second)),
+				At:      now.UTC(),
+			})
+		case firing && silence < after:
+			firing = false
+			raiseAlert(OperationalAlert{
+				Kind:    alertKindStaleFeed,
+				Message: "Token creations are observed again",
+				At:      now.UTC(),
+			})
+		}
+	}
+}
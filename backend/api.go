@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// REST endpoint returning everything known about a mint
+	tokenDetailEndpoint = "/api/tokens/{mint}"
+
+	// REST endpoint searching tokens by name or symbol
+	searchEndpoint = "/api/search"
+
+	// REST endpoint returning aggregate feed counters
+	statsEndpoint = "/api/stats"
+
+	// REST endpoint returning the latest creations held in memory
+	snapshotEndpoint = "/api/snapshot"
+
+	// Number of creations in a snapshot when the client does not pass a limit
+	defaultSnapshotLimit = 50
+
+	// Page size used when the client does not pass a limit
+	defaultPageLimit = 20
+
+	// Largest page size a client may request
+	maxPageLimit = 100
+)
+
+// errorResponse is the JSON body returned for failed API requests
+type errorResponse struct {
+	Error string `json:"error"` // Human readable error message
+}
+
+// searchResponse is the JSON body returned by the search endpoint
+type searchResponse struct {
+	Query   string        `json:"query"`   // Query as passed by the client
+	Total   int           `json:"total"`   // Total number of matches
+	Offset  int           `json:"offset"`  // Number of matches skipped
+	Limit   int           `json:"limit"`   // Maximum number of results in this page
+	Results []TokenRecord `json:"results"` // Matching tokens, best match first
+}
+
+// snapshotResponse is the JSON body returned by the snapshot endpoint
+type snapshotResponse struct {
+	Creations   []snapshotCreation `json:"creations"`    // Latest creations, newest first
+	GeneratedAt time.Time          `json:"generated_at"` // Time the snapshot was taken
+}
+
+// snapshotCreation is a creation in the format of WebSocket messages, with the
+// time it was observed
+type snapshotCreation struct {
+	Name      string    `json:"name"`       // Token name
+	Symbol    string    `json:"symbol"`     // Token symbol
+	Uri       string    `json:"uri"`        // Token metadata URI
+	Mint      string    `json:"mint"`       // Token mint address
+	CreatedAt time.Time `json:"created_at"` // Time the creation was observed
+}
+
+// apiRoutes lists every REST endpoint together with the metadata used to
+// generate the OpenAPI document, so the spec cannot drift from the router
+var apiRoutes = []apiRoute{
+	{
+		Method:   http.MethodGet,
+		Path:     tokenDetailEndpoint,
+		Summary:  "Everything known about a mint: creation event, curve state and migration status",
+		Handler:  HandleTokenDetail,
+		Params:   []apiParam{{Name: "mint", In: "path", Description: "Token mint address", Required: true}},
+		Response: TokenRecord{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    tokenImageEndpoint,
+		Summary: "Image of a token as a WebP thumbnail; requires IMAGE_PROXY",
+		Handler: HandleTokenImage,
+		Params: []apiParam{
+			{Name: "mint", In: "path", Description: "Token mint address", Required: true},
+			{Name: "size", In: "query", Description: "Width and height in pixels the thumbnail fits in, 64 or 256; 256 when omitted", Type: "integer"},
+		},
+		Response: nil,
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    searchEndpoint,
+		Summary: "Fuzzy search tokens by name, symbol or metadata description",
+		Handler: HandleSearch,
+		Params: []apiParam{
+			{Name: "q", In: "query", Description: "Text matched against names and symbols", Required: true},
+			{Name: "limit", In: "query", Description: "Maximum number of results", Type: "integer"},
+			{Name: "offset", In: "query", Description: "Number of results to skip", Type: "integer"},
+		},
+		Response: searchResponse{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     statsEndpoint,
+		Summary:  "Aggregate feed counters and upstream health",
+		Handler:  HandleStats,
+		Response: StatsSnapshot{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    snapshotEndpoint,
+		Summary: "Latest creations held in memory, to fill a list before opening the WebSocket",
+		Handler: HandleSnapshot,
+		Params: []apiParam{
+			{Name: "limit", In: "query", Description: "Maximum number of creations", Type: "integer"},
+		},
+		Response: snapshotResponse{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    ringEventsEndpoint,
+		Summary: "Buffered events after a cursor, for resuming a feed; requires RING_PATH",
+		Handler: HandleRingEvents,
+		Params: []apiParam{
+			{Name: "after", In: "query", Description: "Cursor of the last event already seen, 0 for the oldest buffered event", Type: "integer"},
+			{Name: "limit", In: "query", Description: "Maximum number of events", Type: "integer"},
+		},
+		Response: ringPage{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    exportEndpoint,
+		Summary: "Stored creations as a CSV download; requires a storage backend",
+		Handler: HandleExport,
+		Params: []apiParam{
+			{Name: "from", In: "query", Description: "Earliest creation time, as YYYY-MM-DD or RFC 3339"},
+			{Name: "to", In: "query", Description: "Creation time to stop before, as YYYY-MM-DD or RFC 3339"},
+			{Name: "format", In: "query", Description: "Export format; only csv is supported"},
+			{Name: "columns", In: "query", Description: "Comma separated columns: received_at, mint, name, symbol, uri, signature, slot, creator, bonding_curve, graduated"},
+		},
+		Response: nil,
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    aggregatesEndpoint,
+		Summary: "Launches, graduation rate and median dev buy of stored creations, bucketed by hour or day; requires a storage backend",
+		Handler: HandleAggregates,
+		Params: []apiParam{
+			{Name: "bucket", In: "query", Description: "Bucket width, hour (the default) or day"},
+			{Name: "from", In: "query", Description: "Start of the range, as YYYY-MM-DD or RFC 3339; a week before to by default"},
+			{Name: "to", In: "query", Description: "End of the range, as YYYY-MM-DD or RFC 3339; now by default"},
+			{Name: "program", In: "query", Description: "Program address; only the program the feed listens to is stored"},
+			{Name: "dev_buy", In: "query", Description: "Also compute the dev buys, scanning every stored trade of the range", Type: "boolean"},
+		},
+		Response: AggregateSeries{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     buildBuyEndpoint,
+		Summary:  "Build an unsigned buy transaction spending a SOL amount, quoted on the bonding curve; requires TRANSACTION_BUILDER",
+		Handler:  HandleBuildBuy,
+		Request:  TradeTransactionRequest{},
+		Response: TradeTransactionResponse{},
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     buildSellEndpoint,
+		Summary:  "Build an unsigned sell transaction of a token amount, quoted on the bonding curve; requires TRANSACTION_BUILDER",
+		Handler:  HandleBuildSell,
+		Request:  TradeTransactionRequest{},
+		Response: TradeTransactionResponse{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     priorityFeesEndpoint,
+		Summary:  "Recommended priority fee levels for pump.fun trades, from recently landed transactions; requires PRIORITY_FEE_INTERVAL",
+		Handler:  HandlePriorityFees,
+		Response: PriorityFeeEstimate{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     portfoliosEndpoint,
+		Summary:  "Accounts whose portfolios are tracked; requires PORTFOLIO_WALLETS, the wallet or paper trading",
+		Handler:  HandleListPortfolios,
+		Response: portfoliosResponse{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     portfolioEndpoint,
+		Summary:  "Holdings and realized and unrealized P&L of a tracked account, valued on the latest curve states; live updates with /connect?portfolio=",
+		Handler:  HandleGetPortfolio,
+		Params:   []apiParam{{Name: "account", In: "path", Description: "Wallet address, or paper for the paper trader", Required: true}},
+		Response: Portfolio{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    digestsEndpoint,
+		Summary: "Latest digests of launches, graduations, top volume tokens and alerts, newest first; requires DIGESTS",
+		Handler: HandleDigests,
+		Params: []apiParam{
+			{Name: "period", In: "query", Description: "Only digests of this period, e.g. hourly"},
+			{Name: "limit", In: "query", Description: "Maximum number of digests", Type: "integer"},
+		},
+		Response: []Digest{},
+	},
+	{
+		Method:   http.MethodGet,
+		Path:     schemasEndpoint,
+		Summary:  "Published JSON Schemas of the event envelope and of every event type, with their versions",
+		Handler:  HandleListSchemas,
+		Response: schemaIndex{},
+	},
+	{
+		Method:  http.MethodGet,
+		Path:    schemaEndpoint,
+		Summary: "JSON Schema of an event type, or of the envelope with the name event, to validate payloads and generate types",
+		Handler: HandleGetSchema,
+		Params: []apiParam{
+			{Name: "name", In: "path", Description: "Event type, or event for the envelope", Required: true},
+			{Name: "version", In: "query", Description: "Version of the schema; the latest by default", Type: "integer"},
+		},
+		Response: nil,
+	},
+	{
+		Method:   http.MethodPost,
+		Path:     pushDevicesEndpoint,
+		Summary:  "Register a mobile device token for push notifications; requires the FCM_APP_TOKEN bearer token",
+		Handler:  HandleRegisterPushDevice,
+		Request:  PushDevice{},
+		Response: PushDevice{},
+	},
+	{
+		Method:   http.MethodDelete,
+		Path:     pushDeviceEndpoint,
+		Summary:  "Unregister a mobile device token; requires the FCM_APP_TOKEN bearer token",
+		Handler:  HandleUnregisterPushDevice,
+		Params:   []apiParam{{Name: "token", In: "path", Description: "FCM registration token", Required: true}},
+		Response: nil,
+	},
+}
+
+// registerAPIRoutes registers all REST endpoints on the given router
+func registerAPIRoutes(router *mux.Router) {
+	for _, route := range apiRoutes {
+		router.HandleFunc(route.Path, route.Handler).Methods(route.Method)
+	}
+
+	// Documentation endpoints are not part of the spec themselves
+	router.HandleFunc(openAPIEndpoint, HandleOpenAPI).Methods(http.MethodGet)
+	router.HandleFunc(apiDocsEndpoint, HandleAPIDocs).Methods(http.MethodGet)
+}
+
+// HandleTokenDetail returns the stored record for a single mint
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the mint as a path variable
+func HandleTokenDetail(w http.ResponseWriter, r *http.Request) {
+	mint := mux.Vars(r)["mint"]
+
+	// Reject anything that is not a valid public key before looking it up
+	if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid mint address")
+		return
+	}
+
+	record, found := Tokens.Get(mint)
+	if !found && Persistence != nil {
+		// Fall back to the database for tokens evicted from memory
+		stored, ok, err := Persistence.GetToken(mint)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to look up token")
+			return
+		}
+		record, found = stored, ok
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// HandleSearch returns a ranked, paginated list of tokens matching the q parameter
+// Matching is fuzzy and covers metadata descriptions through the full-text index
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with q, and optionally limit and offset, query parameters
+func HandleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "missing q parameter")
+		return
+	}
+
+	limit, err := intQueryParam(r, "limit", defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageLimit))
+		return
+	}
+
+	offset, err := intQueryParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+		return
+	}
+
+	var results []TokenRecord
+	var total int
+	if FullText != nil {
+		results, total, err = FullText.Search(query, offset, limit)
+		if err != nil {
+			slog.Warn("Full-text search failed", "query", query, logKeyError, err)
+			writeError(w, http.StatusInternalServerError, "search failed")
+			return
+		}
+	} else {
+		results, total = Tokens.Search(query, offset, limit)
+	}
+	writeJSON(w, http.StatusOK, searchResponse{
+		Query:   query,
+		Total:   total,
+		Offset:  offset,
+		Limit:   limit,
+		Results: results,
+	})
+}
+
+// HandleStats returns aggregate counters about the feed and upstream health
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, FeedStats.Snapshot())
+}
+
+// HandleSnapshot returns the most recent creations of the in-memory store
+// It needs no storage backend, so stateless frontends can always hydrate from it
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with an optional limit query parameter
+func HandleSnapshot(w http.ResponseWriter, r *http.Request) {
+	limit, err := intQueryParam(r, "limit", defaultSnapshotLimit)
+	if err != nil || limit < 1 || limit > maxTrackedTokens {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxTrackedTokens))
+		return
+	}
+
+	records := Tokens.Recent(limit)
+	creations := make([]snapshotCreation, 0, len(records))
+	for _, record := range records {
+		creations = append(creations, snapshotCreation{
+			Name:      record.Creation.Name,
+			Symbol:    record.Creation.Symbol,
+			Uri:       record.Creation.Uri,
+			Mint:      record.Creation.Mint,
+			CreatedAt: record.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, snapshotResponse{Creations: creations, GeneratedAt: time.Now().UTC()})
+}
+
+// intQueryParam parses an integer query parameter, returning fallback if it is absent
+func intQueryParam(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// writeJSON writes the given value as a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Warn("Failed to write JSON response", logKeyError, err)
+	}
+}
+
+// writeError writes a JSON error response with the given status code
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the archive location, s3://bucket/prefix or gs://bucket/prefix
+	// The archiver is disabled when it is unset
+	archiveURLEnv = "ARCHIVE_URL"
+
+	// Environment variable overriding the object storage endpoint, e.g. for MinIO or R2
+	archiveEndpointEnv = "ARCHIVE_ENDPOINT"
+
+	// Environment variable with the bucket region
+	archiveRegionEnv = "ARCHIVE_REGION"
+
+	// Environment variables with static access keys; GCS requires HMAC keys
+	// S3 falls back to the standard AWS credential chain when they are unset
+	archiveAccessKeyEnv = "ARCHIVE_ACCESS_KEY"
+	archiveSecretKeyEnv = "ARCHIVE_SECRET_KEY"
+
+	// Environment variable with the local directory files are written to before upload
+	archiveDirEnv = "ARCHIVE_DIR"
+
+	// Environment variable overriding how often the current file is rotated, e.g. 15m
+	archiveRotateEnv = "ARCHIVE_ROTATE_INTERVAL"
+
+	// Rotation interval used when ARCHIVE_ROTATE_INTERVAL is unset
+	defaultArchiveRotateInterval = time.Hour
+
+	// Endpoints used for each scheme when ARCHIVE_ENDPOINT is unset
+	s3Endpoint  = "s3.amazonaws.com"
+	gcsEndpoint = "storage.googleapis.com"
+
+	// Suffix of finished files waiting for upload, and of the file being written
+	archiveFileSuffix    = ".ndjson.gz"
+	archivePartialSuffix = ".part"
+
+	// Number of events buffered before new events are dropped
+	archiveQueueSize = 10000
+
+	// Timeout for uploading a single file
+	archiveUploadTimeout = 5 * time.Minute
+)
+
+// Archiver writes every event to gzipped NDJSON files that are rotated
+// periodically and uploaded to S3 or GCS under date-partitioned keys
+// Files stay in the local directory until their upload succeeds, so uploads
+// that fail, or files left by a previous run, are retried on the next rotation
+type Archiver struct {
+	client   *minio.Client
+	bucket   string
+	prefix   string
+	dir      string
+	interval time.Duration
+
+	queue   chan Event
+	drains  chan chan struct{}
+	pending sync.WaitGroup
+
+	// Current file, nil until the first event after a rotation
+	file    *os.File
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+
+	uploadMutex sync.Mutex
+}
+
+// setupArchiver registers the archiver when ARCHIVE_URL is set
+func setupArchiver() error {
+	location := os.Getenv(archiveURLEnv)
+	if location == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("%s must look like s3://bucket/prefix or gs://bucket/prefix", archiveURLEnv)
+	}
+
+	interval, err := time.ParseDuration(envOrDefault(archiveRotateEnv, defaultArchiveRotateInterval.String()))
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("%s must be a positive duration", archiveRotateEnv)
+	}
+
+	accessKey, secretKey := os.Getenv(archiveAccessKeyEnv), os.Getenv(archiveSecretKeyEnv)
+	var endpoint string
+	var creds *credentials.Credentials
+	switch parsed.Scheme {
+	case "s3":
+		endpoint = s3Endpoint
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.Static{Value: credentials.Value{AccessKeyID: accessKey, SecretAccessKey: secretKey, SignerType: credentials.SignatureV4}},
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		})
+	case "gs":
+		if accessKey == "" || secretKey == "" {
+			return fmt.Errorf("%s and %s must be set to HMAC keys to archive to GCS", archiveAccessKeyEnv, archiveSecretKeyEnv)
+		}
+		endpoint = gcsEndpoint
+		creds = credentials.NewStaticV4(accessKey, secretKey, "")
+	default:
+		return fmt.Errorf("unsupported %s scheme %q, expected s3 or gs", archiveURLEnv, parsed.Scheme)
+	}
+
+	if override := os.Getenv(archiveEndpointEnv); override != "" {
+		endpoint = override
+	}
+	secure := true
+	if strings.HasPrefix(endpoint, "http://") {
+		secure = false
+	}
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: secure,
+		Region: os.Getenv(archiveRegionEnv),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create archive client: %w", err)
+	}
+
+	dir := envOrDefault(archiveDirEnv, filepath.Join(os.TempDir(), "nova-feed-archive"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	archiver := &Archiver{
+		client:   client,
+		bucket:   parsed.Host,
+		prefix:   strings.Trim(parsed.Path, "/"),
+		dir:      dir,
+		interval: interval,
+		queue:    make(chan Event, archiveQueueSize),
+		drains:   make(chan chan struct{}),
+	}
+	if err := archiver.recoverPartialFiles(); err != nil {
+		return err
+	}
+	go archiver.writeLoop()
+	go archiver.upload()
+	RegisterSink(archiver)
+
+	slog.Info("Archiving events", "location", location, "interval", interval, "spool_dir", dir)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (a *Archiver) Name() string {
+	return "archive"
+}
+
+// Publish queues the event for the writer, dropping it if the queue is full
+func (a *Archiver) Publish(event Event) {
+	a.pending.Add(1)
+	select {
+	case a.queue <- event:
+	default:
+		a.pending.Done()
+		slog.Warn("Archive queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// Drain waits until every queued event has been written, then rotates the
+// current file and uploads everything that is waiting
+func (a *Archiver) Drain() {
+	a.pending.Wait()
+
+	done := make(chan struct{})
+	a.drains <- done
+	<-done
+}
+
+// writeLoop appends queued events to the current file and rotates it on the interval
+// The gzip stream is flushed whenever the queue is drained, so a crash loses
+// at most the events still in the queue
+func (a *Archiver) writeLoop() {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-a.queue:
+			if err := a.write(event); err != nil {
+				slog.Error("Failed to archive event", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, err)
+			}
+			if len(a.queue) == 0 && a.gzip != nil {
+				if err := a.gzip.Flush(); err != nil {
+					slog.Error("Failed to flush archive file", logKeyError, err)
+				}
+			}
+			a.pending.Done()
+		case <-ticker.C:
+			a.rotate()
+			go a.upload()
+		case done := <-a.drains:
+			a.rotate()
+			a.upload()
+			close(done)
+		}
+	}
+}
+
+// write appends an event, opening a new file first if none is open
+func (a *Archiver) write(event Event) error {
+	if a.file == nil {
+		startedAt := time.Now().UTC()
+		name := fmt.Sprintf("events-%s-%d%s%s", startedAt.Format("20060102T150405Z"), os.Getpid(), archiveFileSuffix, archivePartialSuffix)
+
+		file, err := os.Create(filepath.Join(a.dir, name))
+		if err != nil {
+			return err
+		}
+		a.file = file
+		a.gzip = gzip.NewWriter(file)
+		a.encoder = json.NewEncoder(a.gzip)
+	}
+	return a.encoder.Encode(event)
+}
+
+// rotate closes the current file and marks it ready for upload
+func (a *Archiver) rotate() {
+	if a.file == nil {
+		return
+	}
+
+	if err := a.gzip.Close(); err != nil {
+		slog.Error("Failed to finish archive file", "path", a.file.Name(), logKeyError, err)
+	}
+	if err := a.file.Close(); err != nil {
+		slog.Error("Failed to close archive file", "path", a.file.Name(), logKeyError, err)
+	}
+	if err := os.Rename(a.file.Name(), strings.TrimSuffix(a.file.Name(), archivePartialSuffix)); err != nil {
+		slog.Error("Failed to rotate archive file", "path", a.file.Name(), logKeyError, err)
+	}
+	a.file, a.gzip, a.encoder = nil, nil, nil
+}
+
+// recoverPartialFiles marks files left open by a previous run ready for upload
+// Their gzip stream may lack a trailer, but everything up to the last flush decodes
+func (a *Archiver) recoverPartialFiles() error {
+	partial, err := filepath.Glob(filepath.Join(a.dir, "*"+archiveFileSuffix+archivePartialSuffix))
+	if err != nil {
+		return err
+	}
+	for _, name := range partial {
+		if err := os.Rename(name, strings.TrimSuffix(name, archivePartialSuffix)); err != nil {
+			return fmt.Errorf("failed to recover archive file %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// upload uploads every finished file in the directory, deleting each once stored
+func (a *Archiver) upload() {
+	a.uploadMutex.Lock()
+	defer a.uploadMutex.Unlock()
+
+	finished, err := filepath.Glob(filepath.Join(a.dir, "*"+archiveFileSuffix))
+	if err != nil {
+		slog.Error("Failed to list archive files", logKeyError, err)
+		return
+	}
+
+	for _, name := range finished {
+		key, err := a.objectKey(filepath.Base(name))
+		if err != nil {
+			slog.Warn("Skipping archive file", "path", name, logKeyError, err)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), archiveUploadTimeout)
+		_, err = a.client.FPutObject(ctx, a.bucket, key, name, minio.PutObjectOptions{
+			ContentType: "application/gzip",
+		})
+		cancel()
+
+		if err != nil {
+			slog.Warn("Failed to upload archive file, will retry", "path", name, "bucket", a.bucket, "key", key, logKeyError, err)
+			continue
+		}
+		if err := os.Remove(name); err != nil {
+			slog.Error("Failed to remove uploaded archive file", "path", name, logKeyError, err)
+		}
+		slog.Info("Archived file", "file", filepath.Base(name), "bucket", a.bucket, "key", key)
+	}
+}
+
+// objectKey returns the date-partitioned key of a file, based on the start
+// time encoded in its name, e.g. prefix/dt=2024-05-01/hour=13/events-....ndjson.gz
+func (a *Archiver) objectKey(name string) (string, error) {
+	stamp, _, found := strings.Cut(strings.TrimPrefix(name, "events-"), "-")
+	if !found {
+		return "", fmt.Errorf("unexpected file name")
+	}
+	startedAt, err := time.Parse("20060102T150405Z", stamp)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(a.prefix, "dt="+startedAt.Format(time.DateOnly), startedAt.Format("hour=15"), name), nil
+}
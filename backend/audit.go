@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the path of a file the connection audit log is
+	// appended to as newline-delimited JSON
+	auditLogPathEnv = "AUDIT_LOG_PATH"
+
+	// Environment variable storing the connection audit log in the connections
+	// table of the SQLite or PostgreSQL storage backend instead, when true
+	auditLogStorageEnv = "AUDIT_LOG_STORAGE"
+
+	// Admin endpoint querying the connection audit log
+	adminConnectionsEndpoint = "/admin/connections"
+
+	// Records returned by a query when no limit is given, and at most
+	defaultAuditQueryLimit = 100
+	maxAuditQueryLimit     = 10000
+)
+
+// ConnectionRecord describes one WebSocket connection once it has ended
+type ConnectionRecord struct {
+	ClientID       string    `json:"client_id"`            // Remote address of the connection
+	IP             string    `json:"ip"`                   // IP address of the client
+	UserAgent      string    `json:"user_agent,omitempty"` // User-Agent of the upgrade request
+	Tenant         string    `json:"tenant,omitempty"`     // Tenant of the client certificate, when mutual TLS is enabled
+	ConnectedAt    time.Time `json:"connected_at"`         // Time the connection was upgraded
+	DisconnectedAt time.Time `json:"disconnected_at"`      // Time the connection ended
+	Duration       float64   `json:"duration_seconds"`     // Seconds the connection lasted
+	MessagesSent   uint64    `json:"messages_sent"`        // Messages written to the client
+	BytesSent      uint64    `json:"bytes_sent"`           // Message bytes written to the client
+	Reason         string    `json:"reason"`               // Why the connection ended
+	Geo            GeoInfo   `json:"geo,omitzero"`         // Location of the IP address, when GeoIP is configured
+}
+
+// AuditQuery selects connection records; zero values leave a bound open
+type AuditQuery struct {
+	IP    string    // Only connections from this IP address
+	From  time.Time // Only connections that ended at or after this time
+	To    time.Time // Only connections that ended before this time
+	Limit int       // Maximum number of records returned, newest first
+}
+
+// matches reports whether a record falls inside the query
+func (q AuditQuery) matches(record ConnectionRecord) bool {
+	if q.IP != "" && record.IP != q.IP {
+		return false
+	}
+	if !q.From.IsZero() && record.DisconnectedAt.Before(q.From) {
+		return false
+	}
+	return q.To.IsZero() || record.DisconnectedAt.Before(q.To)
+}
+
+// connectionAuditLog stores connection records and answers queries over them
+type connectionAuditLog interface {
+	Record(record ConnectionRecord) error
+	Query(query AuditQuery) ([]ConnectionRecord, error)
+}
+
+// AuditLog records every WebSocket connection when configured, nil otherwise
+var AuditLog connectionAuditLog
+
+// setupAuditLog opens the connection audit log when AUDIT_LOG_PATH or
+// AUDIT_LOG_STORAGE is set; it must run after the storage backend is set up
+func setupAuditLog() error {
+	path := os.Getenv(auditLogPathEnv)
+	inStorage := envBool(auditLogStorageEnv)
+
+	switch {
+	case path != "" && inStorage:
+		return fmt.Errorf("only one of %s and %s can be set", auditLogPathEnv, auditLogStorageEnv)
+	case path != "":
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		AuditLog = &fileAuditLog{path: path, file: file}
+		slog.Info("Recording client connections", "path", path)
+	case inStorage:
+		var blocks *eventBlocks
+		switch store := Persistence.(type) {
+		case *SQLiteStore:
+			blocks = store.blocks
+		case *PostgresStore:
+			blocks = store.blocks
+		default:
+			return fmt.Errorf("%s requires the SQLite or PostgreSQL storage backend", auditLogStorageEnv)
+		}
+		AuditLog = &sqlAuditLog{db: blocks.db, placeholder: blocks.placeholder}
+		slog.Info("Recording client connections to the connections table", "storage", Persistence.Name())
+	}
+	return nil
+}
+
+// auditConnection records a connection that just ended
+//
+// Parameters:
+//   - client: The client, carrying the sent message counters
+//   - reason: Why the connection ended
+func auditConnection(client *Client, reason string) {
+	if AuditLog == nil {
+		return
+	}
+
+	now := time.Now().UTC()
+	record := ConnectionRecord{
+		ClientID:       client.Connection.RemoteAddr().String(),
+		IP:             client.ip,
+		UserAgent:      client.userAgent,
+		Tenant:         client.tenant,
+		ConnectedAt:    client.connectedAt,
+		DisconnectedAt: now,
+		Duration:       now.Sub(client.connectedAt).Seconds(),
+		MessagesSent:   client.messagesSent.Load(),
+		BytesSent:      client.bytesSent.Load(),
+		Reason:         reason,
+		Geo:            client.geo,
+	}
+	if err := AuditLog.Record(record); err != nil {
+		slog.Error("Failed to record connection in the audit log", logKeyClientID, record.ClientID, logKeyError, err)
+	}
+}
+
+// disconnectReason describes the error that ended a connection
+func disconnectReason(err error) string {
+	var closeErr *websocket.CloseError
+	switch {
+	case err == nil:
+		return "closed"
+	case errors.As(err, &closeErr) && closeErr.Code == websocket.CloseAbnormalClosure:
+		return "connection dropped"
+	case errors.As(err, &closeErr):
+		return fmt.Sprintf("closed by client (code %d)", closeErr.Code)
+	case errors.Is(err, net.ErrClosed):
+		return "closed by server"
+	default:
+		return err.Error()
+	}
+}
+
+// HandleListConnections returns the connection records matching the ip, from,
+// to and limit query parameters, newest first
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListConnections(w http.ResponseWriter, r *http.Request) {
+	if AuditLog == nil {
+		writeError(w, http.StatusNotFound, "connection audit log is disabled")
+		return
+	}
+
+	limit, err := intQueryParam(r, "limit", defaultAuditQueryLimit)
+	if err != nil || limit < 1 || limit > maxAuditQueryLimit {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditQueryLimit))
+		return
+	}
+
+	query := AuditQuery{IP: r.URL.Query().Get("ip"), Limit: limit}
+	if query.From, err = parseTimeBound(r.URL.Query().Get("from")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid from time")
+		return
+	}
+	if query.To, err = parseTimeBound(r.URL.Query().Get("to")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid to time")
+		return
+	}
+
+	records, err := AuditLog.Query(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// fileAuditLog appends connection records to a newline-delimited JSON file
+// Queries read the whole file, which is fine for investigations but not for
+// dashboards; use the storage backend for large logs
+type fileAuditLog struct {
+	path  string
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// Record appends a record as one line
+func (l *fileAuditLog) Record(record ConnectionRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query scans the file, keeping the newest matching records
+func (l *fileAuditLog) Query(query AuditQuery) ([]ConnectionRecord, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := []ConnectionRecord{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record ConnectionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // A line cut short by a crash
+		}
+		if !query.matches(record) {
+			continue
+		}
+		records = append(records, record)
+		if len(records) > query.Limit {
+			records = records[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Newest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// sqlAuditLog stores connection records in the connections table of the
+// storage backend database; times are stored as Unix milliseconds
+type sqlAuditLog struct {
+	db          *sql.DB
+	placeholder func(n int) string // Returns the nth bind parameter, e.g. ? or $1
+}
+
+// Record inserts a record
+func (l *sqlAuditLog) Record(record ConnectionRecord) error {
+	params := make([]string, 12)
+	for i := range params {
+		params[i] = l.placeholder(i + 1)
+	}
+
+	_, err := l.db.Exec(`INSERT INTO connections (client_id, ip, user_agent, connected_at, disconnected_at, messages_sent, bytes_sent, reason, country, asn, as_org, tenant)
+		VALUES (`+strings.Join(params, ", ")+`)`,
+		record.ClientID, record.IP, record.UserAgent, record.ConnectedAt.UnixMilli(), record.DisconnectedAt.UnixMilli(),
+		int64(record.MessagesSent), int64(record.BytesSent), record.Reason, record.Geo.Country, int64(record.Geo.ASN), record.Geo.ASOrg, record.Tenant)
+	return err
+}
+
+// Query selects the newest matching records
+func (l *sqlAuditLog) Query(query AuditQuery) ([]ConnectionRecord, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+" "+l.placeholder(len(args)))
+	}
+	if query.IP != "" {
+		where("ip =", query.IP)
+	}
+	if !query.From.IsZero() {
+		where("disconnected_at >=", query.From.UnixMilli())
+	}
+	if !query.To.IsZero() {
+		where("disconnected_at <", query.To.UnixMilli())
+	}
+
+	statement := `SELECT client_id, ip, user_agent, connected_at, disconnected_at, messages_sent, bytes_sent, reason, country, asn, as_org, tenant FROM connections`
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, query.Limit)
+	statement += " ORDER BY disconnected_at DESC, id DESC LIMIT " + l.placeholder(len(args))
+
+	rows, err := l.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []ConnectionRecord{}
+	for rows.Next() {
+		var record ConnectionRecord
+		var connectedAt, disconnectedAt, messagesSent, bytesSent, asn int64
+		if err := rows.Scan(&record.ClientID, &record.IP, &record.UserAgent, &connectedAt, &disconnectedAt, &messagesSent, &bytesSent, &record.Reason,
+			&record.Geo.Country, &asn, &record.Geo.ASOrg, &record.Tenant); err != nil {
+			return nil, err
+		}
+		record.ConnectedAt = time.UnixMilli(connectedAt).UTC()
+		record.DisconnectedAt = time.UnixMilli(disconnectedAt).UTC()
+		record.Duration = record.DisconnectedAt.Sub(record.ConnectedAt).Seconds()
+		record.MessagesSent = uint64(messagesSent)
+		record.BytesSent = uint64(bytesSent)
+		record.Geo.ASN = uint(asn)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the abuse score at which an address is banned,
+	// e.g. 20; addresses are never banned automatically when it is unset
+	// Every WebSocket connection scores 1, a rejected admin token 5 and a
+	// client message over the size limit 10
+	autoBanThresholdEnv = "AUTOBAN_THRESHOLD"
+
+	// Environment variable with the time after which half of a score is forgiven, e.g. 1m
+	autoBanHalfLifeEnv = "AUTOBAN_HALF_LIFE"
+
+	// Environment variable with the length of a first ban, e.g. 10m; every
+	// further ban of the same address lasts twice as long, up to a day
+	autoBanDurationEnv = "AUTOBAN_DURATION"
+
+	// Defaults used when the variables above are unset
+	defaultAutoBanHalfLife = time.Minute
+	defaultAutoBanDuration = 10 * time.Minute
+
+	// Longest ban, however often an address was banned before
+	autoBanMaxDuration = 24 * time.Hour
+
+	// Time after its last ban at which an address starts over with short bans
+	autoBanMemory = 24 * time.Hour
+
+	// Interval at which forgiven addresses are forgotten
+	autoBanSweepInterval = time.Minute
+
+	// Admin endpoints listing and lifting bans
+	adminBansEndpoint = "/admin/bans"
+	adminBanEndpoint  = "/admin/bans/{ip}"
+
+	// Close reason sent to connected clients of an address when it is banned
+	bannedCloseReason = "address banned"
+)
+
+// abuseKind names a pattern that raises the abuse score of an address
+type abuseKind string
+
+// Abuse patterns and the score they add
+const (
+	abuseReconnect      abuseKind = "reconnect"       // A WebSocket connection, counted so reconnect loops add up
+	abuseAuthFailure    abuseKind = "auth_failure"    // A request with an invalid admin token
+	abuseOversizedFrame abuseKind = "oversized_frame" // A client message over the size limit
+)
+
+var abuseScores = map[abuseKind]float64{
+	abuseReconnect:      1,
+	abuseAuthFailure:    5,
+	abuseOversizedFrame: 10,
+}
+
+// Ban describes an active ban in the admin API
+type Ban struct {
+	IP        string    `json:"ip"`         // Banned address
+	Reason    abuseKind `json:"reason"`     // Pattern that pushed the score over the threshold
+	BannedAt  time.Time `json:"banned_at"`  // Time the ban started
+	ExpiresAt time.Time `json:"expires_at"` // Time the ban ends
+	Strikes   int       `json:"strikes"`    // Bans of this address within the last day, including this one
+}
+
+// BanStats counts the bans, for the admin stats and metrics
+type BanStats struct {
+	Active int    `json:"active"` // Addresses currently banned
+	Total  uint64 `json:"total"`  // Bans since startup
+}
+
+// abuseRecord tracks the decaying abuse score of one address
+type abuseRecord struct {
+	score     float64   // Score as of updatedAt
+	updatedAt time.Time // Time the score was last decayed
+	strikes   int       // Bans within autoBanMemory of the last one
+	ban       Ban       // Latest ban, zero if never banned
+}
+
+// AutoBanner bans addresses whose abuse score crosses the threshold
+type AutoBanner struct {
+	threshold float64
+	halfLife  time.Duration
+	duration  time.Duration
+
+	mutex   sync.Mutex
+	records map[string]*abuseRecord
+	total   uint64
+}
+
+// AutoBan holds the abuse scores, nil when automatic bans are disabled
+var AutoBan *AutoBanner
+
+// setupAutoBan enables automatic bans when AUTOBAN_THRESHOLD is set
+func setupAutoBan() error {
+	value := os.Getenv(autoBanThresholdEnv)
+	if value == "" {
+		return nil
+	}
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold <= 0 {
+		return fmt.Errorf("invalid %s %q", autoBanThresholdEnv, value)
+	}
+
+	banner := &AutoBanner{
+		threshold: threshold,
+		halfLife:  defaultAutoBanHalfLife,
+		duration:  defaultAutoBanDuration,
+		records:   make(map[string]*abuseRecord),
+	}
+	for _, setting := range []struct {
+		env      string
+		duration *time.Duration
+	}{{autoBanHalfLifeEnv, &banner.halfLife}, {autoBanDurationEnv, &banner.duration}} {
+		if value := os.Getenv(setting.env); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid %s %q", setting.env, value)
+			}
+			*setting.duration = parsed
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(autoBanSweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runRecovered("auto-ban sweep", banner.sweep)
+		}
+	}()
+	AutoBan = banner
+
+	slog.Info("Banning abusive addresses", "threshold", threshold, "duration", banner.duration)
+	return nil
+}
+
+// Record adds the score of an abuse pattern to an address, banning it and
+// disconnecting its clients once the score reaches the threshold
+func (b *AutoBanner) Record(ip string, kind abuseKind) {
+	if b == nil || ip == "" {
+		return
+	}
+
+	now := time.Now()
+	b.mutex.Lock()
+	record := b.records[ip]
+	if record == nil {
+		record = &abuseRecord{updatedAt: now}
+		b.records[ip] = record
+	}
+	if now.Before(record.ban.ExpiresAt) {
+		b.mutex.Unlock()
+		return
+	}
+	b.decay(record, now)
+	record.score += abuseScores[kind]
+	if record.score < b.threshold {
+		b.mutex.Unlock()
+		return
+	}
+
+	if now.Sub(record.ban.BannedAt) > autoBanMemory {
+		record.strikes = 0
+	}
+	record.strikes++
+	duration := b.duration << (record.strikes - 1)
+	if duration > autoBanMaxDuration || duration <= 0 {
+		duration = autoBanMaxDuration
+	}
+	record.score = 0
+	record.ban = Ban{IP: ip, Reason: kind, BannedAt: now, ExpiresAt: now.Add(duration), Strikes: record.strikes}
+	ban := record.ban
+	b.total++
+	b.mutex.Unlock()
+
+	disconnected := disconnectClients(websocket.ClosePolicyViolation, bannedCloseReason, func(client *Client) bool { return client.ip == ip })
+	slog.Warn("Banned address", "client_ip", ip, "reason", kind, "duration", duration, "strikes", ban.Strikes, "disconnected", disconnected)
+}
+
+// Banned reports whether an address is banned
+//
+// Returns:
+//   - time.Time: Time the ban ends
+//   - bool: True while the address is banned
+func (b *AutoBanner) Banned(ip string) (time.Time, bool) {
+	if b == nil {
+		return time.Time{}, false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	record := b.records[ip]
+	if record == nil || !time.Now().Before(record.ban.ExpiresAt) {
+		return time.Time{}, false
+	}
+	return record.ban.ExpiresAt, true
+}
+
+// Bans returns the active bans, those ending first first
+func (b *AutoBanner) Bans() []Ban {
+	bans := []Ban{}
+	if b == nil {
+		return bans
+	}
+
+	now := time.Now()
+	b.mutex.Lock()
+	for _, record := range b.records {
+		if now.Before(record.ban.ExpiresAt) {
+			bans = append(bans, record.ban)
+		}
+	}
+	b.mutex.Unlock()
+
+	slices.SortFunc(bans, func(a, b Ban) int { return a.ExpiresAt.Compare(b.ExpiresAt) })
+	return bans
+}
+
+// Lift ends the ban of an address and forgives its score; later bans are
+// still longer for the strikes it collected
+//
+// Returns:
+//   - bool: False if the address was not banned
+func (b *AutoBanner) Lift(ip string) bool {
+	if b == nil {
+		return false
+	}
+
+	now := time.Now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	record := b.records[ip]
+	if record == nil || !now.Before(record.ban.ExpiresAt) {
+		return false
+	}
+	record.ban.ExpiresAt = now
+	record.score = 0
+	return true
+}
+
+// Stats counts the active bans and those since startup
+func (b *AutoBanner) Stats() BanStats {
+	now := time.Now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats := BanStats{Total: b.total}
+	for _, record := range b.records {
+		if now.Before(record.ban.ExpiresAt) {
+			stats.Active++
+		}
+	}
+	return stats
+}
+
+// decay reduces a score for the time elapsed since it was last updated; the
+// caller must hold the mutex
+func (b *AutoBanner) decay(record *abuseRecord, now time.Time) {
+	elapsed := now.Sub(record.updatedAt)
+	record.score *= math.Pow(0.5, elapsed.Seconds()/b.halfLife.Seconds())
+	record.updatedAt = now
+}
+
+// sweep forgets the addresses whose score is forgiven and whose last ban no
+// longer counts, so the records do not grow with every address ever seen
+func (b *AutoBanner) sweep() {
+	now := time.Now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ip, record := range b.records {
+		b.decay(record, now)
+		if record.score < 0.01 && now.Sub(record.ban.ExpiresAt) > autoBanMemory {
+			delete(b.records, ip)
+		}
+	}
+}
+
+// refuseBanned answers 429 with the remaining ban time if the request comes
+// from a banned address
+//
+// Returns:
+//   - bool: True if the request was refused
+func refuseBanned(w http.ResponseWriter, ip string) bool {
+	expiresAt, banned := AutoBan.Banned(ip)
+	if !banned {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(expiresAt).Seconds()))))
+	writeError(w, http.StatusTooManyRequests, "this address is temporarily banned")
+	return true
+}
+
+// HandleListBans returns the active bans
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListBans(w http.ResponseWriter, r *http.Request) {
+	if AutoBan == nil {
+		writeError(w, http.StatusNotFound, "automatic bans are disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, AutoBan.Bans())
+}
+
+// HandleLiftBan ends the ban of the address in the path
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the address as a path variable
+func HandleLiftBan(w http.ResponseWriter, r *http.Request) {
+	ip := mux.Vars(r)["ip"]
+	if !AutoBan.Lift(ip) {
+		writeError(w, http.StatusNotFound, "address is not banned")
+		return
+	}
+
+	slog.Info("Lifted ban", "client_ip", ip)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testAutoBanner returns a banner with the given threshold whose scores barely
+// decay during a test
+func testAutoBanner(threshold float64) *AutoBanner {
+	return &AutoBanner{
+		threshold: threshold,
+		halfLife:  time.Hour,
+		duration:  time.Minute,
+		records:   make(map[string]*abuseRecord),
+	}
+}
+
+// TestAutoBannerThreshold checks that an address is banned once its score
+// reaches the threshold, for the pattern that pushed it over; thresholds sit
+// just under the scores, which decay a little between records
+func TestAutoBannerThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		abuse     []abuseKind
+		expected  abuseKind
+	}{
+		{name: "under the threshold", threshold: 20, abuse: []abuseKind{abuseAuthFailure, abuseAuthFailure, abuseAuthFailure}},
+		{name: "reaching the threshold", threshold: 19.5, abuse: []abuseKind{abuseAuthFailure, abuseAuthFailure, abuseAuthFailure, abuseAuthFailure}, expected: abuseAuthFailure},
+		{name: "patterns adding up", threshold: 18, abuse: []abuseKind{abuseAuthFailure, abuseReconnect, abuseReconnect, abuseReconnect, abuseReconnect, abuseOversizedFrame}, expected: abuseOversizedFrame},
+		{name: "one pattern over the threshold", threshold: 5, abuse: []abuseKind{abuseOversizedFrame}, expected: abuseOversizedFrame},
+		{name: "reconnects under a low threshold", threshold: 3, abuse: []abuseKind{abuseReconnect, abuseReconnect}},
+		{name: "unknown pattern", threshold: 1, abuse: []abuseKind{"other"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			banner := testAutoBanner(test.threshold)
+			for _, kind := range test.abuse {
+				banner.Record("192.0.2.1", kind)
+			}
+
+			_, banned := banner.Banned("192.0.2.1")
+			if banned != (test.expected != "") {
+				t.Fatalf("banned %v, expected %v", banned, !banned)
+			}
+			if _, banned := banner.Banned("192.0.2.2"); banned {
+				t.Fatal("another address was banned")
+			}
+			if bans := banner.Bans(); banned && (len(bans) != 1 || bans[0].Reason != test.expected) {
+				t.Fatalf("got bans %+v, expected one for %s", bans, test.expected)
+			}
+		})
+	}
+}
+
+// TestAutoBannerStrikes checks that every further ban of an address lasts
+// twice as long up to the longest ban, and that abuse during a ban is ignored
+func TestAutoBannerStrikes(t *testing.T) {
+	banner := testAutoBanner(5)
+	banner.duration = time.Hour
+
+	durations := []time.Duration{time.Hour, 2 * time.Hour, 4 * time.Hour, 8 * time.Hour, 16 * time.Hour, autoBanMaxDuration, autoBanMaxDuration}
+	for strikes, expected := range durations {
+		banner.Record("192.0.2.1", abuseAuthFailure)
+		banner.Record("192.0.2.1", abuseOversizedFrame)
+
+		bans := banner.Bans()
+		if len(bans) != 1 {
+			t.Fatalf("got bans %+v, expected one", bans)
+		}
+		ban := bans[0]
+		if got := ban.ExpiresAt.Sub(ban.BannedAt); ban.Strikes != strikes+1 || got != expected {
+			t.Fatalf("got a ban of %s at strike %d, expected %s at strike %d", got, ban.Strikes, expected, strikes+1)
+		}
+		if ban.Reason != abuseAuthFailure {
+			t.Fatalf("got reason %s, expected %s", ban.Reason, abuseAuthFailure)
+		}
+		if !banner.Lift("192.0.2.1") {
+			t.Fatal("failed to lift the ban")
+		}
+	}
+	if stats := banner.Stats(); stats.Active != 0 || stats.Total != uint64(len(durations)) {
+		t.Fatalf("got %+v, expected no active bans of %d", stats, len(durations))
+	}
+}
+
+// TestAutoBannerDecay checks that half of a score is forgiven every half-life,
+// and that the strikes of an address are forgotten a day after its last ban
+func TestAutoBannerDecay(t *testing.T) {
+	banner := testAutoBanner(20)
+	banner.Record("192.0.2.1", abuseOversizedFrame)
+	banner.records["192.0.2.1"].updatedAt = time.Now().Add(-2 * banner.halfLife)
+	banner.Record("192.0.2.1", abuseOversizedFrame)
+	if score := banner.records["192.0.2.1"].score; score < 12.4 || score > 12.6 {
+		t.Fatalf("got score %f, expected 12.5", score)
+	}
+	banner.Record("192.0.2.1", abuseOversizedFrame)
+	if _, banned := banner.Banned("192.0.2.1"); !banned {
+		t.Fatal("the address was not banned")
+	}
+
+	record := banner.records["192.0.2.1"]
+	record.ban.BannedAt = time.Now().Add(-autoBanMemory - time.Minute)
+	record.ban.ExpiresAt = record.ban.BannedAt.Add(banner.duration)
+	record.updatedAt = time.Now().Add(-24 * banner.halfLife)
+	banner.sweep()
+	if _, found := banner.records["192.0.2.1"]; found {
+		t.Fatal("the forgiven address was not forgotten")
+	}
+}
+
+// TestSetupAutoBan checks that the threshold and durations must be positive
+func TestSetupAutoBan(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold string
+		halfLife  string
+		duration  string
+		invalid   bool
+	}{
+		{name: "disabled"},
+		{name: "threshold", threshold: "20"},
+		{name: "durations", threshold: "20", halfLife: "30s", duration: "1h"},
+		{name: "zero threshold", threshold: "0", invalid: true},
+		{name: "negative threshold", threshold: "-5", invalid: true},
+		{name: "threshold not a number", threshold: "many", invalid: true},
+		{name: "zero half-life", threshold: "20", halfLife: "0s", invalid: true},
+		{name: "duration without a unit", threshold: "20", duration: "10", invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			previous := AutoBan
+			t.Cleanup(func() { AutoBan = previous })
+			AutoBan = nil
+			t.Setenv(autoBanThresholdEnv, test.threshold)
+			t.Setenv(autoBanHalfLifeEnv, test.halfLife)
+			t.Setenv(autoBanDurationEnv, test.duration)
+
+			err := setupAutoBan()
+			if (err != nil) != test.invalid {
+				t.Fatalf("got %v, expected an error %v", err, test.invalid)
+			}
+			if enabled := AutoBan != nil; enabled != (test.threshold != "" && !test.invalid) {
+				t.Fatalf("enabled %v, expected %v", enabled, !enabled)
+			}
+		})
+	}
+}
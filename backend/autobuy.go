@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file with the auto-buy rules
+	// Auto-buying is disabled when it is unset; it also needs WALLET_SIGNING,
+	// or AUTO_BUY_PAPER to simulate the buys
+	autoBuyConfigFileEnv = "AUTO_BUY_CONFIG_FILE"
+
+	// Admin endpoint showing the rules with their spending
+	adminAutoBuyEndpoint = "/admin/autobuy"
+
+	// How long a creation waits for the dev buy of its creator; pump.fun
+	// bundles it in the creation transaction, so it arrives right after
+	autoBuyDevBuyWindow = time.Second
+
+	// Oldest creation the engine acts on, so replayed and backfilled
+	// creations are never bought
+	autoBuyMaxEventAge = 5 * time.Second
+
+	// Number of creations queued for evaluation before new ones are dropped
+	autoBuyQueueSize = 100
+
+	// Number of mints remembered exactly so each is bought only once; older
+	// ones are remembered by the Bloom filters of the cache
+	autoBuyMaxRememberedMints = 10000
+
+	// Links a rule can require in the metadata of the token
+	autoBuySocialTwitter  = "twitter"
+	autoBuySocialTelegram = "telegram"
+	autoBuySocialWebsite  = "website"
+)
+
+// AutoBuyConfig holds the auto-buy rules
+type AutoBuyConfig struct {
+	Rules []AutoBuyRule `json:"rules"` // Rules, evaluated in order; the first one matching a creation buys it
+}
+
+// AutoBuyRule buys the creations matching its conditions, within a budget
+type AutoBuyRule struct {
+	Name                  string       `json:"name"`                              // Rule name used in logs
+	AmountLamports        uint64       `json:"amount_lamports"`                   // Lamports spent per buy, fee included
+	SlippageBps           uint64       `json:"slippage_bps,omitempty"`            // Price move tolerated, in basis points, 100 by default
+	ComputeUnitPrice      uint64       `json:"compute_unit_price,omitempty"`      // Priority fee in micro-lamports per compute unit
+	BudgetLamports        uint64       `json:"budget_lamports"`                   // Most lamports the rule may spend, counting the slippage limit of each buy
+	Cooldown              string       `json:"cooldown,omitempty"`                // Go duration between two buys of the rule
+	Filter                NotifyFilter `json:"filter"`                            // Name pattern, creator and risk filters
+	MinDevBuySOL          float64      `json:"min_dev_buy_sol,omitempty"`         // Smallest buy of the creator in the creation transaction
+	MaxDevBuySOL          float64      `json:"max_dev_buy_sol,omitempty"`         // Largest buy of the creator in the creation transaction
+	MaxCreatorLaunches    int          `json:"max_creator_launches,omitempty"`    // Skip creators who launched more tracked tokens, this one included
+	MinCreatorGraduations int          `json:"min_creator_graduations,omitempty"` // Fewest tracked tokens of the creator that graduated
+	RequireSocials        []string     `json:"require_socials,omitempty"`         // Links the metadata must have: twitter, telegram or website
+}
+
+// AutoBuyRuleStatus is the admin view of a rule
+type AutoBuyRuleStatus struct {
+	Name           string    `json:"name"`                     // Rule name
+	Buys           int       `json:"buys"`                     // Buys submitted by the rule
+	SpentLamports  uint64    `json:"spent_lamports"`           // Budget used, counting the slippage limit of each buy
+	BudgetLamports uint64    `json:"budget_lamports"`          // Budget of the rule
+	LastBuyAt      time.Time `json:"last_buy_at,omitzero"`     // Time of the latest buy
+	LastSignature  string    `json:"last_signature,omitempty"` // Signature of the latest buy
+}
+
+// autoBuyResponse is the JSON body of the admin endpoint
+type autoBuyResponse struct {
+	Enabled bool                `json:"enabled"` // Whether the autobuy kill switch lets buys through
+	Paper   bool                `json:"paper"`   // Whether buys are simulated by the paper trader
+	Rules   []AutoBuyRuleStatus `json:"rules"`   // Rules in evaluation order
+}
+
+// autoBuyRule holds the parsed cooldown and spending state of a rule
+type autoBuyRule struct {
+	config   AutoBuyRule
+	cooldown time.Duration
+	limit    uint64 // Most lamports a buy may cost, with the slippage
+
+	mutex         sync.Mutex
+	spent         uint64
+	buys          int
+	lastBuyAt     time.Time
+	lastSignature string
+}
+
+// autoBuyCandidate is a creation to evaluate, with the dev buy of its creator
+type autoBuyCandidate struct {
+	record TokenRecord
+	devBuy uint64 // Lamports the creator spent in the creation transaction
+}
+
+// AutoBuyEngine buys new tokens matching its rules with the wallet
+//
+// Creations wait up to autoBuyDevBuyWindow for the buy of their creator in
+// the same transaction, then are evaluated one at a time. A mint is bought at
+// most once, by the first matching rule with budget left and no cooldown
+// running. The autobuy feature flag is the kill switch: turning it off through
+// the admin API stops every buy not yet submitted.
+type AutoBuyEngine struct {
+	rules []*autoBuyRule
+	queue chan autoBuyCandidate
+
+	// buyer executes a buy, returning its signature; walletBuy, or the paper
+	// trader in simulation mode
+	buyer func(ctx context.Context, rule *autoBuyRule, mint string) (string, error)
+
+	mutex   sync.Mutex
+	pending map[string]*autoBuyCandidate // Creations waiting for their dev buy, by mint
+	bought  *dedupCache                  // Mints bought
+}
+
+// AutoBuy buys tokens when AUTO_BUY_CONFIG_FILE is set, nil otherwise
+var AutoBuy *AutoBuyEngine
+
+// setupAutoBuy loads the auto-buy rules when AUTO_BUY_CONFIG_FILE is set
+//
+// Returns:
+//   - error: Error if the rules are invalid or the wallet is disabled
+func setupAutoBuy() error {
+	path := os.Getenv(autoBuyConfigFileEnv)
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read auto-buy config file: %w", err)
+	}
+	var config AutoBuyConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse auto-buy config file: %w", err)
+	}
+
+	engine := &AutoBuyEngine{
+		queue:   make(chan autoBuyCandidate, autoBuyQueueSize),
+		pending: make(map[string]*autoBuyCandidate),
+		bought:  newDedupCache("autobuy", autoBuyMaxRememberedMints),
+	}
+	for _, ruleConfig := range config.Rules {
+		rule, err := newAutoBuyRule(ruleConfig)
+		if err != nil {
+			return fmt.Errorf("auto-buy rule %q: %w", ruleConfig.Name, err)
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+	if len(engine.rules) == 0 {
+		return fmt.Errorf("auto-buy config file has no rules")
+	}
+
+	mode := "the wallet"
+	switch {
+	case envBool(paperTradingEnv):
+		if PaperTrades, err = newPaperTrader(); err != nil {
+			return err
+		}
+		engine.buyer = PaperTrades.buy
+		mode = fmt.Sprintf("paper trades from %d lamports", PaperTrades.startingBalance)
+	case HotWallet == nil:
+		return fmt.Errorf("%s needs the wallet, set %s, or %s to simulate", autoBuyConfigFileEnv, walletSigningEnv, paperTradingEnv)
+	default:
+		engine.buyer = walletBuy
+	}
+
+	AutoBuy = engine
+	goSafe("auto-buy", engine.evaluateLoop)
+	RegisterSink(engine)
+
+	slog.Info("Auto-buying enabled; turn the feature flag off to stop", "rules", len(engine.rules), "mode", mode, "flag", featureAutoBuy)
+	return nil
+}
+
+// newAutoBuyRule validates a rule and parses its cooldown
+func newAutoBuyRule(config AutoBuyRule) (*autoBuyRule, error) {
+	if config.AmountLamports == 0 {
+		return nil, fmt.Errorf("amount_lamports must be positive")
+	}
+	if config.SlippageBps == 0 {
+		config.SlippageBps = defaultSlippageBasisPoints
+	}
+	if config.SlippageBps > maxSlippageBasisPoints {
+		return nil, fmt.Errorf("slippage_bps must be at most %d", maxSlippageBasisPoints)
+	}
+	limit := config.AmountLamports + config.AmountLamports*config.SlippageBps/10_000
+	if config.BudgetLamports < limit {
+		return nil, fmt.Errorf("budget_lamports must cover at least one buy, %d lamports with the slippage", limit)
+	}
+	if config.MaxDevBuySOL > 0 && config.MaxDevBuySOL < config.MinDevBuySOL {
+		return nil, fmt.Errorf("max_dev_buy_sol must not be below min_dev_buy_sol")
+	}
+	for _, social := range config.RequireSocials {
+		if !slices.Contains([]string{autoBuySocialTwitter, autoBuySocialTelegram, autoBuySocialWebsite}, social) {
+			return nil, fmt.Errorf("unknown social %q, expected %s, %s or %s", social, autoBuySocialTwitter, autoBuySocialTelegram, autoBuySocialWebsite)
+		}
+	}
+
+	// Rules act on creations only
+	config.Filter.EventTypes = nil
+	if err := config.Filter.Compile(); err != nil {
+		return nil, err
+	}
+
+	rule := &autoBuyRule{config: config, limit: limit}
+	if config.Cooldown != "" {
+		var err error
+		if rule.cooldown, err = time.ParseDuration(config.Cooldown); err != nil {
+			return nil, fmt.Errorf("invalid cooldown: %w", err)
+		}
+	}
+	return rule, nil
+}
+
+// Name identifies the sink in logs
+func (e *AutoBuyEngine) Name() string {
+	return "autobuy"
+}
+
+// Publish holds fresh creations for their dev buy and completes them with it
+func (e *AutoBuyEngine) Publish(event Event) {
+	if !Features.Enabled(featureAutoBuy) || time.Since(event.ReceivedAt) > autoBuyMaxEventAge {
+		return
+	}
+
+	switch event.Type {
+	case EventCreate:
+		record, found := Tokens.Get(event.Mint)
+		if !found {
+			return
+		}
+		e.mutex.Lock()
+		e.pending[event.Mint] = &autoBuyCandidate{record: record}
+		e.mutex.Unlock()
+		time.AfterFunc(autoBuyDevBuyWindow, func() { e.release(event.Mint, "", nil) })
+
+	case EventTrade:
+		if trade, ok := event.Data.(TradeEvent); ok && trade.IsBuy {
+			e.release(event.Mint, event.Signature, &trade)
+		}
+	}
+}
+
+// release queues a pending creation for evaluation
+//
+// Parameters:
+//   - mint: Mint of the creation
+//   - signature: Transaction of the trade
+//   - trade: A buy of the mint, nil once the dev buy window is over; only the
+//     buy of the creator in the creation transaction releases the creation
+func (e *AutoBuyEngine) release(mint, signature string, trade *TradeEvent) {
+	e.mutex.Lock()
+	candidate, found := e.pending[mint]
+	if !found {
+		e.mutex.Unlock()
+		return
+	}
+	if trade != nil {
+		if signature != candidate.record.Signature || trade.User != candidate.record.Creator {
+			e.mutex.Unlock()
+			return
+		}
+		candidate.devBuy = trade.SolAmount
+	}
+	delete(e.pending, mint)
+	e.mutex.Unlock()
+
+	select {
+	case e.queue <- *candidate:
+	default:
+		slog.Warn("Auto-buy queue full, skipping creation", logKeyMint, mint)
+	}
+}
+
+// evaluateLoop evaluates queued creations one at a time
+func (e *AutoBuyEngine) evaluateLoop() {
+	for candidate := range e.queue {
+		e.evaluate(candidate)
+	}
+}
+
+// evaluate buys a creation with the first rule it matches that can buy
+func (e *AutoBuyEngine) evaluate(candidate autoBuyCandidate) {
+	record := candidate.record
+	launched, graduated := Tokens.CreatorStats(record.Creator)
+	flags := newRiskFlagSet(record)
+
+	// The metadata is only fetched when a rule needs it
+	var metadata *tokenMetadata
+	socials := func() *tokenMetadata {
+		if metadata == nil {
+			fetched, _ := fetchMetadata(record.Creation.Uri)
+			metadata = &fetched
+		}
+		return metadata
+	}
+
+	event := Event{Type: EventCreate, Mint: record.Mint}
+	for _, rule := range e.rules {
+		if !rule.config.Filter.Matches(event, record, flags) || !rule.matchesCreator(candidate.devBuy, launched, graduated) {
+			continue
+		}
+		if len(rule.config.RequireSocials) > 0 && !rule.matchesSocials(socials()) {
+			continue
+		}
+		if e.buy(rule, record.Mint) {
+			return
+		}
+	}
+}
+
+// matchesCreator checks the dev buy and reputation conditions of the rule
+func (r *autoBuyRule) matchesCreator(devBuy uint64, launched, graduated int) bool {
+	devBuySOL := float64(devBuy) / lamportsPerSOL
+	if devBuySOL < r.config.MinDevBuySOL || (r.config.MaxDevBuySOL > 0 && devBuySOL > r.config.MaxDevBuySOL) {
+		return false
+	}
+	if r.config.MaxCreatorLaunches > 0 && launched > r.config.MaxCreatorLaunches {
+		return false
+	}
+	return graduated >= r.config.MinCreatorGraduations
+}
+
+// matchesSocials checks that the metadata links every required social
+func (r *autoBuyRule) matchesSocials(metadata *tokenMetadata) bool {
+	links := map[string]string{
+		autoBuySocialTwitter:  metadata.Twitter,
+		autoBuySocialTelegram: metadata.Telegram,
+		autoBuySocialWebsite:  metadata.Website,
+	}
+	for _, social := range r.config.RequireSocials {
+		if links[social] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// buy submits a buy of the mint for the rule, unless the mint was already
+// bought, the kill switch is off, or the rule is cooling down or out of budget
+//
+// Returns:
+//   - bool: Whether the mint is bought, so later rules are not tried
+func (e *AutoBuyEngine) buy(rule *autoBuyRule, mint string) bool {
+	if e.bought.Contains(mint) {
+		return true
+	}
+
+	if !rule.reserve() {
+		return false
+	}
+	// Checked last, so a buy never starts after the switch is turned off
+	if !Features.Enabled(featureAutoBuy) {
+		rule.refund()
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transactionBuildTimeout)
+	defer cancel()
+	signature, err := e.buyer(ctx, rule, mint)
+	if err != nil {
+		slog.Warn("Auto-buy failed", "rule", rule.config.Name, logKeyMint, mint, logKeyError, err)
+		reportError(errorCategoryUpstream, err, "rule", rule.config.Name, logKeyMint, mint)
+
+		// A transaction whose submission failed, such as on a timeout, may
+		// still land: the mint counts as bought and the budget stays spent.
+		// The buy only falls through to the next rule when nothing was sent.
+		if errors.Is(err, errTradeSubmission) {
+			e.bought.Add(mint)
+			return true
+		}
+		rule.refund()
+		return false
+	}
+
+	rule.mutex.Lock()
+	rule.lastSignature = signature
+	rule.mutex.Unlock()
+
+	e.bought.Add(mint)
+
+	slog.Info("Auto-buy submitted", "rule", rule.config.Name, logKeyMint, mint, logKeySignature, signature, "amount_lamports", rule.config.AmountLamports)
+	return true
+}
+
+// walletBuy submits the buy of a rule with the wallet
+//
+// Returns:
+//   - string: Signature of the submitted transaction
+//   - error: Errors of Wallet.Trade
+func walletBuy(ctx context.Context, rule *autoBuyRule, mint string) (string, error) {
+	trade, err := HotWallet.Trade(ctx, WalletTradeRequest{
+		Side:             TradeBuy,
+		Mint:             mint,
+		Amount:           rule.config.AmountLamports,
+		SlippageBps:      rule.config.SlippageBps,
+		ComputeUnitPrice: rule.config.ComputeUnitPrice,
+	})
+	return trade.Signature, err
+}
+
+// reserve takes a buy out of the budget of the rule and starts its cooldown
+//
+// Returns:
+//   - bool: False if the rule is cooling down or the budget cannot cover the buy
+func (r *autoBuyRule) reserve() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cooldown > 0 && time.Since(r.lastBuyAt) < r.cooldown {
+		return false
+	}
+	if r.spent+r.limit > r.config.BudgetLamports {
+		return false
+	}
+	r.spent += r.limit
+	r.buys++
+	r.lastBuyAt = time.Now()
+	return true
+}
+
+// refund gives back a reservation whose buy was not submitted
+// The cooldown keeps running, so a failing rule does not retry in a loop.
+func (r *autoBuyRule) refund() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spent -= r.limit
+	r.buys--
+}
+
+// Status returns the spending of every rule, in evaluation order
+func (e *AutoBuyEngine) Status() []AutoBuyRuleStatus {
+	statuses := make([]AutoBuyRuleStatus, 0, len(e.rules))
+	for _, rule := range e.rules {
+		rule.mutex.Lock()
+		statuses = append(statuses, AutoBuyRuleStatus{
+			Name:           rule.config.Name,
+			Buys:           rule.buys,
+			SpentLamports:  rule.spent,
+			BudgetLamports: rule.config.BudgetLamports,
+			LastBuyAt:      rule.lastBuyAt,
+			LastSignature:  rule.lastSignature,
+		})
+		rule.mutex.Unlock()
+	}
+	return statuses
+}
+
+// HandleGetAutoBuy returns the state of the kill switch and the spending of the rules
+func HandleGetAutoBuy(w http.ResponseWriter, r *http.Request) {
+	if AutoBuy == nil {
+		writeError(w, http.StatusNotFound, "auto-buy is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, autoBuyResponse{
+		Enabled: Features.Enabled(featureAutoBuy),
+		Paper:   PaperTrades != nil,
+		Rules:   AutoBuy.Status(),
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestAutoBuyFailedBuys checks that a buy failing before its transaction is
+// sent returns the budget and lets the next rule try, while a buy whose
+// submission failed keeps its budget and counts the mint as bought
+func TestAutoBuyFailedBuys(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		bought   bool
+		spent    bool
+		attempts int
+	}{
+		{name: "built and sent", bought: true, spent: true, attempts: 1},
+		{name: "build failed", err: errors.New("bonding curve not found"), attempts: 2},
+		{name: "over the cap", err: fmt.Errorf("%w: the buy may cost 2 lamports, the cap is 1", errTradeTooLarge), attempts: 2},
+		{name: "submission timed out", err: fmt.Errorf("%w: %w", errTradeSubmission, context.DeadlineExceeded), bought: true, spent: true, attempts: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, err := newAutoBuyRule(AutoBuyRule{Name: "first", AmountLamports: 1_000_000, BudgetLamports: 10_000_000})
+			if err != nil {
+				t.Fatalf("failed to create rule: %v", err)
+			}
+			second, err := newAutoBuyRule(AutoBuyRule{Name: "second", AmountLamports: 1_000_000, BudgetLamports: 10_000_000})
+			if err != nil {
+				t.Fatalf("failed to create rule: %v", err)
+			}
+
+			attempts := 0
+			engine := &AutoBuyEngine{
+				rules:  []*autoBuyRule{first, second},
+				bought: newDedupCache("autobuy-test", 16),
+				buyer: func(ctx context.Context, rule *autoBuyRule, mint string) (string, error) {
+					attempts++
+					if rule == first {
+						return "signature", test.err
+					}
+					return "", errors.New("second rule failed")
+				},
+			}
+			mint := "2zMMhcVQEXDtdE6vsFS7S7D5oUodfJHE8vd1gnBouauv"
+			for _, rule := range engine.rules {
+				if engine.buy(rule, mint) {
+					break
+				}
+			}
+
+			if attempts != test.attempts {
+				t.Fatalf("got %d attempts, expected %d", attempts, test.attempts)
+			}
+			if bought := engine.bought.Contains(mint); bought != test.bought {
+				t.Fatalf("bought %v, expected %v", bought, test.bought)
+			}
+			if spent := first.spent == first.limit && first.buys == 1; spent != test.spent {
+				t.Fatalf("first rule spent %d in %d buys, expected the budget kept %v", first.spent, first.buys, test.spent)
+			}
+			if second.spent != 0 || second.buys != 0 {
+				t.Fatalf("second rule spent %d in %d buys, expected it refunded", second.spent, second.buys)
+			}
+		})
+	}
+}
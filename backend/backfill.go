@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Maximum number of signatures returned by a single getSignaturesForAddress call
+	maxSignaturesPerPage = 1000
+
+	// Timeout applied to every backfill RPC call
+	backfillRequestTimeout = 30 * time.Second
+)
+
+// backfill fetches the most recent transactions of the PumpFun program over RPC
+// and hands their logs to handle, oldest first, so they are processed in the
+// order they happened
+//
+// Parameters:
+//   - client: RPC client to query
+//   - limit: Maximum number of transactions to fetch
+//   - before: Optional signature to start searching backwards from
+//   - until: Optional signature to stop at
+//   - handle: Function receiving each notification, e.g. processNotification
+//
+// Returns:
+//   - int: Number of transactions processed
+//   - error: Error if the signatures could not be listed
+func backfill(client *rpc.Client, limit int, before, until solana.Signature, handle func(pumpstream.Notification)) (int, error) {
+	// Page backwards through the signatures, newest first
+	var signatures []*rpc.TransactionSignature
+	for len(signatures) < limit {
+		pageSize := min(limit-len(signatures), maxSignaturesPerPage)
+
+		ctx, cancel := context.WithTimeout(context.Background(), backfillRequestTimeout)
+		page, err := client.GetSignaturesForAddressWithOpts(ctx, pumpProgram(), &rpc.GetSignaturesForAddressOpts{
+			Limit:      &pageSize,
+			Before:     before,
+			Until:      until,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		cancel()
+		if err != nil {
+			return 0, fmt.Errorf("failed to list signatures: %w", err)
+		}
+
+		signatures = append(signatures, page...)
+		if len(page) < pageSize {
+			break
+		}
+		before = page[len(page)-1].Signature
+	}
+
+	slog.Info("Backfilling transactions", "count", len(signatures))
+
+	maxVersion := uint64(0)
+	processed := 0
+	for _, signature := range slices.Backward(signatures) {
+		// Failed transactions did not change any state, skip them
+		if signature.Err != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), backfillRequestTimeout)
+		transaction, err := client.GetTransaction(ctx, signature.Signature, &rpc.GetTransactionOpts{
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		cancel()
+		if err != nil {
+			slog.Warn("Failed to fetch transaction", logKeySignature, signature.Signature.String(), logKeyError, err)
+			continue
+		}
+		if transaction.Meta == nil {
+			continue
+		}
+
+		receivedAt := time.Now().UTC()
+		if transaction.BlockTime != nil {
+			receivedAt = transaction.BlockTime.Time().UTC()
+		}
+
+		handle(pumpstream.Notification{
+			Signature:  signature.Signature.String(),
+			Slot:       transaction.Slot,
+			Logs:       transaction.Meta.LogMessages,
+			ReceivedAt: receivedAt,
+		})
+		processed++
+	}
+
+	return processed, nil
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the dataset rows are streamed into
+	// The BigQuery sink is disabled when it is unset
+	bigQueryDatasetEnv = "BIGQUERY_DATASET"
+
+	// Environment variable with the project of the dataset, defaulting to the
+	// project of the credentials
+	bigQueryProjectEnv = "BIGQUERY_PROJECT"
+
+	// Environment variable pointing to a service account JSON key
+	// Application default credentials are used when it is unset
+	bigQueryCredentialsFileEnv = "BIGQUERY_CREDENTIALS_FILE"
+
+	// Environment variable overriding the API root, e.g. for an emulator
+	bigQueryEndpointEnv = "BIGQUERY_ENDPOINT"
+
+	// BigQuery REST API root
+	defaultBigQueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+	// OAuth scope required to create tables and stream rows
+	bigQueryScope = "https://www.googleapis.com/auth/bigquery"
+
+	// Tables written by the sink, see schema/bigquery.json
+	bigQueryCreationsTable   = "pumpfun_creations"
+	bigQueryTradesTable      = "pumpfun_trades"
+	bigQueryCompletionsTable = "pumpfun_completions"
+
+	// Maximum insert attempts per table and batch, including the first one
+	bigQueryMaxAttempts = 6
+
+	// Delay before the first retry; it doubles with every further attempt
+	bigQueryRetryDelay = time.Second
+)
+
+// bigQueryTables describes the tables written by the sink in the format of
+// the tables.insert API, keyed by table name
+//
+//go:embed schema/bigquery.json
+var bigQueryTables []byte
+
+// bigQueryCreation is a row of the creations table
+type bigQueryCreation struct {
+	Mint         string `json:"mint"`
+	Name         string `json:"name"`
+	Symbol       string `json:"symbol"`
+	Uri          string `json:"uri"`
+	BondingCurve string `json:"bonding_curve,omitempty"`
+	Creator      string `json:"creator,omitempty"`
+	Signature    string `json:"signature"`
+	Slot         uint64 `json:"slot"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// bigQueryTrade is a row of the trades table
+type bigQueryTrade struct {
+	Mint                 string  `json:"mint"`
+	Signature            string  `json:"signature"`
+	Slot                 uint64  `json:"slot"`
+	Trader               string  `json:"trader"`
+	IsBuy                bool    `json:"is_buy"`
+	SolAmount            uint64  `json:"sol_amount"`
+	TokenAmount          uint64  `json:"token_amount"`
+	VirtualSolReserves   uint64  `json:"virtual_sol_reserves"`
+	VirtualTokenReserves uint64  `json:"virtual_token_reserves"`
+	MarketCapSOL         float64 `json:"market_cap_sol"`
+	TradedAt             string  `json:"traded_at"`
+}
+
+// bigQueryCompletion is a row of the completions table
+type bigQueryCompletion struct {
+	Mint         string `json:"mint"`
+	Signature    string `json:"signature"`
+	Slot         uint64 `json:"slot"`
+	User         string `json:"user"`
+	BondingCurve string `json:"bonding_curve"`
+	CompletedAt  string `json:"completed_at"`
+}
+
+// bigQueryRow is a row of an insertAll request
+// The insert ID lets BigQuery drop duplicates when a retried request had
+// already been applied
+type bigQueryRow struct {
+	InsertID string      `json:"insertId"`
+	JSON     interface{} `json:"json"`
+}
+
+// bigQueryInsertResponse is the part of the insertAll response the sink reads
+type bigQueryInsertResponse struct {
+	InsertErrors []struct {
+		Index  int `json:"index"`
+		Errors []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"insertErrors"`
+}
+
+// bigQueryErrorResponse is the error body returned by the BigQuery API
+type bigQueryErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Errors  []struct {
+			Reason string `json:"reason"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// BigQuerySink streams creations, trades and completions into BigQuery in
+// batches with the insertAll API, retrying with backoff when a quota is hit
+type BigQuerySink struct {
+	*eventBatcher
+	endpoint string
+	project  string
+	dataset  string
+	client   *http.Client
+}
+
+// setupBigQuerySink registers the BigQuery sink when BIGQUERY_DATASET is set
+func setupBigQuerySink() error {
+	dataset := os.Getenv(bigQueryDatasetEnv)
+	if dataset == "" {
+		return nil
+	}
+
+	var credentials *google.Credentials
+	var err error
+	if path := os.Getenv(bigQueryCredentialsFileEnv); path != "" {
+		data, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return fmt.Errorf("failed to read BigQuery credentials: %w", readErr)
+		}
+		credentials, err = google.CredentialsFromJSON(context.Background(), data, bigQueryScope)
+	} else {
+		credentials, err = google.FindDefaultCredentials(context.Background(), bigQueryScope)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load BigQuery credentials: %w", err)
+	}
+
+	project := envOrDefault(bigQueryProjectEnv, credentials.ProjectID)
+	if project == "" {
+		return fmt.Errorf("%s must be set when the credentials do not name a project", bigQueryProjectEnv)
+	}
+
+	sink := &BigQuerySink{
+		endpoint: strings.TrimSuffix(envOrDefault(bigQueryEndpointEnv, defaultBigQueryEndpoint), "/"),
+		project:  project,
+		dataset:  dataset,
+		client:   &http.Client{Transport: &oauth2.Transport{Source: credentials.TokenSource}, Timeout: webhookRequestTimeout},
+	}
+	if err := sink.createTables(); err != nil {
+		return fmt.Errorf("failed to create BigQuery tables: %w", err)
+	}
+
+	sink.eventBatcher = newEventBatcher("bigquery", sink.writeBatch)
+	RegisterSink(sink)
+
+	slog.Info("Streaming events to BigQuery", "project", project, "dataset", dataset)
+	return nil
+}
+
+// createTables creates the tables of schema/bigquery.json that do not exist yet
+func (s *BigQuerySink) createTables() error {
+	var tables map[string]json.RawMessage
+	if err := json.Unmarshal(bigQueryTables, &tables); err != nil {
+		return err
+	}
+
+	for name, definition := range tables {
+		var table map[string]interface{}
+		if err := json.Unmarshal(definition, &table); err != nil {
+			return err
+		}
+		table["tableReference"] = map[string]string{"projectId": s.project, "datasetId": s.dataset, "tableId": name}
+
+		status, err := s.post(s.datasetURL()+"/tables", table, nil)
+		if err != nil && status != http.StatusConflict {
+			return fmt.Errorf("table %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// writeBatch streams the rows of a batch into their tables
+func (s *BigQuerySink) writeBatch(batch []Event) error {
+	rows := map[string][]bigQueryRow{}
+
+	for i, event := range batch {
+		// Signatures are unique per transaction; the position separates several
+		// events of the same transaction and is stable when a batch is retried
+		insertID := fmt.Sprintf("%s:%s:%d", event.Signature, event.Type, i)
+
+		switch data := event.Data.(type) {
+		case CreateEvent:
+			// Creator and bonding curve are not part of CreateEvent; take them from the store
+			record, _ := Tokens.Get(data.Mint)
+			rows[bigQueryCreationsTable] = append(rows[bigQueryCreationsTable], bigQueryRow{InsertID: insertID, JSON: bigQueryCreation{
+				Mint:         data.Mint,
+				Name:         data.Name,
+				Symbol:       data.Symbol,
+				Uri:          data.Uri,
+				BondingCurve: record.BondingCurve,
+				Creator:      record.Creator,
+				Signature:    event.Signature,
+				Slot:         event.Slot,
+				CreatedAt:    event.ReceivedAt.UTC().Format(time.RFC3339Nano),
+			}})
+		case TradeEvent:
+			curve := CurveState{VirtualSolReserves: data.VirtualSolReserves, VirtualTokenReserves: data.VirtualTokenReserves}
+			rows[bigQueryTradesTable] = append(rows[bigQueryTradesTable], bigQueryRow{InsertID: insertID, JSON: bigQueryTrade{
+				Mint:                 data.Mint,
+				Signature:            event.Signature,
+				Slot:                 event.Slot,
+				Trader:               data.User,
+				IsBuy:                data.IsBuy,
+				SolAmount:            data.SolAmount,
+				TokenAmount:          data.TokenAmount,
+				VirtualSolReserves:   data.VirtualSolReserves,
+				VirtualTokenReserves: data.VirtualTokenReserves,
+				MarketCapSOL:         curve.MarketCapSOL(),
+				TradedAt:             time.Unix(data.Timestamp, 0).UTC().Format(time.RFC3339),
+			}})
+		case CompleteEvent:
+			rows[bigQueryCompletionsTable] = append(rows[bigQueryCompletionsTable], bigQueryRow{InsertID: insertID, JSON: bigQueryCompletion{
+				Mint:         data.Mint,
+				Signature:    event.Signature,
+				Slot:         event.Slot,
+				User:         data.User,
+				BondingCurve: data.BondingCurve,
+				CompletedAt:  time.Unix(data.Timestamp, 0).UTC().Format(time.RFC3339),
+			}})
+		}
+	}
+
+	for table, tableRows := range rows {
+		if err := s.insert(table, tableRows); err != nil {
+			return fmt.Errorf("table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// insert streams rows into a table, retrying with exponential backoff while
+// BigQuery reports a quota or rate limit, or a transient server error
+// Rows BigQuery rejects individually are logged and not retried
+func (s *BigQuerySink) insert(table string, rows []bigQueryRow) error {
+	request := map[string]interface{}{"rows": rows, "skipInvalidRows": true}
+	url := s.datasetURL() + "/tables/" + table + "/insertAll"
+
+	delay := bigQueryRetryDelay
+	for attempt := 1; ; attempt++ {
+		var response bigQueryInsertResponse
+		status, err := s.post(url, request, &response)
+		if err == nil {
+			for _, insertError := range response.InsertErrors {
+				for _, detail := range insertError.Errors {
+					slog.Warn("BigQuery rejected row", "row", insertError.Index, "table", table, "reason", detail.Reason, "message", detail.Message)
+				}
+			}
+			return nil
+		}
+
+		if attempt == bigQueryMaxAttempts || !bigQueryRetryable(status, err) {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// bigQueryRetryable reports whether a failed request should be retried
+func bigQueryRetryable(status int, err error) bool {
+	switch {
+	case status == 0, status == http.StatusTooManyRequests, status >= http.StatusInternalServerError:
+		// No response at all, throttled, or a server side failure
+		return true
+	case status == http.StatusForbidden:
+		// Quota errors are reported as 403 with a reason
+		message := err.Error()
+		return strings.Contains(message, "quotaExceeded") || strings.Contains(message, "rateLimitExceeded")
+	default:
+		return false
+	}
+}
+
+// post sends a JSON request and decodes the response into result if non-nil
+//
+// Returns:
+//   - int: HTTP status code, 0 if no response was received
+//   - error: Error if the request failed or BigQuery returned an error status
+func (s *BigQuerySink) post(url string, body interface{}, result interface{}) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := s.client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		var apiError bigQueryErrorResponse
+		if json.Unmarshal(data, &apiError) == nil && apiError.Error.Message != "" {
+			reasons := make([]string, 0, len(apiError.Error.Errors))
+			for _, detail := range apiError.Error.Errors {
+				reasons = append(reasons, detail.Reason)
+			}
+			return response.StatusCode, fmt.Errorf("BigQuery responded with status %d (%s): %s", response.StatusCode, strings.Join(reasons, ", "), apiError.Error.Message)
+		}
+		return response.StatusCode, fmt.Errorf("BigQuery responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+			return response.StatusCode, err
+		}
+	}
+	return response.StatusCode, nil
+}
+
+// datasetURL returns the API URL of the dataset
+func (s *BigQuerySink) datasetURL() string {
+	return s.endpoint + "/projects/" + s.project + "/datasets/" + s.dataset
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the Redis channel bridging an ingester and its replicas
+	// An ingester (serve with the default upstream source) publishes every upstream
+	// notification to it; replicas (serve --source redis) subscribe to it instead of
+	// the chain. The ingester side is disabled when it is unset
+	redisBridgeChannelEnv = "REDIS_BRIDGE_CHANNEL"
+
+	// Sources serve can take notifications from
+	sourceUpstream = "upstream" // Subscribe to the Solana WebSocket RPC endpoint
+	sourceRedis    = "redis"    // Subscribe to an ingester through the Redis bridge
+
+	// Receive the deliveries of a raw Helius webhook on the HTTP server
+	sourceHeliusWebhook = "helius-webhook"
+
+	// Number of notifications buffered before new ones are dropped
+	redisBridgeQueueSize = 10000
+
+	// Delay before resubscribing after the bridge subscription fails
+	redisBridgeRetryDelay = 2 * time.Second
+)
+
+// RedisBridge publishes upstream notifications to the bridge channel
+// Replicas run the full processing pipeline on the notifications, so their token
+// store, stats and WebSocket output match the ingester's
+type RedisBridge struct {
+	channel string
+	queue   chan pumpstream.Notification
+}
+
+// newRedisBridge starts publishing to the bridge channel when REDIS_BRIDGE_CHANNEL is set
+//
+// Returns:
+//   - *RedisBridge: The bridge, nil when it is not configured
+//   - error: Error if Redis is unreachable
+func newRedisBridge() (*RedisBridge, error) {
+	channel := os.Getenv(redisBridgeChannelEnv)
+	if channel == "" {
+		return nil, nil
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	bridge := &RedisBridge{
+		channel: channel,
+		queue:   make(chan pumpstream.Notification, redisBridgeQueueSize),
+	}
+	go func() {
+		for notification := range bridge.queue {
+			data, err := json.Marshal(notification)
+			if err != nil {
+				slog.Error("Failed to marshal notification for the Redis bridge", logKeyError, err)
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+			err = client.Publish(ctx, channel, data).Err()
+			cancel()
+
+			if err != nil {
+				slog.Warn("Failed to publish notification to the Redis bridge", logKeySignature, notification.Signature, logKeyError, err)
+			}
+		}
+	}()
+
+	slog.Info("Publishing upstream notifications to the Redis bridge", "channel", channel)
+	return bridge, nil
+}
+
+// Publish queues a notification for the bridge, dropping it if the queue is full
+func (b *RedisBridge) Publish(notification pumpstream.Notification) {
+	select {
+	case b.queue <- notification:
+	default:
+		slog.Warn("Redis bridge queue full, dropping notification", logKeySignature, notification.Signature)
+	}
+}
+
+// listenToRedisBridge subscribes to the bridge channel and hands every
+// notification published by the ingester to handle. It resubscribes
+// automatically and records the subscription health in FeedStats, until ctx
+// is done.
+//
+// Parameters:
+//   - ctx: Context whose cancellation closes the subscription
+//   - handle: Function receiving each notification, e.g. processNotification
+//
+// Returns:
+//   - <-chan struct{}: Closed once the subscription stopped
+//   - error: Error if the bridge is not configured or Redis is unreachable at startup
+func listenToRedisBridge(ctx context.Context, handle func(pumpstream.Notification)) (<-chan struct{}, error) {
+	channel := os.Getenv(redisBridgeChannelEnv)
+	if channel == "" {
+		return nil, fmt.Errorf("%s must be set to use the Redis source", redisBridgeChannelEnv)
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			err := receiveBridgeNotifications(ctx, client.Subscribe(ctx, channel), handle)
+			if ctx.Err() != nil {
+				return
+			}
+			FeedStats.RecordUpstreamError(err)
+			slog.Warn("Redis bridge subscription lost", logKeyError, err, "retry_in", redisBridgeRetryDelay)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(redisBridgeRetryDelay):
+			}
+		}
+	}()
+
+	slog.Info("Receiving notifications from the Redis bridge", "channel", channel)
+	return stopped, nil
+}
+
+// receiveBridgeNotifications handles messages of a bridge subscription until it fails or ctx is done
+func receiveBridgeNotifications(ctx context.Context, subscription *redis.PubSub, handle func(pumpstream.Notification)) error {
+	defer subscription.Close()
+
+	for {
+		message, err := subscription.Receive(ctx)
+		if err != nil {
+			return err
+		}
+
+		switch message := message.(type) {
+		case *redis.Subscription:
+			FeedStats.RecordUpstreamConnected()
+		case *redis.Message:
+			FeedStats.RecordUpstreamMessage()
+
+			var notification pumpstream.Notification
+			if err := json.Unmarshal([]byte(message.Payload), &notification); err != nil {
+				slog.Warn("Invalid notification on the Redis bridge", logKeyError, err)
+				continue
+			}
+			handle(notification)
+		}
+	}
+}
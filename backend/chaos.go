@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling chaos mode, for development only: when
+	// true, the faults configured below are injected into the upstream feed
+	// and the WebSocket clients
+	chaosModeEnv = "CHAOS_MODE"
+
+	// Environment variable seeding the fault decisions, so a run can be
+	// replayed; a random seed is used and printed when unset
+	chaosSeedEnv = "CHAOS_SEED"
+
+	// Environment variables setting the chance, from 0 to 1, of each fault
+	chaosDisconnectRateEnv = "CHAOS_DISCONNECT_RATE"  // Per notification, drop the upstream connection before it is handled
+	chaosDelayRateEnv      = "CHAOS_DELAY_RATE"       // Per notification, hold it back before it is handled
+	chaosCorruptRateEnv    = "CHAOS_CORRUPT_RATE"     // Per notification, truncate the payload of one of its events
+	chaosDuplicateRateEnv  = "CHAOS_DUPLICATE_RATE"   // Per notification, handle it twice
+	chaosSlowClientRateEnv = "CHAOS_SLOW_CLIENT_RATE" // Per WebSocket client, delay every write to it
+
+	// Environment variable overriding the longest a delayed notification is
+	// held back, e.g. 5s; the delay is drawn up to it
+	chaosMaxDelayEnv = "CHAOS_MAX_DELAY"
+
+	// Environment variable overriding the delay added to every write to a slow client, e.g. 2s
+	chaosSlowClientDelayEnv = "CHAOS_SLOW_CLIENT_DELAY"
+
+	// Delays used when the variables above are unset
+	defaultChaosMaxDelay        = 2 * time.Second
+	defaultChaosSlowClientDelay = 500 * time.Millisecond
+)
+
+// errChaosDisconnect is the error a disconnect injected by chaos mode is reported with
+var errChaosDisconnect = errors.New("connection dropped by chaos mode")
+
+// ChaosStats counts the faults injected by chaos mode
+type ChaosStats struct {
+	Seed        uint64 `json:"seed"`         // Seed of the fault decisions
+	Disconnects uint64 `json:"disconnects"`  // Upstream connections dropped
+	Delays      uint64 `json:"delays"`       // Notifications held back
+	Corruptions uint64 `json:"corruptions"`  // Payloads truncated
+	Duplicates  uint64 `json:"duplicates"`   // Notifications handled twice
+	SlowClients uint64 `json:"slow_clients"` // WebSocket clients whose writes are delayed
+}
+
+// ChaosInjector decides, from a seeded generator, which faults to inject
+// Upstream decisions are drawn in notification order on the listener
+// goroutine, so a seed replays the same faults for the same feed.
+type ChaosInjector struct {
+	seed            uint64
+	disconnectRate  float64
+	delayRate       float64
+	corruptRate     float64
+	duplicateRate   float64
+	slowClientRate  float64
+	maxDelay        time.Duration
+	slowClientDelay time.Duration
+
+	mutex  sync.Mutex
+	random *rand.Rand
+
+	disconnects atomic.Uint64
+	delays      atomic.Uint64
+	corruptions atomic.Uint64
+	duplicates  atomic.Uint64
+	slowClients atomic.Uint64
+}
+
+// Chaos injects faults when CHAOS_MODE is set, nil otherwise
+var Chaos *ChaosInjector
+
+// setupChaos enables fault injection when CHAOS_MODE is set
+//
+// Returns:
+//   - error: Error if a rate, a delay or the seed is invalid
+func setupChaos() error {
+	if !envBool(chaosModeEnv) {
+		return nil
+	}
+
+	injector := &ChaosInjector{seed: rand.Uint64()}
+	if value := os.Getenv(chaosSeedEnv); value != "" {
+		seed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", chaosSeedEnv, value)
+		}
+		injector.seed = seed
+	}
+	injector.random = rand.New(rand.NewPCG(injector.seed, injector.seed))
+
+	rates := []struct {
+		env  string
+		rate *float64
+	}{
+		{chaosDisconnectRateEnv, &injector.disconnectRate},
+		{chaosDelayRateEnv, &injector.delayRate},
+		{chaosCorruptRateEnv, &injector.corruptRate},
+		{chaosDuplicateRateEnv, &injector.duplicateRate},
+		{chaosSlowClientRateEnv, &injector.slowClientRate},
+	}
+	for _, entry := range rates {
+		value := os.Getenv(entry.env)
+		if value == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return fmt.Errorf("invalid %s %q, expected a number from 0 to 1", entry.env, value)
+		}
+		*entry.rate = rate
+	}
+
+	delays := []struct {
+		env      string
+		fallback time.Duration
+		delay    *time.Duration
+	}{
+		{chaosMaxDelayEnv, defaultChaosMaxDelay, &injector.maxDelay},
+		{chaosSlowClientDelayEnv, defaultChaosSlowClientDelay, &injector.slowClientDelay},
+	}
+	for _, entry := range delays {
+		delay, err := time.ParseDuration(envOrDefault(entry.env, entry.fallback.String()))
+		if err != nil || delay <= 0 {
+			return fmt.Errorf("invalid %s", entry.env)
+		}
+		*entry.delay = delay
+	}
+	Chaos = injector
+
+	slog.Warn("CHAOS MODE: injecting faults", "seed", injector.seed, "disconnect_rate", injector.disconnectRate,
+		"delay_rate", injector.delayRate, "max_delay", injector.maxDelay, "corrupt_rate", injector.corruptRate,
+		"duplicate_rate", injector.duplicateRate, "slow_client_rate", injector.slowClientRate, "slow_client_delay", injector.slowClientDelay)
+	return nil
+}
+
+// Stats returns the counters of the injected faults
+func (c *ChaosInjector) Stats() ChaosStats {
+	return ChaosStats{
+		Seed:        c.seed,
+		Disconnects: c.disconnects.Load(),
+		Delays:      c.delays.Load(),
+		Corruptions: c.corruptions.Load(),
+		Duplicates:  c.duplicates.Load(),
+		SlowClients: c.slowClients.Load(),
+	}
+}
+
+// roll reports whether a fault of the given rate happens
+// The generator is drawn from even at a zero rate, so enabling one fault
+// does not shift the decisions of the others.
+func (c *ChaosInjector) roll(rate float64) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.random.Float64() < rate
+}
+
+// draw returns a number in [0, n)
+func (c *ChaosInjector) draw(n int) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.random.IntN(n)
+}
+
+// run runs a listener, injecting the upstream faults into the notifications
+// it hands to handle
+// A dropped connection ends run as a lost connection would, and the caller
+// subscribes again after the reconnect delay.
+func (c *ChaosInjector) run(ctx context.Context, listener *pumpstream.Listener, handle func(pumpstream.Notification)) {
+	connection, disconnect := context.WithCancel(ctx)
+	defer disconnect()
+
+	listener.Run(connection, func(notification pumpstream.Notification) {
+		if connection.Err() != nil {
+			return
+		}
+		if c.roll(c.disconnectRate) {
+			c.disconnects.Add(1)
+			disconnect()
+			return
+		}
+		if c.roll(c.delayRate) {
+			c.delays.Add(1)
+			delay := time.Duration(c.draw(int(c.maxDelay)) + 1)
+			slog.Debug("Chaos mode delaying notification", logKeySignature, notification.Signature, "delay", delay)
+			select {
+			case <-connection.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+		if c.roll(c.corruptRate) {
+			notification = c.corrupt(notification)
+		}
+		duplicate := c.roll(c.duplicateRate)
+
+		handle(notification)
+		if duplicate {
+			c.duplicates.Add(1)
+			handle(notification)
+		}
+	})
+
+	if ctx.Err() == nil && listener.OnDisconnect != nil {
+		listener.OnDisconnect(errChaosDisconnect)
+	}
+}
+
+// corrupt truncates the payload of one event of a notification, leaving the
+// notification unchanged when it has none
+func (c *ChaosInjector) corrupt(notification pumpstream.Notification) pumpstream.Notification {
+	var payloads []int
+	for index, log := range notification.Logs {
+		if strings.HasPrefix(log, pumpstream.ProgramDataPrefix) {
+			payloads = append(payloads, index)
+		}
+	}
+	if len(payloads) == 0 {
+		return notification
+	}
+
+	index := payloads[c.draw(len(payloads))]
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(notification.Logs[index], pumpstream.ProgramDataPrefix))
+	if err != nil || len(data) < 2 {
+		return notification
+	}
+	data = data[:1+c.draw(len(data)-1)]
+
+	logs := append([]string{}, notification.Logs...)
+	logs[index] = pumpstream.ProgramDataPrefix + base64.StdEncoding.EncodeToString(data)
+	notification.Logs = logs
+	c.corruptions.Add(1)
+	slog.Debug("Chaos mode corrupted notification", logKeySignature, notification.Signature, "log", index)
+	return notification
+}
+
+// clientDelay returns the delay added to every write to a new WebSocket
+// client, zero unless it is picked as a slow client
+func (c *ChaosInjector) clientDelay() time.Duration {
+	if !c.roll(c.slowClientRate) {
+		return 0
+	}
+	c.slowClients.Add(1)
+	return c.slowClientDelay
+}
@@ -0,0 +1,819 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/spf13/cobra"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// globalOptions holds the flags shared by every subcommand
+type globalOptions struct {
+	wsURL     string // WebSocket RPC endpoint used for live subscriptions
+	rpcURL    string // HTTP RPC endpoint used for historical queries
+	logFormat string // Log format, text or json
+	logLevel  string // Minimum level logged
+
+	checkSinks bool // Whether to dial every sink endpoint before starting
+}
+
+// shutdownTracing flushes the spans buffered for export, set up before any subcommand runs
+var shutdownTracing = func() {}
+
+// flushErrorReports sends the error reports still queued, set up before any subcommand runs
+var flushErrorReports = func() {}
+
+// newRootCommand builds the command line interface
+// Running the binary without a subcommand serves the feed, as it always has
+func newRootCommand() *cobra.Command {
+	options := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:           "nova-feed",
+		Short:         "Real-time feed of pump.fun token launches, trades and graduations",
+		Version:       currentBuild.String(),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateConfig(options); err != nil {
+				return err
+			}
+			if err := setupLogging(options.logFormat, options.logLevel); err != nil {
+				return err
+			}
+			if err := setupCoreSettings(); err != nil {
+				return err
+			}
+			shutdown, err := setupTracing()
+			if err != nil {
+				return err
+			}
+			shutdownTracing = shutdown
+
+			flush, err := setupErrorReporting()
+			if err != nil {
+				return err
+			}
+			flushErrorReports = flush
+			return nil
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			shutdownTracing()
+			flushErrorReports()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(options, sourceUpstream, envOrDefault(listenAddrEnv, serverPort), os.Getenv(unixSocketEnv), os.Getenv(grpcAddrEnv), os.Getenv(adminAddrEnv))
+		},
+	}
+	root.PersistentFlags().String(configFileFlag, os.Getenv(configFileEnv), "YAML file of settings, keyed by lowercase environment variable name; the environment overrides it")
+	root.PersistentFlags().String(profileFlag, os.Getenv(profileEnv), "Profile of defaults for the environment: "+strings.Join(profileNames(), ", ")+"; the config file and the environment override it")
+	root.PersistentFlags().StringVar(&options.wsURL, "ws-url", os.Getenv(solanaWSURLEnv), "Solana WebSocket RPC endpoint; Helius with "+heliusAPIKeyEnv+" when empty")
+	root.PersistentFlags().StringVar(&options.rpcURL, "rpc-url", os.Getenv(solanaRPCURLEnv), "Solana HTTP RPC endpoint; Helius with "+heliusAPIKeyEnv+" when empty")
+	root.PersistentFlags().StringVar(&options.logFormat, "log-format", envOrDefault(logFormatEnv, logFormatText), "Log format: text or json")
+	root.PersistentFlags().StringVar(&options.logLevel, "log-level", envOrDefault(logLevelEnv, "info"), "Minimum log level: debug, info, warn or error")
+	root.PersistentFlags().BoolVar(&options.checkSinks, "check-sinks", os.Getenv(checkSinksEnv) == "true", "Dial every sink endpoint before starting, failing if one is unreachable")
+
+	root.AddCommand(
+		newServeCommand(options),
+		newTailCommand(options),
+		newRecordCommand(options),
+		newReplayCommand(),
+		newBackfillCommand(options),
+		newDecodeCommand(options),
+		newExportCommand(),
+	)
+	return root
+}
+
+// newServeCommand builds the serve subcommand: subscribe upstream and serve the
+// WebSocket feed and REST API
+func newServeCommand(options *globalOptions) *cobra.Command {
+	var source, addr, unixSocket, grpcAddr, adminAddr string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Subscribe to the chain and serve the WebSocket feed and REST API",
+		Long: "Subscribe to the chain and serve the WebSocket feed and REST API.\n" +
+			"To scale the number of WebSocket connections, run one ingester with " + redisBridgeChannelEnv + " set " +
+			"and any number of replicas with --source " + sourceRedis + " and the same channel.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(options, source, addr, unixSocket, grpcAddr, adminAddr)
+		},
+	}
+	cmd.Flags().StringVar(&source, "source", sourceUpstream, "Where notifications come from: "+sourceUpstream+", "+sourceRedis+", "+sourceHeliusWebhook+", "+sourceShredStream+" or "+sourceCluster+" for cluster peers only")
+	cmd.Flags().StringVar(&addr, "addr", envOrDefault(listenAddrEnv, serverPort), "TCP addresses to serve the public WebSocket feed and REST API on, comma-separated")
+	cmd.Flags().StringVar(&unixSocket, "unix-socket", os.Getenv(unixSocketEnv), "Unix domain socket path to also listen on")
+	cmd.Flags().StringVar(&grpcAddr, "grpc-addr", os.Getenv(grpcAddrEnv), "TCP address to serve gRPC on; disabled when empty")
+	cmd.Flags().StringVar(&adminAddr, "admin-addr", os.Getenv(adminAddrEnv), "TCP address of the private listener serving the admin API and profiling endpoints instead of the public one; disabled when empty")
+	return cmd
+}
+
+// runServe starts the listener and the HTTP server, returning once interrupted
+func runServe(options *globalOptions, source, addr, unixSocket, grpcAddr, adminAddr string) error {
+	// Keep stdout for events in pipe mode; must happen before anything is printed
+	if envBool(eventsStdoutEnv) {
+		enablePipeMode(nil)
+	}
+
+	slog.Info("Starting Nova Frontend Trial Task", "version", currentBuild)
+
+	// Everything started below stops once the process is interrupted
+	ctx := shutdownContext()
+
+	// Fail before anything starts when the upstream cannot be reached
+	wsURL := options.wsURL
+	if source == sourceUpstream {
+		resolved, err := resolveEndpoint(wsURL, heliusWebSocketURL)
+		if err != nil {
+			return err
+		}
+		wsURL = resolved
+	}
+
+	// Refuse to start with a decoder that fails on a known creation
+	if err := runSelfTest(ctx, options.wsURL); err != nil {
+		return err
+	}
+
+	// Inject faults into the feed and the clients in chaos mode
+	if err := setupChaos(); err != nil {
+		return fmt.Errorf("failed to set up chaos mode: %w", err)
+	}
+
+	addrs, err := parseListenAddrs(addr)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, err := serverTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	// Load the admin tokens before the admin API can be called
+	if err := setupAdminTokens(); err != nil {
+		return fmt.Errorf("failed to set up admin tokens: %w", err)
+	}
+
+	// Register outbound event sinks before any event can be observed
+	if err := setupSinks(); err != nil {
+		return fmt.Errorf("failed to set up event sinks: %w", err)
+	}
+
+	// Dump the state of the process on SIGUSR1
+	if err := setupDiagnosticDump(); err != nil {
+		return fmt.Errorf("failed to set up diagnostic dumps: %w", err)
+	}
+
+	// Capture profiles when goroutines or the heap grow past their thresholds
+	if err := setupWatchdog(); err != nil {
+		return fmt.Errorf("failed to set up the watchdog: %w", err)
+	}
+
+	// Pick up rotated secrets from Vault or AWS Secrets Manager
+	if err := setupSecretRotation(); err != nil {
+		return fmt.Errorf("failed to set up secret rotation: %w", err)
+	}
+
+	// Reload settings on SIGHUP instead of terminating
+	if err := setupConfigReload(); err != nil {
+		return fmt.Errorf("failed to set up configuration reload: %w", err)
+	}
+
+	// Locate clients before any can connect
+	if err := setupGeoIP(); err != nil {
+		return fmt.Errorf("failed to set up GeoIP: %w", err)
+	}
+
+	// Refuse denied addresses before any client can connect
+	if err := setupIPAccess(); err != nil {
+		return fmt.Errorf("failed to set up IP access lists: %w", err)
+	}
+	if err := setupAutoBan(); err != nil {
+		return fmt.Errorf("failed to set up automatic bans: %w", err)
+	}
+
+	// Record client connections, possibly in the storage backend set up above
+	if err := setupAuditLog(); err != nil {
+		return fmt.Errorf("failed to set up the connection audit log: %w", err)
+	}
+
+	// Alert when the feed goes quiet
+	if err := setupStaleFeedAlerts(); err != nil {
+		return fmt.Errorf("failed to set up stale feed alerts: %w", err)
+	}
+
+	// Keep pricing tokens once they leave their bonding curve
+	if err := setupJupiterPrices(); err != nil {
+		return fmt.Errorf("failed to set up Jupiter prices: %w", err)
+	}
+
+	// Share the results of the enrichment RPC calls below
+	if err := setupRPCCache(); err != nil {
+		return fmt.Errorf("failed to set up the RPC cache: %w", err)
+	}
+
+	// Add market data to events, before any is processed
+	if err := setupMarketData(); err != nil {
+		return fmt.Errorf("failed to set up market data enrichment: %w", err)
+	}
+	if err := setupTokenSupply(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up token supply enrichment: %w", err)
+	}
+	if err := setupLPVerification(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up LP verification: %w", err)
+	}
+	if err := setupHolderConcentration(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up holder concentration: %w", err)
+	}
+	if err := setupFundingTrace(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up funding tracing: %w", err)
+	}
+	if err := setupFilterScripts(); err != nil {
+		return fmt.Errorf("failed to set up filter scripts: %w", err)
+	}
+	if err := setupImageProxy(); err != nil {
+		return fmt.Errorf("failed to set up the image proxy: %w", err)
+	}
+
+	// Recover the creations whose logs were truncated
+	if err := setupTruncatedLogFallback(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up the truncated log fallback: %w", err)
+	}
+
+	// Follow previewed creations to confirmation, reverting the others
+	if err := setupConfirmationTracking(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up confirmation tracking: %w", err)
+	}
+
+	// Build trade transactions for clients to sign
+	if err := setupPriorityFees(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up priority fee estimation: %w", err)
+	}
+	if err := setupTransactionBuilder(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up the transaction builder: %w", err)
+	}
+	if err := setupWallet(options.rpcURL); err != nil {
+		return fmt.Errorf("failed to set up the wallet: %w", err)
+	}
+	if err := setupAutoBuy(); err != nil {
+		return fmt.Errorf("failed to set up auto-buy: %w", err)
+	}
+	if err := setupPortfolios(); err != nil {
+		return fmt.Errorf("failed to set up portfolio tracking: %w", err)
+	}
+
+	// Join the cluster before ingesting so peers see every local notification
+	if err := setupCluster(); err != nil {
+		return fmt.Errorf("failed to set up cluster mode: %w", err)
+	}
+
+	// Report readiness and liveness when running as a systemd service
+	if err := setupSystemdNotify(); err != nil {
+		return fmt.Errorf("failed to set up systemd notifications: %w", err)
+	}
+
+	var ingestion <-chan struct{}
+	switch source {
+	case sourceUpstream:
+		handle, err := ingesterHandle()
+		if err != nil {
+			return err
+		}
+
+		// Compare the subscription with an independent RPC endpoint
+		if err := setupSlotLagMonitor(); err != nil {
+			return fmt.Errorf("failed to set up slot lag monitoring: %w", err)
+		}
+
+		// Start the Solana event listener in background
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			listenToNewPairs(ctx, wsURL, handle)
+		}()
+		ingestion = stopped
+	case sourceHeliusWebhook:
+		if httpDisabled() {
+			return fmt.Errorf("the %s source needs the HTTP server to receive deliveries", sourceHeliusWebhook)
+		}
+		handle, err := ingesterHandle()
+		if err != nil {
+			return err
+		}
+		if err := setupHeliusWebhook(handle); err != nil {
+			return err
+		}
+	case sourceShredStream:
+		stopped, err := listenToShredStream(ctx)
+		if err != nil {
+			return err
+		}
+		ingestion = stopped
+	case sourceCluster:
+		if Cluster == nil {
+			return fmt.Errorf("the %s source needs cluster mode, enabled with %s", sourceCluster, clusterAdvertiseURLEnv)
+		}
+		// Peers deliver the notifications, also handed to replicas of a bridge
+		if _, err := ingesterHandle(); err != nil {
+			return err
+		}
+		// The upstream of this node is its peers: it is ready once one answered
+		go func() {
+			select {
+			case <-Cluster.Joined():
+				FeedStats.RecordUpstreamConnected()
+			case <-ctx.Done():
+			}
+		}()
+	case sourceRedis:
+		stopped, err := listenToRedisBridge(ctx, processNotification)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to the Redis bridge: %w", err)
+		}
+		ingestion = stopped
+	default:
+		return fmt.Errorf("unknown source %q", source)
+	}
+
+	// Stop ingesting first, then deliver what the sinks still hold
+	defer finishShutdown(ingestion)
+
+	// Without the HTTP server, only run until interrupted
+	if httpDisabled() {
+		<-ctx.Done()
+		return nil
+	}
+
+	if grpcAddr != "" {
+		grpcServer, err := startGRPCServer(grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start gRPC server: %w", err)
+		}
+		defer grpcServer.Stop()
+	}
+
+	if adminAddr != "" {
+		adminServer, err := startAdminServer(adminAddr)
+		if err != nil {
+			return fmt.Errorf("failed to start admin listener: %w", err)
+		}
+		defer adminServer.Close()
+	}
+
+	// Start the HTTP server (this will block until server stops)
+	return startServer(ctx, addrs, unixSocket, tlsConfig)
+}
+
+// ingesterHandle returns the function handling the notifications this instance
+// ingests itself: it also hands them to replicas when this instance is a bridge
+// ingester, and to peers in cluster mode
+func ingesterHandle() (func(pumpstream.Notification), error) {
+	bridge, err := newRedisBridge()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the Redis bridge: %w", err)
+	}
+	handle := processNotification
+	if bridge != nil {
+		handle = func(notification pumpstream.Notification) {
+			bridge.Publish(notification)
+			processNotification(notification)
+		}
+	}
+	if Cluster != nil {
+		handle = Cluster.Wrap(handle)
+	}
+	return handle, nil
+}
+
+// newTailCommand builds the tail subcommand: write live events to stdout as
+// newline-delimited JSON, or watch them on a terminal dashboard, without
+// starting the HTTP server
+func newTailCommand(options *globalOptions) *cobra.Command {
+	var types []string
+	var tui bool
+	var server string
+
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Write live events to stdout as newline-delimited JSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			eventTypes, err := parseEventTypes(types)
+			if err != nil {
+				return err
+			}
+			wsURL, err := resolveEndpoint(options.wsURL, heliusWebSocketURL)
+			if err != nil {
+				return err
+			}
+
+			if tui {
+				ctx := shutdownContext()
+				go listenToNewPairs(ctx, wsURL, processNotification)
+				return runTUI(ctx, server)
+			}
+			if server != "" {
+				return fmt.Errorf("--server is only used with --tui")
+			}
+			enablePipeMode(eventTypes)
+
+			if err := setupStdoutSink(); err != nil {
+				return err
+			}
+			ctx := shutdownContext()
+			go listenToNewPairs(ctx, wsURL, processNotification)
+
+			<-ctx.Done()
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&types, "types", nil, "Event types to write (create, trade, complete); all when empty")
+	cmd.Flags().BoolVar(&tui, "tui", false, "Render a live dashboard of creations, launch rate and upstream health instead of writing events")
+	cmd.Flags().StringVar(&server, "server", "", "Base URL of a running instance whose connected clients the dashboard counts, e.g. http://localhost:8080")
+	return cmd
+}
+
+// newRecordCommand builds the record subcommand: save raw upstream notifications
+// for later replay
+func newRecordCommand(options *globalOptions) *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record raw upstream notifications to a file for later replay",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wsURL, err := resolveEndpoint(options.wsURL, heliusWebSocketURL)
+			if err != nil {
+				return err
+			}
+
+			output := os.Stdout
+			if out != "-" {
+				file, err := os.OpenFile(out, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+				if err != nil {
+					return fmt.Errorf("failed to open recording: %w", err)
+				}
+				defer file.Close()
+				output = file
+			}
+
+			// Status messages must not end up in a recording written to stdout
+			os.Stdout = os.Stderr
+
+			recorder := NewRecorder(output)
+			ctx := shutdownContext()
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				listenToNewPairs(ctx, wsURL, recorder.Record)
+			}()
+
+			// The file is closed only once nothing records to it any more
+			<-ctx.Done()
+			<-stopped
+			slog.Info("Recording finished", "notifications", recorder.Count())
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "-", "File to append the recording to, - for stdout")
+	return cmd
+}
+
+// newReplayCommand builds the replay subcommand: feed a recording through the
+// normal processing pipeline
+func newReplayCommand() *cobra.Command {
+	var speed float64
+	var ndjson bool
+	var addr string
+
+	cmd := &cobra.Command{
+		Use:   "replay <recording>",
+		Short: "Replay a recording through the configured sinks",
+		Long: "Replay a recording made with record through the configured sinks.\n" +
+			"With --addr the HTTP server is started too and keeps running after the replay, " +
+			"so WebSocket clients and the REST API see the replayed events.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if ndjson {
+				enablePipeMode(nil)
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open recording: %w", err)
+			}
+			defer file.Close()
+
+			if err := setupSinks(); err != nil {
+				return fmt.Errorf("failed to set up event sinks: %w", err)
+			}
+
+			if addr == "" {
+				count, err := replayRecording(file, speed, processNotification)
+				drainSinks()
+				slog.Info("Replay finished", "notifications", count)
+				return err
+			}
+
+			addrs, err := parseListenAddrs(addr)
+			if err != nil {
+				return err
+			}
+			tlsConfig, err := serverTLSConfig()
+			if err != nil {
+				return err
+			}
+			go func() {
+				count, err := replayRecording(file, speed, processNotification)
+				if err != nil {
+					slog.Warn("Replay stopped", logKeyError, err)
+				}
+				slog.Info("Replay finished", "notifications", count)
+			}()
+			err = startServer(shutdownContext(), addrs, "", tlsConfig)
+			drainSinks()
+			return err
+		},
+	}
+	cmd.Flags().Float64Var(&speed, "speed", 0, "Playback speed relative to the recording (1 is real time); 0 replays as fast as possible")
+	cmd.Flags().BoolVar(&ndjson, "ndjson", false, "Write replayed events to stdout as newline-delimited JSON")
+	cmd.Flags().StringVar(&addr, "addr", "", "Also serve the WebSocket feed and REST API on this address")
+	return cmd
+}
+
+// newBackfillCommand builds the backfill subcommand: fetch recent program
+// transactions over RPC and process them like live notifications
+func newBackfillCommand(options *globalOptions) *cobra.Command {
+	var limit int
+	var before, until string
+	var ndjson bool
+
+	cmd := &cobra.Command{
+		Use:   "backfill",
+		Short: "Fetch recent PumpFun transactions over RPC and process them through the configured sinks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit <= 0 {
+				return fmt.Errorf("--limit must be positive")
+			}
+
+			var beforeSignature, untilSignature solana.Signature
+			var err error
+			if before != "" {
+				if beforeSignature, err = solana.SignatureFromBase58(before); err != nil {
+					return fmt.Errorf("invalid --before signature: %w", err)
+				}
+			}
+			if until != "" {
+				if untilSignature, err = solana.SignatureFromBase58(until); err != nil {
+					return fmt.Errorf("invalid --until signature: %w", err)
+				}
+			}
+
+			endpoint, err := resolveEndpoint(options.rpcURL, heliusRPCURL)
+			if err != nil {
+				return err
+			}
+
+			if ndjson {
+				enablePipeMode(nil)
+			}
+			if err := setupSinks(); err != nil {
+				return fmt.Errorf("failed to set up event sinks: %w", err)
+			}
+
+			count, err := backfill(rpc.New(endpoint), limit, beforeSignature, untilSignature, processNotification)
+			drainSinks()
+			slog.Info("Backfill finished", "transactions", count)
+			return err
+		},
+	}
+	cmd.Flags().IntVar(&limit, "limit", 1000, "Maximum number of transactions to fetch")
+	cmd.Flags().StringVar(&before, "before", "", "Start searching backwards from this transaction signature")
+	cmd.Flags().StringVar(&until, "until", "", "Stop at this transaction signature")
+	cmd.Flags().BoolVar(&ndjson, "ndjson", false, "Write backfilled events to stdout as newline-delimited JSON")
+	return cmd
+}
+
+// newExportCommand builds the export subcommand: write stored events to
+// partitioned Parquet files for offline analytics
+func newExportCommand() *cobra.Command {
+	var out, from, to string
+	var types []string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export stored events to Parquet files partitioned by event type and day",
+		Long: "Export stored events to Parquet files partitioned by event type and day.\n" +
+			"Files are written as <out>/type=<type>/dt=<YYYY-MM-DD>/" + parquetFileName + ", a layout DuckDB, Athena and Spark " +
+			"read as Hive partitions. Events are read from the storage backend configured with " + sqlitePathEnv + " or " + postgresURLEnv + ".\n" +
+			"Exporting a day again replaces its files, so a daily job can run with --from yesterday --to today.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			eventTypes, err := parseEventTypes(types)
+			if err != nil {
+				return err
+			}
+			query := EventQuery{Types: eventTypes}
+			if query.From, err = parseTimeBound(from); err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+			if query.To, err = parseTimeBound(to); err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+
+			if err := setupStorage(); err != nil {
+				return fmt.Errorf("failed to open storage: %w", err)
+			}
+			if Persistence == nil {
+				return fmt.Errorf("export reads stored events; set %s or %s", sqlitePathEnv, postgresURLEnv)
+			}
+
+			count, err := exportParquet(Persistence, query, out)
+			slog.Info("Export finished", "events", count, "path", out)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "export", "Root directory of the partitioned files")
+	cmd.Flags().StringVar(&from, "from", "", "Earliest time to export, as YYYY-MM-DD or RFC 3339")
+	cmd.Flags().StringVar(&to, "to", "", "Time to stop before, as YYYY-MM-DD or RFC 3339")
+	cmd.Flags().StringSliceVar(&types, "types", nil, "Event types to export (create, trade, complete); all when empty")
+	return cmd
+}
+
+// decodedPayload is a "Program data" payload as printed by the decode subcommand
+type decodedPayload struct {
+	Signature string      `json:"signature,omitempty"` // Transaction the payload was logged by, with --signature
+	Slot      uint64      `json:"slot,omitempty"`      // Slot of the transaction, with --signature
+	Index     *int        `json:"index,omitempty"`     // Position of the log in the transaction, with --signature
+	Type      EventType   `json:"type,omitempty"`      // Kind of the decoded event
+	Event     interface{} `json:"event,omitempty"`     // Decoded event
+	Error     string      `json:"error,omitempty"`     // Why a payload of the transaction did not decode, with --signature
+}
+
+// newDecodeCommand builds the decode subcommand: decode "Program data" blobs
+// offline, or the payloads logged by transactions fetched over RPC
+func newDecodeCommand(options *globalOptions) *cobra.Command {
+	var signatures bool
+
+	cmd := &cobra.Command{
+		Use:   "decode <program data>...",
+		Short: "Decode base64 \"Program data\" log payloads and print them as JSON",
+		Long: "Decode base64 \"Program data\" log payloads and print them as JSON, with the decoder the server uses.\n" +
+			"With --signature, the arguments are transaction signatures: each transaction is fetched over RPC and every " +
+			"payload it logged is printed, those that fail to decode with the error and their discriminator.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			if !signatures {
+				for _, arg := range args {
+					eventType, event, err := decodeProgramData(arg)
+					if err != nil {
+						return err
+					}
+					if err := encoder.Encode(decodedPayload{Type: eventType, Event: event}); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			endpoint, err := resolveEndpoint(options.rpcURL, heliusRPCURL)
+			if err != nil {
+				return err
+			}
+			client := rpc.New(endpoint)
+			for _, arg := range args {
+				payloads, err := decodeTransaction(client, arg)
+				if err != nil {
+					return err
+				}
+				for _, payload := range payloads {
+					if err := encoder.Encode(payload); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&signatures, "signature", false, "Treat the arguments as transaction signatures to fetch over RPC")
+	return cmd
+}
+
+// decodeTransaction fetches a transaction and decodes every "Program data"
+// payload it logged
+// Payloads that fail to decode are returned with their error rather than
+// failing the transaction, as finding them is what the command is for.
+//
+// Parameters:
+//   - client: RPC client to fetch the transaction with
+//   - signature: Base58 transaction signature
+//
+// Returns:
+//   - []decodedPayload: Payloads in log order
+//   - error: Error if the signature is invalid or the transaction cannot be fetched
+func decodeTransaction(client *rpc.Client, signature string) ([]decodedPayload, error) {
+	parsed, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %q: %w", signature, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backfillRequestTimeout)
+	defer cancel()
+	maxVersion := uint64(0)
+	transaction, err := client.GetTransaction(ctx, parsed, &rpc.GetTransactionOpts{
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction %s: %w", signature, err)
+	}
+	if transaction.Meta == nil {
+		return nil, fmt.Errorf("transaction %s has no logs", signature)
+	}
+
+	var payloads []decodedPayload
+	for index, log := range transaction.Meta.LogMessages {
+		if !strings.HasPrefix(log, pumpstream.ProgramDataPrefix) {
+			continue
+		}
+		payload := decodedPayload{Signature: signature, Slot: transaction.Slot, Index: &index}
+		if payload.Type, payload.Event, err = decodeProgramData(log); err != nil {
+			payload.Error = err.Error()
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
+
+// decodeProgramData decodes a base64 program data payload, with or without the
+// "Program data: " log prefix, into the raw event it contains
+//
+// Parameters:
+//   - data: Base64 payload or full log line
+//
+// Returns:
+//   - EventType: Kind of the decoded event
+//   - interface{}: *pumpstream.CreateEvent, *pumpstream.TradeEvent or *pumpstream.CompleteEvent
+//   - error: Error if the payload is invalid or not a tracked event
+func decodeProgramData(data string) (EventType, interface{}, error) {
+	if _, payload, found := strings.Cut(data, pumpstream.ProgramDataPrefix); found {
+		data = payload
+	}
+
+	data = strings.TrimSpace(data)
+	event, err := pumpstream.DecodeProgramData(data)
+	if errors.Is(err, pumpstream.ErrUnknownEvent) {
+		// Name the discriminator, to tell a new event from a corrupted payload
+		if raw, decodeErr := base64.StdEncoding.DecodeString(data); decodeErr == nil {
+			return "", nil, fmt.Errorf("%w %x", err, raw[:min(len(raw), len(pumpstream.CreateDiscriminator))])
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch event.(type) {
+	case *pumpstream.CreateEvent:
+		return EventCreate, event, nil
+	case *pumpstream.TradeEvent:
+		return EventTrade, event, nil
+	default:
+		return EventComplete, event, nil
+	}
+}
+
+// parseEventTypes validates a list of event type names
+// An empty list selects every type and is returned as nil
+func parseEventTypes(names []string) ([]EventType, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	types := make([]EventType, 0, len(names))
+	for _, name := range names {
+		switch eventType := EventType(name); eventType {
+		case EventCreate, EventTrade, EventComplete, EventPrice, EventMigration, EventRevert:
+			types = append(types, eventType)
+		default:
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+	}
+	return types, nil
+}
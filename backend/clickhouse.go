@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the ClickHouse HTTP interface URL, e.g. http://localhost:8123
+	// The ClickHouse sink is disabled when it is unset
+	clickHouseURLEnv = "CLICKHOUSE_URL"
+
+	// Environment variables selecting the database and credentials
+	clickHouseDatabaseEnv = "CLICKHOUSE_DATABASE"
+	clickHouseUserEnv     = "CLICKHOUSE_USER"
+	clickHousePasswordEnv = "CLICKHOUSE_PASSWORD"
+
+	// Database used when CLICKHOUSE_DATABASE is unset
+	defaultClickHouseDatabase = "default"
+
+	// Tables written by the sink, see schema/clickhouse.sql
+	clickHouseCreationsTable = "pumpfun_creations"
+	clickHouseTradesTable    = "pumpfun_trades"
+
+	// Layout of DateTime64(3) values in JSONEachRow input
+	clickHouseTimeLayout = "2006-01-02 15:04:05.000"
+)
+
+// clickHouseSchema creates the tables written by the sink
+//
+//go:embed schema/clickhouse.sql
+var clickHouseSchema string
+
+// clickHouseCreation is a row of the creations table
+type clickHouseCreation struct {
+	Mint         string `json:"mint"`
+	Name         string `json:"name"`
+	Symbol       string `json:"symbol"`
+	Uri          string `json:"uri"`
+	BondingCurve string `json:"bonding_curve"`
+	Creator      string `json:"creator"`
+	Signature    string `json:"signature"`
+	Slot         uint64 `json:"slot"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// clickHouseTrade is a row of the trades table
+type clickHouseTrade struct {
+	Mint                 string  `json:"mint"`
+	Signature            string  `json:"signature"`
+	Slot                 uint64  `json:"slot"`
+	Trader               string  `json:"trader"`
+	IsBuy                bool    `json:"is_buy"`
+	SolAmount            uint64  `json:"sol_amount"`
+	TokenAmount          uint64  `json:"token_amount"`
+	VirtualSolReserves   uint64  `json:"virtual_sol_reserves"`
+	VirtualTokenReserves uint64  `json:"virtual_token_reserves"`
+	MarketCapSOL         float64 `json:"market_cap_sol"`
+	TradedAt             string  `json:"traded_at"`
+}
+
+// ClickHouseSink writes creations and trades to ClickHouse in batches through
+// its HTTP interface, keeping analytical queries off the serving path
+type ClickHouseSink struct {
+	*eventBatcher
+	endpoint string
+	database string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// setupClickHouseSink registers the ClickHouse sink when CLICKHOUSE_URL is set
+func setupClickHouseSink() error {
+	endpoint := os.Getenv(clickHouseURLEnv)
+	if endpoint == "" {
+		return nil
+	}
+
+	sink := &ClickHouseSink{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		database: envOrDefault(clickHouseDatabaseEnv, defaultClickHouseDatabase),
+		user:     os.Getenv(clickHouseUserEnv),
+		password: os.Getenv(clickHousePasswordEnv),
+		client:   &http.Client{Timeout: webhookRequestTimeout},
+	}
+
+	for _, statement := range strings.Split(stripSQLComments(clickHouseSchema), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if err := sink.exec(statement, nil); err != nil {
+			return fmt.Errorf("failed to create ClickHouse tables: %w", err)
+		}
+	}
+
+	sink.eventBatcher = newEventBatcher("clickhouse", sink.writeBatch)
+	RegisterSink(sink)
+
+	slog.Info("Writing creations and trades to ClickHouse", "database", sink.database)
+	return nil
+}
+
+// writeBatch inserts the creations and trades of a batch; completions are skipped
+func (s *ClickHouseSink) writeBatch(batch []Event) error {
+	var creations, trades bytes.Buffer
+	creationEncoder := json.NewEncoder(&creations)
+	tradeEncoder := json.NewEncoder(&trades)
+
+	for _, event := range batch {
+		switch data := event.Data.(type) {
+		case CreateEvent:
+			// Creator and bonding curve are not part of CreateEvent; take them from the store
+			record, _ := Tokens.Get(data.Mint)
+			creationEncoder.Encode(clickHouseCreation{
+				Mint:         data.Mint,
+				Name:         data.Name,
+				Symbol:       data.Symbol,
+				Uri:          data.Uri,
+				BondingCurve: record.BondingCurve,
+				Creator:      record.Creator,
+				Signature:    event.Signature,
+				Slot:         event.Slot,
+				CreatedAt:    event.ReceivedAt.UTC().Format(clickHouseTimeLayout),
+			})
+		case TradeEvent:
+			curve := CurveState{VirtualSolReserves: data.VirtualSolReserves, VirtualTokenReserves: data.VirtualTokenReserves}
+			tradeEncoder.Encode(clickHouseTrade{
+				Mint:                 data.Mint,
+				Signature:            event.Signature,
+				Slot:                 event.Slot,
+				Trader:               data.User,
+				IsBuy:                data.IsBuy,
+				SolAmount:            data.SolAmount,
+				TokenAmount:          data.TokenAmount,
+				VirtualSolReserves:   data.VirtualSolReserves,
+				VirtualTokenReserves: data.VirtualTokenReserves,
+				MarketCapSOL:         curve.MarketCapSOL(),
+				TradedAt:             time.Unix(data.Timestamp, 0).UTC().Format(clickHouseTimeLayout),
+			})
+		}
+	}
+
+	if creations.Len() > 0 {
+		if err := s.exec("INSERT INTO "+clickHouseCreationsTable+" FORMAT JSONEachRow", &creations); err != nil {
+			return err
+		}
+	}
+	if trades.Len() > 0 {
+		if err := s.exec("INSERT INTO "+clickHouseTradesTable+" FORMAT JSONEachRow", &trades); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exec runs a query through the HTTP interface, with body as the insert data if non-nil
+func (s *ClickHouseSink) exec(query string, body io.Reader) error {
+	params := url.Values{"database": {s.database}}
+	if body == nil {
+		// Without insert data the query itself is the request body
+		body = strings.NewReader(query)
+	} else {
+		params.Set("query", query)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, s.endpoint+"/?"+params.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if s.user != "" {
+		request.SetBasicAuth(s.user, s.password)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("ClickHouse responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// stripSQLComments removes -- line comments from a SQL script
+func stripSQLComments(script string) string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if code, _, _ := strings.Cut(line, "--"); strings.TrimSpace(code) != "" {
+			lines = append(lines, code)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the comma-separated base URLs of the peers to
+	// join at startup, e.g. http://node-b:8080,http://node-c:8080
+	clusterPeersEnv = "CLUSTER_PEERS"
+
+	// Environment variable with the base URL other nodes reach this node at
+	// Cluster mode is disabled when it is unset
+	clusterAdvertiseURLEnv = "CLUSTER_ADVERTISE_URL"
+
+	// Environment variable with the shared secret nodes authenticate with
+	clusterSecretEnv = "CLUSTER_SECRET"
+
+	// Environment variable naming the region of this node, e.g. us-east,
+	// reported with the notifications it ingests
+	clusterRegionEnv = "CLUSTER_REGION"
+
+	// Environment variable making this node a relay: when true, notifications
+	// forwarded by a peer are forwarded again to the other peers, so regional
+	// nodes that only know the relay receive what every region ingested
+	clusterRelayEnv = "CLUSTER_RELAY"
+
+	// Source of a node processing only what its cluster peers forward, such
+	// as a central aggregator
+	sourceCluster = "cluster"
+
+	// Endpoints used between cluster nodes
+	clusterNotificationsEndpoint = "/cluster/notifications"
+	clusterGossipEndpoint        = "/cluster/gossip"
+	clusterPeersEndpoint         = "/cluster/peers"
+	clusterRegionsEndpoint       = "/cluster/regions"
+
+	// Interval between membership exchanges with every known peer
+	clusterGossipInterval = 10 * time.Second
+
+	// Peers learned through gossip are forgotten after failing for this long;
+	// peers from CLUSTER_PEERS are kept forever
+	clusterPeerExpiry = 5 * time.Minute
+
+	// Number of notifications buffered per peer before new ones are dropped
+	clusterQueueSize = 10000
+
+	// Number of recent transaction signatures remembered exactly to drop
+	// duplicates when several nodes ingest the same transaction
+	clusterSeenSize = 100000
+
+	// Largest request accepted from a peer, a notification or a membership list
+	clusterMaxBodySize = 1 << 20
+)
+
+// clusterMessage carries a notification ingested by the origin node
+type clusterMessage struct {
+	Origin       string                  `json:"origin"`           // Advertise URL of the ingesting node
+	Region       string                  `json:"region,omitempty"` // Region of the ingesting node
+	Via          []string                `json:"via,omitempty"`    // Advertise URLs of the relays it went through
+	Notification pumpstream.Notification `json:"notification"`     // Notification as received from upstream
+}
+
+// clusterMembership is exchanged between nodes to discover each other
+type clusterMembership struct {
+	URL    string   `json:"url"`              // Advertise URL of the sending node
+	Region string   `json:"region,omitempty"` // Region of the sending node
+	Peers  []string `json:"peers"`            // Every peer known to the sending node, none for relays
+}
+
+// ClusterPeerStatus reports the state of a peer
+type ClusterPeerStatus struct {
+	URL        string    `json:"url"`                   // Base URL of the peer
+	Region     string    `json:"region,omitempty"`      // Region the peer announced
+	Static     bool      `json:"static"`                // True for peers from CLUSTER_PEERS
+	LastSeenAt time.Time `json:"last_seen_at,omitzero"` // Time the peer last answered
+	LastError  string    `json:"last_error,omitempty"`  // Most recent error talking to the peer
+	Forwarded  uint64    `json:"forwarded"`             // Notifications delivered to the peer
+	Dropped    uint64    `json:"dropped"`               // Notifications dropped because the queue was full
+	Pending    int       `json:"pending"`               // Notifications waiting in the queue
+	JoinedAt   time.Time `json:"joined_at"`             // Time the peer became known
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`   // Time a failing gossip peer will be forgotten
+}
+
+// ClusterRegionStatus reports how often each region delivered a transaction
+// first, telling which regions ingest closest to the chain
+type ClusterRegionStatus struct {
+	Region     string `json:"region"`     // Region, or the advertise URL of a node without one
+	First      uint64 `json:"first"`      // Transactions this region delivered before any other
+	Duplicates uint64 `json:"duplicates"` // Transactions another region had already delivered
+}
+
+// clusterPeer is a known peer with its forwarding queue
+type clusterPeer struct {
+	status ClusterPeerStatus
+	queue  chan clusterMessage
+	stop   chan struct{}
+}
+
+// ClusterNode forwards locally ingested notifications to its peers and
+// processes the notifications they forward
+// Each node processes every transaction once, whichever node saw it first.
+// Nodes either form a mesh, every node knowing every other through gossip, or
+// a star around relays: regional ingesters list the relay in CLUSTER_PEERS,
+// and the relay, which announces no peers, forwards between them.
+type ClusterNode struct {
+	url    string
+	region string
+	relay  bool
+	secret string
+	client *http.Client
+
+	mutex   sync.Mutex
+	peers   map[string]*clusterPeer
+	regions map[string]*ClusterRegionStatus
+	seen    *dedupCache
+	process func(pumpstream.Notification)
+
+	joined     chan struct{}
+	joinedOnce sync.Once
+}
+
+// Cluster is the cluster membership of this node, nil when cluster mode is disabled
+var Cluster *ClusterNode
+
+// setupCluster joins the cluster when CLUSTER_ADVERTISE_URL is set
+func setupCluster() error {
+	advertise := strings.TrimSuffix(os.Getenv(clusterAdvertiseURLEnv), "/")
+	if advertise == "" {
+		return nil
+	}
+
+	secret := os.Getenv(clusterSecretEnv)
+	if secret == "" {
+		return fmt.Errorf("%s must be set in cluster mode", clusterSecretEnv)
+	}
+
+	node := &ClusterNode{
+		url:     advertise,
+		region:  os.Getenv(clusterRegionEnv),
+		relay:   envBool(clusterRelayEnv),
+		secret:  secret,
+		client:  &http.Client{Timeout: webhookRequestTimeout},
+		peers:   make(map[string]*clusterPeer),
+		regions: make(map[string]*ClusterRegionStatus),
+		seen:    newDedupCache("cluster", clusterSeenSize),
+		process: processNotification,
+		joined:  make(chan struct{}),
+	}
+	for _, peer := range strings.Split(os.Getenv(clusterPeersEnv), ",") {
+		node.addPeer(strings.TrimSuffix(strings.TrimSpace(peer), "/"), true)
+	}
+	go node.gossipLoop()
+	Cluster = node
+
+	role := "node"
+	if node.relay {
+		role = "relay"
+	}
+	slog.Info("Cluster mode enabled", "role", role, "advertise", advertise, "static_peers", len(node.peers))
+	return nil
+}
+
+// Wrap returns a notification handler that processes a locally ingested
+// notification with handle and forwards it to every peer
+// Notifications already received from a peer are skipped; notifications
+// forwarded by peers are processed with handle too
+func (c *ClusterNode) Wrap(handle func(pumpstream.Notification)) func(pumpstream.Notification) {
+	c.process = handle
+	return func(notification pumpstream.Notification) {
+		message := clusterMessage{Origin: c.url, Region: c.region, Notification: notification}
+		first := c.seen.Add(notification.Signature)
+		c.countDelivery(message, first)
+		if !first {
+			return
+		}
+		handle(notification)
+		c.forward(message)
+	}
+}
+
+// Joined returns a channel closed once a peer first answered or contacted this node
+func (c *ClusterNode) Joined() <-chan struct{} {
+	return c.joined
+}
+
+// markJoined records that a peer answered or contacted this node
+func (c *ClusterNode) markJoined() {
+	c.joinedOnce.Do(func() { close(c.joined) })
+}
+
+// forward queues a message for every peer it did not come from
+func (c *ClusterNode) forward(message clusterMessage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for url, peer := range c.peers {
+		if url == message.Origin || slices.Contains(message.Via, url) {
+			continue
+		}
+		select {
+		case peer.queue <- message:
+		default:
+			peer.status.Dropped++
+		}
+	}
+}
+
+// countDelivery records whether the region of a message delivered its
+// transaction first
+func (c *ClusterNode) countDelivery(message clusterMessage, first bool) {
+	region := message.Region
+	if region == "" {
+		region = message.Origin
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status, found := c.regions[region]
+	if !found {
+		status = &ClusterRegionStatus{Region: region}
+		c.regions[region] = status
+	}
+	if first {
+		status.First++
+	} else {
+		status.Duplicates++
+	}
+}
+
+// Regions returns the delivery counts of every region seen
+func (c *ClusterNode) Regions() []ClusterRegionStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	statuses := make([]ClusterRegionStatus, 0, len(c.regions))
+	for _, status := range c.regions {
+		statuses = append(statuses, *status)
+	}
+	slices.SortFunc(statuses, func(a, b ClusterRegionStatus) int {
+		return strings.Compare(a.Region, b.Region)
+	})
+	return statuses
+}
+
+// addPeer starts forwarding to a peer unless it is already known or is this node
+// Must not be called with the mutex held
+func (c *ClusterNode) addPeer(url string, static bool) {
+	if url == "" || url == c.url {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, known := c.peers[url]; known {
+		return
+	}
+
+	peer := &clusterPeer{
+		status: ClusterPeerStatus{URL: url, Static: static, JoinedAt: time.Now().UTC()},
+		queue:  make(chan clusterMessage, clusterQueueSize),
+		stop:   make(chan struct{}),
+	}
+	c.peers[url] = peer
+	go c.forwardLoop(peer)
+
+	slog.Info("Cluster peer joined", "peer", url)
+}
+
+// forwardLoop delivers queued notifications to a peer until it is removed
+// Delivery is best effort: a failed notification is not retried, since the
+// peer may have ingested the transaction itself
+func (c *ClusterNode) forwardLoop(peer *clusterPeer) {
+	for {
+		select {
+		case <-peer.stop:
+			return
+		case message := <-peer.queue:
+			err := c.post(peer.status.URL+clusterNotificationsEndpoint, message, nil)
+			c.recordResult(peer, err)
+
+			if err == nil {
+				c.mutex.Lock()
+				peer.status.Forwarded++
+				c.mutex.Unlock()
+			}
+		}
+	}
+}
+
+// gossipLoop periodically exchanges the membership list with every peer
+func (c *ClusterNode) gossipLoop() {
+	ticker := time.NewTicker(clusterGossipInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, url := range c.peerURLs() {
+			var response clusterMembership
+			err := c.post(url+clusterGossipEndpoint, c.membership(), &response)
+
+			c.mutex.Lock()
+			peer, known := c.peers[url]
+			c.mutex.Unlock()
+			if !known {
+				continue
+			}
+			c.recordResult(peer, err)
+			c.setPeerRegion(url, response.Region)
+
+			for _, learned := range response.Peers {
+				c.addPeer(learned, false)
+			}
+		}
+		c.expirePeers()
+	}
+}
+
+// recordResult updates the health of a peer after a request
+func (c *ClusterNode) recordResult(peer *clusterPeer, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now().UTC()
+	if err != nil {
+		peer.status.LastError = err.Error()
+		if !peer.status.Static && peer.status.ExpiresAt.IsZero() {
+			peer.status.ExpiresAt = now.Add(clusterPeerExpiry)
+		}
+		return
+	}
+	peer.status.LastSeenAt = now
+	peer.status.LastError = ""
+	peer.status.ExpiresAt = time.Time{}
+	c.markJoined()
+}
+
+// expirePeers forgets gossip peers that have been failing for too long
+func (c *ClusterNode) expirePeers() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for url, peer := range c.peers {
+		if !peer.status.ExpiresAt.IsZero() && now.After(peer.status.ExpiresAt) {
+			close(peer.stop)
+			delete(c.peers, url)
+			slog.Info("Cluster peer expired", "peer", url)
+		}
+	}
+}
+
+// peerURLs returns the base URLs of every known peer
+func (c *ClusterNode) peerURLs() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	urls := make([]string, 0, len(c.peers))
+	for url := range c.peers {
+		urls = append(urls, url)
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// membership returns the membership announcement of this node
+// A relay keeps its peers to itself, so they keep going through it
+func (c *ClusterNode) membership() clusterMembership {
+	membership := clusterMembership{URL: c.url, Region: c.region, Peers: []string{}}
+	if !c.relay {
+		membership.Peers = c.peerURLs()
+	}
+	return membership
+}
+
+// setPeerRegion records the region a peer announced
+func (c *ClusterNode) setPeerRegion(url, region string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if peer, known := c.peers[url]; known && region != "" {
+		peer.status.Region = region
+	}
+}
+
+// Peers returns the status of every known peer
+func (c *ClusterNode) Peers() []ClusterPeerStatus {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	statuses := make([]ClusterPeerStatus, 0, len(c.peers))
+	for _, peer := range c.peers {
+		status := peer.status
+		status.Pending = len(peer.queue)
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b ClusterPeerStatus) int {
+		return strings.Compare(a.URL, b.URL)
+	})
+	return statuses
+}
+
+// post sends value as JSON to a peer endpoint and decodes the response into out if non-nil
+func (c *ClusterNode) post(url string, value interface{}, out interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+c.secret)
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("peer responded with status %d", response.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(response.Body).Decode(out)
+	}
+	return nil
+}
+
+// registerClusterRoutes registers the endpoints used between cluster nodes
+func registerClusterRoutes(router *mux.Router) {
+	router.HandleFunc(clusterNotificationsEndpoint, requireClusterPeer(HandleClusterNotification)).Methods(http.MethodPost)
+	router.HandleFunc(clusterGossipEndpoint, requireClusterPeer(HandleClusterGossip)).Methods(http.MethodPost)
+	router.HandleFunc(clusterPeersEndpoint, requireClusterPeer(HandleClusterPeers)).Methods(http.MethodGet)
+	router.HandleFunc(clusterRegionsEndpoint, requireClusterPeer(HandleClusterRegions)).Methods(http.MethodGet)
+}
+
+// requireClusterPeer wraps a handler so it only runs in cluster mode for
+// requests carrying the cluster secret
+func requireClusterPeer(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if Cluster == nil {
+			writeError(w, http.StatusNotFound, "cluster mode is disabled")
+			return
+		}
+
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(Cluster.secret)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid cluster secret")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// HandleClusterNotification processes a notification forwarded by a peer
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a clusterMessage body
+func HandleClusterNotification(w http.ResponseWriter, r *http.Request) {
+	var message clusterMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, clusterMaxBodySize)).Decode(&message); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	Cluster.markJoined()
+
+	// Nodes only learn the origin of what it sent them directly: the origin
+	// of a relayed notification may not be reachable from here
+	if len(message.Via) == 0 {
+		Cluster.addPeer(message.Origin, false)
+		Cluster.setPeerRegion(message.Origin, message.Region)
+	}
+	first := Cluster.seen.Add(message.Notification.Signature)
+	Cluster.countDelivery(message, first)
+	if first {
+		Cluster.process(message.Notification)
+
+		// Peers forward only what they ingested, so only relays forward it again
+		if Cluster.relay {
+			message.Via = append(message.Via, Cluster.url)
+			Cluster.forward(message)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleClusterGossip merges the membership of a peer and answers with this node's
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a clusterMembership body
+func HandleClusterGossip(w http.ResponseWriter, r *http.Request) {
+	var membership clusterMembership
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, clusterMaxBodySize)).Decode(&membership); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	Cluster.markJoined()
+
+	Cluster.addPeer(membership.URL, false)
+	Cluster.setPeerRegion(membership.URL, membership.Region)
+	for _, peer := range membership.Peers {
+		Cluster.addPeer(peer, false)
+	}
+
+	writeJSON(w, http.StatusOK, Cluster.membership())
+}
+
+// HandleClusterPeers returns the status of every known peer
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleClusterPeers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Cluster.Peers())
+}
+
+// HandleClusterRegions returns how often each region delivered a transaction first
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleClusterRegions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Cluster.Regions())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// testClusterNode installs a cluster node with the given secret for the
+// duration of a test
+func testClusterNode(t *testing.T, secret string) *ClusterNode {
+	previous := Cluster
+	t.Cleanup(func() { Cluster = previous })
+
+	Cluster = &ClusterNode{
+		url:     "http://node-a:8080",
+		secret:  secret,
+		client:  &http.Client{},
+		peers:   make(map[string]*clusterPeer),
+		regions: make(map[string]*ClusterRegionStatus),
+		seen:    newDedupCache("cluster-test", 16),
+		process: func(pumpstream.Notification) {},
+		joined:  make(chan struct{}),
+	}
+	return Cluster
+}
+
+// TestRequireClusterPeer checks that cluster endpoints need cluster mode and
+// the cluster secret as a bearer token
+func TestRequireClusterPeer(t *testing.T) {
+	tests := []struct {
+		name     string
+		disabled bool
+		header   string
+		expected int
+	}{
+		{name: "secret", header: "Bearer topsecret", expected: http.StatusNoContent},
+		{name: "cluster mode disabled", disabled: true, header: "Bearer topsecret", expected: http.StatusNotFound},
+		{name: "missing header", expected: http.StatusUnauthorized},
+		{name: "without the bearer scheme", header: "topsecret", expected: http.StatusUnauthorized},
+		{name: "other secret", header: "Bearer othersecret", expected: http.StatusUnauthorized},
+		{name: "prefix of the secret", header: "Bearer topsecre", expected: http.StatusUnauthorized},
+		{name: "empty token", header: "Bearer ", expected: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testClusterNode(t, "topsecret")
+			if test.disabled {
+				Cluster = nil
+			}
+
+			called := false
+			handler := requireClusterPeer(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.WriteHeader(http.StatusNoContent)
+			})
+			request := httptest.NewRequest(http.MethodGet, clusterPeersEndpoint, nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler(recorder, request)
+
+			if recorder.Code != test.expected {
+				t.Fatalf("got status %d, expected %d", recorder.Code, test.expected)
+			}
+			if called != (test.expected == http.StatusNoContent) {
+				t.Fatalf("handler called %v, expected %v", called, !called)
+			}
+		})
+	}
+}
+
+// TestHandleClusterGossipJoins checks that gossip from a peer marks the node
+// joined, and that oversized bodies are refused before being decoded
+func TestHandleClusterGossipJoins(t *testing.T) {
+	node := testClusterNode(t, "topsecret")
+
+	oversized := `{"url":"http://node-b:8080","peers":["` + strings.Repeat("a", clusterMaxBodySize) + `"]}`
+	recorder := httptest.NewRecorder()
+	HandleClusterGossip(recorder, httptest.NewRequest(http.MethodPost, clusterGossipEndpoint, strings.NewReader(oversized)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d for an oversized body, expected %d", recorder.Code, http.StatusBadRequest)
+	}
+	select {
+	case <-node.Joined():
+		t.Fatal("joined after a refused body")
+	default:
+	}
+
+	recorder = httptest.NewRecorder()
+	HandleClusterGossip(recorder, httptest.NewRequest(http.MethodPost, clusterGossipEndpoint, strings.NewReader(`{"url":"http://node-b:8080","peers":[]}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, expected %d", recorder.Code, http.StatusOK)
+	}
+	select {
+	case <-node.Joined():
+	default:
+		t.Fatal("not joined after gossip from a peer")
+	}
+	for _, peer := range node.Peers() {
+		close(node.peers[peer.URL].stop)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the age after which stored events are
+	// compressed into blocks, e.g. 72h
+	// Events stay uncompressed when it is unset
+	storageCompressAfterEnv = "STORAGE_COMPRESS_AFTER"
+
+	// Number of consecutive events compressed into one block
+	storageBlockSize = 1000
+
+	// Interval between compaction sweeps
+	storageCompactInterval = 10 * time.Minute
+)
+
+// blockEncoder and blockDecoder compress and decompress event blocks; both are
+// safe for concurrent use through EncodeAll and DecodeAll
+var (
+	blockEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	blockDecoder, _ = zstd.NewReader(nil)
+)
+
+// blockEvent is a line of a block, holding the columns of its former events row
+type blockEvent struct {
+	ID         int64           `json:"id"`          // Id of the events row
+	Type       EventType       `json:"type"`        // Event type
+	Mint       string          `json:"mint"`        // Token mint address
+	Signature  string          `json:"signature"`   // Transaction signature
+	Slot       uint64          `json:"slot"`        // Slot the transaction was observed in
+	ReceivedAt int64           `json:"received_at"` // Unix milliseconds the notification was received
+	Data       json.RawMessage `json:"data"`        // JSON encoded event data
+}
+
+// eventBlocks compresses old events of a SQL backend into zstd blocks kept in
+// the event_blocks table, and reads them back for queries
+//
+// A block holds storageBlockSize consecutive events as NDJSON; field names,
+// mints and wallets repeat across events, so a block shrinks several times
+// more than its rows would on their own. Blocks record the id, received_at and
+// slot range they cover, so range queries only decompress the blocks they touch
+type eventBlocks struct {
+	db               *sql.DB
+	placeholder      func(n int) string // Placeholder of the nth query argument
+	receivedAtMillis string             // Expression selecting events.received_at as Unix milliseconds
+}
+
+// compressAfterFromEnv reads STORAGE_COMPRESS_AFTER, zero when compression is disabled
+func compressAfterFromEnv() (time.Duration, error) {
+	value := os.Getenv(storageCompressAfterEnv)
+	if value == "" {
+		return 0, nil
+	}
+	after, err := time.ParseDuration(value)
+	if err != nil || after <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration", storageCompressAfterEnv)
+	}
+	return after, nil
+}
+
+// compactLoop compresses events older than after at startup and then periodically
+func compactLoop(storage Storage, after time.Duration) {
+	ticker := time.NewTicker(storageCompactInterval)
+	defer ticker.Stop()
+
+	for {
+		compressed, err := storage.Compact(time.Now().Add(-after))
+		if err != nil {
+			slog.Error("Failed to compact storage", "storage", storage.Name(), logKeyError, err)
+		} else if compressed > 0 {
+			slog.Info("Compressed stored events", "storage", storage.Name(), "events", compressed)
+		}
+		<-ticker.C
+	}
+}
+
+// compact moves the oldest events into blocks, one full block per transaction,
+// while the newest event of the next block was received before before
+// A partial block is left for a later sweep, so every block is full
+//
+// Returns:
+//   - int64: Number of events compressed
+//   - error: Error if a block could not be written; earlier blocks stay written
+func (b *eventBlocks) compact(before time.Time) (int64, error) {
+	var compressed int64
+	for {
+		events, err := b.oldestEvents()
+		if err != nil {
+			return compressed, err
+		}
+		if len(events) < storageBlockSize || events[len(events)-1].ReceivedAt >= before.UnixMilli() {
+			return compressed, nil
+		}
+
+		if err := b.writeBlock(events); err != nil {
+			return compressed, err
+		}
+		compressed += int64(len(events))
+	}
+}
+
+// oldestEvents reads the next storageBlockSize uncompressed events
+func (b *eventBlocks) oldestEvents() ([]blockEvent, error) {
+	rows, err := b.db.Query(`SELECT id, type, mint, signature, slot, `+b.receivedAtMillis+`, data FROM events ORDER BY id LIMIT `+b.placeholder(1), storageBlockSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]blockEvent, 0, storageBlockSize)
+	for rows.Next() {
+		var event blockEvent
+		var data []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.Mint, &event.Signature, &event.Slot, &event.ReceivedAt, &data); err != nil {
+			return nil, err
+		}
+		event.Data = data
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// writeBlock compresses the events into a block and deletes their rows in one transaction
+func (b *eventBlocks) writeBlock(events []blockEvent) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	minReceivedAt, maxReceivedAt := events[0].ReceivedAt, events[0].ReceivedAt
+	minSlot, maxSlot := events[0].Slot, events[0].Slot
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		minReceivedAt, maxReceivedAt = min(minReceivedAt, event.ReceivedAt), max(maxReceivedAt, event.ReceivedAt)
+		minSlot, maxSlot = min(minSlot, event.Slot), max(maxSlot, event.Slot)
+	}
+	data := blockEncoder.EncodeAll(buffer.Bytes(), nil)
+
+	first, last := events[0].ID, events[len(events)-1].ID
+	tx, err := b.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO event_blocks (first_id, last_id, min_received_at, max_received_at, min_slot, max_slot, count, data) VALUES (`+b.placeholders(8)+`)`,
+		first, last, minReceivedAt, maxReceivedAt, int64(minSlot), int64(maxSlot), len(events), data); err != nil {
+		return fmt.Errorf("failed to insert block: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM events WHERE id BETWEEN `+b.placeholder(1)+` AND `+b.placeholder(2), first, last); err != nil {
+		return fmt.Errorf("failed to delete compressed events: %w", err)
+	}
+	return tx.Commit()
+}
+
+// placeholders returns a comma separated list of the first n placeholders
+func (b *eventBlocks) placeholders(n int) string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = b.placeholder(i + 1)
+	}
+	return strings.Join(list, ", ")
+}
+
+// scan calls handle for every compressed event matching the query, oldest first
+// Blocks hold older ids than any uncompressed row, so backends scan them before
+// the events table
+func (b *eventBlocks) scan(query EventQuery, handle func(Event) error) error {
+	var conditions []string
+	var args []interface{}
+	bound := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+" "+b.placeholder(len(args)))
+	}
+	if !query.From.IsZero() {
+		bound("max_received_at >=", query.From.UnixMilli())
+	}
+	if !query.To.IsZero() {
+		bound("min_received_at <", query.To.UnixMilli())
+	}
+	if query.FromSlot > 0 {
+		bound("max_slot >=", int64(query.FromSlot))
+	}
+	if query.ToSlot > 0 {
+		bound("min_slot <", int64(query.ToSlot))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := b.db.Query(`SELECT data FROM event_blocks`+where+` ORDER BY first_id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := scanBlock(data, query, handle); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// scanBlock decompresses a block and calls handle for its events matching the query
+func scanBlock(data []byte, query EventQuery, handle func(Event) error) error {
+	decoded, err := blockDecoder.DecodeAll(data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to decompress block: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(decoded))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(decoded)+1)
+	for scanner.Scan() {
+		var stored blockEvent
+		if err := json.Unmarshal(scanner.Bytes(), &stored); err != nil {
+			return err
+		}
+
+		event := Event{
+			Type:       stored.Type,
+			Mint:       stored.Mint,
+			Signature:  stored.Signature,
+			Slot:       stored.Slot,
+			ReceivedAt: time.UnixMilli(stored.ReceivedAt).UTC(),
+		}
+		if !query.matches(event) {
+			continue
+		}
+		if event.Data, err = decodeEventData(event.Type, stored.Data); err != nil {
+			return err
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// pruneOlder deletes blocks whose newest event was received before cutoff
+//
+// Returns:
+//   - int64: Number of events in the deleted blocks
+//   - error: Error if the blocks could not be deleted
+func (b *eventBlocks) pruneOlder(cutoff time.Time) (int64, error) {
+	return b.pruneWhere(`max_received_at < `+b.placeholder(1), cutoff.UnixMilli())
+}
+
+// pruneThrough deletes blocks made up of events with an id of at most id
+//
+// Returns:
+//   - int64: Number of events in the deleted blocks
+//   - error: Error if the blocks could not be deleted
+func (b *eventBlocks) pruneThrough(id int64) (int64, error) {
+	return b.pruneWhere(`last_id <= `+b.placeholder(1), id)
+}
+
+// pruneWhere deletes the blocks matching condition and counts their events
+func (b *eventBlocks) pruneWhere(condition string, arg interface{}) (int64, error) {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var count int64
+	if err := tx.QueryRow(`SELECT COALESCE(SUM(count), 0) FROM event_blocks WHERE `+condition, arg).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+	if _, err := tx.Exec(`DELETE FROM event_blocks WHERE `+condition, arg); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// newestID returns the id of the newest compressed event, 0 if there are none
+func (b *eventBlocks) newestID() (int64, error) {
+	var id int64
+	err := b.db.QueryRow(`SELECT COALESCE(MAX(last_id), 0) FROM event_blocks`).Scan(&id)
+	return id, err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"gopkg.in/yaml.v3"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the path of a YAML configuration file; the
+	// --config flag overrides it
+	configFileEnv = "NOVA_CONFIG"
+
+	// Flag naming the configuration file
+	configFileFlag = "config"
+
+	// Environment variable with the Solana WebSocket RPC endpoint; --ws-url overrides it
+	solanaWSURLEnv = "SOLANA_WS_URL"
+
+	// Environment variable with the Solana HTTP RPC endpoint; --rpc-url overrides it
+	solanaRPCURLEnv = "SOLANA_RPC_URL"
+
+	// Environment variable with the TCP address the server listens on; serve --addr overrides it
+	listenAddrEnv = "LISTEN_ADDR"
+
+	// Environment variable with the address of the program whose logs are
+	// subscribed to, e.g. for a devnet deployment
+	programIDEnv = "PUMPFUN_PROGRAM_ID"
+
+	// Environment variable with the delay before reconnecting upstream, e.g. 2s
+	reconnectDelayEnv = "UPSTREAM_RECONNECT_DELAY"
+
+	// Environment variables with the WebSocket read and write buffer sizes in bytes
+	wsReadBufferSizeEnv  = "WS_READ_BUFFER_SIZE"
+	wsWriteBufferSizeEnv = "WS_WRITE_BUFFER_SIZE"
+)
+
+// Configuration file loaded at startup, and the environment variables it set
+// that were not already in the environment; a reload replaces only those
+var (
+	configFile     string
+	configFileVars = map[string]bool{}
+)
+
+// loadConfigFile sets every environment variable named in a YAML configuration
+// file that is not already set, so the environment overrides the file and the
+// command line flags, whose defaults come from the environment, override both
+// Loading it again, on reload, replaces the variables set the previous time
+//
+// The file is a flat mapping of lowercase environment variable names to values:
+//
+//	solana_ws_url: wss://api.mainnet-beta.solana.com
+//	listen_addr: ":9090"
+//	upstream_reconnect_delay: 5s
+//	statsd_addr: 127.0.0.1:8125
+//
+// Parameters:
+//   - path: Path of the file, nothing is loaded when empty
+//
+// Returns:
+//   - error: Error if the file cannot be read or holds anything but scalars
+func loadConfigFile(path string) error {
+	configFile = path
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var settings map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	for key, value := range settings {
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("config file %s: %s must be a single value", path, key)
+		}
+	}
+
+	// Settings removed from the file fall back to their defaults
+	for name := range configFileVars {
+		os.Unsetenv(name)
+	}
+	configFileVars = map[string]bool{}
+
+	for key, value := range settings {
+		name := strings.ToUpper(key)
+		if _, set := os.LookupEnv(name); set && !profileVars[name] {
+			continue
+		}
+		if err := os.Setenv(name, value.Value); err != nil {
+			return fmt.Errorf("config file %s: failed to set %s: %w", path, key, err)
+		}
+		// The file overrides the profile
+		delete(profileVars, name)
+		configFileVars[name] = true
+	}
+	return nil
+}
+
+// earlyFlagValue finds the value of a flag applying settings, such as the
+// configuration file, on the command line or in its environment variable; the
+// settings have to be applied before the flags are defined, as their defaults
+// come from the environment
+//
+// Parameters:
+//   - args: Command line arguments without the program name
+//   - flag: Name of the flag
+//   - env: Environment variable used when the flag is not given
+func earlyFlagValue(args []string, flag, env string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+flag+"="); ok {
+			return value
+		}
+		if arg == "--"+flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(env)
+}
+
+// loadProgram reads the program address from PUMPFUN_PROGRAM_ID, the mainnet
+// program when it is unset
+func loadProgram() (solana.PublicKey, error) {
+	value := os.Getenv(programIDEnv)
+	if value == "" {
+		return pumpstream.Program, nil
+	}
+	program, err := solana.PublicKeyFromBase58(value)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("invalid %s %q: %w", programIDEnv, value, err)
+	}
+	return program, nil
+}
+
+// setupCoreSettings applies the settings that used to be compiled in: the
+// program address, the upstream reconnect delay and the WebSocket buffer sizes
+func setupCoreSettings() error {
+	program, err := loadProgram()
+	if err != nil {
+		return err
+	}
+	setPumpProgram(program)
+
+	if value := os.Getenv(reconnectDelayEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", reconnectDelayEnv, value)
+		}
+		reconnectDelay = parsed
+	}
+
+	for _, buffer := range []struct {
+		env  string
+		size *int
+	}{{wsReadBufferSizeEnv, &upgrader.ReadBufferSize}, {wsWriteBufferSizeEnv, &upgrader.WriteBufferSize}} {
+		value := os.Getenv(buffer.env)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", buffer.env, value)
+		}
+		*buffer.size = parsed
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Environment variables the configuration file tests set
+var testConfigVars = []string{"NOVA_TEST_WS_URL", "NOVA_TEST_ADDR", "NOVA_TEST_DELAY"}
+
+// testConfigState starts a test with the test variables unset and no file or
+// profile loaded, restoring everything afterwards
+func testConfigState(t *testing.T) {
+	for _, name := range testConfigVars {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	previousFile, previousFileVars, previousProfileVars := configFile, configFileVars, profileVars
+	t.Cleanup(func() { configFile, configFileVars, profileVars = previousFile, previousFileVars, previousProfileVars })
+	configFileVars = map[string]bool{}
+	profileVars = map[string]bool{}
+}
+
+// writeConfigFile writes a configuration file in a temporary directory
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "nova.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+// expectConfigVars fails the test unless the test variables hold the expected
+// values, a missing one being unset
+func expectConfigVars(t *testing.T, expected map[string]string) {
+	t.Helper()
+	for _, name := range testConfigVars {
+		got, set := os.LookupEnv(name)
+		want, wanted := expected[name]
+		if set != wanted || got != want {
+			t.Fatalf("got %s=%q (set %v), expected %q (set %v)", name, got, set, want, wanted)
+		}
+	}
+}
+
+// TestLoadConfigFile checks that the file sets what the environment does not,
+// and overrides the profile
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		profile  map[string]string
+		file     string
+		expected map[string]string
+		invalid  bool
+	}{
+		{
+			name:     "file only",
+			file:     "nova_test_ws_url: wss://file.example.com\nnova_test_addr: \":9090\"\n",
+			expected: map[string]string{"NOVA_TEST_WS_URL": "wss://file.example.com", "NOVA_TEST_ADDR": ":9090"},
+		},
+		{
+			name:     "environment over the file",
+			env:      map[string]string{"NOVA_TEST_WS_URL": "wss://env.example.com"},
+			file:     "nova_test_ws_url: wss://file.example.com\nnova_test_addr: \":9090\"\n",
+			expected: map[string]string{"NOVA_TEST_WS_URL": "wss://env.example.com", "NOVA_TEST_ADDR": ":9090"},
+		},
+		{
+			name:     "empty environment variable over the file",
+			env:      map[string]string{"NOVA_TEST_ADDR": ""},
+			file:     "nova_test_addr: \":9090\"\n",
+			expected: map[string]string{"NOVA_TEST_ADDR": ""},
+		},
+		{
+			name:     "file over the profile",
+			profile:  map[string]string{"NOVA_TEST_DELAY": "1s", "NOVA_TEST_ADDR": ":8080"},
+			file:     "nova_test_delay: 5s\n",
+			expected: map[string]string{"NOVA_TEST_DELAY": "5s", "NOVA_TEST_ADDR": ":8080"},
+		},
+		{
+			name:     "uppercase keys",
+			file:     "NOVA_TEST_DELAY: 5s\n",
+			expected: map[string]string{"NOVA_TEST_DELAY": "5s"},
+		},
+		{
+			name:     "nested value",
+			file:     "nova_test_addr: \":9090\"\nnova_test_ws_url:\n  primary: wss://file.example.com\n",
+			expected: map[string]string{},
+			invalid:  true,
+		},
+		{
+			name:     "list value",
+			file:     "nova_test_ws_url: [wss://a.example.com, wss://b.example.com]\n",
+			expected: map[string]string{},
+			invalid:  true,
+		},
+		{
+			name:     "not a mapping",
+			file:     "- nova_test_addr\n",
+			expected: map[string]string{},
+			invalid:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testConfigState(t)
+			for name, value := range test.env {
+				os.Setenv(name, value)
+			}
+			for name, value := range test.profile {
+				os.Setenv(name, value)
+				profileVars[name] = true
+			}
+
+			err := loadConfigFile(writeConfigFile(t, test.file))
+			if (err != nil) != test.invalid {
+				t.Fatalf("got %v, expected an error %v", err, test.invalid)
+			}
+			expectConfigVars(t, test.expected)
+		})
+	}
+}
+
+// TestLoadConfigFileReload checks that loading the file again replaces only
+// the variables it set, and that a file failing to load changes nothing
+func TestLoadConfigFileReload(t *testing.T) {
+	testConfigState(t)
+	os.Setenv("NOVA_TEST_WS_URL", "wss://env.example.com")
+	path := writeConfigFile(t, "nova_test_ws_url: wss://file.example.com\nnova_test_addr: \":9090\"\nnova_test_delay: 5s\n")
+	if err := loadConfigFile(path); err != nil {
+		t.Fatalf("failed to load config file: %v", err)
+	}
+	expectConfigVars(t, map[string]string{"NOVA_TEST_WS_URL": "wss://env.example.com", "NOVA_TEST_ADDR": ":9090", "NOVA_TEST_DELAY": "5s"})
+
+	if err := os.WriteFile(path, []byte("nova_test_delay: 10s\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+	if err := loadConfigFile(path); err != nil {
+		t.Fatalf("failed to reload config file: %v", err)
+	}
+	expectConfigVars(t, map[string]string{"NOVA_TEST_WS_URL": "wss://env.example.com", "NOVA_TEST_DELAY": "10s"})
+
+	if err := os.WriteFile(path, []byte("nova_test_delay: [1s]\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite config file: %v", err)
+	}
+	if err := loadConfigFile(path); err == nil {
+		t.Fatal("got no error, expected the invalid file refused")
+	}
+	expectConfigVars(t, map[string]string{"NOVA_TEST_WS_URL": "wss://env.example.com", "NOVA_TEST_DELAY": "10s"})
+
+	if err := loadConfigFile(""); err != nil || configFile != "" {
+		t.Fatalf("got %v (file %q), expected no file loaded", err, configFile)
+	}
+}
+
+// TestEarlyFlagValue checks that a flag on the command line overrides its
+// environment variable, in either form, and that arguments after -- are not flags
+func TestEarlyFlagValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      string
+		expected string
+	}{
+		{name: "flag with equals", args: []string{"serve", "--config=/etc/nova.yaml"}, env: "/env.yaml", expected: "/etc/nova.yaml"},
+		{name: "flag and value", args: []string{"--config", "/etc/nova.yaml", "serve"}, env: "/env.yaml", expected: "/etc/nova.yaml"},
+		{name: "environment", args: []string{"serve"}, env: "/env.yaml", expected: "/env.yaml"},
+		{name: "flag without a value", args: []string{"serve", "--config"}, env: "/env.yaml", expected: "/env.yaml"},
+		{name: "after --", args: []string{"serve", "--", "--config=/etc/nova.yaml"}, expected: ""},
+		{name: "other flag", args: []string{"--configuration=/etc/nova.yaml"}, expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(configFileEnv, test.env)
+			if got := earlyFlagValue(test.args, configFileFlag, configFileEnv); got != test.expected {
+				t.Fatalf("got %q, expected %q", got, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling confirmation tracking: when true, every
+	// creation streamed at the processed commitment is followed until it is
+	// confirmed, feeding the confirmed stream, or reverted
+	confirmationTrackingEnv = "CONFIRMATION_TRACKING"
+
+	// Commitments a WebSocket client can stream creations at
+	commitmentProcessed = "processed" // As soon as they are seen, possibly reverted later
+	commitmentConfirmed = "confirmed" // Once confirmed, with confirmation tracking
+
+	// Interval between two status checks of the pending creations
+	confirmationPollInterval = time.Second
+
+	// Longest a creation may stay unconfirmed before it counts as dropped; its
+	// blockhash, valid for 150 slots, has expired by then
+	confirmationTimeout = 90 * time.Second
+
+	// Largest number of signatures getSignatureStatuses accepts
+	maxSignatureStatuses = 256
+
+	// Reasons of a revert
+	revertReasonFailed  = "failed"  // The transaction landed with an error
+	revertReasonDropped = "dropped" // The transaction never reached confirmation, e.g. dropped or on a fork
+)
+
+// RevertEvent announces that a previewed creation did not reach confirmation,
+// so clients can roll back what they showed
+type RevertEvent struct {
+	Type      EventType `json:"type"`            // Always revert, telling it apart from creations on the preview stream
+	Mint      string    `json:"mint"`            // Mint of the reverted creation
+	Signature string    `json:"signature"`       // Creation transaction signature
+	Reason    string    `json:"reason"`          // failed or dropped
+	Error     string    `json:"error,omitempty"` // Error of a failed transaction
+}
+
+// pendingCreation is a creation waiting for confirmation
+type pendingCreation struct {
+	event   Event
+	message []byte // Creation as sent to WebSocket clients
+}
+
+// ConfirmationTracker follows previewed creations to confirmation, streams
+// the confirmed ones and reverts the others
+type ConfirmationTracker struct {
+	client *rpc.Client
+
+	mutex   sync.Mutex
+	pending map[solana.Signature]pendingCreation
+	clients map[*Client]struct{} // Clients of the confirmed stream
+}
+
+// Confirmations tracks creations when CONFIRMATION_TRACKING is set, nil otherwise
+var Confirmations *ConfirmationTracker
+
+// setupConfirmationTracking follows creations to confirmation when CONFIRMATION_TRACKING is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if no RPC endpoint can be resolved
+func setupConfirmationTracking(rpcURL string) error {
+	if !envBool(confirmationTrackingEnv) {
+		return nil
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	Confirmations = &ConfirmationTracker{
+		client:  rpc.New(endpoint),
+		pending: make(map[solana.Signature]pendingCreation),
+		clients: make(map[*Client]struct{}),
+	}
+	RegisterSink(Confirmations)
+	goSafe("confirmation tracking", Confirmations.run)
+
+	slog.Info("Tracking creations to confirmation", "confirmed_stream", "/connect?commitment="+commitmentConfirmed)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (t *ConfirmationTracker) Name() string {
+	return "confirmation"
+}
+
+// Publish starts following every creation
+func (t *ConfirmationTracker) Publish(event Event) {
+	if event.Type != EventCreate {
+		return
+	}
+	signature, err := solana.SignatureFromBase58(event.Signature)
+	if err != nil {
+		return
+	}
+	message, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pending[signature] = pendingCreation{event: event, message: message}
+}
+
+// run checks the pending creations at a fixed interval
+func (t *ConfirmationTracker) run() {
+	ticker := time.NewTicker(confirmationPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.mutex.Lock()
+		signatures := make([]solana.Signature, 0, len(t.pending))
+		for signature := range t.pending {
+			signatures = append(signatures, signature)
+		}
+		t.mutex.Unlock()
+
+		for start := 0; start < len(signatures); start += maxSignatureStatuses {
+			t.check(signatures[start:min(start+maxSignatureStatuses, len(signatures))])
+		}
+	}
+}
+
+// check resolves the creations of a batch of signatures that were confirmed,
+// failed or expired
+func (t *ConfirmationTracker) check(signatures []solana.Signature) {
+	ctx, cancel := context.WithTimeout(context.Background(), confirmationPollInterval*5)
+	defer cancel()
+
+	result, err := t.client.GetSignatureStatuses(ctx, false, signatures...)
+	if err != nil {
+		slog.Warn("Failed to get signature statuses", "signatures", len(signatures), logKeyError, err)
+		reportError(errorCategoryUpstream, err)
+		return
+	}
+
+	for i, signature := range signatures {
+		var status *rpc.SignatureStatusesResult
+		if i < len(result.Value) {
+			status = result.Value[i]
+		}
+
+		t.mutex.Lock()
+		creation, found := t.pending[signature]
+		t.mutex.Unlock()
+		if !found {
+			continue
+		}
+
+		switch {
+		case status != nil && status.Err != nil:
+			failure, _ := json.Marshal(status.Err)
+			t.revert(signature, creation, revertReasonFailed, string(failure))
+		case status != nil && (status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized):
+			t.confirm(signature, creation)
+		case time.Since(creation.event.ReceivedAt) > confirmationTimeout:
+			t.revert(signature, creation, revertReasonDropped, "")
+		}
+	}
+}
+
+// confirm sends a confirmed creation to the clients of the confirmed stream
+func (t *ConfirmationTracker) confirm(signature solana.Signature, creation pendingCreation) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.pending, signature)
+	for client := range t.clients {
+		goSafe("confirmed writer", func() {
+			client.Mutex.Lock()
+			defer client.Mutex.Unlock()
+			if err := client.send(creation.message); err != nil {
+				slog.Warn("Failed to send message to client", logKeyClientID, client.Connection.RemoteAddr().String(), logKeyError, err)
+			}
+		})
+	}
+}
+
+// revert forgets a creation that did not reach confirmation and tells the
+// clients of the preview stream and the sinks
+func (t *ConfirmationTracker) revert(signature solana.Signature, creation pendingCreation, reason, failure string) {
+	t.mutex.Lock()
+	delete(t.pending, signature)
+	t.mutex.Unlock()
+
+	mint := creation.event.Mint
+	slog.Info("Creation reverted", logKeyMint, mint, logKeySignature, creation.event.Signature, "reason", reason)
+
+	revert := RevertEvent{Type: EventRevert, Mint: mint, Signature: creation.event.Signature, Reason: reason, Error: failure}
+	if message, err := json.Marshal(revert); err == nil {
+		sendMessageToAllClients(context.Background(), message)
+	}
+	publishEvent(Event{
+		Type:       EventRevert,
+		Mint:       mint,
+		Signature:  creation.event.Signature,
+		Slot:       creation.event.Slot,
+		ReceivedAt: time.Now().UTC(),
+		Data:       revert,
+	})
+
+	// Forgotten last, so the sinks still find the token
+	Tokens.Remove(mint)
+}
+
+// stream sends confirmed creations to a WebSocket client until it disconnects
+//
+// Returns:
+//   - string: Why the connection ended, for the audit log
+func (t *ConfirmationTracker) stream(client *Client) string {
+	logger := clientLogger(client.Connection.RemoteAddr().String())
+	logger.Info("Streaming confirmed creations")
+
+	t.mutex.Lock()
+	t.clients[client] = struct{}{}
+	t.mutex.Unlock()
+	defer func() {
+		t.mutex.Lock()
+		delete(t.clients, client)
+		t.mutex.Unlock()
+	}()
+
+	reason := disconnectReason(client.readLoop())
+	logger.Info("Client disconnected", "reason", reason)
+	return reason
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// correlationIDKey is the context key of the correlation ID of a notification
+type correlationIDKey struct{}
+
+// newCorrelationID returns the ID tying together the logs, spans and events of
+// one upstream notification: its trace ID when tracing is enabled, so logs lead
+// to the trace, otherwise a random one of the same length
+func newCorrelationID(span trace.SpanContext) string {
+	if span.HasTraceID() {
+		return span.TraceID().String()
+	}
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// withCorrelationID returns a context carrying the correlation ID of the notification being processed
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationID returns the correlation ID carried by a context, empty if there is none
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+// correlationAttrs returns the log attributes of the correlation ID carried by
+// a context, none if there is none
+func correlationAttrs(ctx context.Context) []any {
+	if id := correlationID(ctx); id != "" {
+		return []any{logKeyCorrelationID, id}
+	}
+	return nil
+}
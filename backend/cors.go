@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the comma-separated origins allowed to call the
+	// REST API from a browser, e.g. https://app.example.com,https://*.example.com
+	// or * for any origin; CORS headers are not sent when it is unset
+	corsAllowedOriginsEnv = "CORS_ALLOWED_ORIGINS"
+
+	// Environment variable with the comma-separated methods allowed in cross-origin requests
+	corsAllowedMethodsEnv = "CORS_ALLOWED_METHODS"
+
+	// Environment variable with the comma-separated request headers allowed in cross-origin requests
+	corsAllowedHeadersEnv = "CORS_ALLOWED_HEADERS"
+
+	// Environment variable with how long browsers may cache a preflight response, e.g. 1h
+	corsMaxAgeEnv = "CORS_MAX_AGE"
+
+	// Defaults used when the variables above are unset
+	defaultCORSAllowedMethods = "GET, HEAD, POST, DELETE"
+	defaultCORSAllowedHeaders = "Authorization, Content-Type"
+	defaultCORSMaxAge         = 10 * time.Minute
+)
+
+// Path prefixes of the routes browsers may call cross-origin: the REST API,
+// including the event stream, the feeds and the version; WebSocket upgrades
+// are not subject to CORS
+var corsPathPrefixes = []string{"/api/", rssFeedEndpoint, atomFeedEndpoint, versionEndpoint}
+
+// originList holds allowed origins; an entry may hold a * for one subdomain
+// level, or be * alone
+type originList []string
+
+// corsPolicy holds the parsed CORS settings
+type corsPolicy struct {
+	origins originList // Allowed origins
+	methods string     // Value of Access-Control-Allow-Methods
+	headers string     // Value of Access-Control-Allow-Headers
+	maxAge  string     // Value of Access-Control-Max-Age, in seconds
+}
+
+// loadCORSPolicy reads the CORS settings
+//
+// Returns:
+//   - *corsPolicy: The policy, nil when CORS_ALLOWED_ORIGINS is unset
+//   - error: Error if a setting is invalid
+func loadCORSPolicy() (*corsPolicy, error) {
+	value := os.Getenv(corsAllowedOriginsEnv)
+	if value == "" {
+		return nil, nil
+	}
+
+	origins, err := parseOriginList(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", corsAllowedOriginsEnv, err)
+	}
+	policy := &corsPolicy{
+		origins: origins,
+		methods: envOrDefault(corsAllowedMethodsEnv, defaultCORSAllowedMethods),
+		headers: envOrDefault(corsAllowedHeadersEnv, defaultCORSAllowedHeaders),
+	}
+
+	maxAge := defaultCORSMaxAge
+	if value := os.Getenv(corsMaxAgeEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s %q", corsMaxAgeEnv, value)
+		}
+		maxAge = parsed
+	}
+	policy.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	return policy, nil
+}
+
+// parseOriginList parses comma-separated origins
+func parseOriginList(value string) (originList, error) {
+	var origins originList
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin != "*" && !strings.Contains(origin, "://") {
+			return nil, fmt.Errorf("origin %q has no scheme", origin)
+		}
+		origins = append(origins, origin)
+	}
+	return origins, nil
+}
+
+// match returns the value of Access-Control-Allow-Origin for a request origin,
+// empty if it is not allowed
+func (l originList) match(origin string) string {
+	for _, allowed := range l {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+
+		// https://*.example.com matches https://app.example.com, not https://example.com
+		prefix, suffix, wildcard := strings.Cut(allowed, "*")
+		if wildcard && len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) &&
+			!strings.Contains(origin[len(prefix):len(origin)-len(suffix)], ".") {
+			return origin
+		}
+	}
+	return ""
+}
+
+// cors wraps a handler so cross-origin requests from allowed origins to the
+// REST routes get CORS headers, and answers their preflight requests
+func cors(policy *corsPolicy, next http.Handler) http.Handler {
+	if policy == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		grpcWeb := grpcWebPath(r.URL.Path)
+		if origin == "" || (!corsPath(r.URL.Path) && !grpcWeb) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		allowed := policy.origins.match(origin)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if allowed == "" {
+			if preflight {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+		if grpcWeb {
+			// The status of a gRPC-Web call is read from headers when no message was sent
+			w.Header().Set("Access-Control-Expose-Headers", grpcWebExposedHeaders)
+		}
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Methods", policy.methods)
+		headers := policy.headers
+		if grpcWeb {
+			headers += ", " + grpcWebAllowedHeaders
+		}
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+		w.Header().Set("Access-Control-Max-Age", policy.maxAge)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// corsPath reports whether a request path is one of the cross-origin routes
+func corsPath(path string) bool {
+	for _, prefix := range corsPathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
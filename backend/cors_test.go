@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestOriginListMatch checks that origins match exactly or through a wildcard
+// for one subdomain level, ignoring case, and that * allows any origin
+func TestOriginListMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		origins  originList
+		origin   string
+		expected string
+	}{
+		{name: "exact", origins: originList{"https://app.example.com"}, origin: "https://app.example.com", expected: "https://app.example.com"},
+		{name: "exact in another case", origins: originList{"https://App.Example.com"}, origin: "https://app.example.com", expected: "https://app.example.com"},
+		{name: "other origin", origins: originList{"https://app.example.com"}, origin: "https://evil.example.net"},
+		{name: "other scheme", origins: originList{"https://app.example.com"}, origin: "http://app.example.com"},
+		{name: "other port", origins: originList{"https://app.example.com"}, origin: "https://app.example.com:8443"},
+		{name: "any origin", origins: originList{"*"}, origin: "https://anyone.example.net", expected: "*"},
+		{name: "subdomain", origins: originList{"https://*.example.com"}, origin: "https://app.example.com", expected: "https://app.example.com"},
+		{name: "subdomain in another case", origins: originList{"https://*.example.com"}, origin: "https://APP.Example.COM", expected: "https://APP.Example.COM"},
+		{name: "domain itself", origins: originList{"https://*.example.com"}, origin: "https://example.com"},
+		{name: "empty subdomain", origins: originList{"https://*.example.com"}, origin: "https://.example.com"},
+		{name: "two subdomain levels", origins: originList{"https://*.example.com"}, origin: "https://a.b.example.com"},
+		{name: "suffix of another domain", origins: originList{"https://*.example.com"}, origin: "https://app.example.com.evil.net"},
+		{name: "domain ending alike", origins: originList{"https://*.example.com"}, origin: "https://evilexample.com"},
+		{name: "subdomain with another scheme", origins: originList{"https://*.example.com"}, origin: "http://app.example.com"},
+		{name: "wildcard port", origins: originList{"http://localhost:*"}, origin: "http://localhost:3000", expected: "http://localhost:3000"},
+		{name: "later entry", origins: originList{"https://app.example.com", "https://*.example.org"}, origin: "https://docs.example.org", expected: "https://docs.example.org"},
+		{name: "no origins", origin: "https://app.example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.origins.match(test.origin); got != test.expected {
+				t.Fatalf("got %q, expected %q", got, test.expected)
+			}
+		})
+	}
+}
+
+// TestParseOriginList checks that origins are trimmed of spaces and trailing
+// slashes, and that origins without a scheme are refused
+func TestParseOriginList(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected originList
+		invalid  bool
+	}{
+		{name: "one origin", value: "https://app.example.com", expected: originList{"https://app.example.com"}},
+		{name: "several origins", value: " https://app.example.com/ , https://*.example.org,", expected: originList{"https://app.example.com", "https://*.example.org"}},
+		{name: "any origin", value: "*", expected: originList{"*"}},
+		{name: "no scheme", value: "https://app.example.com,example.org", invalid: true},
+		{name: "empty", value: " , "},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			origins, err := parseOriginList(test.value)
+			if (err != nil) != test.invalid {
+				t.Fatalf("got %v, expected an error %v", err, test.invalid)
+			}
+			if !reflect.DeepEqual(origins, test.expected) {
+				t.Fatalf("got %q, expected %q", origins, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the Helius API key used when no RPC endpoint is
+	// configured explicitly
+	heliusAPIKeyEnv = "HELIUS_API_KEY"
+
+	// Environment variable with the path of a file holding the Helius API key,
+	// e.g. a mounted Kubernetes or Docker secret; HELIUS_API_KEY takes precedence
+	heliusAPIKeyFileEnv = "HELIUS_API_KEY_FILE"
+
+	// Helius mainnet endpoints, completed with the api-key query parameter
+	heliusWebSocketURL = "wss://mainnet.helius-rpc.com/"
+	heliusRPCURL       = "https://mainnet.helius-rpc.com/"
+
+	// Replacement of secrets in log lines and error reports
+	redactedSecret = "[REDACTED]"
+)
+
+// Query parameters that carry credentials in RPC endpoint URLs
+var credentialQueryParams = []string{"api-key", "apikey", "api_key", "token", "key"}
+
+// errNoUpstreamEndpoint is returned when neither an endpoint nor an API key is configured
+var errNoUpstreamEndpoint = errors.New("no Solana RPC endpoint configured: set " + heliusAPIKeyEnv + " or " +
+	heliusAPIKeyFileEnv + ", or pass the endpoints with " + solanaWSURLEnv + "/--ws-url and " + solanaRPCURLEnv + "/--rpc-url")
+
+// secrets are the credentials removed from every log line and error report
+var (
+	secretsMutex sync.RWMutex
+	secrets      []string
+)
+
+// loadHeliusAPIKey reads the Helius API key from HELIUS_API_KEY or the file named by HELIUS_API_KEY_FILE
+//
+// Returns:
+//   - string: The key, empty if neither is set
+//   - error: Error if the key file cannot be read or is empty
+func loadHeliusAPIKey() (string, error) {
+	if key := strings.TrimSpace(os.Getenv(heliusAPIKeyEnv)); key != "" {
+		return key, nil
+	}
+
+	path := os.Getenv(heliusAPIKeyFileEnv)
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", heliusAPIKeyFileEnv, err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("%s %s is empty", heliusAPIKeyFileEnv, path)
+	}
+	return key, nil
+}
+
+// resolveEndpoint returns the configured endpoint or, when none is configured,
+// the Helius endpoint with the API key; credentials in the result are redacted
+// from logs
+//
+// Parameters:
+//   - configured: Endpoint from the flag or environment, may be empty
+//   - helius: Helius endpoint to complete with the API key
+//
+// Returns:
+//   - string: Endpoint to connect to
+//   - error: Error if no endpoint and no API key is configured
+func resolveEndpoint(configured, helius string) (string, error) {
+	if configured != "" {
+		registerURLSecrets(configured)
+		return configured, nil
+	}
+
+	key, err := loadHeliusAPIKey()
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", errNoUpstreamEndpoint
+	}
+	registerSecret(key)
+	return helius + "?" + url.Values{"api-key": {key}}.Encode(), nil
+}
+
+// registerSecret adds a credential to be redacted from logs and error reports
+func registerSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+	secrets = append(secrets, secret)
+}
+
+// registerURLSecrets registers the credentials in the user info and query of an endpoint URL
+func registerURLSecrets(endpoint string) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return
+	}
+	if password, ok := parsed.User.Password(); ok {
+		registerSecret(password)
+	}
+	query := parsed.Query()
+	for _, param := range credentialQueryParams {
+		registerSecret(query.Get(param))
+	}
+}
+
+// redactSecrets replaces every registered credential in s
+func redactSecrets(s string) string {
+	secretsMutex.RLock()
+	defer secretsMutex.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redactedSecret)
+	}
+	return s
+}
+
+// redactAttr is a slog ReplaceAttr function removing credentials from string
+// and error attributes, e.g. the URL in an RPC transport error
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		attr.Value = slog.StringValue(redactSecrets(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			attr.Value = slog.StringValue(redactSecrets(err.Error()))
+		}
+	}
+	return attr
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"container/list"
+	"hash/maphash"
+	"math"
+	"sync"
+	"sync/atomic"
+	"weak"
+)
+
+// Configuration constants
+const (
+	// False positive rate the Bloom filters of the dedup caches are sized for
+	// once a generation is full; a false positive takes a new key for a
+	// duplicate, dropping its event
+	dedupFalsePositiveRate = 1e-9
+
+	// Keys a Bloom filter generation holds, as a multiple of the LRU capacity;
+	// with two generations, duplicates are recognized 4 to 8 capacities back
+	dedupBloomFactor = 4
+)
+
+// dedupSeeds are the seeds of the two hashes the Bloom filter positions are
+// derived from
+var dedupSeeds = [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()}
+
+// dedupCache remembers keys, such as signatures or mints, in bounded memory
+// The most recently seen keys are kept exactly in an LRU. Keys evicted from
+// it are still recognized by two generations of Bloom filters, the older one
+// discarded whenever the newer one fills up, so memory stays bounded however
+// long the process runs.
+type dedupCache struct {
+	capacity int
+	metrics  *dedupMetrics
+
+	mutex    sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List   // Keys, most recently seen first
+	current  *bloomFilter // Generation keys are added to
+	previous *bloomFilter // Generation before it, nil until the first rotation
+}
+
+// dedupMetrics counts the lookups of the caches of one name
+type dedupMetrics struct {
+	lookups    atomic.Uint64
+	duplicates atomic.Uint64
+	bloomHits  atomic.Uint64
+	evictions  atomic.Uint64
+}
+
+// DedupStats describes the dedup caches of one name
+type DedupStats struct {
+	Caches            int     `json:"caches"`              // Live caches, e.g. one per rule
+	Entries           int     `json:"entries"`             // Keys in the LRUs
+	Capacity          int     `json:"capacity"`            // Keys the LRUs hold at most
+	BloomBytes        int     `json:"bloom_bytes"`         // Memory of the Bloom filters
+	Lookups           uint64  `json:"lookups"`             // Keys added since startup
+	Duplicates        uint64  `json:"duplicates"`          // Keys that were already known
+	BloomHits         uint64  `json:"bloom_hits"`          // Duplicates only the Bloom filters recognized, after leaving the LRU
+	Evictions         uint64  `json:"evictions"`           // Keys evicted from the LRUs
+	FalsePositiveRate float64 `json:"false_positive_rate"` // Estimated chance a new key is taken for a duplicate, the highest of the caches
+}
+
+// dedupRegistry tracks the caches and their metrics by name for the stats
+// Caches are held weakly, so those of removed rules are collected.
+var dedupRegistry = struct {
+	mutex   sync.Mutex
+	metrics map[string]*dedupMetrics
+	caches  map[string][]weak.Pointer[dedupCache]
+}{
+	metrics: make(map[string]*dedupMetrics),
+	caches:  make(map[string][]weak.Pointer[dedupCache]),
+}
+
+// newDedupCache creates a cache remembering up to capacity keys exactly
+//
+// Parameters:
+//   - name: Name the cache reports its metrics under, shared by caches of the same kind
+//   - capacity: Keys the LRU holds
+func newDedupCache(name string, capacity int) *dedupCache {
+	dedupRegistry.mutex.Lock()
+	defer dedupRegistry.mutex.Unlock()
+
+	metrics, found := dedupRegistry.metrics[name]
+	if !found {
+		metrics = &dedupMetrics{}
+		dedupRegistry.metrics[name] = metrics
+	}
+	cache := &dedupCache{
+		capacity: capacity,
+		metrics:  metrics,
+		entries:  make(map[string]*list.Element, capacity),
+		lru:      list.New(),
+		current:  newBloomFilter(capacity*dedupBloomFactor, dedupFalsePositiveRate),
+	}
+	dedupRegistry.caches[name] = append(dedupRegistry.caches[name], weak.Make(cache))
+	return cache
+}
+
+// Len returns the number of keys in the LRU
+func (c *dedupCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.lru.Len()
+}
+
+// Contains reports whether key was added before, without adding it
+func (c *dedupCache) Contains(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, found := c.entries[key]; found {
+		return true
+	}
+	return c.current.contains(key) || (c.previous != nil && c.previous.contains(key))
+}
+
+// Add records key and reports whether it was not already known
+func (c *dedupCache) Add(key string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.metrics.lookups.Add(1)
+	if element, found := c.entries[key]; found {
+		c.metrics.duplicates.Add(1)
+		c.lru.MoveToFront(element)
+		return false
+	}
+	known := c.current.contains(key) || (c.previous != nil && c.previous.contains(key))
+	if known {
+		c.metrics.duplicates.Add(1)
+		c.metrics.bloomHits.Add(1)
+	}
+
+	// Keys recognized by the Bloom filters come back to the LRU as recently seen
+	c.entries[key] = c.lru.PushFront(key)
+	if c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(string))
+		c.metrics.evictions.Add(1)
+	}
+	c.current.add(key)
+	if c.current.count >= c.capacity*dedupBloomFactor {
+		c.previous = c.current
+		c.current = newBloomFilter(c.capacity*dedupBloomFactor, dedupFalsePositiveRate)
+	}
+	return !known
+}
+
+// falsePositiveRate estimates the chance a new key is taken for a duplicate,
+// from how full the Bloom filters are
+// The caller must hold the lock
+func (c *dedupCache) falsePositiveRate() float64 {
+	rate := c.current.falsePositiveRate()
+	if c.previous != nil {
+		rate = 1 - (1-rate)*(1-c.previous.falsePositiveRate())
+	}
+	return rate
+}
+
+// dedupStats returns the stats of the dedup caches by name, forgetting the
+// caches that were collected
+func dedupStats() map[string]DedupStats {
+	dedupRegistry.mutex.Lock()
+	defer dedupRegistry.mutex.Unlock()
+
+	stats := make(map[string]DedupStats, len(dedupRegistry.metrics))
+	for name, metrics := range dedupRegistry.metrics {
+		entry := DedupStats{
+			Lookups:    metrics.lookups.Load(),
+			Duplicates: metrics.duplicates.Load(),
+			BloomHits:  metrics.bloomHits.Load(),
+			Evictions:  metrics.evictions.Load(),
+		}
+
+		live := dedupRegistry.caches[name][:0]
+		for _, pointer := range dedupRegistry.caches[name] {
+			cache := pointer.Value()
+			if cache == nil {
+				continue
+			}
+			live = append(live, pointer)
+
+			cache.mutex.Lock()
+			entry.Caches++
+			entry.Entries += cache.lru.Len()
+			entry.Capacity += cache.capacity
+			entry.BloomBytes += cache.current.bytes()
+			if cache.previous != nil {
+				entry.BloomBytes += cache.previous.bytes()
+			}
+			entry.FalsePositiveRate = max(entry.FalsePositiveRate, cache.falsePositiveRate())
+			cache.mutex.Unlock()
+		}
+		dedupRegistry.caches[name] = live
+		stats[name] = entry
+	}
+	return stats
+}
+
+// bloomFilter is a fixed-size Bloom filter over strings
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64 // Number of bits
+	hashes int    // Number of bits set per key
+	count  int    // Keys added
+	set    uint64 // Bits set
+}
+
+// newBloomFilter creates a filter sized so that holding keys keys gives the
+// false positive rate
+func newBloomFilter(keys int, rate float64) *bloomFilter {
+	keys = max(keys, 1)
+	size := uint64(math.Ceil(-float64(keys) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	size = (size + 63) / 64 * 64
+	hashes := max(1, int(math.Round(float64(size)/float64(keys)*math.Ln2)))
+	return &bloomFilter{bits: make([]uint64, size/64), size: size, hashes: hashes}
+}
+
+// positions returns the two hashes the bits of key are derived from, by
+// double hashing
+func (f *bloomFilter) positions(key string) (uint64, uint64) {
+	return maphash.String(dedupSeeds[0], key), maphash.String(dedupSeeds[1], key) | 1
+}
+
+// add sets the bits of key
+func (f *bloomFilter) add(key string) {
+	first, second := f.positions(key)
+	for i := range f.hashes {
+		bit := (first + uint64(i)*second) % f.size
+		if mask := uint64(1) << (bit % 64); f.bits[bit/64]&mask == 0 {
+			f.bits[bit/64] |= mask
+			f.set++
+		}
+	}
+	f.count++
+}
+
+// contains reports whether every bit of key is set
+func (f *bloomFilter) contains(key string) bool {
+	first, second := f.positions(key)
+	for i := range f.hashes {
+		bit := (first + uint64(i)*second) % f.size
+		if f.bits[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// falsePositiveRate estimates the chance that a key never added is reported
+// as contained, from the share of bits set
+func (f *bloomFilter) falsePositiveRate() float64 {
+	return math.Pow(float64(f.set)/float64(f.size), float64(f.hashes))
+}
+
+// bytes returns the memory of the bits
+func (f *bloomFilter) bytes() int {
+	return len(f.bits) * 8
+}
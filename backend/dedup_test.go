@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+// dedupTestCaches numbers the caches of the tests
+var dedupTestCaches atomic.Uint64
+
+// newTestDedupCache creates a cache whose metrics no other cache shares, even
+// when the tests run several times
+//
+// Returns:
+//   - *dedupCache: The cache
+//   - string: The name of its stats
+func newTestDedupCache(t *testing.T, capacity int) (*dedupCache, string) {
+	name := fmt.Sprintf("%s-%d", t.Name(), dedupTestCaches.Add(1))
+	return newDedupCache(name, capacity), name
+}
+
+// TestDedupCacheAddContains checks that Add reports new keys and Contains
+// looks keys up without adding them
+func TestDedupCacheAddContains(t *testing.T) {
+	cache, name := newTestDedupCache(t, 10)
+
+	steps := []struct {
+		op       string // add or contains
+		key      string
+		expected bool
+	}{
+		{op: "contains", key: "a", expected: false},
+		{op: "add", key: "a", expected: true}, // Contains did not add it
+		{op: "add", key: "a", expected: false},
+		{op: "contains", key: "a", expected: true},
+		{op: "contains", key: "b", expected: false},
+		{op: "contains", key: "b", expected: false},
+		{op: "add", key: "b", expected: true},
+	}
+	for i, step := range steps {
+		var got bool
+		if step.op == "add" {
+			got = cache.Add(step.key)
+		} else {
+			got = cache.Contains(step.key)
+		}
+		if got != step.expected {
+			t.Fatalf("step %d: %s(%q) = %v, expected %v", i, step.op, step.key, got, step.expected)
+		}
+	}
+
+	stats := dedupStats()[name]
+	if stats.Lookups != 3 || stats.Duplicates != 1 || stats.BloomHits != 0 || stats.Entries != 2 {
+		t.Fatalf("stats %+v, expected 3 lookups, 1 duplicate, no bloom hit and 2 entries", stats)
+	}
+}
+
+// TestDedupCacheEviction checks that the LRU keeps its capacity, evicting the
+// least recently seen key, and that evicted keys are still recognized
+func TestDedupCacheEviction(t *testing.T) {
+	cache, name := newTestDedupCache(t, 3)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Add(key)
+	}
+	// Seeing a again makes b the least recently seen
+	cache.Add("a")
+	cache.Add("d")
+
+	if cache.Len() != 3 {
+		t.Fatalf("%d keys in the LRU, expected 3", cache.Len())
+	}
+	if _, found := cache.entries["b"]; found {
+		t.Fatal("b is still in the LRU, expected it evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, found := cache.entries[key]; !found {
+			t.Fatalf("%s is not in the LRU", key)
+		}
+	}
+
+	// Only the Bloom filters still know b
+	if !cache.Contains("b") {
+		t.Fatal("evicted key b is not recognized")
+	}
+	if cache.Add("b") {
+		t.Fatal("Add(b) reported an evicted key as new")
+	}
+	stats := dedupStats()[name]
+	if stats.BloomHits != 1 || stats.Duplicates != 2 || stats.Evictions != 2 {
+		t.Fatalf("stats %+v, expected 1 bloom hit, 2 duplicates and 2 evictions", stats)
+	}
+
+	// b came back as recently seen, evicting c
+	if _, found := cache.entries["b"]; !found {
+		t.Fatal("b did not come back to the LRU")
+	}
+	if _, found := cache.entries["c"]; found {
+		t.Fatal("c is still in the LRU, expected it evicted by b")
+	}
+}
+
+// TestDedupCacheRotation checks that the Bloom filters rotate once a
+// generation holds capacity*dedupBloomFactor keys, and that keys are forgotten
+// after two rotations
+func TestDedupCacheRotation(t *testing.T) {
+	const capacity = 2
+	generation := capacity * dedupBloomFactor
+	cache, name := newTestDedupCache(t, capacity)
+
+	key := func(i int) string { return fmt.Sprintf("key-%d", i) }
+	for i := range generation - 1 {
+		cache.Add(key(i))
+	}
+	if cache.previous != nil {
+		t.Fatalf("rotated after %d keys, expected %d", generation-1, generation)
+	}
+
+	cache.Add(key(generation - 1))
+	if cache.previous == nil || cache.current.count != 0 {
+		t.Fatalf("not rotated after %d keys", generation)
+	}
+	first := cache.previous
+	for i := range generation {
+		if !cache.Contains(key(i)) {
+			t.Fatalf("%s forgotten after one rotation", key(i))
+		}
+	}
+
+	// A second generation pushes the first one out
+	for i := generation; i < 2*generation; i++ {
+		cache.Add(key(i))
+	}
+	if cache.previous == first || cache.previous.count != generation {
+		t.Fatal("not rotated a second time")
+	}
+	for i := range generation - capacity {
+		if cache.Contains(key(i)) {
+			t.Fatalf("%s still known after two rotations", key(i))
+		}
+	}
+	for i := generation; i < 2*generation; i++ {
+		if !cache.Contains(key(i)) {
+			t.Fatalf("%s of the last generation forgotten", key(i))
+		}
+	}
+
+	stats := dedupStats()[name]
+	if stats.Duplicates != 0 || stats.Entries != capacity || stats.Evictions != uint64(2*generation-capacity) {
+		t.Fatalf("stats %+v, expected no duplicate, %d entries and %d evictions", stats, capacity, 2*generation-capacity)
+	}
+}
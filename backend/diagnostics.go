@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with a directory diagnostic dumps are written to as
+	// JSON files; dumps are logged when it is unset
+	diagnosticDumpDirEnv = "DIAG_DUMP_DIR"
+)
+
+// queuedSink is implemented by sinks that buffer events, reporting how many
+// are waiting to be sent
+type queuedSink interface {
+	QueueDepth() int
+}
+
+// DiagnosticDump is a snapshot of the state of the process, taken on SIGUSR1
+type DiagnosticDump struct {
+	TakenAt      time.Time          `json:"taken_at"`                // Time the dump was taken
+	Uptime       float64            `json:"uptime_seconds"`          // Seconds since the process started
+	Goroutines   int                `json:"goroutines"`              // Number of running goroutines
+	HeapAlloc    uint64             `json:"heap_alloc_bytes"`        // Bytes of allocated heap objects
+	Upstream     UpstreamHealth     `json:"upstream"`                // Upstream subscription health, including the latest slot
+	SlotLag      *SlotLagStatus     `json:"slot_lag,omitempty"`      // Latest slot lag measurement, when monitored
+	Clients      []ClientInfo       `json:"clients"`                 // Connected WebSocket clients
+	QueueDepths  map[string]int     `json:"queue_depths"`            // Events waiting per sink
+	WALPending   int                `json:"wal_pending,omitempty"`   // Logged events not yet acknowledged by every sink
+	WALAbandoned uint64             `json:"wal_abandoned,omitempty"` // Events a sink still dropped or failed to write after their last retry
+	Cluster      *clusterDiagnostic `json:"cluster,omitempty"`       // Cluster state, when cluster mode is enabled
+	TokensTotal  uint64             `json:"tokens_total"`            // Creations observed since startup
+	LastLaunch   time.Time          `json:"last_launch_at,omitzero"` // Time of the most recent creation
+	Pipeline     []string           `json:"pipeline"`                // Processing middlewares as stage/name, in the order they run
+}
+
+// clusterDiagnostic is the cluster part of a diagnostic dump
+type clusterDiagnostic struct {
+	DedupEntries int                   `json:"dedup_entries"` // Signatures remembered to skip duplicates
+	Peers        []ClusterPeerStatus   `json:"peers"`         // Status of every known peer
+	Regions      []ClusterRegionStatus `json:"regions"`       // Transactions delivered first by each region
+}
+
+// takeDiagnosticDump collects the state of the process
+func takeDiagnosticDump() DiagnosticDump {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	stats := FeedStats.Snapshot()
+	dump := DiagnosticDump{
+		TakenAt:     time.Now().UTC(),
+		Uptime:      time.Since(stats.StartedAt).Seconds(),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   memory.HeapAlloc,
+		Upstream:    stats.Upstream,
+		Clients:     connectedClientInfos(),
+		QueueDepths: map[string]int{},
+		TokensTotal: stats.TokensTotal,
+		LastLaunch:  stats.LastLaunchAt,
+		Pipeline:    Pipeline.Middlewares(),
+	}
+
+	if SlotLag != nil {
+		if status, ok := SlotLag.Status(); ok {
+			dump.SlotLag = &status
+		}
+	}
+
+	eventSinksMutex.RLock()
+	for _, sink := range eventSinks {
+		if queued, ok := sink.(queuedSink); ok {
+			dump.QueueDepths[sink.Name()] = queued.QueueDepth()
+		}
+	}
+	eventSinksMutex.RUnlock()
+
+	if WAL != nil {
+		dump.WALPending = WAL.pendingCount()
+		dump.WALAbandoned = WAL.abandonedCount()
+	}
+	if Cluster != nil {
+		dump.Cluster = &clusterDiagnostic{DedupEntries: Cluster.seen.Len(), Peers: Cluster.Peers(), Regions: Cluster.Regions()}
+	}
+	return dump
+}
+
+// writeDiagnosticDump takes a dump and writes it to DIAG_DUMP_DIR, or logs it
+func writeDiagnosticDump() {
+	dump := takeDiagnosticDump()
+
+	dir := os.Getenv(diagnosticDumpDirEnv)
+	if dir == "" {
+		data, err := json.Marshal(dump)
+		if err != nil {
+			slog.Error("Failed to encode diagnostic dump", logKeyError, err)
+			return
+		}
+		slog.Info("Diagnostic dump", "dump", json.RawMessage(data))
+		return
+	}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		slog.Error("Failed to encode diagnostic dump", logKeyError, err)
+		return
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("diag-%s.json", dump.TakenAt.Format("20060102T150405.000Z")))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		slog.Error("Failed to write diagnostic dump", "path", path, logKeyError, err)
+		return
+	}
+	slog.Info("Wrote diagnostic dump", "path", path)
+}
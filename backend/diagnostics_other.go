@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+// setupDiagnosticDump does nothing where SIGUSR1 does not exist
+func setupDiagnosticDump() error {
+	return nil
+}
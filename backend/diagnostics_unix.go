@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// setupDiagnosticDump writes a diagnostic dump every time the process receives SIGUSR1
+func setupDiagnosticDump() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			runRecovered("diagnostic dump", writeDiagnosticDump)
+		}
+	}()
+	return nil
+}
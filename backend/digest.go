@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the comma-separated digest periods: hourly,
+	// daily, or a Go duration dividing a day, e.g. 4h
+	// Digests are disabled when it is unset
+	digestPeriodsEnv = "DIGESTS"
+
+	// REST endpoint returning the latest digests
+	digestsEndpoint = "/api/digests"
+
+	// Number of digests kept per period for the REST endpoint
+	digestHistorySize = 48
+
+	// Number of tokens listed in the top volume section of a digest
+	digestTopTokens = 10
+
+	// Number of alerts kept in a digest; the rest are only counted
+	digestMaxAlerts = 20
+)
+
+// Digest summarizes the feed over one period, for users who do not watch it live
+type Digest struct {
+	Period      string             `json:"period"`      // Period name, e.g. hourly
+	From        time.Time          `json:"from"`        // Start of the period
+	To          time.Time          `json:"to"`          // End of the period
+	Launches    int                `json:"launches"`    // Tokens created
+	Graduations int                `json:"graduations"` // Bonding curves completed
+	Trades      int                `json:"trades"`      // Bonding curve trades
+	Volume      uint64             `json:"volume"`      // Lamports traded on bonding curves
+	TopVolume   []DigestToken      `json:"top_volume"`  // Tokens with the highest volume, highest first
+	Graduated   []DigestToken      `json:"graduated"`   // Tokens that completed their curve, in order
+	Alerts      []OperationalAlert `json:"alerts"`      // First alerts raised, operational and from alert rules routed to the digest sink
+	AlertCount  int                `json:"alert_count"` // Alerts raised, including those not listed
+}
+
+// DigestToken is a token listed in a digest
+type DigestToken struct {
+	Mint   string `json:"mint"`             // Token mint address
+	Name   string `json:"name,omitempty"`   // Token name, when the creation was seen
+	Symbol string `json:"symbol,omitempty"` // Token symbol, when the creation was seen
+	Volume uint64 `json:"volume"`           // Lamports traded during the period
+	Trades int    `json:"trades"`           // Trades during the period
+}
+
+// digestSink is implemented by sinks that can deliver digests; each sends
+// them only to the targets that opted in
+type digestSink interface {
+	Digest(digest Digest)
+}
+
+// digestWindow accumulates the events of the current period of a schedule
+type digestWindow struct {
+	name   string
+	period time.Duration
+
+	from        time.Time
+	launches    int
+	graduations int
+	graduated   []string
+	volume      map[string]*DigestToken
+	alerts      []OperationalAlert
+	alertCount  int
+	history     []Digest // Newest last
+}
+
+// DigestScheduler counts the events of every digest period and delivers a
+// digest to the digest sinks when a period ends
+// Periods are aligned on UTC, so a daily digest covers midnight to midnight.
+type DigestScheduler struct {
+	mutex   sync.Mutex
+	windows []*digestWindow
+}
+
+// Digests builds digests when DIGESTS is set, nil otherwise
+var Digests *DigestScheduler
+
+// setupDigests starts the digest schedules of DIGESTS
+//
+// Returns:
+//   - error: Error if a period is invalid or does not divide a day
+func setupDigests() error {
+	value := os.Getenv(digestPeriodsEnv)
+	if value == "" {
+		return nil
+	}
+
+	scheduler := &DigestScheduler{}
+	now := time.Now().UTC()
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		var period time.Duration
+		switch name {
+		case "hourly":
+			period = time.Hour
+		case "daily":
+			period = 24 * time.Hour
+		default:
+			parsed, err := time.ParseDuration(name)
+			if err != nil || parsed < time.Minute || (24*time.Hour)%parsed != 0 {
+				return fmt.Errorf("invalid digest period %q in %s, expected hourly, daily or a duration dividing a day", name, digestPeriodsEnv)
+			}
+			period = parsed
+		}
+		window := &digestWindow{name: name, period: period}
+		window.reset(now.Truncate(period))
+		scheduler.windows = append(scheduler.windows, window)
+	}
+
+	Digests = scheduler
+	RegisterSink(scheduler)
+	for _, window := range scheduler.windows {
+		goSafe("digest "+window.name, func() { scheduler.run(window) })
+	}
+
+	slog.Info("Building digests", "periods", value, "endpoint", digestsEndpoint)
+	return nil
+}
+
+// reset starts a new period
+func (w *digestWindow) reset(from time.Time) {
+	w.from = from
+	w.launches = 0
+	w.graduations = 0
+	w.graduated = nil
+	w.volume = make(map[string]*DigestToken)
+	w.alerts = nil
+	w.alertCount = 0
+}
+
+// Name identifies the sink in logs and in the sinks of alert rules
+func (s *DigestScheduler) Name() string {
+	return "digest"
+}
+
+// Publish counts the event in the current period of every schedule
+func (s *DigestScheduler) Publish(event Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, window := range s.windows {
+		switch event.Type {
+		case EventCreate:
+			window.launches++
+		case EventComplete:
+			window.graduations++
+			window.graduated = append(window.graduated, event.Mint)
+		case EventTrade:
+			trade, ok := event.Data.(TradeEvent)
+			if !ok {
+				continue
+			}
+			token, seen := window.volume[event.Mint]
+			if !seen {
+				token = &DigestToken{Mint: event.Mint}
+				window.volume[event.Mint] = token
+			}
+			token.Volume += trade.SolAmount
+			token.Trades++
+		}
+	}
+}
+
+// Alert lists the alert in the current period of every schedule
+// Alert rules routed to the digest sink are summarized instead of posted live.
+func (s *DigestScheduler) Alert(alert OperationalAlert) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, window := range s.windows {
+		window.alertCount++
+		if len(window.alerts) < digestMaxAlerts {
+			window.alerts = append(window.alerts, alert)
+		}
+	}
+}
+
+// run delivers a digest at the end of every period of a schedule
+func (s *DigestScheduler) run(window *digestWindow) {
+	for {
+		s.mutex.Lock()
+		end := window.from.Add(window.period)
+		s.mutex.Unlock()
+		time.Sleep(time.Until(end))
+
+		s.mutex.Lock()
+		digest := window.digest(end)
+		window.history = append(window.history, digest)
+		if len(window.history) > digestHistorySize {
+			window.history = window.history[1:]
+		}
+		window.reset(end)
+		s.mutex.Unlock()
+
+		deliverDigest(digest)
+	}
+}
+
+// digest builds the digest of the current period
+// The caller must hold the mutex of the scheduler
+func (w *digestWindow) digest(to time.Time) Digest {
+	digest := Digest{
+		Period:      w.name,
+		From:        w.from,
+		To:          to,
+		Launches:    w.launches,
+		Graduations: w.graduations,
+		TopVolume:   make([]DigestToken, 0, digestTopTokens),
+		Graduated:   make([]DigestToken, 0, len(w.graduated)),
+		Alerts:      slices.Clone(w.alerts),
+		AlertCount:  w.alertCount,
+	}
+	tokens := make([]*DigestToken, 0, len(w.volume))
+	for _, token := range w.volume {
+		digest.Trades += token.Trades
+		digest.Volume += token.Volume
+		tokens = append(tokens, token)
+	}
+	slices.SortFunc(tokens, func(a, b *DigestToken) int {
+		if a.Volume != b.Volume {
+			if a.Volume > b.Volume {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Mint, b.Mint)
+	})
+	for _, token := range tokens[:min(len(tokens), digestTopTokens)] {
+		digest.TopVolume = append(digest.TopVolume, describeDigestToken(*token))
+	}
+	for _, mint := range w.graduated {
+		token := DigestToken{Mint: mint}
+		if traded, found := w.volume[mint]; found {
+			token = *traded
+		}
+		digest.Graduated = append(digest.Graduated, describeDigestToken(token))
+	}
+	if digest.Alerts == nil {
+		digest.Alerts = []OperationalAlert{}
+	}
+	return digest
+}
+
+// describeDigestToken adds the name and symbol of a token from the token store
+func describeDigestToken(token DigestToken) DigestToken {
+	if record, found := Tokens.Get(token.Mint); found {
+		token.Name = record.Creation.Name
+		token.Symbol = record.Creation.Symbol
+	}
+	return token
+}
+
+// deliverDigest hands a digest to every digest sink
+func deliverDigest(digest Digest) {
+	eventSinksMutex.RLock()
+	defer eventSinksMutex.RUnlock()
+
+	for _, sink := range eventSinks {
+		if digesting, ok := sink.(digestSink); ok {
+			digesting.Digest(digest)
+		}
+	}
+}
+
+// Latest returns the digests of a period, newest first, or of every period
+// when period is empty
+func (s *DigestScheduler) Latest(period string, limit int) []Digest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	digests := []Digest{}
+	for _, window := range s.windows {
+		if period == "" || window.name == period {
+			digests = append(digests, window.history...)
+		}
+	}
+	slices.SortStableFunc(digests, func(a, b Digest) int { return b.To.Compare(a.To) })
+	return digests[:min(len(digests), limit)]
+}
+
+// formatDigest renders a digest as plain text for chat integrations
+func formatDigest(digest Digest) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s digest, %s to %s UTC\n", strings.ToUpper(digest.Period[:1])+digest.Period[1:],
+		digest.From.Format("Jan 2 15:04"), digest.To.Format("Jan 2 15:04"))
+	fmt.Fprintf(&text, "%d launches, %d graduations, %d trades, %.2f SOL volume\n",
+		digest.Launches, digest.Graduations, digest.Trades, float64(digest.Volume)/lamportsPerSOL)
+	if len(digest.TopVolume) > 0 {
+		text.WriteString("Top volume:\n")
+		for i, token := range digest.TopVolume {
+			fmt.Fprintf(&text, "%d. %s %.2f SOL in %d trades\n", i+1, digestTokenLabel(token), float64(token.Volume)/lamportsPerSOL, token.Trades)
+		}
+	}
+	if len(digest.Graduated) > 0 {
+		labels := make([]string, len(digest.Graduated))
+		for i, token := range digest.Graduated {
+			labels[i] = digestTokenLabel(token)
+		}
+		fmt.Fprintf(&text, "Graduated: %s\n", strings.Join(labels, ", "))
+	}
+	if digest.AlertCount > 0 {
+		fmt.Fprintf(&text, "Alerts (%d):\n", digest.AlertCount)
+		for _, alert := range digest.Alerts {
+			fmt.Fprintf(&text, "- %s\n", alert.Message)
+		}
+	}
+	return strings.TrimSuffix(text.String(), "\n")
+}
+
+// digestTokenLabel names a token by its symbol, or its mint when unknown
+func digestTokenLabel(token DigestToken) string {
+	if token.Symbol == "" {
+		return token.Mint
+	}
+	return fmt.Sprintf("%s (%s)", token.Symbol, token.Mint)
+}
+
+// HandleDigests returns the latest digests, newest first
+func HandleDigests(w http.ResponseWriter, r *http.Request) {
+	if Digests == nil {
+		writeError(w, http.StatusNotFound, "digests are disabled")
+		return
+	}
+	limit, err := intQueryParam(r, "limit", digestHistorySize)
+	if err != nil || limit <= 0 {
+		writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+		return
+	}
+	writeJSON(w, http.StatusOK, Digests.Latest(r.URL.Query().Get("period"), limit))
+}
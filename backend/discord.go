@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file listing Discord webhooks
+	// The Discord integration is disabled when it is unset
+	discordWebhooksFileEnv = "DISCORD_WEBHOOKS_FILE"
+
+	// Number of notifications buffered per webhook before new ones are dropped
+	discordQueueSize = 100
+
+	// Maximum send attempts per notification when rate limited
+	discordMaxAttempts = 3
+
+	// Embed colours for the different notification kinds
+	discordColorCreate   = 0x57F287
+	discordColorComplete = 0xFEE75C
+	discordColorTrade    = 0x5865F2
+	discordColorRevert   = 0xED4245
+)
+
+// DiscordWebhook describes a Discord webhook receiving notifications
+type DiscordWebhook struct {
+	URL    string       `json:"url"`    // Webhook URL from the Discord channel settings
+	Filter NotifyFilter `json:"filter"` // Events posted to this webhook
+}
+
+// discordNotification is a queued event together with the data needed to render it
+type discordNotification struct {
+	event  Event
+	record TokenRecord
+	flags  []string
+}
+
+// discordWebhook owns the queue of one configured webhook
+type discordWebhook struct {
+	config DiscordWebhook
+	queue  chan discordNotification
+}
+
+// DiscordSink posts token notifications to Discord webhooks as rich embeds
+type DiscordSink struct {
+	client   *http.Client
+	webhooks []*discordWebhook
+}
+
+// discordEmbed is the subset of the Discord embed object we use
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	URL         string              `json:"url"`
+	Description string              `json:"description"`
+	Color       int                 `json:"color"`
+	Thumbnail   *discordEmbedImage  `json:"thumbnail,omitempty"`
+	Fields      []discordEmbedField `json:"fields"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+// discordEmbedImage references an image shown in an embed
+type discordEmbedImage struct {
+	URL string `json:"url"`
+}
+
+// discordEmbedField is a name/value pair shown in an embed
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// setupDiscordSink registers the Discord integration when DISCORD_WEBHOOKS_FILE is set
+func setupDiscordSink() error {
+	path := os.Getenv(discordWebhooksFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read Discord webhooks file: %w", err)
+	}
+
+	var configs []DiscordWebhook
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("failed to parse Discord webhooks file: %w", err)
+	}
+
+	sink := &DiscordSink{client: &http.Client{Timeout: webhookRequestTimeout}}
+	for i, config := range configs {
+		if err := config.Filter.Compile(); err != nil {
+			return fmt.Errorf("Discord webhook %d: %w", i, err)
+		}
+
+		webhook := &discordWebhook{config: config, queue: make(chan discordNotification, discordQueueSize)}
+		sink.webhooks = append(sink.webhooks, webhook)
+		go sink.sendLoop(webhook)
+	}
+	RegisterSink(sink)
+
+	slog.Info("Posting notifications to Discord", "webhooks", len(sink.webhooks))
+	return nil
+}
+
+// Name identifies the sink in logs
+func (d *DiscordSink) Name() string {
+	return "discord"
+}
+
+// Publish queues the event on every webhook whose filter matches
+func (d *DiscordSink) Publish(event Event) {
+	record, found := Tokens.Get(event.Mint)
+	if !found {
+		return
+	}
+	flags := newRiskFlagSet(record)
+
+	for _, webhook := range d.webhooks {
+		if !webhook.config.Filter.Matches(event, record, flags) {
+			continue
+		}
+
+		select {
+		case webhook.queue <- discordNotification{event: event, record: record, flags: flags.Get()}:
+		default:
+			slog.Warn("Discord queue full, dropping notification", logKeyEventType, event.Type, logKeyMint, event.Mint)
+		}
+	}
+}
+
+// QueueDepth returns the number of notifications waiting on every webhook
+func (d *DiscordSink) QueueDepth() int {
+	depth := 0
+	for _, webhook := range d.webhooks {
+		depth += len(webhook.queue)
+	}
+	return depth
+}
+
+// sendLoop delivers queued notifications to one webhook, waiting out rate limits
+func (d *DiscordSink) sendLoop(webhook *discordWebhook) {
+	for notification := range webhook.queue {
+		body, err := json.Marshal(map[string]interface{}{
+			"embeds": []discordEmbed{buildDiscordEmbed(notification)},
+		})
+		if err != nil {
+			slog.Error("Failed to marshal Discord embed", logKeyError, err)
+			continue
+		}
+
+		for attempt := 1; attempt <= discordMaxAttempts; attempt++ {
+			retryAfter, err := d.send(webhook.config.URL, body)
+			if err == nil {
+				break
+			}
+
+			slog.Warn("Failed to post to Discord webhook", logKeyError, err)
+			if retryAfter == 0 {
+				break
+			}
+			time.Sleep(retryAfter)
+		}
+	}
+}
+
+// send posts a single webhook message
+// Discord reports the wait time both in the Retry-After header (seconds) and
+// in the JSON body; the header is used since it is present on every 429
+//
+// Returns:
+//   - time.Duration: how long to wait before retrying, 0 if the error is not a rate limit
+//   - error: any error that occurred while sending
+func (d *DiscordSink) send(url string, body []byte) (time.Duration, error) {
+	resp, err := d.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, _ := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+		return time.Duration(seconds*float64(time.Second)) + 100*time.Millisecond, fmt.Errorf("rate limited")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	// Pause proactively once the bucket is exhausted
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		seconds, _ := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset-After"), 64)
+		time.Sleep(time.Duration(seconds * float64(time.Second)))
+	}
+	return 0, nil
+}
+
+// buildDiscordEmbed renders a notification as a Discord embed
+func buildDiscordEmbed(notification discordNotification) discordEmbed {
+	record := notification.record
+
+	embed := discordEmbed{
+		Title:       fmt.Sprintf("%s ($%s)", record.Creation.Name, record.Creation.Symbol),
+		URL:         pumpFunLink(record.Mint),
+		Description: fmt.Sprintf("[pump.fun](%s) | [Solscan](%s)", pumpFunLink(record.Mint), solscanLink(record.Mint)),
+		Timestamp:   notification.event.ReceivedAt.Format(time.RFC3339),
+		Fields: []discordEmbedField{
+			{Name: "Mint", Value: "`" + record.Mint + "`"},
+			{Name: "Creator", Value: "`" + record.Creator + "`"},
+		},
+	}
+
+	switch notification.event.Type {
+	case EventComplete:
+		embed.Title = "🎓 Graduated: " + embed.Title
+		embed.Color = discordColorComplete
+	case EventTrade:
+		embed.Title = "💱 Trade: " + embed.Title
+		embed.Color = discordColorTrade
+	case EventPrice:
+		embed.Title = "💵 Price: " + embed.Title
+		embed.Color = discordColorTrade
+		if data, ok := notification.event.Data.(PriceEvent); ok {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Price", Value: formatUSDPrice(data.PriceUSD)})
+		}
+	case EventMigration:
+		embed.Title = "🏊 Migrated: " + embed.Title
+		embed.Color = discordColorComplete
+		if data, ok := notification.event.Data.(MigrationEvent); ok {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "LP", Value: formatLPStatus(data.LP)})
+		}
+	case EventRevert:
+		embed.Title = "↩️ Reverted: " + embed.Title
+		embed.Color = discordColorRevert
+		if data, ok := notification.event.Data.(RevertEvent); ok {
+			embed.Fields = append(embed.Fields, discordEmbedField{Name: "Reason", Value: data.Reason})
+		}
+	default:
+		embed.Title = "🚀 New token: " + embed.Title
+		embed.Color = discordColorCreate
+	}
+
+	if len(notification.flags) > 0 {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "⚠️ Risk flags", Value: strings.Join(notification.flags, ", ")})
+	}
+
+	if image := fetchMetadataImage(record.Creation.Uri); image != "" {
+		embed.Thumbnail = &discordEmbedImage{URL: image}
+	}
+
+	return embed
+}
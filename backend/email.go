@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file with SMTP settings and alert rules
+	// Email alerts are disabled when it is unset
+	emailConfigFileEnv = "EMAIL_CONFIG_FILE"
+
+	// Digest interval used when the config does not set one
+	defaultEmailDigestInterval = 5 * time.Minute
+
+	// Maximum number of alerts listed in a single email; the rest are summarised
+	emailMaxAlertsPerDigest = 50
+)
+
+// EmailConfig holds the SMTP settings and the rules that trigger alert emails
+type EmailConfig struct {
+	SMTPHost       string      `json:"smtp_host"`                 // SMTP server host
+	SMTPPort       int         `json:"smtp_port"`                 // SMTP server port, usually 587
+	Username       string      `json:"username,omitempty"`        // SMTP username, no auth if empty
+	Password       string      `json:"password,omitempty"`        // SMTP password
+	From           string      `json:"from"`                      // Sender address
+	DigestInterval string      `json:"digest_interval,omitempty"` // Go duration between digests, e.g. "5m"
+	Rules          []EmailRule `json:"rules"`                     // Alert rules
+}
+
+// EmailRule sends matching events to a list of recipients
+type EmailRule struct {
+	Name   string       `json:"name"`   // Rule name used in the subject line
+	To     []string     `json:"to"`     // Recipient addresses
+	Filter NotifyFilter `json:"filter"` // Events that trigger the rule
+}
+
+// emailRule holds the alerts collected for a rule since the last digest
+type emailRule struct {
+	config  EmailRule
+	mutex   sync.Mutex
+	pending []string
+	dropped int
+}
+
+// EmailSink collects matching events per rule and mails them as periodic digests,
+// so a spam wave produces one email per interval instead of one per launch
+type EmailSink struct {
+	config EmailConfig
+	auth   smtp.Auth
+	rules  []*emailRule
+}
+
+// setupEmailSink registers the email integration when EMAIL_CONFIG_FILE is set
+func setupEmailSink() error {
+	path := os.Getenv(emailConfigFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read email config file: %w", err)
+	}
+
+	var config EmailConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse email config file: %w", err)
+	}
+	if config.SMTPHost == "" || config.SMTPPort == 0 || config.From == "" {
+		return fmt.Errorf("email config must set smtp_host, smtp_port and from")
+	}
+
+	interval := defaultEmailDigestInterval
+	if config.DigestInterval != "" {
+		if interval, err = time.ParseDuration(config.DigestInterval); err != nil || interval <= 0 {
+			return fmt.Errorf("invalid email digest_interval %q", config.DigestInterval)
+		}
+	}
+
+	sink := &EmailSink{config: config}
+	if config.Username != "" {
+		sink.auth = smtp.PlainAuth("", config.Username, config.Password, config.SMTPHost)
+	}
+	for _, rule := range config.Rules {
+		if len(rule.To) == 0 {
+			return fmt.Errorf("email rule %q has no recipients", rule.Name)
+		}
+		if err := rule.Filter.Compile(); err != nil {
+			return fmt.Errorf("email rule %q: %w", rule.Name, err)
+		}
+		sink.rules = append(sink.rules, &emailRule{config: rule})
+	}
+
+	go sink.digestLoop(interval)
+	RegisterSink(sink)
+
+	slog.Info("Sending email digests", "rules", len(sink.rules), "interval", interval)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (e *EmailSink) Name() string {
+	return "email"
+}
+
+// Publish adds the event to the pending digest of every matching rule
+func (e *EmailSink) Publish(event Event) {
+	record, found := Tokens.Get(event.Mint)
+	if !found {
+		return
+	}
+	flags := newRiskFlagSet(record)
+
+	for _, rule := range e.rules {
+		if !rule.config.Filter.Matches(event, record, flags) {
+			continue
+		}
+
+		rule.mutex.Lock()
+		if len(rule.pending) < emailMaxAlertsPerDigest {
+			rule.pending = append(rule.pending, formatEmailAlert(event, record, flags.Get()))
+		} else {
+			rule.dropped++
+		}
+		rule.mutex.Unlock()
+	}
+}
+
+// digestLoop flushes every rule's pending alerts once per interval
+func (e *EmailSink) digestLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, rule := range e.rules {
+			rule.mutex.Lock()
+			alerts, dropped := rule.pending, rule.dropped
+			rule.pending, rule.dropped = nil, 0
+			rule.mutex.Unlock()
+
+			if len(alerts) == 0 {
+				continue
+			}
+			if err := e.send(rule.config, alerts, dropped); err != nil {
+				slog.Warn("Failed to send email digest", "rule", rule.config.Name, logKeyError, err)
+			}
+		}
+	}
+}
+
+// send mails one digest to the recipients of a rule
+func (e *EmailSink) send(rule EmailRule, alerts []string, dropped int) error {
+	subject := fmt.Sprintf("[%s] %d new alerts", rule.Name, len(alerts)+dropped)
+	if len(alerts) == 1 && dropped == 0 {
+		subject = fmt.Sprintf("[%s] %s", rule.Name, strings.SplitN(alerts[0], "\n", 2)[0])
+	}
+	// Token names are chosen by their creator; a CR or LF would end the
+	// header and let them add their own
+	subject = mime.QEncoding.Encode("utf-8", stripControlChars(subject))
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(rule.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	for _, alert := range alerts {
+		body.WriteString(strings.ReplaceAll(alert, "\n", "\r\n"))
+		body.WriteString("\r\n\r\n")
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&body, "...and %d more alerts not listed.\r\n", dropped)
+	}
+
+	address := net.JoinHostPort(e.config.SMTPHost, strconv.Itoa(e.config.SMTPPort))
+	return smtp.SendMail(address, e.auth, e.config.From, rule.To, []byte(body.String()))
+}
+
+// stripControlChars replaces the control characters of text, such as CR and LF, with spaces
+func stripControlChars(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// formatEmailAlert renders an event as a plain text alert; the first line is a summary
+func formatEmailAlert(event Event, record TokenRecord, flags []string) string {
+	kind := "New token"
+	switch event.Type {
+	case EventComplete:
+		kind = "Graduated"
+	case EventTrade:
+		kind = "Trade"
+	case EventPrice:
+		kind = "Price"
+	case EventMigration:
+		kind = "Migrated"
+	case EventRevert:
+		kind = "Reverted"
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s: %s ($%s)\n", kind, stripControlChars(record.Creation.Name), stripControlChars(record.Creation.Symbol))
+	fmt.Fprintf(&builder, "Mint: %s\n", record.Mint)
+	if data, ok := event.Data.(PriceEvent); ok {
+		fmt.Fprintf(&builder, "Price: %s\n", formatUSDPrice(data.PriceUSD))
+	}
+	if data, ok := event.Data.(MigrationEvent); ok {
+		fmt.Fprintf(&builder, "LP: %s\n", formatLPStatus(data.LP))
+	}
+	if data, ok := event.Data.(RevertEvent); ok {
+		fmt.Fprintf(&builder, "Reason: %s\n", data.Reason)
+	}
+	fmt.Fprintf(&builder, "Creator: %s\n", record.Creator)
+	fmt.Fprintf(&builder, "Time: %s\n", event.ReceivedAt.Format(time.RFC3339))
+	fmt.Fprintf(&builder, "%s\n%s", pumpFunLink(record.Mint), solscanLink(record.Mint))
+	if len(flags) > 0 {
+		fmt.Fprintf(&builder, "\nRisk flags: %s", strings.Join(flags, ", "))
+	}
+	return builder.String()
+}
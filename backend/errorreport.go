@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the Sentry DSN errors are reported to
+	// Error reporting is disabled when it is unset; SENTRY_ENVIRONMENT and
+	// SENTRY_RELEASE are read by the SDK itself
+	sentryDSNEnv = "SENTRY_DSN"
+
+	// Environment variable with the fraction of errors reported, between 0 and 1
+	// Every error is reported when it is unset
+	sentrySampleRateEnv = "SENTRY_SAMPLE_RATE"
+
+	// Time allowed for sending the reports still queued at exit
+	errorReportFlushTimeout = 2 * time.Second
+)
+
+// Categories of reported errors, sent as the category tag and used as the
+// first part of the fingerprint
+const (
+	errorCategoryDecode   = "decode"   // A program log that could not be decoded
+	errorCategoryUpstream = "upstream" // The upstream subscription failed
+	errorCategoryPanic    = "panic"    // A recovered panic
+)
+
+// errorReportingEnabled is set once setupErrorReporting initialized the Sentry client
+var errorReportingEnabled bool
+
+// Patterns of the parts of an error message that vary between occurrences of
+// the same error, such as byte counts, offsets and discriminator values
+var (
+	fingerprintLists   = regexp.MustCompile(`\[[^\]]*\]`)
+	fingerprintNumbers = regexp.MustCompile(`\d+`)
+)
+
+// setupErrorReporting initializes the Sentry client when a DSN is configured
+//
+// Returns:
+//   - func(): Sends the queued reports; a no-op when disabled
+//   - error: Error if the sample rate or the DSN is invalid
+func setupErrorReporting() (func(), error) {
+	dsn := os.Getenv(sentryDSNEnv)
+	if dsn == "" {
+		return func() {}, nil
+	}
+
+	sampleRate := 1.0
+	if value := os.Getenv(sentrySampleRateEnv); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			return nil, fmt.Errorf("invalid %s %q, expected a number above 0 and at most 1", sentrySampleRateEnv, value)
+		}
+		sampleRate = parsed
+	}
+
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:              dsn,
+		SampleRate:       sampleRate,
+		AttachStacktrace: true,
+		ServerName:       envOrDefault(otelServiceNameEnv, defaultServiceName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Sentry: %w", err)
+	}
+	errorReportingEnabled = true
+
+	slog.Info("Reporting errors to Sentry", "sample_rate", sampleRate)
+	return func() { sentry.Flush(errorReportFlushTimeout) }, nil
+}
+
+// reportError sends an error to Sentry, grouped by its category and its message
+// with the varying parts removed
+//
+// Parameters:
+//   - category: One of the errorCategory constants
+//   - err: Error being reported
+//   - attrs: Key-value pairs sent as tags, as passed to slog
+func reportError(category string, err error, attrs ...any) {
+	if !errorReportingEnabled {
+		return
+	}
+	if message := redactSecrets(err.Error()); message != err.Error() {
+		err = errors.New(message)
+	}
+	hub := scopedHub(category, fingerprint(err.Error()), attrs)
+	hub.CaptureException(err)
+}
+
+// reportPanic sends a recovered panic to Sentry with the stack of the
+// goroutine that panicked; it must be called from the deferred function that
+// recovered it
+//
+// Parameters:
+//   - where: What was running, grouping panics together with the panic value
+//   - value: Value passed to panic
+//   - attrs: Key-value pairs sent as tags, as passed to slog
+func reportPanic(where string, value any, attrs ...any) {
+	if !errorReportingEnabled {
+		return
+	}
+	hub := scopedHub(errorCategoryPanic, where+": "+fingerprint(fmt.Sprint(value)), append([]any{"where", where}, attrs...))
+	hub.Recover(value)
+}
+
+// scopedHub returns a hub whose scope carries the tags and fingerprint of one report
+func scopedHub(category, group string, attrs []any) *sentry.Hub {
+	hub := sentry.CurrentHub().Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetTag("category", category)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			if key, ok := attrs[i].(string); ok {
+				scope.SetTag(key, fmt.Sprint(attrs[i+1]))
+			}
+		}
+		scope.SetFingerprint([]string{category, group})
+	})
+	return hub
+}
+
+// fingerprint removes the numbers and value lists from an error message, so
+// e.g. payloads that are too short by different amounts are grouped together
+func fingerprint(message string) string {
+	message = fingerprintLists.ReplaceAllString(message, "[...]")
+	return fingerprintNumbers.ReplaceAllString(message, "N")
+}
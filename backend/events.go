@@ -0,0 +1,221 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of decoded on-chain event
+type EventType string
+
+// Event types emitted by the stream
+const (
+	EventCreate    EventType = "create"    // A new token was created
+	EventTrade     EventType = "trade"     // A buy or sell on a bonding curve
+	EventComplete  EventType = "complete"  // A bonding curve completed (graduation)
+	EventPrice     EventType = "price"     // A new quote for a graduated token
+	EventMigration EventType = "migration" // A graduated token migrated to its pool, with the LP verified
+	EventRevert    EventType = "revert"    // A previewed creation did not reach confirmation
+)
+
+// Event is the envelope published to sinks for every decoded on-chain event
+type Event struct {
+	Type       EventType   `json:"type"`        // Kind of event
+	Mint       string      `json:"mint"`        // Token mint address the event refers to
+	Signature  string      `json:"signature"`   // Transaction signature
+	Slot       uint64      `json:"slot"`        // Slot the transaction was observed in
+	ReceivedAt time.Time   `json:"received_at"` // Time the notification was received
+	Data       interface{} `json:"data"`        // CreateEvent, TradeEvent, CompleteEvent, PriceEvent, MigrationEvent or RevertEvent
+
+	// ID of the notification the event came in, matching the correlation_id
+	// of the logs and spans; empty for events loaded from storage
+	CorrelationID string `json:"correlation_id,omitempty"`
+
+	// Accounts derived from the mint, such as the bonding curve and its token account
+	Addresses *DerivedAddresses `json:"addresses,omitempty"`
+
+	// External market data of the token, when a market data provider is
+	// configured and has already listed the token
+	Market *MarketData `json:"market,omitempty"`
+
+	// Decimals and total supply of the mint, once fetched with supply
+	// enrichment enabled
+	Supply *TokenSupply `json:"supply,omitempty"`
+
+	// Share of the supply held by the largest wallets, for tokens watched with
+	// holder concentration enabled, as of the latest refresh
+	Holders *HolderConcentration `json:"holders,omitempty"`
+
+	// Where the SOL of the creator came from, once traced with funding
+	// tracing enabled
+	Funding *FundingSource `json:"funding,omitempty"`
+
+	// Fields computed by the filter scripts of FILTER_SCRIPTS_FILE, by name
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// TradeEvent represents the formatted trade data sent to sinks
+type TradeEvent struct {
+	Mint                 string `json:"mint"`                   // Token mint address
+	SolAmount            uint64 `json:"sol_amount"`             // Lamports exchanged
+	TokenAmount          uint64 `json:"token_amount"`           // Token base units exchanged
+	IsBuy                bool   `json:"is_buy"`                 // True for buys, false for sells
+	User                 string `json:"user"`                   // Trader wallet
+	Timestamp            int64  `json:"timestamp"`              // Unix timestamp of the trade
+	VirtualSolReserves   uint64 `json:"virtual_sol_reserves"`   // Virtual SOL reserves after the trade
+	VirtualTokenReserves uint64 `json:"virtual_token_reserves"` // Virtual token reserves after the trade
+}
+
+// CompleteEvent represents the formatted curve completion data sent to sinks
+type CompleteEvent struct {
+	Mint         string `json:"mint"`          // Token mint address
+	User         string `json:"user"`          // Wallet that completed the curve
+	BondingCurve string `json:"bonding_curve"` // Bonding curve account of the token
+	Timestamp    int64  `json:"timestamp"`     // Unix timestamp of the completion
+}
+
+// PriceEvent represents a price quoted for a token after it left its bonding
+// curve; it carries no signature, and its slot is the one the quote reflects
+type PriceEvent struct {
+	Mint      string  `json:"mint"`      // Token mint address
+	PriceUSD  float64 `json:"price_usd"` // Price of one token in USD
+	Source    string  `json:"source"`    // Service that quoted the price, e.g. jupiter
+	Timestamp int64   `json:"timestamp"` // Unix timestamp of the quote
+}
+
+// EventSink receives every decoded event
+// Publish must not block the stream; sinks are expected to queue internally
+type EventSink interface {
+	Name() string
+	Publish(event Event)
+}
+
+// ackingSink is implemented by sinks that acknowledge an event once it has been
+// durably written; with the broadcast write-ahead log enabled, an event is only
+// forgotten after every acknowledging sink has called ack with true
+// A sink dropping or failing to write the event calls ack with false, and the
+// log hands it to that sink again
+type ackingSink interface {
+	EventSink
+	PublishWithAck(event Event, ack func(written bool))
+}
+
+// drainableSink is implemented by sinks that can wait for their queue to empty
+// Commands that exit once their input is exhausted, such as replay and backfill,
+// drain sinks so the last events are not lost
+type drainableSink interface {
+	Drain()
+}
+
+// sinkSetups lists the setup functions of every sink; each registers its sink
+// only when it is configured
+var sinkSetups = []func() error{
+	setupStorage,
+	setupRingBuffer,
+	setupFullTextIndex,
+	setupWebhooks,
+	setupKafkaSink,
+	setupNATSSink,
+	setupRedisPubSubSink,
+	setupRedisStreamSink,
+	setupAMQPSink,
+	setupMQTTSink,
+	setupTelegramSink,
+	setupDiscordSink,
+	setupSlackSink,
+	setupEmailSink,
+	setupFCMSink,
+	setupTwitterSink,
+	setupZMQSink,
+	setupTCPFeed,
+	setupStdoutSink,
+	setupClickHouseSink,
+	setupInfluxSink,
+	setupArchiver,
+	setupBigQuerySink,
+	setupStatsDSink,
+	setupDigests,
+	// Last, so alert rules can name every alerting sink
+	setupAlertRules,
+}
+
+// eventSinks holds the registered sinks
+var (
+	eventSinks      []EventSink
+	eventSinksMutex sync.RWMutex
+)
+
+// setupSinks runs every sink setup function, stopping at the first error
+func setupSinks() error {
+	for _, setup := range sinkSetups {
+		if err := setup(); err != nil {
+			return err
+		}
+	}
+	// Every sink has defined its flag by now
+	if err := setupFeatureFlags(); err != nil {
+		return err
+	}
+	return setupBroadcastWAL()
+}
+
+// RegisterSink adds a sink that will receive every subsequently published event
+func RegisterSink(sink EventSink) {
+	eventSinksMutex.Lock()
+	defer eventSinksMutex.Unlock()
+
+	eventSinks = append(eventSinks, sink)
+	Features.Define(sinkFeaturePrefix+sink.Name(), "Publish events to the "+sink.Name()+" sink", true)
+}
+
+// publishEvent hands an event to every registered sink, through the
+// write-ahead log when at-least-once delivery is enabled
+func publishEvent(event Event) {
+	publishedEvents.Add(1)
+	if WAL != nil {
+		WAL.publish(event)
+		return
+	}
+	publishToSinks(event, nil)
+}
+
+// publishToSinks hands an event to every registered sink
+// Acknowledging sinks call ack with themselves once the event is written or
+// failed, if it is not nil
+func publishToSinks(event Event, ack func(sink ackingSink, written bool)) {
+	eventSinksMutex.RLock()
+	defer eventSinksMutex.RUnlock()
+
+	for _, sink := range eventSinks {
+		acking, isAcking := sink.(ackingSink)
+		if !Features.Enabled(sinkFeaturePrefix + sink.Name()) {
+			// Acknowledged so the write-ahead log does not hold the event forever
+			if isAcking && ack != nil {
+				ack(acking, true)
+			}
+			continue
+		}
+		if isAcking && ack != nil {
+			acking.PublishWithAck(event, func(written bool) { ack(acking, written) })
+		} else {
+			sink.Publish(event)
+		}
+	}
+}
+
+// drainSinks waits until every drainable sink has delivered its queued events
+func drainSinks() {
+	eventSinksMutex.RLock()
+	defer eventSinksMutex.RUnlock()
+
+	for _, sink := range eventSinks {
+		if drainable, ok := sink.(drainableSink); ok {
+			drainable.Drain()
+		}
+	}
+
+	// Forget what was just delivered so the next start does not deliver it again
+	if WAL != nil {
+		WAL.checkpoint()
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// REST endpoint streaming stored creations as a spreadsheet
+	exportEndpoint = "/api/export"
+
+	// Export format supported by the endpoint
+	exportFormatCSV = "csv"
+
+	// Leading characters making spreadsheets evaluate a cell as a formula
+	csvFormulaPrefixes = "=+-@\t\r"
+)
+
+// exportColumn is a column that can be selected in a CSV export
+type exportColumn struct {
+	name  string                                                        // Header and columns parameter name
+	value func(event Event, data CreateEvent, token TokenRecord) string // Cell value of a creation
+	token bool                                                          // True if the value needs the token record
+}
+
+// exportColumns lists the selectable columns in their default order
+var exportColumns = []exportColumn{
+	{name: "received_at", value: func(e Event, _ CreateEvent, _ TokenRecord) string { return e.ReceivedAt.Format(time.RFC3339) }},
+	{name: "mint", value: func(e Event, _ CreateEvent, _ TokenRecord) string { return e.Mint }},
+	{name: "name", value: func(_ Event, d CreateEvent, _ TokenRecord) string { return csvText(d.Name) }},
+	{name: "symbol", value: func(_ Event, d CreateEvent, _ TokenRecord) string { return csvText(d.Symbol) }},
+	{name: "uri", value: func(_ Event, d CreateEvent, _ TokenRecord) string { return csvText(d.Uri) }},
+	{name: "signature", value: func(e Event, _ CreateEvent, _ TokenRecord) string { return e.Signature }},
+	{name: "slot", value: func(e Event, _ CreateEvent, _ TokenRecord) string { return strconv.FormatUint(e.Slot, 10) }},
+	{name: "creator", value: func(_ Event, _ CreateEvent, t TokenRecord) string { return t.Creator }, token: true},
+	{name: "bonding_curve", value: func(_ Event, _ CreateEvent, t TokenRecord) string { return t.BondingCurve }, token: true},
+	{name: "graduated", value: func(_ Event, _ CreateEvent, t TokenRecord) string { return strconv.FormatBool(t.Migration.Complete) }, token: true},
+}
+
+// HandleExport streams stored creations received between from and to as CSV
+// Rows are written while the database is scanned, so exports of any size use
+// constant memory
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with optional from, to, format and columns query parameters
+func HandleExport(w http.ResponseWriter, r *http.Request) {
+	if Persistence == nil {
+		writeError(w, http.StatusNotFound, "exports require a storage backend")
+		return
+	}
+
+	params := r.URL.Query()
+	if format := params.Get("format"); format != "" && format != exportFormatCSV {
+		writeError(w, http.StatusBadRequest, "unsupported format, expected "+exportFormatCSV)
+		return
+	}
+
+	from, err := parseTimeBound(params.Get("from"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "from must be a date (YYYY-MM-DD) or RFC 3339 time")
+		return
+	}
+	to, err := parseTimeBound(params.Get("to"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "to must be a date (YYYY-MM-DD) or RFC 3339 time")
+		return
+	}
+
+	columns, err := selectExportColumns(params.Get("columns"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	needsToken := false
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+		needsToken = needsToken || column.token
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+exportFileName(from, to)+`"`)
+
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+
+	row := make([]string, len(columns))
+	query := EventQuery{From: from, To: to, Types: []EventType{EventCreate}}
+	err = Persistence.ScanEvents(query, func(event Event) error {
+		data, _ := event.Data.(CreateEvent)
+
+		var token TokenRecord
+		if needsToken {
+			token = exportToken(event.Mint)
+		}
+
+		for i, column := range columns {
+			row[i] = column.value(event, data, token)
+		}
+		return writer.Write(row)
+	})
+
+	// Headers are already sent, so a failure can only cut the file short
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		slog.Warn("CSV export stopped", logKeyError, err)
+	}
+}
+
+// selectExportColumns resolves a comma separated list of column names
+// An empty list selects every column in the default order
+func selectExportColumns(names string) ([]exportColumn, error) {
+	if names == "" {
+		return exportColumns, nil
+	}
+
+	var selected []exportColumn
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range exportColumns {
+			if column.name == name {
+				selected = append(selected, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	return selected, nil
+}
+
+// exportToken returns the current record of a token, from memory if it is
+// still tracked and from storage otherwise
+func exportToken(mint string) TokenRecord {
+	if record, found := Tokens.Get(mint); found {
+		return record
+	}
+	record, _, err := Persistence.GetToken(mint)
+	if err != nil {
+		slog.Warn("Failed to look up token for export", logKeyMint, mint, logKeyError, err)
+	}
+	return record
+}
+
+// csvText returns a cell chosen by a token creator, prefixed with a quote when
+// a spreadsheet would otherwise evaluate it as a formula
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// exportFileName names the download after the exported range
+func exportFileName(from, to time.Time) string {
+	name := "creations"
+	if !from.IsZero() {
+		name += "-from-" + from.UTC().Format("20060102T150405Z")
+	}
+	if !to.IsZero() {
+		name += "-to-" + to.UTC().Format("20060102T150405Z")
+	}
+	return name + ".csv"
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+// TestCSVText checks that cells a spreadsheet would evaluate as formulas are
+// quoted, and that other cells are left as they are
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "equals", value: `=HYPERLINK("https://example.com","x")`, expected: `'=HYPERLINK("https://example.com","x")`},
+		{name: "plus", value: "+1", expected: "'+1"},
+		{name: "minus", value: "-1+2", expected: "'-1+2"},
+		{name: "at", value: "@SUM(A1)", expected: "'@SUM(A1)"},
+		{name: "tab", value: "\t=1", expected: "'\t=1"},
+		{name: "carriage return", value: "\r=1", expected: "'\r=1"},
+		{name: "plain", value: "Pepe", expected: "Pepe"},
+		{name: "formula later in the cell", value: "Pepe=1", expected: "Pepe=1"},
+		{name: "uri", value: "https://example.com/a.json", expected: "https://example.com/a.json"},
+		{name: "empty", value: "", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := csvText(test.value); got != test.expected {
+				t.Fatalf("got %q, expected %q", got, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,525 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a Firebase service account JSON key
+	// Push notifications are disabled when it is unset
+	fcmCredentialsFileEnv = "FCM_CREDENTIALS_FILE"
+
+	// Environment variable holding the bearer token the mobile app sends to
+	// register and unregister devices; required with FCM_CREDENTIALS_FILE, so
+	// strangers cannot make the project push to tokens of their choosing
+	fcmAppTokenEnv = "FCM_APP_TOKEN"
+
+	// Environment variable pointing to a JSON file the registered devices are
+	// kept in, rewritten on every change and created on the first if missing;
+	// registrations are lost on restart when unset
+	fcmDevicesFileEnv = "FCM_DEVICES_FILE"
+
+	// Environment variable overriding the number of devices registered at
+	// most, e.g. 50000; further registrations are refused until some leave
+	fcmMaxDevicesEnv = "FCM_MAX_DEVICES"
+
+	// Devices registered at most when FCM_MAX_DEVICES is unset
+	defaultFCMMaxDevices = 10000
+
+	// Devices a single client address may register; a phone needs one, a
+	// household or carrier NAT a few more
+	fcmMaxDevicesPerIP = 20
+
+	// FCM HTTP v1 send endpoint
+	fcmSendURL = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+
+	// OAuth scope required by the FCM HTTP v1 API
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+	// REST endpoints for registering and unregistering device tokens
+	pushDevicesEndpoint = "/api/push/devices"
+	pushDeviceEndpoint  = "/api/push/devices/{token}"
+
+	// Number of concurrent senders and the size of their shared queue
+	fcmWorkers   = 4
+	fcmQueueSize = 10000
+
+	// Maximum send attempts per notification for retryable failures
+	fcmMaxAttempts = 3
+
+	// Delay before retrying a throttled or failed send
+	fcmRetryDelay = 2 * time.Second
+)
+
+// Errors of a registration the limits refuse
+var (
+	errPushDevicesFull  = errors.New("too many devices registered")
+	errPushDevicesPerIP = errors.New("too many devices registered from this address")
+)
+
+// errSavePushDevices is returned when a change applies but cannot be persisted
+var errSavePushDevices = errors.New("failed to save push devices")
+
+// PushDevice is a mobile device registered for push notifications
+type PushDevice struct {
+	Token  string       `json:"token"`  // FCM registration token issued to the app
+	Filter NotifyFilter `json:"filter"` // Events pushed to this device
+}
+
+// pushRegistration is a registered device as kept in FCM_DEVICES_FILE
+type pushRegistration struct {
+	Device PushDevice `json:"device"`
+	IP     string     `json:"ip"` // Client address the device registered from, counted against its limit
+}
+
+// fcmJob is a single notification to send to a single device
+type fcmJob struct {
+	token string
+	title string
+	body  string
+	data  map[string]string
+}
+
+// FCMSink pushes notifications to registered mobile devices via Firebase Cloud Messaging
+type FCMSink struct {
+	client     *http.Client
+	projectID  string
+	appToken   string // Bearer token of the registration routes
+	path       string // File the devices are persisted to, empty to keep them in memory
+	maxDevices int
+	mutex      sync.RWMutex
+	devices    map[string]pushRegistration
+	perIP      map[string]int // Devices registered by client address
+	queue      chan fcmJob
+}
+
+// PushNotifications is the FCM sink, nil when push notifications are disabled
+var PushNotifications *FCMSink
+
+// setupFCMSink registers the FCM integration when FCM_CREDENTIALS_FILE is set
+func setupFCMSink() error {
+	path := os.Getenv(fcmCredentialsFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read FCM credentials: %w", err)
+	}
+
+	credentials, err := google.CredentialsFromJSON(context.Background(), data, fcmScope)
+	if err != nil {
+		return fmt.Errorf("failed to parse FCM credentials: %w", err)
+	}
+	if credentials.ProjectID == "" {
+		return fmt.Errorf("FCM credentials do not contain a project ID")
+	}
+	appToken := os.Getenv(fcmAppTokenEnv)
+	if appToken == "" {
+		return fmt.Errorf("push notifications require %s, the token the app registers devices with", fcmAppTokenEnv)
+	}
+	registerSecret(appToken)
+	maxDevices, err := strconv.Atoi(envOrDefault(fcmMaxDevicesEnv, strconv.Itoa(defaultFCMMaxDevices)))
+	if err != nil || maxDevices <= 0 {
+		return fmt.Errorf("invalid %s", fcmMaxDevicesEnv)
+	}
+
+	sink := &FCMSink{
+		client:     &http.Client{Transport: &oauth2.Transport{Source: credentials.TokenSource}, Timeout: webhookRequestTimeout},
+		projectID:  credentials.ProjectID,
+		appToken:   appToken,
+		path:       os.Getenv(fcmDevicesFileEnv),
+		maxDevices: maxDevices,
+		devices:    make(map[string]pushRegistration),
+		perIP:      make(map[string]int),
+		queue:      make(chan fcmJob, fcmQueueSize),
+	}
+	if err := sink.load(); err != nil {
+		return err
+	}
+	for i := 0; i < fcmWorkers; i++ {
+		go sink.sendLoop()
+	}
+
+	PushNotifications = sink
+	RegisterSink(sink)
+
+	slog.Info("Push notifications enabled", "project", credentials.ProjectID, "devices", len(sink.devices))
+	return nil
+}
+
+// load reads the devices from FCM_DEVICES_FILE, if it exists
+func (f *FCMSink) load() error {
+	if f.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read push devices file: %w", err)
+	}
+
+	var registrations []pushRegistration
+	if err := json.Unmarshal(data, &registrations); err != nil {
+		return fmt.Errorf("failed to parse push devices file: %w", err)
+	}
+	for i, registration := range registrations {
+		if err := registration.Device.Filter.Compile(); err != nil {
+			return fmt.Errorf("push devices file: device %d: %w", i, err)
+		}
+		f.devices[registration.Device.Token] = registration
+		f.perIP[registration.IP]++
+	}
+	return nil
+}
+
+// saveLocked writes the devices to the file, through a temporary file so a
+// crash never leaves it half written; the caller must hold the mutex
+// The change stays in effect when it cannot be saved
+func (f *FCMSink) saveLocked() error {
+	if f.path == "" {
+		return nil
+	}
+
+	registrations := make([]pushRegistration, 0, len(f.devices))
+	for _, registration := range f.devices {
+		registrations = append(registrations, registration)
+	}
+	data, err := json.Marshal(registrations)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSavePushDevices, err)
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSavePushDevices, err)
+	}
+	defer os.Remove(temporary.Name())
+
+	_, err = temporary.Write(data)
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), f.path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSavePushDevices, err)
+	}
+	return nil
+}
+
+// Name identifies the sink in logs
+func (f *FCMSink) Name() string {
+	return "fcm"
+}
+
+// Register adds or replaces a device registration
+// A replaced device keeps counting against the address it first registered
+// from; new ones are refused past the total and per address limits.
+//
+// Parameters:
+//   - device: Device to register
+//   - ip: Client address registering it
+//
+// Returns:
+//   - error: Error if the device is invalid or over a limit, or errSavePushDevices
+//     if it was registered but could not be saved
+func (f *FCMSink) Register(device PushDevice, ip string) error {
+	if device.Token == "" {
+		return fmt.Errorf("token must be set")
+	}
+	if err := device.Filter.Compile(); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	registration, exists := f.devices[device.Token]
+	if !exists {
+		switch {
+		case len(f.devices) >= f.maxDevices:
+			return errPushDevicesFull
+		case f.perIP[ip] >= fcmMaxDevicesPerIP:
+			return errPushDevicesPerIP
+		}
+		registration.IP = ip
+		f.perIP[ip]++
+	}
+	registration.Device = device
+	f.devices[device.Token] = registration
+	return f.saveLocked()
+}
+
+// Unregister removes a device registration
+//
+// Returns:
+//   - bool: false if the token was not registered
+//   - error: errSavePushDevices if it was removed but could not be saved
+func (f *FCMSink) Unregister(token string) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	registration, exists := f.devices[token]
+	if !exists {
+		return false, nil
+	}
+	delete(f.devices, token)
+	if f.perIP[registration.IP]--; f.perIP[registration.IP] <= 0 {
+		delete(f.perIP, registration.IP)
+	}
+	return true, f.saveLocked()
+}
+
+// authorized reports whether a request carries the app token
+func (f *FCMSink) authorized(r *http.Request) bool {
+	presented, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return found && subtle.ConstantTimeCompare([]byte(presented), []byte(f.appToken)) == 1
+}
+
+// Publish queues a notification for every device whose filter matches
+func (f *FCMSink) Publish(event Event) {
+	// Trades are never pushed unless asked for; skip the lookup on the firehose
+	if event.Type == EventTrade && !f.anyDeviceWants(EventTrade) {
+		return
+	}
+
+	record, found := Tokens.Get(event.Mint)
+	if !found {
+		return
+	}
+	flags := newRiskFlagSet(record)
+	title, body := formatPushNotification(event, record)
+
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	for _, registration := range f.devices {
+		device := registration.Device
+		if !device.Filter.Matches(event, record, flags) {
+			continue
+		}
+
+		job := fcmJob{
+			token: device.Token,
+			title: title,
+			body:  body,
+			data:  map[string]string{"type": string(event.Type), "mint": event.Mint},
+		}
+		select {
+		case f.queue <- job:
+		default:
+			slog.Warn("FCM queue full, dropping notification", logKeyEventType, event.Type, logKeyMint, event.Mint)
+		}
+	}
+}
+
+// anyDeviceWants reports whether any device explicitly subscribed to an event type
+func (f *FCMSink) anyDeviceWants(eventType EventType) bool {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	for _, registration := range f.devices {
+		for _, wanted := range registration.Device.Filter.EventTypes {
+			if wanted == eventType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendLoop sends queued notifications, dropping devices FCM reports as unregistered
+func (f *FCMSink) sendLoop() {
+	for job := range f.queue {
+		for attempt := 1; attempt <= fcmMaxAttempts; attempt++ {
+			retry, err := f.send(job)
+			if err == nil {
+				break
+			}
+
+			if strings.Contains(err.Error(), "UNREGISTERED") {
+				if _, err := f.Unregister(job.token); err != nil {
+					slog.Error("Failed to save push devices", logKeyError, err)
+				}
+				break
+			}
+
+			slog.Warn("Failed to send push notification", logKeyError, err)
+			if !retry {
+				break
+			}
+			time.Sleep(fcmRetryDelay)
+		}
+	}
+}
+
+// send delivers a single notification
+//
+// Returns:
+//   - bool: true if the failure is transient and worth retrying
+//   - error: any error that occurred while sending
+func (f *FCMSink) send(job fcmJob) (bool, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        job.token,
+			"notification": map[string]string{"title": job.title, "body": job.body},
+			"data":         job.data,
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := f.client.Post(fmt.Sprintf(fcmSendURL, f.projectID), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+
+	var result struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s: %s %s", resp.Status, result.Error.Status, result.Error.Message)
+}
+
+// formatPushNotification returns the title and body of a push notification
+func formatPushNotification(event Event, record TokenRecord) (string, string) {
+	body := fmt.Sprintf("%s ($%s)", record.Creation.Name, record.Creation.Symbol)
+
+	switch event.Type {
+	case EventComplete:
+		return "Token graduated", body
+	case EventTrade:
+		return "Trade", body
+	case EventPrice:
+		if data, ok := event.Data.(PriceEvent); ok {
+			body += " at " + formatUSDPrice(data.PriceUSD)
+		}
+		return "Price update", body
+	case EventMigration:
+		if data, ok := event.Data.(MigrationEvent); ok {
+			body += ", LP " + formatLPStatus(data.LP)
+		}
+		return "Token migrated", body
+	case EventRevert:
+		if data, ok := event.Data.(RevertEvent); ok {
+			body += ", " + data.Reason
+		}
+		return "Creation reverted", body
+	default:
+		return "New token", body
+	}
+}
+
+// authorizePushRequest answers the request unless push notifications are
+// enabled and it carries the app token; rejected tokens count towards the
+// automatic ban of the address
+//
+// Returns:
+//   - bool: True if the request may proceed
+func authorizePushRequest(w http.ResponseWriter, r *http.Request) bool {
+	if PushNotifications == nil {
+		writeError(w, http.StatusNotFound, "push notifications are disabled")
+		return false
+	}
+	if refuseBanned(w, clientIP(r)) {
+		return false
+	}
+	if !PushNotifications.authorized(r) {
+		AutoBan.Record(clientIP(r), abuseAuthFailure)
+		writeError(w, http.StatusUnauthorized, "invalid app token")
+		return false
+	}
+	return true
+}
+
+// HandleRegisterPushDevice registers a device token for push notifications
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the app token and a PushDevice body
+func HandleRegisterPushDevice(w http.ResponseWriter, r *http.Request) {
+	if !authorizePushRequest(w, r) {
+		return
+	}
+
+	var device PushDevice
+	if err := json.NewDecoder(r.Body).Decode(&device); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	err := PushNotifications.Register(device, clientIP(r))
+	switch {
+	case errors.Is(err, errPushDevicesFull):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	case errors.Is(err, errPushDevicesPerIP):
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	case errors.Is(err, errSavePushDevices):
+		// Registered until the restart
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, device)
+}
+
+// HandleUnregisterPushDevice removes a device token
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the app token, and the device token as a path variable
+func HandleUnregisterPushDevice(w http.ResponseWriter, r *http.Request) {
+	if !authorizePushRequest(w, r) {
+		return
+	}
+
+	removed, err := PushNotifications.Unregister(mux.Vars(r)["token"])
+	if !removed {
+		writeError(w, http.StatusNotFound, "device not registered")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
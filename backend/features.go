@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Environment variable with comma-separated feature flags to turn on or
+	// off, e.g. decode.trade=off,sink.slack=off; flags not named keep their default
+	featureFlagsEnv = "FEATURE_FLAGS"
+
+	// Admin endpoints listing and toggling feature flags
+	adminFeaturesEndpoint = "/admin/features"
+	adminFeatureEndpoint  = "/admin/features/{name}"
+
+	// Prefix of the flag every sink gets, followed by its name
+	sinkFeaturePrefix = "sink."
+)
+
+// Feature flags gating subsystems that can be turned off while running
+const (
+	featureDecodeTrade      = "decode.trade"     // Decoding trade events, which update curve state
+	featureDecodeComplete   = "decode.complete"  // Decoding curve completion events
+	featureGeoIP            = "geoip"            // Looking up the location of WebSocket clients
+	featureMarketData       = "enrich.market"    // Adding external market data to events
+	featureDerivedAddresses = "enrich.addresses" // Adding the derived accounts of the token to events
+	featureAutoBuy          = "autobuy"          // Buying tokens matching the auto-buy rules, the kill switch of the engine
+)
+
+// FeatureFlag describes a feature flag in the admin API
+type FeatureFlag struct {
+	Name        string `json:"name"`        // Name of the flag
+	Description string `json:"description"` // What the flag gates
+	Enabled     bool   `json:"enabled"`     // Whether the feature is on
+	Configured  bool   `json:"configured"`  // Value from the default and FEATURE_FLAGS
+	Overridden  bool   `json:"overridden"`  // Whether an admin toggled it, overriding the configuration
+}
+
+// featureFlag holds the state of one flag; enabled is read on hot paths
+type featureFlag struct {
+	description  string
+	defaultValue bool
+	configured   bool
+	overridden   bool
+	enabled      atomic.Bool
+}
+
+// FeatureFlagSet holds the feature flags, so risky subsystems can be turned
+// off without a redeploy. Admin toggles last until cleared or the restart.
+type FeatureFlagSet struct {
+	mutex sync.RWMutex
+	flags map[string]*featureFlag
+}
+
+// Features holds the feature flags of the process
+var Features = newFeatureFlagSet()
+
+// newFeatureFlagSet creates the set with the built-in flags, all on
+func newFeatureFlagSet() *FeatureFlagSet {
+	set := &FeatureFlagSet{flags: make(map[string]*featureFlag)}
+	set.Define(featureDecodeTrade, "Decode trade events and update curve state", true)
+	set.Define(featureDecodeComplete, "Decode bonding curve completion events", true)
+	set.Define(featureGeoIP, "Look up the location of WebSocket clients", true)
+	set.Define(featureMarketData, "Add market data from the configured provider to events", true)
+	set.Define(featureDerivedAddresses, "Add the bonding curve and token accounts of the token to events", true)
+	set.Define(featureAutoBuy, "Buy tokens matching the auto-buy rules with the wallet", true)
+	return set
+}
+
+// setupFeatureFlags applies FEATURE_FLAGS, once every sink has its flag
+func setupFeatureFlags() error {
+	if err := Features.Configure(os.Getenv(featureFlagsEnv)); err != nil {
+		return fmt.Errorf("invalid %s: %w", featureFlagsEnv, err)
+	}
+	return nil
+}
+
+// Define adds a flag unless it exists, so subsystems may define their flag as
+// they set up
+//
+// Parameters:
+//   - name: Name of the flag
+//   - description: What the flag gates
+//   - enabled: Default value
+func (s *FeatureFlagSet) Define(name, description string, enabled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, exists := s.flags[name]; exists {
+		return
+	}
+
+	flag := &featureFlag{description: description, defaultValue: enabled, configured: enabled}
+	flag.enabled.Store(enabled)
+	s.flags[name] = flag
+}
+
+// Enabled reports whether a feature is on; flags never defined are on
+func (s *FeatureFlagSet) Enabled(name string) bool {
+	s.mutex.RLock()
+	flag := s.flags[name]
+	s.mutex.RUnlock()
+	return flag == nil || flag.enabled.Load()
+}
+
+// Configure resets every flag to its default, then applies a FEATURE_FLAGS
+// value; flags toggled through the admin API keep their value
+//
+// Returns:
+//   - error: Error if an entry is malformed or names an unknown flag, in which case nothing changes
+func (s *FeatureFlagSet) Configure(value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	configured := make(map[string]bool, len(s.flags))
+	for name, flag := range s.flags {
+		configured[name] = flag.defaultValue
+	}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, setting, _ := strings.Cut(entry, "=")
+		if _, known := s.flags[name]; !known {
+			return fmt.Errorf("unknown feature flag %q, expected one of %s", name, strings.Join(s.namesLocked(), ", "))
+		}
+		enabled, err := parseSwitch(setting)
+		if err != nil {
+			return fmt.Errorf("feature flag %s: %w", name, err)
+		}
+		configured[name] = enabled
+	}
+
+	for name, flag := range s.flags {
+		flag.configured = configured[name]
+		if !flag.overridden {
+			flag.enabled.Store(flag.configured)
+		}
+	}
+	return nil
+}
+
+// parseSwitch parses on, off or a boolean
+func parseSwitch(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("expected on or off, got %q", value)
+	}
+	return enabled, nil
+}
+
+// Set overrides the configured value of a flag
+//
+// Returns:
+//   - bool: False if there is no such flag
+func (s *FeatureFlagSet) Set(name string, enabled bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	flag := s.flags[name]
+	if flag == nil {
+		return false
+	}
+	flag.overridden = true
+	flag.enabled.Store(enabled)
+	return true
+}
+
+// Clear drops the override of a flag, returning it to its configured value
+//
+// Returns:
+//   - bool: False if there is no such flag
+func (s *FeatureFlagSet) Clear(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	flag := s.flags[name]
+	if flag == nil {
+		return false
+	}
+	flag.overridden = false
+	flag.enabled.Store(flag.configured)
+	return true
+}
+
+// List returns every flag, sorted by name
+func (s *FeatureFlagSet) List() []FeatureFlag {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	list := make([]FeatureFlag, 0, len(s.flags))
+	for _, name := range s.namesLocked() {
+		flag := s.flags[name]
+		list = append(list, FeatureFlag{
+			Name:        name,
+			Description: flag.description,
+			Enabled:     flag.enabled.Load(),
+			Configured:  flag.configured,
+			Overridden:  flag.overridden,
+		})
+	}
+	return list
+}
+
+// Get returns one flag
+//
+// Returns:
+//   - FeatureFlag: The flag
+//   - bool: False if there is no such flag
+func (s *FeatureFlagSet) Get(name string) (FeatureFlag, bool) {
+	for _, flag := range s.List() {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return FeatureFlag{}, false
+}
+
+// namesLocked returns the sorted flag names; the caller must hold the mutex
+func (s *FeatureFlagSet) namesLocked() []string {
+	names := make([]string, 0, len(s.flags))
+	for name := range s.flags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// featureRequest is the JSON body toggling a flag
+type featureRequest struct {
+	Enabled *bool `json:"enabled"` // New value of the flag
+}
+
+// HandleListFeatures returns every feature flag
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListFeatures(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Features.List())
+}
+
+// HandleSetFeature turns the flag in the path on or off until cleared
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the flag name as a path variable and a featureRequest body
+func HandleSetFeature(w http.ResponseWriter, r *http.Request) {
+	var request featureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Enabled == nil {
+		writeError(w, http.StatusBadRequest, `expected a JSON body like {"enabled":false}`)
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if !Features.Set(name, *request.Enabled) {
+		writeError(w, http.StatusNotFound, "unknown feature flag")
+		return
+	}
+
+	slog.Info("Feature flag toggled", "flag", name, "enabled", *request.Enabled)
+	flag, _ := Features.Get(name)
+	writeJSON(w, http.StatusOK, flag)
+}
+
+// HandleClearFeature returns the flag in the path to its configured value
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the flag name as a path variable
+func HandleClearFeature(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !Features.Clear(name) {
+		writeError(w, http.StatusNotFound, "unknown feature flag")
+		return
+	}
+
+	flag, _ := Features.Get(name)
+	slog.Info("Feature flag override cleared", "flag", name, "enabled", flag.Enabled)
+	writeJSON(w, http.StatusOK, flag)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Endpoints serving the recent creations as RSS 2.0 and Atom feeds
+	rssFeedEndpoint  = "/feed.rss"
+	atomFeedEndpoint = "/feed.atom"
+
+	// Number of creations listed in the feeds
+	feedItemCount = 50
+
+	// Title and description published in the feeds
+	feedTitle       = "New pump.fun tokens"
+	feedDescription = "Tokens created on pump.fun, as observed by the Nova feed"
+)
+
+// feedLinkSchemes are the schemes of metadata URIs linked in feed items,
+// others such as javascript: being rendered as text
+var feedLinkSchemes = []string{"http", "https", "ipfs"}
+
+// rssFeed is the root element of an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel describes the feed and holds its items
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssItem is a single creation in the RSS feed
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+// rssGUID identifies an RSS item; mints are not URLs so isPermaLink is false
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// atomFeed is the root element of an Atom document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomLink references a related resource
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// atomEntry is a single creation in the Atom feed
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Summary atomContent `xml:"summary"`
+}
+
+// atomContent is text content with an explicit type
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// registerFeedRoutes registers the syndication feed endpoints on the given router
+func registerFeedRoutes(router *mux.Router) {
+	router.HandleFunc(rssFeedEndpoint, HandleRSSFeed).Methods(http.MethodGet)
+	router.HandleFunc(atomFeedEndpoint, HandleAtomFeed).Methods(http.MethodGet)
+}
+
+// HandleRSSFeed serves the most recent creations as an RSS 2.0 feed
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleRSSFeed(w http.ResponseWriter, r *http.Request) {
+	records := Tokens.Recent(feedItemCount)
+
+	channel := rssChannel{
+		Title:         feedTitle,
+		Link:          feedBaseURL(r),
+		Description:   feedDescription,
+		LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		Items:         make([]rssItem, 0, len(records)),
+	}
+	for _, record := range records {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       feedItemTitle(record),
+			Link:        pumpFunLink(record.Mint),
+			Description: feedItemSummary(record),
+			GUID:        rssGUID{Value: record.Mint},
+			PubDate:     record.CreatedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	writeXML(w, "application/rss+xml", rssFeed{Version: "2.0", Channel: channel})
+}
+
+// HandleAtomFeed serves the most recent creations as an Atom feed
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleAtomFeed(w http.ResponseWriter, r *http.Request) {
+	records := Tokens.Recent(feedItemCount)
+
+	updated := time.Now().UTC()
+	if len(records) > 0 {
+		updated = records[0].CreatedAt
+	}
+
+	feed := atomFeed{
+		Title:   feedTitle,
+		ID:      feedBaseURL(r) + atomFeedEndpoint,
+		Updated: updated.Format(time.RFC3339),
+		Link:    atomLink{Href: feedBaseURL(r) + atomFeedEndpoint, Rel: "self"},
+		Entries: make([]atomEntry, 0, len(records)),
+	}
+	for _, record := range records {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   feedItemTitle(record),
+			ID:      "urn:solana:mint:" + record.Mint,
+			Updated: record.CreatedAt.Format(time.RFC3339),
+			Link:    atomLink{Href: pumpFunLink(record.Mint)},
+			Summary: atomContent{Type: "html", Value: feedItemSummary(record)},
+		})
+	}
+
+	writeXML(w, "application/atom+xml", feed)
+}
+
+// feedItemTitle returns the title of a feed item
+func feedItemTitle(record TokenRecord) string {
+	return fmt.Sprintf("%s ($%s)", record.Creation.Name, record.Creation.Symbol)
+}
+
+// feedItemSummary returns the HTML body of a feed item
+func feedItemSummary(record TokenRecord) string {
+	return fmt.Sprintf(
+		`<p>Mint: <code>%s</code><br>Creator: <code>%s</code><br>Metadata: %s</p><p><a href="%s">Solscan</a></p>`,
+		record.Mint, record.Creator, feedMetadataLink(record.Creation.Uri), solscanLink(record.Mint),
+	)
+}
+
+// feedMetadataLink returns the HTML of a metadata URI: a link when its scheme
+// is one of feedLinkSchemes, the escaped URI otherwise
+// The URI is chosen by the token creator, so it is never trusted as a link target
+func feedMetadataLink(uri string) string {
+	escaped := html.EscapeString(uri)
+	parsed, err := url.Parse(uri)
+	if err != nil || !slices.Contains(feedLinkSchemes, strings.ToLower(parsed.Scheme)) {
+		return escaped
+	}
+	return fmt.Sprintf(`<a href="%s">%s</a>`, escaped, escaped)
+}
+
+// feedBaseURL returns the scheme and host the request was made to
+func feedBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// writeXML writes the given value as an XML document with the given content type
+func writeXML(w http.ResponseWriter, contentType string, value interface{}) {
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Write([]byte(xml.Header))
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		slog.Warn("Failed to write XML response", logKeyError, err)
+	}
+}
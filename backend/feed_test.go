@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+// TestFeedMetadataLink checks that only web and IPFS metadata URIs are linked,
+// and that every URI is escaped
+func TestFeedMetadataLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected string
+	}{
+		{name: "https", uri: "https://example.com/a.json", expected: `<a href="https://example.com/a.json">https://example.com/a.json</a>`},
+		{name: "http", uri: "http://example.com/a.json", expected: `<a href="http://example.com/a.json">http://example.com/a.json</a>`},
+		{name: "ipfs", uri: "ipfs://QmHash", expected: `<a href="ipfs://QmHash">ipfs://QmHash</a>`},
+		{name: "upper case scheme", uri: "HTTPS://example.com", expected: `<a href="HTTPS://example.com">HTTPS://example.com</a>`},
+		{name: "escaped query", uri: `https://example.com/?a=1&b="2"`, expected: `<a href="https://example.com/?a=1&amp;b=&#34;2&#34;">https://example.com/?a=1&amp;b=&#34;2&#34;</a>`},
+		{name: "javascript", uri: "javascript:alert(1)", expected: "javascript:alert(1)"},
+		{name: "data", uri: "data:text/html,<script>alert(1)</script>", expected: "data:text/html,&lt;script&gt;alert(1)&lt;/script&gt;"},
+		{name: "relative", uri: "/metadata.json", expected: "/metadata.json"},
+		{name: "unparsable", uri: "http://[::1", expected: "http://[::1"},
+		{name: "empty", uri: "", expected: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := feedMetadataLink(test.uri); got != test.expected {
+				t.Fatalf("got %s, expected %s", got, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file of filter scripts, read at
+	// startup and on every configuration reload
+	filterScriptsFileEnv = "FILTER_SCRIPTS_FILE"
+
+	// Admin endpoint listing the filter scripts with their counters
+	adminFilterScriptsEndpoint = "/admin/filter-scripts"
+)
+
+// FilterScriptConfig describes an operator-supplied script run on every event
+// before delivery
+// Drop and the fields are expr expressions over the fields alert rules use,
+// plus fields, the map of the fields computed by the scripts before.
+type FilterScriptConfig struct {
+	Name   string            `json:"name"`             // Script name, for logs and the admin API
+	Drop   string            `json:"drop,omitempty"`   // Boolean expression dropping the events it matches, e.g. symbol matches "(?i)scam"
+	Fields map[string]string `json:"fields,omitempty"` // Fields added to the event, by name, e.g. "dev_share": "dev_buy_sol / 85"
+}
+
+// FilterScriptInfo is the admin view of a filter script
+type FilterScriptInfo struct {
+	FilterScriptConfig
+	Evaluated uint64 `json:"evaluated"` // Events the script ran on
+	Dropped   uint64 `json:"dropped"`   // Events it dropped
+	Errors    uint64 `json:"errors"`    // Evaluations that failed; the event is kept
+}
+
+// filterScriptEnv holds what a filter script expression can use
+type filterScriptEnv struct {
+	alertRuleEnv
+	Fields map[string]any `expr:"fields"` // Fields computed by the scripts before
+}
+
+// filterScript is a compiled filter script with its counters
+type filterScript struct {
+	config    FilterScriptConfig
+	drop      *vm.Program // Nil when the script only adds fields
+	fields    []string    // Names of the fields, sorted
+	programs  map[string]*vm.Program
+	evaluated atomic.Uint64
+	dropped   atomic.Uint64
+	errors    atomic.Uint64
+}
+
+// filterScripts holds the scripts loaded from FILTER_SCRIPTS_FILE, in file order
+var filterScripts atomic.Pointer[[]*filterScript]
+
+// setupFilterScripts runs the filter scripts at the end of the enrich stage,
+// so they see every enrichment, and loads FILTER_SCRIPTS_FILE when it is set
+// It runs after the enrichment setups.
+func setupFilterScripts() error {
+	Pipeline.Register(StageEnrich, "filter scripts", runFilterScripts)
+
+	if os.Getenv(filterScriptsFileEnv) == "" {
+		return nil
+	}
+	if err := loadFilterScripts(); err != nil {
+		return err
+	}
+	slog.Info("Loaded filter scripts", "count", len(*filterScripts.Load()), "path", os.Getenv(filterScriptsFileEnv))
+	return nil
+}
+
+// loadFilterScripts compiles the scripts of FILTER_SCRIPTS_FILE and replaces
+// the running ones; they are kept when a script does not compile
+func loadFilterScripts() error {
+	data, err := os.ReadFile(os.Getenv(filterScriptsFileEnv))
+	if err != nil {
+		return fmt.Errorf("failed to read filter scripts file: %w", err)
+	}
+	var configs []FilterScriptConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("failed to parse filter scripts file: %w", err)
+	}
+
+	scripts := make([]*filterScript, 0, len(configs))
+	for _, config := range configs {
+		script, err := compileFilterScript(config)
+		if err != nil {
+			return fmt.Errorf("filter script %q: %w", config.Name, err)
+		}
+		scripts = append(scripts, script)
+	}
+	filterScripts.Store(&scripts)
+	return nil
+}
+
+// compileFilterScript compiles the expressions of a script
+func compileFilterScript(config FilterScriptConfig) (*filterScript, error) {
+	if strings.TrimSpace(config.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if config.Drop == "" && len(config.Fields) == 0 {
+		return nil, fmt.Errorf("drop or fields is required")
+	}
+
+	script := &filterScript{config: config, programs: make(map[string]*vm.Program, len(config.Fields))}
+	if config.Drop != "" {
+		program, err := expr.Compile(config.Drop, expr.Env(filterScriptEnv{}), expr.AsBool())
+		if err != nil {
+			return nil, fmt.Errorf("invalid drop expression: %w", err)
+		}
+		script.drop = program
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Fields)) {
+		program, err := expr.Compile(config.Fields[name], expr.Env(filterScriptEnv{}))
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression of field %s: %w", name, err)
+		}
+		script.fields = append(script.fields, name)
+		script.programs[name] = program
+	}
+	return script, nil
+}
+
+// runFilterScripts runs the scripts in order, dropping the event at the first
+// script that drops it and adding the computed fields to it otherwise
+// A failing expression is counted and skipped: scripts never drop an event by error.
+func runFilterScripts(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		scripts := filterScripts.Load()
+		if scripts == nil || len(*scripts) == 0 {
+			return next(ctx, event)
+		}
+		fields, ok := newAlertRuleEnv(event)
+		if !ok {
+			return next(ctx, event)
+		}
+
+		env := filterScriptEnv{alertRuleEnv: fields, Fields: event.Fields}
+		if env.Fields == nil {
+			env.Fields = make(map[string]any)
+		}
+		for _, script := range *scripts {
+			script.evaluated.Add(1)
+			if script.drop != nil {
+				result, err := expr.Run(script.drop, env)
+				if err != nil {
+					script.errors.Add(1)
+					slog.Debug("Filter script failed", "script", script.config.Name, logKeyError, err)
+				} else if drop, _ := result.(bool); drop {
+					script.dropped.Add(1)
+					return nil
+				}
+			}
+			for _, name := range script.fields {
+				value, err := expr.Run(script.programs[name], env)
+				if err != nil {
+					script.errors.Add(1)
+					slog.Debug("Filter script failed", "script", script.config.Name, "field", name, logKeyError, err)
+					continue
+				}
+				env.Fields[name] = value
+			}
+		}
+		if len(env.Fields) > 0 {
+			event.Fields = env.Fields
+		}
+		return next(ctx, event)
+	}
+}
+
+// HandleListFilterScripts returns the filter scripts with their counters, in the order they run
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListFilterScripts(w http.ResponseWriter, r *http.Request) {
+	infos := []FilterScriptInfo{}
+	if scripts := filterScripts.Load(); scripts != nil {
+		for _, script := range *scripts {
+			infos = append(infos, FilterScriptInfo{
+				FilterScriptConfig: script.config,
+				Evaluated:          script.evaluated.Load(),
+				Dropped:            script.dropped.Load(),
+				Errors:             script.errors.Load(),
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
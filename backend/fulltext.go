@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the directory of a persistent full-text index
+	// The index is kept in memory and covers tracked tokens only when it is unset
+	searchIndexPathEnv = "SEARCH_INDEX_PATH"
+
+	// Number of index operations buffered before new ones are dropped
+	fullTextQueueSize = 10000
+
+	// Number of workers indexing tokens and fetching their metadata
+	fullTextWorkers = 8
+
+	// Boosts of the indexed fields; a symbol hit is the strongest signal
+	symbolBoost      = 4
+	nameBoost        = 3
+	descriptionBoost = 1
+)
+
+// fullTextDocument is the document indexed for every token
+type fullTextDocument struct {
+	Name        string    `json:"name"`        // Token name
+	Symbol      string    `json:"symbol"`      // Token symbol
+	Description string    `json:"description"` // Description from the metadata document
+	CreatedAt   time.Time `json:"created_at"`  // Time the creation was observed, breaks score ties
+}
+
+// fullTextOp is a queued change to the index
+type fullTextOp struct {
+	mint   string      // Token the change applies to
+	record TokenRecord // Record to index; ignored for deletions
+	delete bool        // True to remove the token from the index
+}
+
+// FullTextIndex indexes token names, symbols and metadata descriptions with
+// bleve and answers fuzzy searches for the search endpoint
+// Tokens are indexed as soon as they are created and indexed again once their
+// metadata description has been fetched
+type FullTextIndex struct {
+	index      bleve.Index
+	persistent bool
+	queue      chan fullTextOp
+}
+
+// FullText is the token search index, nil until setupFullTextIndex has run
+var FullText *FullTextIndex
+
+// setupFullTextIndex opens the search index and registers it as a sink
+func setupFullTextIndex() error {
+	path := os.Getenv(searchIndexPathEnv)
+
+	var index bleve.Index
+	var err error
+	switch {
+	case path == "":
+		index, err = bleve.NewMemOnly(fullTextMapping())
+	case directoryExists(path):
+		index, err = bleve.Open(path)
+	default:
+		index, err = bleve.New(path, fullTextMapping())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open search index: %w", err)
+	}
+
+	fullText := &FullTextIndex{
+		index:      index,
+		persistent: path != "",
+		queue:      make(chan fullTextOp, fullTextQueueSize),
+	}
+	for i := 0; i < fullTextWorkers; i++ {
+		go fullText.indexLoop()
+	}
+
+	// A memory index only covers what the store tracks; a persistent one keeps
+	// every token, whose records the storage backend can still resolve
+	if !fullText.persistent {
+		Tokens.OnEvict = func(mint string) {
+			fullText.enqueue(fullTextOp{mint: mint, delete: true})
+		}
+	}
+
+	indexed := fullText.indexTracked()
+	FullText = fullText
+	RegisterSink(fullText)
+
+	if fullText.persistent {
+		slog.Info("Full-text search index opened", "path", path, "restored_tokens", indexed)
+	} else {
+		slog.Info("Full-text search index in memory", "restored_tokens", indexed)
+	}
+	return nil
+}
+
+// fullTextMapping maps the document fields to analyzed text, and the creation
+// time to a date used for sorting
+func fullTextMapping() *mapping.IndexMappingImpl {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = "standard"
+
+	created := bleve.NewDateTimeFieldMapping()
+
+	document := bleve.NewDocumentMapping()
+	document.AddFieldMappingsAt("name", text)
+	document.AddFieldMappingsAt("symbol", text)
+	document.AddFieldMappingsAt("description", text)
+	document.AddFieldMappingsAt("created_at", created)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = document
+	return indexMapping
+}
+
+// directoryExists reports whether path is an existing directory
+func directoryExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// Name identifies the sink in logs
+func (f *FullTextIndex) Name() string {
+	return "fulltext"
+}
+
+// Publish queues newly created tokens for indexing
+func (f *FullTextIndex) Publish(event Event) {
+	if event.Type != EventCreate {
+		return
+	}
+	if record, found := Tokens.Get(event.Mint); found {
+		f.enqueue(fullTextOp{mint: event.Mint, record: record})
+	}
+}
+
+// enqueue queues an index operation, dropping it if the queue is full
+func (f *FullTextIndex) enqueue(op fullTextOp) {
+	select {
+	case f.queue <- op:
+	default:
+		slog.Warn("Search index queue full, dropping update", logKeyMint, op.mint)
+	}
+}
+
+// indexLoop applies queued operations; new tokens are indexed by name and
+// symbol first so they are searchable at once, then with their description
+func (f *FullTextIndex) indexLoop() {
+	for op := range f.queue {
+		if op.delete {
+			if err := f.index.Delete(op.mint); err != nil {
+				slog.Error("Failed to remove token from the search index", logKeyMint, op.mint, logKeyError, err)
+			}
+			continue
+		}
+
+		document := fullTextDocument{
+			Name:      op.record.Creation.Name,
+			Symbol:    op.record.Creation.Symbol,
+			CreatedAt: op.record.CreatedAt,
+		}
+		if err := f.index.Index(op.mint, document); err != nil {
+			slog.Error("Failed to index token", logKeyMint, op.mint, logKeyError, err)
+			continue
+		}
+
+		if metadata, ok := fetchMetadata(op.record.Creation.Uri); ok && metadata.Description != "" {
+			document.Description = metadata.Description
+			if err := f.index.Index(op.mint, document); err != nil {
+				slog.Error("Failed to index token description", logKeyMint, op.mint, logKeyError, err)
+			}
+		}
+	}
+}
+
+// indexTracked adds tokens restored into the store that are not indexed yet
+// Their descriptions are not fetched, to avoid a burst of requests at startup
+func (f *FullTextIndex) indexTracked() int {
+	batch := f.index.NewBatch()
+	for _, record := range Tokens.Recent(maxTrackedTokens) {
+		if f.persistent {
+			if existing, err := f.index.Document(record.Mint); err == nil && existing != nil {
+				continue
+			}
+		}
+		batch.Index(record.Mint, fullTextDocument{
+			Name:      record.Creation.Name,
+			Symbol:    record.Creation.Symbol,
+			CreatedAt: record.CreatedAt,
+		})
+	}
+
+	count := batch.Size()
+	if err := f.index.Batch(batch); err != nil {
+		slog.Error("Failed to index restored tokens", logKeyError, err)
+		return 0
+	}
+	return count
+}
+
+// Search returns tokens matching the query, best match first
+// Misspellings are tolerated in proportion to the query length, and a query
+// also matches names and symbols it is a prefix of
+//
+// Parameters:
+//   - text: the text to search for
+//   - offset: number of ranked results to skip
+//   - limit: maximum number of results to return
+//
+// Returns:
+//   - []TokenRecord: the requested page of matching tokens
+//   - int: the total number of matching tokens
+//   - error: Error if the index could not be searched
+func (f *FullTextIndex) Search(text string, offset, limit int) ([]TokenRecord, int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return []TokenRecord{}, 0, nil
+	}
+
+	request := bleve.NewSearchRequestOptions(fullTextQuery(text), limit, offset, false)
+	request.SortBy([]string{"-_score", "-created_at"})
+
+	result, err := f.index.Search(request)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]TokenRecord, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		record, found := Tokens.Get(hit.ID)
+		if !found && Persistence != nil {
+			record, found, err = Persistence.GetToken(hit.ID)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		if found {
+			records = append(records, record)
+		}
+	}
+	return records, int(result.Total), nil
+}
+
+// fullTextQuery builds the query for a search: fuzzy matches on every field,
+// plus prefix matches on names and symbols for single-word queries
+func fullTextQuery(text string) query.Query {
+	fuzziness := 0
+	switch length := utf8.RuneCountInString(text); {
+	case length >= 8:
+		fuzziness = 2
+	case length >= 4:
+		fuzziness = 1
+	}
+
+	match := func(field string, boost float64) query.Query {
+		q := bleve.NewMatchQuery(text)
+		q.SetField(field)
+		q.SetFuzziness(fuzziness)
+		q.SetBoost(boost)
+		return q
+	}
+
+	queries := []query.Query{
+		match("symbol", symbolBoost),
+		match("name", nameBoost),
+		match("description", descriptionBoost),
+	}
+
+	if !strings.ContainsAny(text, " \t") {
+		for field, boost := range map[string]float64{"symbol": symbolBoost, "name": nameBoost} {
+			q := bleve.NewPrefixQuery(strings.ToLower(text))
+			q.SetField(field)
+			q.SetBoost(boost)
+			queries = append(queries, q)
+		}
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling funding tracing: when true, the wallets
+	// that funded each creator are followed back to a labelled source
+	fundingTraceEnv = "FUNDING_TRACE"
+
+	// Environment variable with the number of funding hops followed from the
+	// creator before a chain of fresh wallets is reported as such
+	fundingTraceHopsEnv = "FUNDING_TRACE_HOPS"
+
+	// Environment variable with a JSON file of address labels, merged over
+	// the built-in labels: {"<address>": {"kind": "cex", "name": "..."}}
+	fundingLabelsFileEnv = "FUNDING_LABELS_FILE"
+
+	// Hops followed when FUNDING_TRACE_HOPS is unset
+	defaultFundingTraceHops = 3
+
+	// Signatures listed per wallet; a wallet with fewer has its whole
+	// history listed, so its oldest transaction is the one that funded it
+	fundingSignatureLimit = 25
+
+	// How long the funding of a creator is reused
+	fundingTraceTTL = 24 * time.Hour
+
+	// Minimum time between the traces of two creators
+	fundingTraceInterval = time.Second
+
+	// Spacing of the RPC requests of a trace
+	fundingRequestInterval = 200 * time.Millisecond
+)
+
+// Kinds of funding sources; labelled kinds come from the address labels
+const (
+	fundingKindCEX              = "cex"                // Exchange hot wallet
+	fundingKindBridge           = "bridge"             // Cross-chain bridge
+	fundingKindFreshWalletChain = "fresh_wallet_chain" // Only fresh wallets within the hop limit
+	fundingKindEstablished      = "established_wallet" // An unlabelled wallet with a longer history
+	fundingKindUnknown          = "unknown"            // No funding transfer was found
+)
+
+// FundingLabel names a known address
+type FundingLabel struct {
+	Kind string `json:"kind"` // cex or bridge
+	Name string `json:"name"` // Exchange or bridge name
+}
+
+// defaultFundingLabels are labelled when FUNDING_LABELS_FILE does not
+// override them: exchange hot wallets, and bridge programs, which match when
+// the funding transaction invokes them
+var defaultFundingLabels = map[string]FundingLabel{
+	"9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM": {Kind: fundingKindCEX, Name: "Binance"},
+	"5tzFkiKscXHK5ZXCGbXZxdw7gTjjD1mBwuoFbhUvuAi9": {Kind: fundingKindCEX, Name: "Binance"},
+	"H8sMJSCQxfKiFTCfDR3DUMLPwcRbM61LGFJ8N4dK3WjS": {Kind: fundingKindCEX, Name: "Coinbase"},
+	"2AQdpHJ2JpcEgPiATUXjQxA8QmafFegfQwSLWSprPicm": {Kind: fundingKindCEX, Name: "Coinbase"},
+	"5VCwKtCXgCJ6kit5FybXjvriW3xELsFDhYrPSqtJNmcD": {Kind: fundingKindCEX, Name: "OKX"},
+	"AC5RDfQFmDS1deWZos921JfqscXdByf8BKHs5ACWjtW2": {Kind: fundingKindCEX, Name: "Bybit"},
+	"FWznbcNXWQuHTawe9RxvQ2LdCENssh12dsznf4RiouN5": {Kind: fundingKindCEX, Name: "Kraken"},
+	"BmFdpraQhkiDQE6SnfG5omcA1VwzqfXrwtNYBwWTymy6": {Kind: fundingKindCEX, Name: "KuCoin"},
+	"wormDTUJ6AWPNvk59vGQbDvGJmqbDTdgWgAqcLBCgUb":  {Kind: fundingKindBridge, Name: "Wormhole"},
+	"dst5MGcFPoBeREFAA5E3tU5ij8m5uVYwkzkSAbsLbNo":  {Kind: fundingKindBridge, Name: "deBridge"},
+}
+
+// FundingSource reports where the SOL of a creator came from
+// Each hop follows the largest SOL transfer into a fresh wallet's first
+// transaction; a wallet with more than 25 transactions ends the trace, as
+// its funding is no longer recent.
+type FundingSource struct {
+	Kind     string    `json:"kind"`           // cex, bridge, fresh_wallet_chain, established_wallet or unknown
+	Name     string    `json:"name,omitempty"` // Exchange or bridge name, for labelled kinds
+	Source   string    `json:"source"`         // Last wallet reached, the labelled one for labelled kinds
+	Hops     int       `json:"hops"`           // Transfers followed from the creator
+	Chain    []string  `json:"chain"`          // Wallets traced, the creator first
+	TracedAt time.Time `json:"traced_at"`      // Time of the trace
+}
+
+// FundingTracer follows the funding of creators over RPC
+type FundingTracer struct {
+	client *rpc.Client
+	hops   int
+	labels map[string]FundingLabel
+}
+
+// FundingSources caches the funding of creators when FUNDING_TRACE is set, nil otherwise
+var FundingSources *lookupCache[FundingSource]
+
+// setupFundingTrace traces the funding of creators when FUNDING_TRACE is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if a setting or the labels file is invalid, or no RPC
+//     endpoint can be resolved
+func setupFundingTrace(rpcURL string) error {
+	if !envBool(fundingTraceEnv) {
+		return nil
+	}
+	hops := defaultFundingTraceHops
+	if value := os.Getenv(fundingTraceHopsEnv); value != "" {
+		var err error
+		if hops, err = strconv.Atoi(value); err != nil || hops <= 0 {
+			return fmt.Errorf("invalid %s %q", fundingTraceHopsEnv, value)
+		}
+	}
+	labels, err := loadFundingLabels()
+	if err != nil {
+		return err
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	tracer := &FundingTracer{client: newEnrichmentRPCClient(endpoint), hops: hops, labels: labels}
+	FundingSources = newLookupCache("funding source", fundingTraceTTL, 1, fundingTraceInterval, tracer.fetch)
+	Pipeline.Register(StageEnrich, "funding source", addFundingSource)
+
+	slog.Info("Tracing the funding of creators", "max_hops", hops, "labelled_addresses", len(labels))
+	return nil
+}
+
+// loadFundingLabels returns the built-in labels with FUNDING_LABELS_FILE merged over them
+func loadFundingLabels() (map[string]FundingLabel, error) {
+	labels := make(map[string]FundingLabel, len(defaultFundingLabels))
+	for address, label := range defaultFundingLabels {
+		labels[address] = label
+	}
+
+	path := os.Getenv(fundingLabelsFileEnv)
+	if path == "" {
+		return labels, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read funding labels file: %w", err)
+	}
+	var overrides map[string]FundingLabel
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("failed to parse funding labels file: %w", err)
+	}
+	for address, label := range overrides {
+		if _, err := solana.PublicKeyFromBase58(address); err != nil {
+			return nil, fmt.Errorf("invalid address %q in funding labels file", address)
+		}
+		if label.Kind != fundingKindCEX && label.Kind != fundingKindBridge {
+			return nil, fmt.Errorf("invalid kind %q of %s in funding labels file, expected cex or bridge", label.Kind, address)
+		}
+		labels[address] = label
+	}
+	return labels, nil
+}
+
+// addFundingSource adds the funding of the creator to the event
+// A creation carries it when the creator was traced before, e.g. for a
+// creator launching again; otherwise the trace is queued, and the later
+// events of the token carry it once it completes.
+func addFundingSource(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		var creator string
+		if create, ok := event.Decoded.(*pumpstream.CreateEvent); ok {
+			creator = create.User.String()
+		} else if record, found := Tokens.Get(pipelineEventMint(event)); found {
+			creator = record.Creator
+		}
+		if creator != "" {
+			event.Funding = FundingSources.Lookup(creator)
+		}
+		return next(ctx, event)
+	}
+}
+
+// fetch traces the funding of each creator
+func (f *FundingTracer) fetch(ctx context.Context, creators []string) (map[string]FundingSource, error) {
+	sources := make(map[string]FundingSource, len(creators))
+	for _, creator := range creators {
+		wallet, err := solana.PublicKeyFromBase58(creator)
+		if err != nil {
+			continue
+		}
+		source, err := f.trace(ctx, wallet)
+		if err != nil {
+			return nil, err
+		}
+		sources[creator] = source
+	}
+	return sources, nil
+}
+
+// trace follows the funding of a wallet until a labelled address, an
+// established wallet or the hop limit is reached
+// Each hop makes two requests, each preceded by fundingRequestInterval.
+func (f *FundingTracer) trace(ctx context.Context, creator solana.PublicKey) (FundingSource, error) {
+	source := FundingSource{Kind: fundingKindUnknown, Source: creator.String(), Chain: []string{creator.String()}}
+	wallet := creator
+	for {
+		fresh, funder, label, err := f.funding(ctx, wallet)
+		if err != nil {
+			return FundingSource{}, err
+		}
+		if !funder.IsZero() {
+			source.Hops++
+			source.Source = funder.String()
+			source.Chain = append(source.Chain, funder.String())
+		}
+		switch {
+		case label != nil:
+			source.Kind, source.Name = label.Kind, label.Name
+		case !fresh:
+			source.Kind = fundingKindEstablished
+		case funder.IsZero():
+			// A fresh wallet without an incoming transfer stays unknown
+		case source.Hops < f.hops:
+			wallet = funder
+			continue
+		default:
+			source.Kind = fundingKindFreshWalletChain
+		}
+		source.TracedAt = time.Now().UTC()
+		return source, nil
+	}
+}
+
+// funding finds the transfer that funded a wallet
+//
+// Returns:
+//   - bool: True when the whole history of the wallet was listed
+//   - solana.PublicKey: Account whose balance dropped the most in the oldest
+//     transaction listed while the wallet's rose, zero if there is none
+//   - *FundingLabel: Label of a labelled account of that transaction, such as
+//     a bridge program, nil if none is labelled
+//   - error: Error if a request failed
+func (f *FundingTracer) funding(ctx context.Context, wallet solana.PublicKey) (bool, solana.PublicKey, *FundingLabel, error) {
+	limit := fundingSignatureLimit
+	time.Sleep(fundingRequestInterval)
+	signatures, err := f.client.GetSignaturesForAddressWithOpts(ctx, wallet, &rpc.GetSignaturesForAddressOpts{
+		Limit:      &limit,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return false, solana.PublicKey{}, nil, fmt.Errorf("failed to list the signatures of %s: %w", wallet, err)
+	}
+	fresh := len(signatures) < limit
+	if len(signatures) == 0 || !fresh {
+		return fresh, solana.PublicKey{}, nil, nil
+	}
+
+	maxVersion := uint64(0)
+	time.Sleep(fundingRequestInterval)
+	transaction, err := f.client.GetTransaction(ctx, signatures[len(signatures)-1].Signature, &rpc.GetTransactionOpts{
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return false, solana.PublicKey{}, nil, fmt.Errorf("failed to get the first transaction of %s: %w", wallet, err)
+	}
+	if transaction.Meta == nil {
+		return fresh, solana.PublicKey{}, nil, nil
+	}
+	decoded, err := transaction.Transaction.GetTransaction()
+	if err != nil {
+		return false, solana.PublicKey{}, nil, fmt.Errorf("failed to decode the first transaction of %s: %w", wallet, err)
+	}
+
+	// Balances follow the static keys, then the loaded writable and
+	// read-only addresses
+	keys := append(solana.PublicKeySlice{}, decoded.Message.AccountKeys...)
+	keys = append(keys, transaction.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, transaction.Meta.LoadedAddresses.ReadOnly...)
+
+	var label *FundingLabel
+	received := false
+	var funder solana.PublicKey
+	var largest uint64
+	for i, key := range keys {
+		if found, labelled := f.labels[key.String()]; labelled && label == nil && !key.Equals(wallet) {
+			label = &found
+		}
+		if i >= len(transaction.Meta.PreBalances) || i >= len(transaction.Meta.PostBalances) {
+			continue
+		}
+		pre, post := transaction.Meta.PreBalances[i], transaction.Meta.PostBalances[i]
+		switch {
+		case key.Equals(wallet):
+			received = post > pre
+		case pre > post && pre-post > largest:
+			funder, largest = key, pre-post
+		}
+	}
+	if !received {
+		return fresh, solana.PublicKey{}, label, nil
+	}
+	return fresh, funder, label, nil
+}
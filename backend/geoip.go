@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the path of a MaxMind country or city database,
+	// e.g. GeoLite2-Country.mmdb
+	geoIPCountryDBEnv = "GEOIP_COUNTRY_DB"
+
+	// Environment variable with the path of a MaxMind ASN database, e.g. GeoLite2-ASN.mmdb
+	geoIPASNDBEnv = "GEOIP_ASN_DB"
+)
+
+// GeoInfo is where a client IP address is located
+// Fields are empty when no database is configured or the address is not found
+type GeoInfo struct {
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 country code
+	ASN     uint   `json:"asn,omitempty"`     // Autonomous system number
+	ASOrg   string `json:"as_org,omitempty"`  // Organization owning the autonomous system
+}
+
+// geoResolver looks client addresses up in the configured MaxMind databases
+type geoResolver struct {
+	countries *geoip2.Reader // Country or city database, nil if not configured
+	asns      *geoip2.Reader // ASN database, nil if not configured
+}
+
+// GeoIP resolves client addresses when a database is configured, nil otherwise
+var GeoIP *geoResolver
+
+// setupGeoIP opens the MaxMind databases named by GEOIP_COUNTRY_DB and GEOIP_ASN_DB
+func setupGeoIP() error {
+	countryPath, asnPath := os.Getenv(geoIPCountryDBEnv), os.Getenv(geoIPASNDBEnv)
+	if countryPath == "" && asnPath == "" {
+		return nil
+	}
+
+	resolver := &geoResolver{}
+	for _, database := range []struct {
+		path   string
+		reader **geoip2.Reader
+	}{{countryPath, &resolver.countries}, {asnPath, &resolver.asns}} {
+		if database.path == "" {
+			continue
+		}
+		reader, err := geoip2.Open(database.path)
+		if err != nil {
+			return fmt.Errorf("failed to open GeoIP database %s: %w", database.path, err)
+		}
+		*database.reader = reader
+	}
+	GeoIP = resolver
+
+	slog.Info("Tagging client connections with their GeoIP location")
+	return nil
+}
+
+// Lookup returns the location of an IP address; it is safe to call on a nil resolver
+func (g *geoResolver) Lookup(address string) GeoInfo {
+	var info GeoInfo
+	ip := net.ParseIP(address)
+	if g == nil || ip == nil {
+		return info
+	}
+
+	if g.countries != nil {
+		if record, err := g.countries.Country(ip); err == nil {
+			info.Country = record.Country.IsoCode
+		}
+	}
+	if g.asns != nil {
+		if record, err := g.asns.ASN(ip); err == nil {
+			info.ASN = record.AutonomousSystemNumber
+			info.ASOrg = record.AutonomousSystemOrganization
+		}
+	}
+	return info
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/feedpb"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the address the gRPC server listens on, e.g. :9090
+	// The gRPC server is not started when it is unset; the serve --grpc-addr flag overrides it
+	grpcAddrEnv = "GRPC_ADDR"
+
+	// Interval at which the health status is refreshed from the upstream state
+	grpcHealthInterval = 5 * time.Second
+)
+
+// GRPCServer is the gRPC surface of the feed
+// Besides the nova.feed.v1.Feed event stream it serves grpc.health.v1,
+// reporting SERVING while the upstream subscription is connected, and server
+// reflection for tools like grpcurl
+type GRPCServer struct {
+	server *grpc.Server
+	health *health.Server
+	feed   *GRPCFeedService
+	stop   chan struct{}
+
+	web bool // Whether the public listener serves gRPC-Web calls, set by GRPC_WEB
+	h2c bool // Whether the admin listener serves gRPC over h2c, set by GRPC_H2C
+}
+
+// GRPCFeed is the running gRPC server, nil when it is not started
+var GRPCFeed *GRPCServer
+
+// startGRPCServer starts the gRPC server on addr
+//
+// Parameters:
+//   - addr: TCP address to listen on
+//
+// Returns:
+//   - *GRPCServer: The running server
+//   - error: Error if the address could not be bound
+func startGRPCServer(addr string) (*GRPCServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s := &GRPCServer{
+		server: grpc.NewServer(),
+		health: health.NewServer(),
+		stop:   make(chan struct{}),
+		web:    envBool(grpcWebEnv),
+		h2c:    envBool(grpcH2CEnv),
+	}
+	s.feed = newGRPCFeedService(s.stop)
+	feedpb.RegisterFeedServer(s.server, s.feed)
+	RegisterSink(s.feed)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+
+	s.updateHealth()
+	go s.healthLoop()
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil {
+			slog.Error("gRPC server failed", logKeyError, err)
+		}
+	}()
+
+	slog.Info("gRPC server listening", "addr", addr)
+	if s.web {
+		slog.Info("Serving gRPC-Web calls on the public listener")
+	}
+	if s.h2c {
+		slog.Info("Serving gRPC over h2c on the admin listener")
+	}
+	GRPCFeed = s
+	return s, nil
+}
+
+// healthLoop keeps the health status in line with the upstream subscription
+func (s *GRPCServer) healthLoop() {
+	ticker := time.NewTicker(grpcHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.updateHealth()
+		}
+	}
+}
+
+// updateHealth sets the status of the server and of every registered service
+func (s *GRPCServer) updateHealth() {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if FeedStats.Snapshot().Upstream.Connected {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	// The empty service name reports the health of the server as a whole
+	s.health.SetServingStatus("", status)
+	for service := range s.server.GetServiceInfo() {
+		s.health.SetServingStatus(service, status)
+	}
+}
+
+// Stop reports NOT_SERVING to health watchers and stops the server once
+// in-flight calls have finished
+func (s *GRPCServer) Stop() {
+	close(s.stop)
+	s.health.Shutdown()
+	s.server.GracefulStop()
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/feedpb"
+)
+
+// Configuration constants
+const (
+	// Number of events buffered per subscriber of the gRPC feed; a subscriber
+	// falling further behind is ended rather than silently missing events
+	grpcFeedClientQueueSize = 4096
+)
+
+// GRPCFeedService serves nova.feed.v1.Feed, streaming the events published
+// to sinks to every subscriber, over native gRPC, gRPC-Web and h2c alike
+type GRPCFeedService struct {
+	feedpb.UnimplementedFeedServer
+
+	stop <-chan struct{} // Closed when the server stops, ending the streams
+
+	mutex       sync.Mutex
+	subscribers map[*grpcFeedSubscriber]struct{}
+}
+
+// grpcFeedSubscriber is a running Subscribe call
+type grpcFeedSubscriber struct {
+	types  map[EventType]bool // Types to receive, every type when empty
+	mints  map[string]bool    // Mints to receive, every mint when empty
+	events chan *feedpb.Event
+	slow   chan struct{} // Closed when the queue overflowed
+	once   sync.Once
+}
+
+// newGRPCFeedService creates the feed service, ending its streams when stop is closed
+func newGRPCFeedService(stop <-chan struct{}) *GRPCFeedService {
+	return &GRPCFeedService{stop: stop, subscribers: map[*grpcFeedSubscriber]struct{}{}}
+}
+
+// Name identifies the sink in logs
+func (f *GRPCFeedService) Name() string {
+	return "grpc"
+}
+
+// Publish hands the event to every subscriber it matches, encoding it once
+func (f *GRPCFeedService) Publish(event Event) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var message *feedpb.Event
+	for subscriber := range f.subscribers {
+		if !subscriber.matches(event) {
+			continue
+		}
+		if message == nil {
+			message = eventProto(event)
+		}
+		select {
+		case subscriber.events <- message:
+		default:
+			subscriber.once.Do(func() { close(subscriber.slow) })
+		}
+	}
+}
+
+// Subscribe streams the events matching the request until the client cancels
+// the call, falls behind or the server stops
+func (f *GRPCFeedService) Subscribe(request *feedpb.SubscribeRequest, stream grpc.ServerStreamingServer[feedpb.Event]) error {
+	subscriber := &grpcFeedSubscriber{
+		types:  map[EventType]bool{},
+		mints:  map[string]bool{},
+		events: make(chan *feedpb.Event, grpcFeedClientQueueSize),
+		slow:   make(chan struct{}),
+	}
+	for _, name := range request.GetTypes() {
+		eventType := EventType(name)
+		switch eventType {
+		case EventCreate, EventTrade, EventComplete, EventPrice, EventMigration, EventRevert:
+			subscriber.types[eventType] = true
+		default:
+			return status.Errorf(codes.InvalidArgument, "unknown event type %q", name)
+		}
+	}
+	for _, mint := range request.GetMints() {
+		subscriber.mints[mint] = true
+	}
+
+	client := "unknown"
+	if remote, ok := peer.FromContext(stream.Context()); ok {
+		client = remote.Addr.String()
+	}
+	f.mutex.Lock()
+	f.subscribers[subscriber] = struct{}{}
+	f.mutex.Unlock()
+	slog.Info("gRPC feed subscriber connected", logKeyClientID, client, "types", request.GetTypes(), "mints", len(subscriber.mints))
+
+	reason := "closed by client"
+	defer func() {
+		f.mutex.Lock()
+		delete(f.subscribers, subscriber)
+		f.mutex.Unlock()
+		slog.Info("gRPC feed subscriber disconnected", logKeyClientID, client, "reason", reason)
+	}()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-f.stop:
+			reason = "server stopping"
+			return status.Error(codes.Unavailable, "server stopping")
+		case <-subscriber.slow:
+			reason = "too slow"
+			return status.Errorf(codes.ResourceExhausted, "subscriber fell more than %d events behind", grpcFeedClientQueueSize)
+		case message := <-subscriber.events:
+			if err := stream.Send(message); err != nil {
+				reason = err.Error()
+				return err
+			}
+		}
+	}
+}
+
+// matches reports whether the subscriber asked for the event
+func (s *grpcFeedSubscriber) matches(event Event) bool {
+	return (len(s.types) == 0 || s.types[event.Type]) && (len(s.mints) == 0 || s.mints[event.Mint])
+}
+
+// eventProto converts an event to its protobuf envelope
+func eventProto(event Event) *feedpb.Event {
+	message := &feedpb.Event{
+		Type:          string(event.Type),
+		Mint:          event.Mint,
+		Signature:     event.Signature,
+		Slot:          event.Slot,
+		ReceivedAt:    protoTime(event.ReceivedAt),
+		CorrelationId: event.CorrelationID,
+	}
+
+	switch data := event.Data.(type) {
+	case CreateEvent:
+		message.Data = &feedpb.Event_Create{Create: &feedpb.CreateEvent{Name: data.Name, Symbol: data.Symbol, Uri: data.Uri, Mint: data.Mint}}
+	case TradeEvent:
+		message.Data = &feedpb.Event_Trade{Trade: &feedpb.TradeEvent{
+			Mint:                 data.Mint,
+			SolAmount:            data.SolAmount,
+			TokenAmount:          data.TokenAmount,
+			IsBuy:                data.IsBuy,
+			User:                 data.User,
+			Timestamp:            data.Timestamp,
+			VirtualSolReserves:   data.VirtualSolReserves,
+			VirtualTokenReserves: data.VirtualTokenReserves,
+		}}
+	case CompleteEvent:
+		message.Data = &feedpb.Event_Complete{Complete: &feedpb.CompleteEvent{Mint: data.Mint, User: data.User, BondingCurve: data.BondingCurve, Timestamp: data.Timestamp}}
+	case PriceEvent:
+		message.Data = &feedpb.Event_Price{Price: &feedpb.PriceEvent{Mint: data.Mint, PriceUsd: data.PriceUSD, Source: data.Source, Timestamp: data.Timestamp}}
+	case MigrationEvent:
+		message.Data = &feedpb.Event_Migration{Migration: &feedpb.MigrationEvent{Mint: data.Mint, Lp: &feedpb.LPStatus{
+			Pool:          data.LP.Pool,
+			LpMint:        data.LP.LPMint,
+			MintedSupply:  data.LP.MintedSupply,
+			CurrentSupply: data.LP.CurrentSupply,
+			BurnedShare:   data.LP.BurnedShare,
+			LockedShare:   data.LP.LockedShare,
+			Burned:        data.LP.Burned,
+			Safe:          data.LP.Safe,
+			VerifiedAt:    protoTime(data.LP.VerifiedAt),
+		}}}
+	case RevertEvent:
+		message.Data = &feedpb.Event_Revert{Revert: &feedpb.RevertEvent{Mint: data.Mint, Signature: data.Signature, Reason: data.Reason, Error: data.Error}}
+	}
+
+	if addresses := event.Addresses; addresses != nil {
+		message.Addresses = &feedpb.DerivedAddresses{
+			BondingCurve:           addresses.BondingCurve,
+			AssociatedBondingCurve: addresses.AssociatedBondingCurve,
+			CreatorTokenAccount:    addresses.CreatorTokenAccount,
+		}
+	}
+	if market := event.Market; market != nil {
+		message.Market = &feedpb.MarketData{
+			Source:        market.Source,
+			PairUrl:       market.PairURL,
+			LiquidityUsd:  market.LiquidityUSD,
+			Volume_24HUsd: market.Volume24hUSD,
+			FetchedAt:     protoTime(market.FetchedAt),
+		}
+	}
+	if supply := event.Supply; supply != nil {
+		message.Supply = &feedpb.TokenSupply{Amount: supply.Amount, Decimals: uint32(supply.Decimals), FetchedAt: protoTime(supply.FetchedAt)}
+	}
+	if holders := event.Holders; holders != nil {
+		message.Holders = &feedpb.HolderConcentration{TopShare: holders.TopShare, Slot: holders.Slot, UpdatedAt: protoTime(holders.UpdatedAt)}
+		for _, holder := range holders.Top {
+			message.Holders.Top = append(message.Holders.Top, &feedpb.TokenHolder{Wallet: holder.Wallet, Amount: holder.Amount, Share: holder.Share})
+		}
+	}
+	if funding := event.Funding; funding != nil {
+		message.Funding = &feedpb.FundingSource{
+			Kind:     funding.Kind,
+			Name:     funding.Name,
+			Source:   funding.Source,
+			Hops:     int64(funding.Hops),
+			Chain:    funding.Chain,
+			TracedAt: protoTime(funding.TracedAt),
+		}
+	}
+	if len(event.Fields) > 0 {
+		message.Fields = make(map[string]*structpb.Value, len(event.Fields))
+		for name, value := range event.Fields {
+			// Values a script computed that JSON cannot carry are left out
+			if converted, err := structpb.NewValue(value); err == nil {
+				message.Fields[name] = converted
+			}
+		}
+	}
+	return message
+}
+
+// protoTime converts a time, leaving the zero time unset
+func protoTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
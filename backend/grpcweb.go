@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling gRPC-Web: when true and the gRPC server
+	// runs, the public listener also serves its services to gRPC-Web clients,
+	// such as browsers, through the CORS policy of the REST API
+	grpcWebEnv = "GRPC_WEB"
+
+	// Environment variable enabling h2c on the admin listener: when true and
+	// the gRPC server runs, the admin listener also serves its services over
+	// cleartext HTTP/2, for proxies that reach backends with h2c upgrades
+	grpcH2CEnv = "GRPC_H2C"
+
+	// Content types of gRPC requests; a +proto or +json suffix may follow
+	grpcContentType        = "application/grpc"
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text" // Base64 bodies, for clients without binary streaming
+
+	// Flag of the frame carrying the trailers at the end of a gRPC-Web response body
+	grpcWebTrailerFlag = 0x80
+
+	// Headers gRPC-Web clients send and read, allowed and exposed in cross-origin calls
+	grpcWebAllowedHeaders = "X-Grpc-Web, X-User-Agent, Grpc-Timeout"
+	grpcWebExposedHeaders = "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin"
+)
+
+// grpcWebRequest reports whether a request is a gRPC-Web call
+func grpcWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), grpcWebContentType)
+}
+
+// grpcWebPath reports whether a path is a method of a service of the gRPC
+// server served to gRPC-Web clients, for the CORS policy
+func grpcWebPath(path string) bool {
+	if GRPCFeed == nil || !GRPCFeed.web {
+		return false
+	}
+	service, _, found := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !found {
+		return false
+	}
+	_, registered := GRPCFeed.server.GetServiceInfo()[service]
+	return registered
+}
+
+// ServeGRPCWeb serves a gRPC-Web call through the gRPC server
+// The call is translated to the gRPC over HTTP/2 the server handles: the
+// request is relabelled, and the trailers of the response are written as the
+// last frame of the body, which HTTP/1.1 and browsers cannot otherwise read.
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: gRPC-Web request
+func (s *GRPCServer) ServeGRPCWeb(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, grpcWebTextContentType)
+	subtype := strings.TrimPrefix(strings.TrimPrefix(contentType, grpcWebTextContentType), grpcWebContentType)
+
+	request := r.Clone(r.Context())
+	request.Proto, request.ProtoMajor, request.ProtoMinor = "HTTP/2.0", 2, 0
+	request.Header.Set("Content-Type", grpcContentType+subtype)
+	request.Header.Del("Content-Length")
+	request.ContentLength = -1
+	if text {
+		request.Body = io.NopCloser(&grpcWebTextReader{source: r.Body})
+	}
+
+	response := &grpcWebResponse{writer: w, header: make(http.Header), contentType: contentType, text: text}
+	response.body = response.newBody()
+	s.server.ServeHTTP(response, request)
+	response.finish()
+}
+
+// grpcWebTextReader decodes the base64 body of a grpc-web-text request
+// Clients encode every message on its own, so the body is a series of padded
+// chunks; each 4 character quantum is decoded separately, as a stream decoder
+// stops at the first padding.
+type grpcWebTextReader struct {
+	source  io.Reader
+	quantum [4]byte // Characters of the quantum being read
+	filled  int     // Characters of quantum read so far
+	decoded []byte  // Decoded bytes not yet returned
+	err     error   // Error of the source, returned once decoded is empty
+}
+
+// Read returns the decoded bytes of the body
+func (r *grpcWebTextReader) Read(p []byte) (int, error) {
+	for len(r.decoded) == 0 {
+		if r.err != nil {
+			if r.err == io.EOF && r.filled > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return 0, r.err
+		}
+
+		var buffer [512]byte
+		n, err := r.source.Read(buffer[:])
+		r.err = err
+		for _, c := range buffer[:n] {
+			if c == '\r' || c == '\n' {
+				continue
+			}
+			r.quantum[r.filled] = c
+			r.filled++
+			if r.filled < len(r.quantum) {
+				continue
+			}
+			r.filled = 0
+			var decoded [3]byte
+			size, err := base64.StdEncoding.Decode(decoded[:], r.quantum[:])
+			if err != nil {
+				r.err = fmt.Errorf("invalid grpc-web-text body: %w", err)
+				break
+			}
+			r.decoded = append(r.decoded, decoded[:size]...)
+		}
+	}
+
+	n := copy(p, r.decoded)
+	r.decoded = r.decoded[n:]
+	return n, nil
+}
+
+// grpcWebResponse turns the gRPC response written by the server into a
+// gRPC-Web one
+type grpcWebResponse struct {
+	writer      http.ResponseWriter
+	header      http.Header // Headers, then trailers, as set by the server
+	contentType string      // Content type of the request, answered with
+	text        bool        // Whether the body is base64 encoded
+	body        io.Writer   // Writer of the body, encoding it in text mode
+	wroteHeader bool
+}
+
+// newBody returns the writer of the body, a base64 encoder in text mode
+func (r *grpcWebResponse) newBody() io.Writer {
+	if r.text {
+		return base64.NewEncoder(base64.StdEncoding, r.writer)
+	}
+	return r.writer
+}
+
+// Header returns the headers the server sets, trailers included
+func (r *grpcWebResponse) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader sends the headers set so far, leaving out the trailer declarations
+func (r *grpcWebResponse) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+
+	header := r.writer.Header()
+	for key, values := range r.header {
+		if key == "Trailer" || strings.HasPrefix(key, http2.TrailerPrefix) {
+			continue
+		}
+		header[key] = values
+	}
+	header.Set("Content-Type", r.contentType)
+	header.Del("Content-Length")
+	r.writer.WriteHeader(status)
+}
+
+// Write writes to the body
+func (r *grpcWebResponse) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+// Flush sends what was written, so streamed messages reach the client as they come
+// In text mode, the base64 of every flushed chunk is padded, which clients decode chunk by chunk.
+func (r *grpcWebResponse) Flush() {
+	r.WriteHeader(http.StatusOK)
+	if encoder, ok := r.body.(io.WriteCloser); ok {
+		encoder.Close()
+		r.body = r.newBody()
+	}
+	if flusher, ok := r.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the trailers the server set as the trailer frame
+func (r *grpcWebResponse) finish() {
+	r.WriteHeader(http.StatusOK)
+
+	trailers := make(http.Header)
+	for _, declared := range r.header.Values("Trailer") {
+		for _, key := range strings.Split(declared, ",") {
+			key = http.CanonicalHeaderKey(strings.TrimSpace(key))
+			if values := r.header.Values(key); len(values) > 0 {
+				trailers[key] = values
+			}
+		}
+	}
+	for key, values := range r.header {
+		if name, found := strings.CutPrefix(key, http2.TrailerPrefix); found {
+			trailers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	var block strings.Builder
+	for key, values := range trailers {
+		for _, value := range values {
+			fmt.Fprintf(&block, "%s: %s\r\n", strings.ToLower(key), value)
+		}
+	}
+	frame := make([]byte, 5, 5+block.Len())
+	frame[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(block.Len()))
+	r.body.Write(append(frame, block.String()...))
+	r.Flush()
+}
+
+// h2cHandler serves native gRPC calls made over HTTP/2 through the gRPC
+// server and everything else through next, accepting cleartext HTTP/2 with
+// prior knowledge or an h2c upgrade
+func (s *GRPCServer) h2cHandler(next http.Handler) http.Handler {
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType) && !grpcWebRequest(r) {
+			s.server.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &http2.Server{})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Liveness endpoint, answering as long as the process serves requests
+	healthzEndpoint = "/healthz"
+
+	// Readiness endpoint, failing while the instance cannot deliver the feed
+	readyzEndpoint = "/readyz"
+
+	// Environment variable overriding how long the feed may go without an
+	// upstream notification before the instance reports not ready, e.g. 2m
+	healthMaxEventAgeEnv = "HEALTH_MAX_EVENT_AGE"
+
+	// Event age used when HEALTH_MAX_EVENT_AGE is unset; PumpFun logs arrive
+	// several times a second, so a minute of silence means the feed is stuck
+	defaultHealthMaxEventAge = time.Minute
+
+	// Status values of a health report
+	healthStatusOK          = "ok"
+	healthStatusUnavailable = "unavailable"
+)
+
+// healthCheck is the outcome of a single readiness check
+type healthCheck struct {
+	Healthy bool   `json:"healthy"`          // True if the check passed
+	Detail  string `json:"detail,omitempty"` // Why the check failed, or what it observed
+}
+
+// healthReport is the JSON body returned by the health endpoints
+type healthReport struct {
+	Status       string                 `json:"status"`                 // ok, or unavailable if any check failed
+	Checks       map[string]healthCheck `json:"checks"`                 // Outcome of every check by name
+	Upstream     UpstreamHealth         `json:"upstream"`               // Upstream subscription health
+	LastEventAge float64                `json:"last_event_age_seconds"` // Seconds since the last upstream notification, -1 if none yet
+	SlotLag      *SlotLagStatus         `json:"slot_lag,omitempty"`     // Latest slot lag measurement, when monitored
+	Uptime       float64                `json:"uptime_seconds"`         // Seconds since the process started
+}
+
+// registerHealthRoutes registers the liveness and readiness endpoints on the given router
+func registerHealthRoutes(router *mux.Router) {
+	router.HandleFunc(healthzEndpoint, HandleHealthz).Methods(http.MethodGet, http.MethodHead)
+	router.HandleFunc(readyzEndpoint, HandleReadyz).Methods(http.MethodGet, http.MethodHead)
+}
+
+// HandleHealthz reports the health of the instance and always answers 200
+// Upstream or storage outages are not fixed by restarting the process, so the
+// liveness probe only fails when the process stops serving altogether
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, checkHealth())
+}
+
+// HandleReadyz reports the health of the instance, answering 503 while any
+// check fails so load balancers stop routing clients to it
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := checkHealth()
+	status := http.StatusOK
+	if report.Status != healthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// checkHealth runs every readiness check
+func checkHealth() healthReport {
+	now := time.Now()
+	stats := FeedStats.Snapshot()
+	upstream := stats.Upstream
+
+	report := healthReport{
+		Status:       healthStatusOK,
+		Checks:       map[string]healthCheck{},
+		Upstream:     upstream,
+		LastEventAge: -1,
+		Uptime:       now.Sub(stats.StartedAt).Seconds(),
+	}
+
+	if upstream.Connected {
+		report.Checks["upstream"] = healthCheck{Healthy: true}
+	} else {
+		detail := "not subscribed yet"
+		if upstream.LastError != "" {
+			detail = upstream.LastError
+		}
+		report.Checks["upstream"] = healthCheck{Detail: detail}
+	}
+
+	maxAge := healthMaxEventAge()
+	switch {
+	case !upstream.LastMessageAt.IsZero():
+		age := now.Sub(upstream.LastMessageAt)
+		report.LastEventAge = age.Seconds()
+		report.Checks["events"] = healthCheck{
+			Healthy: age <= maxAge,
+			Detail:  fmt.Sprintf("last notification %s ago", age.Round(time.Second)),
+		}
+	case upstream.Connected && now.Sub(upstream.ConnectedAt) <= maxAge:
+		// Just subscribed; give the first notification time to arrive
+		report.Checks["events"] = healthCheck{Healthy: true, Detail: "awaiting the first notification"}
+	default:
+		report.Checks["events"] = healthCheck{Detail: "no notification received"}
+	}
+
+	if SlotLag != nil {
+		if status, ok := SlotLag.Status(); ok {
+			report.SlotLag = &status
+			report.Checks["slot_lag"] = healthCheck{
+				Healthy: !status.Behind,
+				Detail:  fmt.Sprintf("%d slots behind the chain", status.Lag),
+			}
+		}
+	}
+
+	report.Checks["self_test"] = SelfTest.Check()
+
+	if Persistence != nil {
+		if err := Persistence.Ping(); err != nil {
+			report.Checks["storage"] = healthCheck{Detail: err.Error()}
+		} else {
+			report.Checks["storage"] = healthCheck{Healthy: true, Detail: Persistence.Name()}
+		}
+	}
+
+	for _, check := range report.Checks {
+		if !check.Healthy {
+			report.Status = healthStatusUnavailable
+		}
+	}
+	return report
+}
+
+// healthMaxEventAge reads HEALTH_MAX_EVENT_AGE, falling back to the default
+// when it is unset or invalid
+func healthMaxEventAge() time.Duration {
+	age, err := time.ParseDuration(envOrDefault(healthMaxEventAgeEnv, defaultHealthMaxEventAge.String()))
+	if err != nil || age <= 0 {
+		return defaultHealthMaxEventAge
+	}
+	return age
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the authentication header configured on the
+	// Helius webhook, required with --source helius-webhook; Helius sends it
+	// verbatim as the Authorization header of every delivery
+	heliusWebhookAuthEnv = "HELIUS_WEBHOOK_AUTH"
+
+	// Endpoint receiving the deliveries of a raw Helius webhook
+	heliusWebhookEndpoint = "/ingest/helius"
+
+	// Largest delivery accepted, Helius batching up to 100 transactions
+	heliusWebhookMaxBodySize = 16 << 20
+)
+
+// heliusTransaction is a transaction of a raw Helius webhook delivery, in the
+// format of getTransaction; only the fields needed for a notification are decoded
+type heliusTransaction struct {
+	Slot uint64 `json:"slot"`
+	Meta *struct {
+		Err         json.RawMessage `json:"err"`         // Null for successful transactions
+		LogMessages []string        `json:"logMessages"` // Log messages of every instruction
+	} `json:"meta"`
+	Transaction struct {
+		Signatures []string `json:"signatures"` // The first one identifies the transaction
+	} `json:"transaction"`
+}
+
+// succeeded reports whether the transaction succeeded and carries logs to process
+func (t *heliusTransaction) succeeded() bool {
+	if t.Meta == nil || len(t.Meta.LogMessages) == 0 || len(t.Transaction.Signatures) == 0 {
+		return false
+	}
+	return len(t.Meta.Err) == 0 || bytes.Equal(t.Meta.Err, []byte("null"))
+}
+
+// decodeHeliusTransactions decodes the array of transactions of a delivery, or
+// the single transaction of a test delivery
+func decodeHeliusTransactions(body json.RawMessage) ([]heliusTransaction, error) {
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '{' {
+		transactions := make([]heliusTransaction, 1)
+		return transactions, json.Unmarshal(body, &transactions[0])
+	}
+	var transactions []heliusTransaction
+	return transactions, json.Unmarshal(body, &transactions)
+}
+
+// heliusWebhookResponse is the JSON body returned for a delivery
+type heliusWebhookResponse struct {
+	Processed int `json:"processed"` // Successful transactions handed to the pipeline
+	Skipped   int `json:"skipped"`   // Failed transactions and transactions without logs
+}
+
+// HeliusWebhookReceiver takes notifications from the deliveries of a raw Helius
+// webhook instead of a WebSocket subscription, for environments where holding
+// an outbound connection is impractical
+type HeliusWebhookReceiver struct {
+	handle func(pumpstream.Notification)
+
+	mutex  sync.RWMutex
+	secret string // Expected Authorization header, never empty
+}
+
+// HeliusWebhook receives Helius webhook deliveries, nil unless serve runs with
+// --source helius-webhook
+var HeliusWebhook *HeliusWebhookReceiver
+
+// setupHeliusWebhook starts accepting webhook deliveries
+//
+// Parameters:
+//   - handle: Function receiving each notification, e.g. processNotification
+//
+// Returns:
+//   - error: Error if HELIUS_WEBHOOK_AUTH is not set
+func setupHeliusWebhook(handle func(pumpstream.Notification)) error {
+	receiver := &HeliusWebhookReceiver{handle: handle}
+	if err := receiver.Reload(); err != nil {
+		return err
+	}
+
+	HeliusWebhook = receiver
+	// Deliveries only arrive once the instance is ready, so readiness cannot wait for the first one
+	FeedStats.RecordUpstreamConnected()
+	slog.Info("Receiving notifications from Helius webhook deliveries", "endpoint", heliusWebhookEndpoint)
+	return nil
+}
+
+// Reload reads HELIUS_WEBHOOK_AUTH again, so a rotated secret applies at once
+// An unset secret is refused and the previous one kept, as an empty one would
+// let deliveries without an Authorization header in.
+func (h *HeliusWebhookReceiver) Reload() error {
+	secret := os.Getenv(heliusWebhookAuthEnv)
+	if secret == "" {
+		return fmt.Errorf("%s must be set to use the %s source", heliusWebhookAuthEnv, sourceHeliusWebhook)
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.secret = secret
+	return nil
+}
+
+// authorized reports whether a delivery carries the configured secret
+func (h *HeliusWebhookReceiver) authorized(header string) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.secret != "" && subtle.ConstantTimeCompare([]byte(header), []byte(h.secret)) == 1
+}
+
+// registerHeliusWebhookRoutes registers the webhook receiver endpoint
+func registerHeliusWebhookRoutes(router *mux.Router) {
+	router.HandleFunc(heliusWebhookEndpoint, HandleHeliusWebhook).Methods(http.MethodPost)
+}
+
+// HandleHeliusWebhook processes the transactions of a raw webhook delivery
+// Failed transactions are skipped, as the WebSocket subscription does
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a JSON array of transactions, or a single one
+func HandleHeliusWebhook(w http.ResponseWriter, r *http.Request) {
+	if HeliusWebhook == nil {
+		writeError(w, http.StatusNotFound, "the Helius webhook source is disabled")
+		return
+	}
+
+	ip := clientIP(r)
+	if refuseBanned(w, ip) {
+		return
+	}
+	if !HeliusWebhook.authorized(r.Header.Get("Authorization")) {
+		AutoBan.Record(ip, abuseAuthFailure)
+		writeError(w, http.StatusUnauthorized, "invalid webhook authorization")
+		return
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, heliusWebhookMaxBodySize)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	transactions, err := decodeHeliusTransactions(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	response := heliusWebhookResponse{}
+	receivedAt := time.Now().UTC()
+	for _, transaction := range transactions {
+		FeedStats.RecordUpstreamMessage()
+		if !transaction.succeeded() {
+			response.Skipped++
+			continue
+		}
+
+		HeliusWebhook.handle(pumpstream.Notification{
+			Signature:  transaction.Transaction.Signatures[0],
+			Slot:       transaction.Slot,
+			Logs:       transaction.Meta.LogMessages,
+			ReceivedAt: receivedAt,
+		})
+		response.Processed++
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
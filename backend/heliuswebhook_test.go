@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// TestHeliusWebhookAuthorization checks that deliveries need the configured
+// secret, and that a reload with the secret unset keeps the previous one
+// rather than letting deliveries without an Authorization header in
+func TestHeliusWebhookAuthorization(t *testing.T) {
+	t.Setenv(heliusWebhookAuthEnv, "topsecret")
+	receiver := &HeliusWebhookReceiver{handle: func(pumpstream.Notification) {}}
+	if err := receiver.Reload(); err != nil {
+		t.Fatalf("failed to load the secret: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		expected bool
+	}{
+		{name: "secret", header: "topsecret", expected: true},
+		{name: "missing header", header: ""},
+		{name: "other secret", header: "othersecret"},
+		{name: "prefix of the secret", header: "topsecre"},
+	}
+	check := func(t *testing.T) {
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				if authorized := receiver.authorized(test.header); authorized != test.expected {
+					t.Fatalf("authorized %v, expected %v", authorized, test.expected)
+				}
+			})
+		}
+	}
+	check(t)
+
+	t.Run("unset on reload", func(t *testing.T) {
+		t.Setenv(heliusWebhookAuthEnv, "")
+		if err := receiver.Reload(); err == nil {
+			t.Fatal("reloaded an empty secret")
+		}
+		check(t)
+	})
+
+	t.Run("never set", func(t *testing.T) {
+		empty := &HeliusWebhookReceiver{}
+		if empty.authorized("") {
+			t.Fatal("authorized an empty header without a secret")
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Configuration constants
+const (
+	// Span of received_at loaded from storage at a time during a time range replay
+	replayTimeWindow = time.Minute
+
+	// Number of slots loaded from storage at a time during a slot range replay,
+	// roughly a minute of chain time
+	replaySlotWindow = 150
+
+	// Largest factor a window grows to while it keeps coming back empty, so quiet
+	// stretches of history are skipped in few queries
+	maxReplayWindowGrowth = 1024
+
+	// Fastest accepted playback speed factor
+	maxReplaySpeed = 1000
+)
+
+// historyReplay replays stored creations over a WebSocket connection in the
+// format of the live feed, so a backtest can consume launch history with the
+// same client code it uses in production
+//
+// Connections opt in with query parameters on the WebSocket endpoint:
+//   - replay_from / replay_to: time range, as YYYY-MM-DD or RFC 3339; replay_to defaults to now
+//   - replay_from_slot / replay_to_slot: slot range, both required when no time range is given
+//   - speed: playback speed factor, 1 (the default) is real time, 0 is as fast as possible
+type historyReplay struct {
+	query EventQuery
+	speed float64
+}
+
+// parseHistoryReplay reads the replay parameters of a WebSocket request
+//
+// Returns:
+//   - *historyReplay: The requested replay, nil for a live connection
+//   - error: Error describing invalid parameters
+func parseHistoryReplay(r *http.Request) (*historyReplay, error) {
+	params := r.URL.Query()
+	if !params.Has("replay_from") && !params.Has("replay_to") && !params.Has("replay_from_slot") && !params.Has("replay_to_slot") {
+		return nil, nil
+	}
+	if Persistence == nil {
+		return nil, fmt.Errorf("replays require a storage backend")
+	}
+
+	replay := &historyReplay{query: EventQuery{Types: []EventType{EventCreate}}, speed: 1}
+	var err error
+	if replay.query.From, err = parseTimeBound(params.Get("replay_from")); err != nil {
+		return nil, fmt.Errorf("replay_from must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if replay.query.To, err = parseTimeBound(params.Get("replay_to")); err != nil {
+		return nil, fmt.Errorf("replay_to must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if value := params.Get("replay_from_slot"); value != "" {
+		if replay.query.FromSlot, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("replay_from_slot must be a slot number")
+		}
+	}
+	if value := params.Get("replay_to_slot"); value != "" {
+		if replay.query.ToSlot, err = strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("replay_to_slot must be a slot number")
+		}
+	}
+	if value := params.Get("speed"); value != "" {
+		if replay.speed, err = strconv.ParseFloat(value, 64); err != nil || replay.speed < 0 || replay.speed > maxReplaySpeed {
+			return nil, fmt.Errorf("speed must be between 0 and %d", maxReplaySpeed)
+		}
+	}
+
+	// Storage is read in windows, which need a start and an end
+	if replay.query.From.IsZero() && (replay.query.FromSlot == 0 || replay.query.ToSlot == 0) {
+		return nil, fmt.Errorf("replay_from, or both replay_from_slot and replay_to_slot, must be set")
+	}
+	if replay.query.From.IsZero() && replay.query.ToSlot <= replay.query.FromSlot {
+		return nil, fmt.Errorf("replay_to_slot must be greater than replay_from_slot")
+	}
+	return replay, nil
+}
+
+// run replays the range to the client and closes the connection once done
+// The original spacing between creations is reproduced, divided by the speed
+//
+// Returns:
+//   - string: why the connection ended
+func (h *historyReplay) run(client *Client) string {
+	conn := client.Connection
+	logger := clientLogger(conn.RemoteAddr().String())
+	logger.Info("Replaying stored creations", "speed", h.speed)
+
+	closed := make(chan struct{})
+	go func() {
+		client.readLoop()
+		close(closed)
+	}()
+
+	var previous time.Time
+	sent := 0
+	err := h.scan(func(event Event) error {
+		if h.speed > 0 && !previous.IsZero() && event.ReceivedAt.After(previous) {
+			select {
+			case <-time.After(time.Duration(float64(event.ReceivedAt.Sub(previous)) / h.speed)):
+			case <-closed:
+				return errReplayClosed
+			}
+		}
+		previous = event.ReceivedAt
+
+		message, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+
+		client.Mutex.Lock()
+		defer client.Mutex.Unlock()
+		sent++
+		return client.send(message)
+	})
+
+	if errors.Is(err, errReplayClosed) {
+		logger.Info("Client left during replay", "sent", sent)
+		return "left during replay"
+	}
+
+	reason := "replay complete"
+	if err != nil {
+		logger.Error("Replay stopped", "sent", sent, logKeyError, err)
+		reason = "replay failed"
+	}
+
+	client.Mutex.Lock()
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), time.Now().Add(time.Second))
+	client.Mutex.Unlock()
+
+	// Give the client a moment to acknowledge the close
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+	}
+	logger.Info("Replay complete", "sent", sent)
+	return reason
+}
+
+// errReplayClosed stops a replay whose client disconnected
+var errReplayClosed = errors.New("client closed the connection")
+
+// scan hands every event of the range to handle, oldest first
+// Events are loaded one window at a time so no read transaction stays open for
+// the length of a slow replay
+func (h *historyReplay) scan(handle func(Event) error) error {
+	load := func(window EventQuery) (int, error) {
+		var events []Event
+		if err := Persistence.ScanEvents(window, func(event Event) error {
+			events = append(events, event)
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+		for _, event := range events {
+			if err := handle(event); err != nil {
+				return 0, err
+			}
+		}
+		return len(events), nil
+	}
+
+	// Page by time when a time range is given, by slot otherwise
+	if !h.query.From.IsZero() {
+		end := h.query.To
+		if end.IsZero() {
+			end = time.Now()
+		}
+		growth := 1
+		for start := h.query.From; start.Before(end); {
+			window := h.query
+			window.From, window.To = start, start.Add(replayTimeWindow*time.Duration(growth))
+			if window.To.After(end) {
+				window.To = end
+			}
+			count, err := load(window)
+			if err != nil {
+				return err
+			}
+			start = window.To
+			growth = nextWindowGrowth(growth, count)
+		}
+		return nil
+	}
+
+	growth := 1
+	for start := h.query.FromSlot; start < h.query.ToSlot; {
+		window := h.query
+		window.FromSlot, window.ToSlot = start, min(start+replaySlotWindow*uint64(growth), h.query.ToSlot)
+		count, err := load(window)
+		if err != nil {
+			return err
+		}
+		start = window.ToSlot
+		growth = nextWindowGrowth(growth, count)
+	}
+	return nil
+}
+
+// nextWindowGrowth doubles the window after an empty one and resets it once
+// events show up again
+func nextWindowGrowth(growth, count int) int {
+	if count > 0 {
+		return 1
+	}
+	return min(growth*2, maxReplayWindowGrowth)
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the interval between top holder refreshes,
+	// e.g. 5m; holder concentration is disabled when it is unset
+	holderConcentrationIntervalEnv = "HOLDER_CONCENTRATION_INTERVAL"
+
+	// Environment variable with the number of tokens refreshed per interval,
+	// bounding the RPC budget to two requests per token
+	holderConcentrationTokensEnv = "HOLDER_CONCENTRATION_TOKENS"
+
+	// Tokens refreshed per interval when HOLDER_CONCENTRATION_TOKENS is unset
+	defaultHolderConcentrationTokens = 20
+
+	// Spacing of the RPC requests of a refresh
+	holderRequestInterval = 200 * time.Millisecond
+
+	// Longest the requests of one token may take together
+	holderRequestTimeout = 10 * time.Second
+
+	// Number of wallets the concentration is computed over
+	topHolderCount = 10
+)
+
+// TokenHolder is a wallet among the largest holders of a token
+type TokenHolder struct {
+	Wallet string  `json:"wallet"` // Owner of the token accounts
+	Amount uint64  `json:"amount"` // Token base units held
+	Share  float64 `json:"share"`  // Share of the total supply
+}
+
+// HolderConcentration reports how much of the supply the largest wallets
+// hold, the bonding curve excluded
+// Holders are derived from the 20 largest token accounts returned by
+// getTokenLargestAccounts, grouped by owner.
+type HolderConcentration struct {
+	TopShare  float64       `json:"top_share"`  // Share of the supply held by the 10 largest wallets
+	Top       []TokenHolder `json:"top"`        // The 10 largest wallets, largest first
+	Slot      uint64        `json:"slot"`       // Slot the holders were read at
+	UpdatedAt time.Time     `json:"updated_at"` // Time of the refresh
+}
+
+// HolderTracker refreshes the holder concentration of the watched tokens:
+// those still on their curve that traded since the previous refresh, most
+// recently traded first
+type HolderTracker struct {
+	client    *rpc.Client
+	maxTokens int
+}
+
+// Holders tracks holder concentration when HOLDER_CONCENTRATION_INTERVAL is set, nil otherwise
+var Holders *HolderTracker
+
+// setupHolderConcentration starts refreshing the holder concentration of the
+// watched tokens when HOLDER_CONCENTRATION_INTERVAL is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if a setting is invalid or no RPC endpoint can be resolved
+func setupHolderConcentration(rpcURL string) error {
+	value := os.Getenv(holderConcentrationIntervalEnv)
+	if value == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid %s %q", holderConcentrationIntervalEnv, value)
+	}
+	maxTokens := defaultHolderConcentrationTokens
+	if value := os.Getenv(holderConcentrationTokensEnv); value != "" {
+		if maxTokens, err = strconv.Atoi(value); err != nil || maxTokens <= 0 {
+			return fmt.Errorf("invalid %s %q", holderConcentrationTokensEnv, value)
+		}
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	Holders = &HolderTracker{client: newEnrichmentRPCClient(endpoint), maxTokens: maxTokens}
+	Pipeline.Register(StageEnrich, "holder concentration", addHolderConcentration)
+	goSafe("holder concentration", func() { Holders.run(interval) })
+
+	slog.Info("Refreshing the top holders of active tokens", "max_tokens", maxTokens, "interval", interval)
+	return nil
+}
+
+// addHolderConcentration adds the latest holder concentration of the token to the event
+func addHolderConcentration(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		if record, found := Tokens.Get(pipelineEventMint(event)); found {
+			event.Holders = record.Holders
+		}
+		return next(ctx, event)
+	}
+}
+
+// run refreshes the watched tokens at a fixed interval
+func (h *HolderTracker) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	since := time.Now().Add(-interval)
+	for range ticker.C {
+		now := time.Now()
+		for _, record := range Tokens.Active(since, h.maxTokens) {
+			holders, err := h.fetch(record)
+			if err != nil {
+				slog.Warn("Failed to refresh top holders", logKeyMint, record.Mint, logKeyError, err)
+				reportError(errorCategoryUpstream, err, logKeyMint, record.Mint)
+				continue
+			}
+			Tokens.RecordHolders(record.Mint, holders)
+		}
+		since = now
+	}
+}
+
+// fetch computes the holder concentration of a token
+// Two requests are made, each preceded by holderRequestInterval.
+func (h *HolderTracker) fetch(record TokenRecord) (HolderConcentration, error) {
+	mint, err := solana.PublicKeyFromBase58(record.Mint)
+	if err != nil {
+		return HolderConcentration{}, err
+	}
+	curve, err := solana.PublicKeyFromBase58(record.BondingCurve)
+	if err != nil {
+		return HolderConcentration{}, err
+	}
+	curveAccount, err := pumpstream.AssociatedBondingCurveAddress(curve, mint)
+	if err != nil {
+		return HolderConcentration{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), holderRequestTimeout)
+	defer cancel()
+
+	time.Sleep(holderRequestInterval)
+	largest, err := h.client.GetTokenLargestAccounts(ctx, mint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return HolderConcentration{}, fmt.Errorf("failed to get the largest token accounts: %w", err)
+	}
+	var accounts []solana.PublicKey
+	amounts := make(map[solana.PublicKey]uint64)
+	for _, account := range largest.Value {
+		if account.Address.Equals(curveAccount) {
+			continue
+		}
+		amount, err := strconv.ParseUint(account.Amount, 10, 64)
+		if err != nil {
+			return HolderConcentration{}, fmt.Errorf("invalid amount %q: %w", account.Amount, err)
+		}
+		accounts = append(accounts, account.Address)
+		amounts[account.Address] = amount
+	}
+
+	concentration := HolderConcentration{Top: []TokenHolder{}, Slot: largest.Context.Slot, UpdatedAt: time.Now().UTC()}
+	if len(accounts) == 0 {
+		return concentration, nil
+	}
+
+	time.Sleep(holderRequestInterval)
+	tokenAccounts, err := h.client.GetMultipleAccountsWithOpts(ctx, accounts, &rpc.GetMultipleAccountsOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return HolderConcentration{}, fmt.Errorf("failed to get the token accounts: %w", err)
+	}
+
+	// The owner of a token account is stored after its mint
+	byWallet := make(map[string]uint64)
+	for i, account := range tokenAccounts.Value {
+		if account == nil {
+			continue
+		}
+		data := account.Data.GetBinary()
+		if len(data) < 2*solana.PublicKeyLength {
+			continue
+		}
+		owner := solana.PublicKeyFromBytes(data[solana.PublicKeyLength : 2*solana.PublicKeyLength])
+		if owner.Equals(curve) {
+			continue
+		}
+		byWallet[owner.String()] += amounts[accounts[i]]
+	}
+
+	for wallet, amount := range byWallet {
+		concentration.Top = append(concentration.Top, TokenHolder{Wallet: wallet, Amount: amount, Share: float64(amount) / pumpTokenTotalSupply})
+	}
+	sort.Slice(concentration.Top, func(i, j int) bool { return concentration.Top[i].Amount > concentration.Top[j].Amount })
+	concentration.Top = concentration.Top[:min(len(concentration.Top), topHolderCount)]
+	for _, holder := range concentration.Top {
+		concentration.TopShare += holder.Share
+	}
+	return concentration, nil
+}
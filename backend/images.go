@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Registers the decoders of the formats token images come in
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/mux"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/singleflight"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling the image proxy: when true, the image
+	// endpoint serves resized thumbnails of token images
+	imageProxyEnv = "IMAGE_PROXY"
+
+	// Environment variable with the IPFS gateway images and metadata on IPFS
+	// are fetched from, e.g. a dedicated gateway
+	ipfsGatewayEnv = "IPFS_GATEWAY"
+
+	// Gateway used when IPFS_GATEWAY is unset
+	defaultIPFSGateway = "https://ipfs.io/ipfs/"
+
+	// REST endpoint returning the image of a token
+	tokenImageEndpoint = "/api/tokens/{mint}/image"
+
+	// Largest image the proxy downloads
+	maxImageBytes = 10 << 20
+
+	// Largest image the proxy decodes, in pixels, bounding the memory a
+	// small file with huge dimensions can take
+	maxImagePixels = 40_000_000
+
+	// Longest the download of an image and its metadata may take
+	imageFetchTimeout = 15 * time.Second
+
+	// Memory budget of the cached thumbnails; the oldest are evicted first
+	imageCacheBytes = 64 << 20
+
+	// Number of tokens whose image URL is remembered, so thumbnails of every
+	// size are made from one metadata download; the oldest are forgotten first
+	imageMaxLocations = 10000
+
+	// How long clients may cache a thumbnail; IPFS content never changes
+	imageCacheMaxAge = 24 * time.Hour
+)
+
+// thumbnailSizes are the widths and heights, in pixels, thumbnails fit in; the
+// last is served when the request names none
+var thumbnailSizes = []int{64, 256}
+
+// errNoImage is returned for tokens whose metadata has no image
+var errNoImage = errors.New("token has no image")
+
+// ImageProxy serves token images, resized to thumbnails encoded as WebP so
+// list views do not download the originals
+// Images are fetched from the URLs creators put in their metadata, so the
+// proxy only connects to public addresses, except for the IPFS gateway and
+// the outbound proxies, which the operator configured. Clients are never sent
+// to those URLs themselves.
+type ImageProxy struct {
+	client  *http.Client
+	gateway *url.URL
+	group   singleflight.Group
+
+	mutex         sync.Mutex
+	cache         map[string][]byte // Thumbnails, keyed by mint and size
+	order         []string          // Keys of the cache, oldest first
+	bytes         int               // Size of the cached thumbnails
+	locations     map[string]string // Image URLs by mint, empty for tokens without an image
+	locationOrder []string          // Mints of locations, oldest first
+}
+
+// Images serves token images when IMAGE_PROXY is set, nil otherwise
+var Images *ImageProxy
+
+// setupImageProxy enables the image endpoint when IMAGE_PROXY is set
+//
+// Returns:
+//   - error: Error if IPFS_GATEWAY is not an HTTP URL
+func setupImageProxy() error {
+	if !envBool(imageProxyEnv) {
+		return nil
+	}
+	value := os.Getenv(ipfsGatewayEnv)
+	if value == "" {
+		value = defaultIPFSGateway
+	}
+	gateway, err := url.Parse(value)
+	if err != nil || (gateway.Scheme != "http" && gateway.Scheme != "https") || gateway.Host == "" {
+		return fmt.Errorf("invalid %s %q", ipfsGatewayEnv, value)
+	}
+	if !strings.HasSuffix(gateway.Path, "/") {
+		gateway.Path += "/"
+	}
+
+	Images = &ImageProxy{
+		// The gateway was configured by the operator and may be private
+		client:    &http.Client{Timeout: imageFetchTimeout, Transport: publicTransport(gateway.Hostname())},
+		gateway:   gateway,
+		cache:     make(map[string][]byte),
+		locations: make(map[string]string),
+	}
+	slog.Info("Serving token thumbnails", "endpoint", tokenImageEndpoint, "gateway", gateway.Redacted())
+	return nil
+}
+
+// publicTransport returns a transport for URLs chosen by token creators, which
+// only connects to public addresses, the outbound proxies and the trusted hosts
+// Requests going through a proxy are checked before they are handed to it,
+// since the proxy, not this process, then connects to the target.
+func publicTransport(trusted ...string) *http.Transport {
+	// Hosts the operator configured may be private
+	dialable := slices.Clone(trusted)
+	for _, name := range []string{httpProxyEnv, httpsProxyEnv} {
+		if proxy, err := url.Parse(lookupProxyEnv(name)); err == nil && proxy.Host != "" {
+			dialable = append(dialable, proxy.Hostname())
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = publicDialer(dialable)
+	transport.Proxy = publicProxy(transport.Proxy, trusted)
+	return transport
+}
+
+// publicProxy wraps the proxy selection of a transport so requests to hosts
+// that are not trusted only go through a proxy when the host resolves to
+// public addresses; requests sent directly are left to the dialer
+// The proxy resolves the host again, so a name whose addresses change between
+// both lookups still reaches it; the check stops the names that always point
+// to private networks.
+func publicProxy(proxy func(*http.Request) (*url.URL, error), trusted []string) func(*http.Request) (*url.URL, error) {
+	return func(r *http.Request) (*url.URL, error) {
+		proxyURL, err := proxy(r)
+		if err != nil || proxyURL == nil {
+			return proxyURL, err
+		}
+		host := r.URL.Hostname()
+		if slices.Contains(trusted, host) {
+			return proxyURL, nil
+		}
+
+		addresses, err := net.DefaultResolver.LookupIPAddr(r.Context(), host)
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			if !publicIP(address.IP) {
+				return nil, fmt.Errorf("refusing to proxy to non-public address %s of %s", address.IP, host)
+			}
+		}
+		return proxyURL, nil
+	}
+}
+
+// publicIP reports whether an address is reachable on the internet, refusing
+// the loopback, private, link-local and unspecified ranges
+func publicIP(ip net.IP) bool {
+	return ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
+
+// publicDialer returns a dial function refusing addresses that are not
+// public, unless the host is trusted
+func publicDialer(trusted []string) func(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	guarded := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicIP(net.ParseIP(host)) {
+				return fmt.Errorf("refusing to connect to non-public address %s", host)
+			}
+			return nil
+		},
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil && slices.Contains(trusted, host) {
+			return dialer.DialContext(ctx, network, address)
+		}
+		return guarded.DialContext(ctx, network, address)
+	}
+}
+
+// HandleTokenImage serves the image of a token as a WebP thumbnail fitting in
+// size pixels, the largest thumbnail size when the request names none
+func HandleTokenImage(w http.ResponseWriter, r *http.Request) {
+	if Images == nil {
+		writeError(w, http.StatusNotFound, "the image proxy is disabled")
+		return
+	}
+	mint := mux.Vars(r)["mint"]
+	if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid mint address")
+		return
+	}
+	size := thumbnailSizes[len(thumbnailSizes)-1]
+	if value := r.URL.Query().Get("size"); value != "" {
+		size, _ = strconv.Atoi(value)
+		if !slices.Contains(thumbnailSizes, size) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("size must be one of %v", thumbnailSizes))
+			return
+		}
+	}
+	record, found := Tokens.Get(mint)
+	if !found {
+		writeError(w, http.StatusNotFound, "token not found")
+		return
+	}
+
+	thumbnail, err := Images.Thumbnail(mint, record.Creation.Uri, size)
+	switch {
+	case errors.Is(err, errNoImage):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		slog.Debug("Failed to generate thumbnail", logKeyMint, mint, logKeyError, err)
+		writeError(w, http.StatusBadGateway, "failed to fetch the token image")
+		return
+	}
+	w.Header().Set("Content-Type", "image/webp")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(imageCacheMaxAge.Seconds())))
+	w.Write(thumbnail)
+}
+
+// Thumbnail returns the cached thumbnail of a token, generating it on the
+// first request; concurrent requests for the same thumbnail share one download
+//
+// Parameters:
+//   - mint: Token mint address
+//   - uri: Metadata URI of the token
+//   - size: Width and height the thumbnail fits in
+//
+// Returns:
+//   - []byte: The thumbnail, encoded as WebP
+//   - error: errNoImage if the metadata has no image, or a download or decoding error
+func (p *ImageProxy) Thumbnail(mint, uri string, size int) ([]byte, error) {
+	key := mint + "/" + strconv.Itoa(size)
+	p.mutex.Lock()
+	thumbnail, cached := p.cache[key]
+	p.mutex.Unlock()
+	if cached {
+		return thumbnail, nil
+	}
+
+	value, err, _ := p.group.Do(key, func() (any, error) {
+		location, err := p.imageURL(mint, uri)
+		if err != nil {
+			return nil, err
+		}
+		thumbnail, err := p.generate(location, size)
+		if err != nil {
+			return nil, err
+		}
+		p.store(key, thumbnail)
+		return thumbnail, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+// imageURL returns the image URL in the metadata document of a token,
+// fetching both through the gateway when they are on IPFS
+// The URL is remembered, as is a missing image, so the metadata is downloaded
+// once per token rather than once per thumbnail request; a download that
+// failed is tried again on the next request.
+func (p *ImageProxy) imageURL(mint, uri string) (string, error) {
+	p.mutex.Lock()
+	location, known := p.locations[mint]
+	p.mutex.Unlock()
+	if !known {
+		var fetched bool
+		if location, fetched = p.fetchImageURL(uri); fetched {
+			p.remember(mint, location)
+		}
+	}
+	if location == "" {
+		return "", errNoImage
+	}
+	return location, nil
+}
+
+// fetchImageURL downloads the metadata document of a token and returns its
+// image URL
+//
+// Returns:
+//   - string: The image URL, empty if the document has no image over HTTP
+//   - bool: False if the document could not be downloaded or decoded
+func (p *ImageProxy) fetchImageURL(uri string) (string, bool) {
+	metadata, ok := fetchMetadataWith(p.client, p.resolve(uri))
+	if !ok {
+		return "", false
+	}
+	location := p.resolve(metadata.Image)
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return "", true
+	}
+	return location, true
+}
+
+// remember records the image URL of a token, forgetting the oldest beyond
+// imageMaxLocations
+func (p *ImageProxy) remember(mint, location string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, known := p.locations[mint]; known {
+		return
+	}
+	p.locations[mint] = location
+	p.locationOrder = append(p.locationOrder, mint)
+	for len(p.locationOrder) > imageMaxLocations {
+		delete(p.locations, p.locationOrder[0])
+		p.locationOrder = p.locationOrder[1:]
+	}
+}
+
+// resolve rewrites ipfs:// URLs and paths of public gateways to the gateway
+func (p *ImageProxy) resolve(location string) string {
+	if cid, found := strings.CutPrefix(location, "ipfs://"); found {
+		return p.gateway.String() + strings.TrimPrefix(cid, "ipfs/")
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	if cid, found := strings.CutPrefix(parsed.Path, "/ipfs/"); found && parsed.Host != p.gateway.Host {
+		return p.gateway.String() + cid
+	}
+	return location
+}
+
+// generate downloads an image and resizes it to fit in size pixels, keeping
+// its aspect ratio; smaller images are not enlarged
+func (p *ImageProxy) generate(location string, size int) ([]byte, error) {
+	resp, err := p.client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image request failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImageBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageBytes)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := source.Bounds()
+	width, height := thumbnailBounds(bounds.Dx(), bounds.Dy(), size)
+	thumbnail := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumbnail, thumbnail.Bounds(), source, bounds, draw.Src, nil)
+
+	var encoded bytes.Buffer
+	if err := nativewebp.Encode(&encoded, thumbnail, nil); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return encoded.Bytes(), nil
+}
+
+// thumbnailBounds returns the dimensions of an image of width by height pixels
+// scaled down to fit in size pixels, keeping its aspect ratio; smaller images
+// keep their dimensions
+func thumbnailBounds(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+// store caches a thumbnail, evicting the oldest ones over the memory budget
+func (p *ImageProxy) store(key string, thumbnail []byte) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, cached := p.cache[key]; cached {
+		return
+	}
+	p.cache[key] = thumbnail
+	p.order = append(p.order, key)
+	p.bytes += len(thumbnail)
+	for p.bytes > imageCacheBytes && len(p.order) > 1 {
+		oldest := p.order[0]
+		p.order = p.order[1:]
+		p.bytes -= len(p.cache[oldest])
+		delete(p.cache, oldest)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestThumbnailBounds checks that images are scaled down to fit, keeping their
+// aspect ratio, and that small images keep their dimensions
+func TestThumbnailBounds(t *testing.T) {
+	tests := []struct {
+		name           string
+		width, height  int
+		size           int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{name: "square", width: 1024, height: 1024, size: 256, expectedWidth: 256, expectedHeight: 256},
+		{name: "landscape", width: 1000, height: 500, size: 256, expectedWidth: 256, expectedHeight: 128},
+		{name: "portrait", width: 500, height: 1000, size: 64, expectedWidth: 32, expectedHeight: 64},
+		{name: "rounded down", width: 1000, height: 333, size: 64, expectedWidth: 64, expectedHeight: 21},
+		{name: "thin line", width: 10000, height: 1, size: 64, expectedWidth: 64, expectedHeight: 1},
+		{name: "only the height too large", width: 100, height: 300, size: 256, expectedWidth: 85, expectedHeight: 256},
+		{name: "smaller", width: 48, height: 32, size: 64, expectedWidth: 48, expectedHeight: 32},
+		{name: "exact fit", width: 64, height: 64, size: 64, expectedWidth: 64, expectedHeight: 64},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			width, height := thumbnailBounds(test.width, test.height, test.size)
+			if width != test.expectedWidth || height != test.expectedHeight {
+				t.Fatalf("got %dx%d, expected %dx%d", width, height, test.expectedWidth, test.expectedHeight)
+			}
+		})
+	}
+}
+
+// TestImageProxyResolve checks that IPFS URLs and paths of other gateways are
+// rewritten to the configured gateway, and other URLs are left alone
+func TestImageProxyResolve(t *testing.T) {
+	gateway, _ := url.Parse("https://gateway.example.com/ipfs/")
+	proxy := &ImageProxy{gateway: gateway}
+
+	tests := []struct {
+		name     string
+		location string
+		expected string
+	}{
+		{name: "ipfs scheme", location: "ipfs://QmHash", expected: "https://gateway.example.com/ipfs/QmHash"},
+		{name: "ipfs scheme with path", location: "ipfs://ipfs/QmHash/image.png", expected: "https://gateway.example.com/ipfs/QmHash/image.png"},
+		{name: "public gateway", location: "https://ipfs.io/ipfs/QmHash", expected: "https://gateway.example.com/ipfs/QmHash"},
+		{name: "configured gateway", location: "https://gateway.example.com/ipfs/QmHash", expected: "https://gateway.example.com/ipfs/QmHash"},
+		{name: "web", location: "https://example.com/image.png", expected: "https://example.com/image.png"},
+		{name: "unparsable", location: "http://[::1", expected: "http://[::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := proxy.resolve(test.location); got != test.expected {
+				t.Fatalf("got %s, expected %s", got, test.expected)
+			}
+		})
+	}
+}
+
+// TestPublicDialer checks that only public addresses and trusted hosts are dialed
+func TestPublicDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	tests := []struct {
+		name     string
+		trusted  []string
+		address  string
+		expected bool
+	}{
+		{name: "loopback", address: "127.0.0.1:" + port},
+		{name: "trusted loopback", trusted: []string{"127.0.0.1"}, address: "127.0.0.1:" + port, expected: true},
+		{name: "other host trusted", trusted: []string{"gateway.example.com"}, address: "127.0.0.1:" + port},
+		{name: "private", address: "10.0.0.1:80"},
+		{name: "link-local", address: "169.254.169.254:80"},
+		{name: "unspecified", address: "0.0.0.0:" + port},
+		{name: "IPv6 loopback", address: "[::1]:" + port},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn, err := publicDialer(test.trusted)(context.Background(), "tcp", test.address)
+			if err == nil {
+				conn.Close()
+			}
+			if (err == nil) != test.expected {
+				t.Fatalf("got error %v, expected a connection %v", err, test.expected)
+			}
+			if err != nil && !test.expected && !strings.Contains(err.Error(), "non-public") {
+				t.Fatalf("got error %v, expected the address refused", err)
+			}
+		})
+	}
+}
+
+// TestPublicProxy checks that requests to hosts resolving to private
+// addresses are refused before they reach a proxy
+func TestPublicProxy(t *testing.T) {
+	proxyURL, _ := url.Parse("http://proxy.internal:3128")
+	proxy := publicProxy(http.ProxyURL(proxyURL), []string{"gateway.internal"})
+
+	tests := []struct {
+		name     string
+		target   string
+		expected bool
+	}{
+		{name: "public address", target: "http://93.184.215.14/image.png", expected: true},
+		{name: "trusted host", target: "http://gateway.internal/ipfs/QmHash", expected: true},
+		{name: "loopback", target: "http://127.0.0.1/image.png"},
+		{name: "localhost", target: "http://localhost/image.png"},
+		{name: "metadata service", target: "http://169.254.169.254/latest/meta-data/"},
+		{name: "private", target: "https://10.0.0.1/image.png"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.target, nil)
+			got, err := proxy(request)
+			if test.expected && (err != nil || got != proxyURL) {
+				t.Fatalf("got %v (%v), expected %s", got, err, proxyURL)
+			}
+			if !test.expected && err == nil {
+				t.Fatalf("got %v, expected the request refused", got)
+			}
+		})
+	}
+
+	direct := publicProxy(func(*http.Request) (*url.URL, error) { return nil, nil }, nil)
+	if got, err := direct(httptest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)); got != nil || err != nil {
+		t.Fatalf("got %v (%v) for a direct request, expected it left to the dialer", got, err)
+	}
+}
+
+// TestImageProxyImageURL checks that the metadata of a token is downloaded once,
+// whether it has an image or not, and again only after a failed download
+func TestImageProxyImageURL(t *testing.T) {
+	var downloads atomic.Int32
+	failing := atomic.Bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		switch {
+		case failing.Load():
+			w.WriteHeader(http.StatusBadGateway)
+		case r.URL.Path == "/image.json":
+			w.Write([]byte(`{"image":"https://example.com/image.png"}`))
+		default:
+			w.Write([]byte(`{"name":"No image"}`))
+		}
+	}))
+	defer server.Close()
+
+	gateway, _ := url.Parse("https://gateway.example.com/ipfs/")
+	proxy := &ImageProxy{client: server.Client(), gateway: gateway, cache: map[string][]byte{}, locations: map[string]string{}}
+
+	for range 3 {
+		if location, err := proxy.imageURL("with-image", server.URL+"/image.json"); err != nil || location != "https://example.com/image.png" {
+			t.Fatalf("got %s (%v), expected the image", location, err)
+		}
+		if _, err := proxy.imageURL("without-image", server.URL+"/none.json"); err != errNoImage {
+			t.Fatalf("got %v, expected %v", err, errNoImage)
+		}
+	}
+	if got := downloads.Load(); got != 2 {
+		t.Fatalf("got %d downloads, expected 2", got)
+	}
+
+	failing.Store(true)
+	for range 2 {
+		if _, err := proxy.imageURL("unreachable", server.URL+"/image.json"); err != errNoImage {
+			t.Fatalf("got %v, expected %v", err, errNoImage)
+		}
+	}
+	if got := downloads.Load(); got != 4 {
+		t.Fatalf("got %d downloads, expected the failed one tried again", got)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the InfluxDB URL, e.g. http://localhost:8086
+	// The InfluxDB sink is disabled when it is unset
+	influxURLEnv = "INFLUXDB_URL"
+
+	// Environment variables with the API token, organization and bucket (InfluxDB 2.x)
+	influxTokenEnv  = "INFLUXDB_TOKEN"
+	influxOrgEnv    = "INFLUXDB_ORG"
+	influxBucketEnv = "INFLUXDB_BUCKET"
+
+	// Bucket used when INFLUXDB_BUCKET is unset
+	defaultInfluxBucket = "pumpfun"
+
+	// Interval of the global activity series
+	influxActivityInterval = 10 * time.Second
+)
+
+// InfluxSink writes time series in line protocol to InfluxDB
+//
+// Measurements:
+//   - pumpfun_launch (tag creator): one point per creation, field count
+//   - pumpfun_trade (tags mint, side): one point per trade, fields sol, tokens,
+//     price_sol and market_cap_sol
+//   - pumpfun_graduation (tag mint): one point per curve completion, field count
+//   - pumpfun_activity: totals over every influxActivityInterval, fields launches,
+//     trades, graduations, buy_volume_sol and sell_volume_sol
+//
+// Mints and wallets are base58 and need no escaping as tag values
+type InfluxSink struct {
+	*eventBatcher
+	writeURL string
+	token    string
+	client   *http.Client
+
+	mutex    sync.Mutex
+	activity influxActivity
+}
+
+// influxActivity accumulates the global activity of the current interval
+type influxActivity struct {
+	launches    int
+	trades      int
+	graduations int
+	buyVolume   float64
+	sellVolume  float64
+}
+
+// setupInfluxSink registers the InfluxDB sink when INFLUXDB_URL is set
+func setupInfluxSink() error {
+	endpoint := os.Getenv(influxURLEnv)
+	if endpoint == "" {
+		return nil
+	}
+
+	params := url.Values{
+		"org":       {os.Getenv(influxOrgEnv)},
+		"bucket":    {envOrDefault(influxBucketEnv, defaultInfluxBucket)},
+		"precision": {"ms"},
+	}
+
+	sink := &InfluxSink{
+		writeURL: strings.TrimSuffix(endpoint, "/") + "/api/v2/write?" + params.Encode(),
+		token:    os.Getenv(influxTokenEnv),
+		client:   &http.Client{Timeout: webhookRequestTimeout},
+	}
+	sink.eventBatcher = newEventBatcher("influxdb", sink.writeBatch)
+	go sink.activityLoop()
+	RegisterSink(sink)
+
+	slog.Info("Writing time series to InfluxDB", "bucket", params.Get("bucket"))
+	return nil
+}
+
+// writeBatch writes one point per event and accumulates the global activity
+func (s *InfluxSink) writeBatch(batch []Event) error {
+	var lines bytes.Buffer
+
+	s.mutex.Lock()
+	for _, event := range batch {
+		at := event.ReceivedAt.UnixMilli()
+
+		switch data := event.Data.(type) {
+		case CreateEvent:
+			s.activity.launches++
+			record, _ := Tokens.Get(data.Mint)
+			fmt.Fprintf(&lines, "pumpfun_launch,creator=%s count=1i %d\n", influxTag(record.Creator), at)
+		case TradeEvent:
+			s.activity.trades++
+			sol := float64(data.SolAmount) / lamportsPerSOL
+			side := "sell"
+			if data.IsBuy {
+				side = "buy"
+				s.activity.buyVolume += sol
+			} else {
+				s.activity.sellVolume += sol
+			}
+
+			curve := CurveState{VirtualSolReserves: data.VirtualSolReserves, VirtualTokenReserves: data.VirtualTokenReserves}
+			price := 0.0
+			if data.VirtualTokenReserves > 0 {
+				// Lamports per base unit scaled to SOL (9 decimals) per whole token (6 decimals)
+				price = float64(data.VirtualSolReserves) / float64(data.VirtualTokenReserves) / 1e3
+			}
+			fmt.Fprintf(&lines, "pumpfun_trade,mint=%s,side=%s sol=%g,tokens=%di,price_sol=%g,market_cap_sol=%g %d\n",
+				data.Mint, side, sol, data.TokenAmount, price, curve.MarketCapSOL(), time.Unix(data.Timestamp, 0).UnixMilli())
+		case CompleteEvent:
+			s.activity.graduations++
+			fmt.Fprintf(&lines, "pumpfun_graduation,mint=%s count=1i %d\n", data.Mint, at)
+		}
+	}
+	s.mutex.Unlock()
+
+	if lines.Len() == 0 {
+		return nil
+	}
+	return s.write(&lines)
+}
+
+// activityLoop writes the global activity totals at a fixed interval
+func (s *InfluxSink) activityLoop() {
+	ticker := time.NewTicker(influxActivityInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mutex.Lock()
+		activity := s.activity
+		s.activity = influxActivity{}
+		s.mutex.Unlock()
+
+		line := fmt.Sprintf("pumpfun_activity launches=%di,trades=%di,graduations=%di,buy_volume_sol=%g,sell_volume_sol=%g %d\n",
+			activity.launches, activity.trades, activity.graduations, activity.buyVolume, activity.sellVolume, now.UnixMilli())
+		if err := s.write(strings.NewReader(line)); err != nil {
+			slog.Warn("Failed to write activity to InfluxDB", logKeyError, err)
+		}
+	}
+}
+
+// write sends line protocol to the write API
+func (s *InfluxSink) write(lines io.Reader) error {
+	request, err := http.NewRequest(http.MethodPost, s.writeURL, lines)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if s.token != "" {
+		request.Header.Set("Authorization", "Token "+s.token)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("InfluxDB responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// influxTag returns a tag value, substituting a placeholder for empty values,
+// which line protocol does not allow
+func influxTag(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
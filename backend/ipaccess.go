@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file with the IP allow and deny
+	// lists of the WebSocket endpoint, e.g. {"allow":[],"deny":["203.0.113.0/24"]}
+	// It is rewritten whenever the lists are edited through the admin API, and
+	// created on the first edit if missing; edits are lost on restart when unset
+	ipAccessFileEnv = "IP_ACCESS_FILE"
+
+	// Admin endpoints listing and editing the lists
+	adminIPAccessEndpoint     = "/admin/ip-access"
+	adminIPAccessListEndpoint = "/admin/ip-access/{list}"
+
+	// Names of the two lists
+	ipAllowList = "allow"
+	ipDenyList  = "deny"
+
+	// Close reason sent to connected clients whose address gets denied
+	ipDeniedCloseReason = "address denied"
+)
+
+// errSaveIPAccess is returned when an edit applies but cannot be persisted
+var errSaveIPAccess = errors.New("failed to save IP access lists")
+
+// IPAccessLists is the JSON form of the lists, in the file and the admin API
+type IPAccessLists struct {
+	Allow []string `json:"allow"` // Addresses and CIDR ranges allowed to connect; anyone when empty
+	Deny  []string `json:"deny"`  // Addresses and CIDR ranges refused, even if allowed
+}
+
+// IPAccessControl decides which client addresses may open WebSocket connections
+type IPAccessControl struct {
+	mutex sync.RWMutex
+	allow []netip.Prefix
+	deny  []netip.Prefix
+	path  string // File the lists are persisted to, empty to keep them in memory
+}
+
+// IPAccess holds the allow and deny lists of the WebSocket endpoint
+var IPAccess = &IPAccessControl{}
+
+// setupIPAccess loads the lists from IP_ACCESS_FILE when it is set
+func setupIPAccess() error {
+	path := os.Getenv(ipAccessFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	IPAccess.mutex.Lock()
+	defer IPAccess.mutex.Unlock()
+	IPAccess.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read IP access file: %w", err)
+	}
+
+	var lists IPAccessLists
+	if err := json.Unmarshal(data, &lists); err != nil {
+		return fmt.Errorf("failed to parse IP access file: %w", err)
+	}
+	if IPAccess.allow, err = parsePrefixes(lists.Allow); err != nil {
+		return fmt.Errorf("IP access file: %w", err)
+	}
+	if IPAccess.deny, err = parsePrefixes(lists.Deny); err != nil {
+		return fmt.Errorf("IP access file: %w", err)
+	}
+
+	slog.Info("Loaded IP access lists", "allowed", len(IPAccess.allow), "denied", len(IPAccess.deny))
+	return nil
+}
+
+// parsePrefixes parses addresses and CIDR ranges, a single address becoming a
+// range of one
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// parsePrefix parses an address or a CIDR range
+func parsePrefix(entry string) (netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR range %q", entry)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q", entry)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Allowed reports whether a client address may connect: it must not be
+// denied, and must be allowed when the allow list is not empty
+func (c *IPAccessControl) Allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return true
+	}
+	addr = addr.Unmap()
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if containsAddr(c.deny, addr) {
+		return false
+	}
+	return len(c.allow) == 0 || containsAddr(c.allow, addr)
+}
+
+// containsAddr reports whether any of the ranges contains addr
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lists returns the current lists
+func (c *IPAccessControl) Lists() IPAccessLists {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.listsLocked()
+}
+
+// listsLocked returns the current lists; the caller must hold the mutex
+func (c *IPAccessControl) listsLocked() IPAccessLists {
+	lists := IPAccessLists{Allow: []string{}, Deny: []string{}}
+	for _, prefix := range c.allow {
+		lists.Allow = append(lists.Allow, formatPrefix(prefix))
+	}
+	for _, prefix := range c.deny {
+		lists.Deny = append(lists.Deny, formatPrefix(prefix))
+	}
+	return lists
+}
+
+// formatPrefix writes a range of one address as the address alone
+func formatPrefix(prefix netip.Prefix) string {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
+
+// Add adds an entry to a list and persists the lists
+//
+// Returns:
+//   - netip.Prefix: The parsed entry
+//   - error: Error if the list or entry is invalid, or the lists could not be saved
+func (c *IPAccessControl) Add(list, entry string) (netip.Prefix, error) {
+	prefix, err := parsePrefix(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	target, err := c.list(list)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if !slices.Contains(*target, prefix) {
+		*target = append(*target, prefix)
+	}
+	return prefix, c.saveLocked()
+}
+
+// Remove removes an entry from a list and persists the lists
+//
+// Returns:
+//   - bool: False if the entry was not on the list
+//   - error: Error if the list or entry is invalid, or the lists could not be saved
+func (c *IPAccessControl) Remove(list, entry string) (bool, error) {
+	prefix, err := parsePrefix(entry)
+	if err != nil {
+		return false, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	target, err := c.list(list)
+	if err != nil {
+		return false, err
+	}
+	index := slices.Index(*target, prefix)
+	if index < 0 {
+		return false, nil
+	}
+	*target = slices.Delete(*target, index, index+1)
+	return true, c.saveLocked()
+}
+
+// list returns the list with the given name; the caller must hold the mutex
+func (c *IPAccessControl) list(name string) (*[]netip.Prefix, error) {
+	switch name {
+	case ipAllowList:
+		return &c.allow, nil
+	case ipDenyList:
+		return &c.deny, nil
+	}
+	return nil, fmt.Errorf("unknown list %q, expected %s or %s", name, ipAllowList, ipDenyList)
+}
+
+// saveLocked writes the lists to the file, through a temporary file so a crash
+// never leaves it half written; the caller must hold the mutex
+// The edit stays in effect when it cannot be saved
+func (c *IPAccessControl) saveLocked() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.listsLocked(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSaveIPAccess, err)
+	}
+	temporary, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSaveIPAccess, err)
+	}
+	defer os.Remove(temporary.Name())
+
+	_, err = temporary.Write(data)
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporary.Name(), c.path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", errSaveIPAccess, err)
+	}
+	return nil
+}
+
+// disconnectDenied closes the connections of clients whose address is no longer allowed
+//
+// Returns:
+//   - int: Number of connections closed
+func disconnectDenied() int {
+	return disconnectClients(websocket.ClosePolicyViolation, ipDeniedCloseReason, func(client *Client) bool {
+		return !IPAccess.Allowed(client.ip)
+	})
+}
+
+// ipAccessRequest is the JSON body adding or removing a list entry
+type ipAccessRequest struct {
+	Entry string `json:"entry"` // Address or CIDR range
+}
+
+// HandleListIPAccess returns the allow and deny lists
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListIPAccess(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, IPAccess.Lists())
+}
+
+// HandleAddIPAccess adds the entry in the JSON body to a list, disconnecting
+// clients that may no longer connect
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the list name as a path variable and an ipAccessRequest body
+func HandleAddIPAccess(w http.ResponseWriter, r *http.Request) {
+	var request ipAccessRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	list := mux.Vars(r)["list"]
+	prefix, err := IPAccess.Add(list, request.Entry)
+	if err != nil && !errors.Is(err, errSaveIPAccess) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// An entry that could not be saved still applies until the restart
+	disconnected := disconnectDenied()
+	slog.Info("IP access list changed", "list", list, "added", formatPrefix(prefix), "disconnected", disconnected)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, IPAccess.Lists())
+}
+
+// HandleRemoveIPAccess removes the entry given by the entry query parameter from a list
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with the list name as a path variable and the entry query parameter
+func HandleRemoveIPAccess(w http.ResponseWriter, r *http.Request) {
+	list := mux.Vars(r)["list"]
+	entry := r.URL.Query().Get("entry")
+	removed, err := IPAccess.Remove(list, entry)
+	if err != nil && !errors.Is(err, errSaveIPAccess) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "entry not on the list")
+		return
+	}
+
+	// Removing an allowed range can lock clients out too
+	disconnected := disconnectDenied()
+	slog.Info("IP access list changed", "list", list, "removed", entry, "disconnected", disconnected)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, IPAccess.Lists())
+}
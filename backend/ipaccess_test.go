@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestParsePrefix checks that addresses become ranges of one, ranges are
+// masked, and that anything else is refused
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		entry    string
+		expected string
+		invalid  bool
+	}{
+		{name: "IPv4 address", entry: "203.0.113.7", expected: "203.0.113.7/32"},
+		{name: "IPv6 address", entry: "2001:db8::1", expected: "2001:db8::1/128"},
+		{name: "IPv4-mapped address", entry: "::ffff:203.0.113.7", expected: "203.0.113.7/32"},
+		{name: "range", entry: "203.0.113.0/24", expected: "203.0.113.0/24"},
+		{name: "range with host bits", entry: "203.0.113.7/24", expected: "203.0.113.0/24"},
+		{name: "surrounding spaces", entry: " 203.0.113.7 ", expected: "203.0.113.7/32"},
+		{name: "hostname", entry: "example.com", invalid: true},
+		{name: "prefix too long", entry: "203.0.113.0/33", invalid: true},
+		{name: "empty", entry: "", invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			prefix, err := parsePrefix(test.entry)
+			if test.invalid {
+				if err == nil {
+					t.Fatalf("got %s, expected an error", prefix)
+				}
+				return
+			}
+			if err != nil || prefix.String() != test.expected {
+				t.Fatalf("got %s (%v), expected %s", prefix, err, test.expected)
+			}
+		})
+	}
+}
+
+// TestIPAccessAllowed checks that denied addresses are refused even when
+// allowed, and that a non-empty allow list refuses everyone else
+func TestIPAccessAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    []string
+		deny     []string
+		ip       string
+		expected bool
+	}{
+		{name: "empty lists", ip: "203.0.113.7", expected: true},
+		{name: "denied address", deny: []string{"203.0.113.7"}, ip: "203.0.113.7"},
+		{name: "other address denied", deny: []string{"203.0.113.8"}, ip: "203.0.113.7", expected: true},
+		{name: "denied range", deny: []string{"203.0.113.0/24"}, ip: "203.0.113.7"},
+		{name: "allowed range", allow: []string{"203.0.113.0/24"}, ip: "203.0.113.7", expected: true},
+		{name: "outside the allowed range", allow: []string{"203.0.113.0/24"}, ip: "198.51.100.7"},
+		{name: "allowed and denied", allow: []string{"203.0.113.0/24"}, deny: []string{"203.0.113.7"}, ip: "203.0.113.7"},
+		{name: "allowed next to a denied address", allow: []string{"203.0.113.0/24"}, deny: []string{"203.0.113.7"}, ip: "203.0.113.8", expected: true},
+		{name: "IPv4-mapped client", deny: []string{"203.0.113.7"}, ip: "::ffff:203.0.113.7"},
+		{name: "IPv6 range", allow: []string{"2001:db8::/32"}, ip: "2001:db8::1", expected: true},
+		{name: "IPv4 client and an IPv6 allow list", allow: []string{"2001:db8::/32"}, ip: "203.0.113.7"},
+		{name: "unparsable address", allow: []string{"203.0.113.0/24"}, ip: "@", expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allow, err := parsePrefixes(test.allow)
+			if err != nil {
+				t.Fatalf("failed to parse the allow list: %v", err)
+			}
+			deny, err := parsePrefixes(test.deny)
+			if err != nil {
+				t.Fatalf("failed to parse the deny list: %v", err)
+			}
+
+			access := &IPAccessControl{allow: allow, deny: deny}
+			if got := access.Allowed(test.ip); got != test.expected {
+				t.Fatalf("got %v, expected %v", got, test.expected)
+			}
+		})
+	}
+}
+
+// TestIPAccessControlSave checks that edits are written to the file, once
+// per entry, and that unknown lists are refused
+func TestIPAccessControlSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ip-access.json")
+	access := &IPAccessControl{path: path}
+
+	for _, entry := range []string{"203.0.113.0/24", "203.0.113.7/24", "198.51.100.7"} {
+		if _, err := access.Add(ipDenyList, entry); err != nil {
+			t.Fatalf("failed to deny %s: %v", entry, err)
+		}
+	}
+	if _, err := access.Add(ipAllowList, "2001:db8::/32"); err != nil {
+		t.Fatalf("failed to allow a range: %v", err)
+	}
+	if removed, err := access.Remove(ipDenyList, "198.51.100.7"); !removed || err != nil {
+		t.Fatalf("got %v (%v), expected the address removed", removed, err)
+	}
+	if removed, err := access.Remove(ipDenyList, "198.51.100.7"); removed || err != nil {
+		t.Fatalf("got %v (%v), expected nothing left to remove", removed, err)
+	}
+	if _, err := access.Add("block", "203.0.113.7"); err == nil {
+		t.Fatal("got no error, expected the unknown list refused")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read the file: %v", err)
+	}
+	var saved IPAccessLists
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("failed to parse the file: %v", err)
+	}
+	expected := IPAccessLists{Allow: []string{"2001:db8::/32"}, Deny: []string{"203.0.113.0/24"}}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Fatalf("got %+v, expected %+v", saved, expected)
+	}
+}
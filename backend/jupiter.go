@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the interval between price updates of the
+	// graduated tokens, e.g. 30s; Jupiter price enrichment is disabled when it is unset
+	jupiterPriceIntervalEnv = "JUPITER_PRICE_INTERVAL"
+
+	// Environment variable overriding the Jupiter Price API endpoint
+	jupiterPriceURLEnv = "JUPITER_PRICE_URL"
+
+	// Environment variable with a Jupiter API key, sent as the x-api-key
+	// header; needed for the paid endpoints
+	jupiterAPIKeyEnv = "JUPITER_API_KEY"
+
+	// Environment variable with the number of Price API requests allowed per minute
+	jupiterRateLimitEnv = "JUPITER_RATE_LIMIT"
+
+	// Endpoint used when JUPITER_PRICE_URL is unset, the free tier of the Price API
+	defaultJupiterPriceURL = "https://lite-api.jup.ag/price/v3"
+
+	// Requests per minute used when JUPITER_RATE_LIMIT is unset, the limit of the free tier
+	defaultJupiterRateLimit = 60
+
+	// Largest number of mints quoted by a single request
+	jupiterPriceBatchSize = 50
+
+	// Longest a Price API request may take
+	jupiterRequestTimeout = 10 * time.Second
+
+	// Source recorded on the quotes
+	jupiterPriceSource = "jupiter"
+)
+
+// jupiterQuote is the entry of a mint in a Price API response; mints without
+// a reliable price are left out of the response
+type jupiterQuote struct {
+	USDPrice float64 `json:"usdPrice"` // Price of one token in USD
+	BlockID  uint64  `json:"blockId"`  // Slot of the swap the price was derived from
+}
+
+// JupiterPricer quotes the tokens that migrated off their bonding curve on
+// the Jupiter Price API, so their update stream carries on with a price once
+// curve trades stop
+//
+// Every interval the graduated tokens of the store are quoted in batches,
+// paced to stay within the rate limit. The last quote of each mint is cached:
+// a price event is only published when the price moves, and the quote is
+// recorded on the token for the REST API.
+type JupiterPricer struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+
+	// Requests are spaced by gap; next is the earliest time of the next one
+	gap  time.Duration
+	next time.Time
+
+	mutex  sync.Mutex
+	quotes map[string]jupiterQuote
+}
+
+// JupiterPrices quotes graduated tokens when JUPITER_PRICE_INTERVAL is set, nil otherwise
+var JupiterPrices *JupiterPricer
+
+// setupJupiterPrices starts quoting graduated tokens when JUPITER_PRICE_INTERVAL is set
+func setupJupiterPrices() error {
+	value := os.Getenv(jupiterPriceIntervalEnv)
+	if value == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid %s %q", jupiterPriceIntervalEnv, value)
+	}
+
+	rateLimit := defaultJupiterRateLimit
+	if value := os.Getenv(jupiterRateLimitEnv); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", jupiterRateLimitEnv, value)
+		}
+		rateLimit = parsed
+	}
+
+	endpoint := os.Getenv(jupiterPriceURLEnv)
+	if endpoint == "" {
+		endpoint = defaultJupiterPriceURL
+	}
+	apiKey := os.Getenv(jupiterAPIKeyEnv)
+	registerSecret(apiKey)
+
+	JupiterPrices = &JupiterPricer{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: jupiterRequestTimeout},
+		gap:      time.Minute / time.Duration(rateLimit),
+		quotes:   map[string]jupiterQuote{},
+	}
+	goSafe("jupiter prices", func() { JupiterPrices.run(interval) })
+
+	slog.Info("Quoting graduated tokens on Jupiter", "interval", interval, "requests_per_minute", rateLimit)
+	return nil
+}
+
+// run updates the prices at a fixed interval
+// A round taking longer than the interval delays the next one
+func (j *JupiterPricer) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		j.update()
+	}
+}
+
+// update quotes every graduated token once and forgets the cached quotes of
+// tokens no longer tracked
+func (j *JupiterPricer) update() {
+	mints := Tokens.Graduated()
+
+	j.mutex.Lock()
+	quotes := make(map[string]jupiterQuote, len(mints))
+	for _, mint := range mints {
+		if quote, cached := j.quotes[mint]; cached {
+			quotes[mint] = quote
+		}
+	}
+	j.quotes = quotes
+	j.mutex.Unlock()
+
+	for start := 0; start < len(mints); start += jupiterPriceBatchSize {
+		batch := mints[start:min(start+jupiterPriceBatchSize, len(mints))]
+		quoted, err := j.fetch(batch)
+		if err != nil {
+			slog.Warn("Failed to get prices from Jupiter", "mints", len(batch), logKeyError, err)
+			reportError(errorCategoryUpstream, err)
+			continue
+		}
+		for _, mint := range batch {
+			if quote, ok := quoted[mint]; ok && quote.USDPrice > 0 {
+				j.record(mint, quote)
+			}
+		}
+	}
+}
+
+// record caches a quote, publishing a price event when the price moved
+func (j *JupiterPricer) record(mint string, quote jupiterQuote) {
+	j.mutex.Lock()
+	previous, cached := j.quotes[mint]
+	j.quotes[mint] = quote
+	j.mutex.Unlock()
+
+	quotedAt := time.Now().UTC()
+	Tokens.RecordPrice(mint, TokenPrice{PriceUSD: quote.USDPrice, Source: jupiterPriceSource, QuotedAt: quotedAt})
+	if cached && previous.USDPrice == quote.USDPrice {
+		return
+	}
+
+	publishEvent(Event{
+		Type:       EventPrice,
+		Mint:       mint,
+		Slot:       quote.BlockID,
+		ReceivedAt: quotedAt,
+		Data: PriceEvent{
+			Mint:      mint,
+			PriceUSD:  quote.USDPrice,
+			Source:    jupiterPriceSource,
+			Timestamp: quotedAt.Unix(),
+		},
+	})
+}
+
+// fetch quotes a batch of mints, waiting for the rate limit first
+// A rate limited response pauses the following requests for as long as it asks
+func (j *JupiterPricer) fetch(mints []string) (map[string]jupiterQuote, error) {
+	if wait := time.Until(j.next); wait > 0 {
+		time.Sleep(wait)
+	}
+	j.next = time.Now().Add(j.gap)
+
+	ctx, cancel := context.WithTimeout(context.Background(), jupiterRequestTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, j.endpoint+"?"+url.Values{"ids": {strings.Join(mints, ",")}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if j.apiKey != "" {
+		request.Header.Set("x-api-key", j.apiKey)
+	}
+
+	resp, err := j.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		j.next = time.Now().Add(max(time.Duration(seconds)*time.Second, j.gap))
+		return nil, fmt.Errorf("rate limited")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var quotes map[string]jupiterQuote
+	if err := json.NewDecoder(resp.Body).Decode(&quotes); err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Configuration constants
+const (
+	// Environment variable with a comma separated list of Kafka brokers
+	// The Kafka sink is disabled when it is unset
+	kafkaBrokersEnv = "KAFKA_BROKERS"
+
+	// Environment variable overriding the topic prefix; topics are <prefix><event type>
+	kafkaTopicPrefixEnv = "KAFKA_TOPIC_PREFIX"
+
+	// Environment variable selecting the partitioning strategy: "mint" or "none"
+	kafkaPartitionByEnv = "KAFKA_PARTITION_BY"
+
+	// Topic prefix used when KAFKA_TOPIC_PREFIX is unset
+	defaultKafkaTopicPrefix = "pumpfun."
+
+	// Maximum time a message waits in the producer batch
+	kafkaBatchTimeout = 50 * time.Millisecond
+)
+
+// KafkaSink publishes every event to a Kafka topic named after its event type
+type KafkaSink struct {
+	writer      *kafka.Writer
+	topicPrefix string
+	keyByMint   bool
+}
+
+// setupKafkaSink registers the Kafka sink when KAFKA_BROKERS is set
+func setupKafkaSink() error {
+	brokers := os.Getenv(kafkaBrokersEnv)
+	if brokers == "" {
+		return nil
+	}
+
+	partitionBy := envOrDefault(kafkaPartitionByEnv, "mint")
+	if partitionBy != "mint" && partitionBy != "none" {
+		return fmt.Errorf("%s must be \"mint\" or \"none\", got %q", kafkaPartitionByEnv, partitionBy)
+	}
+
+	sink := NewKafkaSink(strings.Split(brokers, ","), envOrDefault(kafkaTopicPrefixEnv, defaultKafkaTopicPrefix), partitionBy == "mint")
+	RegisterSink(sink)
+
+	slog.Info("Publishing events to Kafka", "brokers", brokers)
+	return nil
+}
+
+// NewKafkaSink creates an asynchronous Kafka producer
+//
+// Parameters:
+//   - brokers: addresses of the Kafka brokers
+//   - topicPrefix: prefix prepended to the event type to form the topic name
+//   - keyByMint: when true messages are keyed and hash-partitioned by mint, so
+//     all events of a token land on the same partition in order
+func NewKafkaSink(brokers []string, topicPrefix string, keyByMint bool) *KafkaSink {
+	var balancer kafka.Balancer = &kafka.RoundRobin{}
+	if keyByMint {
+		balancer = &kafka.Hash{}
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               balancer,
+		BatchTimeout:           kafkaBatchTimeout,
+		RequiredAcks:           kafka.RequireOne,
+		AllowAutoTopicCreation: true,
+		Async:                  true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				slog.Warn("Failed to publish messages to Kafka", "messages", len(messages), logKeyError, err)
+			}
+		},
+	}
+
+	return &KafkaSink{writer: writer, topicPrefix: topicPrefix, keyByMint: keyByMint}
+}
+
+// Name identifies the sink in logs
+func (k *KafkaSink) Name() string {
+	return "kafka"
+}
+
+// Publish hands the event to the asynchronous producer
+func (k *KafkaSink) Publish(event Event) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("Failed to marshal event for Kafka", logKeyError, err)
+		return
+	}
+
+	message := kafka.Message{
+		Topic: k.topicPrefix + string(event.Type),
+		Value: value,
+		Time:  event.ReceivedAt,
+	}
+	if k.keyByMint {
+		message.Key = []byte(event.Mint)
+	}
+
+	// With Async set this only returns configuration errors; delivery
+	// failures are reported through the Completion callback
+	if err := k.writer.WriteMessages(context.Background(), message); err != nil {
+		slog.Warn("Failed to queue event for Kafka", logKeyError, err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Upper bounds in milliseconds of the delivery latency histogram buckets; a
+// final bucket counts everything slower
+var latencyBucketBounds = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// latencyHistogram counts latencies in fixed buckets
+type latencyHistogram struct {
+	mutex  sync.Mutex
+	counts []uint64 // Observations per bucket, the last one unbounded
+	sum    float64  // Sum of all observations in milliseconds
+}
+
+// LatencyBucket is one cumulative bucket of a latency histogram
+type LatencyBucket struct {
+	UpperBound float64 `json:"le"`    // Upper bound in milliseconds, 0 for the unbounded bucket
+	Count      uint64  `json:"count"` // Observations at or below the bound
+}
+
+// LatencySnapshot is the state of a latency histogram
+type LatencySnapshot struct {
+	Count   uint64          `json:"count"`   // Number of observations
+	SumMs   float64         `json:"sum_ms"`  // Sum of all observations in milliseconds
+	P50     float64         `json:"p50_ms"`  // Estimated median in milliseconds
+	P90     float64         `json:"p90_ms"`  // Estimated 90th percentile in milliseconds
+	P99     float64         `json:"p99_ms"`  // Estimated 99th percentile in milliseconds
+	Buckets []LatencyBucket `json:"buckets"` // Cumulative counts per bucket
+
+	counts []uint64 // Observations per bucket, for differences between snapshots
+}
+
+// deliveryLatency measures the time from the receipt of a notification to the
+// write of its message to each WebSocket client
+var deliveryLatency = newLatencyHistogram()
+
+// newLatencyHistogram creates an empty histogram
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBucketBounds)+1)}
+}
+
+// Observe counts one latency
+func (h *latencyHistogram) Observe(latency time.Duration) {
+	ms := float64(latency) / float64(time.Millisecond)
+	bucket := len(latencyBucketBounds)
+	for i, bound := range latencyBucketBounds {
+		if ms <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.counts[bucket]++
+	h.sum += ms
+}
+
+// Snapshot returns the observations so far
+func (h *latencyHistogram) Snapshot() LatencySnapshot {
+	h.mutex.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	sum := h.sum
+	h.mutex.Unlock()
+
+	return newLatencySnapshot(counts, sum)
+}
+
+// Since returns the observations made after an earlier snapshot
+func (s LatencySnapshot) Since(earlier LatencySnapshot) LatencySnapshot {
+	counts := append([]uint64(nil), s.counts...)
+	for i := range earlier.counts {
+		counts[i] -= earlier.counts[i]
+	}
+	return newLatencySnapshot(counts, s.SumMs-earlier.SumMs)
+}
+
+// newLatencySnapshot derives the cumulative buckets and percentiles from the
+// per-bucket counts
+func newLatencySnapshot(counts []uint64, sum float64) LatencySnapshot {
+	snapshot := LatencySnapshot{SumMs: sum, counts: counts, Buckets: make([]LatencyBucket, len(counts))}
+	for i, count := range counts {
+		snapshot.Count += count
+		snapshot.Buckets[i].Count = snapshot.Count
+		if i < len(latencyBucketBounds) {
+			snapshot.Buckets[i].UpperBound = latencyBucketBounds[i]
+		}
+	}
+	snapshot.P50 = snapshot.quantile(0.5)
+	snapshot.P90 = snapshot.quantile(0.9)
+	snapshot.P99 = snapshot.quantile(0.99)
+	return snapshot
+}
+
+// quantile estimates a quantile by interpolating linearly within its bucket
+// Quantiles in the unbounded bucket are reported as the highest bound
+func (s LatencySnapshot) quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	rank := q * float64(s.Count)
+	lower := 0.0
+	var below uint64
+	for i, bucket := range s.Buckets {
+		if i == len(latencyBucketBounds) {
+			return latencyBucketBounds[len(latencyBucketBounds)-1]
+		}
+		if float64(bucket.Count) >= rank {
+			inBucket := bucket.Count - below
+			return lower + (bucket.UpperBound-lower)*(rank-float64(below))/float64(inBucket)
+		}
+		lower, below = bucket.UpperBound, bucket.Count
+	}
+	return lower
+}
+
+// receivedAtKey is the context key of the receipt time of a notification
+type receivedAtKey struct{}
+
+// withReceivedAt returns a context carrying the receipt time of the notification being processed
+func withReceivedAt(ctx context.Context, receivedAt time.Time) context.Context {
+	return context.WithValue(ctx, receivedAtKey{}, receivedAt)
+}
+
+// deliveryStart returns the receipt time latencies are measured from
+// Notifications replayed from a recording carry their original receipt time
+// and are not measured
+//
+// Returns:
+//   - time.Time: Receipt time of the notification
+//   - bool: False if the latency should not be measured
+func deliveryStart(ctx context.Context) (time.Time, bool) {
+	receivedAt, ok := ctx.Value(receivedAtKey{}).(time.Time)
+	if !ok || receivedAt.IsZero() || time.Since(receivedAt) > maxSpanBackdate {
+		return time.Time{}, false
+	}
+	return receivedAt, true
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Configuration constants
+const (
+	// Environment variable selecting the log format: text (the default) or json
+	logFormatEnv = "LOG_FORMAT"
+
+	// Environment variable with the minimum level logged: debug, info (the
+	// default), warn or error
+	logLevelEnv = "LOG_LEVEL"
+
+	// Log formats
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// Attribute keys shared by every log line about a client or an event, so logs
+// can be filtered the same way whichever module wrote them
+const (
+	logKeyClientID  = "client_id"  // Remote address of a WebSocket client
+	logKeyMint      = "mint"       // Token mint address
+	logKeySignature = "signature"  // Transaction signature
+	logKeySlot      = "slot"       // Slot the transaction was observed in
+	logKeyError     = "error"      // Error being reported
+	logKeyEventType = "event_type" // Kind of event: create, trade, complete, ...
+	logKeySink      = "sink"       // Name of the sink handling the event
+
+	// ID shared by everything logged while processing one upstream notification
+	logKeyCorrelationID = "correlation_id"
+)
+
+// logLevel is the minimum level of the default logger; it can be changed while
+// running, through logLevels
+var logLevel = new(slog.LevelVar)
+
+// setupLogging installs the default structured logger writing to stderr
+// Output of the standard log package goes through the same handler at info level
+//
+// Parameters:
+//   - format: text or json
+//   - level: debug, info, warn or error
+//
+// Returns:
+//   - error: Error if the format or level is unknown
+func setupLogging(format, level string) error {
+	parsed, err := parseLogLevel(level)
+	if err != nil {
+		return err
+	}
+	logLevels.Configure(parsed)
+
+	options := &slog.HandlerOptions{Level: logLevel, ReplaceAttr: redactAttr}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case logFormatText:
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("unknown log format %q, expected %s or %s", format, logFormatText, logFormatJSON)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// clientLogger returns a logger carrying the client_id of a WebSocket client
+func clientLogger(address string) *slog.Logger {
+	return slog.With(logKeyClientID, address)
+}
+
+// eventAttrs returns the attributes identifying the transaction of an event,
+// and the notification it came in
+func eventAttrs(ctx context.Context, mint, signature string, slot uint64) []any {
+	return append([]any{logKeyMint, mint, logKeySignature, signature, logKeySlot, slot}, correlationAttrs(ctx)...)
+}
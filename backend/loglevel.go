@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Admin endpoint reporting and overriding the log level
+	adminLogLevelEndpoint = "/admin/log-level"
+
+	// Longest time an override may be set for
+	maxLogLevelOverride = 24 * time.Hour
+)
+
+// LogLevelStatus is the JSON body returned by the log level endpoint
+type LogLevelStatus struct {
+	Level      string     `json:"level"`                // Level in effect
+	Configured string     `json:"configured"`           // Level from --log-level or LOG_LEVEL, in effect without an override
+	Overridden bool       `json:"overridden"`           // Whether an admin override is in effect
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Time the override ends, if it was given a duration
+}
+
+// logLevelOverride tracks the configured log level and an admin override, so
+// debug logging can be turned on during an incident. An override survives
+// reloads, which only change the configured level it falls back to.
+type logLevelOverride struct {
+	mutex      sync.Mutex
+	configured slog.Level
+	overridden bool
+	expiresAt  time.Time
+	timer      *time.Timer
+}
+
+// logLevels holds the configured log level and its override
+var logLevels = &logLevelOverride{}
+
+// Configure sets the configured level, applied unless overridden
+func (o *logLevelOverride) Configure(level slog.Level) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.configured = level
+	if !o.overridden {
+		logLevel.Set(level)
+	}
+}
+
+// Override sets the level until cleared, or for a duration if it is positive
+func (o *logLevelOverride) Override(level slog.Level, duration time.Duration) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.stopTimerLocked()
+	o.overridden = true
+	logLevel.Set(level)
+	if duration > 0 {
+		o.expiresAt = time.Now().Add(duration)
+		var timer *time.Timer
+		timer = time.AfterFunc(duration, func() { o.expire(timer) })
+		o.timer = timer
+	}
+}
+
+// expire drops the override when its timer fires, unless a newer override
+// replaced it meanwhile
+func (o *logLevelOverride) expire(timer *time.Timer) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.timer != timer {
+		return
+	}
+	o.timer = nil
+	o.expiresAt = time.Time{}
+	o.overridden = false
+	logLevel.Set(o.configured)
+	slog.Info("Log level override expired", "level", logLevelName(o.configured))
+}
+
+// Clear drops the override, returning to the configured level
+func (o *logLevelOverride) Clear() {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.stopTimerLocked()
+	o.overridden = false
+	logLevel.Set(o.configured)
+}
+
+// stopTimerLocked cancels the expiry of an override; the caller must hold the mutex
+func (o *logLevelOverride) stopTimerLocked() {
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.expiresAt = time.Time{}
+}
+
+// Status describes the level in effect
+func (o *logLevelOverride) Status() LogLevelStatus {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	status := LogLevelStatus{
+		Level:      logLevelName(logLevel.Level()),
+		Configured: logLevelName(o.configured),
+		Overridden: o.overridden,
+	}
+	if !o.expiresAt.IsZero() {
+		expiresAt := o.expiresAt
+		status.ExpiresAt = &expiresAt
+	}
+	return status
+}
+
+// logLevelName writes a level the way LOG_LEVEL takes it, e.g. debug
+func logLevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// parseLogLevel parses debug, info, warn or error
+func parseLogLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return level, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", value)
+	}
+	return level, nil
+}
+
+// logLevelRequest is the JSON body overriding the log level
+type logLevelRequest struct {
+	Level    string `json:"level"`    // debug, info, warn or error
+	Duration string `json:"duration"` // Time until the configured level applies again, e.g. 30m; until cleared when empty
+}
+
+// HandleGetLogLevel returns the log level in effect
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleGetLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevels.Status())
+}
+
+// HandleSetLogLevel overrides the log level without a restart, so connected
+// clients are kept
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a logLevelRequest body
+func HandleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var request logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	level, err := parseLogLevel(request.Level)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var duration time.Duration
+	if request.Duration != "" {
+		duration, err = time.ParseDuration(request.Duration)
+		if err != nil || duration <= 0 || duration > maxLogLevelOverride {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("duration must be positive and at most %v", maxLogLevelOverride))
+			return
+		}
+	}
+
+	// Logged before the change, so raising the level does not hide it
+	slog.Warn("Log level overridden", "level", logLevelName(level), "duration", duration)
+	logLevels.Override(level, duration)
+	writeJSON(w, http.StatusOK, logLevels.Status())
+}
+
+// HandleClearLogLevel drops the override, returning to the configured level
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleClearLogLevel(w http.ResponseWriter, r *http.Request) {
+	logLevels.Clear()
+	slog.Info("Log level override cleared", "level", logLevelName(logLevel.Level()))
+	writeJSON(w, http.StatusOK, logLevels.Status())
+}
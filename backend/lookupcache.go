@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Number of keys waiting to be fetched by a lookup cache before new
+	// lookups are not queued
+	lookupCacheQueueSize = 10000
+
+	// Longest a fetch of a lookup cache may take
+	lookupCacheFetchTimeout = 10 * time.Second
+)
+
+// lookupCacheEntry is a cached value
+type lookupCacheEntry[T any] struct {
+	value     *T // Nil if the service has no value for the key
+	fetchedAt time.Time
+}
+
+// lookupCache caches values of a slow external service for the enrich stage
+// of the pipeline, which must never wait for it
+//
+// A lookup returns what the cache holds at once: a missing or expired key is
+// queued and fetched in the background, in batches spaced as the service
+// requires, so later lookups find it. Keys the service has no value for are
+// cached too, so they are not fetched on every lookup.
+type lookupCache[T any] struct {
+	name      string
+	ttl       time.Duration
+	batchSize int
+	interval  time.Duration
+	fetch     func(ctx context.Context, keys []string) (map[string]T, error)
+	queue     chan string
+
+	mutex   sync.Mutex
+	entries map[string]lookupCacheEntry[T]
+	pending map[string]struct{} // Keys queued or being fetched
+}
+
+// newLookupCache creates a cache and starts fetching the keys looked up
+//
+// Parameters:
+//   - name: What the cache holds, for logs
+//   - ttl: How long a value is reused before it is fetched again
+//   - batchSize: Largest number of keys per fetch
+//   - interval: Minimum time between two fetches
+//   - fetch: Returns the values of the keys; keys without a value are left out
+func newLookupCache[T any](name string, ttl time.Duration, batchSize int, interval time.Duration, fetch func(ctx context.Context, keys []string) (map[string]T, error)) *lookupCache[T] {
+	cache := &lookupCache[T]{
+		name:      name,
+		ttl:       ttl,
+		batchSize: batchSize,
+		interval:  interval,
+		fetch:     fetch,
+		queue:     make(chan string, lookupCacheQueueSize),
+		entries:   map[string]lookupCacheEntry[T]{},
+		pending:   map[string]struct{}{},
+	}
+	goSafe(name+" lookups", cache.fetchLoop)
+	return cache
+}
+
+// Lookup returns the cached value of a key, queueing a fetch when it is
+// missing or expired; the stale value is returned until the fetch completes
+//
+// Returns:
+//   - *T: The cached value, nil if none is cached or the service has none
+func (c *lookupCache[T]) Lookup(key string) *T {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, cached := c.entries[key]
+	if _, queued := c.pending[key]; !queued && (!cached || time.Since(entry.fetchedAt) > c.ttl) {
+		select {
+		case c.queue <- key:
+			c.pending[key] = struct{}{}
+		default:
+			// Fetched on a later lookup, once the service caught up
+		}
+	}
+	return entry.value
+}
+
+// fetchLoop fetches queued keys in batches, spacing the fetches by the interval
+func (c *lookupCache[T]) fetchLoop() {
+	var next time.Time
+
+	for key := range c.queue {
+		batch := []string{key}
+	collect:
+		for len(batch) < c.batchSize {
+			select {
+			case key := <-c.queue:
+				batch = append(batch, key)
+			default:
+				break collect
+			}
+		}
+
+		if wait := time.Until(next); wait > 0 {
+			time.Sleep(wait)
+		}
+		next = time.Now().Add(c.interval)
+
+		ctx, cancel := context.WithTimeout(context.Background(), lookupCacheFetchTimeout)
+		values, err := c.fetch(ctx, batch)
+		cancel()
+		if err != nil {
+			slog.Warn("Failed to fetch "+c.name, "keys", len(batch), logKeyError, err)
+			reportError(errorCategoryUpstream, err)
+		}
+		c.store(batch, values, err == nil)
+	}
+}
+
+// store caches the fetched values of a batch and clears its pending keys
+// Keys of a failed fetch are not cached, so they are fetched again
+func (c *lookupCache[T]) store(batch []string, values map[string]T, fetched bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for _, key := range batch {
+		delete(c.pending, key)
+		if !fetched {
+			continue
+		}
+		entry := lookupCacheEntry[T]{fetchedAt: now}
+		if value, found := values[key]; found {
+			entry.value = &value
+		}
+		c.entries[key] = entry
+	}
+
+	// Forget expired entries once the cache outgrows the token store
+	if len(c.entries) > maxTrackedTokens {
+		for key, entry := range c.entries {
+			if now.Sub(entry.fetchedAt) > c.ttl {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
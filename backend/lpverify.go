@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling LP verification: when true, the pool of
+	// every graduated token is inspected once it exists, and a migration
+	// event reports how much of its LP was burned or locked
+	lpVerificationEnv = "LP_VERIFICATION"
+
+	// Environment variable with comma-separated programs whose accounts hold
+	// locked LP, replacing the default lockers
+	lpLockerProgramsEnv = "LP_LOCKER_PROGRAMS"
+
+	// Interval between two lookups of a pool not created yet
+	lpPoolPollInterval = 5 * time.Second
+
+	// Longest the migration of a completed curve is waited for
+	lpPoolWaitTimeout = 10 * time.Minute
+
+	// Longest the RPC requests verifying one pool may take together
+	lpVerificationTimeout = 30 * time.Second
+
+	// Share of the LP that must be burned or locked for the pool to count as safe
+	lpSafeShare = 0.99
+)
+
+// lpIncinerator is the address tokens are sent to when they are burned by transfer
+var lpIncinerator = solana.MustPublicKeyFromBase58("1nc1nerator11111111111111111111111111111111")
+
+// defaultLPLockers are the programs whose accounts hold locked LP when
+// LP_LOCKER_PROGRAMS is unset: Streamflow, Jupiter Lock and the Raydium LP locker
+var defaultLPLockers = []string{
+	"strmRqUCoQUgGUan5YhzUZa6KqdzwX5L6FpUxfmKg5m",
+	"LocpQgucEQHbqNABEYvBvwoxCPsSbG91A1QaQhQQqjn",
+	"LockrWmn6K5twhz3y9w1dQERbmgSaRkfnTeTKbpofwE",
+}
+
+// LPStatus reports what happened to the LP tokens of the pool a token migrated to
+// Shares are of the LP minted at the deposit; only the 20 largest holders are
+// inspected for locks and burns by transfer, as getTokenLargestAccounts returns.
+type LPStatus struct {
+	Pool          string    `json:"pool"`           // PumpSwap pool of the token
+	LPMint        string    `json:"lp_mint"`        // LP token mint of the pool
+	MintedSupply  uint64    `json:"minted_supply"`  // LP minted at the deposit
+	CurrentSupply uint64    `json:"current_supply"` // LP supply left after burns
+	BurnedShare   float64   `json:"burned_share"`   // Share burned, or sent to the incinerator
+	LockedShare   float64   `json:"locked_share"`   // Share held by locker programs
+	Burned        bool      `json:"burned"`         // True when at least 99% was burned
+	Safe          bool      `json:"safe"`           // True when at least 99% was burned or locked
+	VerifiedAt    time.Time `json:"verified_at"`    // Time of the verification
+}
+
+// MigrationEvent represents the migration of a graduated token to its pool,
+// with the verification of its LP
+type MigrationEvent struct {
+	Mint string   `json:"mint"` // Token mint address
+	LP   LPStatus `json:"lp"`   // LP of the pool
+}
+
+// LPVerifier inspects the pools of graduated tokens
+type LPVerifier struct {
+	client  *rpc.Client
+	lockers []solana.PublicKey
+}
+
+// LPVerification verifies the LP of migrations when LP_VERIFICATION is set, nil otherwise
+var LPVerification *LPVerifier
+
+// setupLPVerification verifies the LP of graduated tokens when LP_VERIFICATION is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if a locker program is invalid or no RPC endpoint can be resolved
+func setupLPVerification(rpcURL string) error {
+	if !envBool(lpVerificationEnv) {
+		return nil
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	programs := defaultLPLockers
+	if value := os.Getenv(lpLockerProgramsEnv); value != "" {
+		programs = strings.Split(value, ",")
+	}
+	verifier := &LPVerifier{client: newEnrichmentRPCClient(endpoint)}
+	for _, program := range programs {
+		key, err := solana.PublicKeyFromBase58(strings.TrimSpace(program))
+		if err != nil {
+			return fmt.Errorf("invalid locker program %q in %s", program, lpLockerProgramsEnv)
+		}
+		verifier.lockers = append(verifier.lockers, key)
+	}
+
+	LPVerification = verifier
+	RegisterSink(verifier)
+
+	slog.Info("Verifying the LP of graduated tokens", "locker_programs", len(verifier.lockers))
+	return nil
+}
+
+// Name identifies the sink in logs
+func (v *LPVerifier) Name() string {
+	return "lp-verification"
+}
+
+// Publish starts waiting for the pool of every token whose curve completed
+func (v *LPVerifier) Publish(event Event) {
+	if event.Type != EventComplete {
+		return
+	}
+	mint, err := solana.PublicKeyFromBase58(event.Mint)
+	if err != nil {
+		return
+	}
+	goSafe("lp verification", func() { v.await(mint) })
+}
+
+// await waits for the migration pool of a mint, then verifies its LP and
+// publishes the migration event
+func (v *LPVerifier) await(mint solana.PublicKey) {
+	pool, err := pumpstream.MigrationPoolAddress(pumpProgram(), mint)
+	if err != nil {
+		reportError(errorCategoryDecode, err, logKeyMint, mint.String())
+		return
+	}
+
+	deadline := time.Now().Add(lpPoolWaitTimeout)
+	for {
+		status, slot, err := v.verify(pool)
+		if err == nil {
+			v.publish(mint.String(), status, slot)
+			return
+		}
+		if !errors.Is(err, rpc.ErrNotFound) {
+			slog.Warn("Failed to verify LP", logKeyMint, mint.String(), logKeyError, err)
+			reportError(errorCategoryUpstream, err, logKeyMint, mint.String())
+			return
+		}
+		if time.Now().After(deadline) {
+			slog.Info("Graduated token did not migrate in time", logKeyMint, mint.String(), "pool", pool.String())
+			return
+		}
+		time.Sleep(lpPoolPollInterval)
+	}
+}
+
+// verify inspects the LP of a pool
+//
+// Returns:
+//   - LPStatus: The LP status
+//   - uint64: Slot the status was read at
+//   - error: rpc.ErrNotFound while the pool does not exist, or a request error
+func (v *LPVerifier) verify(address solana.PublicKey) (LPStatus, uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lpVerificationTimeout)
+	defer cancel()
+
+	result, err := v.client.GetAccountInfoWithOpts(ctx, address, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return LPStatus{}, 0, err
+	}
+	if !result.Value.Owner.Equals(pumpstream.SwapProgram) {
+		return LPStatus{}, 0, fmt.Errorf("pool %s is not owned by the swap program", address)
+	}
+	pool, err := pumpstream.DecodePoolAccount(result.Value.Data.GetBinary())
+	if err != nil {
+		return LPStatus{}, 0, err
+	}
+
+	supply, err := v.client.GetTokenSupply(ctx, pool.LPMint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return LPStatus{}, 0, fmt.Errorf("failed to get the LP supply: %w", err)
+	}
+	current, err := strconv.ParseUint(supply.Value.Amount, 10, 64)
+	if err != nil {
+		return LPStatus{}, 0, fmt.Errorf("invalid LP supply %q: %w", supply.Value.Amount, err)
+	}
+
+	status := LPStatus{
+		Pool:          address.String(),
+		LPMint:        pool.LPMint.String(),
+		MintedSupply:  max(pool.LPSupply, current),
+		CurrentSupply: current,
+		VerifiedAt:    time.Now().UTC(),
+	}
+	burned := status.MintedSupply - current
+	var locked uint64
+	if current > 0 {
+		if burned, locked, err = v.inspectHolders(ctx, pool.LPMint, burned); err != nil {
+			return LPStatus{}, 0, err
+		}
+	}
+	if status.MintedSupply > 0 {
+		status.BurnedShare = float64(burned) / float64(status.MintedSupply)
+		status.LockedShare = float64(locked) / float64(status.MintedSupply)
+	}
+	status.Burned = status.BurnedShare >= lpSafeShare
+	status.Safe = status.BurnedShare+status.LockedShare >= lpSafeShare
+	return status, supply.Context.Slot, nil
+}
+
+// inspectHolders adds the LP sent to the incinerator to the burned amount,
+// and sums the LP of the largest holders owned by locker programs
+//
+// Returns:
+//   - uint64: LP burned
+//   - uint64: LP locked
+//   - error: Error if a request failed
+func (v *LPVerifier) inspectHolders(ctx context.Context, lpMint solana.PublicKey, burned uint64) (uint64, uint64, error) {
+	largest, err := v.client.GetTokenLargestAccounts(ctx, lpMint, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get the largest LP holders: %w", err)
+	}
+	if len(largest.Value) == 0 {
+		return burned, 0, nil
+	}
+
+	holders := make([]solana.PublicKey, len(largest.Value))
+	amounts := make([]uint64, len(largest.Value))
+	for i, holder := range largest.Value {
+		holders[i] = holder.Address
+		amounts[i], _ = strconv.ParseUint(holder.Amount, 10, 64)
+	}
+	tokenAccounts, err := v.client.GetMultipleAccountsWithOpts(ctx, holders, &rpc.GetMultipleAccountsOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get the LP token accounts: %w", err)
+	}
+
+	// The owner of a token account is stored after its mint
+	owners := make([]solana.PublicKey, len(holders))
+	for i, account := range tokenAccounts.Value {
+		if account == nil {
+			continue
+		}
+		if data := account.Data.GetBinary(); len(data) >= 2*solana.PublicKeyLength {
+			owners[i] = solana.PublicKeyFromBytes(data[solana.PublicKeyLength : 2*solana.PublicKeyLength])
+		}
+	}
+	ownerAccounts, err := v.client.GetMultipleAccountsWithOpts(ctx, owners, &rpc.GetMultipleAccountsOpts{Commitment: rpc.CommitmentConfirmed})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get the LP holders: %w", err)
+	}
+
+	var locked uint64
+	for i, owner := range owners {
+		switch {
+		case owner.Equals(lpIncinerator):
+			burned += amounts[i]
+		case ownerAccounts.Value[i] != nil && slices.ContainsFunc(v.lockers, ownerAccounts.Value[i].Owner.Equals):
+			locked += amounts[i]
+		}
+	}
+	return burned, locked, nil
+}
+
+// publish records the LP status on the token and publishes the migration event
+func (v *LPVerifier) publish(mint string, status LPStatus, slot uint64) {
+	Tokens.RecordLPStatus(mint, status)
+
+	level := slog.LevelInfo
+	if !status.Safe {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "Verified migration LP", logKeyMint, mint, "pool", status.Pool,
+		"burned_share", status.BurnedShare, "locked_share", status.LockedShare)
+
+	publishEvent(Event{
+		Type:       EventMigration,
+		Mint:       mint,
+		Slot:       slot,
+		ReceivedAt: status.VerifiedAt,
+		Data:       MigrationEvent{Mint: mint, LP: status},
+	})
+}
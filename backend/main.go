@@ -1,83 +1,223 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
 
 // Configuration constants
 const (
-	// Server port to listen on
+	// Default server port to listen on, overridden by LISTEN_ADDR and serve --addr,
+	// which take a comma-separated list of addresses
 	serverPort = ":8080"
 
 	// WebSocket endpoint path
 	websocketEndpoint = "/connect"
+
+	// Environment variable with a Unix domain socket path the server also listens on
+	// No Unix socket is opened when it is unset; the serve --unix-socket flag overrides it
+	unixSocketEnv = "UNIX_SOCKET_PATH"
+
+	// Permissions applied to the Unix socket file
+	unixSocketMode = 0o660
 )
 
 // main is the entry point of the application
-// It starts the Solana event listener in a goroutine and then starts the HTTP server
+// It runs the subcommand selected on the command line, serving the feed by default
 func main() {
-	fmt.Println("Starting Nova Frontend Trial Task...")
-
-	// Start the Solana event listener in background
-	go listenToNewPairs()
-
-	// Start the HTTP server (this will block until server stops)
-	startServer()
+	if err := loadConfigFile(earlyFlagValue(os.Args[1:], configFileFlag, configFileEnv)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if err := applyProfile(earlyFlagValue(os.Args[1:], profileFlag, profileEnv)); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	// Before any request, as the clients read the proxy settings only once
+	if err := setupOutboundProxy(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if _, err := resolveSecretReferences(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", redactSecrets(err.Error()))
+		os.Exit(1)
+	}
+	if err := newRootCommand().Execute(); err != nil {
+		// Errors may quote an endpoint URL, so credentials are removed first
+		fmt.Fprintln(os.Stderr, "Error:", redactSecrets(err.Error()))
+		os.Exit(1)
+	}
 }
 
 // startServer initializes and starts the HTTP server with WebSocket support
-// It sets up routing and handles graceful shutdown
-func startServer() {
+// It sets up routing and blocks until ctx is done, then shuts the server down
+//
+// Parameters:
+//   - ctx: Root context of the command, done once the process is interrupted
+//   - addrs: TCP addresses to listen on, e.g. ":8080" or "127.0.0.1:8080" and "[::1]:8080"
+//   - unixSocket: Optional Unix domain socket path to also listen on
+//   - tlsConfig: TLS configuration of the TCP listeners, nil to serve plain HTTP
+//
+// Returns:
+//   - error: Error if a listener could not be opened
+func startServer(ctx context.Context, addrs []string, unixSocket string, tlsConfig *tls.Config) error {
+	// Browser frontends on other origins may call the REST routes
+	corsPolicy, err := loadCORSPolicy()
+	if err != nil {
+		return err
+	}
+	if err := loadWebSocketOrigins(); err != nil {
+		return err
+	}
+
 	// Create a new router with strict slash handling
 	handler := mux.NewRouter().StrictSlash(true)
 
 	// Register the WebSocket handler
 	handler.HandleFunc(websocketEndpoint, HandleWebSocket)
 
-	// Create HTTP server configuration
+	// gRPC-Web calls go to the gRPC server, whatever their path
+	if GRPCFeed != nil && GRPCFeed.web {
+		handler.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool { return grpcWebRequest(r) }).HandlerFunc(GRPCFeed.ServeGRPCWeb)
+	}
+
+	// Register the REST API handlers
+	registerAPIRoutes(handler)
+	if !adminRoutesPrivate {
+		registerAdminRoutes(handler)
+	}
+	registerFeedRoutes(handler)
+	registerClusterRoutes(handler)
+	registerHeliusWebhookRoutes(handler)
+	registerHealthRoutes(handler)
+	registerVersionRoutes(handler)
+
+	// Serve the embedded web UI for everything else
+	registerUIRoutes(handler)
+
+	// Create HTTP server configuration; requests that match no route are logged too
 	server := &http.Server{
-		Addr:    serverPort,
-		Handler: handler,
+		Handler:   accessLog(recoverPanics(cors(corsPolicy, handler))),
+		TLSConfig: tlsConfig,
 	}
 
-	fmt.Printf("Server starting on port %s\n", serverPort)
-	fmt.Printf("WebSocket endpoint available at %s%s\n", serverPort, websocketEndpoint)
+	// Open every listener first so a taken port fails the start
+	var listeners []net.Listener
+	for _, addr := range addrs {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			for _, opened := range listeners {
+				opened.Close()
+			}
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		listeners = append(listeners, listener)
+	}
 
-	// Start the server in a goroutine to allow for graceful shutdown
-	go func() {
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Printf("Server error: %v\n", err)
+	for _, listener := range listeners {
+		addr := listener.Addr().String()
+		slog.Info("Server starting", "addr", addr, "websocket_endpoint", websocketEndpoint)
+
+		// Start the server in a goroutine to allow for graceful shutdown
+		go func() {
+			var err error
+			if tlsConfig != nil {
+				// The certificate comes from the TLS configuration
+				err = server.ServeTLS(listener, "", "")
+			} else {
+				err = server.Serve(listener)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Server failed", "addr", addr, logKeyError, err)
+			}
+		}()
+	}
+	if tlsConfig != nil {
+		slog.Info("Serving TLS on the TCP listener")
+	}
+
+	// Additionally serve on a Unix domain socket for local sidecars
+	if unixSocket != "" {
+		if err := serveUnixSocket(server, unixSocket); err != nil {
+			shutdownServer(server)
+			return fmt.Errorf("failed to listen on Unix socket: %w", err)
 		}
-	}()
+	}
 
 	// Wait for interrupt signal to gracefully shutdown the server
-	waitForShutdown(server)
+	<-ctx.Done()
+	shutdownServer(server)
+	return nil
 }
 
-// waitForShutdown waits for OS signals and gracefully shuts down the server
-func waitForShutdown(server *http.Server) {
-	// Create a channel to listen for interrupt signals
-	sigChan := make(chan os.Signal, 1)
-
-	// Register signals to listen for
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// parseListenAddrs splits a comma-separated list of TCP listen addresses,
+// e.g. "127.0.0.1:8080,[::1]:8080"; a port alone listens on every interface
+//
+// Returns:
+//   - []string: The addresses, at least one
+//   - error: Error if an address has no port or an IPv6 host is not bracketed
+func parseListenAddrs(value string) ([]string, error) {
+	var addrs []string
+	for _, addr := range strings.Split(value, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid listen address %q: %w", addr, err)
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no listen address given")
+	}
+	return addrs, nil
+}
 
-	// Wait for signal
-	sig := <-sigChan
-	fmt.Printf("\nReceived signal %v, shutting down gracefully...\n", sig)
+// serveUnixSocket serves the server's handler on a Unix domain socket at path
+// A stale socket file left by a previous run is removed first; the listener
+// removes the file again when the server shuts down
+//
+// Parameters:
+//   - server: HTTP server whose handler and lifecycle the socket shares
+//   - path: Filesystem path of the socket
+//
+// Returns:
+//   - error: Error if the socket could not be created
+func serveUnixSocket(server *http.Server, path string) error {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
 
-	// Attempt graceful shutdown
-	if err := server.Shutdown(nil); err != nil {
-		log.Printf("Error during server shutdown: %v\n", err)
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
 	}
 
-	fmt.Println("Server stopped")
+	slog.Info("Server also listening on Unix socket", "path", path)
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Unix socket server failed", logKeyError, err)
+		}
+	}()
+	return nil
 }
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the market data provider enriching events,
+	// dexscreener or birdeye; enrichment is disabled when it is unset
+	marketDataProviderEnv = "MARKET_DATA_PROVIDER"
+
+	// Environment variable overriding the API endpoint of the provider
+	marketDataURLEnv = "MARKET_DATA_URL"
+
+	// Environment variable with how long market data is reused before it is
+	// fetched again, e.g. 1m
+	marketDataTTLEnv = "MARKET_DATA_TTL"
+
+	// Environment variable with the Birdeye API key, required by the birdeye provider
+	birdeyeAPIKeyEnv = "BIRDEYE_API_KEY"
+
+	// TTL used when MARKET_DATA_TTL is unset
+	defaultMarketDataTTL = time.Minute
+
+	// Longest a provider request may take
+	marketDataRequestTimeout = 10 * time.Second
+
+	// DexScreener tokens endpoint, followed by up to 30 comma-separated mints
+	dexScreenerTokensURL = "https://api.dexscreener.com/tokens/v1/solana/"
+
+	// Largest number of mints per DexScreener request
+	dexScreenerBatchSize = 30
+
+	// Spacing of DexScreener requests, within its limit of 300 per minute
+	dexScreenerRequestInterval = 200 * time.Millisecond
+
+	// Birdeye token overview endpoint, taking a single mint
+	birdeyeTokenOverviewURL = "https://public-api.birdeye.so/defi/token_overview"
+
+	// Birdeye page of a token, linked as its pair URL
+	birdeyeTokenPageURL = "https://birdeye.so/token/%s?chain=solana"
+
+	// Spacing of Birdeye requests, within the limit of the standard tier
+	birdeyeRequestInterval = time.Second
+)
+
+// MarketData represents what an external market data provider knows about the
+// trading of a token
+type MarketData struct {
+	Source       string    `json:"source"`             // Provider the data comes from
+	PairURL      string    `json:"pair_url,omitempty"` // Page of the most liquid pair of the token
+	LiquidityUSD float64   `json:"liquidity_usd"`      // Liquidity across the pairs of the token, in USD
+	Volume24hUSD float64   `json:"volume_24h_usd"`     // Volume of the last 24 hours, in USD
+	FetchedAt    time.Time `json:"fetched_at"`         // Time the data was fetched
+}
+
+// MarketDataProvider fetches market data of tokens from an external service
+type MarketDataProvider interface {
+	// Name identifies the provider in logs and in MarketData.Source
+	Name() string
+
+	// BatchSize is the largest number of mints fetched by one request
+	BatchSize() int
+
+	// RequestInterval is the minimum time between two requests
+	RequestInterval() time.Duration
+
+	// Fetch returns the market data of the listed mints; mints the provider
+	// does not list are left out
+	Fetch(ctx context.Context, mints []string) (map[string]MarketData, error)
+}
+
+// marketDataProviders creates the providers selectable with
+// MARKET_DATA_PROVIDER, by name, from the endpoint given by MARKET_DATA_URL
+var marketDataProviders = map[string]func(endpoint string) (MarketDataProvider, error){
+	"dexscreener": newDexScreenerProvider,
+	"birdeye":     newBirdeyeProvider,
+}
+
+// MarketDataEnricher adds the market data of their token to events, in the
+// enrich stage of the pipeline, from a lookup cache so events never wait for
+// the provider: the first events of a token go out without it
+type MarketDataEnricher struct {
+	provider MarketDataProvider
+	cache    *lookupCache[MarketData]
+}
+
+// MarketDataEnrichment enriches events when MARKET_DATA_PROVIDER is set, nil otherwise
+var MarketDataEnrichment *MarketDataEnricher
+
+// setupMarketData enriches events with market data when MARKET_DATA_PROVIDER is set
+func setupMarketData() error {
+	name := os.Getenv(marketDataProviderEnv)
+	if name == "" {
+		return nil
+	}
+	newProvider, found := marketDataProviders[name]
+	if !found {
+		return fmt.Errorf("unknown %s %q, expected one of %s", marketDataProviderEnv, name, strings.Join(slices.Sorted(maps.Keys(marketDataProviders)), ", "))
+	}
+	provider, err := newProvider(os.Getenv(marketDataURLEnv))
+	if err != nil {
+		return err
+	}
+
+	ttl := defaultMarketDataTTL
+	if value := os.Getenv(marketDataTTLEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", marketDataTTLEnv, value)
+		}
+		ttl = parsed
+	}
+
+	MarketDataEnrichment = &MarketDataEnricher{
+		provider: provider,
+		cache:    newLookupCache(provider.Name()+" market data", ttl, provider.BatchSize(), provider.RequestInterval(), provider.Fetch),
+	}
+	Pipeline.Register(StageEnrich, "market data", MarketDataEnrichment.enrich)
+
+	slog.Info("Enriching events with market data", "provider", provider.Name(), "ttl", ttl)
+	return nil
+}
+
+// enrich adds the cached market data of the token to the event
+func (m *MarketDataEnricher) enrich(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		if mint := pipelineEventMint(event); mint != "" && Features.Enabled(featureMarketData) {
+			event.Market = m.cache.Lookup(mint)
+		}
+		return next(ctx, event)
+	}
+}
+
+// marketDataClient sends the requests of every provider
+var marketDataClient = &http.Client{Timeout: marketDataRequestTimeout}
+
+// getMarketJSON sends a GET request and decodes the JSON response
+func getMarketJSON(ctx context.Context, endpoint string, header http.Header, response any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	resp, err := marketDataClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// dexScreenerProvider fetches market data from the DexScreener API, which
+// needs no key and quotes many mints per request
+type dexScreenerProvider struct {
+	endpoint string
+}
+
+// dexScreenerPair is a pair of a DexScreener tokens response
+type dexScreenerPair struct {
+	URL       string `json:"url"` // Page of the pair
+	BaseToken struct {
+		Address string `json:"address"` // Mint of the token
+	} `json:"baseToken"`
+	Liquidity struct {
+		USD float64 `json:"usd"`
+	} `json:"liquidity"`
+	Volume struct {
+		H24 float64 `json:"h24"`
+	} `json:"volume"`
+}
+
+// newDexScreenerProvider creates the DexScreener provider
+func newDexScreenerProvider(endpoint string) (MarketDataProvider, error) {
+	if endpoint == "" {
+		endpoint = dexScreenerTokensURL
+	}
+	return &dexScreenerProvider{endpoint: strings.TrimSuffix(endpoint, "/") + "/"}, nil
+}
+
+// Name identifies the provider
+func (d *dexScreenerProvider) Name() string {
+	return "dexscreener"
+}
+
+// BatchSize is the largest number of mints per request
+func (d *dexScreenerProvider) BatchSize() int {
+	return dexScreenerBatchSize
+}
+
+// RequestInterval is the minimum time between two requests
+func (d *dexScreenerProvider) RequestInterval() time.Duration {
+	return dexScreenerRequestInterval
+}
+
+// Fetch returns the market data of the mints with at least one pair: the
+// liquidity and volume of every pair of a token add up, and the pair URL is
+// the one of its most liquid pair
+func (d *dexScreenerProvider) Fetch(ctx context.Context, mints []string) (map[string]MarketData, error) {
+	var pairs []dexScreenerPair
+	if err := getMarketJSON(ctx, d.endpoint+strings.Join(mints, ","), nil, &pairs); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	listed := make(map[string]MarketData, len(mints))
+	deepest := make(map[string]float64, len(mints))
+	for _, pair := range pairs {
+		mint := pair.BaseToken.Address
+		data, found := listed[mint]
+		if !found || pair.Liquidity.USD > deepest[mint] {
+			data.PairURL = pair.URL
+			deepest[mint] = pair.Liquidity.USD
+		}
+		data.Source = d.Name()
+		data.LiquidityUSD += pair.Liquidity.USD
+		data.Volume24hUSD += pair.Volume.H24
+		data.FetchedAt = now
+		listed[mint] = data
+	}
+	return listed, nil
+}
+
+// birdeyeProvider fetches market data from the Birdeye API, one mint per request
+type birdeyeProvider struct {
+	endpoint string
+	apiKey   string
+}
+
+// birdeyeTokenOverview is the response of the Birdeye token overview endpoint
+type birdeyeTokenOverview struct {
+	Success bool `json:"success"`
+	Data    *struct {
+		Liquidity float64 `json:"liquidity"` // Liquidity in USD
+		Volume24h float64 `json:"v24hUSD"`   // Volume of the last 24 hours in USD
+	} `json:"data"`
+}
+
+// newBirdeyeProvider creates the Birdeye provider from BIRDEYE_API_KEY
+func newBirdeyeProvider(endpoint string) (MarketDataProvider, error) {
+	apiKey := os.Getenv(birdeyeAPIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s must be set to use the birdeye market data provider", birdeyeAPIKeyEnv)
+	}
+	registerSecret(apiKey)
+
+	if endpoint == "" {
+		endpoint = birdeyeTokenOverviewURL
+	}
+	return &birdeyeProvider{endpoint: endpoint, apiKey: apiKey}, nil
+}
+
+// Name identifies the provider
+func (b *birdeyeProvider) Name() string {
+	return "birdeye"
+}
+
+// BatchSize is the largest number of mints per request
+func (b *birdeyeProvider) BatchSize() int {
+	return 1
+}
+
+// RequestInterval is the minimum time between two requests
+func (b *birdeyeProvider) RequestInterval() time.Duration {
+	return birdeyeRequestInterval
+}
+
+// Fetch returns the market data of the mint, if Birdeye lists it
+func (b *birdeyeProvider) Fetch(ctx context.Context, mints []string) (map[string]MarketData, error) {
+	listed := make(map[string]MarketData, len(mints))
+	header := http.Header{"X-Api-Key": {b.apiKey}, "X-Chain": {"solana"}}
+	for _, mint := range mints {
+		var overview birdeyeTokenOverview
+		if err := getMarketJSON(ctx, b.endpoint+"?"+url.Values{"address": {mint}}.Encode(), header, &overview); err != nil {
+			return nil, err
+		}
+		if !overview.Success || overview.Data == nil {
+			continue
+		}
+		listed[mint] = MarketData{
+			Source:       b.Name(),
+			PairURL:      fmt.Sprintf(birdeyeTokenPageURL, mint),
+			LiquidityUSD: overview.Data.Liquidity,
+			Volume24hUSD: overview.Data.Volume24h,
+			FetchedAt:    time.Now().UTC(),
+		}
+	}
+	return listed, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// applyMigrations applies the SQL migration files in dir that have not been
+// applied to db yet, each in its own transaction
+//
+// Files are named <version>_<description>.sql and applied in version order;
+// applied versions are recorded in the schema_migrations table
+//
+// Parameters:
+//   - db: Database to migrate
+//   - files: File system holding the migrations
+//   - dir: Directory of the migrations within files
+//
+// Returns:
+//   - int: Number of migrations applied
+//   - error: Error if a migration failed; earlier migrations stay applied
+func applyMigrations(db *sql.DB, files fs.FS, dir string) (int, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied := map[int]bool{}
+	rows, err := db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	type migration struct {
+		version int
+		name    string
+	}
+	var pending []migration
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if entry.IsDir() || !found || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return 0, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		if !applied[version] {
+			pending = append(pending, migration{version, entry.Name()})
+		}
+	}
+	slices.SortFunc(pending, func(a, b migration) int { return a.version - b.version })
+
+	for _, m := range pending {
+		script, err := fs.ReadFile(files, path.Join(dir, m.name))
+		if err != nil {
+			return 0, err
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(string(script)); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+		// The version is an integer parsed above, so formatting it avoids
+		// depending on the placeholder syntax of the driver
+		if _, err := tx.Exec(fmt.Sprintf(`INSERT INTO schema_migrations (version) VALUES (%d)`, m.version)); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to record migration %s: %w", m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(pending), nil
+}
@@ -0,0 +1,15 @@
+-- Connection audit log, one row per WebSocket connection once it has ended
+CREATE TABLE connections (
+    id              BIGSERIAL PRIMARY KEY,
+    client_id       TEXT    NOT NULL, -- Remote address of the connection
+    ip              TEXT    NOT NULL, -- IP address of the client
+    user_agent      TEXT    NOT NULL, -- User-Agent of the upgrade request
+    connected_at    BIGINT  NOT NULL, -- Unix milliseconds the connection was upgraded
+    disconnected_at BIGINT  NOT NULL, -- Unix milliseconds the connection ended
+    messages_sent   BIGINT  NOT NULL, -- Messages written to the client
+    bytes_sent      BIGINT  NOT NULL, -- Message bytes written to the client
+    reason          TEXT    NOT NULL  -- Why the connection ended
+);
+
+CREATE INDEX connections_disconnected_at ON connections (disconnected_at);
+CREATE INDEX connections_ip ON connections (ip, disconnected_at);
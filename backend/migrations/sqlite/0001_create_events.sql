@@ -0,0 +1,26 @@
+-- Every decoded event, in the order it was observed
+CREATE TABLE events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    type        TEXT    NOT NULL, -- create, trade or complete
+    mint        TEXT    NOT NULL, -- Token mint address
+    signature   TEXT    NOT NULL, -- Transaction signature
+    slot        INTEGER NOT NULL, -- Slot the transaction was observed in
+    received_at INTEGER NOT NULL, -- Unix milliseconds the notification was received
+    data        TEXT    NOT NULL  -- JSON encoded CreateEvent, TradeEvent or CompleteEvent
+);
+
+CREATE INDEX events_mint ON events (mint, id);
+CREATE INDEX events_received_at ON events (received_at);
+CREATE INDEX events_slot ON events (slot);
+
+-- Latest known state of every token, used to restore the in-memory store and
+-- to answer lookups for tokens that were evicted from it
+CREATE TABLE tokens (
+    mint       TEXT    PRIMARY KEY, -- Token mint address
+    created_at INTEGER NOT NULL,    -- Unix milliseconds the creation was observed
+    creator    TEXT    NOT NULL,    -- Creator wallet
+    record     TEXT    NOT NULL     -- JSON encoded TokenRecord
+);
+
+CREATE INDEX tokens_created_at ON tokens (created_at);
+CREATE INDEX tokens_creator ON tokens (creator);
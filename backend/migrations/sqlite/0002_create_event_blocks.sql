@@ -0,0 +1,17 @@
+-- Older events compressed in blocks of consecutive rows, moved out of events
+-- once they are older than STORAGE_COMPRESS_AFTER
+CREATE TABLE event_blocks (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    first_id        INTEGER NOT NULL, -- Id of the first event in the block
+    last_id         INTEGER NOT NULL, -- Id of the last event in the block
+    min_received_at INTEGER NOT NULL, -- Unix milliseconds the oldest event was received
+    max_received_at INTEGER NOT NULL, -- Unix milliseconds the newest event was received
+    min_slot        INTEGER NOT NULL, -- Lowest slot of the block
+    max_slot        INTEGER NOT NULL, -- Highest slot of the block
+    count           INTEGER NOT NULL, -- Number of events in the block
+    data            BLOB    NOT NULL  -- zstd compressed NDJSON of the events and their columns
+);
+
+CREATE INDEX event_blocks_first_id ON event_blocks (first_id);
+CREATE INDEX event_blocks_last_id ON event_blocks (last_id);
+CREATE INDEX event_blocks_received_at ON event_blocks (max_received_at);
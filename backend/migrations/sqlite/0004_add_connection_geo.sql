@@ -0,0 +1,4 @@
+-- Location of the client IP address, empty when GeoIP is not configured
+ALTER TABLE connections ADD COLUMN country TEXT    NOT NULL DEFAULT ''; -- ISO 3166-1 alpha-2 country code
+ALTER TABLE connections ADD COLUMN asn     INTEGER NOT NULL DEFAULT 0;  -- Autonomous system number
+ALTER TABLE connections ADD COLUMN as_org  TEXT    NOT NULL DEFAULT ''; -- Organization owning the autonomous system
@@ -0,0 +1,2 @@
+-- Tenant of the client certificate, empty without mutual TLS
+ALTER TABLE connections ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
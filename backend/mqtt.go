@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the MQTT broker URL, e.g. tcp://localhost:1883
+	// The MQTT sink is disabled when it is unset
+	mqttBrokerEnv = "MQTT_BROKER"
+
+	// Environment variables with optional broker credentials
+	mqttUsernameEnv = "MQTT_USERNAME"
+	mqttPasswordEnv = "MQTT_PASSWORD"
+
+	// Environment variable overriding the topic prefix; topics are <prefix><event type>
+	mqttTopicPrefixEnv = "MQTT_TOPIC_PREFIX"
+
+	// Environment variable overriding the client ID presented to the broker
+	mqttClientIDEnv = "MQTT_CLIENT_ID"
+
+	// Environment variable selecting the publish QoS level (0, 1 or 2)
+	mqttQoSEnv = "MQTT_QOS"
+
+	// Defaults used when the variables above are unset
+	defaultMQTTTopicPrefix = "solana/pumpfun/"
+	defaultMQTTClientID    = "nova-feed"
+
+	// Number of events buffered before new events are dropped
+	mqttQueueSize = 10000
+
+	// Timeout waiting for the broker to accept a publish
+	mqttPublishTimeout = 5 * time.Second
+)
+
+// MQTTSink publishes every event to an MQTT topic named after its event type
+type MQTTSink struct {
+	client      mqtt.Client
+	topicPrefix string
+	qos         byte
+	queue       chan Event
+}
+
+// setupMQTTSink registers the MQTT sink when MQTT_BROKER is set
+func setupMQTTSink() error {
+	broker := os.Getenv(mqttBrokerEnv)
+	if broker == "" {
+		return nil
+	}
+
+	qos, err := strconv.Atoi(envOrDefault(mqttQoSEnv, "0"))
+	if err != nil || qos < 0 || qos > 2 {
+		return fmt.Errorf("%s must be 0, 1 or 2", mqttQoSEnv)
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(envOrDefault(mqttClientIDEnv, defaultMQTTClientID)).
+		SetUsername(os.Getenv(mqttUsernameEnv)).
+		SetPassword(os.Getenv(mqttPasswordEnv)).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			slog.Warn("MQTT connection lost", logKeyError, err)
+		})
+
+	client := mqtt.NewClient(options)
+	if token := client.Connect(); token.WaitTimeout(mqttPublishTimeout) && token.Error() != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", token.Error())
+	}
+
+	sink := &MQTTSink{
+		client:      client,
+		topicPrefix: envOrDefault(mqttTopicPrefixEnv, defaultMQTTTopicPrefix),
+		qos:         byte(qos),
+		queue:       make(chan Event, mqttQueueSize),
+	}
+	go sink.publishLoop()
+	RegisterSink(sink)
+
+	slog.Info("Publishing events to MQTT", "broker", broker, "topic_prefix", sink.topicPrefix)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (m *MQTTSink) Name() string {
+	return "mqtt"
+}
+
+// Publish queues the event for the publisher, dropping it if the queue is full
+func (m *MQTTSink) Publish(event Event) {
+	select {
+	case m.queue <- event:
+	default:
+		slog.Warn("MQTT queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// publishLoop publishes queued events in order
+func (m *MQTTSink) publishLoop() {
+	for event := range m.queue {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("Failed to marshal event for MQTT", logKeyError, err)
+			continue
+		}
+
+		token := m.client.Publish(m.topicPrefix+string(event.Type), m.qos, false, payload)
+		if !token.WaitTimeout(mqttPublishTimeout) {
+			slog.Warn("Timed out publishing event to MQTT", logKeyEventType, event.Type, logKeyMint, event.Mint)
+		} else if token.Error() != nil {
+			slog.Warn("Failed to publish event to MQTT", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, token.Error())
+		}
+	}
+}
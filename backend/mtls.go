@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// Configuration constants
+const (
+	// Environment variable with a PEM bundle of the CAs client certificates
+	// must chain to; client certificates are not requested when it is unset
+	tlsClientCAFileEnv = "TLS_CLIENT_CA_FILE"
+
+	// Environment variable selecting whether clients must present a
+	// certificate: require (the default) or optional, which still verifies
+	// the certificates that are presented
+	tlsClientAuthEnv = "TLS_CLIENT_AUTH"
+
+	// Environment variable with a JSON file mapping certificate identities to
+	// tenants, e.g. {"spiffe://corp/ns/trading/sa/bot": "trading"}
+	// An identity is a URI, DNS or email SAN, the subject common name, or the
+	// SHA-256 fingerprint of the certificate in hex; certificates matching no
+	// entry are rejected
+	// Without it the tenant is the subject common name
+	tlsClientTenantsFileEnv = "TLS_CLIENT_TENANTS_FILE"
+
+	// Client authentication modes
+	tlsClientAuthRequire  = "require"
+	tlsClientAuthOptional = "optional"
+)
+
+// clientTenants maps certificate identities to tenants, nil when every
+// verified certificate is accepted
+var clientTenants map[string]string
+
+// setupClientAuth makes the TLS listener verify client certificates against
+// TLS_CLIENT_CA_FILE
+//
+// Parameters:
+//   - config: TLS configuration of the listener, modified in place
+//
+// Returns:
+//   - error: Error if the CA bundle or the tenants file cannot be loaded
+func setupClientAuth(config *tls.Config) error {
+	path := os.Getenv(tlsClientCAFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return fmt.Errorf("no certificates found in client CA bundle %s", path)
+	}
+	config.ClientCAs = pool
+
+	switch mode := envOrDefault(tlsClientAuthEnv, tlsClientAuthRequire); mode {
+	case tlsClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	case tlsClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return fmt.Errorf("invalid %s %q, expected %s or %s", tlsClientAuthEnv, mode, tlsClientAuthRequire, tlsClientAuthOptional)
+	}
+
+	if tenantsPath := os.Getenv(tlsClientTenantsFileEnv); tenantsPath != "" {
+		data, err := os.ReadFile(tenantsPath)
+		if err != nil {
+			return fmt.Errorf("failed to read client tenants file: %w", err)
+		}
+		if err := json.Unmarshal(data, &clientTenants); err != nil {
+			return fmt.Errorf("failed to parse client tenants file: %w", err)
+		}
+		config.VerifyConnection = verifyClientTenant
+	}
+
+	slog.Info("Verifying client certificates", "ca", path, "client_auth", config.ClientAuth.String())
+	return nil
+}
+
+// verifyClientTenant rejects handshakes whose client certificate maps to no tenant
+func verifyClientTenant(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return nil // Only possible when certificates are optional
+	}
+	if _, ok := certificateTenant(state.PeerCertificates[0]); !ok {
+		return errors.New("client certificate maps to no tenant")
+	}
+	return nil
+}
+
+// certificateTenant returns the tenant a client certificate belongs to
+//
+// Returns:
+//   - string: The tenant
+//   - bool: False if the certificate has no identity known to the tenants file
+func certificateTenant(certificate *x509.Certificate) (string, bool) {
+	if clientTenants == nil {
+		return certificate.Subject.CommonName, true
+	}
+
+	fingerprint := sha256.Sum256(certificate.Raw)
+	identities := []string{hex.EncodeToString(fingerprint[:])}
+	for _, uri := range certificate.URIs {
+		identities = append(identities, uri.String())
+	}
+	identities = append(identities, certificate.DNSNames...)
+	identities = append(identities, certificate.EmailAddresses...)
+	identities = append(identities, certificate.Subject.CommonName)
+
+	for _, identity := range identities {
+		if tenant, ok := clientTenants[identity]; ok && identity != "" {
+			return tenant, true
+		}
+	}
+	return "", false
+}
+
+// requestTenant returns the tenant of the client certificate a request was made with
+// It is empty for plain HTTP and for clients that presented no certificate
+func requestTenant(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	tenant, _ := certificateTenant(r.TLS.PeerCertificates[0])
+	return tenant
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// testClientTenants installs the tenants of TLS_CLIENT_TENANTS_FILE for the
+// duration of a test, nil accepting every verified certificate
+func testClientTenants(t *testing.T, tenants map[string]string) {
+	previous := clientTenants
+	t.Cleanup(func() { clientTenants = previous })
+	clientTenants = tenants
+}
+
+// TestCertificateTenant checks which identity of a client certificate picks
+// its tenant, the fingerprint first and the common name last
+func TestCertificateTenant(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://corp/ns/trading/sa/bot")
+	certificate := &x509.Certificate{
+		Raw:            []byte("certificate"),
+		Subject:        pkix.Name{CommonName: "bot"},
+		URIs:           []*url.URL{spiffe},
+		DNSNames:       []string{"bot.corp.internal"},
+		EmailAddresses: []string{"bot@corp.example"},
+	}
+	sum := sha256.Sum256(certificate.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		tenants     map[string]string
+		certificate *x509.Certificate
+		expected    string
+		ok          bool
+	}{
+		{name: "no tenants file", certificate: certificate, expected: "bot", ok: true},
+		{name: "no tenants file or common name", certificate: &x509.Certificate{}, expected: "", ok: true},
+		{name: "fingerprint", tenants: map[string]string{fingerprint: "pinned"}, certificate: certificate, expected: "pinned", ok: true},
+		{name: "URI", tenants: map[string]string{spiffe.String(): "trading"}, certificate: certificate, expected: "trading", ok: true},
+		{name: "DNS name", tenants: map[string]string{"bot.corp.internal": "dns"}, certificate: certificate, expected: "dns", ok: true},
+		{name: "email", tenants: map[string]string{"bot@corp.example": "email"}, certificate: certificate, expected: "email", ok: true},
+		{name: "common name", tenants: map[string]string{"bot": "common"}, certificate: certificate, expected: "common", ok: true},
+		{
+			name:        "fingerprint before the other identities",
+			tenants:     map[string]string{"bot": "common", spiffe.String(): "trading", fingerprint: "pinned"},
+			certificate: certificate,
+			expected:    "pinned",
+			ok:          true,
+		},
+		{
+			name:        "URI before the common name",
+			tenants:     map[string]string{"bot": "common", "bot.corp.internal": "dns", spiffe.String(): "trading"},
+			certificate: certificate,
+			expected:    "trading",
+			ok:          true,
+		},
+		{name: "no match", tenants: map[string]string{"other": "other"}, certificate: certificate},
+		{name: "empty identity", tenants: map[string]string{"": "anyone"}, certificate: &x509.Certificate{Raw: []byte("other")}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testClientTenants(t, test.tenants)
+			tenant, ok := certificateTenant(test.certificate)
+			if tenant != test.expected || ok != test.ok {
+				t.Fatalf("got %q (%v), expected %q (%v)", tenant, ok, test.expected, test.ok)
+			}
+		})
+	}
+}
+
+// TestVerifyClientTenant checks that handshakes are refused only for a
+// certificate mapping to no tenant, and that requests carry their tenant
+func TestVerifyClientTenant(t *testing.T) {
+	testClientTenants(t, map[string]string{"bot": "trading"})
+	known := &x509.Certificate{Raw: []byte("known"), Subject: pkix.Name{CommonName: "bot"}}
+	unknown := &x509.Certificate{Raw: []byte("unknown"), Subject: pkix.Name{CommonName: "stranger"}}
+
+	tests := []struct {
+		name         string
+		certificates []*x509.Certificate
+		refused      bool
+		tenant       string
+	}{
+		{name: "known certificate", certificates: []*x509.Certificate{known}, tenant: "trading"},
+		{name: "unknown certificate", certificates: []*x509.Certificate{unknown}, refused: true},
+		{name: "unknown intermediate", certificates: []*x509.Certificate{known, unknown}, tenant: "trading"},
+		{name: "no certificate"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			state := tls.ConnectionState{PeerCertificates: test.certificates}
+			if err := verifyClientTenant(state); (err != nil) != test.refused {
+				t.Fatalf("got %v, expected refused %v", err, test.refused)
+			}
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.TLS = &state
+			if tenant := requestTenant(request); tenant != test.tenant {
+				t.Fatalf("got tenant %q, expected %q", tenant, test.tenant)
+			}
+		})
+	}
+
+	if tenant := requestTenant(httptest.NewRequest(http.MethodGet, "/", nil)); tenant != "" {
+		t.Fatalf("got tenant %q for plain HTTP, expected none", tenant)
+	}
+}
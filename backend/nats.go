@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the NATS server URL
+	// The NATS sink is disabled when it is unset
+	natsURLEnv = "NATS_URL"
+
+	// Environment variable overriding the JetStream stream name
+	natsStreamEnv = "NATS_STREAM"
+
+	// Environment variable overriding the subject prefix; subjects are <prefix><event type>
+	natsSubjectPrefixEnv = "NATS_SUBJECT_PREFIX"
+
+	// Defaults used when the variables above are unset
+	defaultNATSStream        = "PUMPFUN"
+	defaultNATSSubjectPrefix = "pumpfun."
+
+	// Number of events buffered before new events are dropped
+	natsQueueSize = 10000
+
+	// Maximum publish attempts per event, including the first one
+	natsMaxAttempts = 5
+
+	// Delay between publish attempts
+	natsRetryDelay = 500 * time.Millisecond
+
+	// Timeout waiting for the JetStream acknowledgement of a single publish
+	natsAckTimeout = 5 * time.Second
+)
+
+// NATSSink publishes every event to a JetStream subject named after its event type
+// Each event is published until JetStream acknowledges it; a message ID derived
+// from the event lets the server discard duplicates produced by retries
+type NATSSink struct {
+	stream        jetstream.JetStream
+	subjectPrefix string
+	queue         chan Event
+}
+
+// setupNATSSink registers the NATS JetStream sink when NATS_URL is set
+func setupNATSSink() error {
+	url := os.Getenv(natsURLEnv)
+	if url == "" {
+		return nil
+	}
+
+	sink, err := NewNATSSink(url, envOrDefault(natsStreamEnv, defaultNATSStream), envOrDefault(natsSubjectPrefixEnv, defaultNATSSubjectPrefix))
+	if err != nil {
+		return err
+	}
+	RegisterSink(sink)
+
+	slog.Info("Publishing events to NATS JetStream", "url", url)
+	return nil
+}
+
+// NewNATSSink connects to NATS, ensures the stream exists and starts the publisher
+//
+// Parameters:
+//   - url: NATS server URL
+//   - streamName: JetStream stream capturing the published subjects
+//   - subjectPrefix: prefix prepended to the event type to form the subject
+func NewNATSSink(url, streamName, subjectPrefix string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	stream, err := jetstream.New(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsAckTimeout)
+	defer cancel()
+
+	_, err = stream.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     streamName,
+		Subjects: []string{subjectPrefix + ">"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream stream %s: %w", streamName, err)
+	}
+
+	sink := &NATSSink{
+		stream:        stream,
+		subjectPrefix: subjectPrefix,
+		queue:         make(chan Event, natsQueueSize),
+	}
+	go sink.publishLoop()
+
+	return sink, nil
+}
+
+// Name identifies the sink in logs
+func (n *NATSSink) Name() string {
+	return "nats"
+}
+
+// Publish queues the event for the publisher, dropping it if the queue is full
+func (n *NATSSink) Publish(event Event) {
+	select {
+	case n.queue <- event:
+	default:
+		slog.Warn("NATS queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// publishLoop publishes queued events in order, retrying until acknowledged
+func (n *NATSSink) publishLoop() {
+	for event := range n.queue {
+		data, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("Failed to marshal event for NATS", logKeyError, err)
+			continue
+		}
+
+		subject := n.subjectPrefix + string(event.Type)
+		messageID := fmt.Sprintf("%s:%s:%s", event.Signature, event.Type, event.Mint)
+
+		for attempt := 1; attempt <= natsMaxAttempts; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), natsAckTimeout)
+			_, err = n.stream.Publish(ctx, subject, data, jetstream.WithMsgID(messageID))
+			cancel()
+
+			if err == nil {
+				break
+			}
+			time.Sleep(natsRetryDelay)
+		}
+
+		if err != nil {
+			slog.Warn("Failed to publish event to NATS", logKeyEventType, event.Type, logKeyMint, event.Mint, "attempts", natsMaxAttempts, logKeyError, err)
+		}
+	}
+}
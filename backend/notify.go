@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Number of tracked launches by the same creator that flags them as a serial creator
+	serialCreatorThreshold = 3
+
+	// Link templates for token pages
+	pumpFunCoinURL  = "https://pump.fun/coin/%s"
+	solscanTokenURL = "https://solscan.io/token/%s"
+
+	// Timeout for fetching token metadata to decorate notifications
+	metadataFetchTimeout = 3 * time.Second
+
+	// Largest metadata document decoded; the documents are a few hundred bytes
+	maxMetadataBytes = 1 << 20
+)
+
+// Risk flags attached to notifications
+const (
+	riskSerialCreator   = "serial_creator"    // Creator launched several tokens recently
+	riskNoMetadata      = "no_metadata"       // Token has no metadata URI
+	riskMetadataOffIPFS = "metadata_off_ipfs" // Metadata is hosted somewhere mutable
+	riskLPNotBurned     = "lp_not_burned"     // Pool LP of a graduated token was mostly neither burned nor locked
+)
+
+// NotifyFilter selects the events forwarded to a notification target
+type NotifyFilter struct {
+	EventTypes []EventType `json:"event_types,omitempty"` // Event types to forward, defaults to creations only
+	Pattern    string      `json:"pattern,omitempty"`     // Case-insensitive regexp matched against name and symbol
+	Creators   []string    `json:"creators,omitempty"`    // Only forward tokens launched by these wallets
+	HideRisky  bool        `json:"hide_risky,omitempty"`  // Skip tokens carrying any risk flag
+	Alerts     bool        `json:"alerts,omitempty"`      // Also post operational alerts, such as a stale feed
+	Digests    bool        `json:"digests,omitempty"`     // Also post the scheduled digests
+
+	pattern *regexp.Regexp
+}
+
+// Compile prepares the filter for matching
+func (f *NotifyFilter) Compile() error {
+	if f.Pattern == "" {
+		return nil
+	}
+
+	pattern, err := regexp.Compile("(?i)" + f.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", f.Pattern, err)
+	}
+	f.pattern = pattern
+	return nil
+}
+
+// Matches reports whether the filter selects the given event
+// Events for tokens that were not seen being created never match, since there
+// is nothing to describe them with. The risk flags are only computed for
+// filters hiding risky tokens, once every other condition matched.
+func (f *NotifyFilter) Matches(event Event, record TokenRecord, flags *riskFlagSet) bool {
+	if len(f.EventTypes) == 0 {
+		if event.Type != EventCreate {
+			return false
+		}
+	} else if !slices.Contains(f.EventTypes, event.Type) {
+		return false
+	}
+
+	if len(f.Creators) > 0 && !slices.Contains(f.Creators, record.Creator) {
+		return false
+	}
+
+	if f.pattern != nil && !f.pattern.MatchString(record.Creation.Name) && !f.pattern.MatchString(record.Creation.Symbol) {
+		return false
+	}
+
+	return !f.HideRisky || len(flags.Get()) == 0
+}
+
+// riskFlagSet computes the risk flags of a token the first time they are
+// needed, so sinks only pay for them once an event passed their filters
+type riskFlagSet struct {
+	record   TokenRecord
+	flags    []string
+	computed bool
+}
+
+// newRiskFlagSet returns the risk flags of a token, computed on first use
+func newRiskFlagSet(record TokenRecord) *riskFlagSet {
+	return &riskFlagSet{record: record}
+}
+
+// Get returns the risk flags, computing them on the first call
+func (s *riskFlagSet) Get() []string {
+	if !s.computed {
+		s.flags = riskFlags(s.record)
+		s.computed = true
+	}
+	return s.flags
+}
+
+// riskFlags computes heuristic warnings for a token from what the backend knows about it
+func riskFlags(record TokenRecord) []string {
+	flags := []string{}
+
+	if Tokens.CountByCreator(record.Creator) >= serialCreatorThreshold {
+		flags = append(flags, riskSerialCreator)
+	}
+
+	uri := strings.ToLower(record.Creation.Uri)
+	switch {
+	case uri == "":
+		flags = append(flags, riskNoMetadata)
+	case !strings.Contains(uri, "ipfs") && !strings.Contains(uri, "arweave"):
+		flags = append(flags, riskMetadataOffIPFS)
+	}
+
+	if lp := record.Migration.LP; lp != nil && !lp.Safe {
+		flags = append(flags, riskLPNotBurned)
+	}
+
+	return flags
+}
+
+// metadataClient fetches token metadata documents
+// Metadata URIs are chosen by token creators, so it only connects to public
+// addresses. It is built on first use, once the outbound proxy is configured.
+var metadataClient = sync.OnceValue(func() *http.Client {
+	return &http.Client{Timeout: metadataFetchTimeout, Transport: publicTransport()}
+})
+
+// tokenMetadata holds the fields used from a token metadata document
+type tokenMetadata struct {
+	Image       string `json:"image"`       // Image URL
+	Description string `json:"description"` // Free-form description written by the creator
+	Twitter     string `json:"twitter"`     // X profile or post linked by the creator
+	Telegram    string `json:"telegram"`    // Telegram group linked by the creator
+	Website     string `json:"website"`     // Website linked by the creator
+}
+
+// fetchMetadata fetches the metadata document at uri
+// It returns false if the document cannot be fetched or decoded
+func fetchMetadata(uri string) (tokenMetadata, bool) {
+	return fetchMetadataWith(metadataClient(), uri)
+}
+
+// fetchMetadataWith fetches the metadata document at uri with the given client
+func fetchMetadataWith(client *http.Client, uri string) (tokenMetadata, bool) {
+	var metadata tokenMetadata
+	if !strings.HasPrefix(uri, "http://") && !strings.HasPrefix(uri, "https://") {
+		return metadata, false
+	}
+
+	resp, err := client.Get(uri)
+	if err != nil {
+		return metadata, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(io.LimitReader(resp.Body, maxMetadataBytes)).Decode(&metadata) != nil {
+		return tokenMetadata{}, false
+	}
+	return metadata, true
+}
+
+// fetchMetadataImage returns the image URL from a token metadata document, or
+// an empty string if the metadata cannot be fetched
+func fetchMetadataImage(uri string) string {
+	metadata, _ := fetchMetadata(uri)
+	return metadata.Image
+}
+
+// formatUSDPrice renders a token price with enough significant digits for
+// the tiny prices of fresh tokens
+func formatUSDPrice(price float64) string {
+	return fmt.Sprintf("$%.6g", price)
+}
+
+// formatLPStatus summarizes the verified LP of a pool, e.g. 100% burned
+func formatLPStatus(lp LPStatus) string {
+	summary := fmt.Sprintf("%.0f%% burned", lp.BurnedShare*100)
+	if lp.LockedShare > 0 {
+		summary += fmt.Sprintf(", %.0f%% locked", lp.LockedShare*100)
+	}
+	if !lp.Safe {
+		summary += " ⚠️"
+	}
+	return summary
+}
+
+// pumpFunLink returns the pump.fun page of a mint
+func pumpFunLink(mint string) string {
+	return fmt.Sprintf(pumpFunCoinURL, mint)
+}
+
+// solscanLink returns the Solscan page of a mint
+func solscanLink(mint string) string {
+	return fmt.Sprintf(solscanTokenURL, mint)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestFetchMetadataRefusesPrivateAddresses checks that metadata URIs pointing
+// to the loopback, private or link-local networks are not fetched
+func TestFetchMetadataRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("fetched %s, expected the connection refused", r.URL)
+		w.Write([]byte(`{"image":"https://example.com/image.png"}`))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	for _, uri := range []string{
+		server.URL + "/metadata.json",
+		"http://localhost:" + port + "/metadata.json",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/metadata.json",
+		"ftp://example.com/metadata.json",
+	} {
+		if metadata, ok := fetchMetadata(uri); ok {
+			t.Fatalf("fetched %+v from %s, expected it refused", metadata, uri)
+		}
+	}
+}
+
+// TestFetchMetadataWithLimit checks that metadata documents are decoded up to
+// maxMetadataBytes
+func TestFetchMetadataWithLimit(t *testing.T) {
+	padding := strings.Repeat(" ", maxMetadataBytes)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.json":
+			w.Write([]byte(`{"image":"https://example.com/image.png"}`))
+		case "/large.json":
+			w.Write([]byte(`{"description":"` + padding + `","image":"https://example.com/image.png"}`))
+		}
+	}))
+	defer server.Close()
+
+	if metadata, ok := fetchMetadataWith(server.Client(), server.URL+"/small.json"); !ok || metadata.Image != "https://example.com/image.png" {
+		t.Fatalf("fetched %+v (%v), expected the image", metadata, ok)
+	}
+	if metadata, ok := fetchMetadataWith(server.Client(), server.URL+"/large.json"); ok {
+		t.Fatalf("fetched %+v from a document over the limit", metadata)
+	}
+}
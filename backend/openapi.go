@@ -0,0 +1,250 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Endpoint serving the generated OpenAPI document
+	openAPIEndpoint = "/api/openapi.json"
+
+	// Endpoint serving the Swagger UI for the OpenAPI document
+	apiDocsEndpoint = "/api/docs"
+
+	// Title and version published in the OpenAPI document
+	apiTitle   = "Nova token feed API"
+	apiVersion = "1.0.0"
+
+	// JSON pointer the schemas of the OpenAPI document are referenced by
+	openAPISchemaRef = "#/components/schemas/"
+)
+
+// apiRoute describes a REST endpoint and the metadata needed to document it
+type apiRoute struct {
+	Method   string           // HTTP method
+	Path     string           // Route path in gorilla/mux syntax
+	Summary  string           // One-line description for the spec
+	Handler  http.HandlerFunc // Handler serving the route
+	Params   []apiParam       // Path and query parameters
+	Request  interface{}      // Zero value of the JSON request body, nil if none
+	Response interface{}      // Zero value of the success response body, nil if none
+}
+
+// apiParam describes a single path or query parameter
+type apiParam struct {
+	Name        string // Parameter name
+	In          string // "path" or "query"
+	Description string // One-line description for the spec
+	Required    bool   // Whether the parameter must be present
+	Type        string // JSON schema type, defaults to "string"
+}
+
+// timeType is used to document time.Time fields as RFC 3339 strings
+var timeType = reflect.TypeOf(time.Time{})
+
+// HandleOpenAPI serves the OpenAPI 3 document generated from apiRoutes
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildOpenAPISpec(apiRoutes))
+}
+
+// HandleAPIDocs serves a Swagger UI page rendering the OpenAPI document
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
+
+// buildOpenAPISpec generates an OpenAPI 3 document for the given routes
+// Response schemas are derived from the JSON tags of the response types and
+// shared through components/schemas
+func buildOpenAPISpec(routes []apiRoute) map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+
+	for _, route := range routes {
+		parameters := []interface{}{}
+		for _, param := range route.Params {
+			paramType := param.Type
+			if paramType == "" {
+				paramType = "string"
+			}
+			parameters = append(parameters, map[string]interface{}{
+				"name":        param.Name,
+				"in":          param.In,
+				"description": param.Description,
+				"required":    param.Required,
+				"schema":      map[string]interface{}{"type": paramType},
+			})
+		}
+
+		success := map[string]interface{}{"description": "Success"}
+		if route.Response != nil {
+			success["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": schemaFor(reflect.TypeOf(route.Response), schemas, openAPISchemaRef),
+				},
+			}
+		}
+
+		operation := map[string]interface{}{
+			"summary":    route.Summary,
+			"parameters": parameters,
+			"responses": map[string]interface{}{
+				successStatus(route.Method): success,
+				"default": map[string]interface{}{
+					"description": "Error",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": schemaFor(reflect.TypeOf(errorResponse{}), schemas, openAPISchemaRef),
+						},
+					},
+				},
+			},
+		}
+
+		if route.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": schemaFor(reflect.TypeOf(route.Request), schemas, openAPISchemaRef),
+					},
+				},
+			}
+		}
+
+		// OpenAPI uses {name} placeholders just like gorilla/mux
+		item, ok := paths[route.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   apiTitle,
+			"version": apiVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+// successStatus returns the status code handlers use for a successful request
+func successStatus(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "201"
+	case http.MethodDelete:
+		return "204"
+	default:
+		return "200"
+	}
+}
+
+// schemaFor returns the JSON schema for a Go type, registering named structs
+// in schemas and referencing them by $ref
+//
+// Parameters:
+//   - t: Go type to describe
+//   - schemas: Schemas of the named structs, by name, filled as they are met
+//   - refPrefix: JSON pointer schemas is published under, e.g. #/components/schemas/
+func schemaFor(t reflect.Type, schemas map[string]interface{}, refPrefix string) map[string]interface{} {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), schemas, refPrefix)}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), schemas, refPrefix)}
+	case t.Kind() == reflect.Struct:
+		return structSchemaRef(t, schemas, refPrefix)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchemaRef registers the schema of a struct type and returns a $ref to it
+func structSchemaRef(t reflect.Type, schemas map[string]interface{}, refPrefix string) map[string]interface{} {
+	ref := map[string]interface{}{"$ref": refPrefix + t.Name()}
+	if _, done := schemas[t.Name()]; done {
+		return ref
+	}
+
+	// Reserve the name first so recursive types terminate
+	schemas[t.Name()] = nil
+
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaFor(field.Type, schemas, refPrefix)
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+			required = append(required, name)
+		}
+	}
+
+	schemas[t.Name()] = map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	return ref
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at the OpenAPI document
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>` + apiTitle + `</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    SwaggerUIBundle({ url: "` + openAPIEndpoint + `", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
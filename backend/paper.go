@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable switching the auto-buy engine to paper trading
+	// when true: rules buy with a virtual balance at the recorded curve
+	// prices, and no transaction is ever built
+	paperTradingEnv = "AUTO_BUY_PAPER"
+
+	// Environment variable with the virtual balance to start from, in lamports
+	paperBalanceEnv = "AUTO_BUY_PAPER_BALANCE"
+
+	// Environment variable with a file the paper trades are appended to, one
+	// JSON object per line, so runs can be evaluated afterwards
+	paperTradeLogEnv = "AUTO_BUY_PAPER_LOG"
+
+	// Virtual balance used when AUTO_BUY_PAPER_BALANCE is unset, 10 SOL
+	defaultPaperBalance = 10_000_000_000
+
+	// Admin endpoint showing the virtual balance, positions and trade log
+	adminPaperTradingEndpoint = "/admin/autobuy/paper"
+
+	// Number of paper trades kept in memory for the admin endpoint
+	paperTradeHistorySize = 1000
+
+	// Reserves of a fresh bonding curve, used for creations without a dev buy
+	initialVirtualSolReserves   = 30_000_000_000
+	initialVirtualTokenReserves = 1_073_000_000_000_000
+	initialRealTokenReserves    = 793_100_000_000_000
+)
+
+// PaperTrade is a simulated buy, as written to the trade log
+type PaperTrade struct {
+	ID          string    `json:"id"`           // Identifier, in place of a signature
+	Rule        string    `json:"rule"`         // Rule that bought
+	Mint        string    `json:"mint"`         // Token mint address
+	SolAmount   uint64    `json:"sol_amount"`   // Lamports spent, fee included
+	TokenAmount uint64    `json:"token_amount"` // Token base units received
+	MarketCap   float64   `json:"market_cap"`   // Market cap in SOL at the price the buy filled at
+	Balance     uint64    `json:"balance"`      // Virtual balance left after the buy, in lamports
+	ExecutedAt  time.Time `json:"executed_at"`  // Time of the simulated buy
+}
+
+// PaperPosition is a token held by the paper trader, valued at the latest curve state
+type PaperPosition struct {
+	Mint        string `json:"mint"`         // Token mint address
+	Rule        string `json:"rule"`         // Rule that bought the token
+	TokenAmount uint64 `json:"token_amount"` // Token base units held
+	CostBasis   uint64 `json:"cost_basis"`   // Lamports spent on the tokens
+	Value       uint64 `json:"value"`        // Lamports a sale of the tokens would return now, after the fee
+	PnL         int64  `json:"pnl"`          // Value minus cost basis, in lamports
+	Graduated   bool   `json:"graduated"`    // Whether the curve completed; the value is then the one of the last curve trade
+}
+
+// paperTradingResponse is the JSON body of the paper trading admin endpoint
+type paperTradingResponse struct {
+	StartingBalance uint64          `json:"starting_balance"` // Virtual balance the run started with, in lamports
+	Balance         uint64          `json:"balance"`          // Virtual balance left, in lamports
+	Equity          uint64          `json:"equity"`           // Balance plus the value of the positions
+	PnL             int64           `json:"pnl"`              // Equity minus the starting balance
+	Positions       []PaperPosition `json:"positions"`        // Open positions, newest first
+	Trades          []PaperTrade    `json:"trades"`           // Latest paper trades, newest first
+}
+
+// PaperTrader executes auto-buys against a virtual balance
+//
+// Buys fill at the latest curve state recorded from the feed, which for a
+// creation with a dev buy is the state right after it, without slippage or
+// priority fees; real buys land later and fill worse. Positions are valued
+// with the sell quote of the latest recorded state.
+type PaperTrader struct {
+	startingBalance uint64
+	log             *os.File
+
+	mutex     sync.Mutex
+	balance   uint64
+	next      int
+	positions map[string]*PaperPosition
+	order     []string // Mints of the positions, oldest first
+	trades    []PaperTrade
+}
+
+// PaperTrades simulates the auto-buys when AUTO_BUY_PAPER is set, nil otherwise
+var PaperTrades *PaperTrader
+
+// errInsufficientPaperBalance is returned when the virtual balance cannot cover a buy
+var errInsufficientPaperBalance = errors.New("insufficient paper balance")
+
+// newPaperTrader creates the paper trader from AUTO_BUY_PAPER_BALANCE and AUTO_BUY_PAPER_LOG
+//
+// Returns:
+//   - *PaperTrader: The paper trader
+//   - error: Error if the balance is invalid or the trade log cannot be opened
+func newPaperTrader() (*PaperTrader, error) {
+	balance := uint64(defaultPaperBalance)
+	if value := os.Getenv(paperBalanceEnv); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("invalid %s %q", paperBalanceEnv, value)
+		}
+		balance = parsed
+	}
+
+	trader := &PaperTrader{
+		startingBalance: balance,
+		balance:         balance,
+		positions:       make(map[string]*PaperPosition),
+	}
+	if path := os.Getenv(paperTradeLogEnv); path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", paperTradeLogEnv, err)
+		}
+		trader.log = file
+	}
+	return trader, nil
+}
+
+// buy simulates the buy of a rule at the latest recorded curve state
+//
+// Returns:
+//   - string: Identifier of the paper trade
+//   - error: errInsufficientPaperBalance, pumpstream.ErrCurveComplete, or an
+//     error if the token is not tracked
+func (p *PaperTrader) buy(ctx context.Context, rule *autoBuyRule, mint string) (string, error) {
+	record, found := Tokens.Get(mint)
+	if !found {
+		return "", fmt.Errorf("token %s is not tracked", mint)
+	}
+	curve := paperCurve(record)
+	amount := rule.config.AmountLamports
+	tokens, err := curve.BuyQuote(amount, pumpFeeBasisPoints)
+	if err != nil {
+		return "", err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.balance < amount {
+		return "", fmt.Errorf("%w: %d lamports left", errInsufficientPaperBalance, p.balance)
+	}
+	p.balance -= amount
+	p.next++
+
+	trade := PaperTrade{
+		ID:          fmt.Sprintf("paper-%d", p.next),
+		Rule:        rule.config.Name,
+		Mint:        mint,
+		SolAmount:   amount,
+		TokenAmount: tokens,
+		MarketCap:   CurveState{VirtualSolReserves: curve.VirtualSolReserves, VirtualTokenReserves: curve.VirtualTokenReserves}.MarketCapSOL(),
+		Balance:     p.balance,
+		ExecutedAt:  time.Now().UTC(),
+	}
+	p.trades = append(p.trades, trade)
+	if len(p.trades) > paperTradeHistorySize {
+		p.trades = p.trades[1:]
+	}
+
+	position, held := p.positions[mint]
+	if !held {
+		position = &PaperPosition{Mint: mint, Rule: rule.config.Name}
+		p.positions[mint] = position
+		p.order = append(p.order, mint)
+	}
+	position.TokenAmount += tokens
+	position.CostBasis += amount
+	if Portfolios != nil {
+		Portfolios.Record(paperPortfolioAccount, mint, true, amount, tokens)
+	}
+
+	if p.log != nil {
+		if err := json.NewEncoder(p.log).Encode(trade); err != nil {
+			return "", fmt.Errorf("failed to write the paper trade log: %w", err)
+		}
+	}
+	return trade.ID, nil
+}
+
+// paperCurve returns the latest recorded curve state of a token, or the state
+// of a fresh curve if no trade was seen
+func paperCurve(record TokenRecord) pumpstream.BondingCurveAccount {
+	curve := pumpstream.BondingCurveAccount{
+		VirtualSolReserves:   initialVirtualSolReserves,
+		VirtualTokenReserves: initialVirtualTokenReserves,
+		RealTokenReserves:    initialRealTokenReserves,
+		TokenTotalSupply:     pumpTokenTotalSupply,
+		Complete:             record.Migration.Complete,
+	}
+	if record.Curve != nil {
+		curve.VirtualSolReserves = record.Curve.VirtualSolReserves
+		curve.VirtualTokenReserves = record.Curve.VirtualTokenReserves
+		curve.RealSolReserves = record.Curve.RealSolReserves
+		curve.RealTokenReserves = record.Curve.RealTokenReserves
+	}
+	return curve
+}
+
+// Report values the positions at the latest recorded curve states
+func (p *PaperTrader) Report() paperTradingResponse {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	report := paperTradingResponse{
+		StartingBalance: p.startingBalance,
+		Balance:         p.balance,
+		Equity:          p.balance,
+		Positions:       make([]PaperPosition, 0, len(p.order)),
+		Trades:          make([]PaperTrade, 0, len(p.trades)),
+	}
+	for i := len(p.order) - 1; i >= 0; i-- {
+		position := *p.positions[p.order[i]]
+		if record, found := Tokens.Get(position.Mint); found {
+			curve := paperCurve(record)
+			// A completed curve cannot be sold on; value at its last state
+			position.Graduated = curve.Complete
+			curve.Complete = false
+			position.Value, _ = curve.SellQuote(position.TokenAmount, pumpFeeBasisPoints)
+		}
+		position.PnL = int64(position.Value) - int64(position.CostBasis)
+		report.Equity += position.Value
+		report.Positions = append(report.Positions, position)
+	}
+	for i := len(p.trades) - 1; i >= 0; i-- {
+		report.Trades = append(report.Trades, p.trades[i])
+	}
+	report.PnL = int64(report.Equity) - int64(report.StartingBalance)
+	return report
+}
+
+// HandleGetPaperTrading returns the virtual balance, positions and trade log
+func HandleGetPaperTrading(w http.ResponseWriter, r *http.Request) {
+	if PaperTrades == nil {
+		writeError(w, http.StatusNotFound, "paper trading is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, PaperTrades.Report())
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Configuration constants
+const (
+	// Name of the file written to every partition directory
+	// Re-exporting a day replaces its files, so scheduled exports are idempotent
+	parquetFileName = "events.parquet"
+)
+
+// creationRow is the Parquet schema of exported creations
+type creationRow struct {
+	Mint       string    `parquet:"mint"`                               // Token mint address
+	Name       string    `parquet:"name"`                               // Token name
+	Symbol     string    `parquet:"symbol"`                             // Token symbol
+	URI        string    `parquet:"uri"`                                // Metadata URI
+	Signature  string    `parquet:"signature"`                          // Creation transaction signature
+	Slot       uint64    `parquet:"slot"`                               // Slot the transaction was observed in
+	ReceivedAt time.Time `parquet:"received_at,timestamp(millisecond)"` // Time the notification was received
+}
+
+// tradeRow is the Parquet schema of exported trades
+type tradeRow struct {
+	Mint                 string    `parquet:"mint"`                               // Token mint address
+	Signature            string    `parquet:"signature"`                          // Trade transaction signature
+	Slot                 uint64    `parquet:"slot"`                               // Slot the transaction was observed in
+	ReceivedAt           time.Time `parquet:"received_at,timestamp(millisecond)"` // Time the notification was received
+	SolAmount            uint64    `parquet:"sol_amount"`                         // Lamports exchanged
+	TokenAmount          uint64    `parquet:"token_amount"`                       // Token base units exchanged
+	IsBuy                bool      `parquet:"is_buy"`                             // True for buys, false for sells
+	User                 string    `parquet:"user"`                               // Trader wallet
+	Timestamp            int64     `parquet:"timestamp"`                          // Unix timestamp of the trade
+	VirtualSolReserves   uint64    `parquet:"virtual_sol_reserves"`               // Virtual SOL reserves after the trade
+	VirtualTokenReserves uint64    `parquet:"virtual_token_reserves"`             // Virtual token reserves after the trade
+}
+
+// completionRow is the Parquet schema of exported curve completions
+type completionRow struct {
+	Mint         string    `parquet:"mint"`                               // Token mint address
+	Signature    string    `parquet:"signature"`                          // Completion transaction signature
+	Slot         uint64    `parquet:"slot"`                               // Slot the transaction was observed in
+	ReceivedAt   time.Time `parquet:"received_at,timestamp(millisecond)"` // Time the notification was received
+	User         string    `parquet:"user"`                               // Wallet that completed the curve
+	BondingCurve string    `parquet:"bonding_curve"`                      // Bonding curve account of the token
+	Timestamp    int64     `parquet:"timestamp"`                          // Unix timestamp of the completion
+}
+
+// priceRow is the Parquet schema of exported price quotes
+type priceRow struct {
+	Mint       string    `parquet:"mint"`                               // Token mint address
+	Slot       uint64    `parquet:"slot"`                               // Slot the quote reflects
+	ReceivedAt time.Time `parquet:"received_at,timestamp(millisecond)"` // Time the quote was received
+	PriceUSD   float64   `parquet:"price_usd"`                          // Price of one token in USD
+	Source     string    `parquet:"source"`                             // Service that quoted the price
+	Timestamp  int64     `parquet:"timestamp"`                          // Unix timestamp of the quote
+}
+
+// migrationRow is the Parquet schema of exported migrations
+type migrationRow struct {
+	Mint          string    `parquet:"mint"`                               // Token mint address
+	Slot          uint64    `parquet:"slot"`                               // Slot the LP was verified at
+	ReceivedAt    time.Time `parquet:"received_at,timestamp(millisecond)"` // Time the LP was verified
+	Pool          string    `parquet:"pool"`                               // Pool of the token
+	LPMint        string    `parquet:"lp_mint"`                            // LP token mint of the pool
+	MintedSupply  uint64    `parquet:"minted_supply"`                      // LP minted at the deposit
+	CurrentSupply uint64    `parquet:"current_supply"`                     // LP supply left after burns
+	BurnedShare   float64   `parquet:"burned_share"`                       // Share of the LP burned
+	LockedShare   float64   `parquet:"locked_share"`                       // Share of the LP held by locker programs
+}
+
+// revertRow is the Parquet schema of exported reverts
+type revertRow struct {
+	Mint       string    `parquet:"mint"`                               // Token mint address
+	Signature  string    `parquet:"signature"`                          // Creation transaction signature
+	Slot       uint64    `parquet:"slot"`                               // Slot the creation was observed in
+	ReceivedAt time.Time `parquet:"received_at,timestamp(millisecond)"` // Time the creation was reverted
+	Reason     string    `parquet:"reason"`                             // failed or dropped
+	Error      string    `parquet:"error"`                              // Error of a failed transaction
+}
+
+// parquetPartition is an open Parquet file of a single type=/dt= partition
+type parquetPartition interface {
+	add(event Event) error
+	close() error
+}
+
+// parquetFile writes rows of one schema to a temporary file that is renamed
+// into place on close, so readers never see a half-written file
+type parquetFile[T any] struct {
+	path    string
+	file    *os.File
+	writer  *parquet.GenericWriter[T]
+	convert func(Event) T
+}
+
+// newParquetFile creates the temporary file of a partition
+func newParquetFile[T any](path string, convert func(Event) T) (*parquetFile[T], error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &parquetFile[T]{
+		path:    path,
+		file:    file,
+		writer:  parquet.NewGenericWriter[T](file, parquet.Compression(&parquet.Zstd)),
+		convert: convert,
+	}, nil
+}
+
+// add appends the row of an event
+func (p *parquetFile[T]) add(event Event) error {
+	_, err := p.writer.Write([]T{p.convert(event)})
+	return err
+}
+
+// close writes the footer and moves the file into place
+func (p *parquetFile[T]) close() error {
+	if err := p.writer.Close(); err != nil {
+		p.file.Close()
+		return err
+	}
+	if err := p.file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(p.file.Name(), p.path)
+}
+
+// exportParquet writes stored events to Hive-style partitioned Parquet files,
+// out/type=<event type>/dt=<YYYY-MM-DD>/events.parquet, partitioned by the UTC
+// day the event was received
+//
+// Parameters:
+//   - storage: Backend to read events from
+//   - query: Events to export
+//   - out: Root directory of the partitions
+//
+// Returns:
+//   - int: Number of events exported
+//   - error: Error if reading or writing failed; finished partitions are kept
+func exportParquet(storage Storage, query EventQuery, out string) (int, error) {
+	partitions := map[string]parquetPartition{}
+	count := 0
+
+	err := storage.ScanEvents(query, func(event Event) error {
+		dir := filepath.Join(out, "type="+string(event.Type), "dt="+event.ReceivedAt.UTC().Format(time.DateOnly))
+		partition, open := partitions[dir]
+		if !open {
+			var err error
+			if partition, err = newParquetPartition(event.Type, filepath.Join(dir, parquetFileName)); err != nil {
+				return err
+			}
+			partitions[dir] = partition
+		}
+
+		count++
+		return partition.add(event)
+	})
+
+	for dir, partition := range partitions {
+		if closeErr := partition.close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to finish %s: %w", dir, closeErr)
+		}
+	}
+	return count, err
+}
+
+// newParquetPartition opens a Parquet file with the schema of the event type
+func newParquetPartition(eventType EventType, path string) (parquetPartition, error) {
+	switch eventType {
+	case EventCreate:
+		return newParquetFile(path, func(event Event) creationRow {
+			data := event.Data.(CreateEvent)
+			return creationRow{
+				Mint:       event.Mint,
+				Name:       data.Name,
+				Symbol:     data.Symbol,
+				URI:        data.Uri,
+				Signature:  event.Signature,
+				Slot:       event.Slot,
+				ReceivedAt: event.ReceivedAt,
+			}
+		})
+	case EventTrade:
+		return newParquetFile(path, func(event Event) tradeRow {
+			data := event.Data.(TradeEvent)
+			return tradeRow{
+				Mint:                 event.Mint,
+				Signature:            event.Signature,
+				Slot:                 event.Slot,
+				ReceivedAt:           event.ReceivedAt,
+				SolAmount:            data.SolAmount,
+				TokenAmount:          data.TokenAmount,
+				IsBuy:                data.IsBuy,
+				User:                 data.User,
+				Timestamp:            data.Timestamp,
+				VirtualSolReserves:   data.VirtualSolReserves,
+				VirtualTokenReserves: data.VirtualTokenReserves,
+			}
+		})
+	case EventComplete:
+		return newParquetFile(path, func(event Event) completionRow {
+			data := event.Data.(CompleteEvent)
+			return completionRow{
+				Mint:         event.Mint,
+				Signature:    event.Signature,
+				Slot:         event.Slot,
+				ReceivedAt:   event.ReceivedAt,
+				User:         data.User,
+				BondingCurve: data.BondingCurve,
+				Timestamp:    data.Timestamp,
+			}
+		})
+	case EventPrice:
+		return newParquetFile(path, func(event Event) priceRow {
+			data := event.Data.(PriceEvent)
+			return priceRow{
+				Mint:       event.Mint,
+				Slot:       event.Slot,
+				ReceivedAt: event.ReceivedAt,
+				PriceUSD:   data.PriceUSD,
+				Source:     data.Source,
+				Timestamp:  data.Timestamp,
+			}
+		})
+	case EventMigration:
+		return newParquetFile(path, func(event Event) migrationRow {
+			data := event.Data.(MigrationEvent)
+			return migrationRow{
+				Mint:          event.Mint,
+				Slot:          event.Slot,
+				ReceivedAt:    event.ReceivedAt,
+				Pool:          data.LP.Pool,
+				LPMint:        data.LP.LPMint,
+				MintedSupply:  data.LP.MintedSupply,
+				CurrentSupply: data.LP.CurrentSupply,
+				BurnedShare:   data.LP.BurnedShare,
+				LockedShare:   data.LP.LockedShare,
+			}
+		})
+	case EventRevert:
+		return newParquetFile(path, func(event Event) revertRow {
+			data := event.Data.(RevertEvent)
+			return revertRow{
+				Mint:       event.Mint,
+				Signature:  event.Signature,
+				Slot:       event.Slot,
+				ReceivedAt: event.ReceivedAt,
+				Reason:     data.Reason,
+				Error:      data.Error,
+			}
+		})
+	default:
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+}
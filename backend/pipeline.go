@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Number of processed logs remembered exactly to drop duplicates, e.g. a
+	// transaction delivered again after an upstream reconnect
+	pipelineDedupSize = 100000
+)
+
+// PipelineStage orders the middlewares of the processing pipeline
+type PipelineStage int
+
+// Stages of the pipeline, in the order events go through them; delivery to
+// clients and sinks follows the last one
+const (
+	StageDecode PipelineStage = iota // Turns the program log into an event
+	StageDedup                       // Drops events already processed
+	StageFilter                      // Drops events that should not be delivered
+	StageEnrich                      // Records and augments events
+)
+
+// Names of the stages, in order
+var pipelineStageNames = []string{"decode", "dedup", "filter", "enrich"}
+
+// String returns the name of the stage
+func (s PipelineStage) String() string {
+	if s < 0 || int(s) >= len(pipelineStageNames) {
+		return "stage " + strconv.Itoa(int(s))
+	}
+	return pipelineStageNames[s]
+}
+
+// PipelineEvent is a program log travelling through the pipeline
+type PipelineEvent struct {
+	Log       string // Program log as received
+	Signature string // Transaction signature
+	Slot      uint64 // Slot the transaction was observed in
+	Index     int    // Position of the log within the notification
+
+	// Event decoded from the log: *pumpstream.CreateEvent, *pumpstream.TradeEvent
+	// or *pumpstream.CompleteEvent; set by the decode stage
+	Decoded any
+
+	// Derived accounts, market data, supply, top holders and creator funding
+	// of the token, set by the enrich stage when available
+	Addresses *DerivedAddresses
+	Market    *MarketData
+	Supply    *TokenSupply
+	Holders   *HolderConcentration
+	Funding   *FundingSource
+
+	// Fields computed by the filter scripts, by name
+	Fields map[string]any
+}
+
+// pipelineEventMint returns the mint of the decoded event, empty before decoding
+func pipelineEventMint(event *PipelineEvent) string {
+	switch decoded := event.Decoded.(type) {
+	case *pumpstream.CreateEvent:
+		return decoded.Mint.String()
+	case *pumpstream.TradeEvent:
+		return decoded.Mint.String()
+	case *pumpstream.CompleteEvent:
+		return decoded.Mint.String()
+	}
+	return ""
+}
+
+// PipelineHandler processes an event; an error is logged and ends processing of the log
+type PipelineHandler func(ctx context.Context, event *PipelineEvent) error
+
+// PipelineMiddleware wraps the rest of the pipeline: it passes the event on by
+// calling next, and drops it by returning without doing so
+type PipelineMiddleware func(next PipelineHandler) PipelineHandler
+
+// pipelineEntry is a registered middleware
+type pipelineEntry struct {
+	stage      PipelineStage
+	name       string
+	middleware PipelineMiddleware
+}
+
+// ProcessingPipeline runs program logs through the registered middlewares,
+// stage by stage, then delivers the surviving events to clients and sinks
+type ProcessingPipeline struct {
+	mutex   sync.Mutex
+	entries []pipelineEntry
+	deliver PipelineHandler
+	handler atomic.Pointer[PipelineHandler] // Chain built from the entries, replaced on registration
+}
+
+// Pipeline processes every program log of every notification
+var Pipeline = newProcessingPipeline()
+
+// newProcessingPipeline creates the pipeline with the built-in middlewares
+func newProcessingPipeline() *ProcessingPipeline {
+	pipeline := &ProcessingPipeline{deliver: deliverEvent}
+	pipeline.Register(StageDecode, "pumpstream", decodeProgramLog)
+	pipeline.Register(StageDedup, "signature", dropDuplicates(newDedupCache("signature", pipelineDedupSize)))
+	pipeline.Register(StageFilter, "feature flags", dropDisabledEvents)
+	pipeline.Register(StageEnrich, "token store", recordInTokenStore)
+	pipeline.Register(StageEnrich, "derived addresses", addDerivedAddresses)
+	return pipeline
+}
+
+// Register adds a middleware to a stage, after those already registered in it
+//
+// Parameters:
+//   - stage: Stage the middleware runs in
+//   - name: Name of the middleware, for diagnostics
+//   - middleware: The middleware
+func (p *ProcessingPipeline) Register(stage PipelineStage, name string, middleware PipelineMiddleware) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.entries = append(p.entries, pipelineEntry{stage: stage, name: name, middleware: middleware})
+	slices.SortStableFunc(p.entries, func(a, b pipelineEntry) int { return int(a.stage) - int(b.stage) })
+
+	handler := p.deliver
+	for i := len(p.entries) - 1; i >= 0; i-- {
+		handler = p.entries[i].middleware(handler)
+	}
+	p.handler.Store(&handler)
+}
+
+// Middlewares returns the registered middlewares as stage/name, in the order they run
+func (p *ProcessingPipeline) Middlewares() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	names := make([]string, len(p.entries))
+	for i, entry := range p.entries {
+		names[i] = entry.stage.String() + "/" + entry.name
+	}
+	return names
+}
+
+// Process runs an event through the pipeline
+func (p *ProcessingPipeline) Process(ctx context.Context, event *PipelineEvent) error {
+	return (*p.handler.Load())(ctx, event)
+}
+
+// decodeProgramLog decodes the log, unless a middleware before it already
+// did, and drops logs that are not events of the program
+func decodeProgramLog(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		if event.Decoded != nil {
+			return next(ctx, event)
+		}
+
+		_, span := tracer.Start(ctx, "decode")
+		decoded, err := pumpstream.DecodeLog(event.Log)
+		span.End()
+		if errors.Is(err, pumpstream.ErrUnknownEvent) {
+			return nil // Not an event we track, skip
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to decode event: %w", err)
+			reportError(errorCategoryDecode, err, logKeySignature, event.Signature, logKeySlot, event.Slot, logKeyCorrelationID, correlationID(ctx))
+			return err
+		}
+		event.Decoded = decoded
+		return next(ctx, event)
+	}
+}
+
+// dropDuplicates drops logs already processed, by signature and position
+func dropDuplicates(seen *dedupCache) PipelineMiddleware {
+	return func(next PipelineHandler) PipelineHandler {
+		return func(ctx context.Context, event *PipelineEvent) error {
+			if event.Signature != "" && !seen.Add(event.Signature+"/"+strconv.Itoa(event.Index)) {
+				return nil
+			}
+			return next(ctx, event)
+		}
+	}
+}
+
+// dropDisabledEvents drops the event types whose feature flag is off
+func dropDisabledEvents(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		switch event.Decoded.(type) {
+		case *pumpstream.TradeEvent:
+			if !Features.Enabled(featureDecodeTrade) {
+				return nil
+			}
+		case *pumpstream.CompleteEvent:
+			if !Features.Enabled(featureDecodeComplete) {
+				return nil
+			}
+		}
+		return next(ctx, event)
+	}
+}
+
+// recordInTokenStore remembers the token an event refers to, so it can be looked up later
+func recordInTokenStore(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		_, span := tracer.Start(ctx, "enrich")
+		switch decoded := event.Decoded.(type) {
+		case *pumpstream.CreateEvent:
+			span.SetAttributes(attrMint.String(decoded.Mint.String()))
+			Tokens.RecordCreation(newCreateEvent(decoded), decoded.BondingCurve.String(), decoded.User.String(), event.Signature, event.Slot)
+			FeedStats.RecordLaunch(pumpProgram().String())
+		case *pumpstream.TradeEvent:
+			span.SetAttributes(attrMint.String(decoded.Mint.String()))
+			Tokens.RecordTrade(decoded.Mint.String(), curveState(decoded))
+		case *pumpstream.CompleteEvent:
+			span.SetAttributes(attrMint.String(decoded.Mint.String()))
+			FeedStats.RecordGraduation()
+			Tokens.RecordCompletion(decoded.Mint.String(), completionStatus(decoded, event.Signature))
+		}
+		span.End()
+		return next(ctx, event)
+	}
+}
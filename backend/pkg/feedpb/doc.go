@@ -0,0 +1,9 @@
+// Package feedpb holds the protobuf messages and the gRPC service of the
+// event feed, generated from proto/feed/v1/feed.proto
+//
+// Envelopes stream from the Feed service to native gRPC clients and, through
+// gRPC-Web, to browsers; clients in other languages generate their types from
+// the same file.
+package feedpb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/luqmanafiq/solana-blockchain/backend --go-grpc_out=../.. --go-grpc_opt=module=github.com/luqmanafiq/solana-blockchain/backend feed/v1/feed.proto
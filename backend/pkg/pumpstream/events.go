@@ -0,0 +1,162 @@
+// Package pumpstream subscribes to the pump.fun bonding curve program and
+// decodes the events it emits: token creations, trades and curve completions
+//
+// A Listener delivers the logs of every successful program transaction as a
+// Notification; DecodeLog turns each log line into a typed event:
+//
+//	listener := &pumpstream.Listener{URL: "wss://api.mainnet-beta.solana.com"}
+//	listener.Run(ctx, func(n pumpstream.Notification) {
+//		for _, log := range n.Logs {
+//			event, err := pumpstream.DecodeLog(log)
+//			...
+//		}
+//	})
+package pumpstream
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Configuration constants
+const (
+	// ProgramID is the pump.fun bonding curve program address on Solana mainnet;
+	// its logs carry the creation, trade and completion events of every token
+	ProgramID = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+
+	// ProgramDataPrefix starts every log message that carries an event
+	ProgramDataPrefix = "Program data: "
+
+	// Capacity of the pooled base64 decode buffers, enough for any event of
+	// the program; larger payloads get a buffer of their own
+	decodeBufferSize = 512
+)
+
+// Program is the pump.fun bonding curve program on mainnet as a public key,
+// the default of Listener.Program; the helpers deriving accounts and building
+// instructions take the program as a parameter, so other deployments such as
+// devnet are served without changing it
+var Program = solana.MPK(ProgramID)
+
+// Discriminators identifying the events emitted by the program
+var (
+	CreateDiscriminator   = []byte{27, 114, 169, 77, 222, 235, 99, 118}  // CreateEvent
+	TradeDiscriminator    = []byte{189, 219, 127, 211, 78, 230, 97, 238} // TradeEvent
+	CompleteDiscriminator = []byte{95, 114, 97, 156, 212, 46, 152, 8}    // CompleteEvent
+)
+
+// decodeBuffers holds the buffers program data is decoded into. Decoded events
+// copy what they keep, so a buffer is reused as soon as its event is decoded.
+var decodeBuffers = sync.Pool{
+	New: func() any {
+		buffer := make([]byte, 0, decodeBufferSize)
+		return &buffer
+	},
+}
+
+// ErrUnknownEvent is returned for program data that is not one of the events
+// decoded by this package
+var ErrUnknownEvent = errors.New("unknown event discriminator")
+
+// CreateEvent is emitted when a new token is created
+type CreateEvent struct {
+	Name         string           // Token name
+	Symbol       string           // Token symbol
+	Uri          string           // Token metadata URI
+	Mint         solana.PublicKey // Token mint address
+	BondingCurve solana.PublicKey // Bonding curve account of the token
+	User         solana.PublicKey // Creator wallet
+}
+
+// TradeEvent is emitted for every buy or sell on a bonding curve
+type TradeEvent struct {
+	Mint                 solana.PublicKey // Token mint address
+	SolAmount            uint64           // Lamports exchanged
+	TokenAmount          uint64           // Token base units exchanged
+	IsBuy                bool             // True for buys, false for sells
+	User                 solana.PublicKey // Trader wallet
+	Timestamp            int64            // Unix timestamp of the trade
+	VirtualSolReserves   uint64           // Virtual SOL reserves after the trade
+	VirtualTokenReserves uint64           // Virtual token reserves after the trade
+	RealSolReserves      uint64           // Real SOL reserves after the trade
+	RealTokenReserves    uint64           // Real token reserves after the trade
+}
+
+// CompleteEvent is emitted when a bonding curve reaches completion (graduation)
+type CompleteEvent struct {
+	User         solana.PublicKey // Wallet that completed the curve
+	Mint         solana.PublicKey // Token mint address
+	BondingCurve solana.PublicKey // Bonding curve account of the token
+	Timestamp    int64            // Unix timestamp of the completion
+}
+
+// DecodeLog decodes the event carried by a program log message
+//
+// Parameters:
+//   - log: A single log message of a transaction
+//
+// Returns:
+//   - interface{}: *CreateEvent, *TradeEvent or *CompleteEvent; nil if the log carries no program data
+//   - error: ErrUnknownEvent for untracked events, or a decoding error
+func DecodeLog(log string) (interface{}, error) {
+	_, data, found := strings.Cut(log, ProgramDataPrefix)
+	if !found {
+		return nil, nil
+	}
+	return DecodeProgramData(data)
+}
+
+// DecodeProgramData decodes the base64 payload of a "Program data" log message
+//
+// Parameters:
+//   - data: Base64-encoded event, without the log prefix
+//
+// Returns:
+//   - interface{}: *CreateEvent, *TradeEvent or *CompleteEvent
+//   - error: ErrUnknownEvent for untracked events, or a decoding error
+func DecodeProgramData(data string) (interface{}, error) {
+	buffer := decodeBuffers.Get().(*[]byte)
+	defer func() {
+		if cap(*buffer) <= decodeBufferSize {
+			decodeBuffers.Put(buffer)
+		}
+	}()
+
+	// The decoder only reads its input, so the string is not copied to bytes
+	src := unsafe.Slice(unsafe.StringData(data), len(data))
+	decoded, err := base64.StdEncoding.AppendDecode((*buffer)[:0], src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 data: %w", err)
+	}
+	*buffer = decoded
+	return DecodeEvent(decoded)
+}
+
+// DecodeEvent decodes a binary event, dispatching on its discriminator
+// The event does not retain data, which may be reused once it returns
+//
+// Parameters:
+//   - data: Event bytes starting with the discriminator
+//
+// Returns:
+//   - interface{}: *CreateEvent, *TradeEvent or *CompleteEvent
+//   - error: ErrUnknownEvent for untracked events, or a decoding error
+func DecodeEvent(data []byte) (interface{}, error) {
+	switch {
+	case bytes.HasPrefix(data, CreateDiscriminator):
+		return decodeCreateEvent(data[len(CreateDiscriminator):])
+	case bytes.HasPrefix(data, TradeDiscriminator):
+		return decodeTradeEvent(data[len(TradeDiscriminator):])
+	case bytes.HasPrefix(data, CompleteDiscriminator):
+		return decodeCompleteEvent(data[len(CompleteDiscriminator):])
+	default:
+		return nil, ErrUnknownEvent
+	}
+}
@@ -0,0 +1,108 @@
+package pumpstream
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+)
+
+// encodeProgramData encodes an event as the "Program data" log message
+// carrying it
+func encodeProgramData(b *testing.B, discriminator []byte, event interface{}) string {
+	var buffer bytes.Buffer
+	buffer.Write(discriminator)
+	if err := bin.NewBorshEncoder(&buffer).Encode(event); err != nil {
+		b.Fatalf("failed to encode event: %v", err)
+	}
+	return ProgramDataPrefix + base64.StdEncoding.EncodeToString(buffer.Bytes())
+}
+
+// programDataLog encodes an event of the program as its log message, checking
+// that it decodes back unchanged
+func programDataLog(b *testing.B, discriminator []byte, event interface{}) string {
+	log := encodeProgramData(b, discriminator, event)
+
+	// The benchmarks are only meaningful if the event decodes back unchanged
+	decoded, err := DecodeLog(log)
+	if err != nil {
+		b.Fatalf("failed to decode event: %v", err)
+	}
+	if !reflect.DeepEqual(reflect.ValueOf(decoded).Elem().Interface(), event) {
+		b.Fatalf("decoded %+v, expected %+v", decoded, event)
+	}
+	return log
+}
+
+// tradeTransactionLogs returns the logs of a typical buy on a bonding curve
+func tradeTransactionLogs(b *testing.B) []string {
+	return []string{
+		"Program ComputeBudget111111111111111111111111111111 invoke [1]",
+		"Program ComputeBudget111111111111111111111111111111 success",
+		"Program " + ProgramID + " invoke [1]",
+		"Program log: Instruction: Buy",
+		"Program 11111111111111111111111111111111 invoke [2]",
+		"Program 11111111111111111111111111111111 success",
+		programDataLog(b, TradeDiscriminator, TradeEvent{
+			Mint:                 solana.NewWallet().PublicKey(),
+			SolAmount:            1_500_000_000,
+			TokenAmount:          52_000_000_000_000,
+			IsBuy:                true,
+			User:                 solana.NewWallet().PublicKey(),
+			Timestamp:            1_760_000_000,
+			VirtualSolReserves:   31_500_000_000,
+			VirtualTokenReserves: 1_020_000_000_000_000,
+			RealSolReserves:      1_500_000_000,
+			RealTokenReserves:    741_000_000_000_000,
+		}),
+		"Program " + ProgramID + " consumed 41234 of 200000 compute units",
+		"Program " + ProgramID + " success",
+	}
+}
+
+// BenchmarkDecodeLog decodes every log of a trade transaction, most of which
+// carry no event
+func BenchmarkDecodeLog(b *testing.B) {
+	logs := tradeTransactionLogs(b)
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, log := range logs {
+			if _, err := DecodeLog(log); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkDecodeLogCreate decodes the log of a token creation
+func BenchmarkDecodeLogCreate(b *testing.B) {
+	log := programDataLog(b, CreateDiscriminator, CreateEvent{
+		Name:         "Example Token",
+		Symbol:       "EXMPL",
+		Uri:          "https://ipfs.io/ipfs/QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG",
+		Mint:         solana.NewWallet().PublicKey(),
+		BondingCurve: solana.NewWallet().PublicKey(),
+		User:         solana.NewWallet().PublicKey(),
+	})
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := DecodeLog(log); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkDecodeLogUnknown decodes the log of an event of another program,
+// which is dropped
+func BenchmarkDecodeLogUnknown(b *testing.B) {
+	log := encodeProgramData(b, []byte{1, 2, 3, 4, 5, 6, 7, 8}, TradeEvent{})
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := DecodeLog(log); err != ErrUnknownEvent {
+			b.Fatal(err)
+		}
+	}
+}
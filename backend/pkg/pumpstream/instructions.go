@@ -0,0 +1,90 @@
+package pumpstream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// CreateInstructionDiscriminator identifies the create instruction of the program
+var CreateInstructionDiscriminator = []byte{24, 30, 200, 40, 5, 28, 7, 119}
+
+// Positions of the accounts of the create instruction
+const (
+	createAccountMint         = 0
+	createAccountBondingCurve = 2
+	createAccountUser         = 7
+)
+
+// Seed of the bonding curve account derived from the mint
+var bondingCurveSeed = []byte("bonding-curve")
+
+// ErrUnknownInstruction is returned for instructions of the program that are
+// not decoded by this package
+var ErrUnknownInstruction = errors.New("unknown instruction discriminator")
+
+// DecodeInstruction decodes an instruction of the program into the event it
+// emits once executed, for sources that see transactions before they run,
+// such as shreds. Only token creations are decoded: the outcome of a trade is
+// not known until it executes.
+//
+// Parameters:
+//   - program: Address of the program, deriving the bonding curve if missing
+//   - data: Instruction data starting with the discriminator
+//   - accounts: Accounts of the instruction, in order; zero keys for accounts
+//     that could not be resolved, e.g. loaded from an address lookup table
+//
+// Returns:
+//   - interface{}: *CreateEvent
+//   - error: ErrUnknownInstruction for other instructions, or a decoding error
+func DecodeInstruction(program solana.PublicKey, data []byte, accounts []solana.PublicKey) (interface{}, error) {
+	if !bytes.HasPrefix(data, CreateInstructionDiscriminator) {
+		return nil, ErrUnknownInstruction
+	}
+	if len(accounts) <= createAccountUser {
+		return nil, errors.New("create instruction has too few accounts")
+	}
+
+	r := eventReader{data: data[len(CreateInstructionDiscriminator):]}
+	event := &CreateEvent{
+		Name:         r.string(),
+		Symbol:       r.string(),
+		Uri:          r.string(),
+		Mint:         accounts[createAccountMint],
+		BondingCurve: accounts[createAccountBondingCurve],
+		User:         accounts[createAccountUser],
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode create instruction: %w", r.err)
+	}
+
+	// The mint and the user sign, so only the bonding curve may be missing
+	if event.BondingCurve.IsZero() {
+		bondingCurve, err := BondingCurveAddress(program, event.Mint)
+		if err != nil {
+			return nil, err
+		}
+		event.BondingCurve = bondingCurve
+	}
+	return event, nil
+}
+
+// BondingCurveAddress derives the bonding curve account of a mint for a program
+func BondingCurveAddress(program, mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{bondingCurveSeed, mint[:]}, program)
+	return address, err
+}
+
+// AssociatedBondingCurveAddress derives the token account of a bonding curve,
+// which holds the tokens not sold yet
+func AssociatedBondingCurveAddress(bondingCurve, mint solana.PublicKey) (solana.PublicKey, error) {
+	return AssociatedTokenAddress(bondingCurve, mint)
+}
+
+// AssociatedTokenAddress derives the associated token account of a wallet for a mint
+func AssociatedTokenAddress(wallet, mint solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindAssociatedTokenAddress(wallet, mint)
+	return address, err
+}
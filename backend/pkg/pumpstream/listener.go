@@ -0,0 +1,190 @@
+package pumpstream
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// Configuration constants
+const (
+	// DefaultReconnectDelay is used when Listener.ReconnectDelay is zero
+	DefaultReconnectDelay = 2 * time.Second
+)
+
+// Notification holds the logs of one successful transaction mentioning the program
+type Notification struct {
+	Signature  string    `json:"signature"`   // Transaction signature
+	Slot       uint64    `json:"slot"`        // Slot the transaction was observed in
+	Logs       []string  `json:"logs"`        // Program log messages of the transaction
+	ReceivedAt time.Time `json:"received_at"` // Time the notification was received
+}
+
+// Listener subscribes to the logs of the program over a Solana WebSocket RPC
+// endpoint and reconnects automatically
+// The hooks are optional and are called from the goroutine running Run
+type Listener struct {
+	URL            string             // WebSocket RPC endpoint
+	Program        solana.PublicKey   // Program whose logs are subscribed to, Program when zero; use SetProgram once running
+	Commitment     rpc.CommitmentType // Subscription commitment, processed when empty
+	ReconnectDelay time.Duration      // Delay before reconnecting, DefaultReconnectDelay when zero
+
+	OnConnect    func()      // Called once the subscription is established
+	OnDisconnect func(error) // Called with the error that ended a connection
+	OnMessage    func()      // Called for every notification, including failed transactions
+
+	mutex   sync.Mutex
+	changed chan struct{} // Closed when SetProgram changes the program
+}
+
+// Run subscribes and hands every notification of a successful transaction to
+// handle until ctx is cancelled
+//
+// Parameters:
+//   - ctx: Context stopping the listener when cancelled
+//   - handle: Function receiving each notification
+//
+// Returns:
+//   - error: The context error once ctx is cancelled
+func (l *Listener) Run(ctx context.Context, handle func(Notification)) error {
+	delay := l.ReconnectDelay
+	if delay == 0 {
+		delay = DefaultReconnectDelay
+	}
+
+	for {
+		err := l.connectAndListen(ctx, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if l.OnDisconnect != nil {
+			l.OnDisconnect(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// SetProgram changes the program whose logs are subscribed to; a running
+// listener subscribes to the new program on its connection, then drops the
+// previous subscription, so the connection is kept and no log is missed
+func (l *Listener) SetProgram(program solana.PublicKey) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.Program = program
+	if l.changed != nil {
+		close(l.changed)
+		l.changed = nil
+	}
+}
+
+// subscription returns the program to subscribe to, and a channel closed when
+// SetProgram changes it
+func (l *Listener) subscription() (solana.PublicKey, <-chan struct{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.changed == nil {
+		l.changed = make(chan struct{})
+	}
+	program := l.Program
+	if program.IsZero() {
+		program = Program
+	}
+	return program, l.changed
+}
+
+// connectAndListen establishes a WebSocket connection and listens for program
+// logs, subscribing again on the same connection whenever the program changes
+func (l *Listener) connectAndListen(ctx context.Context, handle func(Notification)) error {
+	socket, err := ws.Connect(ctx, l.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+	defer socket.Close()
+
+	commitment := l.Commitment
+	if commitment == "" {
+		commitment = rpc.CommitmentProcessed
+	}
+
+	var sub *ws.LogSubscription
+	defer func() {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+	}()
+	for {
+		// Logs of every transaction mentioning the program are delivered, whatever
+		// event they carry; DecodeLog tells the events apart by their discriminator
+		program, changed := l.subscription()
+		next, err := socket.LogsSubscribeMentions(program, commitment)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to logs: %w", err)
+		}
+		if sub == nil {
+			if l.OnConnect != nil {
+				l.OnConnect()
+			}
+		} else {
+			sub.Unsubscribe()
+		}
+		sub = next
+
+		if err := l.listen(ctx, sub, changed, handle); err != nil {
+			return err
+		}
+	}
+}
+
+// listen hands the notifications of a subscription to handle until the
+// connection fails, ctx is cancelled, or changed is closed
+//
+// Returns:
+//   - error: The error ending the subscription, nil when the program changed
+func (l *Listener) listen(ctx context.Context, sub *ws.LogSubscription, changed <-chan struct{}, handle func(Notification)) error {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-changed:
+			cancel()
+		case <-subCtx.Done():
+		}
+	}()
+
+	for {
+		message, err := sub.Recv(subCtx)
+		if err != nil {
+			if ctx.Err() == nil && subCtx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("error receiving message: %w", err)
+		}
+		if l.OnMessage != nil {
+			l.OnMessage()
+		}
+
+		// Failed transactions did not change any state, skip them
+		if message.Value.Err != nil {
+			continue
+		}
+
+		handle(Notification{
+			Signature:  message.Value.Signature.String(),
+			Slot:       message.Context.Slot,
+			Logs:       message.Value.Logs,
+			ReceivedAt: time.Now().UTC(),
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package pumpstream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/websocket"
+)
+
+// rpcRequest is a JSON-RPC request sent by the listener
+type rpcRequest struct {
+	ID     uint64            `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// TestListenerSetProgram checks that changing the program of a running
+// listener subscribes to the new program on the same connection before the
+// previous subscription is dropped
+func TestListenerSetProgram(t *testing.T) {
+	devnet := solana.MustPublicKeyFromBase58("5tzFkiKscXHK5ZXCGbXZxdw7gTjjD1mBwuoFbhUvuAi9")
+
+	var connections atomic.Int32
+	requests := make(chan rpcRequest, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		connections.Add(1)
+
+		for subscription := 1; ; {
+			var request rpcRequest
+			if err := conn.ReadJSON(&request); err != nil {
+				return
+			}
+			requests <- request
+			if request.Method != "logsSubscribe" {
+				conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": true})
+				continue
+			}
+			conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": subscription})
+			conn.WriteJSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "logsNotification",
+				"params": map[string]interface{}{
+					"subscription": subscription,
+					"result": map[string]interface{}{
+						"context": map[string]interface{}{"slot": subscription},
+						"value":   map[string]interface{}{"signature": "1111111111111111111111111111111111111111111111111111111111111111", "err": nil, "logs": []string{}},
+					},
+				},
+			})
+			subscription++
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slots := make(chan uint64, 10)
+	listener := &Listener{URL: "ws" + strings.TrimPrefix(server.URL, "http")}
+	go listener.Run(ctx, func(notification Notification) { slots <- notification.Slot })
+
+	expectSubscription := func(program solana.PublicKey, slot uint64) {
+		t.Helper()
+		select {
+		case request := <-requests:
+			if request.Method != "logsSubscribe" || !strings.Contains(string(request.Params[0]), program.String()) {
+				t.Fatalf("got %s %s, expected a subscription to %s", request.Method, request.Params, program)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no subscription to %s", program)
+		}
+		select {
+		case got := <-slots:
+			if got != slot {
+				t.Fatalf("got a notification of slot %d, expected %d", got, slot)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no notification from the subscription to %s", program)
+		}
+	}
+
+	expectSubscription(Program, 1)
+	listener.SetProgram(devnet)
+	expectSubscription(devnet, 2)
+
+	select {
+	case request := <-requests:
+		if request.Method != "logsUnsubscribe" || string(request.Params[0]) != "1" {
+			t.Fatalf("got %s %s, expected the first subscription dropped", request.Method, request.Params)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the first subscription was not dropped")
+	}
+	if got := connections.Load(); got != 1 {
+		t.Fatalf("got %d connections, expected 1", got)
+	}
+}
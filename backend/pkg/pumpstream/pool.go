@@ -0,0 +1,72 @@
+package pumpstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// SwapProgram is the PumpSwap AMM program graduated tokens migrate to
+var SwapProgram = solana.MustPublicKeyFromBase58("pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA")
+
+// PoolDiscriminator is the discriminator of PumpSwap pool accounts
+var PoolDiscriminator = []byte{241, 154, 109, 4, 17, 177, 109, 188}
+
+// Seeds of the accounts involved in a migration
+var (
+	poolSeed          = []byte("pool")
+	poolAuthoritySeed = []byte("pool-authority")
+)
+
+// PoolAccount is the state of a PumpSwap pool account
+type PoolAccount struct {
+	Index                 uint16
+	Creator               solana.PublicKey
+	BaseMint              solana.PublicKey
+	QuoteMint             solana.PublicKey
+	LPMint                solana.PublicKey
+	PoolBaseTokenAccount  solana.PublicKey
+	PoolQuoteTokenAccount solana.PublicKey
+	LPSupply              uint64 // LP tokens minted at deposit, burns not deducted
+}
+
+// DecodePoolAccount decodes the data of a PumpSwap pool account
+func DecodePoolAccount(data []byte) (*PoolAccount, error) {
+	if !bytes.HasPrefix(data, PoolDiscriminator) {
+		return nil, errors.New("not a pool account")
+	}
+
+	r := eventReader{data: data[len(PoolDiscriminator):]}
+	r.take(1) // Bump
+	account := &PoolAccount{}
+	if index := r.take(2); index != nil {
+		account.Index = binary.LittleEndian.Uint16(index)
+	}
+	account.Creator = r.publicKey()
+	account.BaseMint = r.publicKey()
+	account.QuoteMint = r.publicKey()
+	account.LPMint = r.publicKey()
+	account.PoolBaseTokenAccount = r.publicKey()
+	account.PoolQuoteTokenAccount = r.publicKey()
+	account.LPSupply = r.uint64()
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode pool account: %w", r.err)
+	}
+	return account, nil
+}
+
+// MigrationPoolAddress derives the canonical PumpSwap pool the migration of
+// a completed bonding curve creates: index 0, created by the pool authority
+// of the mint for the bonding curve program, paired with wrapped SOL
+func MigrationPoolAddress(program, mint solana.PublicKey) (solana.PublicKey, error) {
+	authority, _, err := solana.FindProgramAddress([][]byte{poolAuthoritySeed, mint[:]}, program)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+	index := []byte{0, 0}
+	address, _, err := solana.FindProgramAddress([][]byte{poolSeed, index, authority[:], mint[:], solana.WrappedSol[:]}, SwapProgram)
+	return address, err
+}
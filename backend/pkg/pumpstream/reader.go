@@ -0,0 +1,121 @@
+package pumpstream
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// eventReader reads the Borsh-encoded fields of an event in order, straight
+// from the decoded bytes. The first failure is kept and every later read
+// returns a zero value, so a decoder checks err once after its last field.
+type eventReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// take returns the next n bytes, or nil once the data is exhausted
+func (r *eventReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data)-r.pos < n {
+		r.err = fmt.Errorf("need %d bytes at offset %d, %d remaining", n, r.pos, len(r.data)-r.pos)
+		return nil
+	}
+	field := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return field
+}
+
+// publicKey reads a 32-byte public key
+func (r *eventReader) publicKey() solana.PublicKey {
+	var key solana.PublicKey
+	copy(key[:], r.take(solana.PublicKeyLength))
+	return key
+}
+
+// uint64 reads a little-endian u64
+func (r *eventReader) uint64() uint64 {
+	field := r.take(8)
+	if field == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(field)
+}
+
+// int64 reads a little-endian i64
+func (r *eventReader) int64() int64 {
+	return int64(r.uint64())
+}
+
+// bool reads a one-byte boolean, true unless zero
+func (r *eventReader) bool() bool {
+	field := r.take(1)
+	return field != nil && field[0] != 0
+}
+
+// string reads a string prefixed with its u32 little-endian length, copying
+// it so the decoded bytes can be reused
+func (r *eventReader) string() string {
+	length := r.take(4)
+	if length == nil {
+		return ""
+	}
+	return string(r.take(int(binary.LittleEndian.Uint32(length))))
+}
+
+// decodeCreateEvent decodes the fields of a CreateEvent, in declaration order
+func decodeCreateEvent(payload []byte) (*CreateEvent, error) {
+	r := eventReader{data: payload}
+	event := &CreateEvent{
+		Name:         r.string(),
+		Symbol:       r.string(),
+		Uri:          r.string(),
+		Mint:         r.publicKey(),
+		BondingCurve: r.publicKey(),
+		User:         r.publicKey(),
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode create event: %w", r.err)
+	}
+	return event, nil
+}
+
+// decodeTradeEvent decodes the fields of a TradeEvent, in declaration order
+func decodeTradeEvent(payload []byte) (*TradeEvent, error) {
+	r := eventReader{data: payload}
+	event := &TradeEvent{
+		Mint:                 r.publicKey(),
+		SolAmount:            r.uint64(),
+		TokenAmount:          r.uint64(),
+		IsBuy:                r.bool(),
+		User:                 r.publicKey(),
+		Timestamp:            r.int64(),
+		VirtualSolReserves:   r.uint64(),
+		VirtualTokenReserves: r.uint64(),
+		RealSolReserves:      r.uint64(),
+		RealTokenReserves:    r.uint64(),
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode trade event: %w", r.err)
+	}
+	return event, nil
+}
+
+// decodeCompleteEvent decodes the fields of a CompleteEvent, in declaration order
+func decodeCompleteEvent(payload []byte) (*CompleteEvent, error) {
+	r := eventReader{data: payload}
+	event := &CompleteEvent{
+		User:         r.publicKey(),
+		Mint:         r.publicKey(),
+		BondingCurve: r.publicKey(),
+		Timestamp:    r.int64(),
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode complete event: %w", r.err)
+	}
+	return event, nil
+}
@@ -0,0 +1,196 @@
+package pumpstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Discriminators of the trade instructions and of the bonding curve account
+var (
+	BuyInstructionDiscriminator  = []byte{102, 6, 61, 18, 1, 218, 235, 234}
+	SellInstructionDiscriminator = []byte{51, 230, 133, 164, 1, 127, 131, 173}
+	BondingCurveDiscriminator    = []byte{23, 183, 248, 55, 96, 216, 172, 96}
+)
+
+// FeeRecipient is the account receiving the trading fees of the program
+var FeeRecipient = solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM")
+
+// Seeds of the accounts of the program that do not depend on the mint
+var (
+	globalSeed         = []byte("global")
+	eventAuthoritySeed = []byte("__event_authority")
+)
+
+// ErrCurveComplete is returned when quoting a trade on a completed bonding
+// curve, whose token now trades elsewhere
+var ErrCurveComplete = errors.New("bonding curve is complete")
+
+// BondingCurveAccount is the state of a bonding curve account
+type BondingCurveAccount struct {
+	VirtualTokenReserves uint64
+	VirtualSolReserves   uint64
+	RealTokenReserves    uint64
+	RealSolReserves      uint64
+	TokenTotalSupply     uint64
+	Complete             bool
+}
+
+// DecodeBondingCurveAccount decodes the data of a bonding curve account
+func DecodeBondingCurveAccount(data []byte) (*BondingCurveAccount, error) {
+	if !bytes.HasPrefix(data, BondingCurveDiscriminator) {
+		return nil, errors.New("not a bonding curve account")
+	}
+
+	r := eventReader{data: data[len(BondingCurveDiscriminator):]}
+	account := &BondingCurveAccount{
+		VirtualTokenReserves: r.uint64(),
+		VirtualSolReserves:   r.uint64(),
+		RealTokenReserves:    r.uint64(),
+		RealSolReserves:      r.uint64(),
+		TokenTotalSupply:     r.uint64(),
+		Complete:             r.bool(),
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode bonding curve account: %w", r.err)
+	}
+	return account, nil
+}
+
+// BuyQuote returns the tokens a buy spending solAmount lamports receives,
+// fee included, on the constant product curve
+//
+// Parameters:
+//   - solAmount: Lamports spent, fee included
+//   - feeBasisPoints: Trading fee of the program, charged on top of the SOL swapped
+//
+// Returns:
+//   - uint64: Token base units received, at most the real token reserves
+//   - error: ErrCurveComplete once the curve completed
+func (c *BondingCurveAccount) BuyQuote(solAmount, feeBasisPoints uint64) (uint64, error) {
+	if c.Complete {
+		return 0, ErrCurveComplete
+	}
+
+	// solAmount = swapped + swapped * fee / 10000
+	swapped := MulDiv(solAmount, 10_000, 10_000+feeBasisPoints)
+	tokens := MulDiv(c.VirtualTokenReserves, swapped, c.VirtualSolReserves+swapped)
+	return min(tokens, c.RealTokenReserves), nil
+}
+
+// SellQuote returns the lamports a sell of tokenAmount receives, after the fee
+//
+// Parameters:
+//   - tokenAmount: Token base units sold
+//   - feeBasisPoints: Trading fee of the program, deducted from the SOL received
+//
+// Returns:
+//   - uint64: Lamports received, at most the real SOL reserves before the fee
+//   - error: ErrCurveComplete once the curve completed
+func (c *BondingCurveAccount) SellQuote(tokenAmount, feeBasisPoints uint64) (uint64, error) {
+	if c.Complete {
+		return 0, ErrCurveComplete
+	}
+
+	// The virtual reserves price the sell, but only the real ones can be paid out
+	lamports := min(MulDiv(c.VirtualSolReserves, tokenAmount, c.VirtualTokenReserves+tokenAmount), c.RealSolReserves)
+	return lamports - MulDiv(lamports, feeBasisPoints, 10_000), nil
+}
+
+// MulDiv returns a * b / c without overflowing the intermediate product
+func MulDiv(a, b, c uint64) uint64 {
+	if c == 0 {
+		return 0
+	}
+	product := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return product.Div(product, new(big.Int).SetUint64(c)).Uint64()
+}
+
+// GlobalAddress derives the global configuration account of a program
+func GlobalAddress(program solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{globalSeed}, program)
+	return address, err
+}
+
+// EventAuthorityAddress derives the account a program emits its events with
+func EventAuthorityAddress(program solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress([][]byte{eventAuthoritySeed}, program)
+	return address, err
+}
+
+// NewBuyInstruction builds a buy of tokenAmount on the bonding curve of mint
+// The token account of the user must exist when the instruction runs.
+//
+// Parameters:
+//   - program: Address of the bonding curve program
+//   - mint: Mint of the token
+//   - user: Wallet paying and receiving the tokens, the signer
+//   - tokenAmount: Token base units bought
+//   - maxSolCost: Most lamports the buy may cost, fee included
+func NewBuyInstruction(program, mint, user solana.PublicKey, tokenAmount, maxSolCost uint64) (solana.Instruction, error) {
+	return newTradeInstruction(BuyInstructionDiscriminator, program, mint, user, tokenAmount, maxSolCost, false)
+}
+
+// NewSellInstruction builds a sell of tokenAmount on the bonding curve of mint
+//
+// Parameters:
+//   - program: Address of the bonding curve program
+//   - mint: Mint of the token
+//   - user: Wallet selling the tokens and receiving the SOL, the signer
+//   - tokenAmount: Token base units sold
+//   - minSolOutput: Fewest lamports the sell may return, after the fee
+func NewSellInstruction(program, mint, user solana.PublicKey, tokenAmount, minSolOutput uint64) (solana.Instruction, error) {
+	return newTradeInstruction(SellInstructionDiscriminator, program, mint, user, tokenAmount, minSolOutput, true)
+}
+
+// newTradeInstruction builds a buy or a sell, whose accounts only differ in
+// the sell passing the associated token program instead of the rent sysvar
+func newTradeInstruction(discriminator []byte, program, mint, user solana.PublicKey, tokenAmount, solLimit uint64, sell bool) (solana.Instruction, error) {
+	global, err := GlobalAddress(program)
+	if err != nil {
+		return nil, err
+	}
+	eventAuthority, err := EventAuthorityAddress(program)
+	if err != nil {
+		return nil, err
+	}
+	bondingCurve, err := BondingCurveAddress(program, mint)
+	if err != nil {
+		return nil, err
+	}
+	associatedBondingCurve, err := AssociatedBondingCurveAddress(bondingCurve, mint)
+	if err != nil {
+		return nil, err
+	}
+	associatedUser, err := AssociatedTokenAddress(user, mint)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(global),
+		solana.Meta(FeeRecipient).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(bondingCurve).WRITE(),
+		solana.Meta(associatedBondingCurve).WRITE(),
+		solana.Meta(associatedUser).WRITE(),
+		solana.Meta(user).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	}
+	if sell {
+		accounts = append(accounts, solana.Meta(solana.SPLAssociatedTokenAccountProgramID), solana.Meta(solana.TokenProgramID))
+	} else {
+		accounts = append(accounts, solana.Meta(solana.TokenProgramID), solana.Meta(solana.SysVarRentPubkey))
+	}
+	accounts = append(accounts, solana.Meta(eventAuthority), solana.Meta(program))
+
+	data := make([]byte, 0, len(discriminator)+16)
+	data = append(data, discriminator...)
+	data = binary.LittleEndian.AppendUint64(data, tokenAmount)
+	data = binary.LittleEndian.AppendUint64(data, solLimit)
+	return solana.NewInstruction(program, accounts, data), nil
+}
@@ -0,0 +1,224 @@
+package pumpstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Reserves every mainnet bonding curve starts from, set by the global account
+// of the program
+const (
+	launchVirtualTokenReserves = 1_073_000_000_000_000
+	launchVirtualSolReserves   = 30_000_000_000
+	launchRealTokenReserves    = 793_100_000_000_000
+	launchTokenTotalSupply     = 1_000_000_000_000_000
+
+	// Trading fee of the program on mainnet, in basis points
+	mainnetFeeBasisPoints = 100
+)
+
+// launchCurve returns a bonding curve as created on mainnet, before any trade
+func launchCurve() BondingCurveAccount {
+	return BondingCurveAccount{
+		VirtualTokenReserves: launchVirtualTokenReserves,
+		VirtualSolReserves:   launchVirtualSolReserves,
+		RealTokenReserves:    launchRealTokenReserves,
+		TokenTotalSupply:     launchTokenTotalSupply,
+	}
+}
+
+// boughtCurve returns the launch curve after the golden 1 SOL buy, as the
+// program leaves it: the SOL swapped and the tokens bought moved in the reserves
+func boughtCurve() BondingCurveAccount {
+	curve := launchCurve()
+	curve.VirtualTokenReserves -= 34_281_150_129_545
+	curve.RealTokenReserves -= 34_281_150_129_545
+	curve.VirtualSolReserves += 990_099_009
+	curve.RealSolReserves += 990_099_009
+	return curve
+}
+
+// TestBuyQuote checks buy quotes against the constant product of the program,
+// computed independently with integer arithmetic
+func TestBuyQuote(t *testing.T) {
+	tests := []struct {
+		name      string
+		curve     BondingCurveAccount
+		solAmount uint64
+		expected  uint64
+	}{
+		// 990_099_009 lamports swapped after the fee
+		{name: "1 SOL at launch", curve: launchCurve(), solAmount: 1_000_000_000, expected: 34_281_150_129_545},
+		{name: "1 SOL after a buy", curve: boughtCurve(), solAmount: 1_000_000_000, expected: 32_158_478_296_710},
+		{name: "dust", curve: launchCurve(), solAmount: 1, expected: 0},
+		// The curve would give 823_484_267_075_787 tokens, more than it holds
+		{name: "more than the real reserves", curve: launchCurve(), solAmount: 100_000_000_000, expected: launchRealTokenReserves},
+		// The intermediate product overflows 64 bits
+		{name: "largest amount", curve: launchCurve(), solAmount: math.MaxUint64, expected: launchRealTokenReserves},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, err := test.curve.BuyQuote(test.solAmount, mainnetFeeBasisPoints)
+			if err != nil {
+				t.Fatalf("failed to quote: %v", err)
+			}
+			if tokens != test.expected {
+				t.Fatalf("quoted %d tokens, expected %d", tokens, test.expected)
+			}
+		})
+	}
+}
+
+// TestSellQuote checks sell quotes against the constant product of the
+// program, computed independently with integer arithmetic
+func TestSellQuote(t *testing.T) {
+	tests := []struct {
+		name        string
+		curve       BondingCurveAccount
+		tokenAmount uint64
+		expected    uint64
+	}{
+		// 990_099_008 lamports before the fee: the buy rounded down twice
+		{name: "everything bought", curve: boughtCurve(), tokenAmount: 34_281_150_129_545, expected: 980_198_018},
+		{name: "half of it", curve: boughtCurve(), tokenAmount: 17_140_575_064_772, expected: 498_055_162},
+		// The virtual reserves price 15_200_771_313 lamports, the curve holds 990_099_009
+		{name: "more than the real reserves", curve: boughtCurve(), tokenAmount: launchTokenTotalSupply, expected: 980_198_019},
+		{name: "nothing bought yet", curve: launchCurve(), tokenAmount: 1_000_000_000_000, expected: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lamports, err := test.curve.SellQuote(test.tokenAmount, mainnetFeeBasisPoints)
+			if err != nil {
+				t.Fatalf("failed to quote: %v", err)
+			}
+			if lamports != test.expected {
+				t.Fatalf("quoted %d lamports, expected %d", lamports, test.expected)
+			}
+		})
+	}
+}
+
+// TestQuoteCompleteCurve checks that completed curves are not quoted
+func TestQuoteCompleteCurve(t *testing.T) {
+	curve := boughtCurve()
+	curve.Complete = true
+	if _, err := curve.BuyQuote(1_000_000_000, mainnetFeeBasisPoints); !errors.Is(err, ErrCurveComplete) {
+		t.Fatalf("buy quote error %v, expected %v", err, ErrCurveComplete)
+	}
+	if _, err := curve.SellQuote(1_000_000, mainnetFeeBasisPoints); !errors.Is(err, ErrCurveComplete) {
+		t.Fatalf("sell quote error %v, expected %v", err, ErrCurveComplete)
+	}
+}
+
+// tradeAccount is an expected account of a trade instruction
+type tradeAccount struct {
+	name     string
+	key      solana.PublicKey
+	writable bool
+	signer   bool
+}
+
+// TestTradeInstructionAccounts checks the accounts of buys and sells against
+// the order the program expects, with the mainnet addresses of the accounts
+// that do not depend on the mint
+func TestTradeInstructionAccounts(t *testing.T) {
+	mint := solana.MustPublicKeyFromBase58("2zMMhcVQEXDtdE6vsFS7S7D5oUodfJHE8vd1gnBouauv")
+	user := solana.MustPublicKeyFromBase58("5tzFkiKscXHK5ZXCGbXZxdw7gTjjD1mBwuoFbhUvuAi9")
+
+	bondingCurve, err := BondingCurveAddress(Program, mint)
+	if err != nil {
+		t.Fatalf("failed to derive bonding curve: %v", err)
+	}
+	associatedBondingCurve, err := AssociatedBondingCurveAddress(bondingCurve, mint)
+	if err != nil {
+		t.Fatalf("failed to derive associated bonding curve: %v", err)
+	}
+	associatedUser, err := AssociatedTokenAddress(user, mint)
+	if err != nil {
+		t.Fatalf("failed to derive associated user: %v", err)
+	}
+
+	leading := []tradeAccount{
+		{name: "global", key: solana.MustPublicKeyFromBase58("4wTV1YmiEkRvAtNtsSGPtUrqRYQMe5SKy2uB4Jjaxnjf")},
+		{name: "fee recipient", key: solana.MustPublicKeyFromBase58("CebN5WGQ4jvEPvsVU4EoHEpgzq1VV7AbicfhtW4xC9iM"), writable: true},
+		{name: "mint", key: mint},
+		{name: "bonding curve", key: bondingCurve, writable: true},
+		{name: "associated bonding curve", key: associatedBondingCurve, writable: true},
+		{name: "associated user", key: associatedUser, writable: true},
+		{name: "user", key: user, writable: true, signer: true},
+		{name: "system program", key: solana.MustPublicKeyFromBase58("11111111111111111111111111111111")},
+	}
+	trailing := []tradeAccount{
+		{name: "event authority", key: solana.MustPublicKeyFromBase58("Ce6TQqeHC9p8KetsN6JsjHK7UTZk7nasjjnr7XxXp9F1")},
+		{name: "program", key: solana.MustPublicKeyFromBase58("6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P")},
+	}
+	tokenProgram := tradeAccount{name: "token program", key: solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")}
+
+	tests := []struct {
+		name          string
+		build         func() (solana.Instruction, error)
+		discriminator []byte
+		accounts      []tradeAccount
+	}{
+		{
+			name: "buy",
+			build: func() (solana.Instruction, error) {
+				return NewBuyInstruction(Program, mint, user, 34_281_150_129_545, 1_010_000_000)
+			},
+			discriminator: []byte{102, 6, 61, 18, 1, 218, 235, 234},
+			accounts: append(append(append([]tradeAccount{}, leading...),
+				tokenProgram,
+				tradeAccount{name: "rent sysvar", key: solana.MustPublicKeyFromBase58("SysvarRent111111111111111111111111111111111")},
+			), trailing...),
+		},
+		{
+			name: "sell",
+			build: func() (solana.Instruction, error) {
+				return NewSellInstruction(Program, mint, user, 34_281_150_129_545, 1_010_000_000)
+			},
+			discriminator: []byte{51, 230, 133, 164, 1, 127, 131, 173},
+			accounts: append(append(append([]tradeAccount{}, leading...),
+				tradeAccount{name: "associated token program", key: solana.MustPublicKeyFromBase58("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL")},
+				tokenProgram,
+			), trailing...),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instruction, err := test.build()
+			if err != nil {
+				t.Fatalf("failed to build: %v", err)
+			}
+			if instruction.ProgramID() != Program {
+				t.Fatalf("program %s, expected %s", instruction.ProgramID(), Program)
+			}
+
+			accounts := instruction.Accounts()
+			if len(accounts) != len(test.accounts) {
+				t.Fatalf("%d accounts, expected %d", len(accounts), len(test.accounts))
+			}
+			for i, expected := range test.accounts {
+				got := accounts[i]
+				if !got.PublicKey.Equals(expected.key) || got.IsWritable != expected.writable || got.IsSigner != expected.signer {
+					t.Fatalf("account %d is %s (writable %v, signer %v), expected the %s %s (writable %v, signer %v)",
+						i, got.PublicKey, got.IsWritable, got.IsSigner, expected.name, expected.key, expected.writable, expected.signer)
+				}
+			}
+
+			data, err := instruction.Data()
+			if err != nil {
+				t.Fatalf("failed to encode data: %v", err)
+			}
+			expected := binary.LittleEndian.AppendUint64(append([]byte{}, test.discriminator...), 34_281_150_129_545)
+			expected = binary.LittleEndian.AppendUint64(expected, 1_010_000_000)
+			if !bytes.Equal(data, expected) {
+				t.Fatalf("data %v, expected %v", data, expected)
+			}
+		})
+	}
+}
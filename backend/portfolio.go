@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the comma-separated wallets whose positions
+	// are tracked from the trade stream; the wallet and the paper trading
+	// account are tracked whenever they are enabled
+	portfolioWalletsEnv = "PORTFOLIO_WALLETS"
+
+	// REST endpoints listing the tracked accounts and returning one portfolio
+	portfoliosEndpoint = "/api/portfolios"
+	portfolioEndpoint  = "/api/portfolios/{account}"
+
+	// Account name of the paper trader
+	paperPortfolioAccount = "paper"
+
+	// Shortest interval between two portfolio updates pushed to a WebSocket
+	// client; trades on held tokens move the unrealized P&L all the time
+	portfolioPushInterval = time.Second
+)
+
+// Position represents the holding of an account in one token
+//
+// Cost basis is tracked at average cost: a sale realizes the difference
+// between its proceeds and the share of the cost basis of the tokens sold.
+// Tokens sold beyond the tracked holding, bought before tracking started,
+// count with no cost.
+type Position struct {
+	Mint          string    `json:"mint"`           // Token mint address
+	TokenAmount   uint64    `json:"token_amount"`   // Token base units held
+	CostBasis     uint64    `json:"cost_basis"`     // Lamports paid for the tokens held
+	Value         uint64    `json:"value"`          // Lamports a sale of the holding would return on the latest curve state
+	RealizedPnL   int64     `json:"realized_pnl"`   // Lamports gained or lost by sales
+	UnrealizedPnL int64     `json:"unrealized_pnl"` // Value minus cost basis
+	Buys          int       `json:"buys"`           // Buys recorded
+	Sells         int       `json:"sells"`          // Sells recorded
+	UpdatedAt     time.Time `json:"updated_at"`     // Time of the latest trade of the account in the token
+}
+
+// Portfolio represents the positions of an account, valued on the latest curve states
+type Portfolio struct {
+	Account       string     `json:"account"`        // Wallet address, or paper for the paper trader
+	Positions     []Position `json:"positions"`      // Positions, latest trade first; closed ones keep their realized P&L
+	Value         uint64     `json:"value"`          // Total value of the holdings, in lamports
+	CostBasis     uint64     `json:"cost_basis"`     // Total cost basis of the holdings, in lamports
+	RealizedPnL   int64      `json:"realized_pnl"`   // Total realized P&L, in lamports
+	UnrealizedPnL int64      `json:"unrealized_pnl"` // Total unrealized P&L, in lamports
+}
+
+// portfoliosResponse is the JSON body of the portfolio list endpoint
+type portfoliosResponse struct {
+	Accounts []string `json:"accounts"` // Tracked accounts
+}
+
+// PortfolioTracker keeps the positions of the tracked accounts from the
+// trade stream, and pushes portfolio updates to WebSocket subscribers
+type PortfolioTracker struct {
+	mutex       sync.RWMutex
+	accounts    map[string]map[string]*Position // Positions by account, then mint
+	holders     map[string][]string             // Accounts holding a position, by mint
+	subscribers map[string][]chan struct{}      // Update signals of WebSocket clients, by account
+}
+
+// Portfolios tracks positions when any account is tracked, nil otherwise
+var Portfolios *PortfolioTracker
+
+// setupPortfolios tracks the wallets of PORTFOLIO_WALLETS, the wallet and the
+// paper trading account; it runs after the wallet and auto-buy setups
+//
+// Returns:
+//   - error: Error if PORTFOLIO_WALLETS has an invalid address
+func setupPortfolios() error {
+	var accounts []string
+	for _, wallet := range strings.Split(os.Getenv(portfolioWalletsEnv), ",") {
+		if wallet = strings.TrimSpace(wallet); wallet == "" {
+			continue
+		}
+		if _, err := solana.PublicKeyFromBase58(wallet); err != nil {
+			return fmt.Errorf("invalid wallet %q in %s", wallet, portfolioWalletsEnv)
+		}
+		accounts = append(accounts, wallet)
+	}
+	if HotWallet != nil {
+		accounts = append(accounts, HotWallet.key.PublicKey().String())
+	}
+	if PaperTrades != nil {
+		accounts = append(accounts, paperPortfolioAccount)
+	}
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	tracker := &PortfolioTracker{
+		accounts:    make(map[string]map[string]*Position),
+		holders:     make(map[string][]string),
+		subscribers: make(map[string][]chan struct{}),
+	}
+	for _, account := range accounts {
+		tracker.accounts[account] = make(map[string]*Position)
+	}
+	Portfolios = tracker
+	RegisterSink(tracker)
+
+	slog.Info("Tracking portfolios", "accounts", len(tracker.accounts), "endpoint", portfoliosEndpoint)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (p *PortfolioTracker) Name() string {
+	return "portfolio"
+}
+
+// Publish records the trades of tracked wallets and signals the subscribers
+// of every account holding the traded token
+func (p *PortfolioTracker) Publish(event Event) {
+	if event.Type != EventTrade {
+		return
+	}
+	trade, ok := event.Data.(TradeEvent)
+	if !ok {
+		return
+	}
+
+	p.Record(trade.User, trade.Mint, trade.IsBuy, trade.SolAmount, trade.TokenAmount)
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	for _, account := range p.holders[trade.Mint] {
+		// Record already signaled the trader
+		if account != trade.User {
+			p.signalLocked(account)
+		}
+	}
+}
+
+// Record applies a trade to the position of an account; trades of accounts
+// that are not tracked are ignored
+//
+// Parameters:
+//   - account: Wallet address, or paper for the paper trader
+//   - mint: Token mint address
+//   - isBuy: True for buys, false for sells
+//   - solAmount: Lamports paid by a buy or received by a sell
+//   - tokenAmount: Token base units bought or sold
+func (p *PortfolioTracker) Record(account, mint string, isBuy bool, solAmount, tokenAmount uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	positions, tracked := p.accounts[account]
+	if !tracked {
+		return
+	}
+	position, held := positions[mint]
+	if !held {
+		position = &Position{Mint: mint}
+		positions[mint] = position
+		p.holders[mint] = append(p.holders[mint], account)
+	}
+
+	if isBuy {
+		position.TokenAmount += tokenAmount
+		position.CostBasis += solAmount
+		position.Buys++
+	} else {
+		sold := min(tokenAmount, position.TokenAmount)
+		var cost uint64
+		if position.TokenAmount > 0 {
+			// sold <= TokenAmount, so the quotient fits
+			hi, lo := bits.Mul64(position.CostBasis, sold)
+			cost, _ = bits.Div64(hi, lo, position.TokenAmount)
+		}
+		position.RealizedPnL += int64(solAmount) - int64(cost)
+		position.CostBasis -= cost
+		position.TokenAmount -= sold
+		position.Sells++
+	}
+	position.UpdatedAt = time.Now().UTC()
+	p.signalLocked(account)
+}
+
+// signalLocked wakes the WebSocket subscribers of an account
+// The caller must hold the mutex
+func (p *PortfolioTracker) signalLocked(account string) {
+	for _, signal := range p.subscribers[account] {
+		select {
+		case signal <- struct{}{}:
+		default:
+			// An update is already pending
+		}
+	}
+}
+
+// Portfolio values the positions of an account on the latest curve states
+//
+// Returns:
+//   - Portfolio: The portfolio
+//   - bool: False if the account is not tracked
+func (p *PortfolioTracker) Portfolio(account string) (Portfolio, bool) {
+	p.mutex.RLock()
+	positions, tracked := p.accounts[account]
+	if !tracked {
+		p.mutex.RUnlock()
+		return Portfolio{}, false
+	}
+	copied := make([]Position, 0, len(positions))
+	for _, position := range positions {
+		copied = append(copied, *position)
+	}
+	p.mutex.RUnlock()
+
+	portfolio := Portfolio{Account: account, Positions: copied}
+	for i := range portfolio.Positions {
+		position := &portfolio.Positions[i]
+		if record, found := Tokens.Get(position.Mint); found && position.TokenAmount > 0 {
+			// A completed curve is valued at its last state
+			curve := paperCurve(record)
+			curve.Complete = false
+			position.Value, _ = curve.SellQuote(position.TokenAmount, pumpFeeBasisPoints)
+		}
+		position.UnrealizedPnL = int64(position.Value) - int64(position.CostBasis)
+
+		portfolio.Value += position.Value
+		portfolio.CostBasis += position.CostBasis
+		portfolio.RealizedPnL += position.RealizedPnL
+		portfolio.UnrealizedPnL += position.UnrealizedPnL
+	}
+	slices.SortFunc(portfolio.Positions, func(a, b Position) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return portfolio, true
+}
+
+// Accounts returns the tracked accounts, sorted
+func (p *PortfolioTracker) Accounts() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	accounts := make([]string, 0, len(p.accounts))
+	for account := range p.accounts {
+		accounts = append(accounts, account)
+	}
+	slices.Sort(accounts)
+	return accounts
+}
+
+// Tracks reports whether an account is tracked
+func (p *PortfolioTracker) Tracks(account string) bool {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	_, tracked := p.accounts[account]
+	return tracked
+}
+
+// stream sends the portfolio of an account to a WebSocket client, then an
+// update whenever it changes, at most once per portfolioPushInterval
+//
+// Returns:
+//   - string: Why the connection ended, for the audit log
+func (p *PortfolioTracker) stream(client *Client, account string) string {
+	logger := clientLogger(client.Connection.RemoteAddr().String())
+	logger.Info("Streaming portfolio", "account", account)
+
+	signal := make(chan struct{}, 1)
+	signal <- struct{}{}
+	p.mutex.Lock()
+	p.subscribers[account] = append(p.subscribers[account], signal)
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		p.subscribers[account] = slices.DeleteFunc(p.subscribers[account], func(c chan struct{}) bool { return c == signal })
+		p.mutex.Unlock()
+	}()
+
+	closed := make(chan error, 1)
+	go func() { closed <- client.readLoop() }()
+
+	throttle := time.NewTicker(portfolioPushInterval)
+	defer throttle.Stop()
+	for {
+		select {
+		case err := <-closed:
+			reason := disconnectReason(err)
+			logger.Info("Client disconnected", "reason", reason)
+			return reason
+		case <-signal:
+		}
+
+		portfolio, _ := p.Portfolio(account)
+		message, err := json.Marshal(portfolio)
+		if err != nil {
+			return "failed to encode portfolio"
+		}
+		client.Mutex.Lock()
+		err = client.send(message)
+		client.Mutex.Unlock()
+		if err != nil {
+			logger.Warn("Failed to send portfolio", logKeyError, err)
+			return "write failed"
+		}
+
+		select {
+		case err := <-closed:
+			return disconnectReason(err)
+		case <-throttle.C:
+		}
+	}
+}
+
+// HandleListPortfolios returns the tracked accounts
+func HandleListPortfolios(w http.ResponseWriter, r *http.Request) {
+	if Portfolios == nil {
+		writeError(w, http.StatusNotFound, "portfolio tracking is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, portfoliosResponse{Accounts: Portfolios.Accounts()})
+}
+
+// HandleGetPortfolio returns the positions and P&L of a tracked account
+func HandleGetPortfolio(w http.ResponseWriter, r *http.Request) {
+	if Portfolios == nil {
+		writeError(w, http.StatusNotFound, "portfolio tracking is disabled")
+		return
+	}
+	portfolio, found := Portfolios.Portfolio(mux.Vars(r)["account"])
+	if !found {
+		writeError(w, http.StatusNotFound, "account is not tracked")
+		return
+	}
+	writeJSON(w, http.StatusOK, portfolio)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the TCP address of the private admin listener
+	// serving the admin API, health and profiling endpoints, e.g. 127.0.0.1:6060
+	// or [::1]:6060; the admin API then leaves the public listener
+	// The listener is not started when it is unset; serve --admin-addr overrides it
+	adminAddrEnv = "ADMIN_ADDR"
+
+	// Path prefix of the profiling endpoints on the admin listener
+	pprofEndpoint = "/debug/pprof/"
+
+	// Longest request a profile may take; CPU profiles and traces run for the
+	// seconds the client asks for
+	adminWriteTimeout = 5 * time.Minute
+)
+
+// adminRoutesPrivate is set once the admin listener serves the admin API, which
+// the public listener then leaves out
+var adminRoutesPrivate bool
+
+// startAdminServer serves the admin API, the health endpoints and the
+// net/http/pprof endpoints on a listener of their own, so admin calls, stats
+// and profiles of production instances go through a private network without
+// being exposed next to the public feed
+// Every endpoint but the health endpoints requires an admin bearer token; the
+// profiling endpoints need the operator role, since profiles expose the
+// command line and memory of the process and CPU profiles and traces load it
+//
+// Importing net/http/pprof also registers the handlers on http.DefaultServeMux;
+// the public server routes through its own router and never serves them
+//
+// Parameters:
+//   - addr: TCP address to listen on
+//
+// Returns:
+//   - *http.Server: The running admin server
+//   - error: Error if the listener could not be opened or no admin token is set
+func startAdminServer(addr string) (*http.Server, error) {
+	if !adminEnabled() {
+		return nil, fmt.Errorf("the admin listener requires %s or %s", adminTokenEnv, adminTokensFileEnv)
+	}
+
+	router := mux.NewRouter().StrictSlash(true)
+	registerAdminRoutes(router)
+	registerHealthRoutes(router)
+	router.HandleFunc(pprofEndpoint, requireRole(adminRoleOperator, pprof.Index))
+	router.HandleFunc(pprofEndpoint+"cmdline", requireRole(adminRoleOperator, pprof.Cmdline))
+	router.HandleFunc(pprofEndpoint+"profile", requireRole(adminRoleOperator, pprof.Profile))
+	router.HandleFunc(pprofEndpoint+"symbol", requireRole(adminRoleOperator, pprof.Symbol))
+	router.HandleFunc(pprofEndpoint+"trace", requireRole(adminRoleOperator, pprof.Trace))
+	// Named profiles such as heap and goroutine
+	router.PathPrefix(pprofEndpoint).HandlerFunc(requireRole(adminRoleOperator, pprof.Index))
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	var handler http.Handler = accessLog(recoverPanics(router))
+	if GRPCFeed != nil && GRPCFeed.h2c {
+		handler = GRPCFeed.h2cHandler(handler)
+	}
+	server := &http.Server{
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: adminWriteTimeout,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Admin server failed", logKeyError, err)
+		}
+	}()
+
+	adminRoutesPrivate = true
+	slog.Info("Admin listener serving the admin API and profiling endpoints", "addr", listener.Addr().String())
+	return server, nil
+}
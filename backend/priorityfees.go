@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the interval between priority fee refreshes,
+	// e.g. 10s; the priority fee endpoint is disabled when it is unset
+	priorityFeeIntervalEnv = "PRIORITY_FEE_INTERVAL"
+
+	// REST endpoint returning the recommended priority fee levels
+	priorityFeesEndpoint = "/api/priority-fees"
+
+	// Longest a getRecentPrioritizationFees request may take
+	priorityFeeRequestTimeout = 10 * time.Second
+)
+
+// PriorityFeeEstimate represents the priority fees recently paid by landed
+// transactions writing to the fee recipient of the program, which every
+// pump.fun trade does; each slot contributes the lowest fee that landed in it
+// Fees are in micro-lamports per compute unit, as SetComputeUnitPrice takes them.
+type PriorityFeeEstimate struct {
+	Min       uint64    `json:"min"`        // Lowest fee of the window
+	Low       uint64    `json:"low"`        // 25th percentile
+	Medium    uint64    `json:"medium"`     // 50th percentile, the default of the transaction builder
+	High      uint64    `json:"high"`       // 75th percentile
+	VeryHigh  uint64    `json:"very_high"`  // 95th percentile
+	Max       uint64    `json:"max"`        // Highest fee of the window
+	Slots     int       `json:"slots"`      // Number of slots the levels were computed from
+	Slot      uint64    `json:"slot"`       // Latest slot of the window
+	UpdatedAt time.Time `json:"updated_at"` // Time of the refresh
+}
+
+// PriorityFeeEstimator keeps a priority fee estimate for pump.fun trades,
+// refreshed at a fixed interval
+type PriorityFeeEstimator struct {
+	client *rpc.Client
+
+	mutex    sync.RWMutex
+	estimate *PriorityFeeEstimate
+}
+
+// PriorityFees estimates priority fees when PRIORITY_FEE_INTERVAL is set, nil otherwise
+var PriorityFees *PriorityFeeEstimator
+
+// setupPriorityFees starts estimating priority fees when PRIORITY_FEE_INTERVAL is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if the interval is invalid or no RPC endpoint can be resolved
+func setupPriorityFees(rpcURL string) error {
+	value := os.Getenv(priorityFeeIntervalEnv)
+	if value == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid %s %q", priorityFeeIntervalEnv, value)
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	PriorityFees = &PriorityFeeEstimator{client: rpc.New(endpoint)}
+	goSafe("priority fees", func() { PriorityFees.run(interval) })
+
+	slog.Info("Estimating priority fees", "interval", interval, "endpoint", priorityFeesEndpoint)
+	return nil
+}
+
+// run refreshes the estimate right away, then at a fixed interval
+func (p *PriorityFeeEstimator) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.refresh(); err != nil {
+			slog.Warn("Failed to refresh priority fees", logKeyError, err)
+			reportError(errorCategoryUpstream, err)
+		}
+		<-ticker.C
+	}
+}
+
+// refresh replaces the estimate with one computed from the recent fees
+// The previous estimate is kept when no slot of the window saw a trade.
+func (p *PriorityFeeEstimator) refresh() error {
+	ctx, cancel := context.WithTimeout(context.Background(), priorityFeeRequestTimeout)
+	defer cancel()
+
+	results, err := p.client.GetRecentPrioritizationFees(ctx, solana.PublicKeySlice{pumpstream.FeeRecipient})
+	if err != nil {
+		return fmt.Errorf("failed to get recent prioritization fees: %w", err)
+	}
+	estimate := estimatePriorityFees(results)
+	if estimate == nil {
+		slog.Debug("No recent prioritization fees")
+		return nil
+	}
+
+	p.mutex.Lock()
+	p.estimate = estimate
+	p.mutex.Unlock()
+	return nil
+}
+
+// Estimate returns the latest estimate, nil before the first refresh succeeds
+func (p *PriorityFeeEstimator) Estimate() *PriorityFeeEstimate {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.estimate
+}
+
+// estimatePriorityFees computes the fee levels of a window of slots
+//
+// Parameters:
+//   - results: Lowest landed fee of each recent slot
+//
+// Returns:
+//   - *PriorityFeeEstimate: The fee levels, nil for an empty window
+func estimatePriorityFees(results []rpc.PriorizationFeeResult) *PriorityFeeEstimate {
+	if len(results) == 0 {
+		return nil
+	}
+
+	fees := make([]uint64, len(results))
+	var slot uint64
+	for i, result := range results {
+		fees[i] = result.PrioritizationFee
+		slot = max(slot, result.Slot)
+	}
+	slices.Sort(fees)
+
+	percentile := func(p int) uint64 {
+		return fees[(len(fees)-1)*p/100]
+	}
+	return &PriorityFeeEstimate{
+		Min:       fees[0],
+		Low:       percentile(25),
+		Medium:    percentile(50),
+		High:      percentile(75),
+		VeryHigh:  percentile(95),
+		Max:       fees[len(fees)-1],
+		Slots:     len(fees),
+		Slot:      slot,
+		UpdatedAt: time.Now().UTC(),
+	}
+}
+
+// recommendedComputeUnitPrice is the compute unit price used by the
+// transaction builder when a request does not set one: the medium estimate,
+// or defaultComputeUnitPrice without an estimate or while fees are zero
+func recommendedComputeUnitPrice() uint64 {
+	if PriorityFees != nil {
+		if estimate := PriorityFees.Estimate(); estimate != nil && estimate.Medium > 0 {
+			return estimate.Medium
+		}
+	}
+	return defaultComputeUnitPrice
+}
+
+// HandlePriorityFees returns the latest priority fee estimate
+func HandlePriorityFees(w http.ResponseWriter, r *http.Request) {
+	if PriorityFees == nil {
+		writeError(w, http.StatusNotFound, "priority fee estimation is disabled")
+		return
+	}
+	estimate := PriorityFees.Estimate()
+	if estimate == nil {
+		writeError(w, http.StatusServiceUnavailable, "no priority fee estimate yet")
+		return
+	}
+	writeJSON(w, http.StatusOK, estimate)
+}
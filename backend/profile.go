@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the profile of defaults to apply, e.g. prod;
+	// the --profile flag overrides it
+	profileEnv = "NOVA_PROFILE"
+
+	// Flag naming the profile
+	profileFlag = "profile"
+)
+
+// profiles maps every profile name to the environment variables it sets,
+// tuned for each stage of deployment:
+//   - dev: any origin, verbose text logs and no automatic bans
+//   - staging: JSON logs and lenient automatic bans, close to production
+//     without locking out testers
+//   - prod: JSON logs of warnings and errors only, and strict automatic bans
+//
+// Every profile waits 5s before reconnecting upstream, so a failing RPC
+// endpoint is not hammered into rate limiting the feed; notifications sent
+// while disconnected are missed whatever the delay.
+//
+// Staging and prod leave WS_ALLOWED_ORIGINS to the deployment, which must set
+// it to the origins of its frontend: any origin may connect while it is unset.
+// The server has no request rate limits to tune; the automatic ban thresholds
+// are what the profiles set against abusive clients.
+var profiles = map[string]map[string]string{
+	"dev": {
+		logLevelEnv:           "debug",
+		logFormatEnv:          logFormatText,
+		reconnectDelayEnv:     "5s",
+		wsAllowedOriginsEnv:   "*",
+		corsAllowedOriginsEnv: "*",
+	},
+	"staging": {
+		logLevelEnv:         "info",
+		logFormatEnv:        logFormatJSON,
+		reconnectDelayEnv:   "5s",
+		autoBanThresholdEnv: "50",
+		autoBanDurationEnv:  "1m",
+	},
+	"prod": {
+		logLevelEnv:         "warn",
+		logFormatEnv:        logFormatJSON,
+		reconnectDelayEnv:   "5s",
+		autoBanThresholdEnv: "20",
+		autoBanDurationEnv:  "10m",
+	},
+}
+
+// Profile applied at startup, and the environment variables it set that were
+// set neither in the environment nor by the configuration file
+var (
+	activeProfile string
+	profileVars   = map[string]bool{}
+)
+
+// applyProfile sets every environment variable of a profile that is not
+// already set, so the configuration file, the environment and the flags all
+// override it; it runs after the configuration file is loaded, and again on
+// reload so settings removed from the file fall back to the profile
+//
+// Parameters:
+//   - name: Name of the profile, nothing is applied when empty
+//
+// Returns:
+//   - error: Error if there is no profile with that name
+func applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	settings, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q, expected one of %v", name, profileNames())
+	}
+	activeProfile = name
+
+	for variable, value := range settings {
+		if _, set := os.LookupEnv(variable); set && !profileVars[variable] {
+			continue
+		}
+		if err := os.Setenv(variable, value); err != nil {
+			return fmt.Errorf("profile %s: failed to set %s: %w", name, variable, err)
+		}
+		profileVars[variable] = true
+	}
+	return nil
+}
+
+// profileNames returns the names of the profiles, sorted
+func profileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
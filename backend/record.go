@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Largest line accepted when reading a recording; notifications carry up to
+	// a few kilobytes of logs, truncated transactions stay well below this
+	maxRecordingLineSize = 1 << 20
+)
+
+// Recorder writes notifications as newline-delimited JSON, one per line
+type Recorder struct {
+	mutex   sync.Mutex
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	count   uint64
+}
+
+// NewRecorder creates a recorder writing to w
+func NewRecorder(w io.Writer) *Recorder {
+	writer := bufio.NewWriter(w)
+	return &Recorder{
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}
+}
+
+// Record appends a notification to the recording
+// Every line is flushed immediately so an interrupted recording stays usable
+func (r *Recorder) Record(notification pumpstream.Notification) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.encoder.Encode(notification); err != nil {
+		slog.Error("Failed to record notification", logKeySignature, notification.Signature, logKeyError, err)
+		return
+	}
+	if err := r.writer.Flush(); err != nil {
+		slog.Error("Failed to flush recording", logKeyError, err)
+		return
+	}
+	r.count++
+}
+
+// Count returns the number of notifications recorded so far
+func (r *Recorder) Count() uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.count
+}
+
+// replayRecording reads a recording and hands every notification to handle
+//
+// With a positive speed the original spacing between notifications is
+// reproduced, divided by speed (1 is real time, 10 is ten times faster);
+// with speed 0 notifications are replayed as fast as possible
+//
+// Parameters:
+//   - r: Recording to read
+//   - speed: Playback speed factor, 0 for no delay
+//   - handle: Function receiving each notification, e.g. processNotification
+//
+// Returns:
+//   - int: Number of notifications replayed
+//   - error: Error if the recording could not be read or parsed
+func replayRecording(r io.Reader, speed float64, handle func(pumpstream.Notification)) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordingLineSize)
+
+	var previous time.Time
+	count := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var notification pumpstream.Notification
+		if err := json.Unmarshal(scanner.Bytes(), &notification); err != nil {
+			return count, fmt.Errorf("invalid notification on line %d: %w", line, err)
+		}
+
+		if speed > 0 && !previous.IsZero() && notification.ReceivedAt.After(previous) {
+			time.Sleep(time.Duration(float64(notification.ReceivedAt.Sub(previous)) / speed))
+		}
+		previous = notification.ReceivedAt
+
+		handle(notification)
+		count++
+	}
+
+	return count, scanner.Err()
+}
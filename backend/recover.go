@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// logPanic logs a recovered panic with the stack of the goroutine it happened
+// in, and reports it when error reporting is enabled
+//
+// Parameters:
+//   - where: What was running, e.g. a goroutine or request name
+//   - value: Value passed to panic
+//   - attrs: Additional attributes, such as the event being processed
+func logPanic(where string, value any, attrs ...any) {
+	slog.Error("Recovered from panic", append([]any{"where", where, "panic", value, "stack", string(debug.Stack())}, attrs...)...)
+	reportPanic(where, value, attrs...)
+}
+
+// goSafe runs fn in a new goroutine that logs a panic instead of crashing the process
+func goSafe(name string, fn func()) {
+	go runRecovered(name, fn)
+}
+
+// runRecovered runs fn, logging a panic instead of propagating it
+//
+// Returns:
+//   - bool: True if fn panicked
+func runRecovered(name string, fn func()) (panicked bool) {
+	defer func() {
+		if value := recover(); value != nil {
+			logPanic(name, value)
+			panicked = true
+		}
+	}()
+	fn()
+	return false
+}
+
+// headerTracker is implemented by response writers that know whether the
+// response header has been sent
+type headerTracker interface {
+	wroteHeader() bool
+}
+
+// recoverPanics wraps a handler so a panic fails the request with a 500 and a
+// logged stack trace instead of a dropped connection
+// http.ErrAbortHandler is re-raised, as it is the documented way to abort a response
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+			logPanic("http handler", value, "method", r.Method, "path", r.URL.Path, "client_ip", clientIP(r))
+
+			if tracker, ok := w.(headerTracker); ok && tracker.wroteHeader() {
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the Redis connection URL, e.g. redis://localhost:6379/0
+	redisURLEnv = "REDIS_URL"
+
+	// Environment variable with the pub/sub channel prefix; channels are <prefix><event type>
+	// The pub/sub sink is disabled when it is unset
+	redisPubSubPrefixEnv = "REDIS_PUBSUB_PREFIX"
+
+	// Environment variable with the stream key written with XADD
+	// The stream sink is disabled when it is unset
+	redisStreamEnv = "REDIS_STREAM"
+
+	// Environment variable overriding the approximate maximum stream length
+	redisStreamMaxLenEnv = "REDIS_STREAM_MAXLEN"
+
+	// Environment variable naming a consumer group to create on the stream at startup
+	redisStreamGroupEnv = "REDIS_STREAM_GROUP"
+
+	// Stream length kept when REDIS_STREAM_MAXLEN is unset
+	defaultRedisStreamMaxLen = 100000
+
+	// Maximum XADD attempts per event, including the first one
+	redisStreamMaxAttempts = 3
+
+	// Delay between XADD attempts
+	redisStreamRetryDelay = 500 * time.Millisecond
+
+	// Number of events buffered per Redis sink before new events are dropped
+	redisQueueSize = 10000
+
+	// Timeout for a single Redis command issued by a sink
+	redisCommandTimeout = 5 * time.Second
+)
+
+// sharedRedis is the client shared by every Redis based component
+var (
+	sharedRedis     *redis.Client
+	sharedRedisErr  error
+	sharedRedisOnce sync.Once
+)
+
+// redisClient returns the client for REDIS_URL, connecting on first use
+func redisClient() (*redis.Client, error) {
+	sharedRedisOnce.Do(func() {
+		url := os.Getenv(redisURLEnv)
+		if url == "" {
+			sharedRedisErr = fmt.Errorf("%s must be set to use Redis", redisURLEnv)
+			return
+		}
+
+		options, err := redis.ParseURL(url)
+		if err != nil {
+			sharedRedisErr = fmt.Errorf("invalid %s: %w", redisURLEnv, err)
+			return
+		}
+
+		client := redis.NewClient(options)
+		ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+		defer cancel()
+
+		if err := client.Ping(ctx).Err(); err != nil {
+			sharedRedisErr = fmt.Errorf("failed to connect to Redis: %w", err)
+			return
+		}
+		sharedRedis = client
+	})
+	return sharedRedis, sharedRedisErr
+}
+
+// RedisPubSubSink publishes every event to a Redis channel named after its event type
+type RedisPubSubSink struct {
+	client        *redis.Client
+	channelPrefix string
+	queue         chan Event
+}
+
+// setupRedisPubSubSink registers the Redis pub/sub sink when REDIS_PUBSUB_PREFIX is set
+func setupRedisPubSubSink() error {
+	prefix := os.Getenv(redisPubSubPrefixEnv)
+	if prefix == "" {
+		return nil
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	sink := &RedisPubSubSink{
+		client:        client,
+		channelPrefix: prefix,
+		queue:         make(chan Event, redisQueueSize),
+	}
+	go sink.publishLoop()
+	RegisterSink(sink)
+
+	slog.Info("Publishing events to Redis channels", "prefix", prefix)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (s *RedisPubSubSink) Name() string {
+	return "redis-pubsub"
+}
+
+// Publish queues the event for the publisher, dropping it if the queue is full
+func (s *RedisPubSubSink) Publish(event Event) {
+	select {
+	case s.queue <- event:
+	default:
+		slog.Warn("Redis pub/sub queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// publishLoop publishes queued events in order
+// Pub/sub is fire-and-forget, so failed publishes are logged and not retried
+func (s *RedisPubSubSink) publishLoop() {
+	for event := range s.queue {
+		data, err := json.Marshal(event)
+		if err != nil {
+			slog.Error("Failed to marshal event for Redis", logKeyError, err)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+		err = s.client.Publish(ctx, s.channelPrefix+string(event.Type), data).Err()
+		cancel()
+
+		if err != nil {
+			slog.Warn("Failed to publish event to Redis", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, err)
+		}
+	}
+}
+
+// RedisStreamSink appends every event to a Redis Stream
+// Entries use server-assigned IDs, which are monotonic and therefore usable as
+// consumer group cursors; the stream is trimmed approximately to maxLen entries
+type RedisStreamSink struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+	queue  chan Event
+}
+
+// setupRedisStreamSink registers the Redis Stream sink when REDIS_STREAM is set
+func setupRedisStreamSink() error {
+	stream := os.Getenv(redisStreamEnv)
+	if stream == "" {
+		return nil
+	}
+
+	maxLen, err := strconv.ParseInt(envOrDefault(redisStreamMaxLenEnv, strconv.Itoa(defaultRedisStreamMaxLen)), 10, 64)
+	if err != nil || maxLen <= 0 {
+		return fmt.Errorf("%s must be a positive integer", redisStreamMaxLenEnv)
+	}
+
+	client, err := redisClient()
+	if err != nil {
+		return err
+	}
+
+	// Create the consumer group up front so workers can start reading at once
+	if group := os.Getenv(redisStreamGroupEnv); group != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+		defer cancel()
+
+		err := client.XGroupCreateMkStream(ctx, stream, group, "$").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create consumer group %s on %s: %w", group, stream, err)
+		}
+	}
+
+	sink := &RedisStreamSink{
+		client: client,
+		stream: stream,
+		maxLen: maxLen,
+		queue:  make(chan Event, redisQueueSize),
+	}
+	go sink.publishLoop()
+	RegisterSink(sink)
+
+	slog.Info("Appending events to Redis stream", "stream", stream, "max_len", maxLen)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (s *RedisStreamSink) Name() string {
+	return "redis-stream"
+}
+
+// Publish queues the event for the writer, dropping it if the queue is full
+func (s *RedisStreamSink) Publish(event Event) {
+	select {
+	case s.queue <- event:
+	default:
+		slog.Warn("Redis stream queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// publishLoop appends queued events in order, retrying transient failures
+func (s *RedisStreamSink) publishLoop() {
+	for event := range s.queue {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			slog.Error("Failed to marshal event for Redis stream", logKeyError, err)
+			continue
+		}
+
+		args := &redis.XAddArgs{
+			Stream: s.stream,
+			MaxLen: s.maxLen,
+			Approx: true,
+			Values: map[string]interface{}{
+				"type":        string(event.Type),
+				"mint":        event.Mint,
+				"signature":   event.Signature,
+				"slot":        event.Slot,
+				"received_at": event.ReceivedAt.Format(time.RFC3339Nano),
+				"data":        data,
+			},
+		}
+
+		for attempt := 1; attempt <= redisStreamMaxAttempts; attempt++ {
+			ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+			err = s.client.XAdd(ctx, args).Err()
+			cancel()
+
+			if err == nil {
+				break
+			}
+			time.Sleep(redisStreamRetryDelay)
+		}
+
+		if err != nil {
+			slog.Warn("Failed to append event to Redis stream", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Configuration constants
+const (
+	// Admin endpoint reloading the configuration, like SIGHUP
+	adminReloadEndpoint = "/admin/reload"
+)
+
+// reloadableSink is implemented by sinks that can pick up changed targets,
+// filters and endpoints without being restarted
+type reloadableSink interface {
+	Reload() error
+}
+
+// ReloadResult is the JSON body returned by the reload endpoint
+type ReloadResult struct {
+	Reloaded []string `json:"reloaded"`         // Settings and sinks that were reloaded
+	Errors   []string `json:"errors,omitempty"` // Settings and sinks that kept their previous value, and why
+}
+
+// reloadMutex serializes reloads triggered by signal and by the admin endpoint
+var reloadMutex sync.Mutex
+
+// reloadConfig reads the configuration file again and applies the settings
+// that can change while running: the log level, the program address, the
+// feature flags, the admin tokens, the Helius webhook secret, the TLS
+// certificate files and the targets, filters and endpoints of reloadable sinks
+// Client connections and the upstream connection are kept; a changed program
+// address only replaces the subscription on that connection
+func reloadConfig() ReloadResult {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	result := ReloadResult{Reloaded: []string{}}
+	fail := func(what string, err error) {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", what, err))
+	}
+
+	if configFile != "" {
+		if err := loadConfigFile(configFile); err != nil {
+			// Without the file nothing below would change
+			fail("config file", err)
+			return result
+		}
+		result.Reloaded = append(result.Reloaded, "config file")
+
+		// The file may hold secret references again
+		if _, err := resolveSecretReferences(); err != nil {
+			fail("secrets", err)
+		}
+
+		// Settings removed from the file fall back to the profile
+		if err := applyProfile(activeProfile); err != nil {
+			fail("profile", err)
+		}
+	}
+
+	// An override through the admin API stays in effect until it is cleared
+	if value := os.Getenv(logLevelEnv); value != "" {
+		if level, err := parseLogLevel(value); err != nil {
+			fail("log level", err)
+		} else {
+			logLevels.Configure(level)
+			result.Reloaded = append(result.Reloaded, "log level")
+		}
+	}
+
+	// The upstream subscription moves to the new program on its connection
+	if program, err := loadProgram(); err != nil {
+		fail("program address", err)
+	} else if !program.Equals(pumpProgram()) {
+		setPumpProgram(program)
+		result.Reloaded = append(result.Reloaded, "program address")
+		slog.Info("Program changed", "program", program.String())
+	}
+
+	if HeliusWebhook != nil {
+		if err := HeliusWebhook.Reload(); err != nil {
+			fail("Helius webhook secret", err)
+		} else {
+			result.Reloaded = append(result.Reloaded, "Helius webhook secret")
+		}
+	}
+
+	if os.Getenv(adminTokensFileEnv) != "" {
+		if err := loadAdminTokens(); err != nil {
+			fail("admin tokens", err)
+		} else {
+			result.Reloaded = append(result.Reloaded, "admin tokens")
+		}
+	}
+
+	if serverCertificate != nil {
+		if err := serverCertificate.Reload(); err != nil {
+			fail("TLS certificate", err)
+		} else {
+			result.Reloaded = append(result.Reloaded, "TLS certificate")
+		}
+	}
+
+	if os.Getenv(filterScriptsFileEnv) != "" {
+		if err := loadFilterScripts(); err != nil {
+			fail("filter scripts", err)
+		} else {
+			result.Reloaded = append(result.Reloaded, "filter scripts")
+		}
+	}
+
+	if err := setupFeatureFlags(); err != nil {
+		fail("feature flags", err)
+	} else {
+		result.Reloaded = append(result.Reloaded, "feature flags")
+	}
+
+	eventSinksMutex.RLock()
+	sinks := append([]EventSink(nil), eventSinks...)
+	eventSinksMutex.RUnlock()
+	for _, sink := range sinks {
+		if reloadable, ok := sink.(reloadableSink); ok {
+			if err := reloadable.Reload(); err != nil {
+				fail(sink.Name(), err)
+				continue
+			}
+			result.Reloaded = append(result.Reloaded, sink.Name())
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		slog.Warn("Reloaded configuration with errors", "reloaded", strings.Join(result.Reloaded, ","), "errors", strings.Join(result.Errors, "; "))
+	} else {
+		slog.Info("Reloaded configuration", "reloaded", strings.Join(result.Reloaded, ","))
+	}
+	return result
+}
+
+// HandleReload reloads the configuration, reporting what changed
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleReload(w http.ResponseWriter, r *http.Request) {
+	result := reloadConfig()
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+// setupConfigReload does nothing where SIGHUP does not exist; the admin
+// endpoint still reloads the configuration
+func setupConfigReload() error {
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// setupConfigReload reloads the configuration every time the process receives SIGHUP
+func setupConfigReload() error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			runRecovered("config reload", func() { reloadConfig() })
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the path of the ring buffer database file
+	// The ring buffer is disabled when it is unset
+	ringPathEnv = "RING_PATH"
+
+	// Environment variable overriding how long events are kept, e.g. 12h
+	ringRetentionEnv = "RING_RETENTION"
+
+	// Retention used when RING_RETENTION is unset
+	defaultRingRetention = 6 * time.Hour
+
+	// Interval between sweeps deleting expired events
+	ringPruneInterval = time.Minute
+
+	// Maximum number of expired events deleted in a single transaction
+	ringPruneBatchSize = 10000
+
+	// Timeout for acquiring the file lock held by another process
+	ringOpenTimeout = 5 * time.Second
+
+	// Interval between checks whether the file is worth compacting
+	ringCompactInterval = time.Hour
+
+	// Smallest file compacted; below it the freed space is not worth a rewrite
+	ringCompactMinSize = 64 << 20
+
+	// Maximum size of a single transaction while copying into the compacted file
+	ringCompactTxSize = 64 << 20
+
+	// REST endpoint replaying buffered events after a cursor
+	ringEventsEndpoint = "/api/events"
+
+	// Largest number of buffered creations a WebSocket client may request on connect
+	maxWebSocketBacklog = 500
+)
+
+// ringBucket is the bucket holding buffered events keyed by cursor
+var ringBucket = []byte("events")
+
+// RingEntry is a buffered event together with its cursor
+type RingEntry struct {
+	Cursor uint64 `json:"cursor"` // Position of the event in the buffer, increasing
+	Event  Event  `json:"event"`  // The buffered event
+}
+
+// ringPage is the JSON body returned by the events endpoint
+type ringPage struct {
+	Events     []RingEntry `json:"events"`      // Buffered events after the requested cursor, oldest first
+	NextCursor uint64      `json:"next_cursor"` // Cursor to pass as after to continue reading
+}
+
+// RingBuffer keeps the events of the last few hours in an embedded bbolt file
+// Every event is stored under a monotonically increasing cursor, so clients can
+// resume where they left off and new WebSocket clients can be sent a backlog
+// Values are the received_at time in unix milliseconds followed by the event JSON
+//
+// bbolt reuses the pages freed by pruning but never shrinks its file, so the
+// file is rewritten in the background once most of it is free space
+type RingBuffer struct {
+	*eventBatcher
+	mutex     sync.RWMutex // Held for writing while the file is swapped for its compacted copy
+	db        *bolt.DB
+	path      string
+	retention time.Duration
+}
+
+// Ring is the configured ring buffer, nil when it is disabled
+var Ring *RingBuffer
+
+// setupRingBuffer opens the ring buffer when RING_PATH is set
+func setupRingBuffer() error {
+	path := os.Getenv(ringPathEnv)
+	if path == "" {
+		return nil
+	}
+
+	retention, err := time.ParseDuration(envOrDefault(ringRetentionEnv, defaultRingRetention.String()))
+	if err != nil || retention <= 0 {
+		return fmt.Errorf("%s must be a positive duration", ringRetentionEnv)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: ringOpenTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to open ring buffer: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(ringBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize ring buffer: %w", err)
+	}
+
+	ring := &RingBuffer{db: db, path: path, retention: retention}
+	ring.prune()
+	ring.eventBatcher = newEventBatcher("ring", ring.writeBatch)
+	go ring.pruneLoop()
+	go ring.compactLoop()
+
+	Ring = ring
+	RegisterSink(ring)
+
+	slog.Info("Buffering recent events", "retention", retention, "path", path, "events_restored", ring.Len())
+	return nil
+}
+
+// writeBatch appends the events under the next cursors in one transaction
+func (r *RingBuffer) writeBatch(batch []Event) error {
+	return r.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ringBucket)
+		for _, event := range batch {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+
+			cursor, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			value := make([]byte, 8+len(data))
+			binary.BigEndian.PutUint64(value, uint64(event.ReceivedAt.UnixMilli()))
+			copy(value[8:], data)
+
+			if err := bucket.Put(ringKey(cursor), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Len returns the number of buffered events
+func (r *RingBuffer) Len() int {
+	count := 0
+	r.view(func(tx *bolt.Tx) error {
+		count = tx.Bucket(ringBucket).Stats().KeyN
+		return nil
+	})
+	return count
+}
+
+// After returns up to limit buffered events with a cursor greater than cursor,
+// oldest first
+func (r *RingBuffer) After(cursor uint64, limit int) ([]RingEntry, error) {
+	entries := []RingEntry{}
+	err := r.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(ringBucket).Cursor()
+		for key, value := c.Seek(ringKey(cursor + 1)); key != nil && len(entries) < limit; key, value = c.Next() {
+			entry, err := decodeRingEntry(key, value)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Latest returns up to limit of the most recent buffered events of the given
+// type, oldest first
+func (r *RingBuffer) Latest(eventType EventType, limit int) ([]RingEntry, error) {
+	entries := []RingEntry{}
+	err := r.view(func(tx *bolt.Tx) error {
+		c := tx.Bucket(ringBucket).Cursor()
+		for key, value := c.Last(); key != nil && len(entries) < limit; key, value = c.Prev() {
+			entry, err := decodeRingEntry(key, value)
+			if err != nil {
+				return err
+			}
+			if entry.Event.Type == eventType {
+				entries = append(entries, entry)
+			}
+		}
+		return nil
+	})
+
+	// Collected newest first; hand them out in the order they happened
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, err
+}
+
+// pruneLoop periodically deletes events older than the retention
+func (r *RingBuffer) pruneLoop() {
+	ticker := time.NewTicker(ringPruneInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.prune()
+	}
+}
+
+// prune deletes events older than the retention from the front of the buffer
+// Events are appended in arrival order, so it stops at the first one to keep
+func (r *RingBuffer) prune() {
+	cutoff := time.Now().Add(-r.retention).UnixMilli()
+	if cutoff <= 0 {
+		return
+	}
+
+	for {
+		deleted := 0
+		err := r.update(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(ringBucket)
+
+			// Collect first; deleting through the cursor would make it skip keys
+			expired := [][]byte{}
+			c := bucket.Cursor()
+			for key, value := c.First(); key != nil && len(expired) < ringPruneBatchSize; key, value = c.Next() {
+				if len(value) >= 8 && int64(binary.BigEndian.Uint64(value)) >= cutoff {
+					break
+				}
+				expired = append(expired, key)
+			}
+
+			for _, key := range expired {
+				if err := bucket.Delete(key); err != nil {
+					return err
+				}
+			}
+			deleted = len(expired)
+			return nil
+		})
+		if err != nil {
+			slog.Error("Failed to prune ring buffer", logKeyError, err)
+			return
+		}
+		if deleted < ringPruneBatchSize {
+			return
+		}
+	}
+}
+
+// view runs a read-only transaction against the current file
+func (r *RingBuffer) view(fn func(tx *bolt.Tx) error) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.db.View(fn)
+}
+
+// update runs a read-write transaction against the current file
+func (r *RingBuffer) update(fn func(tx *bolt.Tx) error) error {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.db.Update(fn)
+}
+
+// compactLoop periodically compacts the file
+func (r *RingBuffer) compactLoop() {
+	ticker := time.NewTicker(ringCompactInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := r.compact(); err != nil {
+			slog.Error("Failed to compact ring buffer", logKeyError, err)
+		}
+	}
+}
+
+// compact rewrites the file without its free pages once they make up more than
+// half of it; readers and the writer wait while the compacted copy is swapped in
+func (r *RingBuffer) compact() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	free := int64(r.db.Stats().FreeAlloc)
+	if info.Size() < ringCompactMinSize || free*2 < info.Size() {
+		return nil
+	}
+
+	temporary := r.path + ".compact"
+	os.Remove(temporary)
+	compacted, err := bolt.Open(temporary, 0o600, &bolt.Options{Timeout: ringOpenTimeout})
+	if err != nil {
+		return err
+	}
+	// Compact carries the bucket sequence over, so cursors keep increasing
+	if err := bolt.Compact(compacted, r.db, ringCompactTxSize); err != nil {
+		compacted.Close()
+		os.Remove(temporary)
+		return err
+	}
+	if err := compacted.Close(); err != nil {
+		os.Remove(temporary)
+		return err
+	}
+
+	if err := r.db.Close(); err != nil {
+		return err
+	}
+	renameErr := os.Rename(temporary, r.path)
+	// Reopen whichever file is in place so the buffer keeps working either way
+	db, err := bolt.Open(r.path, 0o600, &bolt.Options{Timeout: ringOpenTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to reopen ring buffer: %w", err)
+	}
+	r.db = db
+	if renameErr != nil {
+		os.Remove(temporary)
+		return renameErr
+	}
+
+	compactedInfo, err := os.Stat(r.path)
+	if err == nil {
+		slog.Info("Compacted ring buffer", "bytes_before", info.Size(), "bytes_after", compactedInfo.Size())
+	}
+	return nil
+}
+
+// ringKey encodes a cursor as a big-endian key so keys sort in cursor order
+func ringKey(cursor uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, cursor)
+	return key
+}
+
+// decodeRingEntry decodes a stored key and value into an entry
+func decodeRingEntry(key, value []byte) (RingEntry, error) {
+	if len(value) < 8 {
+		return RingEntry{}, fmt.Errorf("corrupt ring buffer entry %x", key)
+	}
+
+	entry := RingEntry{Cursor: binary.BigEndian.Uint64(key)}
+	if err := json.Unmarshal(value[8:], &entry.Event); err != nil {
+		return RingEntry{}, err
+	}
+	return entry, nil
+}
+
+// HandleRingEvents returns buffered events after the cursor passed as after
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with optional after and limit query parameters
+func HandleRingEvents(w http.ResponseWriter, r *http.Request) {
+	if Ring == nil {
+		writeError(w, http.StatusNotFound, "event buffer is not enabled")
+		return
+	}
+
+	var after uint64
+	if value := r.URL.Query().Get("after"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "after must be a non-negative integer")
+			return
+		}
+		after = parsed
+	}
+
+	limit, err := intQueryParam(r, "limit", defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		writeError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageLimit))
+		return
+	}
+
+	entries, err := Ring.After(after, limit)
+	if err != nil {
+		slog.Error("Failed to read ring buffer", logKeyError, err)
+		writeError(w, http.StatusInternalServerError, "failed to read events")
+		return
+	}
+
+	next := after
+	if len(entries) > 0 {
+		next = entries[len(entries)-1].Cursor
+	}
+	writeJSON(w, http.StatusOK, ringPage{Events: entries, NextCursor: next})
+}
+
+// creationBacklog returns up to limit of the most recent buffered creations
+// encoded as they are broadcast to WebSocket clients, oldest first
+func creationBacklog(limit int) ([][]byte, error) {
+	if Ring == nil || limit <= 0 {
+		return nil, nil
+	}
+
+	entries, err := Ring.Latest(EventCreate, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		// Data was decoded generically; round-trip it through CreateEvent so the
+		// backlog is byte-for-byte the format of live messages
+		data, err := json.Marshal(entry.Event.Data)
+		if err != nil {
+			return nil, err
+		}
+		var creation CreateEvent
+		if err := json.Unmarshal(data, &creation); err != nil {
+			return nil, err
+		}
+		message, err := json.Marshal(creation)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
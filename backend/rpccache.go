@@ -0,0 +1,286 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling the RPC cache: when true, the enrichment
+	// calls share cached results, and concurrent identical calls one request
+	rpcCacheEnv = "RPC_CACHE"
+
+	// Environment variable selecting where results are cached: memory (the
+	// default), or redis to share them between instances through REDIS_URL
+	rpcCacheBackendEnv = "RPC_CACHE_BACKEND"
+
+	// Cache backends
+	rpcCacheBackendMemory = "memory"
+	rpcCacheBackendRedis  = "redis"
+
+	// Prefix of the Redis keys of cached results
+	rpcCacheRedisPrefix = "rpccache:"
+
+	// Memory budget of the results cached in memory; the oldest are evicted first
+	rpcCacheMemoryBytes = 64 << 20
+)
+
+// rpcCacheTTLs are the methods whose results are cached, with how long
+// Transactions do not change once landed; accounts and supplies are reused
+// just long enough to absorb the lookups of a burst of launches.
+var rpcCacheTTLs = map[string]time.Duration{
+	"getTransaction":      time.Hour,
+	"getAccountInfo":      5 * time.Second,
+	"getMultipleAccounts": 5 * time.Second,
+	"getTokenSupply":      time.Minute,
+}
+
+// rpcCacheStore holds cached results by key
+type rpcCacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// RPCCache caches the results of the enrichment RPC calls and coalesces
+// concurrent identical calls
+type RPCCache struct {
+	backend string
+	store   rpcCacheStore
+	group   singleflight.Group
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	coalesced atomic.Uint64
+	errors    atomic.Uint64
+}
+
+// RPCCacheStats counts the calls that went through the RPC cache
+type RPCCacheStats struct {
+	Backend   string `json:"backend"`   // memory or redis
+	Hits      uint64 `json:"hits"`      // Calls answered from the cache
+	Misses    uint64 `json:"misses"`    // Calls sent to the RPC endpoint
+	Coalesced uint64 `json:"coalesced"` // Calls that waited for an identical call in flight
+	Errors    uint64 `json:"errors"`    // Cache backend failures; the call is then sent
+}
+
+// RPCResults caches enrichment RPC calls when RPC_CACHE is set, nil otherwise
+var RPCResults *RPCCache
+
+// setupRPCCache enables the RPC cache when RPC_CACHE is set
+// It runs before the enrichment setups, whose clients it wraps.
+//
+// Returns:
+//   - error: Error if the backend is unknown or Redis is unreachable
+func setupRPCCache() error {
+	if !envBool(rpcCacheEnv) {
+		return nil
+	}
+
+	cache := &RPCCache{backend: cmp.Or(os.Getenv(rpcCacheBackendEnv), rpcCacheBackendMemory)}
+	switch cache.backend {
+	case rpcCacheBackendMemory:
+		cache.store = &memoryRPCCacheStore{entries: make(map[string]memoryRPCCacheEntry)}
+	case rpcCacheBackendRedis:
+		client, err := redisClient()
+		if err != nil {
+			return err
+		}
+		cache.store = &redisRPCCacheStore{client: client}
+	default:
+		return fmt.Errorf("invalid %s %q, expected %s or %s", rpcCacheBackendEnv, cache.backend, rpcCacheBackendMemory, rpcCacheBackendRedis)
+	}
+	RPCResults = cache
+
+	slog.Info("Caching enrichment RPC calls", "backend", cache.backend)
+	return nil
+}
+
+// newEnrichmentRPCClient creates the RPC client of an enrichment, going
+// through the RPC cache when it is enabled
+func newEnrichmentRPCClient(endpoint string) *rpc.Client {
+	client := rpc.New(endpoint)
+	if RPCResults == nil {
+		return client
+	}
+	return rpc.NewWithCustomRPCClient(&cachingRPCClient{next: client, cache: RPCResults})
+}
+
+// Stats returns the counters of the cache
+func (c *RPCCache) Stats() RPCCacheStats {
+	return RPCCacheStats{
+		Backend:   c.backend,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Errors:    c.errors.Load(),
+	}
+}
+
+// call returns the cached result of a call, or sends it once for every
+// concurrent identical call and caches the result
+// Null results, such as a transaction not found yet, are not cached.
+func (c *RPCCache) call(ctx context.Context, next *rpc.Client, ttl time.Duration, method string, params []any) (json.RawMessage, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(append([]byte(method+":"), encoded...))
+	key := hex.EncodeToString(digest[:])
+
+	var leader bool
+	value, err, _ := c.group.Do(key, func() (any, error) {
+		leader = true
+		cached, found, err := c.store.Get(ctx, key)
+		if err != nil {
+			c.errors.Add(1)
+			slog.Debug("Failed to read the RPC cache", "method", method, logKeyError, err)
+		}
+		if found {
+			c.hits.Add(1)
+			return json.RawMessage(cached), nil
+		}
+
+		c.misses.Add(1)
+		var result json.RawMessage
+		if err := next.RPCCallForInto(ctx, &result, method, params); err != nil {
+			return nil, err
+		}
+		if len(result) > 0 && string(result) != "null" {
+			if err := c.store.Set(ctx, key, result, ttl); err != nil {
+				c.errors.Add(1)
+				slog.Debug("Failed to write the RPC cache", "method", method, logKeyError, err)
+			}
+		}
+		return result, nil
+	})
+	if !leader {
+		c.coalesced.Add(1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value.(json.RawMessage), nil
+}
+
+// cachingRPCClient sends the calls of an RPC client through the RPC cache
+// Methods without a TTL, callbacks and batches go straight to the endpoint.
+type cachingRPCClient struct {
+	next  *rpc.Client
+	cache *RPCCache
+}
+
+// CallForInto decodes the result of a call into out, from the cache when possible
+func (c *cachingRPCClient) CallForInto(ctx context.Context, out any, method string, params []any) error {
+	ttl, cacheable := rpcCacheTTLs[method]
+	if !cacheable {
+		return c.next.RPCCallForInto(ctx, out, method, params)
+	}
+	result, err := c.cache.call(ctx, c.next, ttl, method, params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(result, out)
+}
+
+// CallWithCallback sends a call, handing the raw response to callback
+func (c *cachingRPCClient) CallWithCallback(ctx context.Context, method string, params []any, callback func(*http.Request, *http.Response) error) error {
+	return c.next.RPCCallWithCallback(ctx, method, params, callback)
+}
+
+// CallBatch sends a batch of calls
+func (c *cachingRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	return c.next.RPCCallBatch(ctx, requests)
+}
+
+// memoryRPCCacheEntry is a result cached in memory
+type memoryRPCCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// memoryRPCCacheStore caches results in memory, within rpcCacheMemoryBytes
+type memoryRPCCacheStore struct {
+	mutex   sync.Mutex
+	entries map[string]memoryRPCCacheEntry
+	order   []string // Keys, oldest first
+	bytes   int      // Size of the cached results
+}
+
+// Get returns a result that has not expired
+func (s *memoryRPCCacheStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, found := s.entries[key]
+	if !found || time.Now().After(entry.expiresAt) {
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Set caches a result, evicting the oldest ones over the memory budget
+func (s *memoryRPCCacheStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if previous, found := s.entries[key]; found {
+		// An expired result fetched again keeps its place in the eviction order
+		s.bytes -= len(previous.value)
+	} else {
+		s.order = append(s.order, key)
+	}
+	s.entries[key] = memoryRPCCacheEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	s.bytes += len(value)
+	for s.bytes > rpcCacheMemoryBytes && len(s.order) > 1 {
+		oldest := s.order[0]
+		s.order = s.order[1:]
+		s.bytes -= len(s.entries[oldest].value)
+		delete(s.entries, oldest)
+	}
+	return nil
+}
+
+// redisRPCCacheStore caches results in Redis, expiring them there
+type redisRPCCacheStore struct {
+	client *redis.Client
+}
+
+// Get returns a cached result
+func (s *redisRPCCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisCommandTimeout)
+	defer cancel()
+
+	value, err := s.client.Get(ctx, rpcCacheRedisPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set caches a result for ttl
+func (s *redisRPCCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, redisCommandTimeout)
+	defer cancel()
+
+	return s.client.Set(ctx, rpcCacheRedisPrefix+key, value, ttl).Err()
+}
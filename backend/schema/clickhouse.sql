@@ -0,0 +1,35 @@
+-- ClickHouse tables written by the ClickHouse sink
+-- The sink creates them on startup if they do not exist, in the database
+-- selected with CLICKHOUSE_DATABASE
+
+-- One row per token creation
+CREATE TABLE IF NOT EXISTS pumpfun_creations (
+    mint          String,                 -- Token mint address
+    name          String,                 -- Token name
+    symbol        String,                 -- Token symbol
+    uri           String,                 -- Token metadata URI
+    bonding_curve String,                 -- Bonding curve account of the token
+    creator       String,                 -- Creator wallet
+    signature     String,                 -- Creation transaction signature
+    slot          UInt64,                 -- Slot the creation was observed in
+    created_at    DateTime64(3, 'UTC')    -- Time the creation was observed
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(created_at)
+ORDER BY (created_at, mint);
+
+-- One row per buy or sell on a bonding curve
+CREATE TABLE IF NOT EXISTS pumpfun_trades (
+    mint                   String,               -- Token mint address
+    signature              String,               -- Trade transaction signature
+    slot                   UInt64,               -- Slot the trade was observed in
+    trader                 String,               -- Trader wallet
+    is_buy                 Bool,                 -- True for buys, false for sells
+    sol_amount             UInt64,               -- Lamports exchanged
+    token_amount           UInt64,               -- Token base units exchanged
+    virtual_sol_reserves   UInt64,               -- Virtual SOL reserves after the trade
+    virtual_token_reserves UInt64,               -- Virtual token reserves after the trade
+    market_cap_sol         Float64,              -- Market cap implied by the curve after the trade
+    traded_at              DateTime64(3, 'UTC')  -- On-chain time of the trade
+) ENGINE = MergeTree
+PARTITION BY toYYYYMM(traded_at)
+ORDER BY (mint, traded_at);
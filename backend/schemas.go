@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Endpoint listing the published event schemas
+	schemasEndpoint = "/api/schemas"
+
+	// Endpoint serving the JSON Schema of an event type or of the envelope
+	schemaEndpoint = "/api/schemas/{name}"
+
+	// Version of the Event envelope; raised on a change existing clients would
+	// not read, while the schemas of earlier versions stay served
+	eventEnvelopeVersion = 1
+
+	// Name the schema of the envelope is published under, with every event type
+	envelopeSchemaName = "event"
+
+	// Dialect of the published schemas, and the pointer their definitions are referenced by
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	jsonSchemaRef     = "#/$defs/"
+
+	// Formats a schema is published in; protobuf descriptors are to follow
+	schemaFormatJSONSchema = "json-schema"
+)
+
+// eventSchema is a version of an event payload published as a JSON Schema
+type eventSchema struct {
+	Name        string      // Event type, or envelopeSchemaName for the envelope
+	Version     int         // Version of the payload
+	Description string      // One-line description for the index
+	Data        interface{} // Zero value of the data of the event, nil for the envelope
+}
+
+// eventSchemas are the published schemas, each name in ascending versions
+// A change of a data type existing clients would not read adds a version
+// with a new type, rather than altering the type of the published one.
+var eventSchemas = []eventSchema{
+	{Name: envelopeSchemaName, Version: eventEnvelopeVersion, Description: "Envelope of every event, with the data of any type"},
+	{Name: string(EventCreate), Version: 1, Description: "A new token was created", Data: CreateEvent{}},
+	{Name: string(EventTrade), Version: 1, Description: "A buy or sell on a bonding curve", Data: TradeEvent{}},
+	{Name: string(EventComplete), Version: 1, Description: "A bonding curve completed (graduation)", Data: CompleteEvent{}},
+	{Name: string(EventPrice), Version: 1, Description: "A new quote for a graduated token", Data: PriceEvent{}},
+	{Name: string(EventMigration), Version: 1, Description: "A graduated token migrated to its pool, with the LP verified", Data: MigrationEvent{}},
+	{Name: string(EventRevert), Version: 1, Description: "A previewed creation did not reach confirmation", Data: RevertEvent{}},
+}
+
+// schemaIndex lists the published schemas
+type schemaIndex struct {
+	EnvelopeVersion int          `json:"envelope_version"` // Current version of the Event envelope
+	Schemas         []schemaInfo `json:"schemas"`          // Every version of every schema
+}
+
+// schemaInfo describes a published schema
+type schemaInfo struct {
+	Name        string   `json:"name"`        // Event type, or event for the envelope
+	Version     int      `json:"version"`     // Version of the payload
+	Latest      bool     `json:"latest"`      // Whether it is the version currently published
+	Description string   `json:"description"` // What the event announces
+	URL         string   `json:"url"`         // Path of the JSON Schema of this version
+	Formats     []string `json:"formats"`     // Formats the schema is published in
+}
+
+// HandleListSchemas lists the published event schemas and their versions
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleListSchemas(w http.ResponseWriter, r *http.Request) {
+	index := schemaIndex{EnvelopeVersion: eventEnvelopeVersion, Schemas: []schemaInfo{}}
+	for i, entry := range eventSchemas {
+		index.Schemas = append(index.Schemas, schemaInfo{
+			Name:        entry.Name,
+			Version:     entry.Version,
+			Latest:      i == len(eventSchemas)-1 || eventSchemas[i+1].Name != entry.Name,
+			Description: entry.Description,
+			URL:         fmt.Sprintf("%s/%s?version=%d", schemasEndpoint, entry.Name, entry.Version),
+			Formats:     []string{schemaFormatJSONSchema},
+		})
+	}
+	writeJSON(w, http.StatusOK, index)
+}
+
+// HandleGetSchema serves the JSON Schema of an event type or of the envelope,
+// the latest version unless a version is given
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleGetSchema(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	version := 0 // Latest
+	if value := r.URL.Query().Get("version"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "version must be a positive integer")
+			return
+		}
+		version = parsed
+	}
+
+	var found *eventSchema
+	for i, entry := range eventSchemas {
+		if entry.Name == name && (version == 0 || entry.Version == version) {
+			found = &eventSchemas[i]
+		}
+	}
+	switch {
+	case found != nil:
+		writeJSON(w, http.StatusOK, buildEventSchema(*found))
+	case version != 0 && latestEventSchema(name) != nil:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("schema %s has no version %d", name, version))
+	default:
+		writeError(w, http.StatusNotFound, "unknown schema "+name)
+	}
+}
+
+// latestEventSchema returns the latest version of the schema of a name, nil if none
+func latestEventSchema(name string) *eventSchema {
+	var latest *eventSchema
+	for i, entry := range eventSchemas {
+		if entry.Name == name {
+			latest = &eventSchemas[i]
+		}
+	}
+	return latest
+}
+
+// buildEventSchema generates the JSON Schema document of a published schema
+// The document is the schema of the Event envelope. The one of an event type
+// pins type to it and data to its data type; the one of the envelope allows
+// every latest event type and any of their data types.
+func buildEventSchema(entry eventSchema) map[string]interface{} {
+	defs := map[string]interface{}{}
+	structSchemaRef(reflect.TypeOf(Event{}), defs, jsonSchemaRef)
+	envelope := defs["Event"].(map[string]interface{})
+	delete(defs, "Event")
+
+	properties := envelope["properties"].(map[string]interface{})
+	if entry.Name == envelopeSchemaName {
+		types := []string{}
+		data := []interface{}{}
+		for i, event := range eventSchemas {
+			if event.Name == envelopeSchemaName || latestEventSchema(event.Name) != &eventSchemas[i] {
+				continue
+			}
+			types = append(types, event.Name)
+			data = append(data, schemaFor(reflect.TypeOf(event.Data), defs, jsonSchemaRef))
+		}
+		properties["type"] = map[string]interface{}{"type": "string", "enum": types}
+		properties["data"] = map[string]interface{}{"anyOf": data}
+	} else {
+		properties["type"] = map[string]interface{}{"const": entry.Name}
+		properties["data"] = schemaFor(reflect.TypeOf(entry.Data), defs, jsonSchemaRef)
+	}
+
+	title := entry.Name + " event"
+	if entry.Name == envelopeSchemaName {
+		title = "Event envelope"
+	}
+	document := map[string]interface{}{
+		"$schema":     jsonSchemaDialect,
+		"title":       fmt.Sprintf("%s, version %d", title, entry.Version),
+		"description": entry.Description,
+	}
+	for key, value := range envelope {
+		document[key] = value
+	}
+	if len(defs) > 0 {
+		document["$defs"] = defs
+	}
+	return document
+}
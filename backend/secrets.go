@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// Configuration constants
+const (
+	// Prefix of environment variable values read from HashiCorp Vault, e.g.
+	// HELIUS_API_KEY=vault://secret/data/nova#helius_api_key
+	// The path is relative to /v1/ and the field defaults to "value"
+	vaultSecretPrefix = "vault://"
+
+	// Prefix of environment variable values read from AWS Secrets Manager, e.g.
+	// HELIUS_API_KEY=awssm://prod/nova#helius_api_key
+	// Without a field the whole secret string is used
+	awsSecretPrefix = "awssm://"
+
+	// Environment variable with the Vault server address, e.g. https://vault:8200
+	vaultAddrEnv = "VAULT_ADDR"
+
+	// Environment variables with the Vault token, or the path of a file holding it
+	vaultTokenEnv     = "VAULT_TOKEN"
+	vaultTokenFileEnv = "VAULT_TOKEN_FILE"
+
+	// Environment variable with the Vault Enterprise namespace, if any
+	vaultNamespaceEnv = "VAULT_NAMESPACE"
+
+	// Environment variable with the interval at which secrets are fetched
+	// again to pick up rotations, e.g. 5m; secrets are only fetched at startup
+	// when it is unset
+	secretsRefreshIntervalEnv = "SECRETS_REFRESH_INTERVAL"
+
+	// Field read from a Vault secret when the reference does not name one
+	defaultVaultField = "value"
+
+	// Longest a secret may take to fetch
+	secretFetchTimeout = 10 * time.Second
+)
+
+// secretReferences maps every environment variable resolved from a secret
+// manager to its reference, so rotated values can be fetched again
+var (
+	secretReferencesMutex sync.Mutex
+	secretReferences      = map[string]string{}
+)
+
+// awsSecrets is the Secrets Manager client, created on first use
+var awsSecrets *secretsmanager.Client
+
+// resolveSecretReferences replaces every environment variable whose value is
+// a vault:// or awssm:// reference with the secret it points to; the secrets
+// are redacted from logs
+//
+// Returns:
+//   - []string: Names of the variables whose value changed
+//   - error: Error if a secret could not be fetched
+func resolveSecretReferences() ([]string, error) {
+	secretReferencesMutex.Lock()
+	defer secretReferencesMutex.Unlock()
+
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(value, vaultSecretPrefix) || strings.HasPrefix(value, awsSecretPrefix) {
+			secretReferences[name] = value
+		}
+	}
+
+	var changed []string
+	for name, reference := range secretReferences {
+		secret, err := fetchSecret(reference)
+		if err != nil {
+			return changed, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		registerSecret(secret)
+		if os.Getenv(name) == secret {
+			continue
+		}
+		if err := os.Setenv(name, secret); err != nil {
+			return changed, fmt.Errorf("failed to set %s: %w", name, err)
+		}
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
+
+// fetchSecret reads the secret a reference points to
+func fetchSecret(reference string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretFetchTimeout)
+	defer cancel()
+
+	if path, ok := strings.CutPrefix(reference, vaultSecretPrefix); ok {
+		path, field, _ := strings.Cut(path, "#")
+		if field == "" {
+			field = defaultVaultField
+		}
+		return fetchVaultSecret(ctx, path, field)
+	}
+	id, field, _ := strings.Cut(strings.TrimPrefix(reference, awsSecretPrefix), "#")
+	return fetchAWSSecret(ctx, id, field)
+}
+
+// fetchVaultSecret reads a field of a KV secret through the Vault HTTP API
+// Both KV version 1 and version 2 mounts are supported
+func fetchVaultSecret(ctx context.Context, path, field string) (string, error) {
+	addr := os.Getenv(vaultAddrEnv)
+	if addr == "" {
+		return "", fmt.Errorf("%s must be set to read Vault secrets", vaultAddrEnv)
+	}
+	token := os.Getenv(vaultTokenEnv)
+	if token == "" {
+		if tokenFile := os.Getenv(vaultTokenFileEnv); tokenFile != "" {
+			data, err := os.ReadFile(tokenFile)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", vaultTokenFileEnv, err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("%s or %s must be set to read Vault secrets", vaultTokenEnv, vaultTokenFileEnv)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(addr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv(vaultNamespaceEnv); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Vault returned %s for %s", resp.Status, path)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	// KV version 2 nests the fields under data.data, next to data.metadata
+	fields := body.Data
+	if nested, ok := body.Data["data"]; ok {
+		if _, versioned := body.Data["metadata"]; versioned {
+			fields = nil
+			if err := json.Unmarshal(nested, &fields); err != nil {
+				return "", fmt.Errorf("failed to decode Vault secret %s: %w", path, err)
+			}
+		}
+	}
+
+	raw, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("Vault secret %s has no field %s", path, field)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("Vault secret %s field %s is not a string", path, field)
+	}
+	return value, nil
+}
+
+// fetchAWSSecret reads a secret, or a field of a JSON secret, from AWS Secrets
+// Manager, using the default credential chain of the AWS SDK
+func fetchAWSSecret(ctx context.Context, id, field string) (string, error) {
+	if awsSecrets == nil {
+		awsConfig, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to load AWS configuration: %w", err)
+		}
+		awsSecrets = secretsmanager.NewFromConfig(awsConfig)
+	}
+
+	output, err := awsSecrets.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+	secret := aws.ToString(output.SecretString)
+	if field == "" {
+		return secret, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", fmt.Errorf("AWS secret %s is not a JSON object", id)
+	}
+	value, ok := fields[field].(string)
+	if !ok {
+		return "", fmt.Errorf("AWS secret %s has no string field %s", id, field)
+	}
+	return value, nil
+}
+
+// setupSecretRotation fetches the referenced secrets again every
+// SECRETS_REFRESH_INTERVAL, reloading the configuration when one changed so
+// reloadable settings pick the new value up; settings only read at startup
+// keep the value they started with until the process is restarted
+func setupSecretRotation() error {
+	value := os.Getenv(secretsRefreshIntervalEnv)
+	if value == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		return fmt.Errorf("invalid %s %q", secretsRefreshIntervalEnv, value)
+	}
+
+	secretReferencesMutex.Lock()
+	count := len(secretReferences)
+	secretReferencesMutex.Unlock()
+	if count == 0 {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runRecovered("secret rotation", refreshSecrets)
+		}
+	}()
+
+	slog.Info("Refreshing secrets", "count", count, "interval", interval)
+	return nil
+}
+
+// refreshSecrets fetches the referenced secrets and reloads the configuration if any rotated
+func refreshSecrets() {
+	changed, err := resolveSecretReferences()
+	if err != nil {
+		slog.Error("Failed to refresh secrets", logKeyError, err)
+	}
+	if len(changed) == 0 {
+		return
+	}
+	slog.Info("Secrets rotated", "variables", strings.Join(changed, ","))
+	reloadConfig()
+}
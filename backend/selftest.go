@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling the live part of the startup self-test:
+	// when true, the instance only reports ready once a short subscription of
+	// its own received a notification and decoded an event from it
+	selfTestLiveEnv = "SELF_TEST_LIVE"
+
+	// Environment variable overriding how long the live self-test waits for a
+	// decodable notification, e.g. 1m
+	selfTestLiveTimeoutEnv = "SELF_TEST_LIVE_TIMEOUT"
+
+	// Wait used when SELF_TEST_LIVE_TIMEOUT is unset; PumpFun logs arrive
+	// several times a second
+	defaultSelfTestLiveTimeout = 30 * time.Second
+)
+
+// selfTestFixtureJSON is a known-good creation transaction: its logs, with a
+// dev buy, and its create instruction, with the values they decode to
+//
+//go:embed fixtures/selftest.json
+var selfTestFixtureJSON []byte
+
+// selfTestFixture is the decoded form of selfTestFixtureJSON
+type selfTestFixture struct {
+	Signature   string   `json:"signature"` // Transaction signature
+	Slot        uint64   `json:"slot"`      // Slot of the transaction
+	Logs        []string `json:"logs"`      // Log messages of the transaction
+	Instruction struct {
+		Data     string   `json:"data"`     // Base64 data of the create instruction
+		Accounts []string `json:"accounts"` // Accounts of the create instruction, in order
+	} `json:"instruction"`
+	Expected struct {
+		Name           string `json:"name"`
+		Symbol         string `json:"symbol"`
+		Uri            string `json:"uri"`
+		Mint           string `json:"mint"`
+		BondingCurve   string `json:"bonding_curve"`
+		User           string `json:"user"`
+		DevBuyLamports uint64 `json:"dev_buy_lamports"`
+		DevBuyTokens   uint64 `json:"dev_buy_tokens"`
+	} `json:"expected"`
+}
+
+// selfTestStatus is the outcome of the startup self-test, read by the readiness check
+type selfTestStatus struct {
+	mutex  sync.Mutex
+	passed bool
+	err    error
+}
+
+// SelfTest holds the outcome of the startup self-test
+var SelfTest = &selfTestStatus{}
+
+// runSelfTest decodes the bundled fixture, refusing to start with a decoder
+// that cannot read it, then starts the live self-test when SELF_TEST_LIVE is set
+//
+// Parameters:
+//   - ctx: Context stopping the live self-test when cancelled
+//   - wsURL: WebSocket RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if the decoder fails on the fixture, or the live self-test
+//     is enabled without an endpoint
+func runSelfTest(ctx context.Context, wsURL string) error {
+	if err := checkDecoderFixture(); err != nil {
+		return fmt.Errorf("decoder self-test failed: %w", err)
+	}
+	slog.Info("Decoder self-test passed")
+
+	if !envBool(selfTestLiveEnv) {
+		SelfTest.finish(nil)
+		return nil
+	}
+	endpoint, err := resolveEndpoint(wsURL, heliusWebSocketURL)
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(envOrDefault(selfTestLiveTimeoutEnv, defaultSelfTestLiveTimeout.String()))
+	if err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid %s", selfTestLiveTimeoutEnv)
+	}
+
+	goSafe("live self-test", func() {
+		err := checkLiveSubscription(ctx, endpoint, timeout)
+		switch {
+		case ctx.Err() != nil && err != nil:
+			slog.Info("Live self-test interrupted by the shutdown")
+		case err != nil:
+			slog.Error("Live self-test failed, the instance will not report ready", logKeyError, err)
+		default:
+			slog.Info("Live self-test passed")
+		}
+		SelfTest.finish(err)
+	})
+	slog.Info("Running the live self-test", "timeout", timeout)
+	return nil
+}
+
+// finish records the outcome of the self-test
+func (s *selfTestStatus) finish(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.passed = err == nil
+	s.err = err
+}
+
+// Passed reports whether the self-test passed
+func (s *selfTestStatus) Passed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.passed
+}
+
+// Check returns the readiness check of the self-test
+func (s *selfTestStatus) Check() healthCheck {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case s.passed:
+		return healthCheck{Healthy: true}
+	case s.err != nil:
+		return healthCheck{Detail: s.err.Error()}
+	default:
+		return healthCheck{Detail: "live self-test running"}
+	}
+}
+
+// checkDecoderFixture decodes the logs and the create instruction of the
+// fixture and compares them with the expected values
+func checkDecoderFixture() error {
+	var fixture selfTestFixture
+	if err := json.Unmarshal(selfTestFixtureJSON, &fixture); err != nil {
+		return fmt.Errorf("failed to parse fixture: %w", err)
+	}
+	expected := fixture.Expected
+
+	var create *pumpstream.CreateEvent
+	var trade *pumpstream.TradeEvent
+	for _, log := range fixture.Logs {
+		decoded, err := pumpstream.DecodeLog(log)
+		if err != nil {
+			return fmt.Errorf("failed to decode fixture log: %w", err)
+		}
+		switch event := decoded.(type) {
+		case *pumpstream.CreateEvent:
+			create = event
+		case *pumpstream.TradeEvent:
+			trade = event
+		}
+	}
+	if create == nil {
+		return errors.New("no creation decoded from the fixture logs")
+	}
+	if err := compareCreation("log", create, fixture); err != nil {
+		return err
+	}
+	if trade == nil {
+		return errors.New("no dev buy decoded from the fixture logs")
+	}
+	if trade.Mint.String() != expected.Mint || !trade.IsBuy || trade.SolAmount != expected.DevBuyLamports || trade.TokenAmount != expected.DevBuyTokens {
+		return fmt.Errorf("dev buy decoded as %d lamports for %d tokens of %s, expected %d for %d of %s",
+			trade.SolAmount, trade.TokenAmount, trade.Mint, expected.DevBuyLamports, expected.DevBuyTokens, expected.Mint)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(fixture.Instruction.Data)
+	if err != nil {
+		return fmt.Errorf("failed to parse fixture instruction: %w", err)
+	}
+	accounts := make([]solana.PublicKey, len(fixture.Instruction.Accounts))
+	for i, account := range fixture.Instruction.Accounts {
+		if accounts[i], err = solana.PublicKeyFromBase58(account); err != nil {
+			return fmt.Errorf("failed to parse fixture instruction: %w", err)
+		}
+	}
+	// The fixture is a mainnet transaction, whatever program the feed listens to
+	decoded, err := pumpstream.DecodeInstruction(pumpstream.Program, data, accounts)
+	if err != nil {
+		return fmt.Errorf("failed to decode fixture instruction: %w", err)
+	}
+	if err := compareCreation("instruction", decoded.(*pumpstream.CreateEvent), fixture); err != nil {
+		return err
+	}
+
+	mint := solana.MustPublicKeyFromBase58(expected.Mint)
+	bondingCurve, err := pumpstream.BondingCurveAddress(pumpstream.Program, mint)
+	if err != nil || bondingCurve.String() != expected.BondingCurve {
+		return fmt.Errorf("bonding curve derived as %s, expected %s", bondingCurve, expected.BondingCurve)
+	}
+	return nil
+}
+
+// compareCreation compares a creation decoded from the fixture with the expected values
+func compareCreation(source string, create *pumpstream.CreateEvent, fixture selfTestFixture) error {
+	expected := fixture.Expected
+	if create.Name != expected.Name || create.Symbol != expected.Symbol || create.Uri != expected.Uri {
+		return fmt.Errorf("creation %s decoded as %q (%s) %s, expected %q (%s) %s",
+			source, create.Name, create.Symbol, create.Uri, expected.Name, expected.Symbol, expected.Uri)
+	}
+	if create.Mint.String() != expected.Mint || create.BondingCurve.String() != expected.BondingCurve || create.User.String() != expected.User {
+		return fmt.Errorf("creation %s decoded with mint %s, bonding curve %s and user %s, expected %s, %s and %s",
+			source, create.Mint, create.BondingCurve, create.User, expected.Mint, expected.BondingCurve, expected.User)
+	}
+	return nil
+}
+
+// checkLiveSubscription subscribes to the program logs until a notification
+// carries an event that decodes, failing if one does not decode or none
+// arrives within timeout
+//
+// Returns:
+//   - error: The decoding or subscription error, or the error of ctx when it
+//     is cancelled before an event arrives
+func checkLiveSubscription(ctx context.Context, wsURL string, timeout time.Duration) error {
+	check, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var result, lastError error
+	received := false
+	listener := &pumpstream.Listener{
+		URL:     wsURL,
+		Program: pumpProgram(),
+		OnDisconnect: func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			lastError = err
+		},
+	}
+	listener.Run(check, func(notification pumpstream.Notification) {
+		for _, log := range notification.Logs {
+			decoded, err := pumpstream.DecodeLog(log)
+			if errors.Is(err, pumpstream.ErrUnknownEvent) || (err == nil && decoded == nil) {
+				continue
+			}
+
+			mutex.Lock()
+			received = true
+			if err != nil {
+				result = fmt.Errorf("failed to decode live transaction %s: %w", notification.Signature, err)
+			}
+			mutex.Unlock()
+
+			// Done at the first event, decoded or not
+			cancel()
+			return
+		}
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	switch {
+	case result != nil:
+		return result
+	case received:
+		return nil
+	case ctx.Err() != nil:
+		// Shut down before any event arrived, which proves nothing
+		return ctx.Err()
+	case lastError != nil:
+		return fmt.Errorf("no event received within %s: %w", timeout, lastError)
+	default:
+		return fmt.Errorf("no event received within %s", timeout)
+	}
+}
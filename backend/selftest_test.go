@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeLogsEndpoint serves a WebSocket RPC endpoint answering logsSubscribe,
+// then sending one notification with the given logs when there are any
+func fakeLogsEndpoint(t *testing.T, logs []string) string {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var request struct {
+			ID uint64 `json:"id"`
+		}
+		if err := conn.ReadJSON(&request); err != nil {
+			return
+		}
+		conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": 1})
+		if len(logs) > 0 {
+			conn.WriteJSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "logsNotification",
+				"params": map[string]interface{}{
+					"subscription": 1,
+					"result": map[string]interface{}{
+						"context": map[string]interface{}{"slot": 1},
+						"value":   map[string]interface{}{"signature": "1111111111111111111111111111111111111111111111111111111111111111", "err": nil, "logs": logs},
+					},
+				},
+			})
+		}
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// TestCheckLiveSubscription checks that the live self-test passes only once an
+// event decodes, and that a shutdown or a timeout before then is not a pass
+func TestCheckLiveSubscription(t *testing.T) {
+	var fixture selfTestFixture
+	if err := json.Unmarshal(selfTestFixtureJSON, &fixture); err != nil {
+		t.Fatalf("failed to decode the fixture: %v", err)
+	}
+
+	t.Run("event decoded", func(t *testing.T) {
+		if err := checkLiveSubscription(context.Background(), fakeLogsEndpoint(t, fixture.Logs), 5*time.Second); err != nil {
+			t.Fatalf("got %v, expected a pass", err)
+		}
+	})
+
+	t.Run("event not decoding", func(t *testing.T) {
+		// A creation cut short after the discriminator
+		logs := []string{"Program data: G3KpTd7rY3YFAA=="}
+		if err := checkLiveSubscription(context.Background(), fakeLogsEndpoint(t, logs), 5*time.Second); err == nil || strings.Contains(err.Error(), "no event") {
+			t.Fatalf("got %v, expected the decoding error", err)
+		}
+	})
+
+	t.Run("no event in time", func(t *testing.T) {
+		err := checkLiveSubscription(context.Background(), fakeLogsEndpoint(t, nil), 50*time.Millisecond)
+		if err == nil || errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, expected a timeout", err)
+		}
+	})
+
+	t.Run("shut down before an event", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		if err := checkLiveSubscription(ctx, fakeLogsEndpoint(t, nil), 5*time.Second); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, expected %v", err, context.Canceled)
+		}
+	})
+}
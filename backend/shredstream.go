@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the gRPC address of the Jito ShredStream proxy,
+	// e.g. 127.0.0.1:9999, required with --source shredstream
+	shredStreamAddrEnv = "SHREDSTREAM_ADDR"
+
+	// Receive entries from a Jito ShredStream proxy
+	sourceShredStream = "shredstream"
+
+	// Streaming method of the proxy sending the entries of every slot
+	shredStreamSubscribeMethod = "/shredstream.ShredstreamProxy/SubscribeEntries"
+
+	// Field numbers of the Entry message of the proxy
+	shredStreamFieldSlot    = 1 // uint64 slot
+	shredStreamFieldEntries = 2 // bytes entries: bincode of Vec<solana_entry::Entry>
+)
+
+// rawCodec passes gRPC messages through as encoded bytes, so the few fields of
+// the proxy messages are read with protowire instead of generated code
+type rawCodec struct{}
+
+// Name is the content subtype of the messages
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// Marshal returns the already encoded message
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	return v.([]byte), nil
+}
+
+// Unmarshal copies the encoded message
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	message := v.(*[]byte)
+	*message = append((*message)[:0], data...)
+	return nil
+}
+
+// listenToShredStream subscribes to the entries of a Jito ShredStream proxy and
+// runs the creations they contain through the pipeline, before the transactions
+// execute and their logs reach RPC subscriptions. It resubscribes automatically
+// and records the subscription health in FeedStats, until ctx is done.
+//
+// Transactions in shreds have not executed yet: only create instructions are
+// decoded, since the outcome of a trade is not known, and a creation is
+// delivered even if its transaction later fails. Notifications are not handed
+// to the Redis bridge or cluster peers, which expect logs.
+//
+// Parameters:
+//   - ctx: Context whose cancellation closes the subscription
+//
+// Returns:
+//   - <-chan struct{}: Closed once the subscription stopped
+//   - error: Error if SHREDSTREAM_ADDR is not set or invalid
+func listenToShredStream(ctx context.Context) (<-chan struct{}, error) {
+	addr := os.Getenv(shredStreamAddrEnv)
+	if addr == "" {
+		return nil, fmt.Errorf("%s must be set to use the %s source", shredStreamAddrEnv, sourceShredStream)
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the ShredStream proxy: %w", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		defer conn.Close()
+		for {
+			err := receiveShredStreamEntries(ctx, conn)
+			if ctx.Err() != nil {
+				return
+			}
+			FeedStats.RecordUpstreamError(err)
+			slog.Warn("ShredStream subscription lost", logKeyError, err, "retry_in", reconnectDelay)
+			reportError(errorCategoryUpstream, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+
+	slog.Info("Receiving entries from ShredStream proxy", "addr", addr)
+	return stopped, nil
+}
+
+// receiveShredStreamEntries handles the entries of a subscription until it fails or ctx is done
+func receiveShredStreamEntries(ctx context.Context, conn *grpc.ClientConn) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, shredStreamSubscribeMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return err
+	}
+	// SubscribeEntriesRequest has no fields
+	if err := stream.SendMsg([]byte{}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	var message []byte
+	for connected := false; ; connected = true {
+		if err := stream.RecvMsg(&message); err != nil {
+			return err
+		}
+		// The subscription is only known to work once the proxy sends something
+		if !connected {
+			FeedStats.RecordUpstreamConnected()
+		}
+		FeedStats.RecordUpstreamMessage()
+
+		slot, entries, err := parseShredStreamEntry(message)
+		if err != nil {
+			slog.Warn("Invalid ShredStream message", logKeyError, err)
+			continue
+		}
+		runRecovered("shredstream entries", func() {
+			if err := processShredEntries(slot, entries); err != nil {
+				slog.Warn("Invalid ShredStream entries", logKeySlot, slot, logKeyError, err)
+			}
+		})
+	}
+}
+
+// parseShredStreamEntry reads the slot and the encoded entries of an Entry message
+func parseShredStreamEntry(message []byte) (uint64, []byte, error) {
+	var (
+		slot    uint64
+		entries []byte
+	)
+	for len(message) > 0 {
+		number, kind, n := protowire.ConsumeTag(message)
+		if n < 0 {
+			return 0, nil, protowire.ParseError(n)
+		}
+		message = message[n:]
+
+		switch {
+		case number == shredStreamFieldSlot && kind == protowire.VarintType:
+			slot, n = protowire.ConsumeVarint(message)
+		case number == shredStreamFieldEntries && kind == protowire.BytesType:
+			entries, n = protowire.ConsumeBytes(message)
+		default:
+			n = protowire.ConsumeFieldValue(number, kind, message)
+		}
+		if n < 0 {
+			return 0, nil, protowire.ParseError(n)
+		}
+		message = message[n:]
+	}
+	return slot, entries, nil
+}
+
+// processShredEntries decodes the transactions of the entries of a slot and runs
+// the program instructions they contain through the pipeline
+//
+// Parameters:
+//   - slot: Slot the entries belong to
+//   - entries: bincode of Vec<Entry>, each entry being num_hashes: u64,
+//     hash: [u8; 32] and transactions: Vec<VersionedTransaction>
+//
+// Returns:
+//   - error: Error if the entries are malformed; the transactions before the
+//     malformed one are still processed
+func processShredEntries(slot uint64, entries []byte) error {
+	receivedAt := time.Now().UTC()
+	decoder := bin.NewBinDecoder(entries)
+
+	entryCount, err := decoder.ReadUint64(bin.LE)
+	if err != nil {
+		return err
+	}
+	for range entryCount {
+		// Skip num_hashes and hash
+		if err := decoder.Discard(8 + 32); err != nil {
+			return err
+		}
+		transactionCount, err := decoder.ReadUint64(bin.LE)
+		if err != nil {
+			return err
+		}
+		for range transactionCount {
+			var transaction solana.Transaction
+			if err := transaction.UnmarshalWithDecoder(decoder); err != nil {
+				return err
+			}
+			if events := shredTransactionEvents(&transaction, slot); len(events) > 0 {
+				processTransaction(events[0].Signature, slot, receivedAt, events)
+			}
+		}
+	}
+	return nil
+}
+
+// shredTransactionEvents decodes the program instructions of a transaction
+func shredTransactionEvents(transaction *solana.Transaction, slot uint64) []*PipelineEvent {
+	if len(transaction.Signatures) == 0 {
+		return nil
+	}
+	// Accounts loaded from address lookup tables are left zero
+	return instructionEvents(transaction.Signatures[0].String(), slot, transaction.Message.AccountKeys, transaction.Message.Instructions, 0)
+}
+
+// instructionEvents decodes the instructions of the program among instructions
+//
+// Parameters:
+//   - signature: Transaction signature
+//   - slot: Slot the transaction was observed in
+//   - keys: Account keys the instructions index; accounts past them are left zero
+//   - instructions: Instructions of the transaction, in execution order
+//   - firstIndex: Index of the first instruction, numbering the events apart
+//     from those of other sources of the same transaction for deduplication
+func instructionEvents(signature string, slot uint64, keys []solana.PublicKey, instructions []solana.CompiledInstruction, firstIndex int) []*PipelineEvent {
+	var events []*PipelineEvent
+	program := pumpProgram()
+	for index, instruction := range instructions {
+		if int(instruction.ProgramIDIndex) >= len(keys) || !keys[instruction.ProgramIDIndex].Equals(program) {
+			continue
+		}
+
+		accounts := make([]solana.PublicKey, len(instruction.Accounts))
+		for i, account := range instruction.Accounts {
+			if int(account) < len(keys) {
+				accounts[i] = keys[account]
+			}
+		}
+
+		decoded, err := pumpstream.DecodeInstruction(program, instruction.Data, accounts)
+		if errors.Is(err, pumpstream.ErrUnknownInstruction) {
+			continue
+		}
+		if err != nil {
+			reportError(errorCategoryDecode, err, logKeySignature, signature, logKeySlot, slot)
+			continue
+		}
+		events = append(events, &PipelineEvent{Signature: signature, Slot: slot, Index: firstIndex + index, Decoded: decoded})
+	}
+	return events
+}
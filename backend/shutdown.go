@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Configuration constants
+const (
+	// Environment variable with how long each shutdown step may take, e.g. 30s:
+	// finishing in-flight requests, stopping the upstream subscription and
+	// delivering the events queued on sinks
+	shutdownTimeoutEnv = "SHUTDOWN_TIMEOUT"
+
+	// Shutdown step timeout used when SHUTDOWN_TIMEOUT is unset
+	defaultShutdownTimeout = 15 * time.Second
+
+	// Close reason sent to WebSocket clients when the server stops
+	shutdownCloseReason = "server shutting down"
+
+	// Longest a close frame may take to write to one client
+	closeFrameTimeout = time.Second
+)
+
+// shutdownContext returns the root context of a long running command,
+// cancelled once the process receives SIGINT or SIGTERM; everything the
+// command starts stops when it is done. A second signal exits immediately.
+func shutdownContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Info("Received signal, shutting down gracefully", "signal", sig.String())
+		sdNotify("STOPPING=1")
+		cancel()
+
+		sig = <-signals
+		slog.Warn("Received signal again, exiting immediately", "signal", sig.String())
+		os.Exit(1)
+	}()
+	return ctx
+}
+
+// shutdownTimeout returns the timeout of each shutdown step, from SHUTDOWN_TIMEOUT
+func shutdownTimeout() time.Duration {
+	if value := os.Getenv(shutdownTimeoutEnv); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			return parsed
+		}
+		slog.Warn("Ignoring invalid shutdown timeout", "value", value, "default", defaultShutdownTimeout)
+	}
+	return defaultShutdownTimeout
+}
+
+// shutdownServer stops the HTTP server: the listeners close at once, REST
+// requests get until the shutdown timeout to finish, and WebSocket clients,
+// whose upgraded connections the server no longer tracks, receive a close frame
+func shutdownServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Failed to shut down the server", logKeyError, err)
+	}
+	closeClients()
+
+	slog.Info("Server stopped")
+}
+
+// closeClients sends a going away close frame to every connected WebSocket
+// client, so it reconnects elsewhere instead of seeing a dropped connection,
+// and closes the connection; their read loops then end and record the disconnect
+func closeClients() {
+	count := disconnectClients(websocket.CloseGoingAway, shutdownCloseReason, func(*Client) bool { return true })
+	if count > 0 {
+		slog.Info("Closed WebSocket connections", "count", count)
+	}
+}
+
+// disconnectClients sends a close frame to the connected clients matching a
+// condition and closes their connections
+//
+// Parameters:
+//   - code: Close code of the frame
+//   - reason: Close reason of the frame
+//   - match: Reports whether a client is disconnected
+//
+// Returns:
+//   - int: Number of connections closed
+func disconnectClients(code int, reason string, match func(*Client) bool) int {
+	message := websocket.FormatCloseMessage(code, reason)
+
+	count := 0
+	ConnectedClients.Range(func(address string, client *Client) bool {
+		if !match(client) {
+			return true
+		}
+		count++
+		// Control frames may be written while a broadcast holds the client lock
+		if err := client.Connection.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeFrameTimeout)); err != nil {
+			slog.Debug("Failed to send close frame", logKeyClientID, address, logKeyError, err)
+		}
+		client.Connection.Close()
+		return true
+	})
+	return count
+}
+
+// finishShutdown waits for the ingestion to stop, then delivers the events
+// still queued on sinks, giving up on each step after the shutdown timeout
+//
+// Parameters:
+//   - ingestion: Closed once the upstream subscription stopped, nil if there is none
+func finishShutdown(ingestion <-chan struct{}) {
+	timeout := shutdownTimeout()
+
+	if ingestion != nil {
+		select {
+		case <-ingestion:
+		case <-time.After(timeout):
+			slog.Warn("Upstream subscription did not stop in time", "timeout", timeout)
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		runRecovered("sink drain", drainSinks)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(timeout):
+		slog.Warn("Sinks did not deliver their queued events in time", "timeout", timeout)
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file listing Slack targets
+	// The Slack integration is disabled when it is unset
+	slackTargetsFileEnv = "SLACK_TARGETS_FILE"
+
+	// Web API endpoint used with bot tokens
+	slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+	// Buy size in SOL that makes a trade a whale buy when a target does not set one
+	defaultWhaleBuySOL = 10.0
+
+	// Lamports per SOL
+	lamportsPerSOL = 1_000_000_000
+
+	// Minimum delay between two messages to the same target, per Slack limits
+	slackTargetInterval = 1 * time.Second
+
+	// Number of messages buffered per target before new ones are dropped
+	slackQueueSize = 100
+
+	// Maximum send attempts per message when rate limited
+	slackMaxAttempts = 3
+
+	// Template used when a target does not set one
+	defaultSlackTemplate = `{{.Title}}: *{{.Name}}* (${{.Symbol}}){{if .SolAmount}} for {{printf "%.2f" .SolAmount}} SOL{{end}}{{if .PriceUSD}} at ${{printf "%.6g" .PriceUSD}}{{end}} <{{.PumpFunURL}}|pump.fun> | <{{.SolscanURL}}|Solscan>{{if .Flags}} :warning: {{.Flags}}{{end}}`
+)
+
+// SlackTarget describes a Slack destination for alert notifications
+// Either WebhookURL, or BotToken together with Channel, must be set
+type SlackTarget struct {
+	WebhookURL string       `json:"webhook_url,omitempty"` // Incoming webhook URL
+	BotToken   string       `json:"bot_token,omitempty"`   // Bot token used with chat.postMessage
+	Channel    string       `json:"channel,omitempty"`     // Channel ID or name for the bot token
+	Filter     NotifyFilter `json:"filter"`                // Events posted, defaults to trades and graduations
+	MinBuySOL  *float64     `json:"min_buy_sol,omitempty"` // Smallest buy in SOL that is posted, 0 for every buy; defaultWhaleBuySOL when absent
+	Template   string       `json:"template,omitempty"`    // text/template rendering the message
+}
+
+// slackMessageData is the data available to Slack message templates
+type slackMessageData struct {
+	Title      string  // Alert kind, e.g. "Whale buy" or "Graduated"
+	Name       string  // Token name
+	Symbol     string  // Token symbol
+	Mint       string  // Token mint address
+	Creator    string  // Creator wallet
+	Trader     string  // Trader wallet, for trades
+	SolAmount  float64 // SOL spent, for trades
+	PriceUSD   float64 // Quoted USD price, for price updates
+	PumpFunURL string  // pump.fun page of the token
+	SolscanURL string  // Solscan page of the token
+	Flags      string  // Comma separated risk flags
+}
+
+// slackTarget owns the queue and template of one configured target
+type slackTarget struct {
+	config   SlackTarget
+	template *template.Template
+	queue    chan string
+}
+
+// SlackSink posts alert-class events such as whale buys and graduations to Slack
+type SlackSink struct {
+	client *http.Client
+
+	mutex   sync.RWMutex // Guards targets, which are replaced on reload
+	targets []*slackTarget
+}
+
+// setupSlackSink registers the Slack integration when SLACK_TARGETS_FILE is set
+func setupSlackSink() error {
+	path := os.Getenv(slackTargetsFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	targets, err := loadSlackTargets(path)
+	if err != nil {
+		return err
+	}
+
+	sink := &SlackSink{client: &http.Client{Timeout: webhookRequestTimeout}, targets: targets}
+	for _, target := range targets {
+		go sink.sendLoop(target)
+	}
+	RegisterSink(sink)
+
+	slog.Info("Posting alerts to Slack", "targets", len(sink.targets))
+	return nil
+}
+
+// loadSlackTargets reads and validates the targets file, without starting their send loops
+func loadSlackTargets(path string) ([]*slackTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Slack targets file: %w", err)
+	}
+
+	var configs []SlackTarget
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse Slack targets file: %w", err)
+	}
+
+	var targets []*slackTarget
+	for i, config := range configs {
+		if config.WebhookURL == "" && (config.BotToken == "" || config.Channel == "") {
+			return nil, fmt.Errorf("Slack target %d: webhook_url or bot_token and channel must be set", i)
+		}
+		if len(config.Filter.EventTypes) == 0 {
+			config.Filter.EventTypes = []EventType{EventTrade, EventComplete}
+		}
+		if config.MinBuySOL == nil {
+			minBuySOL := defaultWhaleBuySOL
+			config.MinBuySOL = &minBuySOL
+		} else if *config.MinBuySOL < 0 {
+			return nil, fmt.Errorf("Slack target %d: min_buy_sol must not be negative", i)
+		}
+		if config.Template == "" {
+			config.Template = defaultSlackTemplate
+		}
+		if err := config.Filter.Compile(); err != nil {
+			return nil, fmt.Errorf("Slack target %d: %w", i, err)
+		}
+
+		parsed, err := template.New("slack").Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("Slack target %d: invalid template: %w", i, err)
+		}
+
+		targets = append(targets, &slackTarget{config: config, template: parsed, queue: make(chan string, slackQueueSize)})
+	}
+	return targets, nil
+}
+
+// Reload replaces the targets with those in SLACK_TARGETS_FILE
+// Messages already queued on the previous targets are still delivered
+func (s *SlackSink) Reload() error {
+	path := os.Getenv(slackTargetsFileEnv)
+	if path == "" {
+		return fmt.Errorf("%s is no longer set, keeping the current targets", slackTargetsFileEnv)
+	}
+
+	targets, err := loadSlackTargets(path)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		go s.sendLoop(target)
+	}
+
+	s.mutex.Lock()
+	previous := s.targets
+	s.targets = targets
+	s.mutex.Unlock()
+
+	// Nothing queues on the previous targets any more; their loops exit once drained
+	for _, target := range previous {
+		close(target.queue)
+	}
+	return nil
+}
+
+// Name identifies the sink in logs
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+// Publish renders and queues the event on every target it is an alert for
+func (s *SlackSink) Publish(event Event) {
+	// Only buys are interesting among trades; bail out early on the firehose
+	trade, isTrade := event.Data.(TradeEvent)
+	if isTrade && !trade.IsBuy {
+		return
+	}
+
+	record, found := Tokens.Get(event.Mint)
+	if !found {
+		return
+	}
+	flags := newRiskFlagSet(record)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, target := range s.targets {
+		if isTrade && float64(trade.SolAmount)/lamportsPerSOL < *target.config.MinBuySOL {
+			continue
+		}
+		if !target.config.Filter.Matches(event, record, flags) {
+			continue
+		}
+
+		message, err := renderSlackMessage(target.template, event, record, flags.Get())
+		if err != nil {
+			slog.Warn("Failed to render Slack message", logKeyError, err)
+			continue
+		}
+
+		select {
+		case target.queue <- message:
+		default:
+			slog.Warn("Slack queue full, dropping alert", logKeyEventType, event.Type, logKeyMint, event.Mint)
+		}
+	}
+}
+
+// Alert queues an operational alert on every target that opted in
+func (s *SlackSink) Alert(alert OperationalAlert) {
+	icon := ":rotating_light:"
+	if !alert.Firing {
+		icon = ":white_check_mark:"
+	}
+	message := icon + " " + alert.Message
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, target := range s.targets {
+		if !target.config.Filter.Alerts {
+			continue
+		}
+		select {
+		case target.queue <- message:
+		default:
+			slog.Warn("Slack queue full, dropping alert", "kind", alert.Kind)
+		}
+	}
+}
+
+// Digest queues a digest on every target that opted in
+func (s *SlackSink) Digest(digest Digest) {
+	message := ":newspaper: " + formatDigest(digest)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, target := range s.targets {
+		if !target.config.Filter.Digests {
+			continue
+		}
+		select {
+		case target.queue <- message:
+		default:
+			slog.Warn("Slack queue full, dropping digest", "period", digest.Period)
+		}
+	}
+}
+
+// QueueDepth returns the number of messages waiting on every target
+func (s *SlackSink) QueueDepth() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	depth := 0
+	for _, target := range s.targets {
+		depth += len(target.queue)
+	}
+	return depth
+}
+
+// sendLoop delivers queued messages to one target, respecting rate limits
+func (s *SlackSink) sendLoop(target *slackTarget) {
+	for message := range target.queue {
+		for attempt := 1; attempt <= slackMaxAttempts; attempt++ {
+			retryAfter, err := s.send(target.config, message)
+			if err == nil {
+				break
+			}
+
+			slog.Warn("Failed to post to Slack", logKeyError, err)
+			if retryAfter == 0 {
+				break
+			}
+			time.Sleep(retryAfter)
+		}
+
+		time.Sleep(slackTargetInterval)
+	}
+}
+
+// send posts a single message through the webhook or the Web API
+//
+// Returns:
+//   - time.Duration: how long Slack asked us to wait before retrying, 0 if not rate limited
+//   - error: any error that occurred while sending
+func (s *SlackSink) send(target SlackTarget, message string) (time.Duration, error) {
+	payload := map[string]interface{}{"text": message}
+	url := target.WebhookURL
+	if url == "" {
+		payload["channel"] = target.Channel
+		url = slackPostMessageURL
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if target.WebhookURL == "" {
+		req.Header.Set("Authorization", "Bearer "+target.BotToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(max(seconds, 1)) * time.Second, fmt.Errorf("rate limited")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	// The Web API reports failures in the body with a 200 status
+	if target.WebhookURL == "" {
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return 0, err
+		}
+		if !result.OK {
+			return 0, fmt.Errorf("%s", result.Error)
+		}
+	}
+	return 0, nil
+}
+
+// renderSlackMessage executes a target template for an event
+func renderSlackMessage(tmpl *template.Template, event Event, record TokenRecord, flags []string) (string, error) {
+	data := slackMessageData{
+		Title:      "New token",
+		Name:       record.Creation.Name,
+		Symbol:     record.Creation.Symbol,
+		Mint:       record.Mint,
+		Creator:    record.Creator,
+		PumpFunURL: pumpFunLink(record.Mint),
+		SolscanURL: solscanLink(record.Mint),
+		Flags:      strings.Join(flags, ", "),
+	}
+
+	switch eventData := event.Data.(type) {
+	case TradeEvent:
+		data.Title = "Whale buy"
+		data.Trader = eventData.User
+		data.SolAmount = float64(eventData.SolAmount) / lamportsPerSOL
+	case CompleteEvent:
+		data.Title = "Graduated"
+	case PriceEvent:
+		data.Title = "Price"
+		data.PriceUSD = eventData.PriceUSD
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the HTTP RPC endpoint the subscription is
+	// compared against; use a different provider than the WebSocket endpoint,
+	// so an outage of one is not hidden by the other
+	// Slot lag monitoring is disabled when it is unset
+	slotLagRPCURLEnv = "SLOT_LAG_RPC_URL"
+
+	// Environment variable with the interval between comparisons, e.g. 30s
+	slotLagIntervalEnv = "SLOT_LAG_INTERVAL"
+
+	// Environment variable with the lag in slots above which the feed is
+	// considered behind the chain
+	slotLagThresholdEnv = "SLOT_LAG_THRESHOLD"
+
+	// Interval used when SLOT_LAG_INTERVAL is unset
+	defaultSlotLagInterval = 30 * time.Second
+
+	// Threshold used when SLOT_LAG_THRESHOLD is unset; slots take about 400ms,
+	// so this is roughly a minute behind
+	defaultSlotLagThreshold = 150
+
+	// Longest a getSlot request may take
+	slotLagRequestTimeout = 10 * time.Second
+)
+
+// SlotLagStatus is the outcome of the latest comparison of the subscription
+// with the independent RPC endpoint
+type SlotLagStatus struct {
+	SubscriptionSlot uint64    `json:"subscription_slot"` // Highest slot seen on the subscription
+	ChainSlot        uint64    `json:"chain_slot"`        // Slot reported by getSlot
+	Lag              int64     `json:"lag"`               // Chain slot minus subscription slot
+	Threshold        uint64    `json:"threshold"`         // Lag above which the feed is behind
+	Behind           bool      `json:"behind"`            // True if the lag is above the threshold
+	CheckedAt        time.Time `json:"checked_at"`        // Time of the comparison
+	Error            string    `json:"error,omitempty"`   // Why the last getSlot failed
+}
+
+// SlotLagMonitor periodically compares the latest slot of the subscription
+// with getSlot from another RPC endpoint
+type SlotLagMonitor struct {
+	client    *rpc.Client
+	threshold uint64
+
+	mutex  sync.RWMutex
+	status SlotLagStatus
+	ready  bool // True once a comparison succeeded
+}
+
+// SlotLag monitors the feed when SLOT_LAG_RPC_URL is set, nil otherwise
+var SlotLag *SlotLagMonitor
+
+// setupSlotLagMonitor starts monitoring the slot lag when SLOT_LAG_RPC_URL is set
+func setupSlotLagMonitor() error {
+	endpoint := os.Getenv(slotLagRPCURLEnv)
+	if endpoint == "" {
+		return nil
+	}
+	registerURLSecrets(endpoint)
+
+	interval := defaultSlotLagInterval
+	if value := os.Getenv(slotLagIntervalEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", slotLagIntervalEnv, value)
+		}
+		interval = parsed
+	}
+
+	threshold := uint64(defaultSlotLagThreshold)
+	if value := os.Getenv(slotLagThresholdEnv); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", slotLagThresholdEnv, value)
+		}
+		threshold = parsed
+	}
+
+	SlotLag = &SlotLagMonitor{client: rpc.New(endpoint), threshold: threshold}
+	go SlotLag.run(interval)
+
+	slog.Info("Monitoring slot lag", "interval", interval, "threshold", threshold)
+	return nil
+}
+
+// Status returns the latest comparison
+//
+// Returns:
+//   - SlotLagStatus: The latest comparison
+//   - bool: False until a comparison succeeded
+func (m *SlotLagMonitor) Status() (SlotLagStatus, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.status, m.ready
+}
+
+// run compares the slots at a fixed interval
+func (m *SlotLagMonitor) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.check()
+	}
+}
+
+// check compares the slots once, logging when the feed falls behind the
+// threshold or catches up again
+// Nothing is compared before the first notification
+func (m *SlotLagMonitor) check() {
+	subscriptionSlot := FeedStats.Snapshot().Upstream.LatestSlot
+	if subscriptionSlot == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), slotLagRequestTimeout)
+	chainSlot, err := m.client.GetSlot(ctx, rpc.CommitmentProcessed)
+	cancel()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err != nil {
+		m.status.Error = err.Error()
+		slog.Warn("Failed to get the chain slot for lag monitoring", logKeyError, err)
+		return
+	}
+
+	lag := int64(chainSlot) - int64(subscriptionSlot)
+	behind := lag > int64(m.threshold)
+	switch {
+	case behind && !m.status.Behind:
+		slog.Warn("Feed is falling behind the chain", "lag", lag, "threshold", m.threshold, logKeySlot, subscriptionSlot, "chain_slot", chainSlot)
+	case !behind && m.status.Behind:
+		slog.Info("Feed caught up with the chain", "lag", lag, logKeySlot, subscriptionSlot, "chain_slot", chainSlot)
+	}
+
+	m.status = SlotLagStatus{
+		SubscriptionSlot: subscriptionSlot,
+		ChainSlot:        chainSlot,
+		Lag:              lag,
+		Threshold:        m.threshold,
+		Behind:           behind,
+		CheckedAt:        time.Now().UTC(),
+	}
+	m.ready = true
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the path of the SQLite database file
+	// The SQLite backend is disabled when it is unset
+	sqlitePathEnv = "SQLITE_PATH"
+)
+
+// migrationFiles holds the schema migrations of every database backend
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// SQLiteStore persists events and token state to an embedded SQLite database
+type SQLiteStore struct {
+	*eventBatcher
+	db     *sql.DB
+	blocks *eventBlocks
+}
+
+// setupSQLiteStorage opens the SQLite backend when SQLITE_PATH is set
+func setupSQLiteStorage() (Storage, error) {
+	path := os.Getenv(sqlitePathEnv)
+	if path == "" {
+		return nil, nil
+	}
+
+	// WAL lets the API read while the sink writes
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	applied, err := applyMigrations(db, migrationFiles, "migrations/sqlite")
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate SQLite database: %w", err)
+	}
+
+	store := &SQLiteStore{db: db, blocks: &eventBlocks{
+		db:               db,
+		placeholder:      func(int) string { return "?" },
+		receivedAtMillis: "received_at",
+	}}
+	restored, err := store.restoreTokens()
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore tokens from SQLite: %w", err)
+	}
+	store.eventBatcher = newEventBatcher("sqlite", store.writeBatch)
+
+	slog.Info("Persisting events to SQLite", "path", path, "migrations_applied", applied, "tokens_restored", restored)
+	return store, nil
+}
+
+// writeBatch inserts the events and updates the affected tokens in one transaction
+func (s *SQLiteStore) writeBatch(batch []Event) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insertEvent, err := tx.Prepare(`INSERT INTO events (type, mint, signature, slot, received_at, data) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insertEvent.Close()
+
+	upsertToken, err := tx.Prepare(`INSERT INTO tokens (mint, created_at, creator, record) VALUES (?, ?, ?, ?)
+		ON CONFLICT (mint) DO UPDATE SET record = excluded.record`)
+	if err != nil {
+		return err
+	}
+	defer upsertToken.Close()
+
+	for _, event := range batch {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		if _, err := insertEvent.Exec(event.Type, event.Mint, event.Signature, event.Slot, event.ReceivedAt.UnixMilli(), string(data)); err != nil {
+			return err
+		}
+	}
+
+	// The in-memory records already reflect the events of the batch
+	for _, record := range batchTokens(batch) {
+		encoded, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err := upsertToken.Exec(record.Mint, record.CreatedAt.UnixMilli(), record.Creator, string(encoded)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// restoreTokens loads the most recent tokens into the in-memory store
+func (s *SQLiteStore) restoreTokens() (int, error) {
+	rows, err := s.db.Query(`SELECT record FROM tokens ORDER BY created_at DESC LIMIT ?`, maxTrackedTokens)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var records []TokenRecord
+	for rows.Next() {
+		var encoded []byte
+		if err := rows.Scan(&encoded); err != nil {
+			return 0, err
+		}
+		var record TokenRecord
+		if err := json.Unmarshal(encoded, &record); err != nil {
+			return 0, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Restore oldest first so eviction order matches creation order
+	slices.Reverse(records)
+	Tokens.Restore(records)
+	return len(records), nil
+}
+
+// GetToken returns the stored record of a token
+//
+// Returns:
+//   - TokenRecord: the stored record
+//   - bool: false if the mint was never stored
+//   - error: Error if the database could not be queried
+func (s *SQLiteStore) GetToken(mint string) (TokenRecord, bool, error) {
+	var encoded []byte
+	err := s.db.QueryRow(`SELECT record FROM tokens WHERE mint = ?`, mint).Scan(&encoded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenRecord{}, false, nil
+	}
+	if err != nil {
+		return TokenRecord{}, false, err
+	}
+
+	var record TokenRecord
+	if err := json.Unmarshal(encoded, &record); err != nil {
+		return TokenRecord{}, false, err
+	}
+	return record, true, nil
+}
+
+// ScanEvents calls handle for every stored event matching the query, oldest
+// first, starting with compressed events
+func (s *SQLiteStore) ScanEvents(query EventQuery, handle func(Event) error) error {
+	if err := s.blocks.scan(query, handle); err != nil {
+		return err
+	}
+
+	where, args := eventQueryFilter(query,
+		func(int) string { return "?" },
+		func(t time.Time) interface{} { return t.UnixMilli() })
+
+	rows, err := s.db.Query(`SELECT type, mint, signature, slot, received_at, data FROM events`+where+` ORDER BY id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event Event
+		var receivedAt int64
+		var data []byte
+		if err := rows.Scan(&event.Type, &event.Mint, &event.Signature, &event.Slot, &receivedAt, &data); err != nil {
+			return err
+		}
+		event.ReceivedAt = time.UnixMilli(receivedAt).UTC()
+		if event.Data, err = decodeEventData(event.Type, data); err != nil {
+			return err
+		}
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Prune deletes events and tokens the retention policy no longer keeps
+// Rows are deleted in batches so the sink is never blocked for long; SQLite
+// reuses the freed pages rather than shrinking the file
+func (s *SQLiteStore) Prune(policy RetentionPolicy) (int64, error) {
+	var deleted int64
+
+	if cutoff := policy.cutoff(); !cutoff.IsZero() {
+		n, err := s.deleteInBatches(`DELETE FROM events WHERE id IN (SELECT id FROM events WHERE received_at < ? ORDER BY id LIMIT ?)`, cutoff.UnixMilli())
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		if _, err := s.deleteInBatches(`DELETE FROM tokens WHERE mint IN (SELECT mint FROM tokens WHERE created_at < ? LIMIT ?)`, cutoff.UnixMilli()); err != nil {
+			return deleted, err
+		}
+		n, err = s.blocks.pruneOlder(cutoff)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	if policy.MaxEvents > 0 {
+		var newest int64
+		if err := s.db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM events`).Scan(&newest); err != nil {
+			return deleted, err
+		}
+		// All events may have been compressed into blocks
+		newestCompressed, err := s.blocks.newestID()
+		if err != nil {
+			return deleted, err
+		}
+		// Ids are assigned in insertion order, so everything below the threshold
+		// is older than the newest MaxEvents events
+		threshold := max(newest, newestCompressed) - policy.MaxEvents
+		n, err := s.deleteInBatches(`DELETE FROM events WHERE id IN (SELECT id FROM events WHERE id <= ? ORDER BY id LIMIT ?)`, threshold)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+		n, err = s.blocks.pruneThrough(threshold)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// Compact compresses events received before the given time into blocks
+// SQLite reuses the pages the moved rows leave free, so the file stops growing
+// while old events keep arriving at a fraction of their size
+func (s *SQLiteStore) Compact(before time.Time) (int64, error) {
+	return s.blocks.compact(before)
+}
+
+// Ping checks that the database file can still be queried
+func (s *SQLiteStore) Ping() error {
+	return s.db.Ping()
+}
+
+// deleteInBatches runs a DELETE taking a bound and a batch size until it deletes
+// fewer rows than a full batch
+func (s *SQLiteStore) deleteInBatches(statement string, bound int64) (int64, error) {
+	var total int64
+	for {
+		result, err := s.db.Exec(statement, bound, storagePruneBatchSize)
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < storagePruneBatchSize {
+			return total, nil
+		}
+	}
+}
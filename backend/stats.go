@@ -0,0 +1,142 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// UpstreamHealth describes the state of the Solana WebSocket subscription
+type UpstreamHealth struct {
+	Connected     bool      `json:"connected"`                // True while subscribed
+	ConnectedAt   time.Time `json:"connected_at,omitzero"`    // Time of the last successful subscription
+	LastMessageAt time.Time `json:"last_message_at,omitzero"` // Time the last notification was received
+	LatestSlot    uint64    `json:"latest_slot,omitempty"`    // Highest slot of a processed notification
+	Reconnects    uint64    `json:"reconnects"`               // Number of connection failures since startup
+	LastError     string    `json:"last_error,omitempty"`     // Most recent connection error
+}
+
+// StatsSnapshot is the JSON body returned by the stats endpoint
+type StatsSnapshot struct {
+	TokensToday       uint64            `json:"tokens_today"`            // Creations observed since UTC midnight
+	TokensTotal       uint64            `json:"tokens_total"`            // Creations observed since startup
+	LaunchesByProgram map[string]uint64 `json:"launches_by_program"`     // Creations observed per program
+	Graduations       uint64            `json:"graduations"`             // Curve completions observed since startup
+	LastLaunchAt      time.Time         `json:"last_launch_at,omitzero"` // Time of the most recent creation
+	ConnectedClients  int               `json:"connected_clients"`       // Currently connected WebSocket clients
+	Upstream          UpstreamHealth    `json:"upstream"`                // Upstream subscription health
+	StartedAt         time.Time         `json:"started_at"`              // Time the process started
+}
+
+// Stats aggregates counters about the observed feed and the upstream connection
+type Stats struct {
+	mutex             sync.Mutex
+	day               string
+	tokensToday       uint64
+	tokensTotal       uint64
+	launchesByProgram map[string]uint64
+	graduations       uint64
+	lastLaunchAt      time.Time
+	upstream          UpstreamHealth
+	startedAt         time.Time
+}
+
+// FeedStats holds the counters for this process
+var FeedStats = NewStats()
+
+// NewStats creates an empty set of counters
+func NewStats() *Stats {
+	return &Stats{
+		launchesByProgram: make(map[string]uint64),
+		startedAt:         time.Now().UTC(),
+	}
+}
+
+// RecordLaunch counts a token creation observed on the given program
+func (s *Stats) RecordLaunch(program string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now().UTC()
+	s.rollDay(now)
+	s.lastLaunchAt = now
+	s.tokensToday++
+	s.tokensTotal++
+	s.launchesByProgram[program]++
+}
+
+// RecordGraduation counts a bonding curve completion
+func (s *Stats) RecordGraduation() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.graduations++
+}
+
+// RecordUpstreamConnected marks the upstream subscription as established
+func (s *Stats) RecordUpstreamConnected() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.upstream.Connected = true
+	s.upstream.ConnectedAt = time.Now().UTC()
+}
+
+// RecordUpstreamMessage records the receipt of an upstream notification
+func (s *Stats) RecordUpstreamMessage() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.upstream.LastMessageAt = time.Now().UTC()
+}
+
+// RecordSlot records the slot of a processed notification
+func (s *Stats) RecordSlot(slot uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.upstream.LatestSlot = max(s.upstream.LatestSlot, slot)
+}
+
+// RecordUpstreamError marks the upstream subscription as lost
+func (s *Stats) RecordUpstreamError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.upstream.Connected = false
+	s.upstream.Reconnects++
+	s.upstream.LastError = err.Error()
+}
+
+// Snapshot returns a copy of all counters
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rollDay(time.Now().UTC())
+
+	launches := make(map[string]uint64, len(s.launchesByProgram))
+	for program, count := range s.launchesByProgram {
+		launches[program] = count
+	}
+
+	return StatsSnapshot{
+		TokensToday:       s.tokensToday,
+		TokensTotal:       s.tokensTotal,
+		LaunchesByProgram: launches,
+		Graduations:       s.graduations,
+		LastLaunchAt:      s.lastLaunchAt,
+		ConnectedClients:  ConnectedClients.Size(),
+		Upstream:          s.upstream,
+		StartedAt:         s.startedAt,
+	}
+}
+
+// rollDay resets the daily counter when the UTC date changes
+// Must be called with the mutex held
+func (s *Stats) rollDay(now time.Time) {
+	day := now.Format(time.DateOnly)
+	if day != s.day {
+		s.day = day
+		s.tokensToday = 0
+	}
+}
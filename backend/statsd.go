@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the UDP address of the StatsD server or Datadog
+	// agent, e.g. 127.0.0.1:8125
+	// Metrics are not pushed when it is unset
+	statsdAddrEnv = "STATSD_ADDR"
+
+	// Environment variable selecting the wire format: statsd (the default), or
+	// dogstatsd to send dimensions as tags instead of name segments
+	statsdFormatEnv = "STATSD_FORMAT"
+
+	// Environment variable with the prefix of every metric name
+	statsdPrefixEnv = "STATSD_PREFIX"
+
+	// Environment variable with comma-separated tags added to every metric in
+	// dogstatsd format, e.g. env:prod,region:eu
+	statsdTagsEnv = "STATSD_TAGS"
+
+	// Environment variable with the push interval, e.g. 10s
+	statsdIntervalEnv = "STATSD_INTERVAL"
+
+	// Wire formats
+	statsdFormatStatsD    = "statsd"
+	statsdFormatDogStatsD = "dogstatsd"
+
+	// Metric name prefix used when STATSD_PREFIX is unset
+	defaultStatsDPrefix = "nova_feed"
+
+	// Push interval used when STATSD_INTERVAL is unset
+	defaultStatsDInterval = 10 * time.Second
+
+	// Largest datagram sent, small enough not to be fragmented on common networks
+	statsdMaxPacket = 1432
+)
+
+// StatsDSink pushes the feed metrics to StatsD or DogStatsD over UDP
+//
+// Metrics, each prefixed with STATSD_PREFIX:
+//   - events (tag type): counter of published events
+//   - trades.volume_sol (tag side): counter of SOL traded on bonding curves
+//   - clients.connected: gauge of connected WebSocket clients
+//   - clients.by_country (tag country) and clients.by_asn (tag asn): gauges
+//     of connected WebSocket clients by location, when GeoIP is configured
+//   - tokens.today: gauge of creations observed since UTC midnight
+//   - upstream.connected: gauge, 1 while the upstream subscription is up
+//   - upstream.reconnects: gauge of upstream connection failures since startup
+//   - upstream.last_message_age_seconds: gauge of the time since the last
+//     upstream notification, omitted before the first one
+//   - upstream.slot_lag: gauge of the slots the subscription is behind the
+//     chain, when slot lag monitoring is enabled
+//   - bans.active and bans.total: gauges of the addresses currently banned
+//     and of the bans since startup, when automatic bans are enabled
+//   - dedup.entries, dedup.evictions and dedup.bloom_hits (tag cache): gauges
+//     of the keys in the dedup caches, of the keys evicted from their LRUs
+//     and of the duplicates only their Bloom filters recognized, since startup
+//   - dedup.false_positive_rate (tag cache): gauge of the estimated chance a
+//     new key is taken for a duplicate
+//   - delivery_latency.bucket (tag le): counter of messages written to clients
+//     within each latency bound in milliseconds, cumulative like a Prometheus
+//     histogram, with delivery_latency.count and delivery_latency.sum_ms
+//   - delivery_latency.p50_ms, p90_ms and p99_ms: gauges of the estimated
+//     percentiles of the latencies since the last push, omitted when idle
+//
+// In statsd format, tag values are appended to the metric name, e.g.
+// nova_feed.events.create
+type StatsDSink struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	tags      string // Constant tags in dogstatsd format, e.g. "|#env:prod"
+
+	mutex    sync.Mutex
+	counters map[statsdMetric]float64
+
+	latency LatencySnapshot // Delivery latency at the last push, only used by pushLoop
+}
+
+// statsdMetric identifies a metric by its name and its single dimension
+type statsdMetric struct {
+	name     string // Metric name without the prefix
+	tagKey   string // Dimension name, empty for metrics without one
+	tagValue string // Dimension value
+}
+
+// setupStatsDSink registers the StatsD sink when STATSD_ADDR is set
+func setupStatsDSink() error {
+	addr := os.Getenv(statsdAddrEnv)
+	if addr == "" {
+		return nil
+	}
+
+	format := envOrDefault(statsdFormatEnv, statsdFormatStatsD)
+	if format != statsdFormatStatsD && format != statsdFormatDogStatsD {
+		return fmt.Errorf("unknown %s %q, expected %s or %s", statsdFormatEnv, format, statsdFormatStatsD, statsdFormatDogStatsD)
+	}
+
+	interval := defaultStatsDInterval
+	if value := os.Getenv(statsdIntervalEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", statsdIntervalEnv, value)
+		}
+		interval = parsed
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to open StatsD socket: %w", err)
+	}
+
+	sink := &StatsDSink{
+		conn:      conn,
+		prefix:    strings.TrimSuffix(envOrDefault(statsdPrefixEnv, defaultStatsDPrefix), "."),
+		dogstatsd: format == statsdFormatDogStatsD,
+		counters:  make(map[statsdMetric]float64),
+	}
+	if tags := os.Getenv(statsdTagsEnv); tags != "" && sink.dogstatsd {
+		sink.tags = "|#" + tags
+	}
+	go sink.pushLoop(interval)
+	RegisterSink(sink)
+
+	slog.Info("Pushing metrics", "format", format, "addr", addr, "interval", interval)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (s *StatsDSink) Name() string {
+	return "statsd"
+}
+
+// Publish counts the event; counters are sent on the next push
+func (s *StatsDSink) Publish(event Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[statsdMetric{name: "events", tagKey: "type", tagValue: string(event.Type)}]++
+	if trade, ok := event.Data.(TradeEvent); ok {
+		side := "sell"
+		if trade.IsBuy {
+			side = "buy"
+		}
+		s.counters[statsdMetric{name: "trades.volume_sol", tagKey: "side", tagValue: side}] += float64(trade.SolAmount) / lamportsPerSOL
+	}
+}
+
+// pushLoop sends the counters accumulated since the last push and the current
+// gauges at a fixed interval
+func (s *StatsDSink) pushLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.mutex.Lock()
+		counters := s.counters
+		s.counters = make(map[statsdMetric]float64)
+		s.mutex.Unlock()
+
+		var lines []string
+		for metric, value := range counters {
+			lines = append(lines, s.line(metric, value, "c"))
+		}
+		sort.Strings(lines)
+
+		stats := FeedStats.Snapshot()
+		upstreamConnected := 0
+		if stats.Upstream.Connected {
+			upstreamConnected = 1
+		}
+		lines = append(lines,
+			s.line(statsdMetric{name: "clients.connected"}, float64(stats.ConnectedClients), "g"),
+			s.line(statsdMetric{name: "tokens.today"}, float64(stats.TokensToday), "g"),
+			s.line(statsdMetric{name: "upstream.connected"}, float64(upstreamConnected), "g"),
+			s.line(statsdMetric{name: "upstream.reconnects"}, float64(stats.Upstream.Reconnects), "g"),
+		)
+		if !stats.Upstream.LastMessageAt.IsZero() {
+			age := now.Sub(stats.Upstream.LastMessageAt).Seconds()
+			lines = append(lines, s.line(statsdMetric{name: "upstream.last_message_age_seconds"}, age, "g"))
+		}
+		if GeoIP != nil {
+			lines = append(lines, s.clientLocationLines()...)
+		}
+		if SlotLag != nil {
+			if status, ok := SlotLag.Status(); ok {
+				lines = append(lines, s.line(statsdMetric{name: "upstream.slot_lag"}, float64(status.Lag), "g"))
+			}
+		}
+		if AutoBan != nil {
+			bans := AutoBan.Stats()
+			lines = append(lines,
+				s.line(statsdMetric{name: "bans.active"}, float64(bans.Active), "g"),
+				s.line(statsdMetric{name: "bans.total"}, float64(bans.Total), "g"),
+			)
+		}
+
+		lines = append(lines, s.dedupLines()...)
+		lines = append(lines, s.latencyLines()...)
+
+		if err := s.send(lines); err != nil {
+			slog.Warn("Failed to push metrics to StatsD", logKeyError, err)
+		}
+	}
+}
+
+// latencyLines reports the delivery latencies observed since the last push as
+// cumulative bucket counters tagged with their upper bound, plus percentile gauges
+func (s *StatsDSink) latencyLines() []string {
+	current := deliveryLatency.Snapshot()
+	interval := current.Since(s.latency)
+	s.latency = current
+	if interval.Count == 0 {
+		return nil
+	}
+
+	lines := []string{
+		s.line(statsdMetric{name: "delivery_latency.count"}, float64(interval.Count), "c"),
+		s.line(statsdMetric{name: "delivery_latency.sum_ms"}, interval.SumMs, "c"),
+		s.line(statsdMetric{name: "delivery_latency.p50_ms"}, interval.P50, "g"),
+		s.line(statsdMetric{name: "delivery_latency.p90_ms"}, interval.P90, "g"),
+		s.line(statsdMetric{name: "delivery_latency.p99_ms"}, interval.P99, "g"),
+	}
+	for _, bucket := range interval.Buckets {
+		bound := "inf"
+		if bucket.UpperBound > 0 {
+			bound = strconv.FormatFloat(bucket.UpperBound, 'f', -1, 64)
+		}
+		lines = append(lines, s.line(statsdMetric{name: "delivery_latency.bucket", tagKey: "le", tagValue: bound}, float64(bucket.Count), "c"))
+	}
+	return lines
+}
+
+// dedupLines reports the dedup caches by name
+func (s *StatsDSink) dedupLines() []string {
+	var lines []string
+	for name, stats := range dedupStats() {
+		lines = append(lines,
+			s.line(statsdMetric{name: "dedup.entries", tagKey: "cache", tagValue: name}, float64(stats.Entries), "g"),
+			s.line(statsdMetric{name: "dedup.evictions", tagKey: "cache", tagValue: name}, float64(stats.Evictions), "g"),
+			s.line(statsdMetric{name: "dedup.bloom_hits", tagKey: "cache", tagValue: name}, float64(stats.BloomHits), "g"),
+			s.line(statsdMetric{name: "dedup.false_positive_rate", tagKey: "cache", tagValue: name}, stats.FalsePositiveRate, "g"),
+		)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// clientLocationLines counts the connected clients by country and by ASN
+func (s *StatsDSink) clientLocationLines() []string {
+	byCountry := map[string]int{}
+	byASN := map[string]int{}
+	ConnectedClients.Range(func(_ string, client *Client) bool {
+		byCountry[cmp.Or(client.geo.Country, "unknown")]++
+		asn := "unknown"
+		if client.geo.ASN != 0 {
+			asn = strconv.FormatUint(uint64(client.geo.ASN), 10)
+		}
+		byASN[asn]++
+		return true
+	})
+
+	var lines []string
+	for country, count := range byCountry {
+		lines = append(lines, s.line(statsdMetric{name: "clients.by_country", tagKey: "country", tagValue: country}, float64(count), "g"))
+	}
+	for asn, count := range byASN {
+		lines = append(lines, s.line(statsdMetric{name: "clients.by_asn", tagKey: "asn", tagValue: asn}, float64(count), "g"))
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// line formats one metric in the configured wire format
+func (s *StatsDSink) line(metric statsdMetric, value float64, kind string) string {
+	name := s.prefix + "." + metric.name
+	if !s.dogstatsd {
+		if metric.tagKey != "" {
+			name += "." + metric.tagValue
+		}
+		return fmt.Sprintf("%s:%g|%s", name, value, kind)
+	}
+
+	tags := s.tags
+	if metric.tagKey != "" {
+		if tags == "" {
+			tags = "|#"
+		} else {
+			tags += ","
+		}
+		tags += metric.tagKey + ":" + metric.tagValue
+	}
+	return fmt.Sprintf("%s:%g|%s%s", name, value, kind, tags)
+}
+
+// send writes the lines in as few datagrams as fit statsdMaxPacket
+func (s *StatsDSink) send(lines []string) error {
+	var packet bytes.Buffer
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacket {
+			if _, err := s.conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(packet.Bytes())
+	return err
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling pipe mode: every event is written to stdout
+	// as a line of JSON, and all other output moves to stderr
+	eventsStdoutEnv = "EVENTS_STDOUT"
+
+	// Environment variable disabling the HTTP and WebSocket server
+	httpDisabledEnv = "HTTP_DISABLED"
+
+	// Number of events buffered before new events are dropped
+	stdoutQueueSize = 10000
+)
+
+// Pipe mode state, set by enablePipeMode
+var (
+	eventsOutput      *os.File    // Original stdout while pipe mode is enabled, nil otherwise
+	eventsOutputTypes []EventType // Event types written to stdout, nil for all
+)
+
+// enablePipeMode switches to pipe mode, writing events of the given types to stdout
+// It must run before anything else is printed: os.Stdout is pointed at stderr so
+// the startup and status messages printed with fmt do not corrupt the event stream
+//
+// Parameters:
+//   - types: Event types to write, nil for all
+func enablePipeMode(types []EventType) {
+	eventsOutput = os.Stdout
+	eventsOutputTypes = types
+	os.Stdout = os.Stderr
+}
+
+// httpDisabled reports whether the HTTP and WebSocket server should not be started
+func httpDisabled() bool {
+	return envBool(httpDisabledEnv)
+}
+
+// envBool reports whether the environment variable is set to a true value
+func envBool(key string) bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(key))
+	return enabled
+}
+
+// StdoutSink writes every event as newline-delimited JSON
+type StdoutSink struct {
+	writer  *bufio.Writer
+	types   []EventType
+	queue   chan Event
+	pending sync.WaitGroup
+}
+
+// setupStdoutSink registers the stdout sink when pipe mode is enabled
+func setupStdoutSink() error {
+	if eventsOutput == nil {
+		return nil
+	}
+
+	sink := &StdoutSink{
+		writer: bufio.NewWriter(eventsOutput),
+		types:  eventsOutputTypes,
+		queue:  make(chan Event, stdoutQueueSize),
+	}
+	go sink.writeLoop()
+	RegisterSink(sink)
+
+	slog.Info("Writing events to stdout as NDJSON")
+	return nil
+}
+
+// Name identifies the sink in logs
+func (s *StdoutSink) Name() string {
+	return "stdout"
+}
+
+// Publish queues the event for the writer, dropping it if the queue is full
+func (s *StdoutSink) Publish(event Event) {
+	if s.types != nil && !slices.Contains(s.types, event.Type) {
+		return
+	}
+
+	s.pending.Add(1)
+	select {
+	case s.queue <- event:
+	default:
+		s.pending.Done()
+		slog.Warn("Stdout queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// writeLoop writes queued events in order, flushing whenever the queue is drained
+// so a downstream reader sees each event without waiting for the buffer to fill
+func (s *StdoutSink) writeLoop() {
+	encoder := json.NewEncoder(s.writer)
+	for event := range s.queue {
+		if err := encoder.Encode(event); err != nil {
+			slog.Error("Failed to write event to stdout", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, err)
+		}
+
+		if len(s.queue) == 0 {
+			if err := s.writer.Flush(); err != nil {
+				slog.Error("Failed to flush stdout", logKeyError, err)
+			}
+		}
+		s.pending.Done()
+	}
+}
+
+// Drain waits until every queued event has been written and flushed
+func (s *StdoutSink) Drain() {
+	s.pending.Wait()
+}
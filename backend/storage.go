@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Number of events buffered per storage backend before new events are dropped
+	storageQueueSize = 10000
+
+	// Maximum number of events written in a single batch
+	storageBatchSize = 500
+
+	// Maximum time an event waits in the queue before its batch is written
+	storageFlushInterval = time.Second
+
+	// Environment variable with the maximum age of stored events and tokens, e.g. 720h
+	// Nothing is pruned by age when it is unset
+	storageRetentionEnv = "STORAGE_RETENTION"
+
+	// Environment variable with the maximum number of stored events; the oldest are pruned first
+	// Nothing is pruned by count when it is unset
+	storageMaxEventsEnv = "STORAGE_MAX_EVENTS"
+
+	// Interval between retention sweeps
+	storagePruneInterval = 10 * time.Minute
+
+	// Maximum number of rows deleted per statement, keeping write locks short
+	storagePruneBatchSize = 10000
+)
+
+// Storage is a durable backend persisting every event and the latest state of
+// every token
+// On startup the most recent tokens are restored into the in-memory store, and
+// lookups of tokens evicted from memory fall back to the backend
+type Storage interface {
+	EventSink
+
+	// GetToken returns the stored record of a token, false if it was never stored
+	GetToken(mint string) (TokenRecord, bool, error)
+
+	// ScanEvents calls handle for every stored event matching the query, oldest
+	// first, stopping at the first error handle returns
+	ScanEvents(query EventQuery, handle func(Event) error) error
+
+	// Prune deletes what the retention policy no longer keeps and returns the
+	// number of deleted events
+	Prune(policy RetentionPolicy) (int64, error)
+
+	// Compact compresses events received before the given time and returns the
+	// number of events compressed; compressed events stay queryable
+	Compact(before time.Time) (int64, error)
+
+	// Ping checks that the backend is reachable
+	Ping() error
+}
+
+// RetentionPolicy bounds how much a storage backend keeps
+type RetentionPolicy struct {
+	MaxAge    time.Duration // Events received and tokens created longer ago are deleted; 0 keeps everything
+	MaxEvents int64         // Events beyond this many, oldest first, are deleted; 0 keeps everything
+}
+
+// enabled reports whether the policy deletes anything
+func (p RetentionPolicy) enabled() bool {
+	return p.MaxAge > 0 || p.MaxEvents > 0
+}
+
+// String describes the policy for startup messages
+func (p RetentionPolicy) String() string {
+	var limits []string
+	if p.MaxAge > 0 {
+		limits = append(limits, "the last "+p.MaxAge.String())
+	}
+	if p.MaxEvents > 0 {
+		limits = append(limits, "at most "+strconv.FormatInt(p.MaxEvents, 10)+" events")
+	}
+	return strings.Join(limits, " and ")
+}
+
+// cutoff returns the time before which events are deleted, zero if unbounded
+func (p RetentionPolicy) cutoff() time.Time {
+	if p.MaxAge <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-p.MaxAge)
+}
+
+// EventQuery selects stored events
+type EventQuery struct {
+	From     time.Time   // Earliest received_at to include; zero for no lower bound
+	To       time.Time   // Received_at to stop before; zero for no upper bound
+	FromSlot uint64      // Earliest slot to include; zero for no lower bound
+	ToSlot   uint64      // Slot to stop before; zero for no upper bound
+	Types    []EventType // Event types to include, nil for all
+}
+
+// Persistence is the configured storage backend, nil when persistence is disabled
+var Persistence Storage
+
+// storageSetups lists the setup functions of every storage backend; each returns
+// nil when it is not configured
+var storageSetups = []func() (Storage, error){
+	setupSQLiteStorage,
+	setupPostgresStorage,
+}
+
+// setupStorage opens the configured storage backend and registers it as a sink
+// At most one backend may be configured
+func setupStorage() error {
+	for _, setup := range storageSetups {
+		storage, err := setup()
+		if err != nil {
+			return err
+		}
+		if storage == nil {
+			continue
+		}
+		if Persistence != nil {
+			return fmt.Errorf("only one storage backend can be configured, found %s and %s", Persistence.Name(), storage.Name())
+		}
+		Persistence = storage
+		RegisterSink(storage)
+	}
+
+	if Persistence == nil {
+		return nil
+	}
+	policy, err := retentionPolicyFromEnv()
+	if err != nil {
+		return err
+	}
+	if policy.enabled() {
+		go pruneLoop(Persistence, policy)
+		slog.Info("Pruning storage", "storage", Persistence.Name(), "policy", policy.String())
+	}
+
+	compressAfter, err := compressAfterFromEnv()
+	if err != nil {
+		return err
+	}
+	if compressAfter > 0 {
+		go compactLoop(Persistence, compressAfter)
+		slog.Info("Compressing stored events", "storage", Persistence.Name(), "older_than", compressAfter)
+	}
+	return nil
+}
+
+// retentionPolicyFromEnv reads STORAGE_RETENTION and STORAGE_MAX_EVENTS
+func retentionPolicyFromEnv() (RetentionPolicy, error) {
+	var policy RetentionPolicy
+
+	if value := os.Getenv(storageRetentionEnv); value != "" {
+		maxAge, err := time.ParseDuration(value)
+		if err != nil || maxAge <= 0 {
+			return policy, fmt.Errorf("%s must be a positive duration", storageRetentionEnv)
+		}
+		policy.MaxAge = maxAge
+	}
+
+	if value := os.Getenv(storageMaxEventsEnv); value != "" {
+		maxEvents, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || maxEvents <= 0 {
+			return policy, fmt.Errorf("%s must be a positive integer", storageMaxEventsEnv)
+		}
+		policy.MaxEvents = maxEvents
+	}
+	return policy, nil
+}
+
+// pruneLoop applies the retention policy at startup and then periodically
+func pruneLoop(storage Storage, policy RetentionPolicy) {
+	ticker := time.NewTicker(storagePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := storage.Prune(policy)
+		if err != nil {
+			slog.Error("Failed to prune storage", "storage", storage.Name(), logKeyError, err)
+		} else if deleted > 0 {
+			slog.Info("Pruned stored events", "storage", storage.Name(), "events", deleted)
+		}
+		<-ticker.C
+	}
+}
+
+// eventBatcher queues events and hands them to write in batches, either once
+// storageBatchSize events are queued or after storageFlushInterval
+// Storage backends embed it to implement Publish and Drain
+type eventBatcher struct {
+	name    string
+	queue   chan batchedEvent
+	pending sync.WaitGroup
+	dropped atomic.Uint64
+	write   func(batch []Event) error
+}
+
+// batchedEvent is a queued event with the acknowledgement to call once it is written
+type batchedEvent struct {
+	event Event
+	ack   func(written bool) // Called after the write, or the drop; nil when nobody waits for it
+}
+
+// newEventBatcher creates a batcher and starts its writer
+//
+// Parameters:
+//   - name: Backend name used in logs
+//   - write: Function writing a batch; a failed batch is logged and its events
+//     acknowledged as not written
+func newEventBatcher(name string, write func(batch []Event) error) *eventBatcher {
+	b := &eventBatcher{
+		name:  name,
+		queue: make(chan batchedEvent, storageQueueSize),
+		write: write,
+	}
+	go b.writeLoop()
+	return b
+}
+
+// Name identifies the sink in logs
+func (b *eventBatcher) Name() string {
+	return b.name
+}
+
+// QueueDepth returns the number of events waiting for the writer
+func (b *eventBatcher) QueueDepth() int {
+	return len(b.queue)
+}
+
+// DroppedEvents returns the number of events dropped because the queue was full
+func (b *eventBatcher) DroppedEvents() uint64 {
+	return b.dropped.Load()
+}
+
+// Publish queues the event for the writer, dropping it if the queue is full
+func (b *eventBatcher) Publish(event Event) {
+	b.PublishWithAck(event, nil)
+}
+
+// PublishWithAck queues the event and calls ack once it has been written
+// A dropped or failed event is acknowledged with false, so the write-ahead log
+// hands it to the batcher again
+func (b *eventBatcher) PublishWithAck(event Event, ack func(written bool)) {
+	b.pending.Add(1)
+	select {
+	case b.queue <- batchedEvent{event: event, ack: ack}:
+	default:
+		b.pending.Done()
+		b.dropped.Add(1)
+		slog.Warn("Sink queue full, dropping event", logKeySink, b.name, logKeyEventType, event.Type, logKeyMint, event.Mint)
+		if ack != nil {
+			ack(false)
+		}
+	}
+}
+
+// Drain waits until every queued event has been written
+func (b *eventBatcher) Drain() {
+	b.pending.Wait()
+}
+
+// writeLoop writes queued events in batches
+func (b *eventBatcher) writeLoop() {
+	ticker := time.NewTicker(storageFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, storageBatchSize)
+	acks := make([]func(bool), 0, storageBatchSize)
+	for {
+		select {
+		case queued := <-b.queue:
+			batch = append(batch, queued.event)
+			if queued.ack != nil {
+				acks = append(acks, queued.ack)
+			}
+			if len(batch) < storageBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		err := b.write(batch)
+		if err != nil {
+			slog.Error("Failed to write events", logKeySink, b.name, "events", len(batch), logKeyError, err)
+		}
+		for _, ack := range acks {
+			ack(err == nil)
+		}
+		b.pending.Add(-len(batch))
+		batch = batch[:0]
+		acks = acks[:0]
+	}
+}
+
+// parseTimeBound parses a date (2006-01-02, midnight UTC) or an RFC 3339 time
+// An empty value is returned as the zero time, meaning unbounded
+func parseTimeBound(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// decodeEventData decodes the stored JSON data of an event into the
+// CreateEvent, TradeEvent, CompleteEvent, PriceEvent or MigrationEvent its type implies
+func decodeEventData(eventType EventType, data []byte) (interface{}, error) {
+	switch eventType {
+	case EventCreate:
+		var event CreateEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case EventTrade:
+		var event TradeEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case EventComplete:
+		var event CompleteEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case EventPrice:
+		var event PriceEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case EventMigration:
+		var event MigrationEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	case EventRevert:
+		var event RevertEvent
+		err := json.Unmarshal(data, &event)
+		return event, err
+	default:
+		return nil, fmt.Errorf("unknown event type %q", eventType)
+	}
+}
+
+// eventQueryFilter builds the WHERE clause selecting the events of a query
+//
+// Parameters:
+//   - query: The events to select
+//   - placeholder: Returns the bind placeholder of the n-th argument, starting at 1
+//   - timeArg: Converts a time to the database representation of received_at
+//
+// Returns:
+//   - string: The WHERE clause, empty when the query selects everything
+//   - []interface{}: The arguments bound by the clause
+func eventQueryFilter(query EventQuery, placeholder func(n int) string, timeArg func(time.Time) interface{}) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if !query.From.IsZero() {
+		args = append(args, timeArg(query.From))
+		conditions = append(conditions, "received_at >= "+placeholder(len(args)))
+	}
+	if !query.To.IsZero() {
+		args = append(args, timeArg(query.To))
+		conditions = append(conditions, "received_at < "+placeholder(len(args)))
+	}
+	if query.FromSlot > 0 {
+		args = append(args, int64(query.FromSlot))
+		conditions = append(conditions, "slot >= "+placeholder(len(args)))
+	}
+	if query.ToSlot > 0 {
+		args = append(args, int64(query.ToSlot))
+		conditions = append(conditions, "slot < "+placeholder(len(args)))
+	}
+	if len(query.Types) > 0 {
+		placeholders := make([]string, 0, len(query.Types))
+		for _, eventType := range query.Types {
+			args = append(args, string(eventType))
+			placeholders = append(placeholders, placeholder(len(args)))
+		}
+		conditions = append(conditions, "type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// matches reports whether an event falls within the query, for events filtered
+// outside of SQL
+func (q EventQuery) matches(event Event) bool {
+	if !q.From.IsZero() && event.ReceivedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !event.ReceivedAt.Before(q.To) {
+		return false
+	}
+	if q.FromSlot > 0 && event.Slot < q.FromSlot {
+		return false
+	}
+	if q.ToSlot > 0 && event.Slot >= q.ToSlot {
+		return false
+	}
+	return len(q.Types) == 0 || slices.Contains(q.Types, event.Type)
+}
+
+// batchTokens returns the current in-memory record of every token touched by
+// the batch; tokens whose creation was not observed are not tracked and skipped
+func batchTokens(batch []Event) []TokenRecord {
+	seen := map[string]bool{}
+	var records []TokenRecord
+	for _, event := range batch {
+		if seen[event.Mint] {
+			continue
+		}
+		seen[event.Mint] = true
+
+		if record, found := Tokens.Get(event.Mint); found {
+			records = append(records, record)
+		}
+	}
+	return records
+}
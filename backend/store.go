@@ -0,0 +1,429 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Maximum number of tokens kept in memory before the oldest are evicted
+	maxTrackedTokens = 10000
+
+	// Total supply of every pump.fun token in base units (1 billion tokens, 6 decimals)
+	pumpTokenTotalSupply = 1_000_000_000_000_000
+)
+
+// CurveState represents the latest known bonding curve reserves of a token
+type CurveState struct {
+	VirtualSolReserves   uint64    `json:"virtual_sol_reserves"`   // Virtual SOL reserves in lamports
+	VirtualTokenReserves uint64    `json:"virtual_token_reserves"` // Virtual token reserves in base units
+	RealSolReserves      uint64    `json:"real_sol_reserves"`      // Real SOL reserves in lamports
+	RealTokenReserves    uint64    `json:"real_token_reserves"`    // Real token reserves in base units
+	LastTradeAt          time.Time `json:"last_trade_at"`          // Time of the trade that produced this state
+}
+
+// MarketCapSOL returns the fully diluted market cap implied by the curve price, in SOL
+func (c CurveState) MarketCapSOL() float64 {
+	if c.VirtualTokenReserves == 0 {
+		return 0
+	}
+	price := float64(c.VirtualSolReserves) / float64(c.VirtualTokenReserves)
+	return price * pumpTokenTotalSupply / lamportsPerSOL
+}
+
+// MigrationStatus represents whether a token has graduated off its bonding curve
+type MigrationStatus struct {
+	Complete    bool      `json:"complete"`              // True once the curve has completed
+	CompletedAt time.Time `json:"completed_at,omitzero"` // Time the curve completed
+	Signature   string    `json:"signature,omitempty"`   // Transaction that completed the curve
+	LP          *LPStatus `json:"lp,omitempty"`          // LP of the pool, once verified with LP_VERIFICATION
+}
+
+// TokenPrice represents the latest quoted price of a graduated token
+type TokenPrice struct {
+	PriceUSD float64   `json:"price_usd"` // Price of one token in USD
+	Source   string    `json:"source"`    // Service that quoted the price
+	QuotedAt time.Time `json:"quoted_at"` // Time the quote was received
+}
+
+// TokenRecord holds everything the backend knows about a single mint
+type TokenRecord struct {
+	Mint         string               `json:"mint"`              // Token mint address
+	Creation     CreateEvent          `json:"creation"`          // Creation event as broadcast to clients
+	BondingCurve string               `json:"bonding_curve"`     // Bonding curve account of the token
+	Creator      string               `json:"creator"`           // Creator wallet
+	Signature    string               `json:"signature"`         // Creation transaction signature
+	Slot         uint64               `json:"slot"`              // Slot the creation was observed in
+	CreatedAt    time.Time            `json:"created_at"`        // Time the creation was observed
+	Curve        *CurveState          `json:"curve,omitempty"`   // Latest curve state, if any trade was seen
+	Migration    MigrationStatus      `json:"migration"`         // Graduation status
+	Price        *TokenPrice          `json:"price,omitempty"`   // Latest quote, once graduated and priced
+	Holders      *HolderConcentration `json:"holders,omitempty"` // Latest top holder concentration, for watched tokens
+}
+
+// Search match ranks, lower is better
+const (
+	rankExactSymbol = iota
+	rankExactName
+	rankSymbolPrefix
+	rankNamePrefix
+	rankSubstring
+)
+
+// TokenStore keeps an in-memory, size-capped index of observed tokens
+// Tokens are evicted in creation order once maxTrackedTokens is exceeded
+type TokenStore struct {
+	mutex    sync.RWMutex
+	tokens   map[string]*TokenRecord
+	order    []string
+	creators map[string]*creatorCounts // Launches of the tracked tokens by creator, kept with tokens
+
+	// OnEvict, if set, is called with the mint of every evicted token
+	// It runs with the store locked and must neither block nor use the store
+	OnEvict func(mint string)
+}
+
+// creatorCounts are the tracked tokens of a creator
+type creatorCounts struct {
+	launched  int // Tokens launched
+	graduated int // Tokens whose curve completed
+}
+
+// Tokens stores every token observed since startup
+var Tokens = NewTokenStore()
+
+// NewTokenStore creates an empty token store
+func NewTokenStore() *TokenStore {
+	return &TokenStore{
+		tokens:   make(map[string]*TokenRecord),
+		creators: make(map[string]*creatorCounts),
+	}
+}
+
+// countCreator adds a tracked token to the counts of its creator, or removes
+// it with a negative delta
+// The caller must hold the write lock
+func (s *TokenStore) countCreator(record *TokenRecord, delta int) {
+	counts := s.creators[record.Creator]
+	if counts == nil {
+		counts = &creatorCounts{}
+		s.creators[record.Creator] = counts
+	}
+	counts.launched += delta
+	if record.Migration.Complete {
+		counts.graduated += delta
+	}
+	if counts.launched <= 0 {
+		delete(s.creators, record.Creator)
+	}
+}
+
+// RecordCreation stores a newly created token
+//
+// Parameters:
+//   - event: the creation event as broadcast to clients
+//   - bondingCurve: the bonding curve account of the token
+//   - creator: the creator wallet
+//   - signature: the creation transaction signature
+//   - slot: the slot the creation was observed in
+func (s *TokenStore) RecordCreation(event CreateEvent, bondingCurve, creator, signature string, slot uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.tokens[event.Mint]; exists {
+		return
+	}
+
+	record := &TokenRecord{
+		Mint:         event.Mint,
+		Creation:     event,
+		BondingCurve: bondingCurve,
+		Creator:      creator,
+		Signature:    signature,
+		Slot:         slot,
+		CreatedAt:    time.Now().UTC(),
+	}
+	s.tokens[event.Mint] = record
+	s.order = append(s.order, event.Mint)
+	s.countCreator(record, 1)
+
+	// Evict the oldest tokens once over capacity
+	s.evict()
+}
+
+// Restore adds previously stored records, oldest first
+// Records for mints that are already tracked are skipped
+func (s *TokenStore) Restore(records []TokenRecord) {
+	for i := range records {
+		record := records[i]
+
+		s.mutex.Lock()
+		if _, exists := s.tokens[record.Mint]; !exists {
+			s.tokens[record.Mint] = &record
+			s.order = append(s.order, record.Mint)
+			s.countCreator(&record, 1)
+		}
+		s.evict()
+		s.mutex.Unlock()
+	}
+}
+
+// evict drops the oldest tokens while the store is over capacity
+// The caller must hold the write lock
+func (s *TokenStore) evict() {
+	for len(s.order) > maxTrackedTokens {
+		mint := s.order[0]
+		s.countCreator(s.tokens[mint], -1)
+		delete(s.tokens, mint)
+		s.order = s.order[1:]
+
+		if s.OnEvict != nil {
+			s.OnEvict(mint)
+		}
+	}
+}
+
+// Remove forgets a token, e.g. one whose creation was reverted
+// Unknown mints are ignored.
+func (s *TokenStore) Remove(mint string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	record, exists := s.tokens[mint]
+	if !exists {
+		return
+	}
+	s.countCreator(record, -1)
+	delete(s.tokens, mint)
+	if index := slices.Index(s.order, mint); index >= 0 {
+		s.order = slices.Delete(s.order, index, index+1)
+	}
+
+	if s.OnEvict != nil {
+		s.OnEvict(mint)
+	}
+}
+
+// RecordTrade updates the curve state of a tracked token
+// Trades for tokens that were not seen being created are ignored
+func (s *TokenStore) RecordTrade(mint string, curve CurveState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.tokens[mint]; exists {
+		record.Curve = &curve
+	}
+}
+
+// RecordCompletion marks a tracked token as graduated
+func (s *TokenStore) RecordCompletion(mint string, status MigrationStatus) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.tokens[mint]; exists {
+		s.countCreator(record, -1)
+		record.Migration = status
+		s.countCreator(record, 1)
+	}
+}
+
+// RecordLPStatus stores the verified LP of the pool of a tracked token
+func (s *TokenStore) RecordLPStatus(mint string, status LPStatus) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.tokens[mint]; exists {
+		record.Migration.LP = &status
+	}
+}
+
+// RecordHolders stores the latest top holder concentration of a tracked token
+func (s *TokenStore) RecordHolders(mint string, holders HolderConcentration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.tokens[mint]; exists {
+		record.Holders = &holders
+	}
+}
+
+// RecordPrice stores the latest quote of a tracked token
+func (s *TokenStore) RecordPrice(mint string, price TokenPrice) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if record, exists := s.tokens[mint]; exists {
+		record.Price = &price
+	}
+}
+
+// Graduated returns the mints of the tracked tokens whose curve completed,
+// most recently created first
+func (s *TokenStore) Graduated() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var mints []string
+	for i := len(s.order) - 1; i >= 0; i-- {
+		if record := s.tokens[s.order[i]]; record.Migration.Complete {
+			mints = append(mints, record.Mint)
+		}
+	}
+	return mints
+}
+
+// Active returns up to limit tracked tokens still on their curve that traded
+// since the given time, most recently traded first
+func (s *TokenStore) Active(since time.Time, limit int) []TokenRecord {
+	s.mutex.RLock()
+	var records []TokenRecord
+	for _, record := range s.tokens {
+		if !record.Migration.Complete && record.Curve != nil && !record.Curve.LastTradeAt.Before(since) {
+			records = append(records, *record)
+		}
+	}
+	s.mutex.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Curve.LastTradeAt.After(records[j].Curve.LastTradeAt) })
+	return records[:min(len(records), limit)]
+}
+
+// Get returns a copy of the record for the given mint
+//
+// Returns:
+//   - TokenRecord: the stored record
+//   - bool: false if the mint is not tracked
+func (s *TokenStore) Get(mint string) (TokenRecord, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	record, exists := s.tokens[mint]
+	if !exists {
+		return TokenRecord{}, false
+	}
+
+	copied := *record
+	if record.Curve != nil {
+		curve := *record.Curve
+		copied.Curve = &curve
+	}
+	if record.Price != nil {
+		price := *record.Price
+		copied.Price = &price
+	}
+	return copied, true
+}
+
+// Recent returns up to limit of the most recently created tokens, newest first
+func (s *TokenStore) Recent(limit int) []TokenRecord {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	records := make([]TokenRecord, 0, min(limit, len(s.order)))
+	for i := len(s.order) - 1; i >= 0 && len(records) < limit; i-- {
+		records = append(records, *s.tokens[s.order[i]])
+	}
+	return records
+}
+
+// CountByCreator returns the number of tracked tokens launched by the given creator
+func (s *TokenStore) CountByCreator(creator string) int {
+	launched, _ := s.CreatorStats(creator)
+	return launched
+}
+
+// CreatorStats returns the number of tracked tokens launched by the given
+// creator, and how many of them graduated
+func (s *TokenStore) CreatorStats(creator string) (launched, graduated int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if counts := s.creators[creator]; counts != nil {
+		return counts.launched, counts.graduated
+	}
+	return 0, 0
+}
+
+// Search returns tracked tokens whose name or symbol matches the query
+// Matching is case-insensitive; exact matches rank before prefix matches, which
+// rank before substring matches. Within the same rank newer tokens come first.
+//
+// Parameters:
+//   - query: the text to match against token names and symbols
+//   - offset: number of ranked results to skip
+//   - limit: maximum number of results to return
+//
+// Returns:
+//   - []TokenRecord: the requested page of matching tokens
+//   - int: the total number of matching tokens
+func (s *TokenStore) Search(query string, offset, limit int) ([]TokenRecord, int) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []TokenRecord{}, 0
+	}
+
+	type match struct {
+		rank  int
+		index int
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	// Rank every tracked token against the query
+	matches := []match{}
+	for index, mint := range s.order {
+		creation := s.tokens[mint].Creation
+		if rank, ok := matchRank(query, strings.ToLower(creation.Name), strings.ToLower(creation.Symbol)); ok {
+			matches = append(matches, match{rank: rank, index: index})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].index > matches[j].index
+	})
+
+	total := len(matches)
+	if offset >= total {
+		return []TokenRecord{}, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	results := make([]TokenRecord, 0, end-offset)
+	for _, m := range matches[offset:end] {
+		results = append(results, *s.tokens[s.order[m.index]])
+	}
+	return results, total
+}
+
+// matchRank ranks how well a lowercased query matches a token name and symbol
+//
+// Returns:
+//   - int: the match rank, lower is better
+//   - bool: false if neither the name nor the symbol matches
+func matchRank(query, name, symbol string) (int, bool) {
+	switch {
+	case symbol == query:
+		return rankExactSymbol, true
+	case name == query:
+		return rankExactName, true
+	case strings.HasPrefix(symbol, query):
+		return rankSymbolPrefix, true
+	case strings.HasPrefix(name, query):
+		return rankNamePrefix, true
+	case strings.Contains(symbol, query), strings.Contains(name, query):
+		return rankSubstring, true
+	default:
+		return 0, false
+	}
+}
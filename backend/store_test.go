@@ -0,0 +1,86 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestTokenStoreSearch checks that matches rank exact symbols, exact names,
+// symbol prefixes, name prefixes then substrings, newest first within a rank,
+// whatever the case of the query
+func TestTokenStoreSearch(t *testing.T) {
+	store := NewTokenStore()
+	// Recorded oldest first
+	for _, creation := range []CreateEvent{
+		{Mint: "substring-old", Name: "Super Pepe Coin", Symbol: "SPC"},
+		{Mint: "exact-symbol-old", Name: "Frog", Symbol: "PEPE"},
+		{Mint: "name-prefix", Name: "Pepe Classic", Symbol: "PCL"},
+		{Mint: "exact-name", Name: "pepe", Symbol: "FROGGY"},
+		{Mint: "symbol-prefix", Name: "Frog Two", Symbol: "PEPE2"},
+		{Mint: "exact-symbol-new", Name: "Another", Symbol: "Pepe"},
+		{Mint: "substring-new", Name: "Baby Frog", Symbol: "BPEPE"},
+		{Mint: "unrelated", Name: "Doge", Symbol: "DOGE"},
+	} {
+		store.RecordCreation(creation, "", "creator", "signature-"+creation.Mint, 1)
+	}
+	ranked := []string{"exact-symbol-new", "exact-symbol-old", "exact-name", "symbol-prefix", "name-prefix", "substring-new", "substring-old"}
+
+	tests := []struct {
+		name     string
+		query    string
+		offset   int
+		limit    int
+		expected []string
+		total    int
+	}{
+		{name: "ranked", query: "pepe", limit: 10, expected: ranked, total: 7},
+		{name: "case and spaces", query: "  PePe ", limit: 10, expected: ranked, total: 7},
+		{name: "page", query: "pepe", offset: 2, limit: 3, expected: ranked[2:5], total: 7},
+		{name: "last page", query: "pepe", offset: 5, limit: 3, expected: ranked[5:], total: 7},
+		{name: "past the end", query: "pepe", offset: 7, limit: 3, expected: []string{}, total: 7},
+		{name: "single match", query: "doge", limit: 10, expected: []string{"unrelated"}, total: 1},
+		{name: "no match", query: "shib", limit: 10, expected: []string{}, total: 0},
+		{name: "empty query", query: "   ", limit: 10, expected: []string{}, total: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, total := store.Search(test.query, test.offset, test.limit)
+			mints := []string{}
+			for _, record := range results {
+				mints = append(mints, record.Mint)
+			}
+			if !slices.Equal(mints, test.expected) || total != test.total {
+				t.Fatalf("got %v of %d, expected %v of %d", mints, total, test.expected, test.total)
+			}
+		})
+	}
+}
+
+// TestMatchRank checks the rank of each kind of match, case folding being left to the caller
+func TestMatchRank(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		tokenName string
+		symbol    string
+		rank      int
+		expected  bool
+	}{
+		{name: "exact symbol over exact name", query: "pepe", tokenName: "pepe", symbol: "pepe", rank: rankExactSymbol, expected: true},
+		{name: "exact name over symbol prefix", query: "pepe", tokenName: "pepe", symbol: "pepe2", rank: rankExactName, expected: true},
+		{name: "symbol prefix over name prefix", query: "pe", tokenName: "pepe coin", symbol: "pepe", rank: rankSymbolPrefix, expected: true},
+		{name: "name prefix", query: "pepe", tokenName: "pepe coin", symbol: "pc", rank: rankNamePrefix, expected: true},
+		{name: "symbol substring", query: "pepe", tokenName: "baby frog", symbol: "bpepe", rank: rankSubstring, expected: true},
+		{name: "name substring", query: "pepe", tokenName: "super pepe", symbol: "sp", rank: rankSubstring, expected: true},
+		{name: "no match", query: "pepe", tokenName: "doge", symbol: "doge"},
+		{name: "case sensitive", query: "pepe", tokenName: "PEPE", symbol: "PEPE"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rank, ok := matchRank(test.query, test.tokenName, test.symbol)
+			if ok != test.expected || (ok && rank != test.rank) {
+				t.Fatalf("got rank %d (%v), expected rank %d (%v)", rank, ok, test.rank, test.expected)
+			}
+		})
+	}
+}
@@ -1,43 +1,60 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
-	"github.com/gagliardetto/solana-go/rpc/ws"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
 )
 
-// Configuration constants
-const (
-	// WebSocket URL for Helius RPC endpoint
-	websocketURL = "wss://mainnet.helius-rpc.com/?api-key=0f803376-0189-4d72-95f6-a5f41cef157d"
+// Reconnection delay when connection fails, set by UPSTREAM_RECONNECT_DELAY
+var reconnectDelay = 2 * time.Second
 
-	// PumpFun program address on Solana mainnet
-	pumpFunProgram = "TSLvdd1pWpHVjahSpsvCXUbgwsL3JAcvokwaKt1eokM"
+// upstreamProgram is the program whose logs are subscribed to, the mainnet
+// program unless PUMPFUN_PROGRAM_ID names another deployment, with the
+// listener subscribed to it so a reload can move the subscription
+var upstreamProgram = struct {
+	mutex    sync.RWMutex
+	program  solana.PublicKey
+	listener *pumpstream.Listener
+}{program: pumpstream.Program}
 
-	// Reconnection delay when connection fails
-	reconnectDelay = 2 * time.Second
+// pumpProgram returns the program whose logs are subscribed to
+func pumpProgram() solana.PublicKey {
+	upstreamProgram.mutex.RLock()
+	defer upstreamProgram.mutex.RUnlock()
+	return upstreamProgram.program
+}
 
-	// Magic string to identify relevant logs
-	logIdentifier = "G3KpTd7r"
+// setPumpProgram changes the program, moving the upstream subscription to it
+// without dropping the connection
+func setPumpProgram(program solana.PublicKey) {
+	upstreamProgram.mutex.Lock()
+	defer upstreamProgram.mutex.Unlock()
 
-	// Program data prefix in log messages
-	programDataPrefix = "Program data: "
-)
+	upstreamProgram.program = program
+	if upstreamProgram.listener != nil {
+		upstreamProgram.listener.SetProgram(program)
+	}
+}
+
+// followPumpProgram subscribes a listener to the program, and to the new one
+// whenever setPumpProgram changes it
+func followPumpProgram(listener *pumpstream.Listener) {
+	upstreamProgram.mutex.Lock()
+	defer upstreamProgram.mutex.Unlock()
 
-// RawEvent represents the decoded event data from Solana program logs
-type RawEvent struct {
-	Name   string           // Token name
-	Symbol string           // Token symbol
-	Uri    string           // Token metadata URI
-	Mint   solana.PublicKey // Token mint address
+	listener.SetProgram(upstreamProgram.program)
+	upstreamProgram.listener = listener
 }
 
 // CreateEvent represents the formatted event data sent to clients
@@ -48,125 +65,237 @@ type CreateEvent struct {
 	Mint   string `json:"mint"`   // Token mint address as string
 }
 
-// creationDiscriminator is the byte sequence that identifies creation events
-var creationDiscriminator = []byte{27, 114, 169, 77, 222, 235, 99, 118}
+// clientCreateEvent is the creation broadcast to WebSocket clients, with the
+// enrichments they would otherwise fetch themselves
+type clientCreateEvent struct {
+	CreateEvent
+	Addresses *DerivedAddresses `json:"addresses,omitempty"` // Bonding curve, its token account and the token account of the creator
+	Supply    *TokenSupply      `json:"supply,omitempty"`    // Supply and decimals of the mint, once cached with TOKEN_SUPPLY_ENRICHMENT
+}
 
-// listenToNewPairs establishes a WebSocket connection to listen for new token pair creations
-// on the PumpFun program. It handles reconnections automatically and processes incoming
-// program logs to extract creation events.
-func listenToNewPairs() {
-	fmt.Println("Starting to listen for new token pairs...")
+// listenToNewPairs subscribes to the PumpFun program logs and hands every
+// notification of a successful transaction to handle. It reconnects
+// automatically and records the upstream health in FeedStats, until ctx is
+// done, which closes the subscription.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the subscription
+//   - url: WebSocket RPC endpoint to subscribe through
+//   - handle: Function receiving each notification, e.g. processNotification
+func listenToNewPairs(ctx context.Context, url string, handle func(pumpstream.Notification)) {
+	slog.Info("Starting to listen for new token pairs")
 
-	for {
-		if err := connectAndListen(); err != nil {
-			fmt.Printf("Connection error: %v\n", err)
-			fmt.Printf("Reconnecting in %v...\n", reconnectDelay)
-			time.Sleep(reconnectDelay)
-		}
+	listener := &pumpstream.Listener{
+		URL:            url,
+		Commitment:     rpc.CommitmentProcessed,
+		ReconnectDelay: reconnectDelay,
+		OnConnect: func() {
+			slog.Info("Subscribed to PumpFun program logs")
+			FeedStats.RecordUpstreamConnected()
+		},
+		OnDisconnect: func(err error) {
+			FeedStats.RecordUpstreamError(err)
+			slog.Warn("Upstream connection lost", logKeyError, err, "retry_in", reconnectDelay)
+			reportError(errorCategoryUpstream, err)
+		},
+		OnMessage: FeedStats.RecordUpstreamMessage,
 	}
-}
+	followPumpProgram(listener)
 
-// connectAndListen establishes a WebSocket connection and listens for program logs
-func connectAndListen() error {
-	// Establish WebSocket connection
-	socket, err := ws.Connect(context.Background(), websocketURL)
-	if err != nil {
-		return fmt.Errorf("failed to connect to WebSocket: %w", err)
+	run := func() { listener.Run(ctx, handle) }
+	if Chaos != nil {
+		run = func() { Chaos.run(ctx, listener, handle) }
 	}
-	defer socket.Close()
-
-	fmt.Println("Successfully connected to WebSocket")
 
-	// Subscribe to logs mentioning the PumpFun program
-	sub, err := socket.LogsSubscribeMentions(
-		solana.MPK(pumpFunProgram),
-		rpc.CommitmentProcessed,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to subscribe to logs: %w", err)
+	// A panic in the subscription restarts it rather than the process
+	for runRecovered("upstream listener", run) && ctx.Err() == nil {
+		time.Sleep(reconnectDelay)
 	}
+	slog.Info("Stopped listening for new token pairs")
+}
 
-	fmt.Println("Subscribed to PumpFun program logs")
+// processNotification processes every log of a notification
+// A log that makes decoding panic is logged and skipped. Notifications whose
+// logs were truncated are handed to the truncated log fallback when enabled.
+func processNotification(notification pumpstream.Notification) {
+	events := make([]*PipelineEvent, len(notification.Logs))
+	for index, log := range notification.Logs {
+		events[index] = &PipelineEvent{Log: log, Signature: notification.Signature, Slot: notification.Slot, Index: index}
+	}
+	processTransaction(notification.Signature, notification.Slot, notification.ReceivedAt, events)
 
-	// Listen for incoming messages
-	return listenForMessages(sub)
+	if TruncatedLogs != nil {
+		TruncatedLogs.Check(notification)
+	}
 }
 
-// listenForMessages processes incoming WebSocket messages and extracts creation events
-func listenForMessages(sub *ws.LogSubscription) error {
-	for {
-		message, err := sub.Recv(context.Background())
-		if err != nil {
-			return fmt.Errorf("error receiving message: %w", err)
-		}
+// processTransaction runs the events of one transaction through the pipeline,
+// within a span and under a correlation ID of their own
+//
+// Parameters:
+//   - signature: Transaction signature
+//   - slot: Slot the transaction was observed in
+//   - receivedAt: Time the transaction was received
+//   - events: Logs of the transaction, or events already decoded from it
+func processTransaction(signature string, slot uint64, receivedAt time.Time, events []*PipelineEvent) {
+	done := inFlightNotifications.start()
+	defer done()
+
+	ctx, span := startNotificationSpan(signature, slot, receivedAt)
+	defer span.End()
+	id := newCorrelationID(span.SpanContext())
+	span.SetAttributes(attrCorrelationID.String(id))
+	ctx = withCorrelationID(withReceivedAt(ctx, receivedAt), id)
+	FeedStats.RecordSlot(slot)
+	slog.Debug("Processing notification", logKeySignature, signature, logKeySlot, slot, "events", len(events), logKeyCorrelationID, id)
 
-		// Process each log in the message
-		for _, log := range message.Value.Logs {
-			if err := processLog(log); err != nil {
-				// Log error but continue processing other logs
-				fmt.Printf("Error processing log: %v\n", err)
-			}
+	for _, event := range events {
+		if err := processEventRecovered(ctx, event); err != nil {
+			// Log error but continue processing other events
+			span.RecordError(err)
+			slog.Error("Failed to process log", logKeySignature, signature, logKeySlot, slot,
+				logKeyCorrelationID, id, logKeyError, err)
 		}
 	}
 }
 
-// processLog processes a single log entry and extracts creation events
-func processLog(log string) error {
-	// Check if log contains the identifier for relevant events
-	if !strings.Contains(log, logIdentifier) {
-		return nil // Not a relevant log, skip
-	}
+// processEventRecovered runs an event through the pipeline, turning a panic
+// into a logged failure so one malformed payload cannot take the feed down
+func processEventRecovered(ctx context.Context, event *PipelineEvent) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			logPanic("processLog", value, logKeySignature, event.Signature, logKeySlot, event.Slot, logKeyCorrelationID, correlationID(ctx))
+			err = fmt.Errorf("panic while processing log: %v", value)
+		}
+	}()
+	return Pipeline.Process(ctx, event)
+}
 
-	// Extract program data from log
-	data, err := extractProgramData(log)
-	if err != nil {
-		return fmt.Errorf("failed to extract program data: %w", err)
+// deliverEvent is the end of the pipeline: it dispatches the event to the
+// matching delivery function based on its type
+func deliverEvent(ctx context.Context, event *PipelineEvent) error {
+	switch decoded := event.Decoded.(type) {
+	case *pumpstream.CreateEvent:
+		return deliverCreation(ctx, decoded, event)
+	case *pumpstream.TradeEvent:
+		deliverTrade(ctx, decoded, event)
+	case *pumpstream.CompleteEvent:
+		deliverComplete(ctx, decoded, event)
 	}
+	return nil
+}
 
-	// Decode base64 data
-	decoded, err := base64.StdEncoding.DecodeString(data)
-	if err != nil {
-		return fmt.Errorf("failed to decode base64 data: %w", err)
+// publishTraced hands an event to the sinks within a span of the notification,
+// tagged with its correlation ID and the enrichments of the pipeline event
+func publishTraced(ctx context.Context, source *PipelineEvent, event Event) {
+	event.CorrelationID = correlationID(ctx)
+	event.Addresses = source.Addresses
+	event.Market = source.Market
+	event.Supply = source.Supply
+	event.Holders = source.Holders
+	event.Funding = source.Funding
+	event.Fields = source.Fields
+	_, span := tracer.Start(ctx, "publish", trace.WithAttributes(attrMint.String(event.Mint), attribute.String("event.type", string(event.Type))))
+	defer span.End()
+	publishEvent(event)
+}
+
+// newCreateEvent formats a creation event for clients
+func newCreateEvent(event *pumpstream.CreateEvent) CreateEvent {
+	return CreateEvent{
+		Name:   event.Name,
+		Symbol: event.Symbol,
+		Uri:    event.Uri,
+		Mint:   event.Mint.String(),
 	}
+}
 
-	// Check if this is a creation event
-	if !bytes.HasPrefix(decoded, creationDiscriminator) {
-		return nil // Not a creation event, skip
+// curveState returns the curve state of the token after a trade
+func curveState(event *pumpstream.TradeEvent) CurveState {
+	return CurveState{
+		VirtualSolReserves:   event.VirtualSolReserves,
+		VirtualTokenReserves: event.VirtualTokenReserves,
+		RealSolReserves:      event.RealSolReserves,
+		RealTokenReserves:    event.RealTokenReserves,
+		LastTradeAt:          time.Unix(event.Timestamp, 0).UTC(),
 	}
+}
 
-	// Decode the event data
-	event, err := DecodeBase64[RawEvent](decoded, creationDiscriminator)
-	if err != nil {
-		return fmt.Errorf("failed to decode event: %w", err)
+// completionStatus returns the migration status of the token of a curve completion
+func completionStatus(event *pumpstream.CompleteEvent, signature string) MigrationStatus {
+	return MigrationStatus{
+		Complete:    true,
+		CompletedAt: time.Unix(event.Timestamp, 0).UTC(),
+		Signature:   signature,
 	}
+}
 
+// deliverCreation broadcasts a creation event to clients and sinks
+func deliverCreation(ctx context.Context, event *pumpstream.CreateEvent, source *PipelineEvent) error {
 	// Create formatted event for clients
-	createEvent := CreateEvent{
-		Name:   event.Name,
-		Symbol: event.Symbol,
-		Uri:    event.Uri,
-		Mint:   event.Mint.String(),
-	}
+	createEvent := newCreateEvent(event)
 
 	// Marshal to JSON and send to clients
-	marshalled, err := json.Marshal(createEvent)
+	marshalled, err := json.Marshal(clientCreateEvent{CreateEvent: createEvent, Addresses: source.Addresses, Supply: source.Supply})
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	fmt.Printf("New token created: %s\n", string(marshalled))
+	slog.Info("New token created", append(eventAttrs(ctx, createEvent.Mint, source.Signature, source.Slot), "name", createEvent.Name, "symbol", createEvent.Symbol)...)
 
 	// Send to all connected clients asynchronously
-	go sendMessageToAllClients(marshalled)
+	goSafe("broadcast", func() { sendMessageToAllClients(ctx, marshalled) })
+
+	publishTraced(ctx, source, Event{
+		Type:       EventCreate,
+		Mint:       createEvent.Mint,
+		Signature:  source.Signature,
+		Slot:       source.Slot,
+		ReceivedAt: time.Now().UTC(),
+		Data:       createEvent,
+	})
 
 	return nil
 }
 
-// extractProgramData extracts the program data portion from a log message
-func extractProgramData(log string) (string, error) {
-	parts := strings.Split(log, programDataPrefix)
-	if len(parts) < 2 {
-		return "", fmt.Errorf("log does not contain program data")
-	}
-	return parts[1], nil
+// deliverTrade hands a trade event to the sinks
+func deliverTrade(ctx context.Context, event *pumpstream.TradeEvent, source *PipelineEvent) {
+	slog.Debug("Trade", append(eventAttrs(ctx, event.Mint.String(), source.Signature, source.Slot), "is_buy", event.IsBuy, "sol_amount", event.SolAmount)...)
+
+	publishTraced(ctx, source, Event{
+		Type:       EventTrade,
+		Mint:       event.Mint.String(),
+		Signature:  source.Signature,
+		Slot:       source.Slot,
+		ReceivedAt: time.Now().UTC(),
+		Data: TradeEvent{
+			Mint:                 event.Mint.String(),
+			SolAmount:            event.SolAmount,
+			TokenAmount:          event.TokenAmount,
+			IsBuy:                event.IsBuy,
+			User:                 event.User.String(),
+			Timestamp:            event.Timestamp,
+			VirtualSolReserves:   event.VirtualSolReserves,
+			VirtualTokenReserves: event.VirtualTokenReserves,
+		},
+	})
+}
+
+// deliverComplete hands a curve completion event to the sinks
+func deliverComplete(ctx context.Context, event *pumpstream.CompleteEvent, source *PipelineEvent) {
+	slog.Info("Bonding curve completed", eventAttrs(ctx, event.Mint.String(), source.Signature, source.Slot)...)
+
+	publishTraced(ctx, source, Event{
+		Type:       EventComplete,
+		Mint:       event.Mint.String(),
+		Signature:  source.Signature,
+		Slot:       source.Slot,
+		ReceivedAt: time.Now().UTC(),
+		Data: CompleteEvent{
+			Mint:         event.Mint.String(),
+			User:         event.User.String(),
+			BondingCurve: event.BondingCurve.String(),
+			Timestamp:    event.Timestamp,
+		},
+	})
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling the supply enrichment: when true, events
+	// carry the decimals and total supply of their mint, from getTokenSupply
+	tokenSupplyEnrichmentEnv = "TOKEN_SUPPLY_ENRICHMENT"
+
+	// How long the supply of a mint is reused; it only changes with burns,
+	// the mint authority of pump.fun tokens being revoked at creation
+	tokenSupplyTTL = time.Hour
+
+	// Spacing of getTokenSupply requests
+	tokenSupplyRequestInterval = 100 * time.Millisecond
+)
+
+// TokenSupply represents the decimals and total supply of a mint, which
+// clients need to turn base units and curve prices into market caps
+type TokenSupply struct {
+	Amount    uint64    `json:"amount"`     // Total supply in base units
+	Decimals  uint8     `json:"decimals"`   // Number of decimals of the mint
+	FetchedAt time.Time `json:"fetched_at"` // Time the supply was fetched
+}
+
+// TokenSupplies caches the supply of mints when TOKEN_SUPPLY_ENRICHMENT is set, nil otherwise
+var TokenSupplies *lookupCache[TokenSupply]
+
+// setupTokenSupply adds the supply of their mint to events when TOKEN_SUPPLY_ENRICHMENT is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if no RPC endpoint can be resolved
+func setupTokenSupply(rpcURL string) error {
+	if !envBool(tokenSupplyEnrichmentEnv) {
+		return nil
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	client := newEnrichmentRPCClient(endpoint)
+	TokenSupplies = newLookupCache("token supply", tokenSupplyTTL, 1, tokenSupplyRequestInterval, func(ctx context.Context, mints []string) (map[string]TokenSupply, error) {
+		return fetchTokenSupplies(ctx, client, mints)
+	})
+	Pipeline.Register(StageEnrich, "token supply", addTokenSupply)
+
+	slog.Info("Enriching events with the supply of their mint")
+	return nil
+}
+
+// addTokenSupply adds the cached supply of the mint to the event
+func addTokenSupply(next PipelineHandler) PipelineHandler {
+	return func(ctx context.Context, event *PipelineEvent) error {
+		if mint := pipelineEventMint(event); mint != "" {
+			event.Supply = TokenSupplies.Lookup(mint)
+		}
+		return next(ctx, event)
+	}
+}
+
+// fetchTokenSupplies gets the supply of each mint with getTokenSupply
+// The processed commitment is used, as the mint of a creation is not
+// confirmed yet when its log arrives
+func fetchTokenSupplies(ctx context.Context, client *rpc.Client, mints []string) (map[string]TokenSupply, error) {
+	supplies := make(map[string]TokenSupply, len(mints))
+	for _, mint := range mints {
+		key, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			continue
+		}
+
+		result, err := client.GetTokenSupply(ctx, key, rpc.CommitmentProcessed)
+		if err != nil {
+			return nil, err
+		}
+		if result.Value == nil {
+			continue
+		}
+		amount, err := strconv.ParseUint(result.Value.Amount, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid supply %q of %s: %w", result.Value.Amount, mint, err)
+		}
+		supplies[mint] = TokenSupply{Amount: amount, Decimals: result.Value.Decimals, FetchedAt: time.Now().UTC()}
+	}
+	return supplies, nil
+}
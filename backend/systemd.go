@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable set by systemd for services with Type=notify, naming
+	// the datagram socket state changes are sent to
+	notifySocketEnv = "NOTIFY_SOCKET"
+
+	// Environment variables set by systemd when WatchdogSec= is configured: the
+	// watchdog timeout in microseconds and the process expected to ping it
+	systemdWatchdogUsecEnv = "WATCHDOG_USEC"
+	systemdWatchdogPIDEnv  = "WATCHDOG_PID"
+
+	// Interval at which readiness is checked until the upstream is subscribed
+	systemdReadyPollInterval = 500 * time.Millisecond
+)
+
+// notificationTracker records when each notification being processed was
+// picked up; the sources process theirs concurrently
+type notificationTracker struct {
+	mutex   sync.Mutex
+	next    uint64               // Key of the next notification
+	started map[uint64]time.Time // Start of the notifications in flight, by key
+}
+
+// inFlightNotifications are the notifications being processed; the systemd
+// watchdog stops being pinged when the oldest one takes longer than the
+// watchdog timeout
+var inFlightNotifications = &notificationTracker{started: map[uint64]time.Time{}}
+
+// start records a notification being picked up
+//
+// Returns:
+//   - func(): Function to call once the notification is processed
+func (n *notificationTracker) start() func() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	key := n.next
+	n.next++
+	n.started[key] = time.Now()
+	return func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		delete(n.started, key)
+	}
+}
+
+// oldest returns when the oldest notification in flight was picked up
+//
+// Returns:
+//   - time.Time: Start of the oldest notification
+//   - bool: false when none is in flight
+func (n *notificationTracker) oldest() (time.Time, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	var oldest time.Time
+	for _, startedAt := range n.started {
+		if oldest.IsZero() || startedAt.Before(oldest) {
+			oldest = startedAt
+		}
+	}
+	return oldest, !oldest.IsZero()
+}
+
+// setupSystemdNotify tells systemd, when running as a Type=notify service,
+// that the service is ready once the upstream subscription is established,
+// and pings the systemd watchdog while notifications keep being processed, so
+// systemd restarts the service when the pipeline hangs
+func setupSystemdNotify() error {
+	if os.Getenv(notifySocketEnv) == "" {
+		return nil
+	}
+
+	timeout, err := systemdWatchdogTimeout()
+	if err != nil {
+		return err
+	}
+
+	go runRecovered("systemd readiness", waitUntilSubscribed)
+	if timeout > 0 {
+		go func() {
+			// systemd recommends pinging at half the timeout
+			ticker := time.NewTicker(timeout / 2)
+			defer ticker.Stop()
+			for range ticker.C {
+				runRecovered("systemd watchdog", func() { pingSystemdWatchdog(timeout) })
+			}
+		}()
+		slog.Info("Pinging the systemd watchdog", "interval", timeout/2)
+	}
+	return nil
+}
+
+// systemdWatchdogTimeout returns the watchdog timeout systemd configured for
+// this process, 0 when the watchdog is disabled or meant for another process
+func systemdWatchdogTimeout() (time.Duration, error) {
+	value := os.Getenv(systemdWatchdogUsecEnv)
+	if value == "" {
+		return 0, nil
+	}
+	if pid := os.Getenv(systemdWatchdogPIDEnv); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	usec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", systemdWatchdogUsecEnv, value)
+	}
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// waitUntilSubscribed sends READY=1 once the upstream subscription is
+// established and the self-test passed
+func waitUntilSubscribed() {
+	for !FeedStats.Snapshot().Upstream.Connected || !SelfTest.Passed() {
+		time.Sleep(systemdReadyPollInterval)
+	}
+	if err := sdNotify("READY=1\nSTATUS=Subscribed to the upstream feed"); err != nil {
+		slog.Warn("Failed to notify systemd of readiness", logKeyError, err)
+	}
+}
+
+// pingSystemdWatchdog sends WATCHDOG=1 unless a notification being processed
+// has been running for longer than the watchdog timeout
+func pingSystemdWatchdog(timeout time.Duration) {
+	if startedAt, ok := inFlightNotifications.oldest(); ok {
+		if stuck := time.Since(startedAt); stuck > timeout {
+			slog.Error("Notification processing is stuck, no longer pinging the systemd watchdog", "stuck_for", stuck.Round(time.Second))
+			return
+		}
+	}
+	if err := sdNotify("WATCHDOG=1"); err != nil {
+		slog.Warn("Failed to ping the systemd watchdog", logKeyError, err)
+	}
+}
+
+// sdNotify sends a state change to the systemd notification socket, if any
+//
+// Parameters:
+//   - state: Newline-separated assignments, e.g. READY=1
+//
+// Returns:
+//   - error: Error if the socket cannot be written to
+func sdNotify(state string) error {
+	path := os.Getenv(notifySocketEnv)
+	if path == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace
+	if strings.HasPrefix(path, "@") {
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
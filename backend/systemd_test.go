@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNotificationTrackerOldest checks that a notification finishing does not
+// hide an older one still in flight
+func TestNotificationTrackerOldest(t *testing.T) {
+	tracker := &notificationTracker{started: map[uint64]time.Time{}}
+	if _, ok := tracker.oldest(); ok {
+		t.Fatal("a notification is in flight before any started")
+	}
+
+	hung := tracker.start()
+	hungAt, _ := tracker.oldest()
+	time.Sleep(time.Millisecond)
+	finished := tracker.start()
+	finished()
+
+	oldest, ok := tracker.oldest()
+	if !ok || !oldest.Equal(hungAt) {
+		t.Fatalf("oldest started at %v (%v), expected the hung one started at %v", oldest, ok, hungAt)
+	}
+
+	later := tracker.start()
+	hung()
+	if oldest, ok := tracker.oldest(); !ok || !oldest.After(hungAt) {
+		t.Fatalf("oldest started at %v (%v), expected the later one", oldest, ok)
+	}
+	later()
+	if _, ok := tracker.oldest(); ok {
+		t.Fatal("a notification is in flight after every one finished")
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the address of the raw TCP feed, e.g. 127.0.0.1:9200
+	// The feed is disabled when it is unset
+	tcpFeedAddrEnv = "TCP_FEED_ADDR"
+
+	// Number of events buffered before new events are dropped
+	tcpFeedQueueSize = 10000
+
+	// Number of frames buffered per connection; a client falling further
+	// behind is disconnected rather than silently missing events
+	tcpFeedClientQueueSize = 4096
+
+	// Size of the write buffer of each connection
+	tcpFeedWriteBufferSize = 64 * 1024
+
+	// Time allowed for a client to accept a write before it is disconnected
+	tcpFeedWriteTimeout = 5 * time.Second
+
+	// Pause after a failed accept, e.g. when out of file descriptors
+	tcpFeedAcceptBackoff = 100 * time.Millisecond
+)
+
+// TCPFeed streams every event to the clients connected to a plain TCP listener,
+// for trading processes on the same host or rack that cannot afford HTTP,
+// WebSocket framing or JSON
+//
+// Clients only read: the feed writes back-to-back frames encoded by
+// encodeEventFrame, each starting with its u32 little-endian length, and
+// ignores anything clients send. The IP access lists and bans of the WebSocket
+// endpoint apply to it too.
+type TCPFeed struct {
+	listener net.Listener
+	queue    chan Event
+	pending  sync.WaitGroup // Events queued or frames not yet written
+
+	mutex   sync.Mutex
+	clients map[*tcpFeedClient]struct{}
+}
+
+// tcpFeedClient is a connection to the feed
+type tcpFeedClient struct {
+	conn   net.Conn
+	frames chan []byte
+	done   chan struct{} // Closed when the connection is dropped
+	once   sync.Once
+}
+
+// setupTCPFeed starts the raw TCP feed when TCP_FEED_ADDR is set
+func setupTCPFeed() error {
+	addr := os.Getenv(tcpFeedAddrEnv)
+	if addr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for the TCP feed: %w", addr, err)
+	}
+
+	feed := &TCPFeed{
+		listener: listener,
+		queue:    make(chan Event, tcpFeedQueueSize),
+		clients:  map[*tcpFeedClient]struct{}{},
+	}
+	go feed.acceptLoop()
+	go feed.broadcastLoop()
+	RegisterSink(feed)
+
+	slog.Info("Streaming length-prefixed binary events over TCP", "addr", listener.Addr().String())
+	return nil
+}
+
+// Name identifies the sink in logs
+func (t *TCPFeed) Name() string {
+	return "tcp"
+}
+
+// Publish queues the event for the connected clients, dropping it if the queue is full
+func (t *TCPFeed) Publish(event Event) {
+	t.pending.Add(1)
+	select {
+	case t.queue <- event:
+	default:
+		t.pending.Done()
+		slog.Warn("TCP feed queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// QueueDepth returns the number of events waiting to be encoded
+func (t *TCPFeed) QueueDepth() int {
+	return len(t.queue)
+}
+
+// Drain waits until every queued event has been written to the connected clients
+func (t *TCPFeed) Drain() {
+	t.pending.Wait()
+}
+
+// acceptLoop accepts connections until the listener is closed
+func (t *TCPFeed) acceptLoop() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			slog.Warn("TCP feed failed to accept a connection", logKeyError, err)
+			time.Sleep(tcpFeedAcceptBackoff)
+			continue
+		}
+
+		ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+		if !IPAccess.Allowed(ip) {
+			slog.Info("TCP feed connection refused by IP access lists", logKeyClientID, conn.RemoteAddr().String())
+			conn.Close()
+			continue
+		}
+		if _, banned := AutoBan.Banned(ip); banned {
+			conn.Close()
+			continue
+		}
+		AutoBan.Record(ip, abuseReconnect)
+
+		client := &tcpFeedClient{
+			conn:   conn,
+			frames: make(chan []byte, tcpFeedClientQueueSize),
+			done:   make(chan struct{}),
+		}
+		t.mutex.Lock()
+		t.clients[client] = struct{}{}
+		t.mutex.Unlock()
+
+		slog.Info("TCP feed client connected", logKeyClientID, conn.RemoteAddr().String())
+		goSafe("tcp feed writer", func() { t.writeLoop(client) })
+		goSafe("tcp feed reader", func() { t.readLoop(client) })
+	}
+}
+
+// broadcastLoop encodes queued events once and hands the frame to every client
+func (t *TCPFeed) broadcastLoop() {
+	for event := range t.queue {
+		frame, err := encodeEventFrame(event)
+		if err != nil {
+			slog.Error("Failed to encode event for the TCP feed", logKeyError, err)
+			t.pending.Done()
+			continue
+		}
+
+		t.mutex.Lock()
+		for client := range t.clients {
+			t.pending.Add(1)
+			select {
+			case client.frames <- frame:
+			default:
+				t.pending.Done()
+				go t.disconnect(client, "too slow")
+			}
+		}
+		t.mutex.Unlock()
+		t.pending.Done()
+	}
+}
+
+// writeLoop writes the frames of a client in order, flushing whenever its
+// queue is drained so each frame leaves without waiting for the buffer to fill
+func (t *TCPFeed) writeLoop(client *tcpFeedClient) {
+	writer := bufio.NewWriterSize(client.conn, tcpFeedWriteBufferSize)
+	for {
+		select {
+		case <-client.done:
+			// No frame is queued once the client is forgotten; count off the rest
+			for range len(client.frames) {
+				<-client.frames
+				t.pending.Done()
+			}
+			return
+		case frame := <-client.frames:
+			client.conn.SetWriteDeadline(time.Now().Add(tcpFeedWriteTimeout))
+			_, err := writer.Write(frame)
+			if err == nil && len(client.frames) == 0 {
+				err = writer.Flush()
+			}
+			t.pending.Done()
+			if err != nil {
+				t.disconnect(client, err.Error())
+			}
+		}
+	}
+}
+
+// readLoop discards what a client sends and notices when it disconnects
+func (t *TCPFeed) readLoop(client *tcpFeedClient) {
+	_, err := io.Copy(io.Discard, client.conn)
+	reason := "closed by client"
+	if err != nil {
+		reason = err.Error()
+	}
+	t.disconnect(client, reason)
+}
+
+// disconnect forgets a client and closes its connection, once
+func (t *TCPFeed) disconnect(client *tcpFeedClient, reason string) {
+	client.once.Do(func() {
+		t.mutex.Lock()
+		delete(t.clients, client)
+		t.mutex.Unlock()
+
+		close(client.done)
+		client.conn.Close()
+		slog.Info("TCP feed client disconnected", logKeyClientID, client.conn.RemoteAddr().String(), "reason", reason)
+	})
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the Telegram bot token
+	// The Telegram integration is disabled when it is unset
+	telegramBotTokenEnv = "TELEGRAM_BOT_TOKEN"
+
+	// Environment variable pointing to a JSON file listing the chats to post to
+	telegramChatsFileEnv = "TELEGRAM_CHATS_FILE"
+
+	// Bot API endpoint for sending messages
+	telegramSendMessageURL = "https://api.telegram.org/bot%s/sendMessage"
+
+	// Minimum delay between two messages to the same chat, per Telegram limits
+	telegramChatInterval = 1 * time.Second
+
+	// Number of messages buffered per chat before new ones are dropped
+	telegramQueueSize = 100
+
+	// Maximum send attempts per message when rate limited
+	telegramMaxAttempts = 3
+)
+
+// TelegramChat describes a chat receiving notifications
+type TelegramChat struct {
+	ChatID string       `json:"chat_id"` // Numeric chat ID or @channel username
+	Filter NotifyFilter `json:"filter"`  // Events posted to this chat
+}
+
+// telegramChat owns the message queue of one configured chat
+type telegramChat struct {
+	config TelegramChat
+	queue  chan string
+}
+
+// TelegramSink posts formatted token notifications to Telegram chats
+type TelegramSink struct {
+	botToken string
+	client   *http.Client
+
+	mutex sync.RWMutex // Guards chats, which are replaced on reload
+	chats []*telegramChat
+}
+
+// telegramResponse is the subset of the Bot API response we inspect
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// setupTelegramSink registers the Telegram integration when TELEGRAM_BOT_TOKEN is set
+func setupTelegramSink() error {
+	botToken := os.Getenv(telegramBotTokenEnv)
+	if botToken == "" {
+		return nil
+	}
+
+	path := os.Getenv(telegramChatsFileEnv)
+	if path == "" {
+		return fmt.Errorf("%s must be set when %s is set", telegramChatsFileEnv, telegramBotTokenEnv)
+	}
+
+	chats, err := loadTelegramChats(path)
+	if err != nil {
+		return err
+	}
+
+	sink := &TelegramSink{
+		botToken: botToken,
+		client:   &http.Client{Timeout: webhookRequestTimeout},
+		chats:    chats,
+	}
+	for _, chat := range chats {
+		go sink.sendLoop(chat)
+	}
+	RegisterSink(sink)
+
+	slog.Info("Posting notifications to Telegram", "chats", len(sink.chats))
+	return nil
+}
+
+// loadTelegramChats reads and validates the chats file, without starting their send loops
+func loadTelegramChats(path string) ([]*telegramChat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Telegram chats file: %w", err)
+	}
+
+	var configs []TelegramChat
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse Telegram chats file: %w", err)
+	}
+
+	var chats []*telegramChat
+	for _, config := range configs {
+		if err := config.Filter.Compile(); err != nil {
+			return nil, fmt.Errorf("Telegram chat %s: %w", config.ChatID, err)
+		}
+		chats = append(chats, &telegramChat{config: config, queue: make(chan string, telegramQueueSize)})
+	}
+	return chats, nil
+}
+
+// Reload replaces the chats with those in TELEGRAM_CHATS_FILE
+// Messages already queued on the previous chats are still delivered
+func (t *TelegramSink) Reload() error {
+	path := os.Getenv(telegramChatsFileEnv)
+	if path == "" {
+		return fmt.Errorf("%s is no longer set, keeping the current chats", telegramChatsFileEnv)
+	}
+
+	chats, err := loadTelegramChats(path)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		go t.sendLoop(chat)
+	}
+
+	t.mutex.Lock()
+	previous := t.chats
+	t.chats = chats
+	t.mutex.Unlock()
+
+	// Nothing queues on the previous chats any more; their loops exit once drained
+	for _, chat := range previous {
+		close(chat.queue)
+	}
+	return nil
+}
+
+// Name identifies the sink in logs
+func (t *TelegramSink) Name() string {
+	return "telegram"
+}
+
+// Publish formats the event and queues it on every chat whose filter matches
+func (t *TelegramSink) Publish(event Event) {
+	record, found := Tokens.Get(event.Mint)
+	if !found {
+		return
+	}
+	flags := newRiskFlagSet(record)
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	var message string
+	for _, chat := range t.chats {
+		if !chat.config.Filter.Matches(event, record, flags) {
+			continue
+		}
+
+		if message == "" {
+			message = formatTelegramMessage(event, record, flags.Get())
+		}
+
+		select {
+		case chat.queue <- message:
+		default:
+			slog.Warn("Telegram queue full, dropping notification", "chat", chat.config.ChatID, logKeyEventType, event.Type, logKeyMint, event.Mint)
+		}
+	}
+}
+
+// Alert queues an operational alert on every chat that opted in
+func (t *TelegramSink) Alert(alert OperationalAlert) {
+	icon := "🚨"
+	if !alert.Firing {
+		icon = "✅"
+	}
+	message := icon + " " + html.EscapeString(alert.Message)
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for _, chat := range t.chats {
+		if !chat.config.Filter.Alerts {
+			continue
+		}
+		select {
+		case chat.queue <- message:
+		default:
+			slog.Warn("Telegram queue full, dropping alert", "chat", chat.config.ChatID, "kind", alert.Kind)
+		}
+	}
+}
+
+// Digest queues a digest on every chat that opted in
+func (t *TelegramSink) Digest(digest Digest) {
+	message := "📰 " + html.EscapeString(formatDigest(digest))
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for _, chat := range t.chats {
+		if !chat.config.Filter.Digests {
+			continue
+		}
+		select {
+		case chat.queue <- message:
+		default:
+			slog.Warn("Telegram queue full, dropping digest", "chat", chat.config.ChatID, "period", digest.Period)
+		}
+	}
+}
+
+// QueueDepth returns the number of messages waiting on every chat
+func (t *TelegramSink) QueueDepth() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	depth := 0
+	for _, chat := range t.chats {
+		depth += len(chat.queue)
+	}
+	return depth
+}
+
+// sendLoop delivers queued messages to one chat, respecting the per-chat rate limit
+func (t *TelegramSink) sendLoop(chat *telegramChat) {
+	for message := range chat.queue {
+		for attempt := 1; attempt <= telegramMaxAttempts; attempt++ {
+			retryAfter, err := t.send(chat.config.ChatID, message)
+			if err == nil {
+				break
+			}
+
+			slog.Warn("Failed to post to Telegram", "chat", chat.config.ChatID, logKeyError, err)
+			if retryAfter == 0 {
+				break
+			}
+			time.Sleep(retryAfter)
+		}
+
+		time.Sleep(telegramChatInterval)
+	}
+}
+
+// send posts a single HTML message
+//
+// Returns:
+//   - time.Duration: how long Telegram asked us to wait before retrying, 0 if not rate limited
+//   - error: any error that occurred while sending
+func (t *TelegramSink) send(chatID, message string) (time.Duration, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  chatID,
+		"text":                     message,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := t.client.Post(fmt.Sprintf(telegramSendMessageURL, t.botToken), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid response with status %s", resp.Status)
+	}
+	if result.OK {
+		return 0, nil
+	}
+
+	retryAfter := time.Duration(result.Parameters.RetryAfter) * time.Second
+	return retryAfter, fmt.Errorf("%s", result.Description)
+}
+
+// formatTelegramMessage renders an event as a Telegram HTML message
+func formatTelegramMessage(event Event, record TokenRecord, flags []string) string {
+	var builder strings.Builder
+
+	switch event.Type {
+	case EventComplete:
+		builder.WriteString("🎓 <b>Graduated</b>: ")
+	case EventTrade:
+		builder.WriteString("💱 <b>Trade</b>: ")
+	case EventPrice:
+		builder.WriteString("💵 <b>Price</b>: ")
+	case EventMigration:
+		builder.WriteString("🏊 <b>Migrated</b>: ")
+	case EventRevert:
+		builder.WriteString("↩️ <b>Reverted</b>: ")
+	default:
+		builder.WriteString("🚀 <b>New token</b>: ")
+	}
+
+	fmt.Fprintf(&builder, "%s ($%s)\n", html.EscapeString(record.Creation.Name), html.EscapeString(record.Creation.Symbol))
+	fmt.Fprintf(&builder, "Mint: <code>%s</code>\n", record.Mint)
+	if data, ok := event.Data.(PriceEvent); ok {
+		fmt.Fprintf(&builder, "Price: %s\n", formatUSDPrice(data.PriceUSD))
+	}
+	if data, ok := event.Data.(MigrationEvent); ok {
+		fmt.Fprintf(&builder, "LP: %s\n", formatLPStatus(data.LP))
+	}
+	if data, ok := event.Data.(RevertEvent); ok {
+		fmt.Fprintf(&builder, "Reason: %s\n", data.Reason)
+	}
+	fmt.Fprintf(&builder, "<a href=\"%s\">pump.fun</a> | <a href=\"%s\">Solscan</a>", pumpFunLink(record.Mint), solscanLink(record.Mint))
+
+	if len(flags) > 0 {
+		fmt.Fprintf(&builder, "\n⚠️ %s", strings.Join(flags, ", "))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variables with the PEM certificate chain and private key the
+	// server presents; TLS is disabled when both are unset
+	tlsCertFileEnv = "TLS_CERT_FILE"
+	tlsKeyFileEnv  = "TLS_KEY_FILE"
+
+	// Environment variable with the interval at which the certificate files
+	// are checked for changes, e.g. 30s; 0 disables checking, SIGHUP still
+	// reloads them
+	tlsReloadIntervalEnv = "TLS_RELOAD_INTERVAL"
+
+	// Interval used when TLS_RELOAD_INTERVAL is unset
+	defaultTLSReloadInterval = 30 * time.Second
+)
+
+// certificateReloader serves the certificate in TLS_CERT_FILE and
+// TLS_KEY_FILE, loading it again when the files change so rotating it does
+// not require a restart; established connections keep their session
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	certificate atomic.Pointer[tls.Certificate]
+
+	mutex sync.Mutex // Serializes reloads
+	stamp string     // Modification times and sizes of the files at the last load
+}
+
+// serverCertificate is the certificate of the public listener when it is loaded from files, nil otherwise
+var serverCertificate *certificateReloader
+
+// serverTLSConfig builds the TLS configuration of the public listener from
+// TLS_CERT_FILE and TLS_KEY_FILE, or from ACME when ACME_DOMAINS is set
+// TLS 1.2 is the minimum, and TLS 1.2 connections are limited to forward
+// secret AEAD suites; TLS 1.3 suites are not configurable in Go
+//
+// Returns:
+//   - *tls.Config: The configuration, nil when TLS is not enabled
+//   - error: Error if only one file is set, the pair cannot be loaded or both sources are configured
+func serverTLSConfig() (*tls.Config, error) {
+	certFile, keyFile := os.Getenv(tlsCertFileEnv), os.Getenv(tlsKeyFileEnv)
+	if certFile != "" && os.Getenv(acmeDomainsEnv) != "" {
+		return nil, fmt.Errorf("%s and %s are mutually exclusive", tlsCertFileEnv, acmeDomainsEnv)
+	}
+	if certFile == "" && keyFile == "" {
+		config, err := acmeTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			if os.Getenv(tlsClientCAFileEnv) != "" {
+				return nil, fmt.Errorf("%s requires TLS to be enabled", tlsClientCAFileEnv)
+			}
+			return nil, nil
+		}
+		config = withModernCiphers(config)
+		if err := setupClientAuth(config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("%s and %s must be set together", tlsCertFileEnv, tlsKeyFileEnv)
+	}
+
+	interval := defaultTLSReloadInterval
+	if value := os.Getenv(tlsReloadIntervalEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid %s %q", tlsReloadIntervalEnv, value)
+		}
+		interval = parsed
+	}
+
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go reloader.watch(interval)
+	}
+	serverCertificate = reloader
+
+	config := withModernCiphers(&tls.Config{GetCertificate: reloader.GetCertificate})
+	if err := setupClientAuth(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// GetCertificate returns the current certificate for every handshake
+func (c *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.certificate.Load(), nil
+}
+
+// Reload loads the certificate files, keeping the current certificate if they are invalid
+func (c *certificateReloader) Reload() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stamp := c.fileStamp()
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	c.certificate.Store(&certificate)
+	c.stamp = stamp
+	return nil
+}
+
+// watch reloads the certificate whenever the files changed since the last load
+func (c *certificateReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		runRecovered("TLS certificate reload", c.reloadIfChanged)
+	}
+}
+
+// reloadIfChanged reloads the certificate if the files changed since the last load
+func (c *certificateReloader) reloadIfChanged() {
+	c.mutex.Lock()
+	stamp := c.fileStamp()
+	changed := stamp != c.stamp
+	c.mutex.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := c.Reload(); err != nil {
+		// Retried once the files change again, e.g. when the key follows the certificate
+		c.mutex.Lock()
+		c.stamp = stamp
+		c.mutex.Unlock()
+		slog.Error("Failed to reload TLS certificate, keeping the current one", logKeyError, err)
+		return
+	}
+	slog.Info("Reloaded TLS certificate", "cert_file", c.certFile)
+}
+
+// fileStamp describes the modification times and sizes of the certificate
+// files; an unreadable file yields a stamp that never matches a loaded one
+func (c *certificateReloader) fileStamp() string {
+	stamp := ""
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "missing"
+		}
+		stamp += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return stamp
+}
+
+// withModernCiphers restricts a TLS configuration to TLS 1.2 and later with
+// forward secret AEAD suites
+func withModernCiphers(config *tls.Config) *tls.Config {
+	config.MinVersion = tls.VersionTLS12
+	config.CurvePreferences = []tls.CurveID{tls.X25519, tls.CurveP256}
+	config.CipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	}
+	return config
+}
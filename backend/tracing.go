@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Configuration constants
+const (
+	// Standard OpenTelemetry environment variables; setting either endpoint
+	// enables tracing, and the exporter reads the remaining OTEL_EXPORTER_OTLP_*
+	// variables (headers, TLS, timeouts) itself
+	otlpEndpointEnv       = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	otlpTracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+	// Environment variable naming the service in traces
+	otelServiceNameEnv = "OTEL_SERVICE_NAME"
+
+	// Service name used when OTEL_SERVICE_NAME is unset
+	defaultServiceName = "nova-feed"
+
+	// Oldest receipt time a notification span starts at; notifications replayed
+	// from a recording carry their original receipt time and start now instead
+	maxSpanBackdate = time.Minute
+
+	// Time allowed for exporting the spans still buffered at exit
+	tracingShutdownTimeout = 5 * time.Second
+)
+
+// tracer creates the spans of the event pipeline; it does nothing until
+// setupTracing installs a provider
+var tracer = otel.Tracer("github.com/luqmanafiq/solana-blockchain/backend")
+
+// Span attribute keys, matching the structured log fields
+var (
+	attrSignature = attribute.Key(logKeySignature)
+	attrSlot      = attribute.Key(logKeySlot)
+	attrMint      = attribute.Key(logKeyMint)
+
+	attrCorrelationID = attribute.Key(logKeyCorrelationID)
+)
+
+// setupTracing exports spans over OTLP/HTTP when an OTLP endpoint is configured
+// Sampling follows OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG, sampling
+// every trace when they are unset
+//
+// Returns:
+//   - func(): Flushes buffered spans and stops the exporter; a no-op when disabled
+//   - error: Error if the exporter could not be created
+func setupTracing() (func(), error) {
+	if os.Getenv(otlpEndpointEnv) == "" && os.Getenv(otlpTracesEndpointEnv) == "" {
+		return func() {}, nil
+	}
+
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := envOrDefault(otelServiceNameEnv, defaultServiceName)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	slog.Info("Exporting traces over OTLP", "service", serviceName)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		provider.Shutdown(ctx)
+	}, nil
+}
+
+// startNotificationSpan starts the root span of a notification at the time it
+// was received, so the wait before processing shows up in the trace
+func startNotificationSpan(signature string, slot uint64, receivedAt time.Time) (context.Context, trace.Span) {
+	options := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrSignature.String(signature), attrSlot.Int64(int64(slot))),
+	}
+	if !receivedAt.IsZero() && time.Since(receivedAt) < maxSpanBackdate {
+		options = append(options, trace.WithTimestamp(receivedAt))
+	}
+	return tracer.Start(context.Background(), "notification", options...)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling the truncated log fallback: when true, the
+	// transactions whose logs were cut before their creation event are fetched
+	// and their creation decoded from the instruction data
+	truncatedLogFallbackEnv = "TRUNCATED_LOG_FALLBACK"
+
+	// Last log message of a transaction that exceeded the log limit of the
+	// validator, the following messages being dropped
+	truncatedLogMessage = "Log truncated"
+
+	// Number of truncated transactions queued for fetching before new ones are dropped
+	truncatedLogQueueSize = 1000
+
+	// Attempts at fetching a truncated transaction; it is only served once
+	// confirmed, a moment after its processed notification
+	truncatedLogFetchAttempts = 5
+
+	// Delay between two attempts
+	truncatedLogRetryDelay = time.Second
+
+	// Longest a single fetch may take
+	truncatedLogFetchTimeout = 10 * time.Second
+)
+
+// TruncatedLogStats counts the notifications whose logs were truncated
+type TruncatedLogStats struct {
+	Detected  uint64 `json:"detected"`  // Truncated notifications without a creation in their logs
+	Recovered uint64 `json:"recovered"` // Creations recovered from the fetched transactions
+	Failed    uint64 `json:"failed"`    // Transactions that could not be fetched
+	Dropped   uint64 `json:"dropped"`   // Transactions not fetched because the queue was full
+}
+
+// TruncatedLogFallback recovers the creations of transactions whose logs
+// were truncated, from the instructions of the transaction
+type TruncatedLogFallback struct {
+	client *rpc.Client
+	queue  chan pumpstream.Notification
+
+	detected  atomic.Uint64
+	recovered atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// TruncatedLogs recovers truncated creations when TRUNCATED_LOG_FALLBACK is set, nil otherwise
+var TruncatedLogs *TruncatedLogFallback
+
+// setupTruncatedLogFallback starts fetching truncated transactions when TRUNCATED_LOG_FALLBACK is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if no RPC endpoint can be resolved
+func setupTruncatedLogFallback(rpcURL string) error {
+	if !envBool(truncatedLogFallbackEnv) {
+		return nil
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	TruncatedLogs = &TruncatedLogFallback{
+		client: newEnrichmentRPCClient(endpoint),
+		queue:  make(chan pumpstream.Notification, truncatedLogQueueSize),
+	}
+	goSafe("truncated log fallback", TruncatedLogs.run)
+
+	slog.Info("Recovering creations of transactions with truncated logs")
+	return nil
+}
+
+// Check queues the transaction of a notification for fetching when its logs
+// were truncated before a creation event
+func (f *TruncatedLogFallback) Check(notification pumpstream.Notification) {
+	if !slices.Contains(notification.Logs, truncatedLogMessage) {
+		return
+	}
+	for _, log := range notification.Logs {
+		if decoded, err := pumpstream.DecodeLog(log); err == nil {
+			if _, ok := decoded.(*pumpstream.CreateEvent); ok {
+				return
+			}
+		}
+	}
+
+	f.detected.Add(1)
+	select {
+	case f.queue <- notification:
+	default:
+		f.dropped.Add(1)
+		slog.Warn("Truncated log queue is full, dropping transaction", logKeySignature, notification.Signature)
+	}
+}
+
+// Stats returns the counters of the fallback
+func (f *TruncatedLogFallback) Stats() TruncatedLogStats {
+	return TruncatedLogStats{
+		Detected:  f.detected.Load(),
+		Recovered: f.recovered.Load(),
+		Failed:    f.failed.Load(),
+		Dropped:   f.dropped.Load(),
+	}
+}
+
+// run fetches the queued transactions and processes the creations decoded
+// from their instructions
+func (f *TruncatedLogFallback) run() {
+	for notification := range f.queue {
+		events, err := f.recover(notification)
+		if err != nil {
+			f.failed.Add(1)
+			slog.Warn("Failed to recover truncated transaction", logKeySignature, notification.Signature, logKeyError, err)
+			reportError(errorCategoryUpstream, err, logKeySignature, notification.Signature)
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		f.recovered.Add(uint64(len(events)))
+		slog.Info("Recovered creation from truncated logs", logKeySignature, notification.Signature, "events", len(events))
+		processTransaction(notification.Signature, notification.Slot, notification.ReceivedAt, events)
+	}
+}
+
+// recover fetches a transaction, retrying until it is confirmed, and decodes
+// the creations of its instructions, inner instructions included
+// The events are numbered after the logs of the notification, so the
+// signature dedup tells them apart from the events decoded from the logs.
+func (f *TruncatedLogFallback) recover(notification pumpstream.Notification) ([]*PipelineEvent, error) {
+	signature, err := solana.SignatureFromBase58(notification.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	maxVersion := uint64(0)
+	var result *rpc.GetTransactionResult
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), truncatedLogFetchTimeout)
+		result, err = f.client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+			Commitment:                     rpc.CommitmentConfirmed,
+			MaxSupportedTransactionVersion: &maxVersion,
+		})
+		cancel()
+		if err == nil || attempt == truncatedLogFetchAttempts {
+			break
+		}
+		time.Sleep(truncatedLogRetryDelay)
+	}
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, fmt.Errorf("transaction was not confirmed after %d attempts", truncatedLogFetchAttempts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A failed transaction created nothing
+	if result.Meta == nil || result.Meta.Err != nil {
+		return nil, nil
+	}
+	transaction, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+
+	// Instructions index the static keys, then the loaded writable and
+	// read-only addresses
+	keys := append(solana.PublicKeySlice{}, transaction.Message.AccountKeys...)
+	keys = append(keys, result.Meta.LoadedAddresses.Writable...)
+	keys = append(keys, result.Meta.LoadedAddresses.ReadOnly...)
+
+	// Creations made through another program, such as a launch router, are
+	// inner instructions of the instruction calling it
+	var instructions []solana.CompiledInstruction
+	for index, instruction := range transaction.Message.Instructions {
+		instructions = append(instructions, instruction)
+		for _, inner := range result.Meta.InnerInstructions {
+			if int(inner.Index) != index {
+				continue
+			}
+			for _, compiled := range inner.Instructions {
+				instructions = append(instructions, solana.CompiledInstruction{
+					ProgramIDIndex: compiled.ProgramIDIndex,
+					Accounts:       compiled.Accounts,
+					Data:           compiled.Data,
+				})
+			}
+		}
+	}
+
+	return instructionEvents(notification.Signature, result.Slot, keys, instructions, len(notification.Logs)), nil
+}
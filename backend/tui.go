@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// Configuration constants
+const (
+	// Interval between two redraws of the dashboard
+	tuiRefreshInterval = time.Second
+
+	// Window the launch rate is measured over
+	tuiRateWindow = time.Minute
+
+	// Creations kept for the scrolling list; rows that do not fit the terminal are not shown
+	tuiMaxCreations = 200
+
+	// Log lines shown at the bottom of the dashboard
+	tuiLogLines = 4
+
+	// Timeout of a stats request to the instance given with --server
+	tuiServerTimeout = 2 * time.Second
+
+	// Size assumed when the size of the terminal cannot be read
+	tuiDefaultWidth  = 80
+	tuiDefaultHeight = 24
+
+	// ANSI sequences drawing the dashboard
+	ansiAlternateScreen = "\x1b[?1049h\x1b[?25l" // Switch to the alternate screen and hide the cursor
+	ansiMainScreen      = "\x1b[?25h\x1b[?1049l" // Show the cursor and switch back
+	ansiHome            = "\x1b[H"               // Move the cursor to the top left corner
+	ansiClearLine       = "\x1b[K"               // Clear the rest of the line
+	ansiClearBelow      = "\x1b[J"               // Clear the rest of the screen
+	ansiBold            = "\x1b[1m"
+	ansiDim             = "\x1b[2m"
+	ansiGreen           = "\x1b[32m"
+	ansiRed             = "\x1b[31m"
+	ansiReset           = "\x1b[0m"
+)
+
+// tuiCreation is a creation listed on the dashboard
+type tuiCreation struct {
+	receivedAt time.Time
+	creation   CreateEvent
+}
+
+// tuiDashboard renders a live terminal dashboard of the feed
+// It is a sink collecting the creations, and the handler of the logs, which
+// would otherwise be written over the dashboard.
+type tuiDashboard struct {
+	output io.Writer
+	fd     int    // File descriptor of the terminal, to read its size
+	server string // Base URL of the instance whose clients are counted, empty for none
+
+	mutex     sync.Mutex
+	creations []tuiCreation // Most recent first
+	launches  []time.Time   // Times of the creations within the rate window, oldest first
+	logs      []string      // Most recent log lines, oldest first
+	clients   int           // Clients connected to the server, from its last stats
+	clientErr error         // Why the server stats could not be read
+}
+
+// Name identifies the sink in logs
+func (d *tuiDashboard) Name() string {
+	return "tui"
+}
+
+// Publish adds every creation to the list and the launch rate
+func (d *tuiDashboard) Publish(event Event) {
+	creation, ok := event.Data.(CreateEvent)
+	if event.Type != EventCreate || !ok {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.creations = append([]tuiCreation{{receivedAt: event.ReceivedAt, creation: creation}}, d.creations[:min(len(d.creations), tuiMaxCreations-1)]...)
+	d.launches = append(d.launches, event.ReceivedAt)
+}
+
+// Write keeps the last log lines for the dashboard
+func (d *tuiDashboard) Write(p []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for line := range strings.SplitSeq(strings.TrimRight(string(p), "\n"), "\n") {
+		d.logs = append(d.logs, line)
+	}
+	d.logs = d.logs[max(0, len(d.logs)-tuiLogLines):]
+	return len(p), nil
+}
+
+// runTUI renders the dashboard on the terminal until ctx is done
+// The caller subscribes upstream; the dashboard shows what the pipeline publishes.
+//
+// Parameters:
+//   - ctx: Context stopping the dashboard when cancelled
+//   - server: Base URL of an instance to count the clients of, empty for none
+//
+// Returns:
+//   - error: Error if stdout is not a terminal
+func runTUI(ctx context.Context, server string) error {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("--tui needs stdout to be a terminal")
+	}
+
+	dashboard := &tuiDashboard{output: os.Stdout, fd: fd, server: strings.TrimSuffix(server, "/")}
+	RegisterSink(dashboard)
+	slog.SetDefault(slog.New(slog.NewTextHandler(dashboard, &slog.HandlerOptions{Level: logLevel, ReplaceAttr: redactAttr})))
+
+	fmt.Fprint(dashboard.output, ansiAlternateScreen)
+	defer fmt.Fprint(dashboard.output, ansiMainScreen)
+
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+	for {
+		if dashboard.server != "" {
+			goSafe("tui server stats", dashboard.pollServer)
+		}
+		dashboard.draw()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollServer reads the number of connected clients from the stats of the server
+func (d *tuiDashboard) pollServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), tuiServerTimeout)
+	defer cancel()
+
+	var stats StatsSnapshot
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, d.server+statsEndpoint, nil)
+	if err == nil {
+		var response *http.Response
+		if response, err = http.DefaultClient.Do(request); err == nil {
+			defer response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				err = fmt.Errorf("stats request failed with status %d", response.StatusCode)
+			} else {
+				err = json.NewDecoder(response.Body).Decode(&stats)
+			}
+		}
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.clients, d.clientErr = stats.ConnectedClients, err
+}
+
+// draw renders the dashboard over the previous one
+func (d *tuiDashboard) draw() {
+	width, height, err := term.GetSize(d.fd)
+	if err != nil || width <= 0 || height <= 0 {
+		width, height = tuiDefaultWidth, tuiDefaultHeight
+	}
+	now := time.Now().UTC()
+	stats := FeedStats.Snapshot()
+	upstream := stats.Upstream
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for len(d.launches) > 0 && now.Sub(d.launches[0]) > tuiRateWindow {
+		d.launches = d.launches[1:]
+	}
+
+	var lines []string
+	lines = append(lines, ansiBold+fmt.Sprintf("Nova feed %s", currentBuild.Version)+ansiReset+"  "+now.Format(time.DateTime)+" UTC")
+	lines = append(lines, "")
+
+	var health string
+	switch {
+	case upstream.Connected:
+		health = ansiGreen + "connected" + ansiReset + " for " + now.Sub(upstream.ConnectedAt).Round(time.Second).String()
+	case upstream.LastError != "":
+		health = ansiRed + "disconnected" + ansiReset + ": " + upstream.LastError
+	default:
+		health = "connecting"
+	}
+	lines = append(lines, "Upstream   "+health)
+	lastMessage := "none yet"
+	if !upstream.LastMessageAt.IsZero() {
+		lastMessage = now.Sub(upstream.LastMessageAt).Round(time.Second).String() + " ago"
+	}
+	lines = append(lines, fmt.Sprintf("           last notification %s, slot %d, %d reconnects", lastMessage, upstream.LatestSlot, upstream.Reconnects))
+
+	lines = append(lines, fmt.Sprintf("Launches   %d in the last minute, %d since start, %d graduations", len(d.launches), stats.TokensTotal, stats.Graduations))
+
+	var clients string
+	switch {
+	case d.server == "":
+		clients = ansiDim + "pass --server to count the clients of an instance" + ansiReset
+	case d.clientErr != nil:
+		clients = ansiRed + d.clientErr.Error() + ansiReset
+	default:
+		clients = fmt.Sprintf("%d connected to %s", d.clients, d.server)
+	}
+	lines = append(lines, "Clients    "+clients)
+	lines = append(lines, "")
+
+	// The creations take the rows the header and the logs leave
+	lines = append(lines, ansiBold+"Creations"+ansiReset)
+	rows := height - len(lines) - tuiLogLines - 2
+	for _, entry := range d.creations[:max(0, min(len(d.creations), rows))] {
+		lines = append(lines, fmt.Sprintf("%s  %-10s  %-32s  %s",
+			entry.receivedAt.Format(time.TimeOnly), clip(printable(entry.creation.Symbol), 10), clip(printable(entry.creation.Name), 32), entry.creation.Mint))
+	}
+	for range max(0, rows-len(d.creations)) {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, ansiBold+"Log"+ansiReset)
+	for _, line := range d.logs {
+		lines = append(lines, ansiDim+clip(printable(line), width)+ansiReset)
+	}
+
+	var screen bytes.Buffer
+	screen.WriteString(ansiHome)
+	for i, line := range lines[:min(len(lines), height)] {
+		if i > 0 {
+			screen.WriteString("\n")
+		}
+		screen.WriteString(clipANSI(line, width))
+		screen.WriteString(ansiClearLine)
+	}
+	screen.WriteString(ansiClearBelow)
+	d.output.Write(screen.Bytes())
+}
+
+// printable replaces the control characters of a string, such as escape
+// sequences in a token name, so they cannot drive the terminal
+func printable(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// clip shortens a string to at most width runes
+func clip(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string(runes[:max(0, width)])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// clipANSI shortens a line carrying ANSI sequences to width visible runes,
+// resetting the attributes when it is cut
+func clipANSI(line string, width int) string {
+	var clipped strings.Builder
+	visible := 0
+	escape := false
+	for _, r := range line {
+		switch {
+		case escape:
+			clipped.WriteRune(r)
+			escape = !unicode.IsLetter(r)
+		case r == '\x1b':
+			clipped.WriteRune(r)
+			escape = true
+		case visible < width:
+			clipped.WriteRune(r)
+			visible++
+		default:
+			clipped.WriteString(ansiReset)
+			return clipped.String()
+		}
+	}
+	return clipped.String()
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/dghubble/oauth1"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file with X credentials and posting rules
+	// Auto-posting is disabled when it is unset
+	twitterConfigFileEnv = "TWITTER_CONFIG_FILE"
+
+	// X API v2 endpoint for creating posts
+	twitterCreatePostURL = "https://api.twitter.com/2/tweets"
+
+	// Kinds of notable events a rule can post about
+	twitterRuleGraduation = "graduation"
+	twitterRuleMarketCap  = "market_cap"
+
+	// Number of posts buffered before new ones are dropped
+	twitterQueueSize = 100
+
+	// Number of mints remembered exactly per market cap rule so each is posted
+	// only once; older ones are remembered by the Bloom filters of the cache
+	twitterMaxRememberedMints = 10000
+)
+
+// TwitterConfig holds the app credentials of the posting account and its rules
+type TwitterConfig struct {
+	ConsumerKey    string        `json:"consumer_key"`    // App API key
+	ConsumerSecret string        `json:"consumer_secret"` // App API secret
+	AccessToken    string        `json:"access_token"`    // Access token of the posting account
+	AccessSecret   string        `json:"access_secret"`   // Access token secret of the posting account
+	Rules          []TwitterRule `json:"rules"`           // Posting rules
+}
+
+// TwitterRule posts about one kind of notable event
+type TwitterRule struct {
+	Name            string       `json:"name"`                         // Rule name used in logs
+	Kind            string       `json:"kind"`                         // "graduation" or "market_cap"
+	MinMarketCapSOL float64      `json:"min_market_cap_sol,omitempty"` // Threshold for market_cap rules
+	Template        string       `json:"template"`                     // text/template rendering the post
+	MinInterval     string       `json:"min_interval,omitempty"`       // Go duration between two posts of this rule
+	Filter          NotifyFilter `json:"filter"`                       // Additional name, creator and risk filters
+}
+
+// twitterPostData is the data available to post templates
+type twitterPostData struct {
+	Name         string  // Token name
+	Symbol       string  // Token symbol
+	Mint         string  // Token mint address
+	MarketCapSOL float64 // Market cap implied by the latest curve price, in SOL
+	PumpFunURL   string  // pump.fun page of the token
+	SolscanURL   string  // Solscan page of the token
+}
+
+// twitterRule holds the parsed template and throttling state of a rule
+type twitterRule struct {
+	config      TwitterRule
+	template    *template.Template
+	minInterval time.Duration
+	mutex       sync.Mutex
+	lastPostAt  time.Time
+	posted      *dedupCache // Mints posted by a market cap rule
+}
+
+// TwitterSink posts notable events from a configured X account
+type TwitterSink struct {
+	client *http.Client
+	rules  []*twitterRule
+	queue  chan string
+}
+
+// setupTwitterSink registers the X integration when TWITTER_CONFIG_FILE is set
+func setupTwitterSink() error {
+	path := os.Getenv(twitterConfigFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read Twitter config file: %w", err)
+	}
+
+	var config TwitterConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("failed to parse Twitter config file: %w", err)
+	}
+
+	oauthConfig := oauth1.NewConfig(config.ConsumerKey, config.ConsumerSecret)
+	sink := &TwitterSink{
+		client: oauthConfig.Client(oauth1.NoContext, oauth1.NewToken(config.AccessToken, config.AccessSecret)),
+		queue:  make(chan string, twitterQueueSize),
+	}
+	sink.client.Timeout = webhookRequestTimeout
+
+	for _, ruleConfig := range config.Rules {
+		rule, err := newTwitterRule(ruleConfig)
+		if err != nil {
+			return fmt.Errorf("Twitter rule %q: %w", ruleConfig.Name, err)
+		}
+		sink.rules = append(sink.rules, rule)
+	}
+
+	go sink.postLoop()
+	RegisterSink(sink)
+
+	slog.Info("Auto-posting to X", "rules", len(sink.rules))
+	return nil
+}
+
+// newTwitterRule validates a rule and prepares its template
+func newTwitterRule(config TwitterRule) (*twitterRule, error) {
+	switch config.Kind {
+	case twitterRuleGraduation:
+		config.Filter.EventTypes = []EventType{EventComplete}
+	case twitterRuleMarketCap:
+		if config.MinMarketCapSOL <= 0 {
+			return nil, fmt.Errorf("min_market_cap_sol must be positive")
+		}
+		config.Filter.EventTypes = []EventType{EventTrade}
+	default:
+		return nil, fmt.Errorf("kind must be %q or %q", twitterRuleGraduation, twitterRuleMarketCap)
+	}
+
+	if err := config.Filter.Compile(); err != nil {
+		return nil, err
+	}
+
+	parsed, err := template.New(config.Name).Parse(config.Template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	rule := &twitterRule{config: config, template: parsed, posted: newDedupCache("twitter_rule", twitterMaxRememberedMints)}
+	if config.MinInterval != "" {
+		if rule.minInterval, err = time.ParseDuration(config.MinInterval); err != nil {
+			return nil, fmt.Errorf("invalid min_interval: %w", err)
+		}
+	}
+	return rule, nil
+}
+
+// Name identifies the sink in logs
+func (t *TwitterSink) Name() string {
+	return "twitter"
+}
+
+// Publish queues a post for every rule the event triggers
+func (t *TwitterSink) Publish(event Event) {
+	if event.Type == EventCreate {
+		return
+	}
+
+	record, found := Tokens.Get(event.Mint)
+	if !found {
+		return
+	}
+	flags := newRiskFlagSet(record)
+
+	for _, rule := range t.rules {
+		if !rule.config.Filter.Matches(event, record, flags) {
+			continue
+		}
+
+		post, ok := rule.trigger(record)
+		if !ok {
+			continue
+		}
+
+		select {
+		case t.queue <- post:
+		default:
+			slog.Warn("Twitter queue full, dropping post", "rule", rule.config.Name)
+		}
+	}
+}
+
+// trigger decides whether the rule fires for a token and renders the post
+// Market cap rules fire once per mint when the threshold is first crossed, and
+// every rule is throttled to one post per min_interval
+func (r *twitterRule) trigger(record TokenRecord) (string, bool) {
+	marketCap := 0.0
+	if record.Curve != nil {
+		marketCap = record.Curve.MarketCapSOL()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.config.Kind == twitterRuleMarketCap && (marketCap < r.config.MinMarketCapSOL || r.posted.Contains(record.Mint)) {
+		return "", false
+	}
+	if r.minInterval > 0 && time.Since(r.lastPostAt) < r.minInterval {
+		return "", false
+	}
+
+	var builder strings.Builder
+	err := r.template.Execute(&builder, twitterPostData{
+		Name:         record.Creation.Name,
+		Symbol:       record.Creation.Symbol,
+		Mint:         record.Mint,
+		MarketCapSOL: marketCap,
+		PumpFunURL:   pumpFunLink(record.Mint),
+		SolscanURL:   solscanLink(record.Mint),
+	})
+	if err != nil {
+		slog.Warn("Failed to render post", "rule", r.config.Name, logKeyError, err)
+		return "", false
+	}
+
+	r.lastPostAt = time.Now()
+	r.posted.Add(record.Mint)
+
+	return builder.String(), true
+}
+
+// postLoop publishes queued posts one at a time
+func (t *TwitterSink) postLoop() {
+	for post := range t.queue {
+		if err := t.post(post); err != nil {
+			slog.Warn("Failed to post to X", logKeyError, err)
+		}
+	}
+}
+
+// post creates a single post through the X API v2
+func (t *TwitterSink) post(text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Post(twitterCreatePostURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+
+	"github.com/luqmanafiq/solana-blockchain/backend/pkg/pumpstream"
+)
+
+// Configuration constants
+const (
+	// Environment variable enabling the transaction builder endpoints when true
+	transactionBuilderEnv = "TRANSACTION_BUILDER"
+
+	// REST endpoints building unsigned buy and sell transactions
+	buildBuyEndpoint  = "/api/transactions/buy"
+	buildSellEndpoint = "/api/transactions/sell"
+
+	// Trading fee of the program in basis points, used to quote trades
+	pumpFeeBasisPoints = 100
+
+	// Slippage allowed when the request does not set one, in basis points
+	defaultSlippageBasisPoints = 100
+
+	// Largest slippage a request may allow, in basis points
+	maxSlippageBasisPoints = 5000
+
+	// Compute unit limit used when the request does not set one; a buy
+	// creating the token account of the user takes about 70k
+	defaultComputeUnitLimit = 120_000
+
+	// Compute unit price used when the request does not set one and no
+	// priority fee estimate is available, in micro-lamports
+	defaultComputeUnitPrice = 50_000
+
+	// Longest the RPC requests of a build may take
+	transactionBuildTimeout = 10 * time.Second
+)
+
+// TradeSide is the direction of a trade on a bonding curve
+type TradeSide string
+
+// Trade directions
+const (
+	TradeBuy  TradeSide = "buy"
+	TradeSell TradeSide = "sell"
+)
+
+// TradeTransactionRequest is the JSON body of the transaction builder endpoints
+type TradeTransactionRequest struct {
+	Mint             string `json:"mint"`                         // Token mint address
+	User             string `json:"user"`                         // Wallet that signs and pays
+	Amount           uint64 `json:"amount"`                       // Lamports to spend, fee included, for buys; token base units to sell for sells
+	SlippageBps      uint64 `json:"slippage_bps,omitempty"`       // Price move tolerated, in basis points, 100 by default
+	ComputeUnitLimit uint32 `json:"compute_unit_limit,omitempty"` // Compute unit limit of the transaction
+	ComputeUnitPrice uint64 `json:"compute_unit_price,omitempty"` // Priority fee in micro-lamports per compute unit, the medium estimate by default
+}
+
+// TradeTransactionResponse is the JSON body returned by the transaction builder endpoints
+type TradeTransactionResponse struct {
+	Transaction          string `json:"transaction"`             // Unsigned transaction, base64-encoded wire format
+	Blockhash            string `json:"blockhash"`               // Recent blockhash of the transaction
+	LastValidBlockHeight uint64 `json:"last_valid_block_height"` // Block height after which the transaction expires
+	TokenAmount          uint64 `json:"token_amount"`            // Token base units bought or sold
+	SolAmount            uint64 `json:"sol_amount"`              // Lamports spent by a buy, or expected from a sell
+	SolLimit             uint64 `json:"sol_limit"`               // Most lamports a buy may cost, or fewest a sell may return
+}
+
+// builtTrade is a transaction built for a trade, with its quote
+type builtTrade struct {
+	transaction *solana.Transaction
+	response    TradeTransactionResponse
+}
+
+// TransactionBuilder builds unsigned trades on the bonding curves of the
+// program, quoted against the current state of the curve
+type TransactionBuilder struct {
+	client *rpc.Client
+}
+
+// TradeBuilder builds trade transactions when TRANSACTION_BUILDER is set, nil otherwise
+var TradeBuilder *TransactionBuilder
+
+// Typed failures of a build, mapped to HTTP statuses by the handlers
+var (
+	errInvalidTradeRequest = errors.New("invalid trade request")
+	errUnknownBondingCurve = errors.New("bonding curve not found")
+)
+
+// setupTransactionBuilder enables the transaction builder when TRANSACTION_BUILDER is set
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if no RPC endpoint can be resolved
+func setupTransactionBuilder(rpcURL string) error {
+	if !envBool(transactionBuilderEnv) {
+		return nil
+	}
+	endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+	if err != nil {
+		return err
+	}
+
+	TradeBuilder = &TransactionBuilder{client: rpc.New(endpoint)}
+	slog.Info("Building unsigned trade transactions", "buy_endpoint", buildBuyEndpoint, "sell_endpoint", buildSellEndpoint)
+	return nil
+}
+
+// Build quotes a trade against the bonding curve and builds its transaction,
+// with compute budget instructions and, for buys, the creation of the token
+// account of the user if it does not exist yet
+//
+// Parameters:
+//   - ctx: Context of the RPC requests
+//   - side: Buy or sell
+//   - request: The trade
+//
+// Returns:
+//   - *builtTrade: The unsigned transaction and its quote
+//   - error: errInvalidTradeRequest, errUnknownBondingCurve,
+//     pumpstream.ErrCurveComplete, or an RPC error
+func (b *TransactionBuilder) Build(ctx context.Context, side TradeSide, request TradeTransactionRequest) (*builtTrade, error) {
+	mint, err := solana.PublicKeyFromBase58(request.Mint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid mint", errInvalidTradeRequest)
+	}
+	user, err := solana.PublicKeyFromBase58(request.User)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user", errInvalidTradeRequest)
+	}
+	if request.Amount == 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", errInvalidTradeRequest)
+	}
+	slippage := request.SlippageBps
+	if slippage == 0 {
+		slippage = defaultSlippageBasisPoints
+	}
+	if slippage > maxSlippageBasisPoints {
+		return nil, fmt.Errorf("%w: slippage_bps must be at most %d", errInvalidTradeRequest, maxSlippageBasisPoints)
+	}
+
+	curve, err := b.bondingCurve(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+
+	response := TradeTransactionResponse{}
+	var trade solana.Instruction
+	switch side {
+	case TradeBuy:
+		tokens, err := curve.BuyQuote(request.Amount, pumpFeeBasisPoints)
+		if err != nil {
+			return nil, err
+		}
+		response.TokenAmount = tokens
+		response.SolAmount = request.Amount
+		response.SolLimit = request.Amount + pumpstream.MulDiv(request.Amount, slippage, 10_000)
+		if response.SolLimit < request.Amount {
+			response.SolLimit = math.MaxUint64 // Any cost the wallet can pay
+		}
+		trade, err = pumpstream.NewBuyInstruction(pumpProgram(), mint, user, tokens, response.SolLimit)
+		if err != nil {
+			return nil, err
+		}
+	case TradeSell:
+		lamports, err := curve.SellQuote(request.Amount, pumpFeeBasisPoints)
+		if err != nil {
+			return nil, err
+		}
+		response.TokenAmount = request.Amount
+		response.SolAmount = lamports
+		response.SolLimit = lamports - pumpstream.MulDiv(lamports, slippage, 10_000)
+		trade, err = pumpstream.NewSellInstruction(pumpProgram(), mint, user, request.Amount, response.SolLimit)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown side %q", errInvalidTradeRequest, side)
+	}
+
+	limit := request.ComputeUnitLimit
+	if limit == 0 {
+		limit = defaultComputeUnitLimit
+	}
+	price := request.ComputeUnitPrice
+	if price == 0 {
+		price = recommendedComputeUnitPrice()
+	}
+	instructions := []solana.Instruction{
+		computebudget.NewSetComputeUnitLimitInstruction(limit).Build(),
+		computebudget.NewSetComputeUnitPriceInstruction(price).Build(),
+	}
+	if side == TradeBuy {
+		createAccount, err := newCreateTokenAccountIdempotentInstruction(user, mint)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, createAccount)
+	}
+	instructions = append(instructions, trade)
+
+	latest, err := b.client.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a recent blockhash: %w", err)
+	}
+	transaction, err := solana.NewTransaction(instructions, latest.Value.Blockhash, solana.TransactionPayer(user))
+	if err != nil {
+		return nil, err
+	}
+	// Wallets fill in the signature the message asks for
+	transaction.Signatures = make([]solana.Signature, transaction.Message.Header.NumRequiredSignatures)
+
+	encoded, err := transaction.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	response.Transaction = base64.StdEncoding.EncodeToString(encoded)
+	response.Blockhash = latest.Value.Blockhash.String()
+	response.LastValidBlockHeight = latest.Value.LastValidBlockHeight
+	return &builtTrade{transaction: transaction, response: response}, nil
+}
+
+// bondingCurve fetches the current state of the bonding curve of a mint
+func (b *TransactionBuilder) bondingCurve(ctx context.Context, mint solana.PublicKey) (*pumpstream.BondingCurveAccount, error) {
+	address, err := pumpstream.BondingCurveAddress(pumpProgram(), mint)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := b.client.GetAccountInfoWithOpts(ctx, address, &rpc.GetAccountInfoOpts{Commitment: rpc.CommitmentProcessed})
+	if errors.Is(err, rpc.ErrNotFound) {
+		return nil, errUnknownBondingCurve
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the bonding curve: %w", err)
+	}
+	if !result.Value.Owner.Equals(pumpProgram()) {
+		return nil, errUnknownBondingCurve
+	}
+	return pumpstream.DecodeBondingCurveAccount(result.Value.Data.GetBinary())
+}
+
+// newCreateTokenAccountIdempotentInstruction creates the associated token
+// account of a wallet for a mint, doing nothing if it already exists
+func newCreateTokenAccountIdempotentInstruction(wallet, mint solana.PublicKey) (solana.Instruction, error) {
+	account, err := pumpstream.AssociatedTokenAddress(wallet, mint)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(wallet).WRITE().SIGNER(),
+		solana.Meta(account).WRITE(),
+		solana.Meta(wallet),
+		solana.Meta(mint),
+		solana.Meta(solana.SystemProgramID),
+		solana.Meta(solana.TokenProgramID),
+	}
+	// Instruction 1 of the associated token program is CreateIdempotent
+	return solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, accounts, []byte{1}), nil
+}
+
+// HandleBuildBuy builds an unsigned buy transaction
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a TradeTransactionRequest body, amount in lamports
+func HandleBuildBuy(w http.ResponseWriter, r *http.Request) {
+	handleBuildTrade(w, r, TradeBuy)
+}
+
+// HandleBuildSell builds an unsigned sell transaction
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a TradeTransactionRequest body, amount in token base units
+func HandleBuildSell(w http.ResponseWriter, r *http.Request) {
+	handleBuildTrade(w, r, TradeSell)
+}
+
+// handleBuildTrade builds a trade transaction and writes its quote
+func handleBuildTrade(w http.ResponseWriter, r *http.Request, side TradeSide) {
+	if TradeBuilder == nil {
+		writeError(w, http.StatusNotFound, "the transaction builder is disabled")
+		return
+	}
+
+	var request TradeTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), transactionBuildTimeout)
+	defer cancel()
+
+	built, err := TradeBuilder.Build(ctx, side, request)
+	switch {
+	case errors.Is(err, errInvalidTradeRequest):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errUnknownBondingCurve):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, pumpstream.ErrCurveComplete):
+		writeError(w, http.StatusConflict, "the bonding curve is complete; the token no longer trades on it")
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, built.response)
+	}
+}
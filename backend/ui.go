@@ -0,0 +1,24 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// uiFiles holds the bundled web UI served from the root path
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// registerUIRoutes serves the embedded web UI for every path not matched by
+// another route, so it must be registered last
+func registerUIRoutes(router *mux.Router) {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	router.PathPrefix("/").Handler(http.FileServerFS(files)).Methods(http.MethodGet, http.MethodHead)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Endpoint reporting which build is running
+	versionEndpoint = "/version"
+)
+
+// Build information, set at link time:
+//
+//	go build -ldflags "-X main.version=1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// The commit and build time fall back to the VCS stamp of the Go toolchain
+// when the binary was built from a checkout without them
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
+)
+
+// BuildInfo is the JSON body returned by the version endpoint
+type BuildInfo struct {
+	Version   string `json:"version"`              // Release version, dev for local builds
+	Commit    string `json:"commit,omitempty"`     // Git commit the binary was built from
+	Modified  bool   `json:"modified,omitempty"`   // True if the checkout had uncommitted changes
+	BuildTime string `json:"build_time,omitempty"` // Time of the build, or of the commit when only the VCS stamp is known
+	GoVersion string `json:"go_version"`           // Go toolchain version
+}
+
+// currentBuild is the build information of this binary
+var currentBuild = readBuildInfo()
+
+// readBuildInfo combines the link-time variables with the VCS stamp
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{Version: version, Commit: commit, BuildTime: buildTime, GoVersion: runtime.Version()}
+
+	stamp, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range stamp.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// String describes the build in one line, e.g. for the startup log
+func (b BuildInfo) String() string {
+	description := b.Version
+	if b.Commit != "" {
+		short := b.Commit
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		description += " (" + short
+		if b.Modified {
+			description += ", modified"
+		}
+		description += ")"
+	}
+	if b.BuildTime != "" {
+		description += " built " + b.BuildTime
+	}
+	return description + " with " + b.GoVersion
+}
+
+// registerVersionRoutes registers the version endpoint on the given router
+func registerVersionRoutes(router *mux.Router) {
+	router.HandleFunc(versionEndpoint, HandleVersion).Methods(http.MethodGet)
+}
+
+// HandleVersion reports the version, commit and build time of the running binary
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request
+func HandleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentBuild)
+}
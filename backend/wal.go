@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the path of the broadcast write-ahead log
+	// Setting it switches sinks to at-least-once delivery; when it is unset,
+	// events that are queued in memory are lost if the process crashes
+	broadcastWALPathEnv = "BROADCAST_WAL_PATH"
+
+	// Interval between deletions of delivered events from the log
+	walCheckpointInterval = 100 * time.Millisecond
+
+	// Timeout for acquiring the file lock held by another process
+	walOpenTimeout = 5 * time.Second
+
+	// Times an event a sink dropped or failed to write is handed to it again,
+	// the first after walRetryDelay and each following one after twice the
+	// previous delay; the event is given up for that sink afterwards
+	walMaxRetries = 5
+	walRetryDelay = time.Second
+
+	// Retries waiting at most; events failing beyond it are given up at once,
+	// so a sink that is down for long cannot grow the log without bound
+	walMaxRetrying = 100000
+)
+
+// walBucket is the bucket holding undelivered events keyed by sequence number
+var walBucket = []byte("pending")
+
+// walRecord is a logged event with its data kept encoded until it is replayed
+type walRecord struct {
+	Type       EventType       `json:"type"`        // Kind of event
+	Mint       string          `json:"mint"`        // Token mint address the event refers to
+	Signature  string          `json:"signature"`   // Transaction signature
+	Slot       uint64          `json:"slot"`        // Slot the transaction was observed in
+	ReceivedAt time.Time       `json:"received_at"` // Time the notification was received
+	Data       json.RawMessage `json:"data"`        // Encoded CreateEvent, TradeEvent or CompleteEvent
+
+	CorrelationID string `json:"correlation_id,omitempty"` // ID of the notification the event came in
+}
+
+// BroadcastWAL logs every decoded event before it is handed to the sinks and
+// forgets it once it has been delivered, so events a crash interrupted are
+// delivered again on the next start
+//
+// An event counts as delivered when every sink has accepted it and every
+// acknowledging sink (the batched writers of storage, the ring buffer and the
+// analytics warehouses) has written it. An event one of those sinks drops or
+// fails to write is read back from the log and handed to that sink again, up
+// to walMaxRetries times, and then counted as abandoned. Sinks therefore see
+// an event at least once, and possibly twice around a crash
+//
+// The log is written without fsync: it survives the process crashing, which is
+// what it guards against, but not the machine losing power
+type BroadcastWAL struct {
+	db         *bolt.DB
+	retryDelay time.Duration // Delay before the first retry of an event
+	mutex      sync.Mutex
+	remaining  map[uint64]int      // Acknowledgements still expected per logged event
+	retries    map[walRetryKey]int // Retries made of the events waiting for one
+	delivered  [][]byte            // Keys of delivered events not yet deleted from the log
+	abandoned  atomic.Uint64       // Events given up for a sink after their last retry
+}
+
+// walRetryKey identifies a logged event a sink dropped or failed to write
+type walRetryKey struct {
+	seq  uint64
+	sink ackingSink
+}
+
+// WAL is the broadcast write-ahead log, nil when at-least-once delivery is disabled
+var WAL *BroadcastWAL
+
+// setupBroadcastWAL opens the write-ahead log when BROADCAST_WAL_PATH is set and
+// redelivers the events a previous run left undelivered
+// It runs after every sink is registered so redelivered events reach all of them
+func setupBroadcastWAL() error {
+	path := os.Getenv(broadcastWALPathEnv)
+	if path == "" {
+		return nil
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: walOpenTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+	db.NoSync = true
+
+	var undelivered []uint64
+	var events []Event
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(walBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			event, err := decodeWALRecord(value)
+			if err != nil {
+				slog.Warn("Skipping corrupt write-ahead log entry", "key", fmt.Sprintf("%x", key), logKeyError, err)
+				return nil
+			}
+			undelivered = append(undelivered, binary.BigEndian.Uint64(key))
+			events = append(events, event)
+			return nil
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+
+	wal := &BroadcastWAL{db: db, retryDelay: walRetryDelay, remaining: map[uint64]int{}, retries: map[walRetryKey]int{}}
+	go wal.checkpointLoop()
+	WAL = wal
+
+	for i, event := range events {
+		wal.dispatch(undelivered[i], event)
+	}
+
+	slog.Info("At-least-once delivery through the write-ahead log", "path", path, "redelivered", len(events))
+	return nil
+}
+
+// decodeWALRecord decodes a logged event, restoring the type of its data
+func decodeWALRecord(value []byte) (Event, error) {
+	var record walRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return Event{}, err
+	}
+	data, err := decodeEventData(record.Type, record.Data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		Type:       record.Type,
+		Mint:       record.Mint,
+		Signature:  record.Signature,
+		Slot:       record.Slot,
+		ReceivedAt: record.ReceivedAt,
+		Data:       data,
+
+		CorrelationID: record.CorrelationID,
+	}, nil
+}
+
+// publish logs the event and hands it to every sink
+// If the event cannot be logged it is still delivered, at most once
+func (w *BroadcastWAL) publish(event Event) {
+	seq, err := w.append(event)
+	if err != nil {
+		slog.Error("Failed to log event, delivering it without the write-ahead log", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, err)
+		publishToSinks(event, nil)
+		return
+	}
+	w.dispatch(seq, event)
+}
+
+// append writes the event under the next sequence number
+func (w *BroadcastWAL) append(event Event) (uint64, error) {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	var seq uint64
+	err = w.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(walBucket)
+		if seq, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+		return bucket.Put(ringKey(seq), value)
+	})
+	return seq, err
+}
+
+// dispatch hands a logged event to every sink and tracks its acknowledgements
+func (w *BroadcastWAL) dispatch(seq uint64, event Event) {
+	// One acknowledgement per acknowledging sink, plus one once all sinks have
+	// been handed the event
+	w.mutex.Lock()
+	w.remaining[seq] = ackingSinkCount() + 1
+	w.mutex.Unlock()
+
+	publishToSinks(event, func(sink ackingSink, written bool) { w.ack(seq, sink, written) })
+	w.ack(seq, nil, true)
+}
+
+// ackingSinkCount returns the number of registered sinks that acknowledge events
+func ackingSinkCount() int {
+	eventSinksMutex.RLock()
+	defer eventSinksMutex.RUnlock()
+
+	count := 0
+	for _, sink := range eventSinks {
+		if _, ok := sink.(ackingSink); ok {
+			count++
+		}
+	}
+	return count
+}
+
+// pendingCount returns the number of dispatched events still waiting for acknowledgements
+func (w *BroadcastWAL) pendingCount() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return len(w.remaining)
+}
+
+// abandonedCount returns the number of events given up for a sink since startup
+func (w *BroadcastWAL) abandonedCount() uint64 {
+	return w.abandoned.Load()
+}
+
+// ack records the acknowledgement of an event by a sink, nil once every sink
+// has been handed it, and marks the event delivered after the last one
+// An event the sink did not write is retried instead, until it is given up.
+func (w *BroadcastWAL) ack(seq uint64, sink ackingSink, written bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if sink != nil {
+		key := walRetryKey{seq: seq, sink: sink}
+		attempts, retrying := w.retries[key]
+		if !written && attempts < walMaxRetries && (retrying || len(w.retries) < walMaxRetrying) {
+			w.retries[key] = attempts + 1
+			time.AfterFunc(w.retryDelay<<attempts, func() { w.retry(seq, sink) })
+			return
+		}
+		delete(w.retries, key)
+		if !written {
+			w.abandoned.Add(1)
+			slog.Warn("Giving up delivering write-ahead log entry", logKeySink, sink.Name(), "seq", seq, "retries", attempts)
+		}
+	}
+
+	w.remaining[seq]--
+	if w.remaining[seq] > 0 {
+		return
+	}
+	delete(w.remaining, seq)
+	w.delivered = append(w.delivered, ringKey(seq))
+}
+
+// retry reads an event back from the log and hands it to the sink that did not write it
+func (w *BroadcastWAL) retry(seq uint64, sink ackingSink) {
+	var event Event
+	err := w.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(walBucket).Get(ringKey(seq))
+		if value == nil {
+			return fmt.Errorf("entry missing")
+		}
+		var err error
+		event, err = decodeWALRecord(value)
+		return err
+	})
+	if err != nil {
+		slog.Error("Failed to read write-ahead log entry to retry", "seq", seq, logKeyError, err)
+		w.giveUp(seq, sink)
+		return
+	}
+
+	if !Features.Enabled(sinkFeaturePrefix + sink.Name()) {
+		w.ack(seq, sink, true)
+		return
+	}
+	sink.PublishWithAck(event, func(written bool) { w.ack(seq, sink, written) })
+}
+
+// giveUp abandons an event for a sink without retrying it further
+func (w *BroadcastWAL) giveUp(seq uint64, sink ackingSink) {
+	w.mutex.Lock()
+	w.retries[walRetryKey{seq: seq, sink: sink}] = walMaxRetries
+	w.mutex.Unlock()
+	w.ack(seq, sink, false)
+}
+
+// checkpointLoop periodically deletes delivered events from the log
+func (w *BroadcastWAL) checkpointLoop() {
+	ticker := time.NewTicker(walCheckpointInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.checkpoint()
+	}
+}
+
+// checkpoint deletes the events delivered since the last checkpoint in one transaction
+func (w *BroadcastWAL) checkpoint() {
+	w.mutex.Lock()
+	delivered := w.delivered
+	w.delivered = nil
+	w.mutex.Unlock()
+
+	if len(delivered) == 0 {
+		return
+	}
+	err := w.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(walBucket)
+		for _, key := range delivered {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The events stay logged and are delivered again after a restart
+		slog.Error("Failed to checkpoint write-ahead log", logKeyError, err)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// testAckingSink acknowledges events, failing to write the first ones
+type testAckingSink struct {
+	name     string
+	failures int // Writes failing before one succeeds, every write failing when negative
+
+	mutex     sync.Mutex
+	published []Event
+}
+
+// Name identifies the sink in the acknowledgements
+func (s *testAckingSink) Name() string {
+	return s.name
+}
+
+// Publish records the event without acknowledging it
+func (s *testAckingSink) Publish(event Event) {
+	s.PublishWithAck(event, func(bool) {})
+}
+
+// PublishWithAck records the event and acknowledges it, as written once the
+// failures are used up
+func (s *testAckingSink) PublishWithAck(event Event, ack func(written bool)) {
+	s.mutex.Lock()
+	s.published = append(s.published, event)
+	written := s.failures >= 0 && len(s.published) > s.failures
+	s.mutex.Unlock()
+	ack(written)
+}
+
+// attempts returns the number of times the sink was handed an event
+func (s *testAckingSink) attempts() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.published)
+}
+
+// testPlainSink is a sink that does not acknowledge events
+type testPlainSink struct{}
+
+// Name identifies the sink
+func (testPlainSink) Name() string {
+	return "plain"
+}
+
+// Publish ignores events
+func (testPlainSink) Publish(Event) {}
+
+// testSinks registers the given sinks alone for the duration of a test
+func testSinks(t *testing.T, sinks ...EventSink) {
+	eventSinksMutex.Lock()
+	previous := eventSinks
+	eventSinks = sinks
+	eventSinksMutex.Unlock()
+	t.Cleanup(func() {
+		eventSinksMutex.Lock()
+		eventSinks = previous
+		eventSinksMutex.Unlock()
+	})
+}
+
+// testBroadcastWAL opens a write-ahead log in a temporary directory, retrying
+// events after the given delay
+func testBroadcastWAL(t *testing.T, retryDelay time.Duration) *BroadcastWAL {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "wal.db"), 0o600, nil)
+	if err != nil {
+		t.Fatalf("failed to open write-ahead log: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket(walBucket)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	return &BroadcastWAL{db: db, retryDelay: retryDelay, remaining: map[uint64]int{}, retries: map[walRetryKey]int{}}
+}
+
+// loggedCount returns the number of events in the write-ahead log
+func loggedCount(t *testing.T, wal *BroadcastWAL) int {
+	count := 0
+	if err := wal.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(walBucket).Stats().KeyN
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to read write-ahead log: %v", err)
+	}
+	return count
+}
+
+// testWALEvent returns a trade event to log
+func testWALEvent(signature string) Event {
+	return Event{
+		Type:       EventTrade,
+		Mint:       "2zMMhcVQEXDtdE6vsFS7S7D5oUodfJHE8vd1gnBouauv",
+		Signature:  signature,
+		Slot:       1,
+		ReceivedAt: time.Now().UTC(),
+		Data:       TradeEvent{Mint: "2zMMhcVQEXDtdE6vsFS7S7D5oUodfJHE8vd1gnBouauv", SolAmount: 1_000_000, IsBuy: true},
+	}
+}
+
+// TestBroadcastWALDelivery checks that a logged event is retried for each sink
+// that fails to write it, up to walMaxRetries times, and deleted from the log
+// by the next checkpoint once every sink has written it or given up
+func TestBroadcastWALDelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []int // Of each acknowledging sink
+		plain     bool
+		attempts  []int
+		abandoned uint64
+	}{
+		{name: "written", failures: []int{0}, attempts: []int{1}},
+		{name: "written after retries", failures: []int{2}, attempts: []int{3}},
+		{name: "written on the last retry", failures: []int{walMaxRetries}, attempts: []int{walMaxRetries + 1}},
+		{name: "given up", failures: []int{-1}, attempts: []int{walMaxRetries + 1}, abandoned: 1},
+		{name: "one of two sinks retried", failures: []int{0, 1}, attempts: []int{1, 2}},
+		{name: "sink without acknowledgements", plain: true},
+		{name: "no sinks"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sinks []EventSink
+			var acking []*testAckingSink
+			for i, failures := range test.failures {
+				sink := &testAckingSink{name: fmt.Sprintf("acking-%d", i), failures: failures}
+				sinks = append(sinks, sink)
+				acking = append(acking, sink)
+			}
+			if test.plain {
+				sinks = append(sinks, testPlainSink{})
+			}
+			testSinks(t, sinks...)
+
+			wal := testBroadcastWAL(t, time.Millisecond)
+			wal.publish(testWALEvent("signature"))
+			deadline := time.Now().Add(5 * time.Second)
+			for wal.pendingCount() > 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("the event was never delivered")
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			if got := loggedCount(t, wal); got != 1 {
+				t.Fatalf("got %d events logged before the checkpoint, expected 1", got)
+			}
+			wal.checkpoint()
+			if got := loggedCount(t, wal); got != 0 {
+				t.Fatalf("got %d events logged after the checkpoint, expected none", got)
+			}
+			for i, sink := range acking {
+				if got := sink.attempts(); got != test.attempts[i] {
+					t.Fatalf("sink %s got the event %d times, expected %d", sink.name, got, test.attempts[i])
+				}
+			}
+			if got := wal.abandonedCount(); got != test.abandoned {
+				t.Fatalf("got %d abandoned, expected %d", got, test.abandoned)
+			}
+		})
+	}
+}
+
+// TestBroadcastWALRetryBound checks that once walMaxRetrying events wait for a
+// retry, new failures are given up at once while waiting ones keep retrying
+func TestBroadcastWALRetryBound(t *testing.T) {
+	sink := &testAckingSink{name: "acking", failures: -1}
+	wal := testBroadcastWAL(t, time.Hour)
+	other := &testAckingSink{name: "other"}
+	for seq := uint64(1); seq <= walMaxRetrying; seq++ {
+		wal.retries[walRetryKey{seq: seq, sink: other}] = 1
+	}
+
+	retrying := walRetryKey{seq: 1, sink: sink}
+	wal.retries[retrying] = 1
+	wal.remaining[1] = 2
+	wal.ack(1, sink, false)
+	if got := wal.retries[retrying]; got != 2 || wal.remaining[1] != 2 {
+		t.Fatalf("got %d retries and %d acknowledgements remaining, expected the waiting event retried", got, wal.remaining[1])
+	}
+
+	seq := uint64(walMaxRetrying + 1)
+	wal.remaining[seq] = 2
+	wal.ack(seq, sink, false)
+	if _, found := wal.retries[walRetryKey{seq: seq, sink: sink}]; found {
+		t.Fatal("the new failure waits for a retry, expected it given up")
+	}
+	if got := wal.abandonedCount(); got != 1 || wal.remaining[seq] != 1 {
+		t.Fatalf("got %d abandoned and %d acknowledgements remaining, expected the event given up", got, wal.remaining[seq])
+	}
+}
+
+// TestSetupBroadcastWAL checks that the events a previous run left in the log
+// are delivered again at startup, in order, skipping corrupt entries
+func TestSetupBroadcastWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.db")
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatalf("failed to open write-ahead log: %v", err)
+	}
+	previousRun := &BroadcastWAL{db: db}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket(walBucket)
+		return err
+	}); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	for _, signature := range []string{"first", "second"} {
+		if _, err := previousRun.append(testWALEvent(signature)); err != nil {
+			t.Fatalf("failed to log event: %v", err)
+		}
+	}
+	if err := db.Update(func(tx *bolt.Tx) error { return tx.Bucket(walBucket).Put(ringKey(100), []byte("{")) }); err != nil {
+		t.Fatalf("failed to log corrupt entry: %v", err)
+	}
+	db.Close()
+
+	previous := WAL
+	t.Cleanup(func() {
+		WAL.db.Close()
+		WAL = previous
+	})
+	sink := &testAckingSink{name: "acking"}
+	testSinks(t, sink)
+	t.Setenv(broadcastWALPathEnv, path)
+	if err := setupBroadcastWAL(); err != nil {
+		t.Fatalf("failed to set up write-ahead log: %v", err)
+	}
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if len(sink.published) != 2 || sink.published[0].Signature != "first" || sink.published[1].Signature != "second" {
+		t.Fatalf("got %+v, expected the two logged events", sink.published)
+	}
+	if trade, ok := sink.published[0].Data.(TradeEvent); !ok || trade.SolAmount != 1_000_000 {
+		t.Fatalf("got data %#v, expected the logged trade", sink.published[0].Data)
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gorilla/mux"
+)
+
+// Configuration constants
+const (
+	// Environment variable that must be true for the wallet to sign anything;
+	// a configured key alone does not enable signing
+	walletSigningEnv = "WALLET_SIGNING"
+
+	// Environment variable with the path to a keypair file in the format of
+	// solana-keygen, a JSON array of the 64 bytes of the secret key
+	walletKeypairFileEnv = "WALLET_KEYPAIR_FILE"
+
+	// Environment variable with the base58 secret key, instead of a file
+	walletPrivateKeyEnv = "WALLET_PRIVATE_KEY"
+
+	// Environment variable with the most lamports a single buy may cost, fee
+	// and slippage included
+	walletMaxTradeLamportsEnv = "WALLET_MAX_TRADE_LAMPORTS"
+
+	// Cap used when WALLET_MAX_TRADE_LAMPORTS is unset, 0.1 SOL
+	defaultWalletMaxTradeLamports = 100_000_000
+
+	// Admin endpoints showing the wallet and submitting and tracking its trades
+	adminWalletEndpoint       = "/admin/wallet"
+	adminWalletTradesEndpoint = "/admin/wallet/trades"
+	adminWalletTradeEndpoint  = "/admin/wallet/trades/{signature}"
+
+	// Number of trades remembered for tracking
+	walletTradeHistorySize = 1000
+
+	// Interval between confirmation checks of a submitted trade
+	walletConfirmationInterval = time.Second
+
+	// Most confirmation checks of a trade, well past the lifetime of its
+	// blockhash, before tracking gives up on an unreachable RPC node
+	walletMaxConfirmationChecks = 300
+)
+
+// Statuses of a wallet trade
+const (
+	walletTradeSubmitted = "submitted" // Sent, not confirmed yet
+	walletTradeConfirmed = "confirmed" // Confirmed by a supermajority of the cluster
+	walletTradeFailed    = "failed"    // Landed, but the transaction failed
+	walletTradeExpired   = "expired"   // Never landed before its blockhash expired
+	walletTradeUnknown   = "unknown"   // Tracking gave up without learning the outcome
+)
+
+// WalletTrade is a trade signed and submitted by the wallet
+type WalletTrade struct {
+	Signature   string    `json:"signature"`           // Transaction signature
+	Side        TradeSide `json:"side"`                // buy or sell
+	Mint        string    `json:"mint"`                // Token mint address
+	TokenAmount uint64    `json:"token_amount"`        // Token base units bought or sold
+	SolAmount   uint64    `json:"sol_amount"`          // Lamports spent by a buy, or expected from a sell
+	SolLimit    uint64    `json:"sol_limit"`           // Most lamports a buy may cost, or fewest a sell may return
+	Status      string    `json:"status"`              // submitted, confirmed, failed, expired or unknown
+	Error       string    `json:"error,omitempty"`     // Why the transaction failed, or why its outcome is unknown
+	Slot        uint64    `json:"slot,omitempty"`      // Slot the transaction landed in
+	SubmittedAt time.Time `json:"submitted_at"`        // Time the transaction was sent
+	SettledAt   time.Time `json:"settled_at,omitzero"` // Time the final status was known
+	lastValid   uint64    // Block height after which the transaction cannot land
+}
+
+// WalletTradeRequest is the JSON body submitting a wallet trade
+type WalletTradeRequest struct {
+	Side             TradeSide `json:"side"`                         // buy or sell
+	Mint             string    `json:"mint"`                         // Token mint address
+	Amount           uint64    `json:"amount"`                       // Lamports to spend for buys, token base units to sell for sells
+	SlippageBps      uint64    `json:"slippage_bps,omitempty"`       // Price move tolerated, in basis points
+	ComputeUnitPrice uint64    `json:"compute_unit_price,omitempty"` // Priority fee in micro-lamports per compute unit, the medium estimate by default
+}
+
+// walletResponse is the JSON body describing the wallet
+type walletResponse struct {
+	PublicKey        string        `json:"public_key"`         // Address of the wallet
+	MaxTradeLamports uint64        `json:"max_trade_lamports"` // Most lamports a single buy may cost
+	Trades           []WalletTrade `json:"trades"`             // Remembered trades, newest first
+}
+
+// Wallet holds a local keypair that signs and submits the trades built by the
+// transaction builder, and tracks them until they confirm, fail or expire
+//
+// The wallet is a liability: with it, anyone holding an operator admin token
+// can spend its balance. It only loads with WALLET_SIGNING=true, and every buy
+// is capped by WALLET_MAX_TRADE_LAMPORTS; keep only what bots need on it.
+type Wallet struct {
+	key         solana.PrivateKey
+	builder     *TransactionBuilder
+	maxLamports uint64
+
+	mutex  sync.RWMutex
+	trades map[string]*WalletTrade
+	order  []string // Signatures, oldest first
+}
+
+// HotWallet signs trades when WALLET_SIGNING is true, nil otherwise
+var HotWallet *Wallet
+
+// errTradeTooLarge is returned for buys that may cost more than the cap
+var errTradeTooLarge = errors.New("trade exceeds the wallet cap")
+
+// errTradeSubmission is returned when sending a signed trade failed; the
+// transaction may still have reached the network and land
+var errTradeSubmission = errors.New("failed to submit the transaction")
+
+// setupWallet loads the wallet when WALLET_SIGNING is true
+//
+// Parameters:
+//   - rpcURL: HTTP RPC endpoint; the Helius endpoint when empty
+//
+// Returns:
+//   - error: Error if signing is enabled without a valid key or RPC endpoint
+func setupWallet(rpcURL string) error {
+	if !envBool(walletSigningEnv) {
+		return nil
+	}
+
+	key, err := loadWalletKey()
+	if err != nil {
+		return err
+	}
+
+	maxLamports := uint64(defaultWalletMaxTradeLamports)
+	if value := os.Getenv(walletMaxTradeLamportsEnv); value != "" {
+		if maxLamports, err = strconv.ParseUint(value, 10, 64); err != nil || maxLamports == 0 {
+			return fmt.Errorf("invalid %s %q", walletMaxTradeLamportsEnv, value)
+		}
+	}
+
+	builder := TradeBuilder
+	if builder == nil {
+		endpoint, err := resolveEndpoint(rpcURL, heliusRPCURL)
+		if err != nil {
+			return err
+		}
+		builder = &TransactionBuilder{client: rpc.New(endpoint)}
+	}
+
+	HotWallet = &Wallet{
+		key:         key,
+		builder:     builder,
+		maxLamports: maxLamports,
+		trades:      map[string]*WalletTrade{},
+	}
+	slog.Info("Signing trades", "wallet", key.PublicKey().String(), "max_lamports_per_buy", maxLamports)
+	return nil
+}
+
+// loadWalletKey reads the secret key from WALLET_KEYPAIR_FILE or WALLET_PRIVATE_KEY
+func loadWalletKey() (solana.PrivateKey, error) {
+	file, encoded := os.Getenv(walletKeypairFileEnv), os.Getenv(walletPrivateKeyEnv)
+	switch {
+	case file != "" && encoded != "":
+		return nil, fmt.Errorf("set only one of %s and %s", walletKeypairFileEnv, walletPrivateKeyEnv)
+	case file != "":
+		key, err := solana.PrivateKeyFromSolanaKeygenFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", walletKeypairFileEnv, err)
+		}
+		return key, nil
+	case encoded != "":
+		registerSecret(encoded)
+		key, err := solana.PrivateKeyFromBase58(encoded)
+		if err != nil || len(key) != 64 {
+			return nil, fmt.Errorf("invalid %s", walletPrivateKeyEnv)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%s or %s must be set when %s is true", walletKeypairFileEnv, walletPrivateKeyEnv, walletSigningEnv)
+	}
+}
+
+// Trade builds, signs and submits a trade, then tracks it in the background
+//
+// Parameters:
+//   - ctx: Context of the build and submission
+//   - request: The trade
+//
+// Returns:
+//   - WalletTrade: The submitted trade
+//   - error: Errors of TransactionBuilder.Build, errTradeTooLarge, or errTradeSubmission
+func (w *Wallet) Trade(ctx context.Context, request WalletTradeRequest) (WalletTrade, error) {
+	built, err := w.builder.Build(ctx, request.Side, TradeTransactionRequest{
+		Mint:             request.Mint,
+		User:             w.key.PublicKey().String(),
+		Amount:           request.Amount,
+		SlippageBps:      request.SlippageBps,
+		ComputeUnitPrice: request.ComputeUnitPrice,
+	})
+	if err != nil {
+		return WalletTrade{}, err
+	}
+	if request.Side == TradeBuy && built.response.SolLimit > w.maxLamports {
+		return WalletTrade{}, fmt.Errorf("%w: the buy may cost %d lamports, the cap is %d", errTradeTooLarge, built.response.SolLimit, w.maxLamports)
+	}
+
+	transaction := built.transaction
+	if _, err := transaction.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(w.key.PublicKey()) {
+			return &w.key
+		}
+		return nil
+	}); err != nil {
+		return WalletTrade{}, err
+	}
+
+	signature, err := w.builder.client.SendTransactionWithOpts(ctx, transaction, rpc.TransactionOpts{PreflightCommitment: rpc.CommitmentProcessed})
+	if err != nil {
+		return WalletTrade{}, fmt.Errorf("%w: %w", errTradeSubmission, err)
+	}
+
+	trade := &WalletTrade{
+		Signature:   signature.String(),
+		Side:        request.Side,
+		Mint:        request.Mint,
+		TokenAmount: built.response.TokenAmount,
+		SolAmount:   built.response.SolAmount,
+		SolLimit:    built.response.SolLimit,
+		Status:      walletTradeSubmitted,
+		SubmittedAt: time.Now().UTC(),
+		lastValid:   built.response.LastValidBlockHeight,
+	}
+	w.remember(trade)
+	slog.Info("Wallet trade submitted", logKeySignature, trade.Signature, "side", trade.Side, "mint", trade.Mint, "sol_limit", trade.SolLimit)
+
+	goSafe("wallet trade tracking", func() { w.track(signature, trade.lastValid) })
+	return *trade, nil
+}
+
+// remember records a trade, forgetting the oldest beyond the history size
+func (w *Wallet) remember(trade *WalletTrade) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.trades[trade.Signature] = trade
+	w.order = append(w.order, trade.Signature)
+	for len(w.order) > walletTradeHistorySize {
+		delete(w.trades, w.order[0])
+		w.order = w.order[1:]
+	}
+}
+
+// track polls the status of a submitted trade until it is confirmed, failed,
+// or its blockhash expired without it landing
+// It gives up after walletMaxConfirmationChecks, so an RPC node failing every
+// check does not keep the trade submitted and its goroutine running forever.
+func (w *Wallet) track(signature solana.Signature, lastValid uint64) {
+	client := w.builder.client
+	ticker := time.NewTicker(walletConfirmationInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for check := 1; ; check++ {
+		<-ticker.C
+		ctx, cancel := context.WithTimeout(context.Background(), transactionBuildTimeout)
+		statuses, err := client.GetSignatureStatuses(ctx, false, signature)
+		if err == nil && len(statuses.Value) == 1 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			switch {
+			case status.Err != nil:
+				cancel()
+				w.settle(signature.String(), walletTradeFailed, status.Slot, fmt.Sprint(status.Err))
+				return
+			case status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed || status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+				cancel()
+				w.settle(signature.String(), walletTradeConfirmed, status.Slot, "")
+				return
+			}
+		} else {
+			// Not seen yet, or the status is unknown: it can only land while its blockhash is valid
+			lastErr = err
+			height, err := client.GetBlockHeight(ctx, rpc.CommitmentConfirmed)
+			if err == nil && height > lastValid {
+				cancel()
+				w.settle(signature.String(), walletTradeExpired, 0, "")
+				return
+			}
+			if err != nil {
+				lastErr = err
+			}
+		}
+		cancel()
+
+		if check >= walletMaxConfirmationChecks {
+			reason := fmt.Sprintf("not confirmed after %d checks", check)
+			if lastErr != nil {
+				reason = fmt.Sprintf("%s: %v", reason, lastErr)
+			}
+			w.settle(signature.String(), walletTradeUnknown, 0, reason)
+			return
+		}
+	}
+}
+
+// settle records the final status of a trade
+func (w *Wallet) settle(signature, status string, slot uint64, reason string) {
+	w.mutex.Lock()
+	trade, found := w.trades[signature]
+	if found {
+		trade.Status = status
+		trade.Slot = slot
+		trade.Error = reason
+		trade.SettledAt = time.Now().UTC()
+	}
+	w.mutex.Unlock()
+
+	if status == walletTradeConfirmed {
+		slog.Info("Wallet trade confirmed", logKeySignature, signature, logKeySlot, slot)
+	} else {
+		slog.Warn("Wallet trade did not go through", logKeySignature, signature, "status", status, logKeyError, reason)
+	}
+}
+
+// Trades returns the remembered trades, newest first
+func (w *Wallet) Trades() []WalletTrade {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	trades := make([]WalletTrade, 0, len(w.order))
+	for i := len(w.order) - 1; i >= 0; i-- {
+		trades = append(trades, *w.trades[w.order[i]])
+	}
+	return trades
+}
+
+// TradeStatus returns a remembered trade
+func (w *Wallet) TradeStatus(signature string) (WalletTrade, bool) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	trade, found := w.trades[signature]
+	if !found {
+		return WalletTrade{}, false
+	}
+	return *trade, true
+}
+
+// HandleGetWallet returns the address of the wallet and its recent trades
+func HandleGetWallet(w http.ResponseWriter, r *http.Request) {
+	if HotWallet == nil {
+		writeError(w, http.StatusNotFound, "the wallet is disabled")
+		return
+	}
+	writeJSON(w, http.StatusOK, walletResponse{
+		PublicKey:        HotWallet.key.PublicKey().String(),
+		MaxTradeLamports: HotWallet.maxLamports,
+		Trades:           HotWallet.Trades(),
+	})
+}
+
+// HandleSubmitWalletTrade signs and submits a trade with the wallet
+//
+// Parameters:
+//   - w: HTTP response writer
+//   - r: HTTP request with a WalletTradeRequest body
+func HandleSubmitWalletTrade(w http.ResponseWriter, r *http.Request) {
+	if HotWallet == nil {
+		writeError(w, http.StatusNotFound, "the wallet is disabled")
+		return
+	}
+
+	var request WalletTradeRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), transactionBuildTimeout)
+	defer cancel()
+
+	trade, err := HotWallet.Trade(ctx, request)
+	switch {
+	case errors.Is(err, errInvalidTradeRequest), errors.Is(err, errTradeTooLarge):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errUnknownBondingCurve):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+	default:
+		writeJSON(w, http.StatusAccepted, trade)
+	}
+}
+
+// HandleGetWalletTrade returns the status of a wallet trade
+func HandleGetWalletTrade(w http.ResponseWriter, r *http.Request) {
+	if HotWallet == nil {
+		writeError(w, http.StatusNotFound, "the wallet is disabled")
+		return
+	}
+	trade, found := HotWallet.TradeStatus(mux.Vars(r)["signature"])
+	if !found {
+		writeError(w, http.StatusNotFound, "unknown trade")
+		return
+	}
+	writeJSON(w, http.StatusOK, trade)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the number of goroutines above which the
+	// watchdog warns and captures a goroutine profile
+	watchdogMaxGoroutinesEnv = "WATCHDOG_MAX_GOROUTINES"
+
+	// Environment variable with the heap size in MiB above which the watchdog
+	// warns and captures a heap profile
+	watchdogMaxHeapEnv = "WATCHDOG_MAX_HEAP_MB"
+
+	// Environment variable with the interval between checks, e.g. 15s
+	watchdogIntervalEnv = "WATCHDOG_INTERVAL"
+
+	// Environment variable with the minimum time between two warnings and
+	// profiles of the same kind, so a sustained breach does not fill the disk
+	watchdogCooldownEnv = "WATCHDOG_COOLDOWN"
+
+	// Environment variable with the directory profiles are written to; the
+	// system temporary directory is used when it is unset
+	watchdogProfileDirEnv = "WATCHDOG_PROFILE_DIR"
+
+	// Interval used when WATCHDOG_INTERVAL is unset
+	defaultWatchdogInterval = 15 * time.Second
+
+	// Cooldown used when WATCHDOG_COOLDOWN is unset
+	defaultWatchdogCooldown = 10 * time.Minute
+)
+
+// watchdog compares the goroutine count and heap size with their thresholds
+type watchdog struct {
+	maxGoroutines int           // Goroutine threshold, 0 if not watched
+	maxHeap       uint64        // Heap threshold in bytes, 0 if not watched
+	cooldown      time.Duration // Minimum time between two warnings of the same kind
+	profileDir    string        // Directory profiles are written to
+
+	lastWarnings map[string]time.Time // Time of the last warning by profile kind, only used by the watch loop
+}
+
+// setupWatchdog starts the watchdog when WATCHDOG_MAX_GOROUTINES or
+// WATCHDOG_MAX_HEAP_MB is set
+func setupWatchdog() error {
+	w := &watchdog{cooldown: defaultWatchdogCooldown, lastWarnings: map[string]time.Time{}}
+
+	if value := os.Getenv(watchdogMaxGoroutinesEnv); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", watchdogMaxGoroutinesEnv, value)
+		}
+		w.maxGoroutines = parsed
+	}
+	if value := os.Getenv(watchdogMaxHeapEnv); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || parsed == 0 {
+			return fmt.Errorf("invalid %s %q", watchdogMaxHeapEnv, value)
+		}
+		w.maxHeap = parsed << 20
+	}
+	if w.maxGoroutines == 0 && w.maxHeap == 0 {
+		return nil
+	}
+
+	interval := defaultWatchdogInterval
+	if value := os.Getenv(watchdogIntervalEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return fmt.Errorf("invalid %s %q", watchdogIntervalEnv, value)
+		}
+		interval = parsed
+	}
+	if value := os.Getenv(watchdogCooldownEnv); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return fmt.Errorf("invalid %s %q", watchdogCooldownEnv, value)
+		}
+		w.cooldown = parsed
+	}
+
+	w.profileDir = envOrDefault(watchdogProfileDirEnv, os.TempDir())
+	if err := os.MkdirAll(w.profileDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create watchdog profile directory %s: %w", w.profileDir, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runRecovered("watchdog", w.check)
+		}
+	}()
+
+	slog.Info("Watchdog checking goroutines and heap", "interval", interval, "profile_dir", w.profileDir)
+	return nil
+}
+
+// check warns and captures a profile for every threshold that is exceeded,
+// at most once per cooldown
+func (w *watchdog) check() {
+	if w.maxGoroutines > 0 {
+		if goroutines := runtime.NumGoroutine(); goroutines > w.maxGoroutines && w.due("goroutine") {
+			path := w.profile("goroutine")
+			slog.Warn("Goroutine count above watchdog threshold",
+				"goroutines", goroutines, "threshold", w.maxGoroutines,
+				"connected_clients", ConnectedClients.Size(), "profile", path)
+		}
+	}
+
+	if w.maxHeap > 0 {
+		var memory runtime.MemStats
+		runtime.ReadMemStats(&memory)
+		if memory.HeapAlloc > w.maxHeap && w.due("heap") {
+			path := w.profile("heap")
+			slog.Warn("Heap size above watchdog threshold",
+				"heap_alloc_bytes", memory.HeapAlloc, "threshold_bytes", w.maxHeap, "profile", path)
+		}
+	}
+}
+
+// due reports whether the cooldown of a profile kind has passed, starting a new one if so
+func (w *watchdog) due(kind string) bool {
+	now := time.Now()
+	if last, ok := w.lastWarnings[kind]; ok && now.Sub(last) < w.cooldown {
+		return false
+	}
+	w.lastWarnings[kind] = now
+	return true
+}
+
+// profile writes a profile of the given kind to the profile directory
+//
+// Parameters:
+//   - kind: name of the runtime/pprof profile, goroutine or heap
+//
+// Returns:
+//   - string: Path of the written profile, empty if it could not be written
+func (w *watchdog) profile(kind string) string {
+	now := time.Now().UTC()
+	path := filepath.Join(w.profileDir, fmt.Sprintf("%s-%s.pprof", kind, now.Format("20060102T150405.000Z")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		slog.Error("Failed to create watchdog profile", "path", path, logKeyError, err)
+		return ""
+	}
+	defer file.Close()
+
+	if err := pprof.Lookup(kind).WriteTo(file, 0); err != nil {
+		slog.Error("Failed to write watchdog profile", "path", path, logKeyError, err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,530 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Configuration constants
+const (
+	// Environment variable pointing to a JSON file of webhooks to register at startup
+	webhooksFileEnv = "WEBHOOKS_FILE"
+
+	// Number of events buffered per endpoint before new events are dropped
+	webhookQueueSize = 1000
+
+	// Number of alerts and digests buffered per endpoint before new ones are
+	// dropped; they are rare, so a full queue means the endpoint is stuck
+	webhookNoticeQueueSize = 20
+
+	// Most bytes of a response body read before the connection is reused,
+	// larger bodies closing the connection instead
+	webhookMaxDrainBytes = 64 << 10
+
+	// Maximum delivery attempts per event, including the first one
+	webhookMaxAttempts = 6
+
+	// Delay before the first retry, doubled after every failed attempt
+	webhookInitialBackoff = 1 * time.Second
+
+	// Upper bound for the delay between retries
+	webhookMaxBackoff = 1 * time.Minute
+
+	// Timeout for a single delivery request
+	webhookRequestTimeout = 10 * time.Second
+
+	// Number of recent deliveries kept per endpoint for status reporting
+	webhookRecentDeliveries = 20
+
+	// X-Event-Type of operational alert deliveries
+	webhookAlertType = "alert"
+
+	// X-Event-Type of digest deliveries
+	webhookDigestType = "digest"
+
+	// Headers carrying the delivery signature and the signed timestamp
+	webhookSignatureHeader = "X-Signature"
+	webhookTimestampHeader = "X-Signature-Timestamp"
+)
+
+// WebhookFilter selects the events delivered to an endpoint
+type WebhookFilter struct {
+	EventTypes []EventType `json:"event_types,omitempty"` // Event types to deliver, defaults to creations only
+	Mints      []string    `json:"mints,omitempty"`       // Restrict delivery to these mints, empty matches all
+	Alerts     bool        `json:"alerts,omitempty"`      // Also deliver operational alerts, such as a stale feed
+	Digests    bool        `json:"digests,omitempty"`     // Also deliver the scheduled digests
+}
+
+// WebhookConfig describes a registered webhook endpoint
+type WebhookConfig struct {
+	ID     string        `json:"id"`               // Identifier assigned at registration
+	URL    string        `json:"url"`              // Destination receiving POSTed events
+	Secret string        `json:"secret,omitempty"` // HMAC key signing deliveries, unsigned if empty
+	Filter WebhookFilter `json:"filter"`           // Events delivered to this endpoint
+}
+
+// WebhookDelivery records the outcome of delivering a single event
+type WebhookDelivery struct {
+	DeliveryID string    `json:"delivery_id"`           // Identifier sent in the X-Delivery-ID header
+	EventType  EventType `json:"event_type"`            // Type of the delivered event
+	Mint       string    `json:"mint"`                  // Mint of the delivered event
+	Attempts   int       `json:"attempts"`              // Number of attempts made
+	StatusCode int       `json:"status_code,omitempty"` // Status code of the last attempt
+	Error      string    `json:"error,omitempty"`       // Error of the last attempt
+	Success    bool      `json:"success"`               // True if the event was accepted
+	FinishedAt time.Time `json:"finished_at"`           // Time delivery finished or was abandoned
+}
+
+// WebhookStatus tracks delivery counters for an endpoint
+type WebhookStatus struct {
+	Delivered uint64            `json:"delivered"` // Events accepted by the endpoint
+	Failed    uint64            `json:"failed"`    // Events abandoned after all retries
+	Dropped   uint64            `json:"dropped"`   // Events, alerts and digests dropped because their queue was full
+	Pending   int               `json:"pending"`   // Events waiting in the queue
+	Recent    []WebhookDelivery `json:"recent"`    // Most recent deliveries, newest last
+}
+
+// WebhookInfo is the admin view of a registered endpoint
+// The secret itself is never returned, only whether one is set
+type WebhookInfo struct {
+	WebhookConfig
+	Signed bool          `json:"signed"`
+	Status WebhookStatus `json:"status"`
+}
+
+// webhookNotice is an alert or digest waiting to be delivered to an endpoint
+type webhookNotice struct {
+	eventType EventType
+	body      []byte
+	attrs     []any // Logged if the delivery fails
+}
+
+// webhookEndpoint owns the queues and delivery workers of one registered webhook
+// Events and notices have a worker each, so events being retried do not hold
+// back an alert.
+type webhookEndpoint struct {
+	config  WebhookConfig
+	queue   chan Event
+	notices chan webhookNotice
+	stop    chan struct{}
+	mutex   sync.Mutex
+	status  WebhookStatus
+}
+
+// WebhookManager delivers published events to registered webhook endpoints
+type WebhookManager struct {
+	mutex     sync.RWMutex
+	endpoints map[string]*webhookEndpoint
+	client    *http.Client
+}
+
+// Webhooks holds every registered webhook endpoint
+var Webhooks = NewWebhookManager()
+
+// NewWebhookManager creates a manager without any endpoints
+func NewWebhookManager() *WebhookManager {
+	return &WebhookManager{
+		endpoints: make(map[string]*webhookEndpoint),
+		client:    &http.Client{Timeout: webhookRequestTimeout},
+	}
+}
+
+// setupWebhooks registers the webhooks listed in WEBHOOKS_FILE and subscribes
+// the manager to the event stream
+func setupWebhooks() error {
+	RegisterSink(Webhooks)
+
+	path := os.Getenv(webhooksFileEnv)
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read webhooks file: %w", err)
+	}
+
+	var configs []WebhookConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return fmt.Errorf("failed to parse webhooks file: %w", err)
+	}
+
+	for _, config := range configs {
+		if _, err := Webhooks.Add(config); err != nil {
+			return fmt.Errorf("failed to register webhook %s: %w", config.URL, err)
+		}
+	}
+
+	slog.Info("Registered webhooks", "count", len(configs), "path", path)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (m *WebhookManager) Name() string {
+	return "webhooks"
+}
+
+// Publish queues the event on every endpoint whose filter matches
+// Events are dropped for endpoints whose queue is full
+func (m *WebhookManager) Publish(event Event) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, endpoint := range m.endpoints {
+		if !endpoint.config.Filter.Matches(event) {
+			continue
+		}
+
+		select {
+		case endpoint.queue <- event:
+		default:
+			endpoint.mutex.Lock()
+			endpoint.status.Dropped++
+			endpoint.mutex.Unlock()
+		}
+	}
+}
+
+// Alert queues an operational alert on every endpoint that opted in
+// Alerts are sent once without retries, as the next alert supersedes them
+func (m *WebhookManager) Alert(alert OperationalAlert) {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return
+	}
+	notice := webhookNotice{eventType: webhookAlertType, body: body, attrs: []any{"kind", alert.Kind}}
+	m.notify(notice, func(filter WebhookFilter) bool { return filter.Alerts })
+}
+
+// Digest queues a digest on every endpoint that opted in, sent once without retries
+func (m *WebhookManager) Digest(digest Digest) {
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return
+	}
+	notice := webhookNotice{eventType: webhookDigestType, body: body, attrs: []any{"period", digest.Period}}
+	m.notify(notice, func(filter WebhookFilter) bool { return filter.Digests })
+}
+
+// notify queues a notice on every endpoint whose filter selects it
+// Notices are dropped for endpoints whose notice queue is full
+func (m *WebhookManager) notify(notice webhookNotice, selected func(WebhookFilter) bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, endpoint := range m.endpoints {
+		if !selected(endpoint.config.Filter) {
+			continue
+		}
+
+		select {
+		case endpoint.notices <- notice:
+		default:
+			endpoint.mutex.Lock()
+			endpoint.status.Dropped++
+			endpoint.mutex.Unlock()
+		}
+	}
+}
+
+// QueueDepth returns the number of events waiting on every endpoint
+func (m *WebhookManager) QueueDepth() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	depth := 0
+	for _, endpoint := range m.endpoints {
+		depth += len(endpoint.queue)
+	}
+	return depth
+}
+
+// DroppedEvents returns the number of events dropped because an endpoint queue was full
+func (m *WebhookManager) DroppedEvents() uint64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var dropped uint64
+	for _, endpoint := range m.endpoints {
+		endpoint.mutex.Lock()
+		dropped += endpoint.status.Dropped
+		endpoint.mutex.Unlock()
+	}
+	return dropped
+}
+
+// Add registers a new endpoint and starts its delivery worker
+//
+// Parameters:
+//   - config: the endpoint to register; an ID is generated if empty
+//
+// Returns:
+//   - WebhookInfo: the registered endpoint including its ID, without its secret
+//   - error: if the URL is not a valid http(s) URL or the ID is taken
+func (m *WebhookManager) Add(config WebhookConfig) (WebhookInfo, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return WebhookInfo{}, fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+
+	if config.ID == "" {
+		config.ID = newDeliveryID()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.endpoints[config.ID]; exists {
+		return WebhookInfo{}, fmt.Errorf("webhook %s already exists", config.ID)
+	}
+
+	endpoint := &webhookEndpoint{
+		config:  config,
+		queue:   make(chan Event, webhookQueueSize),
+		notices: make(chan webhookNotice, webhookNoticeQueueSize),
+		stop:    make(chan struct{}),
+		status:  WebhookStatus{Recent: []WebhookDelivery{}},
+	}
+	m.endpoints[config.ID] = endpoint
+
+	go m.deliverLoop(endpoint)
+	go m.noticeLoop(endpoint)
+
+	return endpoint.info(), nil
+}
+
+// Remove unregisters an endpoint and stops its worker; queued events are discarded
+//
+// Returns:
+//   - bool: false if no endpoint with the given ID exists
+func (m *WebhookManager) Remove(id string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	endpoint, exists := m.endpoints[id]
+	if !exists {
+		return false
+	}
+
+	close(endpoint.stop)
+	delete(m.endpoints, id)
+	return true
+}
+
+// List returns the configuration and delivery status of every endpoint
+func (m *WebhookManager) List() []WebhookInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	infos := make([]WebhookInfo, 0, len(m.endpoints))
+	for _, endpoint := range m.endpoints {
+		infos = append(infos, endpoint.info())
+	}
+
+	slices.SortFunc(infos, func(a, b WebhookInfo) int {
+		if a.ID < b.ID {
+			return -1
+		}
+		if a.ID > b.ID {
+			return 1
+		}
+		return 0
+	})
+	return infos
+}
+
+// Matches reports whether the filter selects the given event
+func (f WebhookFilter) Matches(event Event) bool {
+	if len(f.EventTypes) == 0 {
+		if event.Type != EventCreate {
+			return false
+		}
+	} else if !slices.Contains(f.EventTypes, event.Type) {
+		return false
+	}
+
+	return len(f.Mints) == 0 || slices.Contains(f.Mints, event.Mint)
+}
+
+// deliverLoop delivers queued events to an endpoint one at a time until the
+// endpoint is removed
+func (m *WebhookManager) deliverLoop(endpoint *webhookEndpoint) {
+	for {
+		select {
+		case <-endpoint.stop:
+			return
+		case event := <-endpoint.queue:
+			delivery := m.deliverWithRetry(endpoint, event)
+			endpoint.recordDelivery(delivery)
+		}
+	}
+}
+
+// noticeLoop delivers queued alerts and digests to an endpoint one at a time
+// until the endpoint is removed
+func (m *WebhookManager) noticeLoop(endpoint *webhookEndpoint) {
+	for {
+		select {
+		case <-endpoint.stop:
+			return
+		case notice := <-endpoint.notices:
+			if _, err := m.post(endpoint.config, newDeliveryID(), notice.eventType, notice.body); err != nil {
+				attrs := append([]any{"type", notice.eventType, "webhook", endpoint.config.ID, logKeyError, err}, notice.attrs...)
+				slog.Warn("Failed to deliver notice to webhook", attrs...)
+			}
+		}
+	}
+}
+
+// deliverWithRetry POSTs an event, retrying failures with exponential backoff
+// Client errors other than 429 are not retried since resending cannot fix them
+func (m *WebhookManager) deliverWithRetry(endpoint *webhookEndpoint, event Event) WebhookDelivery {
+	delivery := WebhookDelivery{
+		DeliveryID: newDeliveryID(),
+		EventType:  event.Type,
+		Mint:       event.Mint,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to marshal event: %v", err)
+		delivery.FinishedAt = time.Now().UTC()
+		return delivery
+	}
+
+	backoff := webhookInitialBackoff
+	for delivery.Attempts < webhookMaxAttempts {
+		delivery.Attempts++
+
+		statusCode, err := m.post(endpoint.config, delivery.DeliveryID, event.Type, body)
+		delivery.StatusCode = statusCode
+		if err == nil {
+			delivery.Error = ""
+			delivery.Success = true
+			break
+		}
+		delivery.Error = err.Error()
+
+		// Permanent failures are not worth retrying
+		if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusTooManyRequests {
+			break
+		}
+		if delivery.Attempts == webhookMaxAttempts {
+			break
+		}
+
+		select {
+		case <-endpoint.stop:
+			delivery.FinishedAt = time.Now().UTC()
+			return delivery
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, webhookMaxBackoff)
+	}
+
+	delivery.FinishedAt = time.Now().UTC()
+	if !delivery.Success {
+		slog.Warn("Webhook gave up on event", "webhook", endpoint.config.ID, logKeyEventType, event.Type, logKeyMint, event.Mint,
+			"attempts", delivery.Attempts, logKeyError, delivery.Error)
+	}
+	return delivery
+}
+
+// post sends a single delivery attempt
+// When the endpoint has a secret, every attempt is signed with a fresh timestamp
+// so receivers can reject replays of old deliveries
+//
+// Returns:
+//   - int: the response status code, 0 if no response was received
+//   - error: nil only for 2xx responses
+func (m *WebhookManager) post(config WebhookConfig, deliveryID string, eventType EventType, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Delivery-ID", deliveryID)
+	req.Header.Set("X-Event-Type", string(eventType))
+
+	if config.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(webhookTimestampHeader, timestamp)
+		req.Header.Set(webhookSignatureHeader, signWebhookPayload(config.Secret, timestamp, body))
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	// Reading the body lets the connection be reused for the next delivery
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookMaxDrainBytes))
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// info returns the admin view of the endpoint, with the secret stripped
+func (e *webhookEndpoint) info() WebhookInfo {
+	e.mutex.Lock()
+	status := e.status
+	status.Recent = slices.Clone(e.status.Recent)
+	e.mutex.Unlock()
+
+	status.Pending = len(e.queue)
+
+	config := e.config
+	config.Secret = ""
+	return WebhookInfo{WebhookConfig: config, Signed: e.config.Secret != "", Status: status}
+}
+
+// recordDelivery updates the counters and history of an endpoint
+func (e *webhookEndpoint) recordDelivery(delivery WebhookDelivery) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if delivery.Success {
+		e.status.Delivered++
+	} else {
+		e.status.Failed++
+	}
+
+	e.status.Recent = append(e.status.Recent, delivery)
+	if len(e.status.Recent) > webhookRecentDeliveries {
+		e.status.Recent = e.status.Recent[1:]
+	}
+}
+
+// signWebhookPayload computes the X-Signature header value for a delivery
+// The signature is "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the endpoint secret
+func signWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID returns a random identifier for webhooks and deliveries
+func newDeliveryID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSignWebhookPayload checks signatures against HMAC-SHA256 values computed independently
+func TestSignWebhookPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		expected  string
+	}{
+		{
+			name:      "event body",
+			secret:    "topsecret",
+			timestamp: "1700000000",
+			body:      `{"type":"create"}`,
+			expected:  "sha256=e50bec28f777d81c8a215747a71db03519b95f7f5e485268395e43d5be202510",
+		},
+		{
+			name:      "empty secret and body",
+			secret:    "",
+			timestamp: "0",
+			body:      "",
+			expected:  "sha256=b849d5a581847b281957065739df36df2463d1977ea8d6e1e4e6cf33fadc68c3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if signature := signWebhookPayload(test.secret, test.timestamp, []byte(test.body)); signature != test.expected {
+				t.Fatalf("signed %q, expected %q", signature, test.expected)
+			}
+		})
+	}
+}
+
+// TestSignWebhookPayloadVerification checks that a receiver recomputing the
+// signature accepts the delivery, and rejects it once anything signed changed
+func TestSignWebhookPayloadVerification(t *testing.T) {
+	const secret, timestamp, body = "topsecret", "1700000000", `{"type":"trade"}`
+	signature := signWebhookPayload(secret, timestamp, []byte(body))
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		valid     bool
+	}{
+		{name: "untouched", secret: secret, timestamp: timestamp, body: body, valid: true},
+		{name: "other secret", secret: "othersecret", timestamp: timestamp, body: body},
+		{name: "replayed timestamp", secret: secret, timestamp: "1700000001", body: body},
+		{name: "tampered body", secret: secret, timestamp: timestamp, body: `{"type":"create"}`},
+		// The separator keeps the timestamp and the body from running into each other
+		{name: "moved separator", secret: secret, timestamp: "170000000", body: "0." + body},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recomputed := signWebhookPayload(test.secret, test.timestamp, []byte(test.body))
+			if valid := hmac.Equal([]byte(recomputed), []byte(signature)); valid != test.valid {
+				t.Fatalf("verified %v, expected %v", valid, test.valid)
+			}
+		})
+	}
+}
+
+// TestHandleCreateWebhookStripsSecret checks that the registration response
+// tells a webhook is signed without returning its secret, like the list does
+func TestHandleCreateWebhookStripsSecret(t *testing.T) {
+	body := `{"id":"test-signed","url":"https://example.com/hook","secret":"topsecret"}`
+	recorder := httptest.NewRecorder()
+	HandleCreateWebhook(recorder, httptest.NewRequest(http.MethodPost, adminWebhooksEndpoint, strings.NewReader(body)))
+	defer Webhooks.Remove("test-signed")
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status %d, expected %d: %s", recorder.Code, http.StatusCreated, recorder.Body)
+	}
+	if strings.Contains(recorder.Body.String(), "topsecret") {
+		t.Fatalf("response contains the secret: %s", recorder.Body)
+	}
+
+	var created WebhookInfo
+	if err := json.Unmarshal(recorder.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if created.ID != "test-signed" || !created.Signed || created.Secret != "" {
+		t.Fatalf("created %+v, expected a signed webhook without its secret", created)
+	}
+
+	for _, listed := range Webhooks.List() {
+		if listed.ID == created.ID && (listed.Signed != created.Signed || listed.Secret != "") {
+			t.Fatalf("listed %+v, created %+v", listed, created)
+		}
+	}
+}
+
+// TestWebhookAlertsQueued checks that alerts to a stuck endpoint wait in its
+// bounded notice queue, one request at a time, and are dropped once it is full
+func TestWebhookAlertsQueued(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, peak := 0, 0
+	started := make(chan struct{}, webhookNoticeQueueSize+10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mutex.Unlock()
+		started <- struct{}{}
+
+		<-release
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+	}))
+	defer server.Close()
+	defer close(release)
+
+	manager := NewWebhookManager()
+	info, err := manager.Add(WebhookConfig{URL: server.URL, Filter: WebhookFilter{Alerts: true}})
+	if err != nil {
+		t.Fatalf("failed to add webhook: %v", err)
+	}
+	defer manager.Remove(info.ID)
+
+	// One alert is being delivered, the queue holds the next ones
+	sent := webhookNoticeQueueSize + 10
+	manager.Alert(OperationalAlert{Kind: "stale_feed", Firing: true})
+	<-started
+	for range sent - 1 {
+		manager.Alert(OperationalAlert{Kind: "stale_feed", Firing: true})
+	}
+
+	if dropped := manager.DroppedEvents(); dropped != uint64(sent-1-webhookNoticeQueueSize) {
+		t.Fatalf("got %d dropped, expected %d", dropped, sent-1-webhookNoticeQueueSize)
+	}
+	time.Sleep(10 * time.Millisecond)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if peak != 1 {
+		t.Fatalf("got %d concurrent deliveries, expected 1", peak)
+	}
+}
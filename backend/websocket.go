@@ -1,13 +1,23 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/puzpuzpuz/xsync/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Configuration constants
@@ -21,6 +31,20 @@ const (
 
 	// Pong response message
 	pongResponse = `{"message":"pong"}`
+
+	// Environment variable with the comma-separated origins browsers may open
+	// the WebSocket feed from, in the CORS_ALLOWED_ORIGINS format, or
+	// same-origin for pages served from the host the feed is on; any origin
+	// may connect when it is unset. Clients other than browsers send no
+	// origin and are never refused.
+	wsAllowedOriginsEnv = "WS_ALLOWED_ORIGINS"
+
+	// Value of WS_ALLOWED_ORIGINS allowing only the host of the feed
+	wsSameOrigin = "same-origin"
+
+	// Largest message a client may send; clients only send pings, so anything
+	// bigger ends the connection and counts towards an automatic ban
+	maxClientMessageSize = 4096
 )
 
 // Client represents a connected WebSocket client
@@ -28,6 +52,31 @@ const (
 type Client struct {
 	Connection *websocket.Conn
 	Mutex      sync.Mutex
+
+	ip          string    // IP address of the client
+	userAgent   string    // User-Agent of the upgrade request
+	tenant      string    // Tenant of the client certificate, when mutual TLS is enabled
+	connectedAt time.Time // Time the connection was upgraded
+	geo         GeoInfo   // Location of the IP address, when GeoIP is configured
+
+	chaosDelay time.Duration // Delay added to every write, when chaos mode picked the client as slow
+
+	messagesSent atomic.Uint64 // Messages written, for the audit log
+	bytesSent    atomic.Uint64 // Message bytes written, for the audit log
+}
+
+// send writes a text message to the client and counts it
+// The caller must hold the client mutex
+func (c *Client) send(message []byte) error {
+	if c.chaosDelay > 0 {
+		time.Sleep(c.chaosDelay)
+	}
+	if err := c.Connection.WriteMessage(websocket.TextMessage, message); err != nil {
+		return err
+	}
+	c.messagesSent.Add(1)
+	c.bytesSent.Add(uint64(len(message)))
+	return nil
 }
 
 // ConnectedClients stores all currently connected WebSocket clients
@@ -36,40 +85,161 @@ var ConnectedClients = xsync.NewMap[string, *Client]()
 
 // upgrader handles HTTP to WebSocket connection upgrades
 var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for development (should be restricted in production)
-		return true
-	},
+	CheckOrigin:       checkWebSocketOrigin,
 	EnableCompression: true,
 	ReadBufferSize:    readBufferSize,
 	WriteBufferSize:   writeBufferSize,
 }
 
+// Origins allowed to open the WebSocket feed, set by WS_ALLOWED_ORIGINS; nil
+// allows any origin
+var (
+	wsAllowedOrigins originList
+	wsSameOriginOnly bool
+)
+
+// loadWebSocketOrigins reads the origins allowed to open the WebSocket feed
+func loadWebSocketOrigins() error {
+	value := strings.TrimSpace(os.Getenv(wsAllowedOriginsEnv))
+	wsAllowedOrigins, wsSameOriginOnly = nil, value == wsSameOrigin
+	if value == "" || wsSameOriginOnly {
+		return nil
+	}
+
+	origins, err := parseOriginList(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", wsAllowedOriginsEnv, err)
+	}
+	wsAllowedOrigins = origins
+	return nil
+}
+
+// checkWebSocketOrigin reports whether the origin of an upgrade request may
+// open the WebSocket feed
+func checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if wsSameOriginOnly {
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+	return wsAllowedOrigins == nil || wsAllowedOrigins.match(origin) != ""
+}
+
 // HandleWebSocket handles incoming WebSocket connection requests
 // It upgrades the HTTP connection to WebSocket and manages the client lifecycle
+// Clients may pass ?backlog=N to first receive the last N buffered creations,
+// replay parameters to receive stored creations instead of live ones,
+// ?portfolio=ACCOUNT to receive the updates of a tracked portfolio instead,
+// ?alerts=true to receive operational and alert rule alerts instead, or
+// ?commitment=confirmed to receive creations once confirmed instead of as
+// soon as they are seen; the processed stream then also carries the reverts
 //
 // Parameters:
 //   - w: HTTP response writer
 //   - r: HTTP request containing the WebSocket upgrade request
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	backlog, err := intQueryParam(r, "backlog", 0)
+	if err != nil || backlog < 0 || backlog > maxWebSocketBacklog {
+		http.Error(w, "backlog must be between 0 and "+strconv.Itoa(maxWebSocketBacklog), http.StatusBadRequest)
+		return
+	}
+
+	replay, err := parseHistoryReplay(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	portfolio := r.URL.Query().Get("portfolio")
+	if portfolio != "" && (Portfolios == nil || !Portfolios.Tracks(portfolio)) {
+		http.Error(w, "portfolio account is not tracked", http.StatusNotFound)
+		return
+	}
+
+	var alerts bool
+	if value := r.URL.Query().Get("alerts"); value != "" {
+		if alerts, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "alerts must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	commitment := r.URL.Query().Get("commitment")
+	switch commitment {
+	case "", commitmentProcessed:
+	case commitmentConfirmed:
+		if Confirmations == nil {
+			http.Error(w, "the confirmed stream requires "+confirmationTrackingEnv, http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "commitment must be processed or confirmed", http.StatusBadRequest)
+		return
+	}
+
+	ip := clientIP(r)
+	if !IPAccess.Allowed(ip) {
+		slog.Info("Refused WebSocket connection from denied address", "client_ip", ip)
+		http.Error(w, "connections from this address are not allowed", http.StatusForbidden)
+		return
+	}
+	if refuseBanned(w, ip) {
+		return
+	}
+	// Every connection counts, so clients stuck in a reconnect loop get banned
+	AutoBan.Record(ip, abuseReconnect)
+
 	// Upgrade the HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Failed to upgrade connection to WebSocket: %v", err)
+		slog.Warn("Failed to upgrade connection to WebSocket", logKeyClientID, r.RemoteAddr, logKeyError, err)
 		return
 	}
 	defer conn.Close()
+	conn.SetReadLimit(maxClientMessageSize)
 
-	// Handle the WebSocket connection
-	handleConnection(conn)
+	client := &Client{
+		Connection:  conn,
+		ip:          ip,
+		userAgent:   r.UserAgent(),
+		tenant:      requestTenant(r),
+		connectedAt: time.Now().UTC(),
+	}
+	if Features.Enabled(featureGeoIP) {
+		client.geo = GeoIP.Lookup(ip)
+	}
+	if Chaos != nil {
+		client.chaosDelay = Chaos.clientDelay()
+	}
+
+	// Replay, portfolio, alert and confirmed connections never join the live broadcast
+	var reason string
+	switch {
+	case replay != nil:
+		reason = replay.run(client)
+	case portfolio != "":
+		reason = Portfolios.stream(client, portfolio)
+	case alerts:
+		reason = WebSocketAlerts.stream(client)
+	case commitment == commitmentConfirmed:
+		reason = Confirmations.stream(client)
+	default:
+		reason = handleConnection(client, backlog)
+	}
+	auditConnection(client, reason)
 }
 
 // sendMessageToAllClients broadcasts a message to all connected WebSocket clients
-// It creates a copy of the client list to avoid holding locks during iteration
+// It creates a copy of the client list to avoid holding locks during iteration,
+// and returns once every client has been written to
 //
 // Parameters:
+//   - ctx: context carrying the span and receipt time of the notification being delivered
 //   - message: the message to broadcast to all clients
-func sendMessageToAllClients(message []byte) {
+func sendMessageToAllClients(ctx context.Context, message []byte) {
 	// Create a slice to store client pointers (avoiding mutex copying)
 	allClients := []*Client{}
 
@@ -79,64 +249,113 @@ func sendMessageToAllClients(message []byte) {
 		return true
 	})
 
+	_, span := tracer.Start(ctx, "deliver", trace.WithAttributes(attribute.Int("clients", len(allClients))))
+	defer span.End()
+	correlation := correlationAttrs(ctx)
+	receivedAt, measured := deliveryStart(ctx)
+
 	// Send message to each client asynchronously
+	var failed atomic.Int64
+	var writes sync.WaitGroup
 	for _, client := range allClients {
-		go func(c *Client) {
-			c.Mutex.Lock()
-			defer c.Mutex.Unlock()
+		writes.Add(1)
+		goSafe("client writer", func() {
+			defer writes.Done()
+			client.Mutex.Lock()
+			defer client.Mutex.Unlock()
 
 			// Send the message to this client
-			if err := c.Connection.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("Failed to send message to client %s: %v", c.Connection.RemoteAddr(), err)
+			if err := client.send(message); err != nil {
+				failed.Add(1)
+				slog.Warn("Failed to send message to client", append([]any{logKeyClientID, client.Connection.RemoteAddr().String(), logKeyError, err}, correlation...)...)
+				return
+			}
+			if measured {
+				deliveryLatency.Observe(time.Since(receivedAt))
 			}
-		}(client)
+		})
 	}
+	writes.Wait()
+	broadcastWrites.Add(uint64(len(allClients)))
+	broadcastFailures.Add(uint64(failed.Load()))
+	span.SetAttributes(attribute.Int64("failed_clients", failed.Load()))
+	slog.Debug("Delivered message", append([]any{"clients", len(allClients), "failed", failed.Load()}, correlation...)...)
 }
 
 // handleConnection manages an individual WebSocket connection
 // It handles incoming messages, ping/pong responses, and client lifecycle
 //
 // Parameters:
-//   - conn: the WebSocket connection to manage
-func handleConnection(conn *websocket.Conn) {
+//   - client: the newly connected client
+//   - backlog: number of buffered creations to send before live messages
+//
+// Returns:
+//   - string: why the connection ended
+func handleConnection(client *Client, backlog int) string {
 	// Get the client's remote address for identification
-	address := conn.RemoteAddr().String()
-	log.Printf("New WebSocket connection from: %s", address)
+	address := client.Connection.RemoteAddr().String()
+	logger := clientLogger(address)
+	logger.Info("New WebSocket connection")
 
-	// Create a new client instance
-	client := &Client{
-		Connection: conn,
-		Mutex:      sync.Mutex{},
-	}
+	// Hold the client lock while the backlog is sent so live messages queue
+	// behind it; creations still waiting for the ring buffer writer are missed
+	client.Mutex.Lock()
 
 	// Store the client in the connected clients map
 	ConnectedClients.Store(address, client)
-	log.Printf("Client %s added to connected clients", address)
+	logger.Debug("Client added to connected clients", "connected_clients", ConnectedClients.Size())
+
+	messages, err := creationBacklog(backlog)
+	if err != nil {
+		logger.Error("Failed to read backlog", logKeyError, err)
+	}
+	for _, message := range messages {
+		if err := client.send(message); err != nil {
+			logger.Warn("Failed to send backlog", logKeyError, err)
+			break
+		}
+	}
+	client.Mutex.Unlock()
 
 	// Main message handling loop
+	err = client.readLoop()
+
+	// Clean up when connection is closed
+	ConnectedClients.Delete(address)
+	reason := disconnectReason(err)
+	logger.Info("Client disconnected", "reason", reason, "connected_clients", ConnectedClients.Size())
+	return reason
+}
+
+// readLoop reads client messages until the connection fails or is closed,
+// answering ping messages with pong responses
+//
+// Returns:
+//   - error: the read error that ended the loop
+func (c *Client) readLoop() error {
+	logger := clientLogger(c.Connection.RemoteAddr().String())
 	for {
 		// Read incoming messages
-		_, message, err := conn.ReadMessage()
+		_, message, err := c.Connection.ReadMessage()
 		if err != nil {
-			log.Printf("Error reading message from client %s: %v", address, err)
-			break
+			if errors.Is(err, websocket.ErrReadLimit) {
+				AutoBan.Record(c.ip, abuseOversizedFrame)
+			}
+			logger.Debug("Stopped reading from client", logKeyError, err)
+			return err
 		}
 
 		// Handle ping messages with pong responses
 		if strings.Contains(string(message), pingMessage) {
-			go func() {
-				client.Mutex.Lock()
-				defer client.Mutex.Unlock()
+			goSafe("pong writer", func() {
+				c.Mutex.Lock()
+				defer c.Mutex.Unlock()
 
 				// Send pong response
-				if err := client.Connection.WriteMessage(websocket.TextMessage, []byte(pongResponse)); err != nil {
-					log.Printf("Failed to send pong to client %s: %v", address, err)
+				if err := c.send([]byte(pongResponse)); err != nil {
+					logger.Warn("Failed to send pong", logKeyError, err)
 				}
-			}()
+			})
 		}
 	}
-
-	// Clean up when connection is closed
-	ConnectedClients.Delete(address)
-	log.Printf("Client %s disconnected and removed from connected clients", address)
 }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+
+	"github.com/go-zeromq/zmq4"
+)
+
+// Configuration constants
+const (
+	// Environment variable with the endpoint the PUB socket binds to, e.g. tcp://127.0.0.1:5556
+	// or ipc:///tmp/nova.sock. The ZeroMQ sink is disabled when it is unset
+	zmqEndpointEnv = "ZMQ_ENDPOINT"
+
+	// Number of events buffered before new events are dropped
+	zmqQueueSize = 10000
+
+	// Version byte leading every binary event frame
+	zmqFrameVersion = 1
+)
+
+// Event type codes used in binary event frames
+const (
+	zmqTypeCreate   byte = 0
+	zmqTypeTrade    byte = 1
+	zmqTypeComplete byte = 2
+	zmqTypePrice    byte = 3
+)
+
+// ZMQSink publishes every event on a ZeroMQ PUB socket
+//
+// Each message has two frames: the event type as the topic, so subscribers can
+// filter by prefix, and the event encoded by encodeEventFrame
+type ZMQSink struct {
+	socket zmq4.Socket
+	queue  chan Event
+}
+
+// setupZMQSink registers the ZeroMQ sink when ZMQ_ENDPOINT is set
+func setupZMQSink() error {
+	endpoint := os.Getenv(zmqEndpointEnv)
+	if endpoint == "" {
+		return nil
+	}
+
+	socket := zmq4.NewPub(context.Background())
+	if err := socket.Listen(endpoint); err != nil {
+		return fmt.Errorf("failed to bind ZeroMQ socket to %s: %w", endpoint, err)
+	}
+
+	sink := &ZMQSink{
+		socket: socket,
+		queue:  make(chan Event, zmqQueueSize),
+	}
+	go sink.publishLoop()
+	RegisterSink(sink)
+
+	slog.Info("Publishing events on ZeroMQ", "endpoint", endpoint)
+	return nil
+}
+
+// Name identifies the sink in logs
+func (z *ZMQSink) Name() string {
+	return "zmq"
+}
+
+// Publish queues the event for the publisher, dropping it if the queue is full
+func (z *ZMQSink) Publish(event Event) {
+	select {
+	case z.queue <- event:
+	default:
+		slog.Warn("ZeroMQ queue full, dropping event", logKeyEventType, event.Type, logKeyMint, event.Mint)
+	}
+}
+
+// publishLoop publishes queued events in order
+func (z *ZMQSink) publishLoop() {
+	for event := range z.queue {
+		frame, err := encodeEventFrame(event)
+		if err != nil {
+			slog.Error("Failed to encode event for ZeroMQ", logKeyError, err)
+			continue
+		}
+
+		if err := z.socket.Send(zmq4.NewMsgFrom([]byte(event.Type), frame)); err != nil {
+			slog.Warn("Failed to publish event to ZeroMQ", logKeyEventType, event.Type, logKeyMint, event.Mint, logKeyError, err)
+		}
+	}
+}
+
+// encodeEventFrame encodes an event into a compact little-endian binary frame
+//
+// The frame starts with its length as a u32 (excluding the prefix itself), so
+// frames can also be concatenated into a stream, as on the TCP feed. Strings are encoded as in Borsh:
+// a u32 length followed by UTF-8 bytes. The layout is:
+//
+//	u32 length | u8 version | u8 type | u64 slot | i64 received_at (unix nanoseconds)
+//	string mint | string signature | type-specific fields
+//
+// Type-specific fields:
+//   - create: string name, string symbol, string uri, string bonding_curve, string creator
+//   - trade: u64 sol_amount, u64 token_amount, u8 is_buy, string user, i64 timestamp,
+//     u64 virtual_sol_reserves, u64 virtual_token_reserves
+//   - complete: string user, string bonding_curve, i64 timestamp
+//   - price: f64 price_usd, string source, i64 timestamp; the signature is empty
+//
+// Parameters:
+//   - event: Event to encode
+//
+// Returns:
+//   - []byte: The encoded frame
+//   - error: Error if the event data has an unexpected type
+func encodeEventFrame(event Event) ([]byte, error) {
+	frame := make([]byte, 4, 256)
+	frame = append(frame, zmqFrameVersion)
+
+	switch data := event.Data.(type) {
+	case CreateEvent:
+		frame = append(frame, zmqTypeCreate)
+		frame = appendFrameHeader(frame, event)
+		frame = appendFrameString(frame, data.Name)
+		frame = appendFrameString(frame, data.Symbol)
+		frame = appendFrameString(frame, data.Uri)
+
+		// Creator and bonding curve are not part of CreateEvent; take them from the store
+		record, _ := Tokens.Get(data.Mint)
+		frame = appendFrameString(frame, record.BondingCurve)
+		frame = appendFrameString(frame, record.Creator)
+	case TradeEvent:
+		frame = append(frame, zmqTypeTrade)
+		frame = appendFrameHeader(frame, event)
+		frame = binary.LittleEndian.AppendUint64(frame, data.SolAmount)
+		frame = binary.LittleEndian.AppendUint64(frame, data.TokenAmount)
+		if data.IsBuy {
+			frame = append(frame, 1)
+		} else {
+			frame = append(frame, 0)
+		}
+		frame = appendFrameString(frame, data.User)
+		frame = binary.LittleEndian.AppendUint64(frame, uint64(data.Timestamp))
+		frame = binary.LittleEndian.AppendUint64(frame, data.VirtualSolReserves)
+		frame = binary.LittleEndian.AppendUint64(frame, data.VirtualTokenReserves)
+	case CompleteEvent:
+		frame = append(frame, zmqTypeComplete)
+		frame = appendFrameHeader(frame, event)
+		frame = appendFrameString(frame, data.User)
+		frame = appendFrameString(frame, data.BondingCurve)
+		frame = binary.LittleEndian.AppendUint64(frame, uint64(data.Timestamp))
+	case PriceEvent:
+		frame = append(frame, zmqTypePrice)
+		frame = appendFrameHeader(frame, event)
+		frame = binary.LittleEndian.AppendUint64(frame, math.Float64bits(data.PriceUSD))
+		frame = appendFrameString(frame, data.Source)
+		frame = binary.LittleEndian.AppendUint64(frame, uint64(data.Timestamp))
+	default:
+		return nil, fmt.Errorf("unsupported event data %T", event.Data)
+	}
+
+	binary.LittleEndian.PutUint32(frame, uint32(len(frame)-4))
+	return frame, nil
+}
+
+// appendFrameHeader appends the fields shared by every event type
+func appendFrameHeader(frame []byte, event Event) []byte {
+	frame = binary.LittleEndian.AppendUint64(frame, event.Slot)
+	frame = binary.LittleEndian.AppendUint64(frame, uint64(event.ReceivedAt.UnixNano()))
+	frame = appendFrameString(frame, event.Mint)
+	return appendFrameString(frame, event.Signature)
+}
+
+// appendFrameString appends a u32 length-prefixed string
+func appendFrameString(frame []byte, value string) []byte {
+	frame = binary.LittleEndian.AppendUint32(frame, uint32(len(value)))
+	return append(frame, value...)
+}